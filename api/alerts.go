@@ -0,0 +1,339 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// alertQueueFile holds alerts suppressed during quiet hours across restarts.
+const alertQueueFile = "alert-queue.json"
+
+// maxQueuedAlerts caps the number of alerts queued per channel.
+const maxQueuedAlerts = 200
+
+// Alert is a notification delivered to the alert channels.
+type Alert struct {
+	Title    string    `json:"title"`
+	Body     string    `json:"body,omitempty"`
+	Severity string    `json:"severity"` // info, warning, critical, ok
+	URL      string    `json:"url,omitempty"`
+	Tag      string    `json:"tag,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// AlertSender delivers an alert on one channel.
+type AlertSender func(ctx context.Context, a Alert) error
+
+// QuietHours is a daily window in which non-critical alerts are queued instead of sent.
+type QuietHours struct {
+	Start string `json:"start,omitempty"` // HH:MM, local time
+	End   string `json:"end,omitempty"`   // HH:MM, local time; may be before start to span midnight
+	// SuppressCritical also queues critical alerts (by default they are always delivered)
+	SuppressCritical bool `json:"suppressCritical,omitempty"`
+}
+
+// QuietHoursConfig holds the global quiet hours and per-channel overrides.
+type QuietHoursConfig struct {
+	QuietHours
+	Channels map[string]QuietHours `json:"channels,omitempty"`
+}
+
+// parseClock parses an HH:MM time into minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (use HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Enabled reports whether a quiet hours window is configured.
+func (q QuietHours) Enabled() bool {
+	return q.Start != "" && q.End != ""
+}
+
+// Validate checks that start and end are both set or both empty and valid.
+func (q QuietHours) Validate() error {
+	if q.Start == "" && q.End == "" {
+		return nil
+	}
+	if q.Start == "" || q.End == "" {
+		return fmt.Errorf("quiet hours need both start and end")
+	}
+	if _, err := parseClock(q.Start); err != nil {
+		return err
+	}
+	_, err := parseClock(q.End)
+	return err
+}
+
+// Active reports whether t falls inside the quiet hours window.
+func (q QuietHours) Active(t time.Time) bool {
+	if !q.Enabled() {
+		return false
+	}
+	start, err1 := parseClock(q.Start)
+	end, err2 := parseClock(q.End)
+	if err1 != nil || err2 != nil || start == end {
+		return false
+	}
+	now := t.Hour()*60 + t.Minute()
+	if start < end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// Validate checks the global and per-channel quiet hours.
+func (c QuietHoursConfig) Validate() error {
+	if err := c.QuietHours.Validate(); err != nil {
+		return fmt.Errorf("quietHours: %w", err)
+	}
+	for name, q := range c.Channels {
+		if err := q.Validate(); err != nil {
+			return fmt.Errorf("quietHours.channels.%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// For returns the quiet hours that apply to a channel.
+func (c QuietHoursConfig) For(channel string) QuietHours {
+	if q, ok := c.Channels[channel]; ok {
+		return q
+	}
+	return c.QuietHours
+}
+
+// AlertChannelStatus describes a channel for the alerts endpoint.
+type AlertChannelStatus struct {
+	Name       string     `json:"name"`
+	QuietHours QuietHours `json:"quietHours"`
+	Quiet      bool       `json:"quiet"`
+	Queued     []Alert    `json:"queued"`
+}
+
+// AlertManager dispatches alerts to the registered channels, queuing them during
+// quiet hours and delivering a digest of the queue once quiet hours end.
+type AlertManager struct {
+	mu       sync.Mutex
+	channels map[string]AlertSender
+	config   QuietHoursConfig
+	queue    map[string][]Alert
+	loaded   bool
+}
+
+// Global alert manager instance
+var alertManager = &AlertManager{
+	channels: map[string]AlertSender{
+		"push": sendPushAlert,
+	},
+	queue: make(map[string][]Alert),
+}
+
+// GetAlertManager returns the global alert manager instance.
+func GetAlertManager() *AlertManager {
+	return alertManager
+}
+
+// Configure sets the quiet hours.
+func (am *AlertManager) Configure(cfg QuietHoursConfig) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.config = cfg
+}
+
+// RegisterChannel adds or replaces an alert channel.
+func (am *AlertManager) RegisterChannel(name string, send AlertSender) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.channels[name] = send
+}
+
+// Start delivers queued alerts as a digest once a channel's quiet hours end.
+func (am *AlertManager) Start() {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		am.flush(time.Now())
+	}
+}
+
+// load reads the queue file. Caller must hold mu.
+func (am *AlertManager) load() {
+	if am.loaded {
+		return
+	}
+	am.loaded = true
+	data, err := os.ReadFile(alertQueueFile)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &am.queue); err != nil {
+		GetDebugLogger().Logf("alerts", "failed to parse %s: %v", alertQueueFile, err)
+	}
+	if am.queue == nil {
+		am.queue = make(map[string][]Alert)
+	}
+}
+
+// save writes the queue file. Caller must hold mu.
+func (am *AlertManager) save() {
+	data, err := json.MarshalIndent(am.queue, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(alertQueueFile, data, 0644); err != nil {
+		GetDebugLogger().Logf("alerts", "failed to write %s: %v", alertQueueFile, err)
+	}
+}
+
+// Dispatch sends an alert on every channel, or queues it for channels in quiet hours.
+func (am *AlertManager) Dispatch(a Alert) {
+	am.DispatchTo(nil, a)
+}
+
+// DispatchTo sends an alert on the named channels (all channels if empty),
+// or queues it for channels in quiet hours.
+func (am *AlertManager) DispatchTo(channels []string, a Alert) {
+	if a.Time.IsZero() {
+		a.Time = time.Now()
+	}
+	if a.Severity == "" {
+		a.Severity = "info"
+	}
+
+	GetStore().RecordNotification(a)
+
+	am.mu.Lock()
+	am.load()
+	send := make(map[string]AlertSender)
+	queued := false
+	for name, sender := range am.channels {
+		if len(channels) > 0 && !slices.Contains(channels, name) {
+			continue
+		}
+		q := am.config.For(name)
+		if q.Active(a.Time) && (a.Severity != "critical" || q.SuppressCritical) {
+			am.queue[name] = append(am.queue[name], a)
+			if len(am.queue[name]) > maxQueuedAlerts {
+				am.queue[name] = am.queue[name][len(am.queue[name])-maxQueuedAlerts:]
+			}
+			queued = true
+			continue
+		}
+		send[name] = sender
+	}
+	if queued {
+		am.save()
+	}
+	am.mu.Unlock()
+
+	for name, sender := range send {
+		go am.deliver(name, sender, a)
+	}
+}
+
+// deliver sends one alert on a channel, logging failures.
+func (am *AlertManager) deliver(name string, send AlertSender, a Alert) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := send(ctx, a); err != nil {
+		GetDebugLogger().Logf("alerts", "%s delivery failed: %v", name, err)
+	}
+}
+
+// flush sends a digest to every channel that has queued alerts and is no longer in quiet hours.
+func (am *AlertManager) flush(now time.Time) {
+	am.mu.Lock()
+	am.load()
+	digests := make(map[string]Alert)
+	senders := make(map[string]AlertSender)
+	for name, alerts := range am.queue {
+		sender, ok := am.channels[name]
+		if len(alerts) == 0 || !ok || am.config.For(name).Active(now) {
+			continue
+		}
+		digests[name] = alertDigest(alerts)
+		senders[name] = sender
+		delete(am.queue, name)
+	}
+	if len(digests) > 0 {
+		am.save()
+	}
+	am.mu.Unlock()
+
+	for name, digest := range digests {
+		go am.deliver(name, senders[name], digest)
+	}
+}
+
+// alertDigest combines queued alerts into a single summary alert.
+func alertDigest(alerts []Alert) Alert {
+	severityRank := map[string]int{"ok": 0, "info": 1, "warning": 2, "critical": 3}
+	digest := Alert{
+		Title:    fmt.Sprintf("%d alert(s) during quiet hours", len(alerts)),
+		Severity: "ok",
+		URL:      "/",
+		Tag:      "quiet-hours-digest",
+		Time:     time.Now(),
+	}
+	lines := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		lines = append(lines, a.Time.Format("15:04")+" "+a.Title)
+		if severityRank[a.Severity] > severityRank[digest.Severity] {
+			digest.Severity = a.Severity
+		}
+	}
+	digest.Body = strings.Join(lines, "\n")
+	return digest
+}
+
+// Status returns the quiet hours state and queue of every channel.
+func (am *AlertManager) Status() []AlertChannelStatus {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.load()
+
+	now := time.Now()
+	result := make([]AlertChannelStatus, 0, len(am.channels))
+	for name := range am.channels {
+		q := am.config.For(name)
+		queued := make([]Alert, len(am.queue[name]))
+		copy(queued, am.queue[name])
+		result = append(result, AlertChannelStatus{
+			Name:       name,
+			QuietHours: q,
+			Quiet:      q.Active(now),
+			Queued:     queued,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// NotifyMonitorChange dispatches an alert for a monitor going down or coming back up.
+func (am *AlertManager) NotifyMonitorChange(key, name string, up bool, errMsg string) {
+	a := Alert{
+		Title:    "Monitor " + name + " is back up",
+		Body:     name + " is responding again",
+		Severity: "ok",
+		URL:      "/",
+		Tag:      "monitor-" + key,
+	}
+	if !up {
+		a.Title = "Monitor " + name + " went down"
+		a.Body = errMsg
+		a.Severity = "critical"
+	}
+	am.Dispatch(a)
+}
@@ -0,0 +1,387 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Settings of the sky overview behind /api/astro.
+const (
+	astroDefaultTLEURL = "https://celestrak.org/NORAD/elements/gp.php?CATNR=25544&FORMAT=tle"
+	astroTLETTL        = 12 * time.Hour
+	astroCacheTTL      = 10 * time.Minute
+	// Sun altitude below which the sky is dark enough for planets and satellite passes
+	astroTwilight = -6.0
+	// Planets lower than this are lost in the haze near the horizon
+	astroPlanetMinAltitude = 5.0
+	maxAstroDays           = 7
+)
+
+// AstroConfig configures the ISS pass predictions of /api/astro. The location is the
+// weather location.
+type AstroConfig struct {
+	TLEURL       string  `json:"tleUrl,omitempty"`       // Two-line elements of the ISS, default CelesTrak
+	MinElevation float64 `json:"minElevation,omitempty"` // Lowest peak elevation of a listed pass in degrees, default 10
+	Days         int     `json:"days,omitempty"`         // Days of passes to predict, default 3
+}
+
+// Validate checks the pass prediction settings.
+func (c AstroConfig) Validate() error {
+	if c.MinElevation < 0 || c.MinElevation >= 90 {
+		return fmt.Errorf("astro: minElevation must be between 0 and 90 degrees")
+	}
+	if c.Days < 0 || c.Days > maxAstroDays {
+		return fmt.Errorf("astro: days must be between 1 and %d", maxAstroDays)
+	}
+	return nil
+}
+
+// AstroPlanet is the position of a naked-eye planet now and its visibility tonight.
+type AstroPlanet struct {
+	Name          string     `json:"name"`
+	Magnitude     float64    `json:"magnitude"` // Apparent brightness; lower is brighter
+	Altitude      float64    `json:"altitude"`  // Degrees above the horizon now
+	Azimuth       float64    `json:"azimuth"`   // Degrees from north through east
+	Direction     string     `json:"direction"` // Compass point of the azimuth, e.g. "SW"
+	Visible       bool       `json:"visible"`   // Above the horizon haze in tonight's dark sky
+	VisibleFrom   *time.Time `json:"visibleFrom,omitempty"`
+	VisibleUntil  *time.Time `json:"visibleUntil,omitempty"`
+	BestTime      *time.Time `json:"bestTime,omitempty"` // Highest point in the dark sky
+	BestAltitude  float64    `json:"bestAltitude,omitempty"`
+	BestDirection string     `json:"bestDirection,omitempty"`
+}
+
+// AstroNight is the dark part of tonight, from the end of dusk to the start of dawn
+// (civil twilight).
+type AstroNight struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// AstroReport is the sky over a location: tonight's dark hours, the planets and the
+// coming ISS passes.
+type AstroReport struct {
+	Latitude    float64       `json:"latitude"`
+	Longitude   float64       `json:"longitude"`
+	SunAltitude float64       `json:"sunAltitude"`     // Degrees, negative after sunset
+	Night       *AstroNight   `json:"night,omitempty"` // Omitted when the sun does not set far enough
+	Planets     []AstroPlanet `json:"planets"`
+	ISS         []ISSPass     `json:"iss"`
+	ISSError    string        `json:"issError,omitempty"`
+	TLEEpoch    time.Time     `json:"tleEpoch,omitzero"` // Age of the orbital elements the passes are computed from
+	Updated     time.Time     `json:"updated"`
+}
+
+// AstroService computes the sky reports and keeps the ISS orbital elements.
+type AstroService struct {
+	mu         sync.Mutex
+	config     AstroConfig
+	tle        *issTLE
+	tleFetched time.Time
+	reports    map[string]AstroReport
+}
+
+// Global astro service instance
+var astroService = &AstroService{reports: make(map[string]AstroReport)}
+
+// GetAstroService returns the global astro service instance.
+func GetAstroService() *AstroService {
+	return astroService
+}
+
+// Configure sets the pass prediction settings.
+func (as *AstroService) Configure(cfg AstroConfig) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.config = cfg
+	as.tle = nil
+	as.reports = make(map[string]AstroReport)
+}
+
+// Report returns the sky over a location, computed at most every ten minutes.
+func (as *AstroService) Report(ctx context.Context, lat, lon float64) AstroReport {
+	key := fmt.Sprintf("%.2f,%.2f", lat, lon)
+	as.mu.Lock()
+	cfg := as.config
+	report, ok := as.reports[key]
+	as.mu.Unlock()
+	if ok && time.Since(report.Updated) < astroCacheTTL {
+		return report
+	}
+
+	now := time.Now()
+	report = AstroReport{Latitude: lat, Longitude: lon, Planets: []AstroPlanet{}, ISS: []ISSPass{}, Updated: now}
+	report.SunAltitude, _ = sunHorizontal(now, lat, lon)
+	report.SunAltitude = math.Round(report.SunAltitude*10) / 10
+	report.Night = astroNightAt(now, lat, lon)
+	for _, p := range astroPlanets {
+		report.Planets = append(report.Planets, planetReport(p, now, report.Night, lat, lon))
+	}
+
+	if cfg.MinElevation == 0 {
+		cfg.MinElevation = 10
+	}
+	if cfg.Days == 0 {
+		cfg.Days = 3
+	}
+	tle, err := as.issElements(ctx, cfg)
+	if err != nil {
+		report.ISSError = err.Error()
+	} else {
+		report.TLEEpoch = tle.epoch
+		if age := now.Sub(tle.epoch); age > 14*24*time.Hour {
+			report.ISSError = fmt.Sprintf("the ISS orbital elements are %d days old", int(age.Hours()/24))
+		} else {
+			report.ISS = issPasses(tle, lat, lon, now, now.Add(time.Duration(cfg.Days)*24*time.Hour), cfg.MinElevation)
+		}
+	}
+
+	as.mu.Lock()
+	for k, r := range as.reports {
+		if time.Since(r.Updated) >= astroCacheTTL {
+			delete(as.reports, k)
+		}
+	}
+	as.reports[key] = report
+	as.mu.Unlock()
+	return report
+}
+
+// Low-precision positions of the sun and planets from the orbital elements of Paul
+// Schlyter's "How to compute planetary positions", good to a fraction of a degree.
+
+// orbitalElements are the elements of an orbit as value and daily change, for the days
+// since 2000 January 0.0 UT.
+type orbitalElements struct {
+	N, i, w, a, e, M [2]float64 // Node, inclination, argument of perihelion (degrees), semi-major axis (AU), eccentricity, mean anomaly (degrees)
+}
+
+// at returns the elements on day d.
+func (el orbitalElements) at(d float64) (N, i, w, a, e, M float64) {
+	v := func(x [2]float64) float64 { return x[0] + x[1]*d }
+	return v(el.N), v(el.i), v(el.w), v(el.a), v(el.e), normDegrees(v(el.M))
+}
+
+var sunElements = orbitalElements{
+	w: [2]float64{282.9404, 4.70935e-5}, a: [2]float64{1, 0},
+	e: [2]float64{0.016709, -1.151e-9}, M: [2]float64{356.0470, 0.9856002585},
+}
+
+// astroPlanet is a naked-eye planet with its orbit and magnitude formula.
+type astroPlanet struct {
+	name      string
+	elements  orbitalElements
+	magnitude func(r, R, phase float64) float64 // Sun and Earth distance (AU), phase angle (degrees)
+}
+
+var astroPlanets = []astroPlanet{
+	{"Mercury", orbitalElements{
+		N: [2]float64{48.3313, 3.24587e-5}, i: [2]float64{7.0047, 5.00e-8}, w: [2]float64{29.1241, 1.01444e-5},
+		a: [2]float64{0.387098, 0}, e: [2]float64{0.205635, 5.59e-10}, M: [2]float64{168.6562, 4.0923344368},
+	}, func(r, R, fv float64) float64 {
+		return -0.36 + 5*math.Log10(r*R) + 0.027*fv + 2.2e-13*math.Pow(fv, 6)
+	}},
+	{"Venus", orbitalElements{
+		N: [2]float64{76.6799, 2.46590e-5}, i: [2]float64{3.3946, 2.75e-8}, w: [2]float64{54.8910, 1.38374e-5},
+		a: [2]float64{0.723330, 0}, e: [2]float64{0.006773, -1.302e-9}, M: [2]float64{48.0052, 1.6021302244},
+	}, func(r, R, fv float64) float64 {
+		return -4.34 + 5*math.Log10(r*R) + 0.013*fv + 4.2e-7*math.Pow(fv, 3)
+	}},
+	{"Mars", orbitalElements{
+		N: [2]float64{49.5574, 2.11081e-5}, i: [2]float64{1.8497, -1.78e-8}, w: [2]float64{286.5016, 2.92961e-5},
+		a: [2]float64{1.523688, 0}, e: [2]float64{0.093405, 2.516e-9}, M: [2]float64{18.6021, 0.5240207766},
+	}, func(r, R, fv float64) float64 {
+		return -1.51 + 5*math.Log10(r*R) + 0.016*fv
+	}},
+	{"Jupiter", orbitalElements{
+		N: [2]float64{100.4542, 2.76854e-5}, i: [2]float64{1.3030, -1.557e-7}, w: [2]float64{273.8777, 1.64505e-5},
+		a: [2]float64{5.20256, 0}, e: [2]float64{0.048498, 4.469e-9}, M: [2]float64{19.8950, 0.0830853001},
+	}, func(r, R, fv float64) float64 {
+		return -9.25 + 5*math.Log10(r*R) + 0.014*fv
+	}},
+	{"Saturn", orbitalElements{
+		N: [2]float64{113.6634, 2.38980e-5}, i: [2]float64{2.4886, -1.081e-7}, w: [2]float64{339.3939, 2.97661e-5},
+		a: [2]float64{9.55475, 0}, e: [2]float64{0.055546, -9.499e-9}, M: [2]float64{316.9670, 0.0334442282},
+	}, func(r, R, fv float64) float64 {
+		// Without the rings, which brighten Saturn by up to a magnitude
+		return -9.0 + 5*math.Log10(r*R) + 0.044*fv
+	}},
+}
+
+// astroDay returns the days since 2000 January 0.0 UT.
+func astroDay(t time.Time) float64 {
+	return float64(t.UnixNano())/86400e9 + 2440587.5 - 2451543.5
+}
+
+// heliocentric returns the ecliptic position of an orbit on day d in AU.
+func heliocentric(el orbitalElements, d float64) (x, y, z float64) {
+	N, i, w, a, e, M := el.at(d)
+	E := keplerSolve(M*math.Pi/180, e)
+	xv := a * (math.Cos(E) - e)
+	yv := a * math.Sqrt(1-e*e) * math.Sin(E)
+	v := math.Atan2(yv, xv)*180/math.Pi + w
+	r := math.Hypot(xv, yv)
+	x = r * (cosd(N)*cosd(v) - sind(N)*sind(v)*cosd(i))
+	y = r * (sind(N)*cosd(v) + cosd(N)*sind(v)*cosd(i))
+	z = r * sind(v) * sind(i)
+	return x, y, z
+}
+
+// keplerSolve solves Kepler's equation for the eccentric anomaly (radians).
+func keplerSolve(M, e float64) float64 {
+	E := M + e*math.Sin(M)*(1+e*math.Cos(M))
+	for range 20 {
+		dE := (E - e*math.Sin(E) - M) / (1 - e*math.Cos(E))
+		E -= dE
+		if math.Abs(dE) < 1e-10 {
+			break
+		}
+	}
+	return E
+}
+
+// sunEcliptic returns the geocentric ecliptic position of the sun on day d in AU.
+func sunEcliptic(d float64) (x, y float64) {
+	// The sun's orbit around the earth is the earth's around the sun, mirrored
+	x, y, _ = heliocentric(sunElements, d)
+	return x, y
+}
+
+// eclipticToEquatorial converts ecliptic coordinates to right ascension and declination
+// (degrees) on day d.
+func eclipticToEquatorial(x, y, z, d float64) (ra, dec float64) {
+	ecl := 23.4393 - 3.563e-7*d
+	ye := y*cosd(ecl) - z*sind(ecl)
+	ze := y*sind(ecl) + z*cosd(ecl)
+	return normDegrees(math.Atan2(ye, x) * 180 / math.Pi), math.Atan2(ze, math.Hypot(x, ye)) * 180 / math.Pi
+}
+
+// sunEquatorial returns the sun's right ascension and declination (degrees) at t.
+func sunEquatorial(t time.Time) (ra, dec float64) {
+	d := astroDay(t)
+	x, y := sunEcliptic(d)
+	return eclipticToEquatorial(x, y, 0, d)
+}
+
+// gmst returns the Greenwich mean sidereal time at t in degrees.
+func gmst(t time.Time) float64 {
+	return normDegrees(280.46061837 + 360.98564736629*(astroDay(t)-1.5))
+}
+
+// horizontal converts right ascension and declination to altitude and azimuth (degrees,
+// azimuth from north through east) for an observer at t.
+func horizontal(ra, dec float64, t time.Time, lat, lon float64) (alt, az float64) {
+	ha := gmst(t) + lon - ra
+	alt = math.Asin(sind(lat)*sind(dec)+cosd(lat)*cosd(dec)*cosd(ha)) * 180 / math.Pi
+	az = normDegrees(math.Atan2(-cosd(dec)*sind(ha), sind(dec)*cosd(lat)-cosd(dec)*cosd(ha)*sind(lat)) * 180 / math.Pi)
+	return alt, az
+}
+
+// sunHorizontal returns the sun's altitude and azimuth for an observer at t.
+func sunHorizontal(t time.Time, lat, lon float64) (alt, az float64) {
+	ra, dec := sunEquatorial(t)
+	return horizontal(ra, dec, t, lat, lon)
+}
+
+// planetPosition returns a planet's right ascension, declination and magnitude at t.
+func planetPosition(p astroPlanet, t time.Time) (ra, dec, mag float64) {
+	d := astroDay(t)
+	xh, yh, zh := heliocentric(p.elements, d)
+	xs, ys := sunEcliptic(d)
+	xg, yg, zg := xh+xs, yh+ys, zh
+	r := math.Sqrt(xh*xh + yh*yh + zh*zh)
+	R := math.Sqrt(xg*xg + yg*yg + zg*zg)
+	s := math.Hypot(xs, ys)
+	phase := math.Acos(math.Max(-1, math.Min(1, (r*r+R*R-s*s)/(2*r*R)))) * 180 / math.Pi
+	ra, dec = eclipticToEquatorial(xg, yg, zg, d)
+	return ra, dec, p.magnitude(r, R, phase)
+}
+
+// astroNightAt finds the dark hours of the night in progress at t or, during the day, of
+// the coming night. It returns nil when the sun stays too high for a day.
+func astroNightAt(t time.Time, lat, lon float64) *AstroNight {
+	const step = 5 * time.Minute
+	dark := func(t time.Time) bool {
+		alt, _ := sunHorizontal(t, lat, lon)
+		return alt < astroTwilight
+	}
+	start := t
+	if dark(t) {
+		for i := 0; i < 288 && dark(start.Add(-step)); i++ {
+			start = start.Add(-step)
+		}
+	} else {
+		for i := 0; !dark(start); i++ {
+			if i == 288 {
+				return nil
+			}
+			start = start.Add(step)
+		}
+	}
+	end := start
+	for i := 0; i < 288 && dark(end.Add(step)); i++ {
+		end = end.Add(step)
+	}
+	return &AstroNight{Start: start.Truncate(time.Minute), End: end.Add(step).Truncate(time.Minute)}
+}
+
+// planetReport places a planet now and finds when it is above the horizon haze in the
+// rest of tonight's dark hours.
+func planetReport(p astroPlanet, now time.Time, night *AstroNight, lat, lon float64) AstroPlanet {
+	ra, dec, mag := planetPosition(p, now)
+	alt, az := horizontal(ra, dec, now, lat, lon)
+	result := AstroPlanet{
+		Name:      p.name,
+		Magnitude: math.Round(mag*10) / 10,
+		Altitude:  math.Round(alt*10) / 10,
+		Azimuth:   math.Round(az),
+		Direction: compassPoint(az),
+	}
+	if night == nil {
+		return result
+	}
+	from := night.Start
+	if now.After(from) {
+		from = now
+	}
+	for t := from; !t.After(night.End); t = t.Add(10 * time.Minute) {
+		ra, dec, _ := planetPosition(p, t)
+		alt, az := horizontal(ra, dec, t, lat, lon)
+		if alt < astroPlanetMinAltitude {
+			continue
+		}
+		at := t
+		if !result.Visible {
+			result.Visible = true
+			result.VisibleFrom = &at
+		}
+		result.VisibleUntil = &at
+		if alt > result.BestAltitude {
+			result.BestAltitude = math.Round(alt*10) / 10
+			result.BestTime = &at
+			result.BestDirection = compassPoint(az)
+		}
+	}
+	return result
+}
+
+// compassPoint names the 16-point compass direction of an azimuth, e.g. "NNE".
+func compassPoint(az float64) string {
+	points := []string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
+	return points[int(math.Round(normDegrees(az)/22.5))%16]
+}
+
+// normDegrees reduces an angle to [0, 360).
+func normDegrees(x float64) float64 {
+	x = math.Mod(x, 360)
+	if x < 0 {
+		x += 360
+	}
+	return x
+}
+
+func sind(x float64) float64 { return math.Sin(x * math.Pi / 180) }
+func cosd(x float64) float64 { return math.Cos(x * math.Pi / 180) }
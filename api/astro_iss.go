@@ -0,0 +1,320 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Earth constants of the WGS 84 ellipsoid and the J2 oblateness term.
+const (
+	earthRadiusKm = 6378.137
+	earthMu       = 398600.4418 // km³/s²
+	earthJ2       = 1.08262668e-3
+	earthFlat     = 1 / 298.257223563
+)
+
+// ISSPass is a pass of the International Space Station over the observer.
+type ISSPass struct {
+	Start          time.Time `json:"start"` // Rises above the horizon
+	Peak           time.Time `json:"peak"`
+	End            time.Time `json:"end"`          // Sets below the horizon
+	Duration       int       `json:"duration"`     // Seconds above the horizon
+	MaxElevation   float64   `json:"maxElevation"` // Degrees
+	StartDirection string    `json:"startDirection"`
+	PeakDirection  string    `json:"peakDirection"`
+	EndDirection   string    `json:"endDirection"`
+	// Sunlit while the observer's sky is dark, so the station can be seen with the naked eye
+	Visible bool `json:"visible"`
+}
+
+// issTLE are the mean orbital elements of a two-line element set.
+type issTLE struct {
+	epoch         time.Time
+	inclination   float64 // Degrees
+	raan          float64 // Right ascension of the ascending node, degrees
+	eccentricity  float64
+	argPerigee    float64 // Degrees
+	meanAnomaly   float64 // Degrees
+	meanMotion    float64 // Revolutions per day
+	meanMotionDot float64 // Half the first derivative of the mean motion, revolutions per day²
+}
+
+// issElements returns the ISS orbital elements, fetched at most every twelve hours. A
+// failed fetch keeps the elements fetched before.
+func (as *AstroService) issElements(ctx context.Context, cfg AstroConfig) (*issTLE, error) {
+	as.mu.Lock()
+	tle, fetched := as.tle, as.tleFetched
+	as.mu.Unlock()
+	if tle != nil && time.Since(fetched) < astroTLETTL {
+		return tle, nil
+	}
+
+	source := cfg.TLEURL
+	if source == "" {
+		source = astroDefaultTLEURL
+	}
+	fresh, err := fetchTLE(ctx, source)
+	if err != nil {
+		GetDebugLogger().Logf("astro", "fetching ISS elements failed: %v", err)
+		if tle != nil {
+			return tle, nil
+		}
+		return nil, errors.New("ISS orbital elements unavailable: " + err.Error())
+	}
+	as.mu.Lock()
+	as.tle, as.tleFetched = fresh, time.Now()
+	as.mu.Unlock()
+	return fresh, nil
+}
+
+// fetchTLE downloads and parses a two-line element set.
+func fetchTLE(ctx context.Context, source string) (*issTLE, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, errors.New("TLE http status " + res.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+	return parseTLE(string(data))
+}
+
+// parseTLE reads the first two-line element set of a text, with or without a name line.
+func parseTLE(text string) (*issTLE, error) {
+	var line1 string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if strings.HasPrefix(line, "1 ") && len(line) >= 64 {
+			line1 = line
+			continue
+		}
+		if line1 != "" && strings.HasPrefix(line, "2 ") && len(line) >= 63 {
+			return parseTLELines(line1, line)
+		}
+		line1 = ""
+	}
+	return nil, errors.New("no two-line element set found")
+}
+
+// parseTLELines decodes the fixed columns of a two-line element set.
+func parseTLELines(line1, line2 string) (*issTLE, error) {
+	var err error
+	field := func(line string, from, to int) float64 {
+		v, e := strconv.ParseFloat(strings.TrimSpace(line[from-1:to]), 64)
+		if e != nil && err == nil {
+			err = errors.New("invalid two-line element set")
+		}
+		return v
+	}
+	year := int(field(line1, 19, 20))
+	if year < 57 {
+		year += 2000
+	} else {
+		year += 1900
+	}
+	day := field(line1, 21, 32)
+	tle := &issTLE{
+		epoch:         time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration((day - 1) * 24 * float64(time.Hour))),
+		meanMotionDot: field(line1, 34, 43),
+		inclination:   field(line2, 9, 16),
+		raan:          field(line2, 18, 25),
+		eccentricity:  field(line2, 27, 33) / 1e7,
+		argPerigee:    field(line2, 35, 42),
+		meanAnomaly:   field(line2, 44, 51),
+		meanMotion:    field(line2, 53, 63),
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tle.meanMotion <= 0 {
+		return nil, errors.New("invalid mean motion in two-line element set")
+	}
+	return tle, nil
+}
+
+// position returns the satellite's position at t in an earth-centred inertial frame (km).
+// The orbit is propagated as an ellipse whose node and perigee drift with the earth's
+// oblateness and whose period shrinks with drag. Short-period terms are left out, so
+// passes are off by up to a minute a few days after the element set's epoch.
+func (tle *issTLE) position(t time.Time) [3]float64 {
+	days := t.Sub(tle.epoch).Hours() / 24
+	n := tle.meanMotion + 2*tle.meanMotionDot*days // Revolutions per day
+	nRad := n * 2 * math.Pi / 86400
+	a := math.Cbrt(earthMu / (nRad * nRad))
+	e := tle.eccentricity
+	p := a * (1 - e*e)
+	drift := 1.5 * earthJ2 * (earthRadiusKm / p) * (earthRadiusKm / p) * tle.meanMotion * 360 // Degrees per day
+	raan := tle.raan - drift*cosd(tle.inclination)*days
+	argp := tle.argPerigee + drift*(2-2.5*sind(tle.inclination)*sind(tle.inclination))*days
+	M := normDegrees(tle.meanAnomaly + 360*(tle.meanMotion*days+tle.meanMotionDot*days*days))
+
+	E := keplerSolve(M*math.Pi/180, e)
+	xp := a * (math.Cos(E) - e)
+	yp := a * math.Sqrt(1-e*e) * math.Sin(E)
+	cO, sO := cosd(raan), sind(raan)
+	cw, sw := cosd(argp), sind(argp)
+	ci, si := cosd(tle.inclination), sind(tle.inclination)
+	return [3]float64{
+		(cO*cw-sO*sw*ci)*xp + (-cO*sw-sO*cw*ci)*yp,
+		(sO*cw+cO*sw*ci)*xp + (-sO*sw+cO*cw*ci)*yp,
+		sw*si*xp + cw*si*yp,
+	}
+}
+
+// issObserver looks at the satellite from a place on the ground.
+type issObserver struct {
+	tle      *issTLE
+	lat, lon float64
+	ecef     [3]float64
+}
+
+func newISSObserver(tle *issTLE, lat, lon float64) *issObserver {
+	e2 := earthFlat * (2 - earthFlat)
+	n := earthRadiusKm / math.Sqrt(1-e2*sind(lat)*sind(lat))
+	return &issObserver{tle: tle, lat: lat, lon: lon, ecef: [3]float64{
+		n * cosd(lat) * cosd(lon),
+		n * cosd(lat) * sind(lon),
+		n * (1 - e2) * sind(lat),
+	}}
+}
+
+// look returns the satellite's elevation and azimuth (degrees) at t.
+func (o *issObserver) look(t time.Time) (el, az float64) {
+	sat := o.tle.position(t)
+	theta := gmst(t)
+	x := cosd(theta)*sat[0] + sind(theta)*sat[1] - o.ecef[0]
+	y := -sind(theta)*sat[0] + cosd(theta)*sat[1] - o.ecef[1]
+	z := sat[2] - o.ecef[2]
+	south := sind(o.lat)*cosd(o.lon)*x + sind(o.lat)*sind(o.lon)*y - cosd(o.lat)*z
+	east := -sind(o.lon)*x + cosd(o.lon)*y
+	up := cosd(o.lat)*cosd(o.lon)*x + cosd(o.lat)*sind(o.lon)*y + sind(o.lat)*z
+	el = math.Asin(up/math.Sqrt(x*x+y*y+z*z)) * 180 / math.Pi
+	az = normDegrees(math.Atan2(east, -south) * 180 / math.Pi)
+	return el, az
+}
+
+// visible reports whether the satellite is sunlit at t while the observer's sky is dark.
+func (o *issObserver) visible(t time.Time) bool {
+	if alt, _ := sunHorizontal(t, o.lat, o.lon); alt >= astroTwilight {
+		return false
+	}
+	ra, dec := sunEquatorial(t)
+	sun := [3]float64{cosd(dec) * cosd(ra), cosd(dec) * sind(ra), sind(dec)}
+	sat := o.tle.position(t)
+	along := sat[0]*sun[0] + sat[1]*sun[1] + sat[2]*sun[2]
+	if along >= 0 {
+		return true
+	}
+	// Behind the earth: in its (cylindrical) shadow when closer to the axis than its radius
+	var off float64
+	for i := range sat {
+		d := sat[i] - along*sun[i]
+		off += d * d
+	}
+	return math.Sqrt(off) > earthRadiusKm
+}
+
+// issPasses predicts the passes between from and until that peak at least minElevation
+// degrees high. A pass in progress at from is included.
+func issPasses(tle *issTLE, lat, lon float64, from, until time.Time, minElevation float64) []ISSPass {
+	const step = 30 * time.Second
+	o := newISSObserver(tle, lat, lon)
+	elevation := func(t time.Time) float64 {
+		el, _ := o.look(t)
+		return el
+	}
+	// crossing finds when the elevation passes zero between a and b, to within a second
+	crossing := func(a, b time.Time) time.Time {
+		rising := elevation(a) < elevation(b)
+		for b.Sub(a) > time.Second {
+			mid := a.Add(b.Sub(a) / 2)
+			if (elevation(mid) > 0) == rising {
+				b = mid
+			} else {
+				a = mid
+			}
+		}
+		return b.Truncate(time.Second)
+	}
+
+	passes := []ISSPass{}
+	var pass *ISSPass
+	prev := elevation(from)
+	if prev > 0 {
+		pass = &ISSPass{Start: from.Truncate(time.Second)}
+	}
+	for t := from; t.Before(until) || pass != nil; t = t.Add(step) {
+		next := t.Add(step)
+		el := elevation(next)
+		switch {
+		case pass == nil && el > 0 && prev <= 0:
+			pass = &ISSPass{Start: crossing(t, next)}
+		case pass != nil && el <= 0:
+			pass.End = crossing(t, next)
+			if pass.Peak.IsZero() {
+				pass.Peak = pass.Start
+			}
+			if pass.MaxElevation >= minElevation {
+				o.finish(pass)
+				passes = append(passes, *pass)
+			}
+			pass = nil
+		}
+		if pass != nil {
+			if el > pass.MaxElevation {
+				pass.MaxElevation, pass.Peak = el, next
+			}
+			if !pass.Visible && el >= minElevation && o.visible(next) {
+				pass.Visible = true
+			}
+		}
+		prev = el
+	}
+	return passes
+}
+
+// finish refines the peak of a pass and fills in its directions and duration.
+func (o *issObserver) finish(pass *ISSPass) {
+	// The sampled peak is within one step of the true one; narrow it down by ternary search
+	a, b := pass.Peak.Add(-30*time.Second), pass.Peak.Add(30*time.Second)
+	for b.Sub(a) > time.Second {
+		m1 := a.Add(b.Sub(a) / 3)
+		m2 := b.Add(-b.Sub(a) / 3)
+		e1, _ := o.look(m1)
+		e2, _ := o.look(m2)
+		if e1 < e2 {
+			a = m1
+		} else {
+			b = m2
+		}
+	}
+	pass.Peak = a.Truncate(time.Second)
+	el, az := o.look(pass.Peak)
+	pass.MaxElevation = math.Round(el*10) / 10
+	pass.PeakDirection = compassPoint(az)
+	_, az = o.look(pass.Start)
+	pass.StartDirection = compassPoint(az)
+	_, az = o.look(pass.End)
+	pass.EndDirection = compassPoint(az)
+	pass.Duration = int(pass.End.Sub(pass.Start).Seconds())
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// auditFile holds the audit log across restarts.
+const auditFile = "audit.json"
+
+// maxAuditEntries caps the audit log between retention runs.
+const maxAuditEntries = 5000
+
+// AuditEntry records a security-relevant action: sign-ins, tokens, sessions, webhooks,
+// stored configs, metric history imports and manual pruning.
+type AuditEntry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"` // e.g. "token.create", "auth.login"
+	Actor  string    `json:"actor"`  // Token or user name, "anonymous" without one
+	Client string    `json:"client"` // Client IP
+	Detail string    `json:"detail,omitempty"`
+}
+
+// AuditLog keeps the audit entries, oldest first.
+type AuditLog struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+	loaded  bool
+}
+
+var auditLog = &AuditLog{}
+
+// GetAuditLog returns the global audit log.
+func GetAuditLog() *AuditLog {
+	return auditLog
+}
+
+// load reads the audit file. Caller must hold mu.
+func (al *AuditLog) load() {
+	if al.loaded {
+		return
+	}
+	al.loaded = true
+	data, err := os.ReadFile(auditFile)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &al.entries); err != nil {
+		GetDebugLogger().Logf("audit", "failed to parse %s: %v", auditFile, err)
+		al.entries = nil
+	}
+}
+
+// save writes the audit file. Caller must hold mu.
+func (al *AuditLog) save() {
+	data, err := json.MarshalIndent(al.entries, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(auditFile, data, 0600); err != nil {
+		GetDebugLogger().Logf("audit", "failed to write %s: %v", auditFile, err)
+	}
+}
+
+// Record appends an entry.
+func (al *AuditLog) Record(e AuditEntry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	al.load()
+	al.entries = append(al.entries, e)
+	if len(al.entries) > maxAuditEntries {
+		al.entries = al.entries[len(al.entries)-maxAuditEntries:]
+	}
+	al.save()
+}
+
+// Entries returns up to limit entries newest first, optionally only of one action.
+func (al *AuditLog) Entries(limit int, action string) []AuditEntry {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	al.load()
+	result := []AuditEntry{}
+	for i := len(al.entries) - 1; i >= 0; i-- {
+		if action != "" && al.entries[i].Action != action {
+			continue
+		}
+		result = append(result, al.entries[i])
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result
+}
+
+// Len returns the number of entries.
+func (al *AuditLog) Len() int {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	al.load()
+	return len(al.entries)
+}
+
+// Prune removes entries older than before and returns how many were removed.
+func (al *AuditLog) Prune(before time.Time) int {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	al.load()
+	kept := al.entries[:0]
+	for _, e := range al.entries {
+		if !e.Time.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	removed := len(al.entries) - len(kept)
+	al.entries = kept
+	if removed > 0 {
+		al.save()
+	}
+	return removed
+}
+
+// Audit records an action by the caller of a request.
+func Audit(r *http.Request, action, detail string) {
+	id := RequestIdentity(r)
+	actor := id.Name
+	if actor == "" {
+		actor = id.TokenID
+	}
+	if actor == "" {
+		actor = "anonymous"
+	}
+	GetAuditLog().Record(AuditEntry{Action: action, Actor: actor, Client: GetClientIP(r), Detail: detail})
+}
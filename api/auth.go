@@ -0,0 +1,450 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokensFile holds the API tokens across restarts. Only token hashes are stored.
+const tokensFile = "tokens.json"
+
+// authCookieName is the cookie set by /api/auth/login for browsers.
+const authCookieName = "homepage_token"
+
+// tokenPrefix marks API tokens so they are easy to recognise in configs and logs.
+const tokenPrefix = "hp_"
+
+// Role is the access level of an API token.
+type Role string
+
+// Token roles, from least to most privileged.
+const (
+	RoleViewer   Role = "viewer"   // Read the dashboard
+	RoleOperator Role = "operator" // Also run actions (rotate guest Wi-Fi, send tests, dismiss banners)
+	RoleEditor   Role = "editor"   // Also change settings and storage
+	RoleAdmin    Role = "admin"    // Also manage tokens and webhooks
+)
+
+var roleRank = map[Role]int{RoleViewer: 1, RoleOperator: 2, RoleEditor: 3, RoleAdmin: 4}
+
+// ParseRole validates a role name.
+func ParseRole(s string) (Role, error) {
+	r := Role(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := roleRank[r]; !ok {
+		return "", fmt.Errorf("unknown role %q (use viewer, operator, editor or admin)", s)
+	}
+	return r, nil
+}
+
+// Allows reports whether the role grants at least the required role.
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
+
+// AuthConfig configures API authentication.
+type AuthConfig struct {
+	// RequireToken also requires a viewer token for reading the API and the WebSocket
+	// once tokens exist (by default anonymous clients can read)
+	RequireToken bool `json:"requireToken,omitempty"`
+	// ProfileRoles limits the role of requests for a dashboard profile, e.g. a wall display
+	// profile that never offers actions. Clients choose their profile, so this is not a
+	// substitute for tokens.
+	ProfileRoles map[string]Role `json:"profileRoles,omitempty"`
+	// OIDC lists OpenID Connect providers (Authelia, Keycloak, Google, ...) users can sign
+	// in with instead of pasting a token
+	OIDC []OIDCConfig `json:"oidc,omitempty"`
+}
+
+// Validate checks the profile role limits and single sign-on providers.
+func (c AuthConfig) Validate() error {
+	if err := validateProfileRoles(c.ProfileRoles); err != nil {
+		return err
+	}
+	ids := make(map[string]bool)
+	for i, p := range c.OIDC {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("auth.oidc[%d]: %w", i, err)
+		}
+		if ids[p.ID] {
+			return fmt.Errorf("auth.oidc[%d]: duplicate id %q", i, p.ID)
+		}
+		ids[p.ID] = true
+	}
+	return nil
+}
+
+// APIToken is a bearer token with a role. The token itself is only returned on creation.
+type APIToken struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Role      Role       `json:"role"`
+	Profile   string     `json:"profile,omitempty"` // Dashboard profile used when the request selects none
+	Hash      string     `json:"hash,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	LastUsed  *time.Time `json:"lastUsed,omitempty"`
+}
+
+// Identity is the caller of a request as resolved by the auth middleware.
+type Identity struct {
+	Role      Role   `json:"role,omitempty"`
+	TokenID   string `json:"tokenId,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Profile   string `json:"profile,omitempty"`
+	Anonymous bool   `json:"anonymous"`
+	// SessionID is set for signed-in browsers, Provider for single sign-on
+	SessionID string `json:"sessionId,omitempty"`
+	Provider  string `json:"provider,omitempty"`
+	// Limited is set when the role was lowered by the listener's or the profile's role limit
+	Limited bool `json:"limited,omitempty"`
+}
+
+// identityKey is the context key of the request identity.
+type identityKey struct{}
+
+// ErrInvalidToken is returned when a request carries a token that does not exist.
+var ErrInvalidToken = errors.New("invalid API token")
+
+// TokenManager manages API tokens and single sign-on sessions.
+type TokenManager struct {
+	mu       sync.Mutex
+	config   AuthConfig
+	tokens   map[string]*APIToken     // By hash
+	sessions map[string]*LoginSession // By hash
+	loaded   bool
+}
+
+// Global token manager instance
+var tokenManager = &TokenManager{
+	tokens:   make(map[string]*APIToken),
+	sessions: make(map[string]*LoginSession),
+}
+
+// GetTokenManager returns the global token manager instance.
+func GetTokenManager() *TokenManager {
+	return tokenManager
+}
+
+// Configure sets the authentication options.
+func (tm *TokenManager) Configure(cfg AuthConfig) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.config = cfg
+}
+
+// load reads the tokens and sessions files. Caller must hold mu.
+func (tm *TokenManager) load() {
+	if tm.loaded {
+		return
+	}
+	tm.loaded = true
+	tm.loadSessions()
+	data, err := os.ReadFile(tokensFile)
+	if err != nil {
+		return
+	}
+	var tokens []*APIToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		GetDebugLogger().Logf("auth", "failed to parse %s: %v", tokensFile, err)
+		return
+	}
+	for _, t := range tokens {
+		tm.tokens[t.Hash] = t
+	}
+}
+
+// save writes the tokens file. Caller must hold mu.
+func (tm *TokenManager) save() error {
+	data, err := json.MarshalIndent(tm.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(tokensFile, data, 0600)
+}
+
+// list returns tokens sorted by creation time. Caller must hold mu.
+func (tm *TokenManager) list() []APIToken {
+	result := make([]APIToken, 0, len(tm.tokens))
+	for _, t := range tm.tokens {
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// hashToken returns the stored hash of a token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Enabled reports whether any tokens exist or single sign-on is configured. Without
+// either the API is open.
+func (tm *TokenManager) Enabled() bool {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.load()
+	return len(tm.tokens) > 0 || len(tm.config.OIDC) > 0
+}
+
+// Tokens returns all tokens without their hashes.
+func (tm *TokenManager) Tokens() []APIToken {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.load()
+	tokens := tm.list()
+	for i := range tokens {
+		tokens[i].Hash = ""
+	}
+	return tokens
+}
+
+// CreateToken creates a token and returns it together with its secret.
+// The first token must be an admin token so that tokens can still be managed afterwards.
+func (tm *TokenManager) CreateToken(name string, role Role, profile string) (APIToken, string, error) {
+	if profile != "" && !ValidProfileName(profile) {
+		return APIToken{}, "", fmt.Errorf("invalid profile name %q", profile)
+	}
+
+	secret := make([]byte, 24)
+	id := make([]byte, 6)
+	if _, err := rand.Read(secret); err != nil {
+		return APIToken{}, "", err
+	}
+	if _, err := rand.Read(id); err != nil {
+		return APIToken{}, "", err
+	}
+	token := tokenPrefix + hex.EncodeToString(secret)
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.load()
+	if len(tm.tokens) == 0 && role != RoleAdmin {
+		return APIToken{}, "", fmt.Errorf("the first token must have the admin role")
+	}
+	t := &APIToken{
+		ID:        hex.EncodeToString(id),
+		Name:      name,
+		Role:      role,
+		Profile:   profile,
+		Hash:      hashToken(token),
+		CreatedAt: time.Now(),
+	}
+	tm.tokens[t.Hash] = t
+	if err := tm.save(); err != nil {
+		return APIToken{}, "", err
+	}
+	result := *t
+	result.Hash = ""
+	return result, token, nil
+}
+
+// DeleteToken removes a token by ID. The last admin token can only be removed
+// together with all other tokens, which disables authentication.
+func (tm *TokenManager) DeleteToken(id string) (bool, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.load()
+
+	var target *APIToken
+	admins := 0
+	for _, t := range tm.tokens {
+		if t.ID == id {
+			target = t
+		}
+		if t.Role == RoleAdmin {
+			admins++
+		}
+	}
+	if target == nil {
+		return false, nil
+	}
+	if target.Role == RoleAdmin && admins == 1 && len(tm.tokens) > 1 {
+		return false, fmt.Errorf("cannot delete the last admin token while other tokens exist")
+	}
+	delete(tm.tokens, target.Hash)
+	tm.removeTokenSessions(target.ID)
+	return true, tm.save()
+}
+
+// Lookup returns the token matching a secret and records its use.
+func (tm *TokenManager) Lookup(token string) (APIToken, bool) {
+	hash := hashToken(token)
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.load()
+	t, ok := tm.tokens[hash]
+	if !ok {
+		return APIToken{}, false
+	}
+	// Persist the last use at most hourly to avoid rewriting the file on every request
+	now := time.Now()
+	persist := t.LastUsed == nil || now.Sub(*t.LastUsed) > time.Hour
+	t.LastUsed = &now
+	if persist {
+		if err := tm.save(); err != nil {
+			GetDebugLogger().Logf("auth", "failed to write %s: %v", tokensFile, err)
+		}
+	}
+	return *t, true
+}
+
+// requestToken returns the token from the Authorization header or the login cookie.
+func requestToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	if c, err := r.Cookie(authCookieName); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// Authenticate resolves the identity of a request from an API token or a sign-on session.
+// Without tokens or single sign-on every caller is an anonymous admin; otherwise anonymous
+// callers are viewers unless RequireToken is set.
+func (tm *TokenManager) Authenticate(r *http.Request) (Identity, error) {
+	if !tm.Enabled() {
+		return Identity{Role: RoleAdmin, Anonymous: true}, nil
+	}
+	token := requestToken(r)
+	if strings.HasPrefix(token, sessionPrefix) {
+		// Expired or revoked sessions sign the browser out rather than failing requests
+		if s, ok := tm.lookupSession(token, DeviceOf(r)); ok {
+			return Identity{Role: s.Role, TokenID: s.TokenID, Name: s.Name, Profile: s.Profile, SessionID: s.ID, Provider: s.Provider}, nil
+		}
+		token = ""
+	}
+	if token == "" {
+		tm.mu.Lock()
+		requireToken := tm.config.RequireToken
+		tm.mu.Unlock()
+		if requireToken {
+			return Identity{Anonymous: true}, nil
+		}
+		return Identity{Role: RoleViewer, Anonymous: true}, nil
+	}
+	t, ok := tm.Lookup(token)
+	if !ok {
+		return Identity{Anonymous: true}, ErrInvalidToken
+	}
+	return Identity{Role: t.Role, TokenID: t.ID, Name: t.Name, Profile: t.Profile}, nil
+}
+
+// RequestIdentity returns the identity resolved by WithAuth, authenticating the request
+// if the middleware did not run.
+func RequestIdentity(r *http.Request) Identity {
+	if id, ok := r.Context().Value(identityKey{}).(Identity); ok {
+		return id
+	}
+	id, _ := GetTokenManager().Authenticate(r)
+	return id
+}
+
+// authExempt reports whether a path is reachable without a viewer token.
+func authExempt(path string) bool {
+	if path == "/ws" {
+		return false
+	}
+	if !strings.HasPrefix(path, "/api/") {
+		return true
+	}
+	return strings.HasPrefix(path, "/api/auth") || strings.HasPrefix(path, "/api/webhooks/in/") || path == "/api/presence/owntracks"
+}
+
+// WithAuth wraps an HTTP handler with token authentication. It rejects invalid tokens,
+// enforces RequireToken for the API and stores the identity in the request context.
+func WithAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := GetTokenManager().Authenticate(r)
+		if err != nil && !authExempt(r.URL.Path) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if id.Role == "" && !authExempt(r.URL.Path) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="homepage"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		id = GetTokenManager().limitRole(r, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, id)))
+	})
+}
+
+// limitRole lowers the role of an identity to the limit of the listener the request came in
+// on and to that of the profile the request is for: the ?profile= parameter, the
+// /p/{profile} page or the token's profile.
+func (tm *TokenManager) limitRole(r *http.Request, id Identity) Identity {
+	if id.Role == "" {
+		return id
+	}
+	if limit, ok := r.Context().Value(listenerRoleKey{}).(Role); ok && roleRank[id.Role] > roleRank[limit] {
+		id.Role = limit
+		id.Limited = true
+	}
+	tm.mu.Lock()
+	roles := tm.config.ProfileRoles
+	tm.mu.Unlock()
+	if len(roles) == 0 {
+		return id
+	}
+	profile := strings.ToLower(r.URL.Query().Get("profile"))
+	if profile == "" {
+		profile, _ = ProfileFromPath(r.URL.Path)
+	}
+	if profile == "" {
+		profile = id.Profile
+	}
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	if limit, ok := roles[profile]; ok && roleRank[id.Role] > roleRank[limit] {
+		id.Role = limit
+		id.Limited = true
+	}
+	return id
+}
+
+// RequireRole wraps a handler so that every request needs at least the given role.
+func RequireRole(role Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := RequestIdentity(r)
+		if !id.Role.Allows(role) {
+			if id.Anonymous {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="homepage"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			} else {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+			}
+			return
+		}
+		next(w, r)
+	}
+}
+
+// RequireWriteRole is like RequireRole but lets GET and HEAD requests through.
+func RequireWriteRole(role Role, next http.HandlerFunc) http.HandlerFunc {
+	guarded := RequireRole(role, next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next(w, r)
+			return
+		}
+		guarded(w, r)
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import "fmt"
+
+// DataFiles returns the files the dashboard keeps its state in, relative to its working
+// directory. Caches that are fetched again when missing (favicons, icons, certificates)
+// are left out.
+func DataFiles() []string {
+	return []string{
+		tokensFile,
+		sessionsFile,
+		auditFile,
+		alertQueueFile,
+		bookmarksFile,
+		layoutHistoryFile,
+		exposureFile,
+		guestWiFiFile,
+		metricsHistoryFile,
+		notesFile,
+		publicIPHistoryFile,
+		pushStateFile,
+		searchEnginesFile,
+		searchHistoryFile,
+		snmpProfilesFile,
+		timelineFile,
+		bootHistoryFile,
+		usageFile,
+		webhooksFile,
+	}
+}
+
+// BackupTo writes a consistent copy of the database to path, which must not exist. It is
+// safe while the dashboard is running.
+func (s *SQLiteStore) BackupTo(path string) error {
+	if s == nil {
+		return fmt.Errorf("no sqlite store is configured")
+	}
+	if _, err := s.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// Path returns the database file, "" when the JSON files are used.
+func (s *SQLiteStore) Path() string {
+	if s == nil {
+		return ""
+	}
+	return s.path
+}
@@ -0,0 +1,386 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Birthdays of the address books.
+const (
+	EventTypeBirthday = "birthday"
+	// Address books are read again after this long
+	birthdaysTTL = 12 * time.Hour
+	// A failed fetch is not repeated sooner
+	birthdaysRetry = time.Hour
+)
+
+// CardDAVSource is an address book whose birthdays are added to the calendar, such as one of
+// Nextcloud, Radicale or iCloud (with an app-specific password).
+type CardDAVSource struct {
+	Name         string `json:"name,omitempty"`
+	URL          string `json:"url"` // Address book collection, e.g. https://cloud.lan/remote.php/dav/addressbooks/users/me/contacts/
+	Username     string `json:"username,omitempty"`
+	Password     string `json:"password,omitempty"`
+	PasswordFile string `json:"passwordFile,omitempty"`
+	PasswordEnv  string `json:"passwordEnv,omitempty"`
+	Insecure     bool   `json:"insecure,omitempty"` // Skip TLS verification for self-signed certificates
+}
+
+// BirthdaysConfig lists the CardDAV address books read for birthdays. Uploaded vCard files
+// need no config.
+type BirthdaysConfig struct {
+	CardDAV []CardDAVSource `json:"carddav"`
+}
+
+// Validate checks the address book URLs and credentials.
+func (c BirthdaysConfig) Validate() error {
+	for i, src := range c.CardDAV {
+		if u, err := url.Parse(src.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("birthdays: carddav[%d]: url must be an http(s) URL", i)
+		}
+		if _, err := ResolveSecret(src.Password, src.PasswordFile, src.PasswordEnv); err != nil {
+			return fmt.Errorf("birthdays: carddav[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Birthday is the birthday of a contact.
+type Birthday struct {
+	Name   string `json:"name"`
+	Month  int    `json:"month"`
+	Day    int    `json:"day"`
+	Year   int    `json:"year,omitempty"` // 0 when the year of birth is unknown
+	Source string `json:"source"`         // Address book or uploaded file
+}
+
+// Next returns the date of the birthday on or after a day. Those of 29 February fall on
+// 28 February in other years.
+func (b Birthday) Next(day time.Time) time.Time {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	for year := day.Year(); ; year++ {
+		if date := b.In(year); !date.Before(day) {
+			return date
+		}
+	}
+}
+
+// In returns the date of the birthday in a year.
+func (b Birthday) In(year int) time.Time {
+	date := time.Date(year, time.Month(b.Month), b.Day, 0, 0, 0, 0, time.UTC)
+	if date.Month() != time.Month(b.Month) {
+		date = time.Date(year, time.Month(b.Month)+1, 0, 0, 0, 0, 0, time.UTC)
+	}
+	return date
+}
+
+// AgeIn returns the age reached on the birthday of a year, 0 when unknown.
+func (b Birthday) AgeIn(year int) int {
+	if b.Year == 0 || year <= b.Year {
+		return 0
+	}
+	return year - b.Year
+}
+
+// parseVCardDate reads a BDAY value: 1985-04-12, 19850412, --04-12 or --0412 without a
+// year, optionally followed by a time. Apple marks unknown years with X-APPLE-OMIT-YEAR.
+func parseVCardDate(value string, omitYear int) (year, month, day int, ok bool) {
+	value, _, _ = strings.Cut(value, "T")
+	value = strings.ReplaceAll(value, "-", "")
+	switch len(value) {
+	case 8:
+		year, _ = strconv.Atoi(value[:4])
+		value = value[4:]
+	case 4:
+	default:
+		return 0, 0, 0, false
+	}
+	month, errMonth := strconv.Atoi(value[:2])
+	day, errDay := strconv.Atoi(value[2:])
+	if errMonth != nil || errDay != nil || month < 1 || month > 12 || day < 1 || day > 31 {
+		return 0, 0, 0, false
+	}
+	if year == omitYear {
+		year = 0
+	}
+	return year, month, day, true
+}
+
+// ParseVCardBirthdays returns the birthdays of the contacts in vCard content. Contacts
+// without a name or a date of birth are left out.
+func ParseVCardBirthdays(content, source string) []Birthday {
+	var birthdays []Birthday
+	var current *Birthday
+	var fullName, structuredName string
+	for _, line := range unfoldICS(content) {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// Properties may be grouped, e.g. "item1.BDAY"
+		key, params, _ := strings.Cut(strings.ToUpper(name), ";")
+		if _, after, grouped := strings.Cut(key, "."); grouped {
+			key = after
+		}
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "BEGIN":
+			if strings.EqualFold(value, "VCARD") {
+				current, fullName, structuredName = &Birthday{Source: source}, "", ""
+			}
+		case "END":
+			if current != nil && strings.EqualFold(value, "VCARD") {
+				if current.Name = cmp.Or(strings.TrimSpace(fullName), strings.TrimSpace(structuredName)); current.Name != "" && current.Month > 0 {
+					birthdays = append(birthdays, *current)
+				}
+				current = nil
+			}
+		case "FN":
+			fullName = unescapeICS(value)
+		case "N":
+			// Family;Given;Additional;Prefix;Suffix
+			parts := strings.Split(value, ";")
+			if len(parts) > 1 {
+				structuredName = unescapeICS(parts[1] + " " + parts[0])
+			} else {
+				structuredName = unescapeICS(parts[0])
+			}
+		case "BDAY":
+			if current == nil {
+				continue
+			}
+			omitYear := -1
+			for param := range strings.SplitSeq(params, ";") {
+				if v, found := strings.CutPrefix(param, "X-APPLE-OMIT-YEAR="); found {
+					omitYear, _ = strconv.Atoi(v)
+				}
+			}
+			if year, month, day, ok := parseVCardDate(value, omitYear); ok {
+				current.Year, current.Month, current.Day = year, month, day
+			} else {
+				GetDebugLogger().Logf("calendar", "Failed to parse BDAY: %s", value)
+			}
+		}
+	}
+	return birthdays
+}
+
+// GetUploadedBirthdays returns the birthdays of the uploaded vCard files.
+func GetUploadedBirthdays() []Birthday {
+	item, exists := GetStorage().Get("birthdays")
+	if !exists {
+		return nil
+	}
+	data, err := json.Marshal(item.Value)
+	if err != nil {
+		return nil
+	}
+	var birthdays []Birthday
+	if err := json.Unmarshal(data, &birthdays); err != nil {
+		GetDebugLogger().Logf("calendar", "GetUploadedBirthdays: Failed to unmarshal birthdays: %v", err)
+		return nil
+	}
+	return birthdays
+}
+
+// SaveUploadedBirthdays replaces the birthdays of the uploaded vCard files.
+func SaveUploadedBirthdays(birthdays []Birthday) {
+	storage := GetStorage()
+	version := time.Now().Unix()
+	if item, exists := storage.Get("birthdays"); exists {
+		version = item.Version + 1
+	}
+	storage.Set("birthdays", birthdays, version)
+}
+
+// birthdaysCacheEntry is the birthdays of one address book.
+type birthdaysCacheEntry struct {
+	birthdays []Birthday
+	fetched   time.Time
+	tried     time.Time
+}
+
+// BirthdayService reads the birthdays of the configured address books and uploaded files.
+type BirthdayService struct {
+	mu     sync.Mutex
+	config BirthdaysConfig
+	cache  map[string]*birthdaysCacheEntry
+}
+
+// Global birthday service instance
+var birthdayService = &BirthdayService{cache: make(map[string]*birthdaysCacheEntry)}
+
+// GetBirthdayService returns the global birthday service instance.
+func GetBirthdayService() *BirthdayService {
+	return birthdayService
+}
+
+// Configure sets the CardDAV address books.
+func (bs *BirthdayService) Configure(cfg BirthdaysConfig) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.config = cfg
+	clear(bs.cache)
+}
+
+// Birthdays returns the birthdays of all address books and uploaded files, by date.
+func (bs *BirthdayService) Birthdays(ctx context.Context) []Birthday {
+	bs.mu.Lock()
+	sources := slices.Clone(bs.config.CardDAV)
+	bs.mu.Unlock()
+
+	birthdays := GetUploadedBirthdays()
+	for _, src := range sources {
+		birthdays = append(birthdays, bs.addressBook(ctx, src)...)
+	}
+	slices.SortStableFunc(birthdays, func(a, b Birthday) int {
+		if a.Month != b.Month {
+			return a.Month - b.Month
+		}
+		return a.Day - b.Day
+	})
+	return birthdays
+}
+
+// Events returns the birthdays between two dates as all-day calendar events of type
+// "birthday", titled with the age reached when the year of birth is known.
+func (bs *BirthdayService) Events(ctx context.Context, from, to time.Time) []CalendarEvent {
+	first, last := from.Format(time.DateOnly), to.Format(time.DateOnly)
+	var events []CalendarEvent
+	for _, b := range bs.Birthdays(ctx) {
+		for year := from.Year(); year <= to.Year(); year++ {
+			date := b.In(year).Format(time.DateOnly)
+			if date < first || date > last {
+				continue
+			}
+			evt := CalendarEvent{
+				ID:    fmt.Sprintf("birthday_%s_%s", date, b.Name),
+				Title: b.Name,
+				Date:  date,
+				Type:  EventTypeBirthday,
+				Age:   b.AgeIn(year),
+			}
+			if evt.Age > 0 {
+				evt.Title += fmt.Sprintf(" (%d)", evt.Age)
+			}
+			events = append(events, evt)
+		}
+	}
+	return events
+}
+
+// addressBook returns the birthdays of an address book, read again when half a day old.
+// When a fetch fails the last birthdays are kept.
+func (bs *BirthdayService) addressBook(ctx context.Context, src CardDAVSource) []Birthday {
+	bs.mu.Lock()
+	e, ok := bs.cache[src.URL]
+	if !ok {
+		e = &birthdaysCacheEntry{}
+		bs.cache[src.URL] = e
+	}
+	if time.Since(e.fetched) < birthdaysTTL || time.Since(e.tried) < birthdaysRetry {
+		RecordTiming(ctx, "birthdays", 0, TimingHit)
+		defer bs.mu.Unlock()
+		return e.birthdays
+	}
+	e.tried = time.Now()
+	bs.mu.Unlock()
+
+	done := StartTiming(ctx, "birthdays")
+	content, err := fetchCardDAV(ctx, src)
+	done(TimingMiss)
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if err != nil {
+		GetDebugLogger().Logf("calendar", "fetching address book %s failed: %v", src.URL, err)
+	} else {
+		name := src.Name
+		if name == "" {
+			if u, err := url.Parse(src.URL); err == nil {
+				name = u.Host
+			}
+		}
+		e.birthdays, e.fetched = ParseVCardBirthdays(content, name), time.Now()
+	}
+	return e.birthdays
+}
+
+// cardDAVQuery asks an address book for the vCards of all its contacts.
+const cardDAVQuery = `<?xml version="1.0" encoding="utf-8"?>
+<C:addressbook-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:carddav">
+  <D:prop><C:address-data/></D:prop>
+</C:addressbook-query>`
+
+// fetchCardDAV returns the vCards of an address book, joined. A URL that answers the query
+// with a plain vCard file, rather than a multistatus, is read as is.
+func fetchCardDAV(ctx context.Context, src CardDAVSource) (string, error) {
+	password, err := ResolveSecret(src.Password, src.PasswordFile, src.PasswordEnv)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "REPORT", src.URL, strings.NewReader(cardDAVQuery))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", "1")
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	if src.Username != "" {
+		req.SetBasicAuth(src.Username, password)
+	}
+	client := &http.Client{}
+	if src.Insecure {
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch address book: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read address book: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusMultiStatus:
+	case http.StatusOK:
+		return string(body), nil
+	default:
+		return "", fmt.Errorf("address book: HTTP %s", resp.Status)
+	}
+
+	var ms struct {
+		Responses []struct {
+			Propstat []struct {
+				AddressData string `xml:"prop>address-data"`
+			} `xml:"propstat"`
+		} `xml:"response"`
+	}
+	if err := xml.Unmarshal(body, &ms); err != nil {
+		return "", fmt.Errorf("invalid address book answer: %w", err)
+	}
+	var cards strings.Builder
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstat {
+			cards.WriteString(ps.AddressData)
+			cards.WriteString("\n")
+		}
+	}
+	return cards.String(), nil
+}
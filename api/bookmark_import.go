@@ -0,0 +1,141 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// maxBookmarkIconSize caps the data URI favicons kept from an import; larger ones are dropped
+// and the dashboard falls back to /api/favicon.
+const maxBookmarkIconSize = 8 << 10
+
+// netscapeTokenPattern finds the folders, links and list boundaries of a Netscape bookmark file.
+var netscapeTokenPattern = regexp.MustCompile(`(?is)<h3[^>]*>(.*?)</h3>|<a\s([^>]*)>(.*?)</a>|<dl[^>]*>|</dl>`)
+
+// htmlAttrPattern finds the attributes of a tag, e.g. HREF="https://example.com".
+var htmlAttrPattern = regexp.MustCompile(`(?is)([a-z_]+)\s*=\s*"([^"]*)"`)
+
+// ParseBookmarkImport reads a Chrome/Edge/Brave Bookmarks JSON file or a Netscape HTML
+// export (the "Export bookmarks" file of every browser) and returns the bookmarks with the
+// folder path they were in.
+func ParseBookmarkImport(data []byte) ([]Bookmark, string, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, "", errors.New("empty bookmark file")
+	}
+	if trimmed[0] == '{' {
+		var root struct {
+			Roots map[string]json.RawMessage `json:"roots"`
+		}
+		if err := json.Unmarshal(trimmed, &root); err != nil || root.Roots == nil {
+			return nil, "", errors.New("not a Chrome bookmarks file")
+		}
+		var bookmarks []Bookmark
+		for _, name := range []string{"bookmark_bar", "other", "synced"} {
+			var node ChromeBookmarkNode
+			if json.Unmarshal(root.Roots[name], &node) == nil {
+				collectChromeBookmarks(&node, node.Name, &bookmarks)
+			}
+		}
+		return bookmarks, "chrome", nil
+	}
+	if bytes.Contains(bytes.ToUpper(trimmed[:min(len(trimmed), 512)]), []byte("NETSCAPE-BOOKMARK-FILE")) ||
+		bytes.Contains(bytes.ToLower(trimmed), []byte("<dt><a")) {
+		return parseNetscapeBookmarks(string(trimmed)), "netscape", nil
+	}
+	return nil, "", errors.New("unrecognized bookmark file (use a Chrome Bookmarks JSON file or an HTML export)")
+}
+
+// collectChromeBookmarks walks a Chrome bookmark node, recording the folder path of each URL.
+func collectChromeBookmarks(node *ChromeBookmarkNode, folder string, bookmarks *[]Bookmark) {
+	for i := range node.Children {
+		child := &node.Children[i]
+		switch {
+		case child.Type == "url" && child.URL != "":
+			*bookmarks = append(*bookmarks, Bookmark{Title: child.Name, URL: child.URL, Folder: folder})
+		case len(child.Children) > 0:
+			collectChromeBookmarks(child, joinBookmarkFolder(folder, child.Name), bookmarks)
+		}
+	}
+}
+
+// parseNetscapeBookmarks reads a Netscape bookmark file. A folder is an <H3> followed by a
+// <DL> list of its entries; links may carry their favicon as an ICON data URI.
+func parseNetscapeBookmarks(content string) []Bookmark {
+	var bookmarks []Bookmark
+	var folders []string
+	pending := ""
+	for _, m := range netscapeTokenPattern.FindAllStringSubmatch(content, -1) {
+		token := strings.ToLower(m[0])
+		switch {
+		case strings.HasPrefix(token, "<h3"):
+			pending = strings.TrimSpace(html.UnescapeString(m[1]))
+		case strings.HasPrefix(token, "<dl"):
+			folders = append(folders, pending)
+			pending = ""
+		case token == "</dl>":
+			if len(folders) > 0 {
+				folders = folders[:len(folders)-1]
+			}
+		default:
+			attrs := make(map[string]string)
+			for _, a := range htmlAttrPattern.FindAllStringSubmatch(m[2], -1) {
+				attrs[strings.ToLower(a[1])] = html.UnescapeString(a[2])
+			}
+			href := attrs["href"]
+			if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") || strings.HasPrefix(href, "place:") {
+				continue
+			}
+			b := Bookmark{
+				Title: strings.TrimSpace(html.UnescapeString(stripHTMLTags(m[3]))),
+				URL:   href,
+			}
+			for _, f := range folders {
+				b.Folder = joinBookmarkFolder(b.Folder, f)
+			}
+			if icon := attrs["icon"]; strings.HasPrefix(icon, "data:image/") && len(icon) <= maxBookmarkIconSize {
+				b.Icon = icon
+			} else if uri := attrs["icon_uri"]; strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://") {
+				b.Icon = uri
+			}
+			if b.Title == "" {
+				b.Title = b.URL
+			}
+			bookmarks = append(bookmarks, b)
+		}
+	}
+	return bookmarks
+}
+
+// joinBookmarkFolder appends a folder name to a folder path, e.g. "Bookmarks bar/Dev".
+func joinBookmarkFolder(path, name string) string {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return path
+	case path == "":
+		return name
+	}
+	return path + "/" + name
+}
+
+// stripHTMLTags removes tags from a fragment of HTML.
+func stripHTMLTags(s string) string {
+	var b strings.Builder
+	inTag := false
+	for _, r := range s {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
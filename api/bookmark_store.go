@@ -0,0 +1,336 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// bookmarksFile holds the bookmarks imported into the dashboard across restarts.
+const bookmarksFile = "bookmarks.json"
+
+// maxStoredBookmarks caps the bookmarks kept by the server.
+const maxStoredBookmarks = 10000
+
+// ErrBookmarkNotFound is returned when a bookmark ID is not in the store.
+var ErrBookmarkNotFound = errors.New("bookmark not found")
+
+// BookmarkImportResult describes the outcome of a bookmark import.
+type BookmarkImportResult struct {
+	Format  string `json:"format"` // chrome or netscape
+	Found   int    `json:"found"`
+	Added   int    `json:"added"`
+	Updated int    `json:"updated"` // Already stored; title, folder or icon replaced
+	Total   int    `json:"total"`   // Bookmarks stored after the import
+}
+
+// BookmarkStore keeps the bookmarks uploaded to the dashboard. They are shared by every
+// client and profile, so autocomplete works on devices whose browser the server cannot read.
+type BookmarkStore struct {
+	mu        sync.Mutex
+	bookmarks []Bookmark
+	loaded    bool
+}
+
+// Global bookmark store instance
+var bookmarkStore = &BookmarkStore{}
+
+// GetBookmarkStore returns the global bookmark store instance.
+func GetBookmarkStore() *BookmarkStore {
+	return bookmarkStore
+}
+
+// load reads the bookmarks file. Caller must hold mu.
+func (bs *BookmarkStore) load() {
+	if bs.loaded {
+		return
+	}
+	bs.loaded = true
+	data, err := os.ReadFile(bookmarksFile)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &bs.bookmarks); err != nil {
+		GetDebugLogger().Logf("bookmarks", "failed to parse %s: %v", bookmarksFile, err)
+		bs.bookmarks = nil
+	}
+	for i := range bs.bookmarks {
+		if bs.bookmarks[i].ID == "" {
+			bs.bookmarks[i].ID = newStoreID()
+		}
+	}
+}
+
+// save writes the bookmarks file. Caller must hold mu.
+func (bs *BookmarkStore) save() error {
+	data, err := json.Marshal(bs.bookmarks)
+	if err != nil {
+		return err
+	}
+	InvalidateSearchIndex()
+	return os.WriteFile(bookmarksFile, data, 0644)
+}
+
+// All returns the stored bookmarks in the order they were added.
+func (bs *BookmarkStore) All() []Bookmark {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.load()
+	return slices.Clone(bs.bookmarks)
+}
+
+// Tagged returns the stored bookmarks with "dashboard" as their source, for merging with
+// the bookmarks of the browsers on the server.
+func (bs *BookmarkStore) Tagged() []Bookmark {
+	return withBookmarkSource(bs.All(), "dashboard")
+}
+
+// Import merges bookmarks into the store by URL, or replaces the stored bookmarks when
+// replace is set.
+func (bs *BookmarkStore) Import(imported []Bookmark, replace bool) (BookmarkImportResult, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.load()
+
+	stored := slices.Clone(bs.bookmarks)
+	if replace {
+		stored = nil
+	}
+	result := BookmarkImportResult{Found: len(imported)}
+	index := make(map[string]int, len(stored))
+	for i, b := range stored {
+		index[bookmarkKey(b.URL)] = i
+	}
+	for _, b := range imported {
+		if b.URL == "" {
+			continue
+		}
+		b.Sources = nil
+		key := bookmarkKey(b.URL)
+		if i, ok := index[key]; ok {
+			old := stored[i]
+			// Keep the icon already stored when the new export has none
+			if b.Icon == "" {
+				b.Icon = old.Icon
+			}
+			if b.Title != old.Title || b.URL != old.URL || b.Folder != old.Folder || b.Icon != old.Icon {
+				b.ID = old.ID
+				stored[i] = b
+				result.Updated++
+			}
+			continue
+		}
+		if len(stored) >= maxStoredBookmarks {
+			break
+		}
+		b.ID = newStoreID()
+		index[key] = len(stored)
+		stored = append(stored, b)
+		result.Added++
+	}
+	result.Total = len(stored)
+
+	if result.Added == 0 && result.Updated == 0 && !replace {
+		return result, nil
+	}
+	bs.bookmarks = stored
+	return result, bs.save()
+}
+
+// Add stores a new bookmark and returns it with its ID.
+func (bs *BookmarkStore) Add(b Bookmark) (Bookmark, error) {
+	if err := normalizeBookmark(&b); err != nil {
+		return Bookmark{}, err
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.load()
+	if len(bs.bookmarks) >= maxStoredBookmarks {
+		return Bookmark{}, fmt.Errorf("bookmark limit of %d reached", maxStoredBookmarks)
+	}
+	key := bookmarkKey(b.URL)
+	for _, existing := range bs.bookmarks {
+		if bookmarkKey(existing.URL) == key {
+			return Bookmark{}, fmt.Errorf("%s is already bookmarked in %q", b.URL, existing.Folder)
+		}
+	}
+	b.ID = newStoreID()
+	bs.bookmarks = append(bs.bookmarks, b)
+	return b, bs.save()
+}
+
+// Update replaces the title, URL, folder and icon of a stored bookmark.
+func (bs *BookmarkStore) Update(b Bookmark) (Bookmark, error) {
+	if err := normalizeBookmark(&b); err != nil {
+		return Bookmark{}, err
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.load()
+	i := slices.IndexFunc(bs.bookmarks, func(existing Bookmark) bool { return existing.ID == b.ID })
+	if b.ID == "" || i < 0 {
+		return Bookmark{}, ErrBookmarkNotFound
+	}
+	key := bookmarkKey(b.URL)
+	for _, existing := range bs.bookmarks {
+		if existing.ID != b.ID && bookmarkKey(existing.URL) == key {
+			return Bookmark{}, fmt.Errorf("%s is already bookmarked in %q", b.URL, existing.Folder)
+		}
+	}
+	bs.bookmarks[i] = b
+	return b, bs.save()
+}
+
+// Delete removes a stored bookmark by ID.
+func (bs *BookmarkStore) Delete(id string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.load()
+	i := slices.IndexFunc(bs.bookmarks, func(b Bookmark) bool { return b.ID == id })
+	if id == "" || i < 0 {
+		return ErrBookmarkNotFound
+	}
+	bs.bookmarks = slices.Delete(bs.bookmarks, i, i+1)
+	return bs.save()
+}
+
+// DeleteFolder removes the stored bookmarks in a folder and its subfolders and returns
+// how many were removed.
+func (bs *BookmarkStore) DeleteFolder(folder string) (int, error) {
+	folder = cleanBookmarkFolder(folder)
+	if folder == "" {
+		return 0, errors.New("folder is required")
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.load()
+	before := len(bs.bookmarks)
+	bs.bookmarks = slices.DeleteFunc(bs.bookmarks, func(b Bookmark) bool { return inBookmarkFolder(b.Folder, folder) })
+	removed := before - len(bs.bookmarks)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, bs.save()
+}
+
+// RenameFolder moves the stored bookmarks of a folder and its subfolders to another path
+// and returns how many were moved.
+func (bs *BookmarkStore) RenameFolder(from, to string) (int, error) {
+	from, to = cleanBookmarkFolder(from), cleanBookmarkFolder(to)
+	if from == "" {
+		return 0, errors.New("folder is required")
+	}
+	if inBookmarkFolder(to, from) {
+		return 0, errors.New("a folder cannot be moved into itself")
+	}
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.load()
+	moved := 0
+	for i, b := range bs.bookmarks {
+		if inBookmarkFolder(b.Folder, from) {
+			bs.bookmarks[i].Folder = joinBookmarkFolder(to, strings.TrimPrefix(strings.TrimPrefix(b.Folder, from), "/"))
+			moved++
+		}
+	}
+	if moved == 0 {
+		return 0, nil
+	}
+	return moved, bs.save()
+}
+
+// Clear removes every stored bookmark.
+func (bs *BookmarkStore) Clear() error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.load()
+	bs.bookmarks = []Bookmark{}
+	return bs.save()
+}
+
+// BookmarkFolder is a folder of the bookmark hierarchy.
+type BookmarkFolder struct {
+	Path   string `json:"path"` // e.g. "Bookmarks bar/Dev"
+	Name   string `json:"name"`
+	Parent string `json:"parent,omitempty"`
+	Count  int    `json:"count"` // Bookmarks directly in the folder
+	Total  int    `json:"total"` // Bookmarks in the folder and its subfolders
+}
+
+// BookmarkFolders lists the folders of bookmarks, parents before their subfolders.
+func BookmarkFolders(bookmarks []Bookmark) []BookmarkFolder {
+	folders := make(map[string]*BookmarkFolder)
+	for _, b := range bookmarks {
+		if b.Folder == "" {
+			continue
+		}
+		path := ""
+		for _, name := range strings.Split(b.Folder, "/") {
+			parent := path
+			path = joinBookmarkFolder(path, name)
+			f, ok := folders[path]
+			if !ok {
+				f = &BookmarkFolder{Path: path, Name: strings.TrimSpace(name), Parent: parent}
+				folders[path] = f
+			}
+			f.Total++
+		}
+		folders[path].Count++
+	}
+	result := make([]BookmarkFolder, 0, len(folders))
+	for _, f := range folders {
+		result = append(result, *f)
+	}
+	slices.SortFunc(result, func(a, b BookmarkFolder) int { return strings.Compare(a.Path, b.Path) })
+	return result
+}
+
+// normalizeBookmark checks a bookmark from a client and cleans up its folder path.
+func normalizeBookmark(b *Bookmark) error {
+	b.URL = strings.TrimSpace(b.URL)
+	u, err := url.Parse(b.URL)
+	if err != nil || u.Scheme == "" || (u.Host == "" && u.Opaque == "") {
+		return errors.New("a valid URL is required")
+	}
+	if strings.EqualFold(u.Scheme, "javascript") {
+		return errors.New("javascript: bookmarks are not supported")
+	}
+	b.Title = strings.TrimSpace(b.Title)
+	if b.Title == "" {
+		b.Title = b.URL
+	}
+	b.Folder = cleanBookmarkFolder(b.Folder)
+	if len(b.Icon) > maxBookmarkIconSize {
+		b.Icon = ""
+	}
+	b.Sources = nil
+	return nil
+}
+
+// cleanBookmarkFolder trims the names of a folder path and drops empty ones.
+func cleanBookmarkFolder(folder string) string {
+	path := ""
+	for _, name := range strings.Split(folder, "/") {
+		path = joinBookmarkFolder(path, name)
+	}
+	return path
+}
+
+// inBookmarkFolder reports whether a folder path is folder or one of its subfolders.
+func inBookmarkFolder(path, folder string) bool {
+	return path == folder || strings.HasPrefix(path, folder+"/")
+}
+
+// newStoreID returns a random ID for a stored bookmark or search engine.
+func newStoreID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
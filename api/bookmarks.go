@@ -4,16 +4,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 )
 
 // Bookmark represents a browser bookmark.
 type Bookmark struct {
-	Title string `json:"title"`
-	URL   string `json:"url"`
+	ID     string `json:"id,omitempty"` // Set on bookmarks kept by the dashboard, which can be edited
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Folder string `json:"folder,omitempty"` // Folder path, e.g. "Bookmarks bar/Dev"
+	Icon   string `json:"icon,omitempty"`   // Favicon as a data URI or URL
+	// Sources are where the bookmark was found: dashboard, chrome, firefox, edge or brave
+	Sources []string `json:"sources,omitempty"`
 }
 
 // ChromeBookmarkNode represents a node in Chrome's bookmark JSON structure.
@@ -49,7 +56,7 @@ func GetBookmarks(preferredBrowser string) ([]Bookmark, error) {
 			chromeBookmarks, err := getChromeBookmarks()
 			GetDebugLogger().Logf("bookmarks", "Chrome bookmarks: count=%d, error=%v", len(chromeBookmarks), err)
 			if err == nil && len(chromeBookmarks) > 0 {
-				allBookmarks = append(allBookmarks, chromeBookmarks...)
+				allBookmarks = append(allBookmarks, withBookmarkSource(chromeBookmarks, "chrome")...)
 				foundPreferred = true
 				GetDebugLogger().Logf("bookmarks", "Successfully loaded %d Chrome bookmarks", len(chromeBookmarks))
 			}
@@ -57,7 +64,7 @@ func GetBookmarks(preferredBrowser string) ([]Bookmark, error) {
 			firefoxBookmarks, err := getFirefoxBookmarks()
 			GetDebugLogger().Logf("bookmarks", "Firefox bookmarks: count=%d, error=%v", len(firefoxBookmarks), err)
 			if err == nil && len(firefoxBookmarks) > 0 {
-				allBookmarks = append(allBookmarks, firefoxBookmarks...)
+				allBookmarks = append(allBookmarks, withBookmarkSource(firefoxBookmarks, "firefox")...)
 				foundPreferred = true
 				GetDebugLogger().Logf("bookmarks", "Successfully loaded %d Firefox bookmarks", len(firefoxBookmarks))
 			}
@@ -65,7 +72,7 @@ func GetBookmarks(preferredBrowser string) ([]Bookmark, error) {
 			edgeBookmarks, err := getEdgeBookmarks()
 			GetDebugLogger().Logf("bookmarks", "Edge bookmarks: count=%d, error=%v", len(edgeBookmarks), err)
 			if err == nil && len(edgeBookmarks) > 0 {
-				allBookmarks = append(allBookmarks, edgeBookmarks...)
+				allBookmarks = append(allBookmarks, withBookmarkSource(edgeBookmarks, "edge")...)
 				foundPreferred = true
 				GetDebugLogger().Logf("bookmarks", "Successfully loaded %d Edge bookmarks", len(edgeBookmarks))
 			}
@@ -73,7 +80,7 @@ func GetBookmarks(preferredBrowser string) ([]Bookmark, error) {
 			braveBookmarks, err := getBraveBookmarks()
 			GetDebugLogger().Logf("bookmarks", "Brave bookmarks: count=%d, error=%v", len(braveBookmarks), err)
 			if err == nil && len(braveBookmarks) > 0 {
-				allBookmarks = append(allBookmarks, braveBookmarks...)
+				allBookmarks = append(allBookmarks, withBookmarkSource(braveBookmarks, "brave")...)
 				foundPreferred = true
 				GetDebugLogger().Logf("bookmarks", "Successfully loaded %d Brave bookmarks", len(braveBookmarks))
 			}
@@ -87,47 +94,97 @@ func GetBookmarks(preferredBrowser string) ([]Bookmark, error) {
 		chromeBookmarks, err := getChromeBookmarks()
 		GetDebugLogger().Logf("bookmarks", "Chrome bookmarks: count=%d, error=%v", len(chromeBookmarks), err)
 		if err == nil {
-			allBookmarks = append(allBookmarks, chromeBookmarks...)
+			allBookmarks = append(allBookmarks, withBookmarkSource(chromeBookmarks, "chrome")...)
 		}
 
 		// Try Firefox bookmarks (HTML format)
 		firefoxBookmarks, err := getFirefoxBookmarks()
 		GetDebugLogger().Logf("bookmarks", "Firefox bookmarks: count=%d, error=%v", len(firefoxBookmarks), err)
 		if err == nil {
-			allBookmarks = append(allBookmarks, firefoxBookmarks...)
+			allBookmarks = append(allBookmarks, withBookmarkSource(firefoxBookmarks, "firefox")...)
 		}
 
 		// Try Edge bookmarks (same format as Chrome)
 		edgeBookmarks, err := getEdgeBookmarks()
 		GetDebugLogger().Logf("bookmarks", "Edge bookmarks: count=%d, error=%v", len(edgeBookmarks), err)
 		if err == nil {
-			allBookmarks = append(allBookmarks, edgeBookmarks...)
+			allBookmarks = append(allBookmarks, withBookmarkSource(edgeBookmarks, "edge")...)
 		}
 
 		// Try Brave bookmarks (same format as Chrome)
 		braveBookmarks, err := getBraveBookmarks()
 		GetDebugLogger().Logf("bookmarks", "Brave bookmarks: count=%d, error=%v", len(braveBookmarks), err)
 		if err == nil {
-			allBookmarks = append(allBookmarks, braveBookmarks...)
+			allBookmarks = append(allBookmarks, withBookmarkSource(braveBookmarks, "brave")...)
 		}
 	}
 
 	GetDebugLogger().Logf("bookmarks", "Total bookmarks before deduplication: %d", len(allBookmarks))
 
-	// Remove duplicates (by URL)
-	uniqueBookmarks := make([]Bookmark, 0)
-	seen := make(map[string]bool)
-	for _, bookmark := range allBookmarks {
-		if bookmark.URL != "" && !seen[bookmark.URL] {
-			seen[bookmark.URL] = true
-			uniqueBookmarks = append(uniqueBookmarks, bookmark)
-		}
-	}
+	// Remove duplicates, merging bookmarks saved in several browsers
+	uniqueBookmarks := MergeBookmarks(allBookmarks)
 
 	GetDebugLogger().Logf("bookmarks", "Total unique bookmarks after deduplication: %d", len(uniqueBookmarks))
 	return uniqueBookmarks, nil
 }
 
+// withBookmarkSource records where bookmarks were read from.
+func withBookmarkSource(bookmarks []Bookmark, source string) []Bookmark {
+	for i := range bookmarks {
+		if !slices.Contains(bookmarks[i].Sources, source) {
+			bookmarks[i].Sources = append(bookmarks[i].Sources, source)
+		}
+	}
+	return bookmarks
+}
+
+// bookmarkKey normalizes a URL for finding the same bookmark in several places: the scheme
+// and host are compared case-insensitively and a trailing slash or fragment is ignored.
+func bookmarkKey(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(rawURL)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.String()
+}
+
+// MergeBookmarks removes duplicate URLs. The first bookmark of a URL keeps its ID, title and
+// folder; the others add their sources and fill in a missing folder or icon.
+func MergeBookmarks(bookmarks []Bookmark) []Bookmark {
+	merged := make([]Bookmark, 0, len(bookmarks))
+	index := make(map[string]int, len(bookmarks))
+	for _, b := range bookmarks {
+		if b.URL == "" {
+			continue
+		}
+		key := bookmarkKey(b.URL)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			b.Sources = slices.Clone(b.Sources)
+			merged = append(merged, b)
+			continue
+		}
+		m := &merged[i]
+		for _, source := range b.Sources {
+			if !slices.Contains(m.Sources, source) {
+				m.Sources = append(m.Sources, source)
+			}
+		}
+		if m.Folder == "" {
+			m.Folder = b.Folder
+		}
+		if m.Icon == "" {
+			m.Icon = b.Icon
+		}
+	}
+	return merged
+}
+
 // DetectBrowserFromUserAgent detects the browser from User-Agent string.
 func DetectBrowserFromUserAgent(userAgent string) string {
 	ua := strings.ToLower(userAgent)
@@ -272,28 +329,14 @@ func readChromeBookmarksFile(path string) ([]Bookmark, error) {
 	}
 
 	var bookmarks []Bookmark
-	extractBookmarks(&root.Roots.BookmarkBar, &bookmarks)
-	extractBookmarks(&root.Roots.Other, &bookmarks)
-	extractBookmarks(&root.Roots.Synced, &bookmarks)
+	collectChromeBookmarks(&root.Roots.BookmarkBar, root.Roots.BookmarkBar.Name, &bookmarks)
+	collectChromeBookmarks(&root.Roots.Other, root.Roots.Other.Name, &bookmarks)
+	collectChromeBookmarks(&root.Roots.Synced, root.Roots.Synced.Name, &bookmarks)
 
 	GetDebugLogger().Logf("bookmarks", "Successfully parsed %d bookmarks from %s", len(bookmarks), path)
 	return bookmarks, nil
 }
 
-// extractBookmarks recursively extracts bookmarks from a Chrome bookmark node.
-func extractBookmarks(node *ChromeBookmarkNode, bookmarks *[]Bookmark) {
-	if node.Type == "url" && node.URL != "" {
-		*bookmarks = append(*bookmarks, Bookmark{
-			Title: node.Name,
-			URL:   node.URL,
-		})
-	}
-
-	for i := range node.Children {
-		extractBookmarks(&node.Children[i], bookmarks)
-	}
-}
-
 // getEdgeBookmarks reads bookmarks from Microsoft Edge.
 func getEdgeBookmarks() ([]Bookmark, error) {
 	GetDebugLogger().Logf("bookmarks", "Searching for Edge bookmarks...")
@@ -467,90 +510,9 @@ func readFirefoxBookmarksFile(path string) ([]Bookmark, error) {
 	}
 
 	GetDebugLogger().Logf("bookmarks", "Read %d bytes from Firefox bookmarks file", len(content))
-	var bookmarks []Bookmark
-	htmlContent := string(content)
-
-	// Parse HTML bookmarks - Firefox uses <DT><A HREF="url">title</A></DT> format
-	lines := strings.Split(htmlContent, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if !strings.Contains(line, "<DT><A") && !strings.Contains(line, "<dt><a") {
-			continue
-		}
-
-		// Extract URL
-		urlStart := strings.Index(line, "HREF=\"")
-		if urlStart == -1 {
-			urlStart = strings.Index(line, "href=\"")
-		}
-		if urlStart == -1 {
-			continue
-		}
-
-		var urlEnd int
-		if urlStart > 0 {
-			if strings.Contains(line[urlStart:], "HREF=\"") {
-				urlStart += len("HREF=\"")
-				urlEnd = strings.Index(line[urlStart:], "\"")
-			} else {
-				urlStart += len("href=\"")
-				urlEnd = strings.Index(line[urlStart:], "\"")
-			}
-		}
-
-		if urlEnd == -1 {
-			continue
-		}
-
-		url := line[urlStart : urlStart+urlEnd]
-
-		// Extract title (between > and </A>)
-		titleStart := strings.Index(line, ">")
-		if titleStart == -1 {
-			continue
-		}
-		titleStart++
-
-		titleEnd := strings.Index(line[titleStart:], "</A>")
-		if titleEnd == -1 {
-			titleEnd = strings.Index(line[titleStart:], "</a>")
-		}
-		if titleEnd == -1 {
-			continue
-		}
-
-		title := line[titleStart : titleStart+titleEnd]
-		title = strings.TrimSpace(title)
-
-		if url != "" && title != "" {
-			bookmarks = append(bookmarks, Bookmark{
-				Title: title,
-				URL:   url,
-			})
-		}
-	}
+	// Firefox exports the Netscape bookmark format, with folders as nested lists
+	bookmarks := parseNetscapeBookmarks(string(content))
 
 	GetDebugLogger().Logf("bookmarks", "Parsed %d bookmarks from Firefox HTML file", len(bookmarks))
 	return bookmarks, nil
 }
-
-// FilterBookmarks filters bookmarks by search term.
-func FilterBookmarks(bookmarks []Bookmark, term string) []Bookmark {
-	if term == "" {
-		return bookmarks
-	}
-
-	termLower := strings.ToLower(term)
-	var filtered []Bookmark
-
-	for _, bookmark := range bookmarks {
-		titleLower := strings.ToLower(bookmark.Title)
-		urlLower := strings.ToLower(bookmark.URL)
-
-		if strings.Contains(titleLower, termLower) || strings.Contains(urlLower, termLower) {
-			filtered = append(filtered, bookmark)
-		}
-	}
-
-	return filtered
-}
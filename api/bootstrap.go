@@ -0,0 +1,294 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bootstrapWait is how long /api/bootstrap waits for its parts. Slower parts are left out
+// and fetched by the browser itself; they keep running to warm the caches it will hit.
+const bootstrapWait = 2 * time.Second
+
+// bootstrapModuleURLs are the requests the cards of a module make for their first paint.
+var bootstrapModuleURLs = map[string][]string{
+	"network":    {"/api/ip"},
+	"cpu":        {"/api/system"},
+	"ram":        {"/api/system"},
+	"cpuid":      {"/api/cpuid"},
+	"raminfo":    {"/api/raminfo"},
+	"firmware":   {"/api/firmware"},
+	"systeminfo": {"/api/systeminfo"},
+	"baseboard":  {"/api/baseboard"},
+}
+
+// BootstrapHandler serves GET /api/bootstrap?page=&profile=: the responses of the requests
+// the dashboard makes for its first paint, fetched side by side through mux and keyed by
+// their URL, so a slow connection pays for one round trip instead of a dozen. Only the
+// modules that are enabled and placed on the page are fetched for.
+func BootstrapHandler(mux http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		start := time.Now()
+		profile := ProfileFromRequest(r)
+		page, ok := PageFromRequest(r, profile)
+		if !ok {
+			WriteJSON(w, map[string]any{"error": ErrPageNotFound.Error()})
+			return
+		}
+		modules := bootstrapPageModules(profile, page)
+		urls := bootstrapURLs(profile, modules)
+		var githubToken string
+		GetStorage().GetAsForProfile(profile, "githubToken", &githubToken)
+
+		type part struct {
+			url  string
+			body json.RawMessage
+		}
+		parts := make(chan part, len(urls))
+		// Parts outlive the response when they are slow, see bootstrapWait
+		ctx := context.WithoutCancel(r.Context())
+		for _, u := range urls {
+			sub := r.Clone(ctx)
+			sub.Method = http.MethodGet
+			sub.Body = http.NoBody
+			sub.ContentLength = 0
+			sub.RequestURI = ""
+			sub.URL, _ = url.Parse(u)
+			query := sub.URL.Query()
+			if q := r.URL.Query().Get("profile"); q != "" {
+				query.Set("profile", q)
+			}
+			// The GitHub card sends the token saved in Preferences, which is kept out of the keys
+			if githubToken != "" && strings.HasPrefix(sub.URL.Path, "/api/github/") {
+				query.Set("token", githubToken)
+			}
+			sub.URL.RawQuery = query.Encode()
+			go guardLoop("bootstrap", func() {
+				rec := &bootstrapRecorder{header: make(http.Header)}
+				mux.ServeHTTP(rec, sub)
+				var body json.RawMessage
+				if rec.status == http.StatusOK && json.Valid(rec.body.Bytes()) {
+					body = rec.body.Bytes()
+				}
+				parts <- part{u, body}
+			})
+		}
+
+		responses := make(map[string]json.RawMessage, len(urls))
+		pending := slices.Clone(urls)
+		timeout := time.NewTimer(bootstrapWait)
+		defer timeout.Stop()
+	collect:
+		for range urls {
+			select {
+			case p := <-parts:
+				pending = slices.DeleteFunc(pending, func(u string) bool { return u == p.url })
+				if p.body != nil {
+					responses[p.url] = p.body
+				}
+			case <-timeout.C:
+				break collect
+			}
+		}
+
+		WriteJSON(w, map[string]any{
+			"profile":   profile,
+			"page":      page,
+			"modules":   modules,
+			"responses": responses,
+			"pending":   pending,
+			"ms":        time.Since(start).Milliseconds(),
+		})
+	}
+}
+
+// bootstrapPageModules returns the enabled modules placed on a page of a profile. A page
+// without a saved layout shows every enabled module.
+func bootstrapPageModules(profile, page string) []string {
+	var prefs map[string]any
+	GetStorage().GetAsForProfile(profile, "modulePrefs", &prefs)
+
+	var config LayoutConfig
+	if !GetStorage().GetAsForProfile(profile, LayoutPageKey(page), &config) || (config.Modules == nil && config.Rows == nil) {
+		for id := range GetModuleMetadata() {
+			config.Modules = append(config.Modules, LayoutModule{ID: id})
+		}
+		for _, inst := range ModuleInstances(profile, "") {
+			if inst.Enabled {
+				config.Modules = append(config.Modules, LayoutModule{ID: inst.ID})
+			}
+		}
+	}
+	config = ProcessLayoutConfig(config, prefs)
+
+	var modules []string
+	add := func(id any) {
+		if s, ok := id.(string); ok && s != "" && !slices.Contains(modules, s) {
+			modules = append(modules, s)
+		}
+	}
+	for _, m := range config.Modules {
+		add(m.ID)
+	}
+	for _, row := range config.Rows {
+		for _, slot := range row.Modules {
+			if split, ok := slot.([]any); ok {
+				for _, id := range split {
+					add(id)
+				}
+			} else {
+				add(slot)
+			}
+		}
+	}
+	slices.Sort(modules)
+	return modules
+}
+
+// bootstrapURLs returns the requests of the first paint of the modules, as the browser
+// makes them: the query sorted and without the profile or GitHub token. The requests of
+// module instances are built from their config, with the schema defaults the cards use for
+// empty fields.
+func bootstrapURLs(profile string, modules []string) []string {
+	urls := []string{"/api/modules", "/api/storage/get-all", "/api/capabilities", "/api/summary"}
+	add := func(u string) {
+		if !slices.Contains(urls, u) {
+			urls = append(urls, u)
+		}
+	}
+	for _, id := range modules {
+		for _, u := range bootstrapModuleURLs[id] {
+			add(u)
+		}
+		moduleType := ModuleType(id)
+		if _, isInstanceType := moduleInstanceStores[moduleType]; isInstanceType {
+			inst, err := FindModuleInstance(profile, id)
+			if err != nil || !inst.Enabled {
+				continue
+			}
+			schema, _ := ModuleSchemaFor(moduleType)
+			field := func(name string) string {
+				return bootstrapConfigValue(schema, inst.Config, name)
+			}
+			switch moduleType {
+			case "disk":
+				mount := field("mountPoint")
+				if mount == "" {
+					mount = "/"
+				}
+				add("/api/disk?" + url.Values{"mount": {mount}}.Encode())
+			case "github":
+				accountType := field("accountType")
+				// Cards saved before accountType keep the account type in type
+				if legacy, _ := inst.Config["type"].(string); legacy != "" && inst.Config["accountType"] == nil {
+					accountType = legacy
+				}
+				if name := field("name"); name != "" {
+					add("/api/github/" + field("displayType") + "?" + url.Values{
+						"name": {name}, "type": {accountType}, "count": {field("maxItems")},
+						"sort": {field("sort")}, "order": {field("order")},
+					}.Encode())
+				}
+			case "rss":
+				if feed := field("url"); feed != "" {
+					add("/api/rss?" + url.Values{"url": {feed}, "count": {field("maxItems")}}.Encode())
+				}
+			}
+			continue
+		}
+		switch moduleType {
+		case "weather":
+			query := bootstrapWeatherQuery(profile)
+			add("/api/weather" + bootstrapQuery(query))
+			query.Set("hours", "24")
+			add("/api/weather/hourly?" + query.Encode())
+		}
+	}
+	return urls
+}
+
+// bootstrapWeatherQuery returns the location the weather card asks for: the saved
+// weatherLocation, or none for the server's default.
+func bootstrapWeatherQuery(profile string) url.Values {
+	query := url.Values{}
+	var saved any
+	if !GetStorage().GetAsForProfile(profile, "weatherLocation", &saved) {
+		return query
+	}
+	// Older dashboards saved the location as a JSON string
+	if s, ok := saved.(string); ok {
+		_ = json.Unmarshal([]byte(s), &saved)
+	}
+	loc, _ := saved.(map[string]any)
+	lat, latOK := loc["latitude"].(float64)
+	lon, lonOK := loc["longitude"].(float64)
+	if latOK && lonOK {
+		query.Set("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+		query.Set("lon", strconv.FormatFloat(lon, 'f', -1, 64))
+	}
+	return query
+}
+
+// bootstrapConfigValue returns a config field of a module instance as its card sends it:
+// empty values take the field's schema default.
+func bootstrapConfigValue(schema ModuleSchema, config map[string]any, field string) string {
+	switch v := config[field].(type) {
+	case nil:
+	case string:
+		if v != "" {
+			return v
+		}
+	case float64:
+		if v != 0 {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	default:
+		return fmt.Sprint(v)
+	}
+	if v, ok := schema.Defaults()[field]; ok {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+func bootstrapQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
+
+// bootstrapRecorder keeps the response of one part.
+type bootstrapRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (br *bootstrapRecorder) Header() http.Header {
+	return br.header
+}
+
+func (br *bootstrapRecorder) WriteHeader(status int) {
+	if br.status == 0 {
+		br.status = status
+	}
+}
+
+func (br *bootstrapRecorder) Write(p []byte) (int, error) {
+	if br.status == 0 {
+		br.status = http.StatusOK
+	}
+	return br.body.Write(p)
+}
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BotConfig configures chat bots that answer commands and forward alerts.
+type BotConfig struct {
+	Telegram *TelegramBotConfig `json:"telegram,omitempty"`
+	Matrix   *MatrixBotConfig   `json:"matrix,omitempty"`
+	Discord  *DiscordBotConfig  `json:"discord,omitempty"`
+}
+
+// Validate checks each configured bot.
+func (c BotConfig) Validate() error {
+	if c.Telegram != nil {
+		if err := c.Telegram.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Matrix != nil {
+		if err := c.Matrix.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Discord != nil {
+		if err := c.Discord.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chatBot is a chat network connection.
+type chatBot interface {
+	// Run receives messages and passes commands to the handler until ctx is done.
+	Run(ctx context.Context, handle func(ctx context.Context, text string) string)
+	// SendAlert posts an alert to the configured chats.
+	SendAlert(ctx context.Context, a Alert) error
+}
+
+// BotManager runs the configured chat bots.
+type BotManager struct {
+	mu      sync.Mutex
+	weather WeatherConfig
+	bots    map[string]chatBot
+}
+
+var botManager = &BotManager{bots: make(map[string]chatBot)}
+
+// GetBotManager returns the global bot manager.
+func GetBotManager() *BotManager {
+	return botManager
+}
+
+// Configure creates the bots and registers those that forward alerts as alert channels.
+func (bm *BotManager) Configure(cfg BotConfig, weather WeatherConfig) {
+	bots := make(map[string]chatBot)
+	if cfg.Telegram != nil {
+		bots["telegram"] = newTelegramBot(*cfg.Telegram)
+		if !cfg.Telegram.NoAlerts {
+			GetAlertManager().RegisterChannel("telegram", bots["telegram"].SendAlert)
+		}
+	}
+	if cfg.Matrix != nil {
+		bots["matrix"] = newMatrixBot(*cfg.Matrix)
+		if !cfg.Matrix.NoAlerts {
+			GetAlertManager().RegisterChannel("matrix", bots["matrix"].SendAlert)
+		}
+	}
+	if cfg.Discord != nil {
+		bots["discord"] = newDiscordBot(*cfg.Discord)
+		if !cfg.Discord.NoAlerts {
+			GetAlertManager().RegisterChannel("discord", bots["discord"].SendAlert)
+		}
+	}
+
+	bm.mu.Lock()
+	bm.weather = weather
+	bm.bots = bots
+	bm.mu.Unlock()
+}
+
+// Start runs every bot until the process exits.
+func (bm *BotManager) Start() {
+	bm.mu.Lock()
+	bots := bm.bots
+	bm.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for name, bot := range bots {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Logger("bot").Info("chat bot started", "network", name)
+			bot.Run(context.Background(), bm.HandleCommand)
+		}()
+	}
+	wg.Wait()
+}
+
+// botCommands lists the commands for /help.
+var botCommands = []struct{ name, help string }{
+	{"status", "server load, memory, disk and monitors"},
+	{"weather", "current weather and today's forecast"},
+	{"monitors", "state of every monitor"},
+	{"brief", "today's events, todos and incidents"},
+	{"help", "this list"},
+}
+
+// HandleCommand answers a chat message. Commands start with "/" or "!" (Matrix clients
+// capture "/"); a "@botname" suffix as used in Telegram groups is ignored. Other
+// messages get an empty reply and are not answered.
+func (bm *BotManager) HandleCommand(ctx context.Context, text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" || (text[0] != '/' && text[0] != '!') {
+		return ""
+	}
+	fields := strings.Fields(text[1:])
+	if len(fields) == 0 {
+		return ""
+	}
+	command, _, _ := strings.Cut(strings.ToLower(fields[0]), "@")
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	switch command {
+	case "status":
+		return botStatus(ctx)
+	case "weather":
+		bm.mu.Lock()
+		weather := bm.weather
+		bm.mu.Unlock()
+		return botWeather(ctx, weather)
+	case "monitors":
+		return botMonitors()
+	case "brief":
+		bm.mu.Lock()
+		weather := bm.weather
+		bm.mu.Unlock()
+		text := strings.TrimSpace(BuildDigest(ctx, weather).Text())
+		if text == "" {
+			return "Nothing on today."
+		}
+		return text
+	case "help", "start":
+		var b strings.Builder
+		b.WriteString("Commands:\n")
+		for _, c := range botCommands {
+			fmt.Fprintf(&b, "/%s - %s\n", c.name, c.help)
+		}
+		return strings.TrimSpace(b.String())
+	}
+	return "Unknown command. Send /help for a list."
+}
+
+// botStatus summarises the server and monitors.
+func botStatus(ctx context.Context) string {
+	m := GetMetricsCollector().Current(ctx)
+	uptime := time.Duration(GetSystemUptime()) * time.Second
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s, up %s\n", MustHostname(), formatBotDuration(uptime))
+	fmt.Fprintf(&b, "CPU %.0f%%, RAM %.0f%%, disk %.0f%%", m.CPU.Usage, m.RAM.Percent, m.Disk.Percent)
+	states := GetTimeline().MonitorStates()
+	if len(states) > 0 {
+		down := 0
+		for _, s := range states {
+			if !s.Up {
+				down++
+			}
+		}
+		if down > 0 {
+			fmt.Fprintf(&b, "\nMonitors: %d of %d down", down, len(states))
+		} else {
+			fmt.Fprintf(&b, "\nMonitors: all %d up", len(states))
+		}
+	}
+	return b.String()
+}
+
+// botWeather describes the current weather at the saved location.
+func botWeather(ctx context.Context, cfg WeatherConfig) string {
+	lat, lon, name := SavedWeatherLocation(cfg)
+	if !cfg.Enabled || lat == "" || lon == "" {
+		return "Weather is not configured."
+	}
+	wd, err := CachedWeather(ctx, cfg, lat, lon)
+	if err != nil {
+		return "Weather is unavailable: " + err.Error()
+	}
+	var lines []string
+	if name != "" {
+		lines = append(lines, name)
+	}
+	if c := wd.Current; c != nil {
+		lines = append(lines, fmt.Sprintf("Now %.0f%s, humidity %.0f%%, wind %.0f %s", c.Temperature, c.TempUnit, c.Humidity, c.WindSpeed, c.WindUnit))
+	}
+	if nc := wd.Nowcast; nc != nil && (nc.Raining || nc.StartsIn != nil) {
+		lines = append(lines, nc.Summary)
+	}
+	if d := wd.Today; d != nil {
+		line := fmt.Sprintf("Today: %s, %.0f–%.0f%s", d.IconDescription, d.TempMin, d.TempMax, d.TempUnit)
+		if d.PrecipitationProb > 0 {
+			line += fmt.Sprintf(", %.0f%% chance of rain", d.PrecipitationProb)
+		}
+		lines = append(lines, line)
+	}
+	if d := wd.Tomorrow; d != nil {
+		lines = append(lines, fmt.Sprintf("Tomorrow: %s, %.0f–%.0f%s", d.IconDescription, d.TempMin, d.TempMax, d.TempUnit))
+	}
+	if len(lines) == 0 || (len(lines) == 1 && name != "") {
+		lines = append(lines, wd.Summary)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// botMonitors lists every monitor, down ones first.
+func botMonitors() string {
+	states := GetTimeline().MonitorStates()
+	if len(states) == 0 {
+		return "No monitor results yet."
+	}
+	var b strings.Builder
+	for _, s := range states {
+		if s.Up {
+			fmt.Fprintf(&b, "✅ %s\n", s.Name)
+		} else if s.Error != "" {
+			fmt.Fprintf(&b, "❌ %s: %s\n", s.Name, s.Error)
+		} else {
+			fmt.Fprintf(&b, "❌ %s\n", s.Name)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func formatBotDuration(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh", days, hours)
+	}
+	return fmt.Sprintf("%dh %dm", hours, int(d.Minutes())%60)
+}
+
+// botAlertText formats an alert as a chat message.
+func botAlertText(a Alert) string {
+	icon := "ℹ️"
+	switch a.Severity {
+	case "critical":
+		icon = "🔴"
+	case "warning":
+		icon = "⚠️"
+	case "ok":
+		icon = "✅"
+	}
+	text := icon + " " + a.Title
+	if a.Body != "" {
+		text += "\n" + a.Body
+	}
+	return text
+}
+
+// botRetryDelay is the wait before reconnecting after the n-th consecutive failure.
+func botRetryDelay(failures int) time.Duration {
+	return min(time.Duration(1<<min(failures, 6))*time.Second, time.Minute)
+}
+
+// sleepContext waits for d or until ctx is done, reporting whether it waited the full time.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const discordAPI = "https://discord.com/api/v10"
+
+// Discord gateway intents: guild and direct messages with their content.
+const discordIntents = 1<<9 | 1<<12 | 1<<15
+
+// DiscordBotConfig configures a Discord bot. The Message Content intent must be enabled
+// for the bot in the developer portal.
+type DiscordBotConfig struct {
+	Token string `json:"token,omitempty"`
+	// TokenFile and TokenEnv read the bot token from a secret file or environment variable
+	TokenFile  string   `json:"tokenFile,omitempty"`
+	TokenEnv   string   `json:"tokenEnv,omitempty"`
+	ChannelIDs []string `json:"channelIds"`         // Channels allowed to send commands; alerts go to all of them
+	NoAlerts   bool     `json:"noAlerts,omitempty"` // Only answer commands
+}
+
+// Validate checks the Discord configuration.
+func (c DiscordBotConfig) Validate() error {
+	if c.Token == "" && c.TokenFile == "" && c.TokenEnv == "" {
+		return fmt.Errorf("bots.discord: token, tokenFile or tokenEnv is required")
+	}
+	if len(c.ChannelIDs) == 0 {
+		return fmt.Errorf("bots.discord: channelIds is required")
+	}
+	return nil
+}
+
+// discordBot receives messages over the Discord gateway and replies with the REST API.
+type discordBot struct {
+	config DiscordBotConfig
+	client *http.Client
+}
+
+func newDiscordBot(cfg DiscordBotConfig) *discordBot {
+	return &discordBot{config: cfg, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// request calls the REST API and decodes the JSON response into result.
+func (d *discordBot) request(ctx context.Context, method, path string, body any, result any) error {
+	token, err := ResolveSecret(d.config.Token, d.config.TokenFile, d.config.TokenEnv)
+	if err != nil {
+		return err
+	}
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, discordAPI+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&e)
+		return fmt.Errorf("discord: HTTP %d: %s", resp.StatusCode, e.Message)
+	}
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
+
+func (d *discordBot) send(ctx context.Context, channelID, text string) error {
+	// Messages are limited to 2000 characters
+	if r := []rune(text); len(r) > 2000 {
+		text = string(r[:1997]) + "..."
+	}
+	return d.request(ctx, http.MethodPost, "/channels/"+channelID+"/messages", map[string]string{"content": text}, nil)
+}
+
+// discordPayload is a gateway message.
+type discordPayload struct {
+	Op       int             `json:"op"`
+	Data     json.RawMessage `json:"d"`
+	Sequence *int64          `json:"s,omitempty"`
+	Type     string          `json:"t,omitempty"`
+}
+
+// Run keeps a gateway connection open and answers commands in the configured channels.
+func (d *discordBot) Run(ctx context.Context, handle func(ctx context.Context, text string) string) {
+	logger := Logger("bot")
+	failures := 0
+	for ctx.Err() == nil {
+		start := time.Now()
+		err := d.session(ctx, handle)
+		if time.Since(start) > time.Minute {
+			failures = 0
+		}
+		failures++
+		if err != nil {
+			logger.Warn("discord gateway disconnected", "error", err)
+		}
+		sleepContext(ctx, botRetryDelay(failures))
+	}
+}
+
+// session runs one gateway connection until it fails or Discord asks to reconnect.
+func (d *discordBot) session(ctx context.Context, handle func(ctx context.Context, text string) string) error {
+	token, err := ResolveSecret(d.config.Token, d.config.TokenFile, d.config.TokenEnv)
+	if err != nil {
+		return err
+	}
+	var gateway struct {
+		URL string `json:"url"`
+	}
+	if err := d.request(ctx, http.MethodGet, "/gateway", nil, &gateway); err != nil {
+		return err
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, gateway.URL+"/?v=10&encoding=json", nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var writeMu sync.Mutex
+	write := func(op int, data any) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteJSON(map[string]any{"op": op, "d": data})
+	}
+
+	var seqMu sync.Mutex
+	var seq *int64
+	heartbeatDone := make(chan struct{})
+	defer close(heartbeatDone)
+
+	for {
+		var p discordPayload
+		if err := conn.ReadJSON(&p); err != nil {
+			return err
+		}
+		if p.Sequence != nil {
+			seqMu.Lock()
+			seq = p.Sequence
+			seqMu.Unlock()
+		}
+		switch p.Op {
+		case 10: // Hello: start heartbeats and identify
+			var hello struct {
+				Interval int `json:"heartbeat_interval"`
+			}
+			json.Unmarshal(p.Data, &hello)
+			go func() {
+				ticker := time.NewTicker(time.Duration(hello.Interval) * time.Millisecond)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-heartbeatDone:
+						return
+					case <-ticker.C:
+						seqMu.Lock()
+						s := seq
+						seqMu.Unlock()
+						if write(1, s) != nil {
+							conn.Close()
+							return
+						}
+					}
+				}
+			}()
+			if err := write(2, map[string]any{
+				"token":      token,
+				"intents":    discordIntents,
+				"properties": map[string]string{"os": "linux", "browser": "lan-index", "device": "lan-index"},
+			}); err != nil {
+				return err
+			}
+		case 1: // Heartbeat request
+			seqMu.Lock()
+			s := seq
+			seqMu.Unlock()
+			write(1, s)
+		case 7: // Reconnect
+			return nil
+		case 9: // Invalid session
+			return errors.New("discord: invalid session")
+		case 0:
+			if p.Type != "MESSAGE_CREATE" {
+				continue
+			}
+			var msg struct {
+				ChannelID string `json:"channel_id"`
+				Content   string `json:"content"`
+				Author    struct {
+					Bot bool `json:"bot"`
+				} `json:"author"`
+			}
+			if json.Unmarshal(p.Data, &msg) != nil || msg.Author.Bot || !slices.Contains(d.config.ChannelIDs, msg.ChannelID) {
+				continue
+			}
+			if !strings.HasPrefix(msg.Content, "/") && !strings.HasPrefix(msg.Content, "!") {
+				continue
+			}
+			go func() {
+				if reply := handle(ctx, msg.Content); reply != "" {
+					if err := d.send(ctx, msg.ChannelID, reply); err != nil {
+						Logger("bot").Warn("discord reply failed", "error", err)
+					}
+				}
+			}()
+		}
+	}
+}
+
+// SendAlert posts an alert to every configured channel.
+func (d *discordBot) SendAlert(ctx context.Context, a Alert) error {
+	var errs []error
+	for _, channel := range d.config.ChannelIDs {
+		if err := d.send(ctx, channel, botAlertText(a)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
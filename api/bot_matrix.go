@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// MatrixBotConfig configures a Matrix bot account. The account must be a member of the rooms.
+type MatrixBotConfig struct {
+	Homeserver  string `json:"homeserver"` // e.g. https://matrix.example.org
+	AccessToken string `json:"accessToken,omitempty"`
+	// AccessTokenFile and AccessTokenEnv read the token from a secret file or environment variable
+	AccessTokenFile string   `json:"accessTokenFile,omitempty"`
+	AccessTokenEnv  string   `json:"accessTokenEnv,omitempty"`
+	Rooms           []string `json:"rooms"`              // Room IDs (!abc:example.org) that may send commands and get alerts
+	Users           []string `json:"users,omitempty"`    // If set, only these user IDs may send commands
+	NoAlerts        bool     `json:"noAlerts,omitempty"` // Only answer commands
+}
+
+// Validate checks the Matrix configuration.
+func (c MatrixBotConfig) Validate() error {
+	if !strings.HasPrefix(c.Homeserver, "http://") && !strings.HasPrefix(c.Homeserver, "https://") {
+		return fmt.Errorf("bots.matrix: homeserver must be an http:// or https:// URL")
+	}
+	if c.AccessToken == "" && c.AccessTokenFile == "" && c.AccessTokenEnv == "" {
+		return fmt.Errorf("bots.matrix: accessToken, accessTokenFile or accessTokenEnv is required")
+	}
+	if len(c.Rooms) == 0 {
+		return fmt.Errorf("bots.matrix: rooms is required")
+	}
+	return nil
+}
+
+// matrixBot talks to a Matrix homeserver with the client-server API and /sync long polling.
+type matrixBot struct {
+	config MatrixBotConfig
+	client *http.Client
+	userID string
+	txn    atomic.Int64
+}
+
+func newMatrixBot(cfg MatrixBotConfig) *matrixBot {
+	cfg.Homeserver = strings.TrimRight(cfg.Homeserver, "/")
+	b := &matrixBot{config: cfg, client: &http.Client{Timeout: 60 * time.Second}}
+	b.txn.Store(time.Now().UnixNano())
+	return b
+}
+
+// request calls the client-server API and decodes the JSON response into result.
+func (m *matrixBot) request(ctx context.Context, method, path string, body any, result any) error {
+	token, err := ResolveSecret(m.config.AccessToken, m.config.AccessTokenFile, m.config.AccessTokenEnv)
+	if err != nil {
+		return err
+	}
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, m.config.Homeserver+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&e)
+		return fmt.Errorf("matrix: HTTP %d: %s", resp.StatusCode, e.Error)
+	}
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
+
+func (m *matrixBot) send(ctx context.Context, roomID, text string) error {
+	txn := strconv.FormatInt(m.txn.Add(1), 10)
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/send/m.room.message/" + txn
+	return m.request(ctx, http.MethodPut, path, map[string]string{"msgtype": "m.notice", "body": text}, nil)
+}
+
+// matrixSync is the part of a /sync response the bot reads.
+type matrixSync struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []struct {
+					Type    string `json:"type"`
+					Sender  string `json:"sender"`
+					Content struct {
+						MsgType string `json:"msgtype"`
+						Body    string `json:"body"`
+					} `json:"content"`
+				} `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+	} `json:"rooms"`
+}
+
+// Run syncs with the homeserver and answers commands in the configured rooms. Messages
+// sent before the bot started are skipped.
+func (m *matrixBot) Run(ctx context.Context, handle func(ctx context.Context, text string) string) {
+	logger := Logger("bot")
+	since := ""
+	failures := 0
+	for ctx.Err() == nil {
+		if m.userID == "" {
+			var whoami struct {
+				UserID string `json:"user_id"`
+			}
+			if err := m.request(ctx, http.MethodGet, "/_matrix/client/v3/account/whoami", nil, &whoami); err != nil {
+				failures++
+				logger.Warn("matrix login check failed", "error", err)
+				sleepContext(ctx, botRetryDelay(failures))
+				continue
+			}
+			m.userID = whoami.UserID
+		}
+
+		query := url.Values{"timeout": {"30000"}}
+		if since == "" {
+			// Only fetch the sync token on the first request, not the room history
+			query.Set("filter", `{"room":{"timeline":{"limit":0}}}`)
+		} else {
+			query.Set("since", since)
+		}
+		var sync matrixSync
+		if err := m.request(ctx, http.MethodGet, "/_matrix/client/v3/sync?"+query.Encode(), nil, &sync); err != nil {
+			failures++
+			logger.Warn("matrix sync failed", "error", err)
+			sleepContext(ctx, botRetryDelay(failures))
+			continue
+		}
+		failures = 0
+		first := since == ""
+		since = sync.NextBatch
+		if first {
+			continue
+		}
+		for roomID, room := range sync.Rooms.Join {
+			if !slices.Contains(m.config.Rooms, roomID) {
+				continue
+			}
+			for _, ev := range room.Timeline.Events {
+				if ev.Type != "m.room.message" || ev.Content.MsgType != "m.text" || ev.Sender == m.userID {
+					continue
+				}
+				if len(m.config.Users) > 0 && !slices.Contains(m.config.Users, ev.Sender) {
+					logger.Debug("matrix command from unknown user ignored", "user", ev.Sender)
+					continue
+				}
+				if reply := handle(ctx, ev.Content.Body); reply != "" {
+					if err := m.send(ctx, roomID, reply); err != nil {
+						logger.Warn("matrix reply failed", "error", err)
+					}
+				}
+			}
+		}
+	}
+}
+
+// SendAlert posts an alert to every configured room.
+func (m *matrixBot) SendAlert(ctx context.Context, a Alert) error {
+	var errs []error
+	for _, room := range m.config.Rooms {
+		if err := m.send(ctx, room, botAlertText(a)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
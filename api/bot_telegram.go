@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TelegramBotConfig configures a Telegram bot (created with @BotFather).
+type TelegramBotConfig struct {
+	Token string `json:"token,omitempty"`
+	// TokenFile and TokenEnv read the bot token from a secret file or environment variable
+	TokenFile string  `json:"tokenFile,omitempty"`
+	TokenEnv  string  `json:"tokenEnv,omitempty"`
+	ChatIDs   []int64 `json:"chatIds"`            // Chats allowed to send commands; alerts go to all of them
+	NoAlerts  bool    `json:"noAlerts,omitempty"` // Only answer commands
+	APIURL    string  `json:"apiUrl,omitempty"`   // Default: https://api.telegram.org
+}
+
+// Validate checks the Telegram configuration.
+func (c TelegramBotConfig) Validate() error {
+	if c.Token == "" && c.TokenFile == "" && c.TokenEnv == "" {
+		return fmt.Errorf("bots.telegram: token, tokenFile or tokenEnv is required")
+	}
+	if len(c.ChatIDs) == 0 {
+		return fmt.Errorf("bots.telegram: chatIds is required")
+	}
+	return nil
+}
+
+// telegramBot talks to the Telegram Bot API with long polling.
+type telegramBot struct {
+	config TelegramBotConfig
+	client *http.Client
+}
+
+func newTelegramBot(cfg TelegramBotConfig) *telegramBot {
+	if cfg.APIURL == "" {
+		cfg.APIURL = "https://api.telegram.org"
+	}
+	cfg.APIURL = strings.TrimRight(cfg.APIURL, "/")
+	return &telegramBot{config: cfg, client: &http.Client{Timeout: 70 * time.Second}}
+}
+
+// call invokes a Bot API method and decodes its result.
+func (t *telegramBot) call(ctx context.Context, method string, params any, result any) error {
+	token, err := ResolveSecret(t.config.Token, t.config.TokenFile, t.config.TokenEnv)
+	if err != nil {
+		return err
+	}
+	body, _ := json.Marshal(params)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.config.APIURL+"/bot"+token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// The token is part of the URL; keep it out of error messages
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("telegram %s: %w", method, urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	var envelope struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("telegram %s: HTTP %d", method, resp.StatusCode)
+	}
+	if !envelope.OK {
+		return fmt.Errorf("telegram %s: %s", method, envelope.Description)
+	}
+	if result != nil {
+		return json.Unmarshal(envelope.Result, result)
+	}
+	return nil
+}
+
+func (t *telegramBot) send(ctx context.Context, chatID int64, text string) error {
+	return t.call(ctx, "sendMessage", map[string]any{"chat_id": chatID, "text": text}, nil)
+}
+
+// Run polls getUpdates and answers commands from the allowed chats.
+func (t *telegramBot) Run(ctx context.Context, handle func(ctx context.Context, text string) string) {
+	logger := Logger("bot")
+	offset := int64(0)
+	failures := 0
+	for ctx.Err() == nil {
+		var updates []struct {
+			UpdateID int64 `json:"update_id"`
+			Message  *struct {
+				Chat struct {
+					ID int64 `json:"id"`
+				} `json:"chat"`
+				Text string `json:"text"`
+			} `json:"message"`
+		}
+		err := t.call(ctx, "getUpdates", map[string]any{"offset": offset, "timeout": 50, "allowed_updates": []string{"message"}}, &updates)
+		if err != nil {
+			failures++
+			logger.Warn("telegram poll failed", "error", err)
+			sleepContext(ctx, botRetryDelay(failures))
+			continue
+		}
+		failures = 0
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message == nil {
+				continue
+			}
+			chatID := u.Message.Chat.ID
+			if !slices.Contains(t.config.ChatIDs, chatID) {
+				logger.Debug("telegram message from unknown chat ignored", "chat", strconv.FormatInt(chatID, 10))
+				continue
+			}
+			if reply := handle(ctx, u.Message.Text); reply != "" {
+				if err := t.send(ctx, chatID, reply); err != nil {
+					logger.Warn("telegram reply failed", "error", err)
+				}
+			}
+		}
+	}
+}
+
+// SendAlert posts an alert to every configured chat.
+func (t *telegramBot) SendAlert(ctx context.Context, a Alert) error {
+	var errs []error
+	for _, chatID := range t.config.ChatIDs {
+		if err := t.send(ctx, chatID, botAlertText(a)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
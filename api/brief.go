@@ -0,0 +1,308 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Brief is a compact printable summary of the day: the daily digest plus the current
+// state of every monitor.
+type Brief struct {
+	Digest
+	Monitors  []MonitorState `json:"monitors"`
+	Generated time.Time      `json:"generated"`
+}
+
+// BuildBrief collects the digest and the latest monitor states.
+func BuildBrief(ctx context.Context, weather WeatherConfig) Brief {
+	return Brief{
+		Digest:    BuildDigest(ctx, weather),
+		Monitors:  GetTimeline().MonitorStates(),
+		Generated: time.Now(),
+	}
+}
+
+// MonitorsDown returns the number of monitors whose last check failed.
+func (b Brief) MonitorsDown() int {
+	n := 0
+	for _, m := range b.Monitors {
+		if !m.Up {
+			n++
+		}
+	}
+	return n
+}
+
+// Title returns the heading of the brief, e.g. "Thursday, 15 October 2026".
+func (b Brief) Title() string {
+	if t, err := time.Parse("2006-01-02", b.Date); err == nil {
+		return t.Format("Monday, 2 January 2006")
+	}
+	return b.Date
+}
+
+// briefTemplate renders the brief as a self-contained black and white page without
+// scripts or images, readable on e-ink displays and printers.
+var briefTemplate = template.Must(template.New("brief").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Brief – {{.Title}}</title>
+<style>
+body { font-family: Georgia, "Times New Roman", serif; color: #000; background: #fff; margin: 1.5em auto; max-width: 40em; padding: 0 1em; font-size: 18px; line-height: 1.4; }
+h1 { font-size: 1.6em; margin: 0 0 .2em; }
+h2 { font-size: 1.1em; text-transform: uppercase; letter-spacing: .05em; border-bottom: 2px solid #000; margin: 1.2em 0 .4em; }
+ul { list-style: none; padding: 0; margin: 0; }
+li { padding: .15em 0; border-bottom: 1px dotted #000; }
+.time { display: inline-block; min-width: 3.5em; font-weight: bold; }
+.down { font-weight: bold; }
+.muted { font-size: .8em; }
+@media print { body { margin: 0; max-width: none; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Weather}}<p>{{if .Location}}{{.Location}}: {{end}}{{.Weather}}</p>{{end}}
+
+<h2>Events</h2>
+{{if .Events}}<ul>{{range .Events}}<li><span class="time">{{.Time}}</span> {{.Title}}</li>{{end}}</ul>{{else}}<p class="muted">No events today.</p>{{end}}
+
+<h2>Todos</h2>
+{{if .Todos}}<ul>{{range .Todos}}<li>☐ {{.Title}}{{if lt .DueDate $.Date}} <span class="muted">(due {{.DueDate}})</span>{{end}}</li>{{end}}</ul>{{else}}<p class="muted">Nothing due.</p>{{end}}
+
+<h2>Monitors</h2>
+{{if .Monitors}}<p>{{if .MonitorsDown}}<span class="down">{{.MonitorsDown}} of {{len .Monitors}} down</span>{{else}}All {{len .Monitors}} up{{end}}</p>
+<ul>{{range .Monitors}}{{if not .Up}}<li class="down">✗ {{.Name}}{{if .Error}} <span class="muted">{{.Error}}</span>{{end}}</li>{{end}}{{end}}</ul>{{else}}<p class="muted">No monitor results yet.</p>{{end}}
+{{if .Incidents}}
+<h2>Last 24 hours</h2>
+<ul>{{range .Incidents}}<li><span class="time">{{.Time.Format "15:04"}}</span> {{.Title}}</li>{{end}}</ul>{{end}}
+{{if .DiskWarnings}}
+<h2>Disks</h2>
+<ul>{{range .DiskWarnings}}<li>{{.}}</li>{{end}}</ul>{{end}}
+
+<p class="muted">Generated {{.Generated.Format "2006-01-02 15:04"}}</p>
+</body>
+</html>
+`))
+
+// WriteHTML renders the brief as a printable HTML page.
+func (b Brief) WriteHTML(w io.Writer) error {
+	return briefTemplate.Execute(w, b)
+}
+
+// WritePDF renders the brief as a single-column PDF. Paper is "A4" or "Letter".
+func (b Brief) WritePDF(w io.Writer, paper string) error {
+	pdf := fpdf.New("P", "mm", paper, "")
+	pdf.SetTitle("Brief – "+b.Title(), true)
+	pdf.SetMargins(18, 18, 18)
+	pdf.SetAutoPageBreak(true, 18)
+	pdf.AddPage()
+	// The core fonts are cp1252; translate UTF-8 text to it
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.MultiCell(0, 9, tr(b.Title()), "", "L", false)
+	if b.Weather != "" {
+		text := b.Weather
+		if b.Location != "" {
+			text = b.Location + ": " + text
+		}
+		pdf.SetFont("Helvetica", "", 12)
+		pdf.MultiCell(0, 6, tr(text), "", "L", false)
+	}
+
+	heading := func(title string) {
+		pdf.Ln(4)
+		pdf.SetFont("Helvetica", "B", 13)
+		pdf.CellFormat(0, 7, tr(title), "B", 1, "L", false, 0, "")
+		pdf.Ln(1)
+		pdf.SetFont("Helvetica", "", 12)
+	}
+	item := func(label, text string) {
+		if label != "" {
+			pdf.SetFont("Helvetica", "B", 12)
+			pdf.CellFormat(16, 6, tr(label), "", 0, "L", false, 0, "")
+			pdf.SetFont("Helvetica", "", 12)
+		}
+		pdf.MultiCell(0, 6, tr(text), "", "L", false)
+	}
+	empty := func(text string) {
+		pdf.SetFont("Helvetica", "I", 11)
+		pdf.MultiCell(0, 6, tr(text), "", "L", false)
+		pdf.SetFont("Helvetica", "", 12)
+	}
+
+	heading("Events")
+	if len(b.Events) == 0 {
+		empty("No events today.")
+	}
+	for _, e := range b.Events {
+		item(e.Time, e.Title)
+	}
+
+	heading("Todos")
+	if len(b.Todos) == 0 {
+		empty("Nothing due.")
+	}
+	for _, t := range b.Todos {
+		text := "[ ] " + t.Title
+		if t.DueDate < b.Date {
+			text += " (due " + t.DueDate + ")"
+		}
+		item("", text)
+	}
+
+	heading("Monitors")
+	switch down := b.MonitorsDown(); {
+	case len(b.Monitors) == 0:
+		empty("No monitor results yet.")
+	case down == 0:
+		item("", fmt.Sprintf("All %d up", len(b.Monitors)))
+	default:
+		item("", fmt.Sprintf("%d of %d down", down, len(b.Monitors)))
+		for _, m := range b.Monitors {
+			if m.Up {
+				continue
+			}
+			text := m.Name
+			if m.Error != "" {
+				text += " - " + m.Error
+			}
+			item("DOWN", text)
+		}
+	}
+
+	if len(b.Incidents) > 0 {
+		heading("Last 24 hours")
+		for _, ev := range b.Incidents {
+			item(ev.Time.Format("15:04"), ev.Title)
+		}
+	}
+
+	if len(b.DiskWarnings) > 0 {
+		heading("Disks")
+		for _, warning := range b.DiskWarnings {
+			item("", warning)
+		}
+	}
+
+	pdf.Ln(4)
+	pdf.SetFont("Helvetica", "I", 9)
+	pdf.MultiCell(0, 5, "Generated "+b.Generated.Format("2006-01-02 15:04"), "", "L", false)
+
+	return pdf.Output(w)
+}
+
+// SpeechText renders the brief as a short script for text-to-speech: greeting, weather,
+// today's events, due todos and the monitors that are down or went down recently.
+func (b Brief) SpeechText() string {
+	var parts []string
+	greeting := "Good morning."
+	switch hour := b.Generated.Hour(); {
+	case hour >= 18:
+		greeting = "Good evening."
+	case hour >= 12:
+		greeting = "Good afternoon."
+	}
+	parts = append(parts, greeting+" It's "+b.Title()+".")
+
+	if b.Weather != "" && !strings.HasPrefix(b.Weather, "unavailable") {
+		if b.Location != "" {
+			parts = append(parts, fmt.Sprintf("The weather in %s: %s.", b.Location, speechWeather(b.Weather)))
+		} else {
+			parts = append(parts, fmt.Sprintf("The weather: %s.", speechWeather(b.Weather)))
+		}
+	}
+
+	switch len(b.Events) {
+	case 0:
+		parts = append(parts, "You have no events today.")
+	default:
+		items := make([]string, 0, len(b.Events))
+		for _, e := range b.Events {
+			if e.Time != "" {
+				items = append(items, fmt.Sprintf("at %s, %s", e.Time, e.Title))
+			} else {
+				items = append(items, e.Title)
+			}
+		}
+		parts = append(parts, fmt.Sprintf("You have %s today: %s.", plural(len(b.Events), "event"), speechList(items)))
+	}
+
+	if len(b.Todos) > 0 {
+		items := make([]string, 0, len(b.Todos))
+		overdue := 0
+		for _, t := range b.Todos {
+			items = append(items, t.Title)
+			if t.DueDate < b.Date {
+				overdue++
+			}
+		}
+		sentence := fmt.Sprintf("%s due: %s.", capitalize(plural(len(b.Todos), "todo")), speechList(items))
+		if overdue > 0 {
+			sentence += fmt.Sprintf(" %d of them overdue.", overdue)
+		}
+		parts = append(parts, sentence)
+	}
+
+	var down []string
+	for _, m := range b.Monitors {
+		if !m.Up {
+			down = append(down, m.Name)
+		}
+	}
+	switch {
+	case len(down) > 0:
+		verb := "are"
+		if len(down) == 1 {
+			verb = "is"
+		}
+		parts = append(parts, fmt.Sprintf("Alert: %s %s down.", speechList(down), verb))
+	case len(b.Monitors) > 0:
+		parts = append(parts, "All monitors are up.")
+	}
+	switch n := len(b.Incidents); {
+	case n == 1:
+		parts = append(parts, "There was one incident in the last 24 hours.")
+	case n > 1:
+		parts = append(parts, fmt.Sprintf("There were %d incidents in the last 24 hours.", n))
+	}
+	for _, w := range b.DiskWarnings {
+		parts = append(parts, "Disk warning: "+w+".")
+	}
+	return strings.Join(parts, " ")
+}
+
+// speechWeather spells out symbols that TTS engines read poorly.
+func speechWeather(s string) string {
+	return strings.NewReplacer("–", " to ", "°C", " degrees", "°F", " degrees", "°", " degrees", "%", " percent").Replace(s)
+}
+
+// speechList joins items as "a, b and c".
+func speechList(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return "one " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
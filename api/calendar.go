@@ -1,18 +1,34 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"time"
 )
 
+// EventTypeHoliday marks the public holidays merged into the calendar.
+const EventTypeHoliday = "holiday"
+
 // CalendarEvent represents a calendar event.
 type CalendarEvent struct {
-	ID            string `json:"id"`
-	Title         string `json:"title"`
-	Date          string `json:"date"`  // YYYY-MM-DD
-	Time          string `json:"time"`  // HH:MM (24h format)
-	FormattedDate string `json:"formattedDate,omitempty"` // Formatted for display
+	ID            string      `json:"id"`
+	Title         string      `json:"title"`
+	Date          string      `json:"date"`                    // YYYY-MM-DD
+	Time          string      `json:"time"`                    // HH:MM (24h format)
+	FormattedDate string      `json:"formattedDate,omitempty"` // Formatted for display
+	Weather       *WeatherDay `json:"weather,omitempty"`       // Forecast for the event's date, set server-side
+	Type          string      `json:"type,omitempty"`          // EventTypeHoliday or EventTypeBirthday for generated events, empty otherwise
+	Age           int         `json:"age,omitempty"`           // Age reached on a birthday, when the year of birth is known
+}
+
+// How far ahead generated events count as upcoming
+const generatedEventsAhead = 60 * 24 * time.Hour
+
+// GeneratedCalendarEvents returns the all-day events the server adds between two dates: the
+// public holidays and the birthdays of the address books.
+func GeneratedCalendarEvents(ctx context.Context, from, to time.Time) []CalendarEvent {
+	return append(GetHolidayService().Events(ctx, from, to), GetBirthdayService().Events(ctx, from, to)...)
 }
 
 // CalendarProcessedData contains processed calendar data.
@@ -23,13 +39,14 @@ type CalendarProcessedData struct {
 }
 
 // ProcessCalendarEvents processes calendar events and returns calculated data.
-func ProcessCalendarEvents(events []CalendarEvent, count int) CalendarProcessedData {
+// Upcoming events are those after the current time of the client's zone.
+func ProcessCalendarEvents(events []CalendarEvent, count int, cl ClientLocale) CalendarProcessedData {
 	result := CalendarProcessedData{
 		EventsByDate:   make(map[string][]CalendarEvent),
 		DatesWithEvents: []string{},
 	}
 
-	now := time.Now()
+	now := cl.Now()
 	todayStr := now.Format("2006-01-02")
 	nowTime := now.Format("15:04")
 
@@ -67,7 +84,7 @@ func ProcessCalendarEvents(events []CalendarEvent, count int) CalendarProcessedD
 	
 	// Add formatted dates to events
 	for i := range limited {
-		limited[i].FormattedDate = FormatEventDate(limited[i].Date, limited[i].Time)
+		limited[i].FormattedDate = cl.FormatEventDate(limited[i].Date, limited[i].Time)
 	}
 	
 	result.UpcomingEvents = limited
@@ -85,6 +102,13 @@ func ProcessCalendarEvents(events []CalendarEvent, count int) CalendarProcessedD
 	return result
 }
 
+// AnnotateEventsWithWeather sets the forecast on events whose date is in the forecast window.
+func AnnotateEventsWithWeather(events []CalendarEvent, wd WeatherData) {
+	for i := range events {
+		events[i].Weather = wd.ForecastFor(events[i].Date)
+	}
+}
+
 // GetEventsForDate returns events for a specific date.
 func GetEventsForDate(events []CalendarEvent, dateStr string) []CalendarEvent {
 	var result []CalendarEvent
@@ -133,21 +157,30 @@ type MonthCalendarData struct {
 	FirstDay     int      `json:"firstDay"`     // 0 = Sunday, 1 = Monday, etc.
 	Today        string   `json:"today"`       // YYYY-MM-DD
 	DatesWithEvents []string `json:"datesWithEvents"`
+	Holidays     map[string]string `json:"holidays,omitempty"` // Public holiday names by date
+	WeekStart    int      `json:"weekStart"`   // First day of the week of the settings, 0 = Sunday
 }
 
 // GetMonthCalendarData calculates month calendar data.
-func GetMonthCalendarData(year, month int, events []CalendarEvent) MonthCalendarData {
+func GetMonthCalendarData(year, month int, events []CalendarEvent, cl ClientLocale) MonthCalendarData {
 	firstDay := time.Date(year, time.Month(month+1), 1, 0, 0, 0, 0, time.UTC).Weekday()
 	daysInMonth := time.Date(year, time.Month(month+2), 0, 0, 0, 0, 0, time.UTC).Day()
-	today := time.Now().Format("2006-01-02")
+	today := cl.Now().Format("2006-01-02")
 
 	// Get dates with events for this month
 	datesWithEvents := []string{}
 	monthStart := fmt.Sprintf("%04d-%02d-01", year, month+1)
 	monthEnd := fmt.Sprintf("%04d-%02d-%02d", year, month+1, daysInMonth)
 
+	var holidays map[string]string
 	for _, evt := range events {
 		if evt.Date >= monthStart && evt.Date <= monthEnd {
+			if evt.Type == EventTypeHoliday {
+				if holidays == nil {
+					holidays = make(map[string]string)
+				}
+				holidays[evt.Date] = evt.Title
+			}
 			// Check if already in list
 			found := false
 			for _, d := range datesWithEvents {
@@ -173,6 +206,8 @@ func GetMonthCalendarData(year, month int, events []CalendarEvent) MonthCalendar
 		FirstDay:      int(firstDay),
 		Today:         today,
 		DatesWithEvents: datesWithEvents,
+		Holidays:      holidays,
+		WeekStart:     cl.FirstDay,
 	}
 }
 
@@ -195,7 +230,7 @@ type WeekDay struct {
 }
 
 // GetWeekCalendarData calculates week calendar data.
-func GetWeekCalendarData(weekStart time.Time, workWeekOnly bool, startDay int, events []CalendarEvent) WeekCalendarData {
+func GetWeekCalendarData(weekStart time.Time, workWeekOnly bool, startDay int, events []CalendarEvent, cl ClientLocale) WeekCalendarData {
 	// Adjust week start based on startDay setting
 	day := int(weekStart.Weekday())
 	diff := (day - startDay + 7) % 7
@@ -218,7 +253,7 @@ func GetWeekCalendarData(weekStart time.Time, workWeekOnly bool, startDay int, e
 	}
 
 	weekEnd := actualStart.AddDate(0, 0, daysToShow-1)
-	today := time.Now().Format("2006-01-02")
+	today := cl.Now().Format("2006-01-02")
 
 	dayNames := []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
 	days := []WeekDay{}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Capability is something the dashboard lets a client do, with the least role it needs.
+// Endpoints are guarded with RequireCapability so that /api/capabilities always matches
+// what the server enforces.
+type Capability struct {
+	Name        string `json:"name"`
+	Role        Role   `json:"role"`
+	Description string `json:"description"`
+}
+
+// Capabilities lists the guarded actions, from least to most privileged.
+var Capabilities = []Capability{
+	{"banners.dismiss", RoleOperator, "Dismiss dashboard banners"},
+	{"guestwifi.rotate", RoleOperator, "Rotate the guest Wi-Fi password on the router"},
+	{"notifications.subscribe", RoleOperator, "Subscribe browsers to push notifications"},
+	{"notifications.test", RoleOperator, "Send test push notifications and emails"},
+	{"digest.send", RoleOperator, "Send the daily digest now"},
+	{"drop.share", RoleOperator, "Share and delete text and files in the drop"},
+	{"timeline.write", RoleOperator, "Record incidents on the timeline"},
+	{"settings.write", RoleEditor, "Change settings, layouts and stored data"},
+	{"profiles.manage", RoleEditor, "Delete dashboard profiles"},
+	{"configs.manage", RoleEditor, "Upload, download and delete stored configs"},
+	{"themes.manage", RoleEditor, "Install and reload custom themes"},
+	{"guestwifi.edit", RoleEditor, "Change the guest Wi-Fi credentials"},
+	{"snmp.profiles", RoleEditor, "Manage SNMP credential profiles"},
+	{"stats.manage", RoleEditor, "Reset request statistics"},
+	{"retention.prune", RoleEditor, "Prune data past its retention now"},
+	{"graphs.import", RoleEditor, "Import metric history from another instance"},
+	{"tools.httpreq", RoleEditor, "Send HTTP requests from the server with the request tester"},
+	{"tokens.manage", RoleAdmin, "Create and revoke API tokens"},
+	{"webhooks.manage", RoleAdmin, "Manage incoming webhooks"},
+	{"oob.power", RoleAdmin, "Power servers on, off or reset through their BMC"},
+	{"command.run", RoleAdmin, "Run the configured commands of the Commands card now"},
+	{"exposure.view", RoleAdmin, "See the ports this host listens on and the processes behind them"},
+	{"sessions.manage", RoleAdmin, "See and sign out every user's devices"},
+	{"audit.view", RoleAdmin, "Read the audit log"},
+}
+
+// CapabilityRole returns the role a capability needs. Unknown capabilities need admin.
+func CapabilityRole(name string) Role {
+	for _, c := range Capabilities {
+		if c.Name == name {
+			return c.Role
+		}
+	}
+	return RoleAdmin
+}
+
+// CapabilitiesFor reports which capabilities a role has.
+func CapabilitiesFor(role Role) map[string]bool {
+	allowed := make(map[string]bool, len(Capabilities))
+	for _, c := range Capabilities {
+		allowed[c.Name] = role.Allows(c.Role)
+	}
+	return allowed
+}
+
+// RequireCapability wraps a handler so that every request needs the capability's role.
+func RequireCapability(name string, next http.HandlerFunc) http.HandlerFunc {
+	return RequireRole(CapabilityRole(name), next)
+}
+
+// RequireWriteCapability is like RequireCapability but lets GET and HEAD requests through.
+func RequireWriteCapability(name string, next http.HandlerFunc) http.HandlerFunc {
+	return RequireWriteRole(CapabilityRole(name), next)
+}
+
+// validateProfileRoles checks the role limits of dashboard profiles.
+func validateProfileRoles(roles map[string]Role) error {
+	for profile, role := range roles {
+		if !ValidProfileName(profile) {
+			return fmt.Errorf("auth.profileRoles: invalid profile name %q", profile)
+		}
+		if _, err := ParseRole(string(role)); err != nil {
+			return fmt.Errorf("auth.profileRoles.%s: %w", profile, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the changelog.
+const (
+	defaultChangelogRepo  = "earentir/homepage"
+	defaultChangelogLimit = 20
+	changelogTTL          = 6 * time.Hour
+	// A failed fetch is not repeated sooner, so GitHub's unauthenticated limit is not used up
+	changelogRetry = 15 * time.Minute
+)
+
+var changelogRepoPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+// ChangelogConfig configures the release notes of /api/changelog.
+type ChangelogConfig struct {
+	Repo     string `json:"repo,omitempty"`     // GitHub repository the releases are read from, default "earentir/homepage"
+	Limit    int    `json:"limit,omitempty"`    // Releases listed, default 20
+	Disabled bool   `json:"disabled,omitempty"` // Do not fetch release notes
+}
+
+// Validate checks the repository name.
+func (c ChangelogConfig) Validate() error {
+	if c.Repo != "" && !changelogRepoPattern.MatchString(c.Repo) {
+		return fmt.Errorf("changelog: repo must be owner/name")
+	}
+	if c.Limit < 0 || c.Limit > 100 {
+		return fmt.Errorf("changelog: limit must be between 1 and 100")
+	}
+	return nil
+}
+
+// ChangelogEntry is the release notes of one version.
+type ChangelogEntry struct {
+	Version    string    `json:"version"`
+	Name       string    `json:"name,omitempty"`
+	Published  time.Time `json:"published"`
+	URL        string    `json:"url"`
+	Notes      string    `json:"notes"` // Markdown
+	Prerelease bool      `json:"prerelease,omitempty"`
+	New        bool      `json:"new,omitempty"`    // Newer than the running version
+	Unseen     bool      `json:"unseen,omitempty"` // Newer than the version the client saw last, up to the running one
+}
+
+// Changelog is the answer of /api/changelog.
+type Changelog struct {
+	Current         string           `json:"current"`
+	Latest          string           `json:"latest,omitempty"`
+	UpdateAvailable bool             `json:"updateAvailable"`
+	Entries         []ChangelogEntry `json:"entries"`
+	Error           string           `json:"error,omitempty"`
+	Fetched         time.Time        `json:"fetched,omitzero"`
+}
+
+// githubRelease is the part of a GitHub release the changelog reads.
+type githubRelease struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	HTMLURL     string    `json:"html_url"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// ChangelogService fetches and caches the project's release notes.
+type ChangelogService struct {
+	mu       sync.Mutex
+	config   ChangelogConfig
+	version  string
+	releases []githubRelease
+	fetched  time.Time
+	tried    time.Time
+	err      error
+}
+
+// Global changelog instance
+var changelogService = &ChangelogService{}
+
+// GetChangelogService returns the global changelog instance.
+func GetChangelogService() *ChangelogService {
+	return changelogService
+}
+
+// Configure sets the repository and the running version entries are compared with.
+func (cs *ChangelogService) Configure(cfg ChangelogConfig, version string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cfg.Repo != cs.config.Repo {
+		cs.releases, cs.fetched, cs.tried = nil, time.Time{}, time.Time{}
+	}
+	cs.config = cfg
+	cs.version = version
+}
+
+// Changelog returns the release notes, newest first. since is the version the client saw
+// last; entries after it up to the running version are marked unseen. refresh fetches the
+// notes again unless a fetch failed moments ago.
+func (cs *ChangelogService) Changelog(ctx context.Context, since string, refresh bool) Changelog {
+	cs.mu.Lock()
+	cfg, version := cs.config, cs.version
+	stale := time.Since(cs.fetched) >= changelogTTL || refresh
+	canTry := time.Since(cs.tried) >= changelogRetry || (refresh && cs.err == nil)
+	cs.mu.Unlock()
+
+	result := Changelog{Current: version, Entries: []ChangelogEntry{}}
+	if cfg.Disabled {
+		return result
+	}
+	if stale && canTry {
+		cs.fetch(ctx, cfg)
+	}
+
+	cs.mu.Lock()
+	releases, fetched, err := cs.releases, cs.fetched, cs.err
+	cs.mu.Unlock()
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Fetched = fetched
+
+	limit := cfg.Limit
+	if limit <= 0 {
+		limit = defaultChangelogLimit
+	}
+	for _, rel := range releases {
+		if len(result.Entries) >= limit {
+			break
+		}
+		entry := ChangelogEntry{
+			Version:    strings.TrimPrefix(rel.TagName, "v"),
+			Name:       rel.Name,
+			Published:  rel.PublishedAt,
+			URL:        rel.HTMLURL,
+			Notes:      strings.TrimSpace(rel.Body),
+			Prerelease: rel.Prerelease,
+		}
+		entry.New = compareVersions(entry.Version, version) > 0
+		entry.Unseen = since != "" && !entry.New && compareVersions(entry.Version, since) > 0
+		if result.Latest == "" && !rel.Prerelease {
+			result.Latest = entry.Version
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+	result.UpdateAvailable = result.Latest != "" && compareVersions(result.Latest, version) > 0
+	return result
+}
+
+// fetch reads the releases from GitHub, keeping the ones fetched before when it fails.
+func (cs *ChangelogService) fetch(ctx context.Context, cfg ChangelogConfig) {
+	repo := cfg.Repo
+	if repo == "" {
+		repo = defaultChangelogRepo
+	}
+	releases, err := fetchGitHubReleases(ctx, repo)
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.tried = time.Now()
+	cs.err = err
+	if err != nil {
+		GetDebugLogger().Logf("changelog", "fetching releases of %s failed: %v", repo, err)
+		return
+	}
+	cs.releases, cs.fetched = releases, time.Now()
+}
+
+// fetchGitHubReleases returns the published releases of a repository, newest first.
+func fetchGitHubReleases(ctx context.Context, repo string) ([]githubRelease, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	res, err := makeGitHubRequest(ctx, "https://api.github.com/repos/"+repo+"/releases?per_page=50", "")
+	if err != nil {
+		return nil, fmt.Errorf("fetching release notes: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusForbidden || res.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("GitHub rate limit reached, available again in %s", formatRateLimitResetForUI(res.Header.Get("X-RateLimit-Reset")))
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("release notes http status %s", res.Status)
+	}
+	var all []githubRelease
+	if err := json.NewDecoder(io.LimitReader(res.Body, 4<<20)).Decode(&all); err != nil {
+		return nil, fmt.Errorf("decoding release notes: %w", err)
+	}
+	releases := make([]githubRelease, 0, len(all))
+	for _, rel := range all {
+		if !rel.Draft {
+			releases = append(releases, rel)
+		}
+	}
+	return releases, nil
+}
+
+// compareVersions compares dotted versions such as "0.4.141" or "v1.2.0-rc1" numerically,
+// returning -1, 0 or 1. A pre-release sorts before the release it leads up to.
+func compareVersions(a, b string) int {
+	a, b = strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v")
+	a, preA, _ := strings.Cut(a, "-")
+	b, preB, _ := strings.Cut(b, "-")
+	partsA, partsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(partsA), len(partsB)); i++ {
+		var x, y int
+		if i < len(partsA) {
+			x, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			y, _ = strconv.Atoi(partsB[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	return strings.Compare(preA, preB)
+}
@@ -0,0 +1,656 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Climate metrics.
+const (
+	ClimateTemperature = "temperature"
+	ClimateHumidity    = "humidity"
+)
+
+// Default comfort band of a room: 20-24 °C and 40-60 % relative humidity.
+var defaultClimateComfort = ClimateComfort{TemperatureMin: 20, TemperatureMax: 24, HumidityMin: 40, HumidityMax: 60}
+
+// ClimateConfig configures the indoor climate overview behind /api/climate.
+type ClimateConfig struct {
+	Rooms         []ClimateRoom               `json:"rooms,omitempty"`
+	HomeAssistant *ClimateHomeAssistantConfig `json:"homeAssistant,omitempty"`
+	Comfort       *ClimateComfort             `json:"comfort,omitempty"`    // Default comfort band of every room
+	ManualOnly    bool                        `json:"manualOnly,omitempty"` // Only the sensors listed in rooms, no discovered ones
+	StaleAfter    string                      `json:"staleAfter,omitempty"` // Age after which a reading is ignored, default "1h"
+	Interval      string                      `json:"interval,omitempty"`   // Poll interval, default "1m"
+}
+
+// ClimateHomeAssistantConfig reads temperature and humidity sensors from Home Assistant.
+type ClimateHomeAssistantConfig struct {
+	URL       string `json:"url"`
+	Token     string `json:"token,omitempty"`
+	TokenFile string `json:"tokenFile,omitempty"`
+	TokenEnv  string `json:"tokenEnv,omitempty"`
+}
+
+// ClimateRoom groups the sensors of one room.
+type ClimateRoom struct {
+	Name    string          `json:"name"`
+	Sensors []ClimateSensor `json:"sensors"`
+	Comfort *ClimateComfort `json:"comfort,omitempty"` // Overrides the default comfort band
+}
+
+// ClimateSensor is a temperature or humidity reading from one of the sensor sources.
+type ClimateSensor struct {
+	Source string `json:"source"`           // "mqtt", "homeassistant" or "snmp"
+	Metric string `json:"metric,omitempty"` // "temperature" or "humidity", default from the device class or unit
+	Sensor string `json:"sensor,omitempty"` // MQTT sensor id or name (mqtt)
+	Entity string `json:"entity,omitempty"` // Entity ID, e.g. sensor.kitchen_temperature (homeassistant)
+	// SNMP agent and OID (snmp); credentials from a saved profile or a v2c community
+	Host      string  `json:"host,omitempty"`
+	Port      string  `json:"port,omitempty"` // Default 161
+	Profile   string  `json:"profile,omitempty"`
+	Community string  `json:"community,omitempty"`
+	OID       string  `json:"oid,omitempty"`
+	Scale     float64 `json:"scale,omitempty"` // Multiplier of the raw value, e.g. 0.1 for tenths of a degree
+	Unit      string  `json:"unit,omitempty"`  // "°C" or "°F", default the unit the source reports, else °C
+}
+
+// ClimateComfort is the range of temperature (°C) and relative humidity (%) considered
+// comfortable.
+type ClimateComfort struct {
+	TemperatureMin float64 `json:"temperatureMin"`
+	TemperatureMax float64 `json:"temperatureMax"`
+	HumidityMin    float64 `json:"humidityMin"`
+	HumidityMax    float64 `json:"humidityMax"`
+}
+
+// validate checks that each range is not inverted.
+func (c ClimateComfort) validate() error {
+	if c.TemperatureMin > c.TemperatureMax {
+		return errors.New("temperatureMin is above temperatureMax")
+	}
+	if c.HumidityMin > c.HumidityMax || c.HumidityMin < 0 || c.HumidityMax > 100 {
+		return errors.New("humidity range must be within 0-100 with humidityMin below humidityMax")
+	}
+	return nil
+}
+
+// Validate checks the rooms, their sensors and the poll settings.
+func (c ClimateConfig) Validate() error {
+	if ha := c.HomeAssistant; ha != nil {
+		if ha.URL == "" {
+			return fmt.Errorf("climate.homeAssistant: url is required")
+		}
+		if _, err := ResolveSecret(ha.Token, ha.TokenFile, ha.TokenEnv); err != nil {
+			return fmt.Errorf("climate.homeAssistant: %w", err)
+		}
+	}
+	if c.Comfort != nil {
+		if err := c.Comfort.validate(); err != nil {
+			return fmt.Errorf("climate.comfort: %w", err)
+		}
+	}
+	seen := make(map[string]bool)
+	for i, room := range c.Rooms {
+		if strings.TrimSpace(room.Name) == "" {
+			return fmt.Errorf("climate: rooms[%d]: name is required", i)
+		}
+		if seen[strings.ToLower(room.Name)] {
+			return fmt.Errorf("climate: rooms[%d]: duplicate room %s", i, room.Name)
+		}
+		seen[strings.ToLower(room.Name)] = true
+		if room.Comfort != nil {
+			if err := room.Comfort.validate(); err != nil {
+				return fmt.Errorf("climate: rooms[%d].comfort: %w", i, err)
+			}
+		}
+		for j, s := range room.Sensors {
+			if err := s.validate(c.HomeAssistant != nil); err != nil {
+				return fmt.Errorf("climate: rooms[%d].sensors[%d]: %w", i, j, err)
+			}
+		}
+	}
+	if c.StaleAfter != "" {
+		if d, err := time.ParseDuration(c.StaleAfter); err != nil || d < time.Minute {
+			return fmt.Errorf("climate: staleAfter must be a duration of at least 1m")
+		}
+	}
+	if c.Interval != "" {
+		if d, err := time.ParseDuration(c.Interval); err != nil || d < 10*time.Second {
+			return fmt.Errorf("climate: interval must be a duration of at least 10s")
+		}
+	}
+	return nil
+}
+
+// validate checks that the sensor names what to read from its source.
+func (s ClimateSensor) validate(homeAssistant bool) error {
+	switch s.Metric {
+	case "", ClimateTemperature, ClimateHumidity:
+	default:
+		return errors.New("metric must be temperature or humidity")
+	}
+	switch s.Unit {
+	case "", "°C", "C", "°F", "F", "%":
+	default:
+		return errors.New("unit must be °C, °F or %")
+	}
+	switch s.Source {
+	case "mqtt":
+		if s.Sensor == "" {
+			return errors.New("sensor (the MQTT sensor id or name) is required")
+		}
+	case "homeassistant":
+		if !homeAssistant {
+			return errors.New("homeAssistant must be configured for homeassistant sensors")
+		}
+		if s.Entity == "" {
+			return errors.New("entity is required")
+		}
+	case "snmp":
+		if s.Host == "" || s.OID == "" {
+			return errors.New("host and oid are required")
+		}
+		if s.Metric == "" {
+			return errors.New("metric is required for snmp sensors")
+		}
+	default:
+		return errors.New("source must be mqtt, homeassistant or snmp")
+	}
+	return nil
+}
+
+// ClimateReading is the last value of one sensor of a room.
+type ClimateReading struct {
+	Name    string    `json:"name"`
+	Source  string    `json:"source"`
+	Metric  string    `json:"metric"`
+	Value   *float64  `json:"value,omitempty"` // °C or %
+	Updated time.Time `json:"updated,omitzero"`
+	Stale   bool      `json:"stale,omitempty"` // Older than staleAfter, left out of the room's value
+	Error   string    `json:"error,omitempty"`
+}
+
+// ClimateRange is the lowest and highest value of a metric today.
+type ClimateRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// ClimateRoomStatus is the climate of one room: the mean of its fresh readings, today's
+// extremes and where the values fall within the comfort band.
+type ClimateRoomStatus struct {
+	Name             string           `json:"name"`
+	Temperature      *float64         `json:"temperature,omitempty"`
+	Humidity         *float64         `json:"humidity,omitempty"`
+	TemperatureToday *ClimateRange    `json:"temperatureToday,omitempty"`
+	HumidityToday    *ClimateRange    `json:"humidityToday,omitempty"`
+	TemperatureLevel string           `json:"temperatureLevel,omitempty"` // cold, comfortable or warm
+	HumidityLevel    string           `json:"humidityLevel,omitempty"`    // dry, comfortable or humid
+	Comfortable      bool             `json:"comfortable"`                // Every known metric within the band
+	Comfort          ClimateComfort   `json:"comfort"`
+	Discovered       bool             `json:"discovered,omitempty"` // Formed from sensors not listed in rooms
+	Sensors          []ClimateReading `json:"sensors"`
+}
+
+// ClimateMonitor polls the climate sensors and keeps today's extremes of each room.
+type ClimateMonitor struct {
+	mu      sync.Mutex
+	config  *ClimateConfig
+	rooms   []ClimateRoomStatus
+	updated time.Time
+	// Today's extremes by room and metric, reset at local midnight
+	day   string
+	today map[string]*ClimateRange
+}
+
+// Global climate monitor instance
+var climateMonitor = &ClimateMonitor{today: make(map[string]*ClimateRange)}
+
+// GetClimateMonitor returns the global climate monitor instance.
+func GetClimateMonitor() *ClimateMonitor {
+	return climateMonitor
+}
+
+// Configure sets the rooms and sources to poll.
+func (cm *ClimateMonitor) Configure(cfg ClimateConfig) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.config = &cfg
+	cm.rooms = nil
+	cm.today = make(map[string]*ClimateRange)
+}
+
+// Enabled reports whether the climate overview is configured.
+func (cm *ClimateMonitor) Enabled() bool {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.config != nil
+}
+
+// Start polls the sensors at the configured interval.
+func (cm *ClimateMonitor) Start() {
+	cm.mu.Lock()
+	cfg := cm.config
+	cm.mu.Unlock()
+	if cfg == nil {
+		return
+	}
+
+	interval := time.Minute
+	if d, err := time.ParseDuration(cfg.Interval); err == nil {
+		interval = d
+	}
+
+	guardLoop("climate", func() { cm.poll(*cfg) })
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		guardLoop("climate", func() { cm.poll(*cfg) })
+	}
+}
+
+// Status returns the rooms from the last poll.
+func (cm *ClimateMonitor) Status() ([]ClimateRoomStatus, time.Time) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return slices.Clone(cm.rooms), cm.updated
+}
+
+// climateState is a Home Assistant entity state with the attributes the climate overview uses.
+type climateState struct {
+	EntityID    string `json:"entity_id"`
+	State       string `json:"state"`
+	LastUpdated string `json:"last_updated"`
+	Attributes  struct {
+		FriendlyName      string `json:"friendly_name"`
+		DeviceClass       string `json:"device_class"`
+		UnitOfMeasurement string `json:"unit_of_measurement"`
+	} `json:"attributes"`
+}
+
+// poll reads every source and updates the rooms and today's extremes.
+func (cm *ClimateMonitor) poll(cfg ClimateConfig) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	staleAfter := time.Hour
+	if d, err := time.ParseDuration(cfg.StaleAfter); err == nil {
+		staleAfter = d
+	}
+	comfort := defaultClimateComfort
+	if cfg.Comfort != nil {
+		comfort = *cfg.Comfort
+	}
+
+	var errs []error
+	mqttSensors := GetMQTTManager().Status().Sensors
+	var states []climateState
+	if cfg.HomeAssistant != nil {
+		var err error
+		if states, err = fetchHomeAssistantStates(ctx, *cfg.HomeAssistant); err != nil {
+			errs = append(errs, fmt.Errorf("home assistant: %w", err))
+		}
+	}
+
+	var rooms []ClimateRoomStatus
+	mapped := make(map[string]bool)
+	for _, room := range cfg.Rooms {
+		status := ClimateRoomStatus{Name: room.Name, Comfort: comfort, Sensors: []ClimateReading{}}
+		if room.Comfort != nil {
+			status.Comfort = *room.Comfort
+		}
+		for _, s := range room.Sensors {
+			var r ClimateReading
+			switch s.Source {
+			case "mqtt":
+				r = climateMQTTReading(s, mqttSensors, mapped)
+			case "homeassistant":
+				r = climateHomeAssistantReading(s, states, cfg.HomeAssistant != nil && states == nil, mapped)
+			case "snmp":
+				r = climateSNMPReading(ctx, s)
+			}
+			if r.Error != "" {
+				errs = append(errs, fmt.Errorf("%s: %s: %s", room.Name, r.Name, r.Error))
+			}
+			status.Sensors = append(status.Sensors, r)
+		}
+		rooms = append(rooms, status)
+	}
+
+	// Temperature and humidity sensors not listed in a room form rooms named after them
+	if !cfg.ManualOnly {
+		discovered := make(map[string]*ClimateRoomStatus)
+		var names []string
+		add := func(name string, r ClimateReading) {
+			room := climateRoomName(name)
+			key := strings.ToLower(room)
+			if slices.ContainsFunc(rooms, func(s ClimateRoomStatus) bool { return strings.EqualFold(s.Name, room) }) {
+				key = strings.ToLower(room + " (" + r.Source + ")")
+				room += " (" + r.Source + ")"
+			}
+			if discovered[key] == nil {
+				discovered[key] = &ClimateRoomStatus{Name: room, Comfort: comfort, Discovered: true, Sensors: []ClimateReading{}}
+				names = append(names, key)
+			}
+			discovered[key].Sensors = append(discovered[key].Sensors, r)
+		}
+		for _, s := range mqttSensors {
+			if mapped["mqtt:"+s.ID] || (s.DeviceClass != ClimateTemperature && s.DeviceClass != ClimateHumidity) {
+				continue
+			}
+			add(s.Name, climateMQTTValue(ClimateSensor{Source: "mqtt"}, s))
+		}
+		for _, st := range states {
+			class := st.Attributes.DeviceClass
+			if mapped["homeassistant:"+st.EntityID] || !strings.HasPrefix(st.EntityID, "sensor.") || (class != ClimateTemperature && class != ClimateHumidity) {
+				continue
+			}
+			r := climateHomeAssistantValue(ClimateSensor{Source: "homeassistant"}, st)
+			if r.Value == nil {
+				continue
+			}
+			add(r.Name, r)
+		}
+		slices.Sort(names)
+		for _, key := range names {
+			rooms = append(rooms, *discovered[key])
+		}
+	}
+	GetModuleHealth().Record("climate", errors.Join(errs...))
+
+	now := time.Now()
+	for i := range rooms {
+		room := &rooms[i]
+		for j := range room.Sensors {
+			r := &room.Sensors[j]
+			// Home Assistant only updates a state when it changes, and marks dead sensors unavailable
+			r.Stale = r.Value != nil && r.Source != "homeassistant" && now.Sub(r.Updated) > staleAfter
+		}
+		room.Temperature = climateMean(room.Sensors, ClimateTemperature)
+		room.Humidity = climateMean(room.Sensors, ClimateHumidity)
+		room.TemperatureLevel = climateLevel(room.Temperature, room.Comfort.TemperatureMin, room.Comfort.TemperatureMax, "cold", "warm")
+		room.HumidityLevel = climateLevel(room.Humidity, room.Comfort.HumidityMin, room.Comfort.HumidityMax, "dry", "humid")
+		room.Comfortable = (room.Temperature != nil || room.Humidity != nil) &&
+			(room.Temperature == nil || room.TemperatureLevel == "comfortable") &&
+			(room.Humidity == nil || room.HumidityLevel == "comfortable")
+	}
+
+	cm.mu.Lock()
+	day := now.Format(time.DateOnly)
+	if day != cm.day {
+		cm.day = day
+		cm.today = make(map[string]*ClimateRange)
+	}
+	for i := range rooms {
+		room := &rooms[i]
+		room.TemperatureToday = cm.record(room.Name+"/"+ClimateTemperature, room.Temperature)
+		room.HumidityToday = cm.record(room.Name+"/"+ClimateHumidity, room.Humidity)
+	}
+	changed := len(rooms) != len(cm.rooms)
+	for i := 0; !changed && i < len(rooms); i++ {
+		old := cm.rooms[i]
+		changed = rooms[i].Name != old.Name || !climateEqual(rooms[i].Temperature, old.Temperature) || !climateEqual(rooms[i].Humidity, old.Humidity)
+	}
+	cm.rooms = rooms
+	cm.updated = now
+	cm.mu.Unlock()
+
+	if changed {
+		GetWSManager().BroadcastTopic(TopicForTimer("climate"), map[string]interface{}{
+			"type":      "refresh",
+			"module":    "climate",
+			"timestamp": now.Unix(),
+		})
+	}
+}
+
+// record widens today's range of a room's metric by value and returns a copy of it. Caller
+// must hold mu.
+func (cm *ClimateMonitor) record(key string, value *float64) *ClimateRange {
+	r := cm.today[key]
+	if value != nil {
+		if r == nil {
+			r = &ClimateRange{Min: *value, Max: *value}
+			cm.today[key] = r
+		}
+		r.Min = min(r.Min, *value)
+		r.Max = max(r.Max, *value)
+	}
+	if r == nil {
+		return nil
+	}
+	result := *r
+	return &result
+}
+
+// climateMQTTReading reads a room's MQTT sensor by id or name.
+func climateMQTTReading(s ClimateSensor, sensors []MQTTSensorValue, mapped map[string]bool) ClimateReading {
+	for _, v := range sensors {
+		if v.ID == s.Sensor || strings.EqualFold(v.Name, s.Sensor) {
+			mapped["mqtt:"+v.ID] = true
+			return climateMQTTValue(s, v)
+		}
+	}
+	return ClimateReading{Name: s.Sensor, Source: "mqtt", Metric: s.Metric, Error: "unknown MQTT sensor"}
+}
+
+// climateMQTTValue converts the value of an MQTT sensor.
+func climateMQTTValue(s ClimateSensor, v MQTTSensorValue) ClimateReading {
+	r := ClimateReading{Name: v.Name, Source: "mqtt", Metric: climateMetric(s.Metric, v.DeviceClass, v.Unit)}
+	if v.Updated == nil {
+		r.Error = "no value received yet"
+		return r
+	}
+	r.Updated = *v.Updated
+	var value float64
+	switch x := v.Value.(type) {
+	case float64:
+		value = x
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		if err != nil {
+			r.Error = "value is not a number"
+			return r
+		}
+		value = f
+	default:
+		r.Error = "value is not a number"
+		return r
+	}
+	return climateValue(r, s, value, v.Unit)
+}
+
+// climateHomeAssistantReading reads a room's Home Assistant entity. unavailable is set when
+// the states could not be fetched.
+func climateHomeAssistantReading(s ClimateSensor, states []climateState, unavailable bool, mapped map[string]bool) ClimateReading {
+	mapped["homeassistant:"+s.Entity] = true
+	if unavailable {
+		return ClimateReading{Name: s.Entity, Source: "homeassistant", Metric: s.Metric, Error: "Home Assistant is unavailable"}
+	}
+	for _, st := range states {
+		if st.EntityID == s.Entity {
+			return climateHomeAssistantValue(s, st)
+		}
+	}
+	return ClimateReading{Name: s.Entity, Source: "homeassistant", Metric: s.Metric, Error: "unknown entity"}
+}
+
+// climateHomeAssistantValue converts the state of a Home Assistant sensor.
+func climateHomeAssistantValue(s ClimateSensor, st climateState) ClimateReading {
+	r := ClimateReading{
+		Name:   st.Attributes.FriendlyName,
+		Source: "homeassistant",
+		Metric: climateMetric(s.Metric, st.Attributes.DeviceClass, st.Attributes.UnitOfMeasurement),
+	}
+	if r.Name == "" {
+		r.Name = st.EntityID
+	}
+	r.Updated, _ = time.Parse(time.RFC3339, st.LastUpdated)
+	value, err := strconv.ParseFloat(st.State, 64)
+	if err != nil {
+		r.Error = "state is " + st.State
+		return r
+	}
+	return climateValue(r, s, value, st.Attributes.UnitOfMeasurement)
+}
+
+// climateSNMPReading reads a room's SNMP sensor.
+func climateSNMPReading(ctx context.Context, s ClimateSensor) ClimateReading {
+	r := ClimateReading{Name: s.Host + " " + s.OID, Source: "snmp", Metric: s.Metric}
+	profile := SNMPProfile{Version: "2c", Community: s.Community}
+	if s.Profile != "" {
+		p, ok := GetSNMPProfiles().Get(s.Profile)
+		if !ok {
+			r.Error = "unknown SNMP profile " + s.Profile
+			return r
+		}
+		profile = p
+	} else if profile.Community == "" {
+		profile.Community = "public"
+	}
+	port := s.Port
+	if port == "" {
+		port = "161"
+	}
+	vars, err := SNMPGet(ctx, s.Host, port, profile, []string{s.OID})
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(vars[0].Value), 64)
+	if err != nil {
+		r.Error = "value is not a number"
+		return r
+	}
+	r.Updated = time.Now()
+	return climateValue(r, s, value, "")
+}
+
+// climateValue scales a raw value, converts Fahrenheit to Celsius and stores it in r.
+func climateValue(r ClimateReading, s ClimateSensor, value float64, unit string) ClimateReading {
+	if r.Metric == "" {
+		r.Error = "metric is unknown, set temperature or humidity"
+		return r
+	}
+	if s.Scale != 0 {
+		value *= s.Scale
+	}
+	if s.Unit != "" {
+		unit = s.Unit
+	}
+	if r.Metric == ClimateTemperature && (unit == "°F" || unit == "F") {
+		value = (value - 32) * 5 / 9
+	}
+	value = math.Round(value*10) / 10
+	r.Value = &value
+	return r
+}
+
+// climateMetric returns the configured metric, else the one implied by the device class
+// or unit.
+func climateMetric(metric, deviceClass, unit string) string {
+	switch {
+	case metric != "":
+		return metric
+	case deviceClass == ClimateTemperature || deviceClass == ClimateHumidity:
+		return deviceClass
+	case unit == "°C" || unit == "°F":
+		return ClimateTemperature
+	case unit == "%":
+		return ClimateHumidity
+	}
+	return ""
+}
+
+// climateRoomName derives a room from a sensor name, e.g. "Kitchen Temperature" is in
+// the kitchen.
+func climateRoomName(name string) string {
+	words := strings.Fields(name)
+	words = slices.DeleteFunc(words, func(w string) bool {
+		switch strings.ToLower(strings.Trim(w, "()-_:")) {
+		case "temperature", "temp", "humidity", "sensor", "":
+			return true
+		}
+		return false
+	})
+	if len(words) == 0 {
+		return name
+	}
+	return strings.Join(words, " ")
+}
+
+// climateMean returns the mean of the fresh readings of a metric, or nil without any.
+func climateMean(readings []ClimateReading, metric string) *float64 {
+	sum, n := 0.0, 0
+	for _, r := range readings {
+		if r.Metric == metric && r.Value != nil && !r.Stale {
+			sum += *r.Value
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	mean := math.Round(sum/float64(n)*10) / 10
+	return &mean
+}
+
+// climateLevel places a value below, within or above a comfort range.
+func climateLevel(value *float64, low, high float64, below, above string) string {
+	switch {
+	case value == nil:
+		return ""
+	case *value < low:
+		return below
+	case *value > high:
+		return above
+	}
+	return "comfortable"
+}
+
+// climateEqual reports whether two optional values are the same.
+func climateEqual(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// fetchHomeAssistantStates reads every entity state from the Home Assistant REST API.
+func fetchHomeAssistantStates(ctx context.Context, cfg ClimateHomeAssistantConfig) ([]climateState, error) {
+	token, err := ResolveSecret(cfg.Token, cfg.TokenFile, cfg.TokenEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(cfg.URL, "/")+"/api/states", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, errors.New("home assistant http status " + res.Status)
+	}
+
+	var states []climateState
+	if err := json.NewDecoder(res.Body).Decode(&states); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
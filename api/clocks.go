@@ -0,0 +1,358 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WorldClockZone is a time zone of the World clock card ("worldClockZones" in storage).
+type WorldClockZone struct {
+	ID       string `json:"id,omitempty"`
+	Label    string `json:"label,omitempty"`
+	TimeZone string `json:"timeZone"`
+}
+
+// ZoneClock is the time in a zone, worked out with the server's zone database.
+type ZoneClock struct {
+	Label         string      `json:"label"`
+	TimeZone      string      `json:"timeZone"`
+	Time          string      `json:"time"` // HH:MM:SS
+	Date          string      `json:"date"` // YYYY-MM-DD
+	Weekday       string      `json:"weekday"`
+	Abbrev        string      `json:"abbrev"` // e.g. "EEST", or "+04" where the zone has none
+	Offset        string      `json:"offset"` // e.g. "UTC+03:00"
+	OffsetSeconds int         `json:"offsetSeconds"`
+	DST           bool        `json:"dst"`
+	DayDiff       int         `json:"dayDiff"` // Calendar days ahead of the reference zone
+	NextChange    *ZoneChange `json:"nextChange,omitempty"`
+	Error         string      `json:"error,omitempty"`
+}
+
+// ZoneChange is the next change of a zone's offset, e.g. the start or end of summer time.
+type ZoneChange struct {
+	At            time.Time `json:"at"`
+	Abbrev        string    `json:"abbrev"`
+	Offset        string    `json:"offset"`
+	OffsetSeconds int       `json:"offsetSeconds"`
+	DST           bool      `json:"dst"`
+}
+
+// Countdown counts down to a date, or to a calendar event ("clockCountdowns" in storage).
+type Countdown struct {
+	ID       string `json:"id,omitempty"`
+	Title    string `json:"title"`
+	Date     string `json:"date,omitempty"`     // YYYY-MM-DD
+	Time     string `json:"time,omitempty"`     // HH:MM, midnight when empty
+	TimeZone string `json:"timeZone,omitempty"` // Zone of the date and time, the reference zone when empty
+	// Event is the ID of a calendar event, or text in the title of one; the countdown is to
+	// its next occurrence
+	Event string `json:"event,omitempty"`
+	// Yearly moves a date that has passed to the same day next year (birthdays, anniversaries)
+	Yearly bool `json:"yearly,omitempty"`
+}
+
+// CountdownStatus is a countdown with the moment it counts down to.
+type CountdownStatus struct {
+	Countdown
+	Target     time.Time `json:"target"`
+	Remaining  int64     `json:"remaining"` // Seconds, negative once passed
+	Days       int       `json:"days"`      // Calendar days until the target date in its zone
+	Passed     bool      `json:"passed"`
+	EventTitle string    `json:"eventTitle,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// ClocksResult is the response of /api/clocks.
+type ClocksResult struct {
+	TimeZone   string            `json:"timeZone"` // Reference zone
+	Now        time.Time         `json:"now"`
+	Local      ZoneClock         `json:"local"`
+	Clocks     []ZoneClock       `json:"clocks"`
+	Countdowns []CountdownStatus `json:"countdowns"`
+}
+
+// BuildClocks works out the clocks of zones and the countdowns relative to the reference
+// zone ref. events are the calendar events countdowns may refer to, in the server's zone.
+func BuildClocks(now time.Time, ref *time.Location, zones []WorldClockZone, countdowns []Countdown, events []CalendarEvent) ClocksResult {
+	result := ClocksResult{
+		TimeZone:   ref.String(),
+		Now:        now.UTC().Truncate(time.Second),
+		Local:      zoneClock(now, ref, ref),
+		Clocks:     []ZoneClock{},
+		Countdowns: []CountdownStatus{},
+	}
+	// The server's own zone is named by its zoneinfo file rather than "Local"
+	if ref == time.Local {
+		result.TimeZone = serverZone()
+		result.Local.TimeZone = result.TimeZone
+	}
+	result.Local.Label = zoneLabel(result.TimeZone)
+	for _, z := range zones {
+		name := strings.TrimSpace(z.TimeZone)
+		loc, err := time.LoadLocation(name)
+		if err != nil || name == "" || name == "Local" {
+			result.Clocks = append(result.Clocks, ZoneClock{Label: z.Label, TimeZone: name, Error: "unknown time zone"})
+			continue
+		}
+		c := zoneClock(now, loc, ref)
+		c.Label = strings.TrimSpace(z.Label)
+		if c.Label == "" {
+			c.Label = zoneLabel(name)
+		}
+		result.Clocks = append(result.Clocks, c)
+	}
+	for _, c := range countdowns {
+		result.Countdowns = append(result.Countdowns, countdownStatus(now, ref, c, events))
+	}
+	return result
+}
+
+// zoneLabel names a zone by its city, e.g. "New York" for America/New_York.
+func zoneLabel(name string) string {
+	return strings.ReplaceAll(name[strings.LastIndex(name, "/")+1:], "_", " ")
+}
+
+// zoneClock returns the time of now in loc.
+func zoneClock(now time.Time, loc, ref *time.Location) ZoneClock {
+	t := now.In(loc)
+	abbrev, offset := t.Zone()
+	c := ZoneClock{
+		TimeZone:      loc.String(),
+		Time:          t.Format("15:04:05"),
+		Date:          t.Format("2006-01-02"),
+		Weekday:       t.Weekday().String(),
+		Abbrev:        abbrev,
+		Offset:        formatUTCOffset(offset),
+		OffsetSeconds: offset,
+		DST:           t.IsDST(),
+		DayDiff:       calendarDaysBetween(now.In(ref), t),
+	}
+	// Zones without transitions (UTC, most of Asia) have no end
+	if _, end := t.ZoneBounds(); !end.IsZero() {
+		next := end.In(loc)
+		nextAbbrev, nextOffset := next.Zone()
+		c.NextChange = &ZoneChange{
+			At:            end.UTC(),
+			Abbrev:        nextAbbrev,
+			Offset:        formatUTCOffset(nextOffset),
+			OffsetSeconds: nextOffset,
+			DST:           next.IsDST(),
+		}
+	}
+	return c
+}
+
+// formatUTCOffset formats an offset in seconds as "UTC+03:00".
+func formatUTCOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("UTC%c%02d:%02d", sign, offset/3600, offset/60%60)
+}
+
+// calendarDaysBetween returns how many calendar days the date of b is after that of a,
+// each in its own zone.
+func calendarDaysBetween(a, b time.Time) int {
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da) / (24 * time.Hour))
+}
+
+// countdownStatus works out the moment a countdown counts down to.
+func countdownStatus(now time.Time, ref *time.Location, c Countdown, events []CalendarEvent) CountdownStatus {
+	s := CountdownStatus{Countdown: c}
+	loc := ref
+	if c.TimeZone != "" {
+		zone, err := time.LoadLocation(c.TimeZone)
+		if err != nil {
+			s.Error = "unknown time zone " + c.TimeZone
+			return s
+		}
+		loc = zone
+	}
+
+	var target time.Time
+	if c.Event != "" {
+		evt, ok := nextCalendarEvent(now, c.Event, events)
+		if !ok {
+			s.Error = "no calendar event matches " + c.Event
+			return s
+		}
+		s.EventTitle = evt.Title
+		if s.Title == "" {
+			s.Title = evt.Title
+		}
+		// Timed events are in the server's zone, all-day events start at midnight where the
+		// countdown is shown
+		if evt.Time == "" {
+			target, _ = time.ParseInLocation("2006-01-02", evt.Date, loc)
+		} else {
+			target, _ = time.ParseInLocation("2006-01-02 15:04", evt.Date+" "+evt.Time, time.Local)
+		}
+	} else {
+		clock := c.Time
+		if clock == "" {
+			clock = "00:00"
+		}
+		t, err := time.ParseInLocation("2006-01-02 15:04", c.Date+" "+clock, loc)
+		if err != nil {
+			s.Error = "invalid date or time"
+			return s
+		}
+		target = t
+		// A date in the past moves to the next year it is ahead again (29 February falls on
+		// 1 March in other years)
+		for years := 1; c.Yearly && !target.After(now); years++ {
+			target = t.AddDate(years, 0, 0)
+		}
+	}
+
+	s.Target = target.UTC()
+	s.Remaining = int64(target.Sub(now) / time.Second)
+	s.Passed = !target.After(now)
+	s.Days = calendarDaysBetween(now.In(loc), target.In(loc))
+	return s
+}
+
+// nextCalendarEvent returns the next occurrence of the event with the ID ref, or else of the
+// first event with ref in its title. Without a future occurrence the latest one is returned.
+func nextCalendarEvent(now time.Time, ref string, events []CalendarEvent) (CalendarEvent, bool) {
+	start := func(e CalendarEvent) time.Time {
+		clock := e.Time
+		if clock == "" {
+			clock = "00:00"
+		}
+		t, _ := time.ParseInLocation("2006-01-02 15:04", e.Date+" "+clock, time.Local)
+		return t
+	}
+	// All-day events count as upcoming for the whole day
+	upcoming := func(e CalendarEvent) bool {
+		if e.Time == "" {
+			return e.Date >= now.In(time.Local).Format("2006-01-02")
+		}
+		return start(e).After(now)
+	}
+	pick := func(match func(CalendarEvent) bool) (CalendarEvent, bool) {
+		var next, last CalendarEvent
+		var haveNext, haveLast bool
+		for _, e := range events {
+			if !match(e) || start(e).IsZero() {
+				continue
+			}
+			if upcoming(e) {
+				if !haveNext || start(e).Before(start(next)) {
+					next, haveNext = e, true
+				}
+			} else if !haveLast || start(e).After(start(last)) {
+				last, haveLast = e, true
+			}
+		}
+		if haveNext {
+			return next, true
+		}
+		return last, haveLast
+	}
+	if e, ok := pick(func(e CalendarEvent) bool { return e.ID == ref }); ok {
+		return e, true
+	}
+	text := strings.ToLower(ref)
+	return pick(func(e CalendarEvent) bool { return strings.Contains(strings.ToLower(e.Title), text) })
+}
+
+// Known time zone names, read once from the zone database.
+var (
+	timeZoneNamesOnce sync.Once
+	timeZoneNames     []string
+)
+
+// TimeZoneNames lists the IANA zone names of the server's zone database, so clients do not
+// depend on the zones their browser knows. It is empty when the database cannot be listed.
+func TimeZoneNames() []string {
+	timeZoneNamesOnce.Do(func() {
+		seen := make(map[string]bool)
+		add := func(name string, header []byte) {
+			// Skip the posix/right copies and files that are not zone data (zone.tab, ...)
+			if strings.HasPrefix(name, "posix/") || strings.HasPrefix(name, "right/") || !bytes.HasPrefix(header, []byte("TZif")) {
+				return
+			}
+			// Top-level names are legacy aliases (EST5EDT, GB, ...) apart from UTC
+			if (!strings.Contains(name, "/") && name != "UTC") || name[0] < 'A' || name[0] > 'Z' {
+				return
+			}
+			seen[name] = true
+		}
+		for _, dir := range zoneinfoSources() {
+			if strings.HasSuffix(dir, ".zip") {
+				readZoneZip(dir, add)
+				continue
+			}
+			filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return nil
+				}
+				name, _ := filepath.Rel(dir, path)
+				header := make([]byte, 4)
+				if f, err := os.Open(path); err == nil {
+					f.Read(header)
+					f.Close()
+				}
+				add(filepath.ToSlash(name), header)
+				return nil
+			})
+			if len(seen) > 0 {
+				break
+			}
+		}
+		timeZoneNames = make([]string, 0, len(seen))
+		for name := range seen {
+			timeZoneNames = append(timeZoneNames, name)
+		}
+		slices.Sort(timeZoneNames)
+	})
+	return timeZoneNames
+}
+
+// zoneinfoSources returns where the zone database may be, in the order Go looks: $ZONEINFO
+// (a directory or a zoneinfo.zip) and the system directories.
+func zoneinfoSources() []string {
+	var sources []string
+	if env := os.Getenv("ZONEINFO"); env != "" {
+		sources = append(sources, env)
+	}
+	if runtime.GOOS != "windows" {
+		sources = append(sources, "/usr/share/zoneinfo", "/usr/share/lib/zoneinfo", "/usr/lib/locale/TZ", "/etc/zoneinfo")
+	}
+	return sources
+}
+
+// readZoneZip passes the zones of a zoneinfo.zip to add.
+func readZoneZip(path string, add func(name string, header []byte)) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			continue
+		}
+		header := make([]byte, 4)
+		rc.Read(header)
+		rc.Close()
+		add(f.Name, header)
+	}
+}
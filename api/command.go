@@ -0,0 +1,303 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// commandOutputLimit caps the output kept of one run; the rest is dropped.
+const commandOutputLimit = 64 << 10
+
+// commandNamePattern matches command names, the last part of /api/command/{name}.
+var commandNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Errors returned when a command cannot be run.
+var (
+	ErrCommandNotFound = errors.New("unknown command")
+	ErrCommandRunning  = errors.New("command is already running")
+)
+
+// CommandConfig configures the commands of the Commands card behind /api/command. Only
+// these commands can be run; clients name them but never pass arguments.
+type CommandConfig struct {
+	Commands []CommandDef `json:"commands"`
+}
+
+// CommandDef is one command the server may run. It is run without a shell, so pipes and
+// variables need ["sh", "-c", "..."].
+type CommandDef struct {
+	Name     string   `json:"name"`               // Lowercase letters, digits, - and _, e.g. "zpool"
+	Title    string   `json:"title,omitempty"`    // Display name, default: the name
+	Command  []string `json:"command"`            // Program and arguments, e.g. ["zpool", "status", "-x"]
+	Dir      string   `json:"dir,omitempty"`      // Working directory, default: the server's
+	Interval string   `json:"interval,omitempty"` // Run on a timer, e.g. "5m"; without it only on demand
+	Timeout  string   `json:"timeout,omitempty"`  // Default: 10s
+}
+
+// Validate checks the configured commands.
+func (c CommandConfig) Validate() error {
+	if len(c.Commands) == 0 {
+		return fmt.Errorf("command: at least one command is required")
+	}
+	seen := make(map[string]bool)
+	for i, d := range c.Commands {
+		if !commandNamePattern.MatchString(d.Name) {
+			return fmt.Errorf("command: commands[%d]: name must be lowercase letters, digits, - and _", i)
+		}
+		if seen[d.Name] {
+			return fmt.Errorf("command: commands[%d]: duplicate name %s", i, d.Name)
+		}
+		seen[d.Name] = true
+		if len(d.Command) == 0 || d.Command[0] == "" {
+			return fmt.Errorf("command: commands[%d]: command is required", i)
+		}
+		if d.Interval != "" {
+			if iv, err := time.ParseDuration(d.Interval); err != nil || iv < 10*time.Second {
+				return fmt.Errorf("command: commands[%d]: interval must be a duration of at least 10s", i)
+			}
+		}
+		if d.Timeout != "" {
+			if t, err := time.ParseDuration(d.Timeout); err != nil || t <= 0 || t > 10*time.Minute {
+				return fmt.Errorf("command: commands[%d]: timeout must be a duration of at most 10m", i)
+			}
+		}
+	}
+	return nil
+}
+
+func (d CommandDef) title() string {
+	if d.Title != "" {
+		return d.Title
+	}
+	return d.Name
+}
+
+func (d CommandDef) interval() time.Duration {
+	iv, _ := time.ParseDuration(d.Interval)
+	return iv
+}
+
+func (d CommandDef) timeout() time.Duration {
+	if t, err := time.ParseDuration(d.Timeout); err == nil && t > 0 {
+		return t
+	}
+	return 10 * time.Second
+}
+
+// CommandResult is a command with the output of its last run. ExitCode is -1 when the
+// command could not be started or was killed at its timeout.
+type CommandResult struct {
+	Name      string    `json:"name"`
+	Title     string    `json:"title"`
+	Interval  int64     `json:"interval,omitempty"` // Seconds between timed runs
+	Running   bool      `json:"running"`
+	Output    string    `json:"output"` // Standard output and error, interleaved
+	Truncated bool      `json:"truncated,omitempty"`
+	ExitCode  int       `json:"exitCode"`
+	Error     string    `json:"error,omitempty"`
+	Duration  float64   `json:"durationMs"`
+	Ran       time.Time `json:"ran,omitzero"`
+}
+
+// CommandRunner runs the configured commands on their timers and on demand and keeps the
+// result of each last run.
+type CommandRunner struct {
+	mu      sync.Mutex
+	config  *CommandConfig
+	results map[string]CommandResult
+	running map[string]bool
+}
+
+// Global command runner instance
+var commandRunner = &CommandRunner{results: make(map[string]CommandResult), running: make(map[string]bool)}
+
+// GetCommandRunner returns the global command runner instance.
+func GetCommandRunner() *CommandRunner {
+	return commandRunner
+}
+
+// Configure sets the commands that may be run.
+func (cr *CommandRunner) Configure(cfg CommandConfig) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.config = &cfg
+	cr.results = make(map[string]CommandResult)
+}
+
+// Enabled reports whether any command is configured.
+func (cr *CommandRunner) Enabled() bool {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	return cr.config != nil && len(cr.config.Commands) > 0
+}
+
+// Start runs the commands that have an interval, each on its own timer.
+func (cr *CommandRunner) Start() {
+	cr.mu.Lock()
+	cfg := cr.config
+	cr.mu.Unlock()
+	if cfg == nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range cfg.Commands {
+		if d.Interval == "" {
+			continue
+		}
+		wg.Go(func() {
+			run := func() {
+				if _, err := cr.Run(context.Background(), d.Name); err != nil && !errors.Is(err, ErrCommandRunning) {
+					Logger("command").Warn("timed run failed", "command", d.Name, "error", err)
+				}
+			}
+			guardLoop("command", run)
+			ticker := time.NewTicker(d.interval())
+			defer ticker.Stop()
+			for range ticker.C {
+				guardLoop("command", run)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// Results returns every command with its last result, in config order.
+func (cr *CommandRunner) Results() []CommandResult {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.config == nil {
+		return []CommandResult{}
+	}
+	results := make([]CommandResult, 0, len(cr.config.Commands))
+	for _, d := range cr.config.Commands {
+		results = append(results, cr.resultLocked(d))
+	}
+	return results
+}
+
+// Result returns one command with its last result.
+func (cr *CommandRunner) Result(name string) (CommandResult, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	d, ok := cr.findLocked(name)
+	if !ok {
+		return CommandResult{}, ErrCommandNotFound
+	}
+	return cr.resultLocked(d), nil
+}
+
+// Run runs a command now and returns its result. A command runs once at a time.
+func (cr *CommandRunner) Run(ctx context.Context, name string) (CommandResult, error) {
+	cr.mu.Lock()
+	d, ok := cr.findLocked(name)
+	if !ok {
+		cr.mu.Unlock()
+		return CommandResult{}, ErrCommandNotFound
+	}
+	if cr.running[name] {
+		cr.mu.Unlock()
+		return CommandResult{}, ErrCommandRunning
+	}
+	cr.running[name] = true
+	cr.mu.Unlock()
+
+	result := runCommand(ctx, d)
+
+	cr.mu.Lock()
+	delete(cr.running, name)
+	cr.results[name] = result
+	var errs []error
+	for _, r := range cr.results {
+		if r.Error != "" {
+			errs = append(errs, fmt.Errorf("%s: %s", r.Name, r.Error))
+		}
+	}
+	result = cr.resultLocked(d)
+	cr.mu.Unlock()
+
+	GetModuleHealth().Record("command", errors.Join(errs...))
+	GetWSManager().BroadcastTopic(TopicForTimer("command"), map[string]interface{}{
+		"type":      "refresh",
+		"module":    "command",
+		"timestamp": time.Now().Unix(),
+	})
+	return result, nil
+}
+
+func (cr *CommandRunner) findLocked(name string) (CommandDef, bool) {
+	if cr.config != nil {
+		for _, d := range cr.config.Commands {
+			if d.Name == name {
+				return d, true
+			}
+		}
+	}
+	return CommandDef{}, false
+}
+
+// resultLocked returns the last result of a command with its current settings.
+func (cr *CommandRunner) resultLocked(d CommandDef) CommandResult {
+	r := cr.results[d.Name]
+	r.Name, r.Title = d.Name, d.title()
+	r.Interval = int64(d.interval().Seconds())
+	r.Running = cr.running[d.Name]
+	return r
+}
+
+// runCommand runs a command with its timeout and captures its output.
+func runCommand(ctx context.Context, d CommandDef) CommandResult {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout())
+	defer cancel()
+
+	var out commandOutput
+	cmd := exec.CommandContext(ctx, d.Command[0], d.Command[1:]...)
+	cmd.Dir = d.Dir
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	// Children left holding the output open (sh -c) do not keep the run going
+	cmd.WaitDelay = 2 * time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	result := CommandResult{
+		Output:    string(out.buf),
+		Truncated: out.truncated,
+		Duration:  float64(time.Since(start).Microseconds()) / 1000,
+		Ran:       start,
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.ExitCode = -1
+		result.Error = "timed out after " + d.timeout().String()
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		result.Error = exitErr.Error()
+	case err != nil:
+		result.ExitCode = -1
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// commandOutput keeps the first commandOutputLimit bytes written to it.
+type commandOutput struct {
+	buf       []byte
+	truncated bool
+}
+
+func (o *commandOutput) Write(p []byte) (int, error) {
+	if room := commandOutputLimit - len(o.buf); room < len(p) {
+		o.buf = append(o.buf, p[:max(room, 0)]...)
+		o.truncated = true
+	} else {
+		o.buf = append(o.buf, p...)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,391 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConfigLintWarning is a problem found in the stored configuration.
+type ConfigLintWarning struct {
+	Severity string `json:"severity"`       // warning or info
+	Check    string `json:"check"`          // e.g. "monitor-self"
+	Key      string `json:"key"`            // Storage key the problem is in
+	Item     string `json:"item,omitempty"` // Name of the affected entry
+	Message  string `json:"message"`
+	Fix      string `json:"fix"` // What to change
+}
+
+// lintIntervalLimit is the shortest sensible refresh interval of a module.
+type lintIntervalLimit struct {
+	seconds int64
+	reason  string
+}
+
+// lintIntervalLimits are the refresh intervals below which a module wastes requests or
+// runs into rate limits. Other modules with a timer are checked against lintMinInterval.
+var lintIntervalLimits = map[string]lintIntervalLimit{
+	"network":    {300, "public IP lookups are rate limited by the IP services"},
+	"weather":    {600, "weather providers update every 10-15 minutes and rate limit API keys"},
+	"github":     {300, "GitHub allows 60 requests an hour without a token"},
+	"rss":        {120, "most feeds update at most every few minutes and may block frequent polling"},
+	"speedplane": {60, "speed tests take tens of seconds"},
+	"guestwifi":  {60, "the guest network rarely changes"},
+	"virt":       {15, "hypervisor status is cached for 15 seconds"},
+	"monitoring": {10, "every check runs against all monitors"},
+	"snmp":       {10, "SNMP agents on small devices are slow to answer"},
+}
+
+// lintMinInterval is the shortest sensible refresh interval of any module.
+const lintMinInterval = 5
+
+// lintMinMonitorInterval is the shortest sensible monitor check interval in seconds.
+const lintMinMonitorInterval = 10
+
+// lintMonitor is the part of a stored monitor the linter reads.
+type lintMonitor struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"` // http, port or ping
+	URL  string `json:"url,omitempty"`
+	Host string `json:"host,omitempty"`
+	Port any    `json:"port,omitempty"`
+}
+
+// target returns the host and port a monitor connects to, and a normalized description
+// of what it checks.
+func (m lintMonitor) target() (host, port, norm string) {
+	switch m.Type {
+	case "port":
+		host = strings.ToLower(m.Host)
+		port = strings.TrimSuffix(fmt.Sprint(m.Port), ".0")
+		return host, port, "port:" + net.JoinHostPort(host, port)
+	case "ping":
+		host = strings.ToLower(m.Host)
+		return host, "", "ping:" + host
+	}
+	u, err := url.Parse(strings.TrimSpace(m.URL))
+	if err != nil || u.Host == "" {
+		return "", "", "http:" + m.URL
+	}
+	host, port = strings.ToLower(u.Hostname()), u.Port()
+	if port == "" {
+		port = "80"
+		if strings.EqualFold(u.Scheme, "https") {
+			port = "443"
+		}
+	}
+	return host, port, "http:" + lintNormalizeURL(m.URL)
+}
+
+// lintQuickLink is the part of a stored quick link the linter reads.
+type lintQuickLink struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// lintNormalizeURL lowercases the scheme and host and drops default ports and trailing
+// slashes, so equivalent URLs compare equal.
+func lintNormalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return strings.TrimRight(strings.TrimSpace(raw), "/")
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(scheme == "http" && port == "80") && !(scheme == "https" && port == "443") {
+		host = net.JoinHostPort(host, port)
+	}
+	norm := scheme + "://" + host + strings.TrimRight(u.EscapedPath(), "/")
+	if u.RawQuery != "" {
+		norm += "?" + u.RawQuery
+	}
+	return norm
+}
+
+// lintSelf describes the addresses the dashboard itself is reachable on.
+type lintSelf struct {
+	hosts       map[string]bool
+	port        string
+	requestHost string // Host:port the linting request came in on, e.g. through a proxy
+}
+
+// newLintSelf collects the loopback and interface addresses and host name of this machine.
+func newLintSelf(listenAddr, requestHost string) lintSelf {
+	self := lintSelf{hosts: map[string]bool{"localhost": true}, requestHost: strings.ToLower(requestHost)}
+	listenHost, port, err := net.SplitHostPort(listenAddr)
+	if err == nil {
+		self.port = port
+		if listenHost != "" && listenHost != "0.0.0.0" && listenHost != "::" {
+			self.hosts[strings.ToLower(listenHost)] = true
+		}
+	}
+	if name, err := os.Hostname(); err == nil {
+		self.hosts[strings.ToLower(name)] = true
+	}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok {
+				self.hosts[ipnet.IP.String()] = true
+			}
+		}
+	}
+	return self
+}
+
+// matches reports whether host:port is the dashboard itself.
+func (s lintSelf) matches(host, port string) bool {
+	if host == "" || port == "" {
+		return false
+	}
+	if s.requestHost != "" {
+		rh, rp, err := net.SplitHostPort(s.requestHost)
+		if err != nil {
+			rh = s.requestHost
+		}
+		if host == strings.Trim(rh, "[]") && (port == rp || (rp == "" && (port == "80" || port == "443"))) {
+			return true
+		}
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return port == s.port
+	}
+	return s.hosts[host] && port == s.port
+}
+
+// LintConfig checks the stored configuration of a profile for common problems: monitors
+// pointing at the dashboard itself, duplicate monitors and quick links, unreachable ICS
+// calendars and refresh intervals below safe limits.
+func LintConfig(ctx context.Context, profile, listenAddr, requestHost string) []ConfigLintWarning {
+	warnings := []ConfigLintWarning{}
+	self := newLintSelf(listenAddr, requestHost)
+
+	var monitors []lintMonitor
+	lintGet(profile, "monitors", &monitors)
+	seenMonitors := make(map[string]string)
+	for _, m := range monitors {
+		host, port, norm := m.target()
+		name := m.Name
+		if name == "" {
+			name = m.ID
+		}
+		if self.matches(host, port) {
+			warnings = append(warnings, ConfigLintWarning{
+				Severity: "warning",
+				Check:    "monitor-self",
+				Key:      "monitors",
+				Item:     name,
+				Message:  "Monitor checks the dashboard itself, so it can never report the dashboard being down",
+				Fix:      "Point the monitor at the service you want to watch, or use an external uptime checker for the dashboard",
+			})
+		}
+		if first, ok := seenMonitors[norm]; ok {
+			warnings = append(warnings, ConfigLintWarning{
+				Severity: "info",
+				Check:    "monitor-duplicate",
+				Key:      "monitors",
+				Item:     name,
+				Message:  "Monitor checks the same target as " + first,
+				Fix:      "Remove one of the two monitors",
+			})
+		} else {
+			seenMonitors[norm] = name
+		}
+	}
+
+	var links []lintQuickLink
+	if !lintGet(profile, "quicklinks", &links) {
+		lintGet(profile, "quickLinks", &links)
+	}
+	seenLinks := make(map[string]string)
+	for _, l := range links {
+		norm := lintNormalizeURL(l.URL)
+		if norm == "" {
+			continue
+		}
+		if first, ok := seenLinks[norm]; ok {
+			warnings = append(warnings, ConfigLintWarning{
+				Severity: "warning",
+				Check:    "quicklink-duplicate",
+				Key:      "quicklinks",
+				Item:     l.Title,
+				Message:  "Quick link opens the same URL as " + first,
+				Fix:      "Remove the duplicate quick link",
+			})
+		} else {
+			seenLinks[norm] = l.Title
+		}
+	}
+
+	warnings = append(warnings, lintICSCalendars(ctx, profile)...)
+	warnings = append(warnings, lintIntervals(profile)...)
+
+	severity := map[string]int{"warning": 0, "info": 1}
+	sort.SliceStable(warnings, func(i, j int) bool {
+		if severity[warnings[i].Severity] != severity[warnings[j].Severity] {
+			return severity[warnings[i].Severity] < severity[warnings[j].Severity]
+		}
+		return warnings[i].Key < warnings[j].Key
+	})
+	return warnings
+}
+
+// lintICSCalendars fetches every enabled ICS calendar and reports the unreachable ones.
+func lintICSCalendars(ctx context.Context, profile string) []ConfigLintWarning {
+	var calendars []ICSCalendar
+	lintGet(profile, icsCalendarsKey, &calendars)
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		warnings []ConfigLintWarning
+	)
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	for _, cal := range calendars {
+		if !cal.Enabled || cal.URL == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(cal ICSCalendar) {
+			defer wg.Done()
+			content, err := FetchICSCalendar(ctx, cal.URL)
+			w := ConfigLintWarning{
+				Severity: "warning",
+				Check:    "ics-unreachable",
+				Key:      icsCalendarsKey,
+				Item:     cal.Name,
+			}
+			switch {
+			case err != nil:
+				w.Message = "Calendar cannot be fetched: " + err.Error()
+				w.Fix = "Check the URL (a secret calendar address may have been reset) or disable the calendar"
+			case !strings.Contains(content, "BEGIN:VCALENDAR"):
+				w.Message = "Calendar URL does not return an iCalendar file"
+				w.Fix = "Use the calendar's ICS or iCal export address, not its web page"
+			default:
+				return
+			}
+			mu.Lock()
+			warnings = append(warnings, w)
+			mu.Unlock()
+		}(cal)
+	}
+	wg.Wait()
+	return warnings
+}
+
+// lintIntervals reports module refresh and monitor check intervals below safe limits.
+func lintIntervals(profile string) []ConfigLintWarning {
+	var warnings []ConfigLintWarning
+	metadata := GetModuleMetadata()
+
+	var prefs map[string]struct {
+		Enabled  *bool    `json:"enabled"`
+		Interval *float64 `json:"interval"`
+	}
+	lintGet(profile, "modulePrefs", &prefs)
+	keys := make([]string, 0, len(prefs))
+	for key := range prefs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		pref := prefs[key]
+		meta, ok := metadata[key]
+		if !ok || !meta.HasTimer || pref.Interval == nil || (pref.Enabled != nil && !*pref.Enabled) {
+			continue
+		}
+		interval := int64(*pref.Interval)
+		limit, ok := lintIntervalLimits[key]
+		if !ok {
+			limit = lintIntervalLimit{lintMinInterval, "refreshing more often only adds load"}
+		}
+		if interval >= limit.seconds {
+			continue
+		}
+		warnings = append(warnings, ConfigLintWarning{
+			Severity: "warning",
+			Check:    "interval-too-short",
+			Key:      "modulePrefs",
+			Item:     meta.Name,
+			Message:  fmt.Sprintf("Refreshes every %ds: %s", interval, limit.reason),
+			Fix:      fmt.Sprintf("Set the refresh interval to at least %ds", limit.seconds),
+		})
+	}
+
+	var monitorInterval, monitorTimeout float64
+	hasInterval := lintGetNumber(profile, "monitorInterval", &monitorInterval)
+	if hasInterval && monitorInterval < lintMinMonitorInterval {
+		warnings = append(warnings, ConfigLintWarning{
+			Severity: "warning",
+			Check:    "interval-too-short",
+			Key:      "monitorInterval",
+			Message:  fmt.Sprintf("Monitors are checked every %.0fs, which can trip rate limits and intrusion detection on the checked services", monitorInterval),
+			Fix:      fmt.Sprintf("Check monitors at most every %ds", lintMinMonitorInterval),
+		})
+	}
+	if hasInterval && lintGetNumber(profile, "monitorTimeoutSeconds", &monitorTimeout) && monitorTimeout >= monitorInterval {
+		warnings = append(warnings, ConfigLintWarning{
+			Severity: "warning",
+			Check:    "monitor-timeout",
+			Key:      "monitorTimeoutSeconds",
+			Message:  fmt.Sprintf("Monitor timeout (%.0fs) is not shorter than the check interval (%.0fs), so slow checks overlap", monitorTimeout, monitorInterval),
+			Fix:      "Lower the monitor timeout below the check interval",
+		})
+	}
+
+	var icsTTL float64
+	if lintGetNumber(profile, "icsCacheTTL", &icsTTL) && icsTTL < 5 {
+		warnings = append(warnings, ConfigLintWarning{
+			Severity: "info",
+			Check:    "interval-too-short",
+			Key:      "icsCacheTTL",
+			Message:  fmt.Sprintf("ICS calendars are fetched again after %.0f minutes; providers publish changes with a delay anyway", icsTTL),
+			Fix:      "Cache ICS calendars for at least 5 minutes",
+		})
+	}
+	return warnings
+}
+
+// lintGet decodes a stored value of a profile into v.
+func lintGet(profile, key string, v any) bool {
+	item, ok := GetStorage().GetForProfile(profile, key)
+	if !ok {
+		return false
+	}
+	data, err := json.Marshal(item.Value)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// lintGetNumber reads a stored number, which the dashboard saves as a number or string.
+func lintGetNumber(profile, key string, n *float64) bool {
+	item, ok := GetStorage().GetForProfile(profile, key)
+	if !ok {
+		return false
+	}
+	switch v := item.Value.(type) {
+	case float64:
+		*n = v
+		return true
+	case string:
+		f, err := strconv.ParseFloat(strings.Trim(v, `"`), 64)
+		if err == nil {
+			*n = f
+		}
+		return err == nil
+	}
+	return false
+}
@@ -0,0 +1,342 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults of the internet connectivity check.
+const (
+	defaultConnectivityInterval = 30 * time.Second
+	connectivityDialTimeout     = 3 * time.Second
+	// A failed fetch checks the connection again when the last check is older
+	connectivityRecheck = 10 * time.Second
+	// Responses kept to show while offline, and the largest one kept
+	offlineCacheMax   = 200
+	offlineCacheLimit = 2 << 20
+)
+
+// defaultConnectivityTargets are dialed to tell whether the internet is reachable. They
+// are names, so a failing DNS resolver counts as offline as well.
+var defaultConnectivityTargets = []string{"one.one.one.one:443", "dns.google:443", "dns.quad9.net:443"}
+
+// ConnectivityConfig configures the internet connectivity check.
+type ConnectivityConfig struct {
+	Targets  []string `json:"targets,omitempty"`  // host:port dialed over TCP, default the Cloudflare, Google and Quad9 resolvers on 443
+	Interval string   `json:"interval,omitempty"` // Check interval, default "30s"
+	Disabled bool     `json:"disabled,omitempty"` // Never report offline, show fetch errors as they are
+}
+
+// Validate checks the targets and interval.
+func (c ConnectivityConfig) Validate() error {
+	for _, target := range c.Targets {
+		if host, port, err := net.SplitHostPort(target); err != nil || host == "" || port == "" {
+			return fmt.Errorf("connectivity: target %q must be host:port", target)
+		}
+	}
+	if c.Interval != "" {
+		if d, err := time.ParseDuration(c.Interval); err != nil || d < 5*time.Second {
+			return fmt.Errorf("connectivity: interval must be a duration of at least 5s")
+		}
+	}
+	return nil
+}
+
+// ConnectivityStatus tells whether the internet is reachable.
+type ConnectivityStatus struct {
+	Enabled    bool       `json:"enabled"`
+	Online     bool       `json:"online"`
+	Since      *time.Time `json:"since,omitempty"` // When the current state began
+	Checked    *time.Time `json:"checked,omitempty"`
+	LastOnline *time.Time `json:"lastOnline,omitempty"`
+	Error      string     `json:"error,omitempty"` // Why the last check failed
+}
+
+// offlineEntry is the last good response of a module request.
+type offlineEntry struct {
+	body    []byte
+	fetched time.Time
+}
+
+// ConnectivityMonitor checks the internet connection and keeps the last good response of
+// the external modules to show while it is down.
+type ConnectivityMonitor struct {
+	mu       sync.Mutex
+	config   ConnectivityConfig
+	status   ConnectivityStatus
+	checking chan struct{} // Closed when the running check ends
+	cache    map[string]offlineEntry
+}
+
+// Global connectivity monitor instance; online until a check says otherwise
+var connectivityMonitor = &ConnectivityMonitor{
+	status: ConnectivityStatus{Enabled: true, Online: true},
+	cache:  make(map[string]offlineEntry),
+}
+
+// GetConnectivity returns the global connectivity monitor instance.
+func GetConnectivity() *ConnectivityMonitor {
+	return connectivityMonitor
+}
+
+// Configure replaces the targets and interval.
+func (cm *ConnectivityMonitor) Configure(cfg ConnectivityConfig) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.config = cfg
+	cm.status.Enabled = !cfg.Disabled
+	if cfg.Disabled {
+		cm.status.Online = true
+	}
+}
+
+// Start checks the connection until the process exits.
+func (cm *ConnectivityMonitor) Start() {
+	cm.mu.Lock()
+	cfg := cm.config
+	cm.mu.Unlock()
+	if cfg.Disabled {
+		return
+	}
+	interval := defaultConnectivityInterval
+	if d, err := time.ParseDuration(cfg.Interval); err == nil && d > 0 {
+		interval = d
+	}
+	for {
+		cm.Check(context.Background())
+		time.Sleep(interval)
+	}
+}
+
+// Status returns the result of the last check.
+func (cm *ConnectivityMonitor) Status() ConnectivityStatus {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.status
+}
+
+// Check dials the targets and updates the status; any target answering means online.
+// Concurrent callers share one check.
+func (cm *ConnectivityMonitor) Check(ctx context.Context) ConnectivityStatus {
+	cm.mu.Lock()
+	if cm.config.Disabled {
+		defer cm.mu.Unlock()
+		return cm.status
+	}
+	if wait := cm.checking; wait != nil {
+		cm.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+		}
+		return cm.Status()
+	}
+	done := make(chan struct{})
+	cm.checking = done
+	targets := cm.config.Targets
+	cm.mu.Unlock()
+	if len(targets) == 0 {
+		targets = defaultConnectivityTargets
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), connectivityDialTimeout)
+	defer cancel()
+	results := make(chan error, len(targets))
+	dialer := &net.Dialer{}
+	for _, target := range targets {
+		go func() {
+			conn, err := dialer.DialContext(ctx, "tcp", target)
+			if err == nil {
+				conn.Close()
+			}
+			results <- err
+		}()
+	}
+	var lastErr error
+	online := false
+	for range targets {
+		if err := <-results; err == nil {
+			online = true
+			cancel()
+		} else if !online {
+			lastErr = err
+		}
+	}
+
+	now := time.Now()
+	cm.mu.Lock()
+	changed := cm.status.Online != online
+	cm.status.Checked = &now
+	cm.status.Online = online
+	cm.status.Error = ""
+	if online {
+		cm.status.LastOnline = &now
+	} else if lastErr != nil {
+		cm.status.Error = lastErr.Error()
+	}
+	if changed || cm.status.Since == nil {
+		cm.status.Since = &now
+	}
+	status := cm.status
+	cm.checking = nil
+	close(done)
+	cm.mu.Unlock()
+
+	if changed {
+		if online {
+			Logger("connectivity").Info("internet connection restored")
+		} else {
+			Logger("connectivity").Warn("internet connection lost", "error", status.Error)
+		}
+		GetWSManager().Broadcast(map[string]interface{}{
+			"type":         "connectivity",
+			"connectivity": status,
+		})
+	}
+	return status
+}
+
+// offline tells whether the internet is down, checking again when the last check is old.
+func (cm *ConnectivityMonitor) offline(ctx context.Context) bool {
+	status := cm.Status()
+	if !status.Enabled {
+		return false
+	}
+	if status.Checked == nil || time.Since(*status.Checked) >= connectivityRecheck {
+		status = cm.Check(ctx)
+	}
+	return !status.Online
+}
+
+// remember keeps a good response.
+func (cm *ConnectivityMonitor) remember(key string, body []byte) {
+	if len(body) > offlineCacheLimit {
+		return
+	}
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if _, ok := cm.cache[key]; !ok && len(cm.cache) >= offlineCacheMax {
+		oldest := ""
+		for k, e := range cm.cache {
+			if oldest == "" || e.fetched.Before(cm.cache[oldest].fetched) {
+				oldest = k
+			}
+		}
+		delete(cm.cache, oldest)
+	}
+	cm.cache[key] = offlineEntry{body: bytes.Clone(body), fetched: time.Now()}
+}
+
+// cached returns the last good response of a request.
+func (cm *ConnectivityMonitor) cached(key string) (offlineEntry, bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	e, ok := cm.cache[key]
+	return e, ok
+}
+
+// offlineRecorder holds a response back until it is known whether it failed.
+type offlineRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (or *offlineRecorder) Header() http.Header {
+	return or.header
+}
+
+func (or *offlineRecorder) WriteHeader(status int) {
+	if or.status == 0 {
+		or.status = status
+	}
+}
+
+func (or *offlineRecorder) Write(p []byte) (int, error) {
+	if or.status == 0 {
+		or.status = http.StatusOK
+	}
+	return or.body.Write(p)
+}
+
+// flush sends the held response.
+func (or *offlineRecorder) flush(w http.ResponseWriter) {
+	for k, v := range or.header {
+		w.Header()[k] = v
+	}
+	if or.status != 0 {
+		w.WriteHeader(or.status)
+	}
+	_, _ = w.Write(or.body.Bytes())
+}
+
+// responseFailed reports whether a JSON response holds an "error", at the top or in one of
+// its objects (as the public address of /api/ip does).
+func responseFailed(body []byte) bool {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return false
+	}
+	var resp moduleErrorResponse
+	for name, raw := range fields {
+		if name == "error" {
+			var msg string
+			if json.Unmarshal(raw, &msg) == nil && msg != "" {
+				return true
+			}
+		} else if json.Unmarshal(raw, &resp) == nil && resp.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// OfflineCached serves the last good response of a module's endpoint, marked with
+// "offline" and "cachedAt", when a request fails because the internet is down. Without
+// one the failed response is sent marked "offline", so clients can say so instead of
+// showing the fetch error.
+func OfflineCached(module string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cm := GetConnectivity()
+		if !cm.Status().Enabled {
+			next(w, r)
+			return
+		}
+		rec := &offlineRecorder{header: make(http.Header)}
+		next(rec, r)
+		// Weather responses differ with the client's units
+		cl := ClientLocaleFromRequest(r)
+		key := module + " " + r.URL.RequestURI() + " " + cl.Units + " " + cl.Temperature + " " + cl.Wind
+		failed := rec.status >= http.StatusInternalServerError || responseFailed(rec.body.Bytes())
+		if !failed {
+			if rec.status == 0 || rec.status == http.StatusOK {
+				cm.remember(key, rec.body.Bytes())
+			}
+			rec.flush(w)
+			return
+		}
+		if !cm.offline(r.Context()) {
+			rec.flush(w)
+			return
+		}
+
+		var fields map[string]json.RawMessage
+		e, ok := cm.cached(key)
+		if ok && json.Unmarshal(e.body, &fields) == nil {
+			fields["cachedAt"], _ = json.Marshal(e.fetched)
+			RecordTiming(r.Context(), module, 0, TimingHit)
+		} else if json.Unmarshal(rec.body.Bytes(), &fields) != nil {
+			rec.flush(w)
+			return
+		}
+		fields["offline"] = json.RawMessage("true")
+		GetDebugLogger().Logf("connectivity", "offline, %s answered from cache: %v", key, ok)
+		WriteJSON(w, fields)
+	}
+}
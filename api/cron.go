@@ -0,0 +1,278 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMacros are the shorthands accepted for common schedules.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronMonthNames and cronDayNames name the values of the month and day-of-week fields.
+var (
+	cronMonthNames = []string{"", "January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+	cronDayNames   = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+)
+
+// cronField describes one of the five fields of a cron expression.
+type cronField struct {
+	name     string
+	unit     string // Plural used for steps, e.g. "every 5 minutes"
+	min, max int
+	names    []string // Full names, matched by their first three letters
+}
+
+var cronFields = []cronField{
+	{name: "minute", unit: "minutes", min: 0, max: 59},
+	{name: "hour", unit: "hours", min: 0, max: 23},
+	{name: "day of month", unit: "days", min: 1, max: 31},
+	{name: "month", unit: "months", min: 1, max: 12, names: cronMonthNames},
+	{name: "day of week", unit: "days", min: 0, max: 7, names: cronDayNames},
+}
+
+// CronSchedule is a parsed five-field cron expression: minute, hour, day of month, month
+// and day of week.
+type CronSchedule struct {
+	Expression string
+	fields     [5]string
+	sets       [5]uint64 // Bit n is set when value n matches
+}
+
+// ParseCron parses a cron expression of five fields or one of the @yearly, @monthly,
+// @weekly, @daily and @hourly macros. Fields accept *, lists, ranges, steps and, for months
+// and days of the week, names such as JAN or MON-FRI.
+func ParseCron(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	spec := expr
+	if strings.HasPrefix(spec, "@") {
+		m, ok := cronMacros[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("unknown macro %s", spec)
+		}
+		spec = m
+	}
+	parts := strings.Fields(spec)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("expected 5 fields (minute hour day-of-month month day-of-week), got %d", len(parts))
+	}
+	s := &CronSchedule{Expression: expr}
+	for i, part := range parts {
+		set, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cronFields[i].name, err)
+		}
+		s.fields[i] = part
+		s.sets[i] = set
+	}
+	// Sunday is both 0 and 7
+	if s.sets[4]&(1<<7) != 0 {
+		s.sets[4] |= 1
+	}
+	return s, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps.
+func parseCronField(field string, f cronField) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", item)
+			}
+			rangePart, step = item[:i], n
+		}
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*":
+			if f.max == 7 {
+				hi = 6
+			}
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = cronValue(a, f); err != nil {
+				return 0, err
+			}
+			if hi, err = cronValue(b, f); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("range %q is backwards", rangePart)
+			}
+		default:
+			v, err := cronValue(rangePart, f)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// "5/15" means from 5 to the end in steps of 15
+			if step == 1 {
+				hi = v
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// cronValue parses a number or, for months and days of the week, a three-letter name.
+func cronValue(s string, f cronField) (int, error) {
+	for i, name := range f.names {
+		if len(name) >= 3 && strings.EqualFold(s, name[:3]) {
+			return i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%q is not between %d and %d", s, f.min, f.max)
+	}
+	return n, nil
+}
+
+// matchesDay reports whether a day matches the day-of-month and day-of-week fields. When
+// both are restricted a day matching either runs, as in Vixie cron.
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	dom := s.sets[2]&(1<<t.Day()) != 0
+	dow := s.sets[4]&(1<<int(t.Weekday())) != 0
+	if strings.HasPrefix(s.fields[2], "*") || strings.HasPrefix(s.fields[4], "*") {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first time after t the schedule runs, in t's location.
+func (s *CronSchedule) Next(t time.Time) (time.Time, error) {
+	loc := t.Location()
+	after := t
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Schedules such as February 30 never run; give up after five years
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.sets[3]&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.sets[1]&(1<<t.Hour()) == 0:
+			next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			if !next.After(t) {
+				next = t.Add(time.Hour)
+			}
+			t = next
+		case s.sets[0]&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		case t.Format("2006-01-02 15:04") == after.Format("2006-01-02 15:04"):
+			// The same wall clock time again as clocks go back; it already ran
+			t = t.Add(time.Minute)
+		default:
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("the schedule never runs")
+}
+
+// Describe explains the schedule in English, e.g. "At 09:30 on Monday through Friday".
+func (s *CronSchedule) Describe() string {
+	minute, hour := s.fields[0], s.fields[1]
+	var b strings.Builder
+	_, minErr := strconv.Atoi(minute)
+	_, hourErr := strconv.Atoi(hour)
+	switch {
+	case minErr == nil && hourErr == nil:
+		m, _ := strconv.Atoi(minute)
+		h, _ := strconv.Atoi(hour)
+		fmt.Fprintf(&b, "At %02d:%02d", h, m)
+	default:
+		text := describeCronField(minute, cronFields[0])
+		if strings.HasPrefix(text, "every ") {
+			b.WriteString("E" + text[1:])
+		} else {
+			b.WriteString("At minute " + text)
+		}
+		text = describeCronField(hour, cronFields[1])
+		switch {
+		case hour == "*":
+			if !strings.HasPrefix(minute, "*") {
+				b.WriteString(" past every hour")
+			}
+		case strings.HasPrefix(text, "every "):
+			b.WriteString(" past " + text)
+		default:
+			b.WriteString(" past hour " + text)
+		}
+	}
+
+	dom, month, dow := s.fields[2], s.fields[3], s.fields[4]
+	if dom != "*" {
+		if text := describeCronField(dom, cronFields[2]); strings.HasPrefix(text, "every ") {
+			b.WriteString(" " + text)
+		} else {
+			fmt.Fprintf(&b, " on day %s of the month", text)
+		}
+	}
+	if dow != "*" {
+		// Either day runs when both are restricted
+		if !strings.HasPrefix(dom, "*") {
+			b.WriteString(" or")
+		}
+		fmt.Fprintf(&b, " on %s", describeCronField(dow, cronFields[4]))
+	}
+	if month != "*" {
+		fmt.Fprintf(&b, " in %s", describeCronField(month, cronFields[3]))
+	}
+	return b.String()
+}
+
+// describeCronField explains a field, e.g. "1-5" of the days of the week as "Monday
+// through Friday".
+func describeCronField(field string, f cronField) string {
+	var items []string
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step, hasStep := strings.Cut(item, "/")
+		var text string
+		if a, b, ok := strings.Cut(rangePart, "-"); ok {
+			text = cronValueName(a, f) + " through " + cronValueName(b, f)
+		} else if rangePart != "*" {
+			text = cronValueName(rangePart, f)
+		}
+		switch {
+		case hasStep && text == "":
+			text = "every " + step + " " + f.unit
+		case hasStep:
+			text = "every " + step + " " + f.unit + " from " + text
+		case text == "":
+			text = "every " + f.name
+		}
+		items = append(items, text)
+	}
+	if len(items) == 1 {
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+// cronValueName returns the name of a month or day of the week, or the value itself.
+func cronValueName(s string, f cronField) string {
+	if f.names == nil {
+		return s
+	}
+	if v, err := cronValue(s, f); err == nil {
+		return f.names[v]
+	}
+	return s
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the exchange rates.
+const (
+	defaultCurrencyURL  = "https://api.frankfurter.dev/v1"
+	defaultCurrencyBase = "EUR"
+	// The reference rates change once a working day
+	currencyTTL = 24 * time.Hour
+	// A failed fetch is not repeated sooner
+	currencyRetry = 15 * time.Minute
+)
+
+var (
+	defaultCurrencyTargets = []string{"USD", "GBP"}
+	currencyCodePattern    = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// CurrencyConfig sets the base currency and targets of /api/currency.
+type CurrencyConfig struct {
+	Base    string   `json:"base,omitempty"`    // Default "EUR"
+	Targets []string `json:"targets,omitempty"` // Default ["USD", "GBP"]
+	URL     string   `json:"url,omitempty"`     // Frankfurter-compatible API, default "https://api.frankfurter.dev/v1"
+}
+
+// Validate checks the currency codes and API URL.
+func (c CurrencyConfig) Validate() error {
+	for _, code := range append([]string{cmp.Or(c.Base, defaultCurrencyBase)}, c.Targets...) {
+		if !currencyCodePattern.MatchString(code) {
+			return fmt.Errorf("currency: %q is not an uppercase ISO 4217 code such as \"EUR\"", code)
+		}
+	}
+	if c.URL != "" {
+		if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("currency: url must be an http(s) URL")
+		}
+	}
+	return nil
+}
+
+// ExchangeRates is the answer of /api/currency: what one unit of the base buys.
+type ExchangeRates struct {
+	Base    string             `json:"base"`
+	Date    string             `json:"date,omitempty"` // Day the rates were published, e.g. "2026-10-14"
+	Rates   map[string]float64 `json:"rates"`
+	Fetched time.Time          `json:"fetched,omitzero"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// Conversion is the answer of /api/currency/convert.
+type Conversion struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Amount float64 `json:"amount"`
+	Rate   float64 `json:"rate"`
+	Result float64 `json:"result"`
+	Date   string  `json:"date,omitempty"`
+}
+
+// currencyCacheEntry is every rate of one base currency.
+type currencyCacheEntry struct {
+	date    string
+	rates   map[string]float64
+	fetched time.Time
+	tried   time.Time
+	err     error
+}
+
+// CurrencyService fetches the exchange rates of a base currency once a day.
+type CurrencyService struct {
+	mu     sync.Mutex
+	config CurrencyConfig
+	cache  map[string]*currencyCacheEntry
+}
+
+// Global currency service instance
+var currencyService = &CurrencyService{cache: make(map[string]*currencyCacheEntry)}
+
+// GetCurrencyService returns the global currency service instance.
+func GetCurrencyService() *CurrencyService {
+	return currencyService
+}
+
+// Configure sets the base currency, targets and API.
+func (cs *CurrencyService) Configure(cfg CurrencyConfig) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cfg.URL != cs.config.URL {
+		clear(cs.cache)
+	}
+	cs.config = cfg
+}
+
+// Defaults returns the configured base currency and targets.
+func (cs *CurrencyService) Defaults() (string, []string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	targets := cs.config.Targets
+	if len(targets) == 0 {
+		targets = defaultCurrencyTargets
+	}
+	return cmp.Or(cs.config.Base, defaultCurrencyBase), slices.Clone(targets)
+}
+
+// Rates returns what one unit of base buys in each target. All rates of the base are
+// cached for a day; when a fetch fails the last rates are kept.
+func (cs *CurrencyService) Rates(ctx context.Context, base string, targets []string) ExchangeRates {
+	e := cs.entry(ctx, base)
+	result := ExchangeRates{Base: base, Date: e.date, Rates: make(map[string]float64, len(targets)), Fetched: e.fetched}
+	if e.err != nil {
+		result.Error = e.err.Error()
+	}
+	if e.rates == nil {
+		return result
+	}
+	var unknown []string
+	for _, t := range targets {
+		if rate, ok := e.rates[t]; ok {
+			result.Rates[t] = rate
+		} else {
+			unknown = append(unknown, t)
+		}
+	}
+	if len(unknown) > 0 && result.Error == "" {
+		result.Error = "no rate for " + strings.Join(unknown, ", ")
+	}
+	return result
+}
+
+// Convert converts amount from one currency to another at the day's rate.
+func (cs *CurrencyService) Convert(ctx context.Context, from, to string, amount float64) (Conversion, error) {
+	conv := Conversion{From: from, To: to, Amount: amount, Rate: 1}
+	if from != to {
+		e := cs.entry(ctx, from)
+		rate, ok := e.rates[to]
+		if !ok {
+			if e.err != nil {
+				return conv, e.err
+			}
+			return conv, fmt.Errorf("no rate from %s to %s", from, to)
+		}
+		conv.Rate, conv.Date = rate, e.date
+	}
+	conv.Result = math.Round(amount*conv.Rate*10000) / 10000
+	return conv, nil
+}
+
+// entry returns a copy of the cached rates of base, fetching them when a day old.
+func (cs *CurrencyService) entry(ctx context.Context, base string) currencyCacheEntry {
+	cs.mu.Lock()
+	e, ok := cs.cache[base]
+	if !ok {
+		e = &currencyCacheEntry{}
+		cs.cache[base] = e
+	}
+	stale := time.Since(e.fetched) >= currencyTTL && time.Since(e.tried) >= currencyRetry
+	apiURL := cmp.Or(cs.config.URL, defaultCurrencyURL)
+	if !stale {
+		RecordTiming(ctx, "currency", 0, TimingHit)
+		defer cs.mu.Unlock()
+		return *e
+	}
+	e.tried = time.Now()
+	cs.mu.Unlock()
+
+	done := StartTiming(ctx, "currency")
+	date, rates, err := fetchExchangeRates(ctx, apiURL, base)
+	done(TimingMiss)
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	e.err = err
+	if err == nil {
+		e.date, e.rates, e.fetched = date, rates, time.Now()
+	} else {
+		GetDebugLogger().Logf("currency", "fetching %s rates failed: %v", base, err)
+	}
+	return *e
+}
+
+// fetchExchangeRates reads the latest rates of base from a Frankfurter-compatible API.
+func fetchExchangeRates(ctx context.Context, apiURL, base string) (string, map[string]float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(apiURL, "/")+"/latest?base="+url.QueryEscape(base), nil)
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var data struct {
+		Date    string             `json:"date"`
+		Rates   map[string]float64 `json:"rates"`
+		Message string             `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil && resp.StatusCode == http.StatusOK {
+		return "", nil, fmt.Errorf("invalid exchange rates: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if data.Message != "" {
+			return "", nil, fmt.Errorf("exchange rates: %s", data.Message)
+		}
+		return "", nil, fmt.Errorf("exchange rates: HTTP %s", resp.Status)
+	}
+	if data.Rates == nil {
+		return "", nil, fmt.Errorf("exchange rates: no rates for %s", base)
+	}
+	return data.Date, data.Rates, nil
+}
@@ -1,20 +1,24 @@
 package api
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 	"sync"
 )
 
 // DebugLogger provides debug logging controlled by user preferences
 type DebugLogger struct {
-	mu    sync.RWMutex
-	prefs map[string]bool
+	mu      sync.RWMutex
+	prefs   map[string]bool
+	modules map[string]bool // Enabled in the config file (logging.modules)
 }
 
 // NewDebugLogger creates a new debug logger
 func NewDebugLogger() *DebugLogger {
 	return &DebugLogger{
-		prefs: make(map[string]bool),
+		prefs:   make(map[string]bool),
+		modules: make(map[string]bool),
 	}
 }
 
@@ -47,17 +51,28 @@ func (dl *DebugLogger) UpdatePrefs() {
 	dl.mu.Unlock()
 }
 
+// SetModules enables debug logging for modules regardless of the preferences
+func (dl *DebugLogger) SetModules(modules []string) {
+	dl.mu.Lock()
+	dl.modules = make(map[string]bool)
+	for _, m := range modules {
+		dl.modules[m] = true
+	}
+	dl.mu.Unlock()
+}
+
 // IsEnabled checks if debug logging is enabled for a module
 func (dl *DebugLogger) IsEnabled(module string) bool {
 	dl.mu.RLock()
 	defer dl.mu.RUnlock()
-	return dl.prefs[module] == true
+	return dl.prefs[module] || dl.modules[module]
 }
 
-// Logf logs a formatted debug message if the module is enabled
+// Logf logs a formatted debug message if the module is enabled (or the log level is debug)
 func (dl *DebugLogger) Logf(module string, format string, args ...interface{}) {
-	if dl.IsEnabled(module) {
-		log.Printf("[%s] "+format, append([]interface{}{module}, args...)...)
+	logger := Logger(module)
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		logger.Debug(fmt.Sprintf(format, args...))
 	}
 }
 
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// digestDiskWarnPercent is the disk usage at which a mount is listed in the digest.
+const digestDiskWarnPercent = 90.0
+
+// DigestConfig configures the daily digest.
+type DigestConfig struct {
+	Time     string   `json:"time"`               // HH:MM, local time
+	Channels []string `json:"channels,omitempty"` // Alert channels to deliver to (all if empty)
+}
+
+// Validate checks the digest time.
+func (c DigestConfig) Validate() error {
+	if c.Time == "" {
+		return nil
+	}
+	if _, err := parseClock(c.Time); err != nil {
+		return fmt.Errorf("digest: %w", err)
+	}
+	return nil
+}
+
+// Digest is a daily summary of the dashboard.
+type Digest struct {
+	Date         string          `json:"date"`
+	Location     string          `json:"location,omitempty"`
+	Weather      string          `json:"weather,omitempty"`
+	Events       []CalendarEvent `json:"events"`
+	Todos        []Todo          `json:"todos"`
+	Incidents    []TimelineEvent `json:"incidents"`
+	DiskWarnings []string        `json:"diskWarnings"`
+}
+
+// BuildDigest collects today's weather, events, due todos, monitor incidents of the
+// last 24 hours and disk warnings.
+func BuildDigest(ctx context.Context, weather WeatherConfig) Digest {
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	d := Digest{
+		Date:         today,
+		Events:       []CalendarEvent{},
+		Todos:        []Todo{},
+		DiskWarnings: []string{},
+	}
+	storage := GetStorage()
+
+	lat, lon, name := SavedWeatherLocation(weather)
+	d.Location = name
+	if weather.Enabled && lat != "" && lon != "" {
+		if wd, err := FetchWeather(ctx, weather, lat, lon); err != nil {
+			d.Weather = "unavailable (" + err.Error() + ")"
+		} else if wd.Today != nil {
+			d.Weather = fmt.Sprintf("%s, %.0f–%.0f%s", wd.Today.IconDescription, wd.Today.TempMin, wd.Today.TempMax, wd.Today.TempUnit)
+			if wd.Today.PrecipitationProb > 0 {
+				d.Weather += fmt.Sprintf(", %.0f%% chance of rain", wd.Today.PrecipitationProb)
+			}
+		} else {
+			d.Weather = wd.Summary
+		}
+	}
+
+	var events []CalendarEvent
+	if storage.GetAs("calendarEvents", &events) {
+		d.Events = append(d.Events, GetEventsForDate(events, today)...)
+	}
+
+	var todos []Todo
+	if storage.GetAs("todos", &todos) {
+		for _, t := range todos {
+			if !t.Completed && t.DueDate != "" && t.DueDate <= today {
+				d.Todos = append(d.Todos, t)
+			}
+		}
+	}
+
+	d.Incidents = GetTimeline().Events(now.Add(-24*time.Hour), []string{TimelineSourceMonitor, TimelineSourceIncident}, 0)
+
+	if partitions, err := disk.PartitionsWithContext(ctx, false); err == nil {
+		for _, p := range partitions {
+			if usage, err := disk.UsageWithContext(ctx, p.Mountpoint); err == nil && usage.UsedPercent >= digestDiskWarnPercent {
+				d.DiskWarnings = append(d.DiskWarnings, fmt.Sprintf("%s is %.0f%% full (%s free)", p.Mountpoint, usage.UsedPercent, FormatBytes(usage.Free)))
+			}
+		}
+	}
+
+	return d
+}
+
+// Text renders the digest as plain text.
+func (d Digest) Text() string {
+	var b strings.Builder
+	if d.Weather != "" {
+		if d.Location != "" {
+			fmt.Fprintf(&b, "Weather in %s: %s\n", d.Location, d.Weather)
+		} else {
+			fmt.Fprintf(&b, "Weather: %s\n", d.Weather)
+		}
+	}
+
+	if len(d.Events) > 0 {
+		b.WriteString("\nToday's events:\n")
+		for _, e := range d.Events {
+			if e.Time != "" {
+				fmt.Fprintf(&b, "- %s %s\n", e.Time, e.Title)
+			} else {
+				fmt.Fprintf(&b, "- %s\n", e.Title)
+			}
+		}
+	}
+
+	if len(d.Todos) > 0 {
+		b.WriteString("\nTodos due:\n")
+		for _, t := range d.Todos {
+			if t.DueDate < d.Date {
+				fmt.Fprintf(&b, "- %s (overdue since %s)\n", t.Title, t.DueDate)
+			} else {
+				fmt.Fprintf(&b, "- %s\n", t.Title)
+			}
+		}
+	}
+
+	if len(d.Incidents) > 0 {
+		b.WriteString("\nIncidents in the last 24 hours:\n")
+		for _, ev := range d.Incidents {
+			fmt.Fprintf(&b, "- %s %s\n", ev.Time.Format("15:04"), ev.Title)
+		}
+	}
+
+	if len(d.DiskWarnings) > 0 {
+		b.WriteString("\nDisk warnings:\n")
+		for _, w := range d.DiskWarnings {
+			fmt.Fprintf(&b, "- %s\n", w)
+		}
+	}
+
+	if b.Len() == 0 {
+		return "Nothing to report today."
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Alert converts the digest into an alert.
+func (d Digest) Alert() Alert {
+	severity := "info"
+	if len(d.DiskWarnings) > 0 || len(d.Incidents) > 0 {
+		severity = "warning"
+	}
+	return Alert{
+		Title:    "Daily digest for " + d.Date,
+		Body:     d.Text(),
+		Severity: severity,
+		URL:      "/",
+		Tag:      "daily-digest",
+	}
+}
+
+// DigestScheduler sends the daily digest at the configured time.
+type DigestScheduler struct {
+	mu       sync.Mutex
+	config   DigestConfig
+	weather  WeatherConfig
+	lastSent string // Date of the last scheduled digest
+}
+
+// Global digest scheduler instance
+var digestScheduler = &DigestScheduler{}
+
+// GetDigestScheduler returns the global digest scheduler instance.
+func GetDigestScheduler() *DigestScheduler {
+	return digestScheduler
+}
+
+// Configure sets the digest schedule and the weather provider used to build it.
+func (ds *DigestScheduler) Configure(cfg DigestConfig, weather WeatherConfig) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.config = cfg
+	ds.weather = weather
+}
+
+// Config returns the digest schedule.
+func (ds *DigestScheduler) Config() DigestConfig {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.config
+}
+
+// Start checks every minute whether the digest is due.
+func (ds *DigestScheduler) Start() {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		ds.mu.Lock()
+		due := ds.config.Time != "" && now.Format("15:04") == ds.config.Time && ds.lastSent != now.Format("2006-01-02")
+		if due {
+			ds.lastSent = now.Format("2006-01-02")
+		}
+		ds.mu.Unlock()
+
+		if due {
+			ds.Send(context.Background())
+		}
+	}
+}
+
+// Build creates today's digest.
+func (ds *DigestScheduler) Build(ctx context.Context) Digest {
+	ds.mu.Lock()
+	weather := ds.weather
+	ds.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	return BuildDigest(ctx, weather)
+}
+
+// Send builds the digest and dispatches it to the configured alert channels.
+func (ds *DigestScheduler) Send(ctx context.Context) Digest {
+	d := ds.Build(ctx)
+	GetAlertManager().DispatchTo(ds.Config().Channels, d.Alert())
+	GetDebugLogger().Logf("digest", "daily digest sent for %s", d.Date)
+	return d
+}
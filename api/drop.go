@@ -0,0 +1,365 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Defaults of the drop behind /api/drop.
+const (
+	defaultDropDir       = "drops"
+	defaultDropTTL       = 24 * time.Hour
+	defaultDropMaxSizeMB = 25
+	defaultDropMaxTotal  = 250
+	maxDropTextSize      = 64 << 10
+	maxDropTTL           = 30 * 24 * time.Hour
+	// dropIndexFile lists the drops in the drop directory
+	dropIndexFile = "index.json"
+)
+
+// ErrDropNotFound is returned when a drop ID is not in the drop, or it expired.
+var ErrDropNotFound = errors.New("drop not found or expired")
+
+// DropConfig sets where the drop keeps text and files and how long and large they may be.
+type DropConfig struct {
+	Dir        string `json:"dir,omitempty"`        // Directory of the dropped files, default "drops"
+	TTL        string `json:"ttl,omitempty"`        // How long a drop is kept unless the sender asks for less, default "24h"
+	MaxSizeMB  int    `json:"maxSizeMB,omitempty"`  // Largest file, default 25
+	MaxTotalMB int    `json:"maxTotalMB,omitempty"` // All drops together, default 250; the oldest are removed to make room
+	Disabled   bool   `json:"disabled,omitempty"`
+}
+
+// Validate checks the TTL and sizes.
+func (c DropConfig) Validate() error {
+	if c.TTL != "" {
+		d, err := ParseHistoryRange(c.TTL)
+		if err != nil || d < time.Minute || d > maxDropTTL {
+			return fmt.Errorf("drop: ttl must be a duration between 1m and 30d")
+		}
+	}
+	if c.MaxSizeMB < 0 || c.MaxTotalMB < 0 {
+		return fmt.Errorf("drop: maxSizeMB and maxTotalMB cannot be negative")
+	}
+	if c.MaxSizeMB > 0 && c.MaxTotalMB > 0 && c.MaxSizeMB > c.MaxTotalMB {
+		return fmt.Errorf("drop: maxSizeMB cannot be larger than maxTotalMB")
+	}
+	return nil
+}
+
+// Drop is a text or file shared through the drop.
+type Drop struct {
+	ID          string    `json:"id"`
+	Kind        string    `json:"kind"` // "text" or "file"
+	Name        string    `json:"name,omitempty"`
+	ContentType string    `json:"contentType,omitempty"`
+	Size        int64     `json:"size"`
+	SizeText    string    `json:"sizeFormatted"`
+	Text        string    `json:"text,omitempty"`   // Content of a text drop
+	Device      string    `json:"device,omitempty"` // Device it was sent from, e.g. "Firefox on Android"
+	Created     time.Time `json:"created"`
+	Expires     time.Time `json:"expires"`
+}
+
+// DropStatus is the limits of the drop and what it holds.
+type DropStatus struct {
+	Enabled    bool   `json:"enabled"`
+	MaxSize    int64  `json:"maxSize"` // Bytes
+	MaxTotal   int64  `json:"maxTotal"`
+	MaxTextLen int    `json:"maxText"`
+	TTL        int64  `json:"ttl"` // Seconds
+	Used       int64  `json:"used"`
+	UsedText   string `json:"usedFormatted"`
+	Count      int    `json:"count"`
+}
+
+// DropStore keeps the drops, a LAN clipboard shared by every device. Text is kept in the
+// index and files beside it, until they expire.
+type DropStore struct {
+	mu       sync.Mutex
+	dir      string
+	ttl      time.Duration
+	maxSize  int64
+	maxTotal int64
+	disabled bool
+	drops    []Drop
+	loaded   bool
+}
+
+// Global drop store instance
+var dropStore = &DropStore{dir: defaultDropDir, ttl: defaultDropTTL, maxSize: defaultDropMaxSizeMB << 20, maxTotal: defaultDropMaxTotal << 20}
+
+// GetDropStore returns the global drop store instance.
+func GetDropStore() *DropStore {
+	return dropStore
+}
+
+// Configure sets the directory and limits.
+func (ds *DropStore) Configure(cfg DropConfig) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if cfg.Dir != "" {
+		ds.dir = cfg.Dir
+	}
+	if d, err := ParseHistoryRange(cfg.TTL); err == nil && d > 0 {
+		ds.ttl = d
+	}
+	if cfg.MaxSizeMB > 0 {
+		ds.maxSize = int64(cfg.MaxSizeMB) << 20
+	}
+	if cfg.MaxTotalMB > 0 {
+		ds.maxTotal = int64(cfg.MaxTotalMB) << 20
+	}
+	ds.disabled = cfg.Disabled
+	ds.loaded = false
+}
+
+// Enabled reports whether the drop is on.
+func (ds *DropStore) Enabled() bool {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return !ds.disabled
+}
+
+// load reads the index. Caller must hold mu.
+func (ds *DropStore) load() {
+	if ds.loaded {
+		return
+	}
+	ds.loaded = true
+	ds.drops = nil
+	data, err := os.ReadFile(filepath.Join(ds.dir, dropIndexFile))
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &ds.drops); err != nil {
+		GetDebugLogger().Logf("drop", "failed to parse %s: %v", dropIndexFile, err)
+		ds.drops = nil
+	}
+}
+
+// save writes the index. Caller must hold mu.
+func (ds *DropStore) save() error {
+	if err := os.MkdirAll(ds.dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(ds.drops)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(ds.dir, dropIndexFile), data, 0600)
+}
+
+// filePath returns where a file drop's content is kept. IDs are hex, so they are safe
+// as file names.
+func (ds *DropStore) filePath(id string) string {
+	return filepath.Join(ds.dir, id)
+}
+
+// Status returns the limits and current use.
+func (ds *DropStore) Status() DropStatus {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.load()
+	status := DropStatus{Enabled: !ds.disabled, MaxSize: ds.maxSize, MaxTotal: ds.maxTotal, MaxTextLen: maxDropTextSize, TTL: int64(ds.ttl / time.Second)}
+	now := time.Now()
+	for _, d := range ds.drops {
+		if d.Expires.After(now) {
+			status.Used += d.Size
+			status.Count++
+		}
+	}
+	status.UsedText = FormatBytes(uint64(status.Used))
+	return status
+}
+
+// List returns the drops not yet expired, newest first.
+func (ds *DropStore) List() []Drop {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.load()
+	now := time.Now()
+	drops := []Drop{}
+	for _, d := range ds.drops {
+		if d.Expires.After(now) {
+			drops = append(drops, d)
+		}
+	}
+	sort.Slice(drops, func(i, j int) bool { return drops[i].Created.After(drops[j].Created) })
+	return drops
+}
+
+// Get returns a drop and, for a file, the path of its content.
+func (ds *DropStore) Get(id string) (Drop, string, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.load()
+	i := slices.IndexFunc(ds.drops, func(d Drop) bool { return d.ID == id })
+	if id == "" || i < 0 || !ds.drops[i].Expires.After(time.Now()) {
+		return Drop{}, "", ErrDropNotFound
+	}
+	d := ds.drops[i]
+	if d.Kind == "file" {
+		return d, ds.filePath(d.ID), nil
+	}
+	return d, "", nil
+}
+
+// expiry returns when a new drop expires: after ttl when it is shorter than the
+// configured TTL, which it is capped at.
+func (ds *DropStore) expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 || ttl > ds.ttl {
+		ttl = ds.ttl
+	}
+	return time.Now().UTC().Add(ttl).Truncate(time.Second)
+}
+
+// AddText stores a text drop.
+func (ds *DropStore) AddText(text string, ttl time.Duration, device string) (Drop, error) {
+	if strings.TrimSpace(text) == "" {
+		return Drop{}, errors.New("the text is empty")
+	}
+	if len(text) > maxDropTextSize {
+		return Drop{}, fmt.Errorf("text is larger than %d KB; send it as a file", maxDropTextSize>>10)
+	}
+	if !utf8.ValidString(text) {
+		return Drop{}, errors.New("the text is not valid UTF-8; send it as a file")
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.load()
+	d := Drop{ID: newStoreID(), Kind: "text", Size: int64(len(text)), Text: text, Device: device, Created: time.Now().UTC().Truncate(time.Second)}
+	d.Expires = ds.expiry(ttl)
+	return ds.add(d)
+}
+
+// AddFile stores a file drop. content must hold at most the largest file size plus one
+// byte, so a file that is too large is detected.
+func (ds *DropStore) AddFile(name, contentType string, content []byte, ttl time.Duration, device string) (Drop, error) {
+	name = filepath.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	if name == "" || name == "." || name == "/" {
+		name = "file"
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.load()
+	if int64(len(content)) > ds.maxSize {
+		return Drop{}, fmt.Errorf("files can be up to %d MB", ds.maxSize>>20)
+	}
+	d := Drop{ID: newStoreID(), Kind: "file", Name: name, ContentType: contentType, Size: int64(len(content)), Device: device, Created: time.Now().UTC().Truncate(time.Second)}
+	d.Expires = ds.expiry(ttl)
+	if err := os.MkdirAll(ds.dir, 0700); err != nil {
+		return Drop{}, err
+	}
+	if err := os.WriteFile(ds.filePath(d.ID), content, 0600); err != nil {
+		return Drop{}, err
+	}
+	return ds.add(d)
+}
+
+// add stores a drop, removing expired drops and then the oldest until the total fits.
+// Caller must hold mu.
+func (ds *DropStore) add(d Drop) (Drop, error) {
+	ds.removeExpired(time.Now())
+	total := d.Size
+	for _, other := range ds.drops {
+		total += other.Size
+	}
+	for total > ds.maxTotal && len(ds.drops) > 0 {
+		total -= ds.drops[0].Size
+		ds.remove(0)
+	}
+	d.SizeText = FormatBytes(uint64(d.Size))
+	ds.drops = append(ds.drops, d)
+	if err := ds.save(); err != nil {
+		return Drop{}, err
+	}
+	notifyDrop("add", d)
+	return d, nil
+}
+
+// Delete removes a drop by ID.
+func (ds *DropStore) Delete(id string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.load()
+	i := slices.IndexFunc(ds.drops, func(d Drop) bool { return d.ID == id })
+	if id == "" || i < 0 {
+		return ErrDropNotFound
+	}
+	ds.remove(i)
+	if err := ds.save(); err != nil {
+		return err
+	}
+	notifyDrop("delete", Drop{ID: id})
+	return nil
+}
+
+// remove drops the i-th drop and its file. Caller must hold mu.
+func (ds *DropStore) remove(i int) {
+	if ds.drops[i].Kind == "file" {
+		if err := os.Remove(ds.filePath(ds.drops[i].ID)); err != nil && !os.IsNotExist(err) {
+			GetDebugLogger().Logf("drop", "failed to remove %s: %v", ds.drops[i].ID, err)
+		}
+	}
+	ds.drops = slices.Delete(ds.drops, i, i+1)
+}
+
+// removeExpired removes the drops expired at now and returns how many. Caller must hold mu.
+func (ds *DropStore) removeExpired(now time.Time) int {
+	removed := 0
+	for i := len(ds.drops) - 1; i >= 0; i-- {
+		if !ds.drops[i].Expires.After(now) {
+			ds.remove(i)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Prune removes the expired drops and their files, and returns how many were removed.
+func (ds *DropStore) Prune() int {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.load()
+	removed := ds.removeExpired(time.Now())
+	if removed > 0 {
+		if err := ds.save(); err != nil {
+			GetDebugLogger().Logf("drop", "failed to save %s: %v", dropIndexFile, err)
+		}
+	}
+	return removed
+}
+
+// Start removes expired drops every minute, so they do not wait for the hourly retention job.
+func (ds *DropStore) Start() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		if ds.Enabled() {
+			guardLoop("drop", func() { ds.Prune() })
+		}
+	}
+}
+
+// notifyDrop tells every open tab that a drop was added or deleted. Text is left out, as
+// it may be long; tabs fetch the list again.
+func notifyDrop(action string, d Drop) {
+	d.Text = ""
+	GetWSManager().Broadcast(map[string]interface{}{
+		"type":   "drop",
+		"action": action,
+		"drop":   d,
+	})
+}
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EinkModules are the sections of the e-ink view, in display order.
+var EinkModules = []string{"clock", "weather", "calendar", "todo", "monitors", "system"}
+
+// Limits of the e-ink view: refresh interval in seconds and list lengths.
+const (
+	einkDefaultRefresh = 300
+	einkMinRefresh     = 60
+	einkEventDays      = 3
+	einkMaxItems       = 6
+)
+
+// EinkOptions selects the sections, refresh interval and page size of the e-ink view.
+type EinkOptions struct {
+	Modules []string
+	Refresh int // Seconds between meta refreshes
+	Width   int // Fixed page size in pixels (0 = fill the screen)
+	Height  int
+	Invert  bool // White on black, for displays that ghost less that way
+}
+
+// ParseEinkOptions reads ?modules=, ?refresh=, ?w=, ?h= and ?invert= from a query.
+func ParseEinkOptions(get func(string) string) (EinkOptions, error) {
+	opts := EinkOptions{Modules: EinkModules, Refresh: einkDefaultRefresh}
+	if m := get("modules"); m != "" {
+		opts.Modules = nil
+		for _, name := range strings.Split(m, ",") {
+			name = strings.TrimSpace(name)
+			if !slices.Contains(EinkModules, name) {
+				return opts, fmt.Errorf("unknown module %q (available: %s)", name, strings.Join(EinkModules, ", "))
+			}
+			opts.Modules = append(opts.Modules, name)
+		}
+	}
+	if v := get("refresh"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return opts, fmt.Errorf("invalid refresh: %q is not a number of seconds", v)
+		}
+		opts.Refresh = max(n, einkMinRefresh)
+	}
+	for _, dim := range []struct {
+		key string
+		dst *int
+	}{{"w", &opts.Width}, {"h", &opts.Height}} {
+		if v := get(dim.key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 200 || n > 4000 {
+				return opts, fmt.Errorf("invalid %s: must be between 200 and 4000 pixels", dim.key)
+			}
+			*dim.dst = n
+		}
+	}
+	opts.Invert = get("invert") == "1" || get("invert") == "true"
+	return opts, nil
+}
+
+// EinkDay groups the events of one day.
+type EinkDay struct {
+	Label  string
+	Events []CalendarEvent
+}
+
+// EinkView is the data of the e-ink page, composed from the same sources as the
+// weather, calendar, todo, monitor and system modules.
+type EinkView struct {
+	EinkOptions
+	Now          time.Time
+	Location     string
+	Weather      *WeatherData
+	WeatherError string
+	Days         []EinkDay
+	Todos        []Todo
+	MoreTodos    int
+	Monitors     []MonitorState
+	MonitorsDown int
+	System       SystemMetrics
+}
+
+// Show reports whether a section is enabled.
+func (v EinkView) Show(module string) bool {
+	return slices.Contains(v.Modules, module)
+}
+
+// BuildEinkView collects the data of the enabled sections.
+func BuildEinkView(ctx context.Context, weather WeatherConfig, opts EinkOptions) EinkView {
+	now := time.Now()
+	v := EinkView{EinkOptions: opts, Now: now}
+	storage := GetStorage()
+
+	if v.Show("weather") {
+		lat, lon, name := SavedWeatherLocation(weather)
+		v.Location = name
+		switch {
+		case !weather.Enabled || lat == "" || lon == "":
+			v.WeatherError = "Weather is not configured"
+		default:
+			if wd, err := CachedWeather(ctx, weather, lat, lon); err != nil {
+				v.WeatherError = "Weather unavailable"
+			} else {
+				v.Weather = &wd
+			}
+		}
+	}
+
+	if v.Show("calendar") {
+		var events []CalendarEvent
+		storage.GetAs("calendarEvents", &events)
+		for i := 0; i < einkEventDays; i++ {
+			day := now.AddDate(0, 0, i)
+			dayEvents := GetEventsForDate(events, day.Format("2006-01-02"))
+			if len(dayEvents) == 0 {
+				continue
+			}
+			label := day.Format("Monday")
+			switch i {
+			case 0:
+				label = "Today"
+			case 1:
+				label = "Tomorrow"
+			}
+			if len(dayEvents) > einkMaxItems {
+				dayEvents = dayEvents[:einkMaxItems]
+			}
+			v.Days = append(v.Days, EinkDay{Label: label, Events: dayEvents})
+		}
+	}
+
+	if v.Show("todo") {
+		var todos []Todo
+		storage.GetAs("todos", &todos)
+		for _, t := range todos {
+			if !t.Completed {
+				v.Todos = append(v.Todos, t)
+			}
+		}
+		// Due dates first (earliest first), then by priority
+		priority := map[string]int{"high": 0, "medium": 1, "low": 2, "": 3}
+		sort.SliceStable(v.Todos, func(i, j int) bool {
+			a, b := v.Todos[i], v.Todos[j]
+			if (a.DueDate == "") != (b.DueDate == "") {
+				return a.DueDate != ""
+			}
+			if a.DueDate != b.DueDate {
+				return a.DueDate < b.DueDate
+			}
+			return priority[a.Priority] < priority[b.Priority]
+		})
+		if len(v.Todos) > einkMaxItems {
+			v.MoreTodos = len(v.Todos) - einkMaxItems
+			v.Todos = v.Todos[:einkMaxItems]
+		}
+	}
+
+	if v.Show("monitors") {
+		v.Monitors = GetTimeline().MonitorStates()
+		for _, m := range v.Monitors {
+			if !m.Up {
+				v.MonitorsDown++
+			}
+		}
+	}
+
+	if v.Show("system") {
+		v.System = GetMetricsCollector().Current(ctx)
+	}
+
+	return v
+}
+
+// einkTemplate renders the view as static grayscale HTML: no scripts, web fonts or
+// images, tables instead of flex or grid for old e-reader browsers, and a meta refresh.
+var einkTemplate = template.Must(template.New("eink").Funcs(template.FuncMap{
+	"pct": func(v float64) string { return fmt.Sprintf("%.0f%%", v) },
+	"deg": func(v float64) string { return fmt.Sprintf("%.0f°", v) },
+	"bar": func(v float64) int { return int(math.Round(min(max(v, 0), 100))) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Refresh}}">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Dashboard</title>
+<style>
+* { box-sizing: border-box; }
+html, body { margin: 0; padding: 0; }
+body { font-family: Helvetica, Arial, sans-serif; color: #000; background: #fff; font-size: 20px; line-height: 1.25;{{if .Width}} width: {{.Width}}px;{{end}}{{if .Height}} height: {{.Height}}px; overflow: hidden;{{end}} padding: 12px; }
+body.invert { color: #fff; background: #000; }
+table.layout { width: 100%; border-collapse: collapse; }
+table.layout td { vertical-align: top; padding: 0 10px 12px 0; width: 50%; }
+h2 { font-size: 15px; text-transform: uppercase; letter-spacing: 1px; margin: 0 0 4px; padding-bottom: 2px; border-bottom: 2px solid; }
+.clock { font-size: 64px; font-weight: bold; line-height: 1; }
+.date { font-size: 22px; }
+.temp { font-size: 48px; font-weight: bold; line-height: 1; }
+.muted { color: #555; font-size: 16px; }
+body.invert .muted { color: #aaa; }
+ul { list-style: none; margin: 0; padding: 0; }
+li { padding: 2px 0; border-bottom: 1px solid #bbb; }
+.t { display: inline-block; min-width: 60px; font-weight: bold; }
+.day { font-weight: bold; margin-top: 4px; }
+.down { font-weight: bold; }
+.bar { height: 12px; border: 2px solid; margin: 2px 0 6px; }
+.bar div { height: 100%; background: #000; }
+body.invert .bar div { background: #fff; }
+</style>
+</head>
+<body{{if .Invert}} class="invert"{{end}}>
+<table class="layout"><tr>
+{{if .Show "clock"}}<td>
+<div class="clock">{{.Now.Format "15:04"}}</div>
+<div class="date">{{.Now.Format "Monday, 2 January"}}</div>
+</td>{{end}}
+{{if .Show "weather"}}<td>
+{{if .Weather}}{{with .Weather}}
+{{if .Current}}<div class="temp">{{deg .Current.Temperature}}</div>{{end}}
+{{if .Today}}<div>{{.Today.IconDescription}}</div><div class="muted">{{deg .Today.TempMin}} / {{deg .Today.TempMax}}{{if .Today.PrecipitationProb}} · {{pct .Today.PrecipitationProb}} rain{{end}}</div>{{else}}<div>{{.Summary}}</div>{{end}}
+{{if .Tomorrow}}<div class="muted">Tomorrow: {{.Tomorrow.IconDescription}}, {{deg .Tomorrow.TempMin}} / {{deg .Tomorrow.TempMax}}</div>{{end}}
+{{end}}{{if .Location}}<div class="muted">{{.Location}}</div>{{end}}
+{{else}}<div class="muted">{{.WeatherError}}</div>{{end}}
+</td>{{end}}
+</tr><tr>
+{{if .Show "calendar"}}<td>
+<h2>Calendar</h2>
+{{range .Days}}<div class="day">{{.Label}}</div><ul>{{range .Events}}<li><span class="t">{{.Time}}</span>{{.Title}}</li>{{end}}</ul>{{else}}<div class="muted">No upcoming events</div>{{end}}
+</td>{{end}}
+{{if .Show "todo"}}<td>
+<h2>Todo</h2>
+{{if .Todos}}<ul>{{range .Todos}}<li>☐ {{.Title}}{{if .DueDate}} <span class="muted">{{.DueDate}}</span>{{end}}</li>{{end}}</ul>{{if .MoreTodos}}<div class="muted">+{{.MoreTodos}} more</div>{{end}}{{else}}<div class="muted">Nothing to do</div>{{end}}
+</td>{{end}}
+</tr><tr>
+{{if .Show "monitors"}}<td>
+<h2>Monitors</h2>
+{{if .Monitors}}{{if .MonitorsDown}}<div class="down">{{.MonitorsDown}} of {{len .Monitors}} down</div>
+<ul>{{range .Monitors}}{{if not .Up}}<li class="down">✗ {{.Name}}</li>{{end}}{{end}}</ul>{{else}}<div>All {{len .Monitors}} up</div>{{end}}{{else}}<div class="muted">No monitor results yet</div>{{end}}
+</td>{{end}}
+{{if .Show "system"}}<td>
+<h2>System</h2>
+{{with .System}}
+<div>CPU {{pct .CPU.Usage}}</div><div class="bar"><div style="width:{{bar .CPU.Usage}}%"></div></div>
+<div>RAM {{pct .RAM.Percent}}{{if .RAM.UsedFormatted}} <span class="muted">{{.RAM.UsedFormatted}} / {{.RAM.TotalFormatted}}</span>{{end}}</div><div class="bar"><div style="width:{{bar .RAM.Percent}}%"></div></div>
+<div>Disk {{pct .Disk.Percent}}{{if .Disk.MountPoint}} <span class="muted">{{.Disk.MountPoint}}</span>{{end}}</div><div class="bar"><div style="width:{{bar .Disk.Percent}}%"></div></div>
+{{end}}
+</td>{{end}}
+</tr></table>
+<div class="muted">Updated {{.Now.Format "15:04"}}</div>
+</body>
+</html>
+`))
+
+// WriteHTML renders the e-ink page.
+func (v EinkView) WriteHTML(w io.Writer) error {
+	return einkTemplate.Execute(w, v)
+}
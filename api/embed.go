@@ -0,0 +1,190 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// Settings of the embed widgets behind /api/embed.
+const (
+	defaultEmbedHeight  = 300
+	defaultEmbedSandbox = "allow-scripts allow-same-origin allow-forms allow-popups"
+)
+
+// EmbedConfig configures the widgets of the Embed card, for services without an API.
+type EmbedConfig struct {
+	Widgets []EmbedWidget `json:"widgets"`
+}
+
+// EmbedWidget is one widget of the Embed card: a page in a frame, or an HTML or Markdown
+// snippet sanitized on the server.
+type EmbedWidget struct {
+	Title    string `json:"title,omitempty"`
+	URL      string `json:"url,omitempty"`      // Page shown in a frame
+	HTML     string `json:"html,omitempty"`     // HTML snippet, scripts and styles removed
+	Markdown string `json:"markdown,omitempty"` // Markdown snippet
+	Height   int    `json:"height,omitempty"`   // Frame height in pixels, default 300
+	Sandbox  string `json:"sandbox,omitempty"`  // Frame sandbox tokens, default allow-scripts allow-same-origin allow-forms allow-popups
+}
+
+// Validate checks that every widget has exactly one of url, html and markdown.
+func (c EmbedConfig) Validate() error {
+	if len(c.Widgets) == 0 {
+		return fmt.Errorf("embed: widgets is required")
+	}
+	for i, w := range c.Widgets {
+		set := 0
+		for _, v := range []string{w.URL, w.HTML, w.Markdown} {
+			if strings.TrimSpace(v) != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("embed: widgets[%d]: exactly one of url, html and markdown is required", i)
+		}
+		if w.URL != "" {
+			u, err := url.Parse(w.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("embed: widgets[%d]: url must be an http or https URL", i)
+			}
+		}
+		if w.Height != 0 && (w.Height < 50 || w.Height > 2000) {
+			return fmt.Errorf("embed: widgets[%d]: height must be between 50 and 2000", i)
+		}
+	}
+	return nil
+}
+
+// EmbedItem is a widget as served to the card. Snippets are already sanitized HTML.
+type EmbedItem struct {
+	Title   string `json:"title,omitempty"`
+	Type    string `json:"type"` // "frame" or "html"
+	URL     string `json:"url,omitempty"`
+	Height  int    `json:"height,omitempty"`
+	Sandbox string `json:"sandbox,omitempty"`
+	HTML    string `json:"html,omitempty"`
+}
+
+// EmbedService holds the sanitized widgets and the origins they load frames and images
+// from, which the Content-Security-Policy allows.
+type EmbedService struct {
+	mu           sync.RWMutex
+	items        []EmbedItem
+	frameOrigins []string
+	imageOrigins []string
+}
+
+// Global embed service instance
+var embedService = &EmbedService{}
+
+// GetEmbedService returns the global embed service instance.
+func GetEmbedService() *EmbedService {
+	return embedService
+}
+
+// Configure sanitizes the widgets once, as the config does not change while running.
+func (es *EmbedService) Configure(cfg EmbedConfig) {
+	var items []EmbedItem
+	frames := make(map[string]bool)
+	images := make(map[string]bool)
+	for _, w := range cfg.Widgets {
+		item := EmbedItem{Title: w.Title}
+		switch {
+		case w.URL != "":
+			item.Type, item.URL = "frame", w.URL
+			item.Height = w.Height
+			if item.Height == 0 {
+				item.Height = defaultEmbedHeight
+			}
+			item.Sandbox = w.Sandbox
+			if item.Sandbox == "" {
+				item.Sandbox = defaultEmbedSandbox
+			}
+			if origin := urlOrigin(w.URL); origin != "" {
+				frames[origin] = true
+			}
+		case w.HTML != "":
+			item.Type, item.HTML = "html", SanitizeHTML(w.HTML)
+		default:
+			item.Type, item.HTML = "html", RenderMarkdown(w.Markdown)
+		}
+		for _, origin := range imageOrigins(item.HTML) {
+			images[origin] = true
+		}
+		items = append(items, item)
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.items = items
+	es.frameOrigins = sortedKeys(frames)
+	es.imageOrigins = sortedKeys(images)
+}
+
+// Enabled reports whether any widget is configured.
+func (es *EmbedService) Enabled() bool {
+	es.mu.RLock()
+	defer es.mu.RUnlock()
+	return len(es.items) > 0
+}
+
+// Items returns the widgets in config order.
+func (es *EmbedService) Items() []EmbedItem {
+	es.mu.RLock()
+	defer es.mu.RUnlock()
+	return append([]EmbedItem(nil), es.items...)
+}
+
+// Origins returns the origins of the framed pages and of the snippets' images.
+func (es *EmbedService) Origins() (frames, images []string) {
+	es.mu.RLock()
+	defer es.mu.RUnlock()
+	return es.frameOrigins, es.imageOrigins
+}
+
+// urlOrigin returns the scheme and host of an absolute http or https URL.
+func urlOrigin(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// imageOrigins returns the origins of the absolute image URLs of sanitized HTML.
+func imageOrigins(snippet string) []string {
+	var origins []string
+	z := xhtml.NewTokenizer(strings.NewReader(snippet))
+	for {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			return origins
+		}
+		tok := z.Token()
+		if tok.Data != "img" {
+			continue
+		}
+		for _, attr := range tok.Attr {
+			if attr.Key == "src" {
+				if origin := urlOrigin(attr.Val); origin != "" {
+					origins = append(origins, origin)
+				}
+			}
+		}
+	}
+}
+
+// sortedKeys returns the keys of a set in order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
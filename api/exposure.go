@@ -0,0 +1,398 @@
+package api
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exposureFile holds the sockets seen by the last scan across restarts of the dashboard.
+const exposureFile = "exposure.json"
+
+// ssProcessPattern finds the processes in the users:(...) column of ss, e.g. ("sshd",pid=812,fd=3).
+var ssProcessPattern = regexp.MustCompile(`\("([^"]*)",pid=(\d+)`)
+
+// ExposureConfig configures the periodic scan of the listening sockets of the dashboard host.
+type ExposureConfig struct {
+	Interval string `json:"interval,omitempty"` // Scan interval, default "15m"
+	// Ignore lists ports that are never flagged as new, e.g. "5353" or "udp/5353"
+	Ignore []string `json:"ignore,omitempty"`
+}
+
+// Validate checks the exposure scan settings.
+func (c ExposureConfig) Validate() error {
+	if c.Interval != "" {
+		if d, err := time.ParseDuration(c.Interval); err != nil || d < time.Minute {
+			return fmt.Errorf("exposure: interval must be a duration of at least 1m")
+		}
+	}
+	for _, entry := range c.Ignore {
+		proto, port, ok := strings.Cut(entry, "/")
+		if !ok {
+			proto, port = "", entry
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 || (proto != "" && proto != "tcp" && proto != "udp") {
+			return fmt.Errorf("exposure: invalid ignore entry %q (use a port or tcp/port, udp/port)", entry)
+		}
+	}
+	return nil
+}
+
+// ignores reports whether a socket is on an ignored port.
+func (c ExposureConfig) ignores(s ListeningSocket) bool {
+	port := strconv.Itoa(s.Port)
+	return slices.Contains(c.Ignore, port) || slices.Contains(c.Ignore, s.Proto+"/"+port)
+}
+
+// ListeningSocket is a port the host listens on and the process behind it.
+type ListeningSocket struct {
+	Proto     string    `json:"proto"` // tcp or udp
+	Address   string    `json:"address"`
+	Port      int       `json:"port"`
+	Scope     string    `json:"scope"`             // loopback, lan, all or public
+	Process   string    `json:"process,omitempty"` // Empty when the process belongs to another user
+	PID       int       `json:"pid,omitempty"`
+	New       bool      `json:"new,omitempty"` // Not open at the previous scan
+	FirstSeen time.Time `json:"firstSeen"`
+}
+
+// key identifies the socket across scans, e.g. "tcp/0.0.0.0:22".
+func (s ListeningSocket) key() string {
+	return s.Proto + "/" + net.JoinHostPort(s.Address, strconv.Itoa(s.Port))
+}
+
+// ExposureReport is the result of the last scan.
+type ExposureReport struct {
+	Scanned *time.Time        `json:"scanned,omitempty"`
+	Source  string            `json:"source,omitempty"` // ss or netstat
+	Sockets []ListeningSocket `json:"sockets"`
+	Exposed int               `json:"exposed"` // Sockets reachable from other hosts
+	New     int               `json:"new"`
+	Closed  []string          `json:"closed,omitempty"` // Sockets open at the previous scan but not this one
+	Error   string            `json:"error,omitempty"`
+}
+
+// ExposureScanner scans the listening sockets of the host and flags ports opened since
+// the previous scan.
+type ExposureScanner struct {
+	mu        sync.Mutex
+	scanMu    sync.Mutex // Serializes scans
+	config    *ExposureConfig
+	report    ExposureReport
+	firstSeen map[string]time.Time // Sockets of the last scan and when they opened
+	loaded    bool
+}
+
+// Global exposure scanner instance
+var exposureScanner = &ExposureScanner{}
+
+// GetExposureScanner returns the global exposure scanner instance.
+func GetExposureScanner() *ExposureScanner {
+	return exposureScanner
+}
+
+// Configure sets up the scanner.
+func (es *ExposureScanner) Configure(cfg ExposureConfig) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.config = &cfg
+}
+
+// Enabled reports whether the scanner is configured.
+func (es *ExposureScanner) Enabled() bool {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	return es.config != nil
+}
+
+// Start scans at the configured interval. It blocks and is meant to run in a goroutine.
+func (es *ExposureScanner) Start() {
+	es.mu.Lock()
+	cfg := es.config
+	es.mu.Unlock()
+	if cfg == nil {
+		return
+	}
+
+	interval := 15 * time.Minute
+	if d, err := time.ParseDuration(cfg.Interval); err == nil {
+		interval = d
+	}
+
+	es.Scan(context.Background())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		es.Scan(context.Background())
+	}
+}
+
+// Report returns the result of the last scan.
+func (es *ExposureScanner) Report() ExposureReport {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	report := es.report
+	report.Sockets = slices.Clone(report.Sockets)
+	if report.Sockets == nil {
+		report.Sockets = []ListeningSocket{}
+	}
+	return report
+}
+
+// Scan lists the listening sockets now, compares them with the previous scan and records
+// newly exposed ports on the timeline.
+func (es *ExposureScanner) Scan(ctx context.Context) ExposureReport {
+	es.scanMu.Lock()
+	defer es.scanMu.Unlock()
+
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	sockets, source, err := ListListeningSockets(cctx)
+	now := time.Now()
+
+	es.mu.Lock()
+	es.load()
+	cfg := es.config
+	if err != nil {
+		es.report.Error = err.Error()
+		es.report.Scanned = &now
+		es.mu.Unlock()
+		GetModuleHealth().Record("exposure", err)
+		return es.Report()
+	}
+
+	slices.SortFunc(sockets, func(a, b ListeningSocket) int {
+		return cmp.Or(cmp.Compare(a.Port, b.Port), cmp.Compare(a.Proto, b.Proto), cmp.Compare(a.Address, b.Address))
+	})
+
+	// The first scan only records a baseline, so nothing is flagged as new
+	baseline := es.firstSeen == nil
+	current := make(map[string]time.Time, len(sockets))
+	report := ExposureReport{Scanned: &now, Source: source, Sockets: sockets}
+	var opened []ListeningSocket
+	for i := range report.Sockets {
+		s := &report.Sockets[i]
+		key := s.key()
+		if seen, ok := es.firstSeen[key]; ok {
+			s.FirstSeen = seen
+		} else {
+			s.FirstSeen = now
+			s.New = !baseline && (cfg == nil || !cfg.ignores(*s))
+		}
+		current[key] = s.FirstSeen
+		if s.Scope != "loopback" {
+			report.Exposed++
+		}
+		if s.New {
+			report.New++
+			if s.Scope != "loopback" {
+				opened = append(opened, *s)
+			}
+		}
+	}
+	for key := range es.firstSeen {
+		if _, ok := current[key]; !ok {
+			report.Closed = append(report.Closed, key)
+		}
+	}
+	slices.Sort(report.Closed)
+
+	changed := report.New > 0 || len(report.Closed) > 0 || baseline
+	es.report = report
+	es.firstSeen = current
+	if changed {
+		es.save()
+	}
+	es.mu.Unlock()
+	GetModuleHealth().Record("exposure", nil)
+
+	if len(opened) > 0 {
+		notifyPortsOpened(opened)
+	}
+	return es.Report()
+}
+
+// notifyPortsOpened records newly exposed ports on the timeline and dispatches an alert.
+func notifyPortsOpened(opened []ListeningSocket) {
+	var details []string
+	for _, s := range opened {
+		detail := s.Proto + " " + net.JoinHostPort(s.Address, strconv.Itoa(s.Port))
+		if s.Process != "" {
+			detail += " (" + s.Process + ")"
+		}
+		details = append(details, detail)
+	}
+	title := "New port open on this host"
+	if len(opened) > 1 {
+		title = strconv.Itoa(len(opened)) + " new ports open on this host"
+	}
+	ev := GetTimeline().Add(TimelineEvent{
+		Source:   TimelineSourceExposure,
+		Title:    title,
+		Detail:   strings.Join(details, ", "),
+		Severity: "warning",
+	})
+	Logger("exposure").Warn(ev.Title, "sockets", ev.Detail)
+	GetAlertManager().Dispatch(Alert{
+		Title:    ev.Title,
+		Body:     ev.Detail,
+		Severity: ev.Severity,
+		URL:      "/",
+		Tag:      "exposure",
+	})
+}
+
+// load reads the sockets of the last scan. Caller must hold mu.
+func (es *ExposureScanner) load() {
+	if es.loaded {
+		return
+	}
+	es.loaded = true
+	data, err := os.ReadFile(exposureFile)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &es.firstSeen); err != nil {
+		GetDebugLogger().Logf("exposure", "failed to parse %s: %v", exposureFile, err)
+		es.firstSeen = nil
+	}
+}
+
+// save writes the sockets of the last scan. Caller must hold mu.
+func (es *ExposureScanner) save() {
+	data, err := json.MarshalIndent(es.firstSeen, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(exposureFile, data, 0644); err != nil {
+		GetDebugLogger().Logf("exposure", "failed to write %s: %v", exposureFile, err)
+	}
+}
+
+// ListListeningSockets lists the TCP and UDP sockets the host listens on using ss,
+// falling back to netstat. Processes of other users are only named when running as root.
+func ListListeningSockets(ctx context.Context) ([]ListeningSocket, string, error) {
+	if out, err := exec.CommandContext(ctx, "ss", "-H", "-l", "-n", "-t", "-u", "-p").Output(); err == nil {
+		return parseSS(string(out)), "ss", nil
+	}
+	if out, err := exec.CommandContext(ctx, "netstat", "-l", "-n", "-t", "-u", "-p").Output(); err == nil {
+		return parseNetstat(string(out)), "netstat", nil
+	}
+	return nil, "", errors.New("neither ss nor netstat is available")
+}
+
+// parseSS parses `ss -Hlntup`, e.g.
+// tcp LISTEN 0 4096 0.0.0.0:22 0.0.0.0:* users:(("sshd",pid=812,fd=3))
+func parseSS(out string) []ListeningSocket {
+	var sockets []ListeningSocket
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		s, ok := newListeningSocket(fields[0], fields[4])
+		if !ok {
+			continue
+		}
+		if len(fields) > 6 {
+			if m := ssProcessPattern.FindStringSubmatch(fields[6]); m != nil {
+				s.Process = m[1]
+				s.PID, _ = strconv.Atoi(m[2])
+			}
+		}
+		sockets = appendSocket(sockets, s)
+	}
+	return sockets
+}
+
+// parseNetstat parses `netstat -lntup`, e.g.
+// tcp 0 0 0.0.0.0:22 0.0.0.0:* LISTEN 812/sshd
+func parseNetstat(out string) []ListeningSocket {
+	var sockets []ListeningSocket
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || !strings.HasPrefix(fields[0], "tcp") && !strings.HasPrefix(fields[0], "udp") {
+			continue
+		}
+		s, ok := newListeningSocket(fields[0], fields[3])
+		if !ok {
+			continue
+		}
+		// UDP sockets have no state column
+		if pid, name, ok := strings.Cut(fields[len(fields)-1], "/"); ok {
+			s.PID, _ = strconv.Atoi(pid)
+			s.Process = name
+		}
+		sockets = appendSocket(sockets, s)
+	}
+	return sockets
+}
+
+// newListeningSocket parses a local address such as "0.0.0.0:22", "[::1]:631", ":::22",
+// "*:5353" or "127.0.0.53%lo:53".
+func newListeningSocket(proto, local string) (ListeningSocket, bool) {
+	proto = strings.TrimRight(proto, "6")
+	if proto != "tcp" && proto != "udp" {
+		return ListeningSocket{}, false
+	}
+	i := strings.LastIndex(local, ":")
+	if i < 0 {
+		return ListeningSocket{}, false
+	}
+	port, err := strconv.Atoi(local[i+1:])
+	if err != nil || port == 0 {
+		return ListeningSocket{}, false
+	}
+	addr := strings.Trim(local[:i], "[]")
+	if zone := strings.Index(addr, "%"); zone >= 0 {
+		addr = addr[:zone]
+	}
+	return ListeningSocket{Proto: proto, Address: addr, Port: port, Scope: socketScope(addr)}, true
+}
+
+// socketScope tells who can reach an address: loopback, lan, all (every interface) or public.
+func socketScope(addr string) string {
+	if addr == "*" || addr == "" {
+		return "all"
+	}
+	ip := net.ParseIP(addr)
+	switch {
+	case ip == nil:
+		return "all"
+	case ip.IsUnspecified():
+		return "all"
+	case ip.IsLoopback():
+		return "loopback"
+	case ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast():
+		return "lan"
+	}
+	return "public"
+}
+
+// appendSocket adds a socket unless it is already listed, as with several workers
+// sharing a port.
+func appendSocket(sockets []ListeningSocket, s ListeningSocket) []ListeningSocket {
+	for i, existing := range sockets {
+		if existing.key() == s.key() {
+			if existing.Process == "" {
+				sockets[i].Process, sockets[i].PID = s.Process, s.PID
+			}
+			return sockets
+		}
+	}
+	return append(sockets, s)
+}
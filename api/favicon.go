@@ -0,0 +1,261 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the favicon cache.
+const (
+	defaultFaviconCacheDir = "favicon-cache"
+	defaultFaviconTTL      = 7 * 24 * time.Hour
+	// Sites without a favicon are asked again after an hour
+	faviconMissingTTL = time.Hour
+)
+
+// FaviconConfig configures the disk cache behind /api/favicon and /api/favicon/img.
+type FaviconConfig struct {
+	CacheDir string `json:"cacheDir,omitempty"` // Default "favicon-cache"
+	TTL      string `json:"ttl,omitempty"`      // How long a favicon is served before it is fetched again, default "7d"
+}
+
+// Validate checks the cache TTL.
+func (c FaviconConfig) Validate() error {
+	if c.TTL != "" {
+		if d, err := ParseHistoryRange(c.TTL); err != nil || d < time.Hour {
+			return fmt.Errorf("favicon: ttl must be a duration of at least 1h (e.g. 24h or 7d)")
+		}
+	}
+	return nil
+}
+
+// Favicon is a cached favicon of a site. Sites without one are cached as Missing, so they
+// are not asked again on every request.
+type Favicon struct {
+	Origin      string    `json:"origin"`
+	ContentType string    `json:"contentType,omitempty"`
+	ETag        string    `json:"etag,omitempty"`
+	Fetched     time.Time `json:"fetched"`
+	Missing     bool      `json:"missing,omitempty"`
+	Data        []byte    `json:"-"`
+}
+
+// DataURL returns the favicon as a data: URL.
+func (f *Favicon) DataURL() string {
+	return "data:" + f.ContentType + ";base64," + base64.StdEncoding.EncodeToString(f.Data)
+}
+
+// FaviconCache keeps favicons on disk, one metadata and one image file per origin named
+// after the origin's hash.
+type FaviconCache struct {
+	mu       sync.Mutex
+	dir      string
+	ttl      time.Duration
+	fetching map[string]*faviconFetch
+}
+
+// faviconFetch is a fetch in progress, shared by concurrent requests for the same origin.
+type faviconFetch struct {
+	done chan struct{}
+	icon *Favicon
+	err  error
+}
+
+// Global favicon cache instance
+var faviconCache = &FaviconCache{dir: defaultFaviconCacheDir, ttl: defaultFaviconTTL, fetching: make(map[string]*faviconFetch)}
+
+// GetFaviconCache returns the global favicon cache instance.
+func GetFaviconCache() *FaviconCache {
+	return faviconCache
+}
+
+// Configure sets the cache directory and TTL.
+func (fc *FaviconCache) Configure(cfg FaviconConfig) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if cfg.CacheDir != "" {
+		fc.dir = cfg.CacheDir
+	}
+	if d, err := ParseHistoryRange(cfg.TTL); err == nil && d > 0 {
+		fc.ttl = d
+	}
+}
+
+// FaviconOrigin returns the scheme and host of an http or https URL.
+func FaviconOrigin(rawURL string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", errors.New("an http or https URL is required")
+	}
+	return parsed.Scheme + "://" + parsed.Host, nil
+}
+
+// Get returns the favicon of an origin, from the cache while it is fresh. refresh fetches
+// it again regardless; when fetching fails, a stale favicon is returned instead, as an
+// unreachable site looks the same as one without a favicon. ctx
+// decides whether private addresses may be reached (see OutboundContext), for cached
+// favicons too.
+func (fc *FaviconCache) Get(ctx context.Context, origin string, refresh bool) (*Favicon, error) {
+	if err := CheckOutboundURL(ctx, origin); err != nil {
+		return nil, err
+	}
+	fc.mu.Lock()
+	ttl := fc.ttl
+	fc.mu.Unlock()
+
+	cached := fc.load(origin)
+	if cached != nil && !refresh {
+		if cached.Missing && time.Since(cached.Fetched) < faviconMissingTTL {
+			return nil, ErrFaviconNotFound
+		}
+		if !cached.Missing && time.Since(cached.Fetched) < ttl {
+			return cached, nil
+		}
+	}
+
+	icon, err := fc.fetch(ctx, origin)
+	if err != nil {
+		if cached != nil && !cached.Missing {
+			Logger("favicon").Debug("serving stale favicon", "origin", origin, "error", err)
+			return cached, nil
+		}
+		return nil, err
+	}
+	return icon, nil
+}
+
+// fetch downloads the favicon of an origin and stores it, sharing the download with
+// concurrent requests for the same origin.
+func (fc *FaviconCache) fetch(ctx context.Context, origin string) (*Favicon, error) {
+	fc.mu.Lock()
+	if f, ok := fc.fetching[origin]; ok {
+		fc.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.icon, f.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	f := &faviconFetch{done: make(chan struct{})}
+	fc.fetching[origin] = f
+	fc.mu.Unlock()
+
+	defer func() {
+		fc.mu.Lock()
+		delete(fc.fetching, origin)
+		fc.mu.Unlock()
+		close(f.done)
+	}()
+
+	data, contentType, err := FetchFavicon(ctx, origin)
+	icon := &Favicon{Origin: origin, Fetched: time.Now().UTC()}
+	switch {
+	case errors.Is(err, ErrFaviconNotFound):
+		f.err = err
+		// Keep a favicon fetched before, to serve while the site is down
+		if old := fc.load(origin); old != nil && !old.Missing {
+			return nil, err
+		}
+		icon.Missing = true
+	case err != nil:
+		f.err = err
+		return nil, err
+	default:
+		sum := sha256.Sum256(data)
+		icon.ContentType = contentType
+		icon.ETag = `"` + hex.EncodeToString(sum[:8]) + `"`
+		icon.Data = data
+		f.icon = icon
+	}
+	if err := fc.save(icon); err != nil {
+		Logger("favicon").Warn("caching favicon failed", "origin", origin, "error", err)
+	}
+	return f.icon, f.err
+}
+
+// path returns the cache file of an origin without its extension.
+func (fc *FaviconCache) path(origin string) string {
+	sum := sha256.Sum256([]byte(origin))
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return filepath.Join(fc.dir, hex.EncodeToString(sum[:16]))
+}
+
+// load reads the cached favicon of an origin, or nil if there is none.
+func (fc *FaviconCache) load(origin string) *Favicon {
+	path := fc.path(origin)
+	meta, err := os.ReadFile(path + ".json")
+	if err != nil {
+		return nil
+	}
+	var icon Favicon
+	if err := json.Unmarshal(meta, &icon); err != nil || icon.Origin != origin {
+		return nil
+	}
+	if !icon.Missing {
+		if icon.Data, err = os.ReadFile(path + ".img"); err != nil {
+			return nil
+		}
+	}
+	return &icon
+}
+
+// save writes a favicon to the cache, the image before the metadata that refers to it.
+func (fc *FaviconCache) save(icon *Favicon) error {
+	path := fc.path(icon.Origin)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if icon.Missing {
+		os.Remove(path + ".img")
+	} else if err := os.WriteFile(path+".img", icon.Data, 0644); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(icon)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+".json", meta, 0644)
+}
+
+// Prune removes favicons that have not been fetched again for twice the TTL, such as
+// those of links no longer on the dashboard, and returns how many were removed.
+func (fc *FaviconCache) Prune() int {
+	fc.mu.Lock()
+	dir, ttl := fc.dir, fc.ttl
+	fc.mu.Unlock()
+	metas, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	removed := 0
+	for _, meta := range metas {
+		info, err := os.Stat(meta)
+		if err != nil || time.Since(info.ModTime()) < 2*ttl {
+			continue
+		}
+		os.Remove(strings.TrimSuffix(meta, ".json") + ".img")
+		if os.Remove(meta) == nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+// Len returns the number of cached origins.
+func (fc *FaviconCache) Len() int {
+	fc.mu.Lock()
+	dir := fc.dir
+	fc.mu.Unlock()
+	metas, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	return len(metas)
+}
@@ -0,0 +1,392 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxFormatInput caps the documents accepted by the JSON and YAML formatter.
+const maxFormatInput = 1 << 20
+
+// maxFormatNodes caps the values a YAML document may expand to through aliases.
+const maxFormatNodes = 1000000
+
+// maxFormatDepth caps the nesting of mappings and sequences, which recursive aliases
+// make endless.
+const maxFormatDepth = 500
+
+// Formats of the JSON and YAML formatter.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// FormatOptions selects how a document is read and written.
+type FormatOptions struct {
+	From   string `json:"from"`   // json, yaml or empty to detect
+	To     string `json:"to"`     // json, yaml or empty for the input format
+	Indent int    `json:"indent"` // 2 (default), 4 or 8 spaces
+	Minify bool   `json:"minify"` // JSON on one line
+}
+
+// FormattedDocument is a document written by the formatter.
+type FormattedDocument struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Output string `json:"output"`
+}
+
+// FormatDocument validates a JSON or YAML document and pretty-prints it or converts it to
+// the other format. Keys keep their order and YAML comments are kept when writing YAML.
+// Only the first document of a YAML stream is read.
+func FormatDocument(text string, opts FormatOptions) (FormattedDocument, error) {
+	if len(text) > maxFormatInput {
+		return FormattedDocument{}, errors.New("the document is too long")
+	}
+	if strings.TrimSpace(text) == "" {
+		return FormattedDocument{}, errors.New("the document is empty")
+	}
+	from := strings.ToLower(opts.From)
+	if from == "" {
+		// YAML flow style can look like JSON too, but JSON is the likelier intent
+		from = FormatYAML
+		if t := strings.TrimSpace(text); t[0] == '{' || t[0] == '[' {
+			from = FormatJSON
+		}
+	}
+	to := strings.ToLower(opts.To)
+	if to == "" {
+		to = from
+	}
+	for _, f := range []string{from, to} {
+		if f != FormatJSON && f != FormatYAML {
+			return FormattedDocument{}, fmt.Errorf("unknown format %q, expected json or yaml", f)
+		}
+	}
+	indent := opts.Indent
+	switch indent {
+	case 0:
+		indent = 2
+	case 2, 4, 8:
+	default:
+		return FormattedDocument{}, errors.New("indent must be 2, 4 or 8")
+	}
+
+	result := FormattedDocument{From: from, To: to}
+	if from == FormatJSON && to == FormatJSON {
+		// Indent and Compact keep numbers and duplicate keys exactly as written
+		var buf bytes.Buffer
+		var err error
+		if opts.Minify {
+			err = json.Compact(&buf, []byte(text))
+		} else {
+			err = json.Indent(&buf, []byte(text), "", strings.Repeat(" ", indent))
+		}
+		if err != nil {
+			return FormattedDocument{}, jsonPositionError(text, err)
+		}
+		result.Output = buf.String()
+		return result, nil
+	}
+
+	var node *yaml.Node
+	var err error
+	if from == FormatJSON {
+		node, err = jsonToYAMLNode(text)
+	} else {
+		node, err = parseYAMLDocument(text)
+	}
+	if err != nil {
+		return FormattedDocument{}, err
+	}
+
+	if to == FormatYAML {
+		setBlockStyle(node)
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(indent)
+		if err := enc.Encode(node); err != nil {
+			return FormattedDocument{}, err
+		}
+		enc.Close()
+		result.Output = buf.String()
+		return result, nil
+	}
+
+	var buf bytes.Buffer
+	budget := maxFormatNodes
+	if err := writeYAMLNodeJSON(&buf, node, &budget, 0); err != nil {
+		return FormattedDocument{}, err
+	}
+	if !opts.Minify {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, buf.Bytes(), "", strings.Repeat(" ", indent)); err != nil {
+			return FormattedDocument{}, err
+		}
+		buf = indented
+	}
+	result.Output = buf.String()
+	return result, nil
+}
+
+// jsonPositionError adds the line and column of a JSON syntax error to its message.
+func jsonPositionError(text string, err error) error {
+	var syntax *json.SyntaxError
+	if !errors.As(err, &syntax) {
+		return err
+	}
+	before := text[:min(int(syntax.Offset), len(text))]
+	line := strings.Count(before, "\n") + 1
+	col := len(before) - strings.LastIndex(before, "\n")
+	return fmt.Errorf("line %d, column %d: %s", line, col, syntax.Error())
+}
+
+// jsonToYAMLNode parses a JSON document into a YAML node tree, keeping the order of keys
+// and numbers as written.
+func jsonToYAMLNode(text string) (*yaml.Node, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var value func(depth int) (*yaml.Node, error)
+	value = func(depth int) (*yaml.Node, error) {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch v := tok.(type) {
+		case json.Delim:
+			if depth > maxFormatDepth {
+				return nil, errors.New("the document is nested too deeply")
+			}
+			node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			if v == '{' {
+				node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			}
+			for dec.More() {
+				if node.Kind == yaml.MappingNode {
+					key, err := dec.Token()
+					if err != nil {
+						return nil, err
+					}
+					node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+				}
+				child, err := value(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, child)
+			}
+			// The closing bracket
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return node, nil
+		case string:
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}, nil
+		case json.Number:
+			tag := "!!int"
+			if strings.ContainsAny(string(v), ".eE") {
+				tag = "!!float"
+			}
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: string(v)}, nil
+		case bool:
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(v)}, nil
+		default:
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+		}
+	}
+	node, err := value(0)
+	if err == nil {
+		if _, extra := dec.Token(); extra != io.EOF {
+			err = errors.New("unexpected data after the top-level value")
+		}
+	}
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errors.New("unexpected end of JSON input")
+		}
+		return nil, jsonPositionError(text, err)
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{node}}, nil
+}
+
+// parseYAMLDocument parses the first document of a YAML stream.
+func parseYAMLDocument(text string) (*yaml.Node, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &node); err != nil {
+		return nil, errors.New(strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+	if node.Kind == 0 {
+		return nil, errors.New("the document is empty")
+	}
+	return &node, nil
+}
+
+// setBlockStyle writes mappings and sequences on their own lines rather than inline.
+func setBlockStyle(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode {
+		node.Style &^= yaml.FlowStyle
+	}
+	// Without this merge keys are written as "!!merge <<"
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!merge" {
+		node.Tag = ""
+	}
+	for _, child := range node.Content {
+		setBlockStyle(child)
+	}
+}
+
+// writeYAMLNodeJSON writes a YAML node as compact JSON. budget counts down the values
+// written so aliases cannot expand a small document without limit.
+func writeYAMLNodeJSON(buf *bytes.Buffer, node *yaml.Node, budget *int, depth int) error {
+	if *budget--; *budget < 0 {
+		return errors.New("the document expands to too many values")
+	}
+	if depth > maxFormatDepth {
+		return errors.New("the document is nested too deeply")
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeYAMLNodeJSON(buf, node.Content[0], budget, depth)
+	case yaml.AliasNode:
+		return writeYAMLNodeJSON(buf, node.Alias, budget, depth)
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, child := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeYAMLNodeJSON(buf, child, budget, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case yaml.MappingNode:
+		pairs, err := yamlMappingPairs(node, budget, depth)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('{')
+		for i, pair := range pairs {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := yamlKeyString(pair[0])
+			if err != nil {
+				return err
+			}
+			buf.Write(jsonText(key))
+			buf.WriteByte(':')
+			if err := writeYAMLNodeJSON(buf, pair[1], budget, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	}
+
+	switch node.ShortTag() {
+	case "!!null", "!!bool", "!!int", "!!float":
+		var v any
+		if err := node.Decode(&v); err != nil {
+			return fmt.Errorf("line %d: %v", node.Line, err)
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("line %d: %s cannot be written as JSON", node.Line, node.Value)
+		}
+		buf.Write(data)
+	default:
+		// Strings, timestamps and custom tags keep their text
+		buf.Write(jsonText(node.Value))
+	}
+	return nil
+}
+
+// yamlMappingPairs returns the keys and values of a mapping with merge keys ("<<: *base")
+// expanded. Keys given directly win over merged ones.
+func yamlMappingPairs(node *yaml.Node, budget *int, depth int) ([][2]*yaml.Node, error) {
+	if *budget--; *budget < 0 {
+		return nil, errors.New("the document expands to too many values")
+	}
+	if depth > maxFormatDepth {
+		return nil, errors.New("the document is nested too deeply")
+	}
+	var pairs [][2]*yaml.Node
+	index := make(map[string]int)
+	add := func(key, value *yaml.Node, override bool) {
+		k := key.Value
+		if i, ok := index[k]; ok {
+			if override {
+				pairs[i][1] = value
+			}
+			return
+		}
+		index[k] = len(pairs)
+		pairs = append(pairs, [2]*yaml.Node{key, value})
+	}
+	var merged [][2]*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.ShortTag() != "!!merge" {
+			add(key, value, true)
+			continue
+		}
+		sources := []*yaml.Node{value}
+		if resolved := yamlResolve(value); resolved.Kind == yaml.SequenceNode {
+			sources = resolved.Content
+		}
+		for _, source := range sources {
+			source = yamlResolve(source)
+			if source.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("line %d: only mappings can be merged", source.Line)
+			}
+			sourcePairs, err := yamlMappingPairs(source, budget, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			merged = append(merged, sourcePairs...)
+		}
+	}
+	for _, pair := range merged {
+		add(pair[0], pair[1], false)
+	}
+	return pairs, nil
+}
+
+// yamlResolve follows an alias to the node it refers to.
+func yamlResolve(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// yamlKeyString returns a mapping key as a JSON object key. Scalars of any type are
+// written as their text; mappings and sequences cannot be keys in JSON.
+func yamlKeyString(key *yaml.Node) (string, error) {
+	key = yamlResolve(key)
+	if key.Kind != yaml.ScalarNode {
+		return "", fmt.Errorf("line %d: a mapping or sequence key cannot be written as JSON", key.Line)
+	}
+	return key.Value, nil
+}
+
+// jsonText encodes a string as JSON without escaping HTML characters.
+func jsonText(s string) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return bytes.TrimRight(buf.Bytes(), "\n")
+}
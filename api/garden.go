@@ -0,0 +1,280 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Settings of the watering advice behind /api/garden.
+const (
+	gardenCacheTTL = 30 * time.Minute
+	maxGardenDays  = 7
+)
+
+// GardenConfig sets the thresholds of the watering advice of /api/garden. The location is
+// the weather location.
+type GardenConfig struct {
+	PastDays   int     `json:"pastDays,omitempty"`   // Days of past rain counted, default 3
+	AheadDays  int     `json:"aheadDays,omitempty"`  // Days of forecast rain counted, today included, default 2
+	Needed     float64 `json:"needed,omitempty"`     // Rain in mm the garden needs over pastDays, default 10
+	RainChance float64 `json:"rainChance,omitempty"` // Lowest probability (%) of forecast rain that is counted, default 60
+	HotTemp    float64 `json:"hotTemp,omitempty"`    // Forecast high in °C from which the garden needs half as much again, default 28
+}
+
+// Validate checks the day counts and thresholds.
+func (c GardenConfig) Validate() error {
+	if c.PastDays < 0 || c.PastDays > maxGardenDays {
+		return fmt.Errorf("garden: pastDays must be between 1 and %d", maxGardenDays)
+	}
+	if c.AheadDays < 0 || c.AheadDays > maxGardenDays {
+		return fmt.Errorf("garden: aheadDays must be between 1 and %d", maxGardenDays)
+	}
+	if c.Needed < 0 {
+		return fmt.Errorf("garden: needed cannot be negative")
+	}
+	if c.RainChance < 0 || c.RainChance > 100 {
+		return fmt.Errorf("garden: rainChance must be between 0 and 100")
+	}
+	return nil
+}
+
+// withDefaults fills the unset thresholds.
+func (c GardenConfig) withDefaults() GardenConfig {
+	if c.PastDays == 0 {
+		c.PastDays = 3
+	}
+	if c.AheadDays == 0 {
+		c.AheadDays = 2
+	}
+	if c.Needed == 0 {
+		c.Needed = 10
+	}
+	if c.RainChance == 0 {
+		c.RainChance = 60
+	}
+	if c.HotTemp == 0 {
+		c.HotTemp = 28
+	}
+	return c
+}
+
+// GardenDay is the rain of one day in the window of the advice.
+type GardenDay struct {
+	Date        string  `json:"date"`        // YYYY-MM-DD
+	Rain        float64 `json:"rain"`        // Total precipitation, measured for past days and forecast from today
+	Probability float64 `json:"probability"` // Chance of precipitation (%), 100 for past days
+	TempMax     float64 `json:"tempMax"`
+	Past        bool    `json:"past"`
+	Counted     bool    `json:"counted"` // Whether the rain counts toward the garden's need
+}
+
+// GardenReport is the watering advice for a location.
+type GardenReport struct {
+	Advice       string      `json:"advice"` // "water", "wait" (enough rain is forecast) or "skip" (enough rain fell)
+	Reason       string      `json:"reason"`
+	PastRain     float64     `json:"pastRain"`     // Rain of the past days
+	ExpectedRain float64     `json:"expectedRain"` // Counted rain of the forecast days
+	Needed       float64     `json:"needed"`       // Need of the garden, raised on hot days
+	Deficit      float64     `json:"deficit"`      // Water still missing after the expected rain
+	Hot          bool        `json:"hot"`          // A forecast high reaches hotTemp
+	PastDays     int         `json:"pastDays"`
+	AheadDays    int         `json:"aheadDays"`
+	Unit         string      `json:"unit"` // Unit of the amounts, "mm" or "in"
+	TempUnit     string      `json:"tempUnit"`
+	Days         []GardenDay `json:"days"`
+	Latitude     float64     `json:"latitude"`
+	Longitude    float64     `json:"longitude"`
+	Updated      time.Time   `json:"updated"`
+}
+
+// GardenService computes the watering advice from the rain history and forecast of
+// Open-Meteo.
+type GardenService struct {
+	mu      sync.Mutex
+	config  GardenConfig
+	reports map[string]GardenReport
+}
+
+// Global garden service instance
+var gardenService = &GardenService{reports: make(map[string]GardenReport)}
+
+// GetGardenService returns the global garden service instance.
+func GetGardenService() *GardenService {
+	return gardenService
+}
+
+// Configure sets the thresholds.
+func (gs *GardenService) Configure(cfg GardenConfig) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.config = cfg
+	gs.reports = make(map[string]GardenReport)
+}
+
+// Report returns the advice for a location, fetched at most every thirty minutes.
+func (gs *GardenService) Report(ctx context.Context, lat, lon float64) (GardenReport, error) {
+	key := fmt.Sprintf("%.2f,%.2f", lat, lon)
+	gs.mu.Lock()
+	cfg := gs.config.withDefaults()
+	report, ok := gs.reports[key]
+	gs.mu.Unlock()
+	if ok && time.Since(report.Updated) < gardenCacheTTL {
+		return report, nil
+	}
+
+	days, err := fetchGardenDays(ctx, lat, lon, cfg)
+	if err != nil {
+		return GardenReport{}, err
+	}
+	report = gardenAdvice(days, cfg)
+	report.Latitude, report.Longitude = lat, lon
+	report.Updated = time.Now()
+
+	gs.mu.Lock()
+	gs.reports[key] = report
+	gs.mu.Unlock()
+	return report, nil
+}
+
+// fetchGardenDays fetches the daily rain of the past days and the forecast days.
+func fetchGardenDays(ctx context.Context, lat, lon float64, cfg GardenConfig) ([]GardenDay, error) {
+	q := url.Values{}
+	q.Set("latitude", strconv.FormatFloat(lat, 'f', 4, 64))
+	q.Set("longitude", strconv.FormatFloat(lon, 'f', 4, 64))
+	q.Set("daily", "precipitation_sum,precipitation_probability_max,temperature_2m_max")
+	q.Set("past_days", strconv.Itoa(cfg.PastDays))
+	q.Set("forecast_days", strconv.Itoa(cfg.AheadDays))
+	q.Set("timezone", "auto")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.open-meteo.com/v1/forecast?"+q.Encode(), nil)
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, errors.New("weather http status " + res.Status)
+	}
+
+	var raw struct {
+		Daily struct {
+			Time        []string   `json:"time"`
+			Rain        []*float64 `json:"precipitation_sum"`
+			Probability []*float64 `json:"precipitation_probability_max"`
+			TempMax     []*float64 `json:"temperature_2m_max"`
+		} `json:"daily"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	value := func(values []*float64, i int) float64 {
+		if i < len(values) && values[i] != nil {
+			return *values[i]
+		}
+		return 0
+	}
+	var days []GardenDay
+	for i, date := range raw.Daily.Time {
+		day := GardenDay{
+			Date:    date,
+			Rain:    value(raw.Daily.Rain, i),
+			TempMax: value(raw.Daily.TempMax, i),
+			Past:    i < cfg.PastDays,
+		}
+		day.Probability = value(raw.Daily.Probability, i)
+		if day.Past {
+			day.Probability = 100
+		}
+		days = append(days, day)
+	}
+	if len(days) == 0 {
+		return nil, errors.New("no daily weather data")
+	}
+	return days, nil
+}
+
+// gardenAdvice weighs the rain that fell and the likely rain ahead against the garden's
+// need: enough past rain means skip, enough with the forecast means wait, else water.
+func gardenAdvice(days []GardenDay, cfg GardenConfig) GardenReport {
+	report := GardenReport{Needed: cfg.Needed, Unit: "mm", TempUnit: "°C", Days: days, PastDays: cfg.PastDays, AheadDays: cfg.AheadDays}
+	for i := range days {
+		day := &days[i]
+		if day.Past {
+			day.Counted = true
+			report.PastRain += day.Rain
+			continue
+		}
+		if day.TempMax >= cfg.HotTemp {
+			report.Hot = true
+		}
+		if day.Probability >= cfg.RainChance {
+			day.Counted = true
+			report.ExpectedRain += day.Rain
+		}
+	}
+	if report.Hot {
+		report.Needed *= 1.5
+	}
+	report.PastRain = math.Round(report.PastRain*10) / 10
+	report.ExpectedRain = math.Round(report.ExpectedRain*10) / 10
+	report.Deficit = math.Max(0, math.Round((report.Needed-report.PastRain-report.ExpectedRain)*10)/10)
+
+	switch {
+	case report.PastRain >= report.Needed:
+		report.Advice = "skip"
+	case report.Deficit == 0:
+		report.Advice = "wait"
+	default:
+		report.Advice = "water"
+	}
+	report.Reason = report.reason()
+	return report
+}
+
+// reason explains the advice in the report's unit.
+func (r GardenReport) reason() string {
+	amount := func(v float64) string {
+		if r.Unit == "in" {
+			return strconv.FormatFloat(v, 'f', 2, 64) + " in"
+		}
+		return Format1(v) + " mm"
+	}
+	var reason string
+	switch r.Advice {
+	case "skip":
+		reason = fmt.Sprintf("%s of rain fell in the last %d days", amount(r.PastRain), r.PastDays)
+	case "wait":
+		reason = fmt.Sprintf("%s of rain is expected in the next %d days", amount(r.ExpectedRain), r.AheadDays)
+	default:
+		reason = fmt.Sprintf("%s short of the %s the garden needs", amount(r.Deficit), amount(r.Needed))
+	}
+	if r.Hot {
+		reason += ", with hot days ahead"
+	}
+	return reason
+}
+
+// Imperial returns a copy of the report in inches and °F.
+func (r GardenReport) Imperial() GardenReport {
+	inches := func(mm float64) float64 { return math.Round(mm/25.4*100) / 100 }
+	r.PastRain, r.ExpectedRain = inches(r.PastRain), inches(r.ExpectedRain)
+	r.Needed, r.Deficit = inches(r.Needed), inches(r.Deficit)
+	r.Unit, r.TempUnit = "in", "°F"
+	days := make([]GardenDay, len(r.Days))
+	for i, day := range r.Days {
+		day.Rain = inches(day.Rain)
+		day.TempMax = math.Round(celsiusToFahrenheit(day.TempMax)*10) / 10
+		days[i] = day
+	}
+	r.Days = days
+	r.Reason = r.reason()
+	return r
+}
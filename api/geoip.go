@@ -0,0 +1,293 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// geoIPCacheTTL is how long online lookups are kept. Databases are read on every lookup.
+const geoIPCacheTTL = 24 * time.Hour
+
+// GeoIPConfig configures the location and network lookup of the public IP address.
+type GeoIPConfig struct {
+	// Database is a GeoLite2-City or GeoLite2-Country (or compatible) .mmdb file
+	Database string `json:"database,omitempty"`
+	// ASNDatabase is a GeoLite2-ASN .mmdb file
+	ASNDatabase string `json:"asnDatabase,omitempty"`
+	// Provider is an online lookup used when no database is set: "ip-api" or "ipinfo"
+	Provider string `json:"provider,omitempty"`
+	// Token is the ipinfo access token (optional for low volumes).
+	// TokenFile and TokenEnv read it from a secret file or environment variable
+	Token     string `json:"token,omitempty"`
+	TokenFile string `json:"tokenFile,omitempty"`
+	TokenEnv  string `json:"tokenEnv,omitempty"`
+}
+
+// Validate checks the GeoIP settings.
+func (c GeoIPConfig) Validate() error {
+	if c.Database == "" && c.ASNDatabase == "" && c.Provider == "" {
+		return fmt.Errorf("geoip: database, asnDatabase or provider is required")
+	}
+	switch c.Provider {
+	case "", "ip-api", "ipinfo":
+	default:
+		return fmt.Errorf("geoip: provider must be ip-api or ipinfo")
+	}
+	return nil
+}
+
+// GeoIPInfo is where an IP address is and which network announces it.
+type GeoIPInfo struct {
+	Country     string `json:"country,omitempty"`
+	CountryCode string `json:"countryCode,omitempty"` // ISO 3166-1 alpha-2
+	Region      string `json:"region,omitempty"`
+	City        string `json:"city,omitempty"`
+	ASN         uint   `json:"asn,omitempty"`
+	ISP         string `json:"isp,omitempty"` // Organization of the autonomous system
+	Source      string `json:"source"`        // mmdb, ip-api or ipinfo
+}
+
+// GeoIPProvider looks up an IP address.
+type GeoIPProvider interface {
+	Lookup(ctx context.Context, ip net.IP) (*GeoIPInfo, error)
+}
+
+// GeoIPResolver answers GeoIP lookups from the configured provider.
+type GeoIPResolver struct {
+	mu       sync.Mutex
+	provider GeoIPProvider
+	cache    map[string]geoIPCacheEntry
+}
+
+// geoIPCacheEntry is a cached online lookup.
+type geoIPCacheEntry struct {
+	info    *GeoIPInfo
+	expires time.Time
+}
+
+// Global GeoIP resolver instance
+var geoIPResolver = &GeoIPResolver{cache: make(map[string]geoIPCacheEntry)}
+
+// GetGeoIPResolver returns the global GeoIP resolver instance.
+func GetGeoIPResolver() *GeoIPResolver {
+	return geoIPResolver
+}
+
+// Configure opens the databases or sets up the online provider.
+func (gr *GeoIPResolver) Configure(cfg GeoIPConfig) error {
+	var provider GeoIPProvider
+	if cfg.Database != "" || cfg.ASNDatabase != "" {
+		p := &mmdbGeoIPProvider{}
+		var err error
+		if cfg.Database != "" {
+			if p.city, err = OpenMMDB(cfg.Database); err != nil {
+				return fmt.Errorf("geoip: %w", err)
+			}
+		}
+		if cfg.ASNDatabase != "" {
+			if p.asn, err = OpenMMDB(cfg.ASNDatabase); err != nil {
+				return fmt.Errorf("geoip: %w", err)
+			}
+		}
+		provider = p
+	} else {
+		token, err := ResolveSecret(cfg.Token, cfg.TokenFile, cfg.TokenEnv)
+		if err != nil {
+			return fmt.Errorf("geoip: %w", err)
+		}
+		provider = &onlineGeoIPProvider{name: cfg.Provider, token: token}
+	}
+
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+	gr.provider = provider
+	gr.cache = make(map[string]geoIPCacheEntry)
+	return nil
+}
+
+// Enabled reports whether a provider is configured.
+func (gr *GeoIPResolver) Enabled() bool {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+	return gr.provider != nil
+}
+
+// Lookup returns the location and network of an IP address. Online results are cached.
+func (gr *GeoIPResolver) Lookup(ctx context.Context, ip string) (*GeoIPInfo, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil, errors.New("invalid IP address")
+	}
+	gr.mu.Lock()
+	provider := gr.provider
+	entry, cached := gr.cache[ip]
+	gr.mu.Unlock()
+	if provider == nil {
+		return nil, errors.New("geoip not configured")
+	}
+	if cached && time.Now().Before(entry.expires) {
+		return entry.info, nil
+	}
+
+	info, err := provider.Lookup(ctx, parsed)
+	if err != nil {
+		return nil, err
+	}
+	if _, online := provider.(*onlineGeoIPProvider); online {
+		gr.mu.Lock()
+		gr.cache[ip] = geoIPCacheEntry{info: info, expires: time.Now().Add(geoIPCacheTTL)}
+		gr.mu.Unlock()
+	}
+	return info, nil
+}
+
+// LookupGeoIP enriches an IP address when GeoIP is configured, or returns nil.
+func LookupGeoIP(ctx context.Context, ip string) *GeoIPInfo {
+	if !GetGeoIPResolver().Enabled() {
+		return nil
+	}
+	info, err := GetGeoIPResolver().Lookup(ctx, ip)
+	if err != nil {
+		GetDebugLogger().Logf("geoip", "lookup of %s failed: %v", ip, err)
+		return nil
+	}
+	return info
+}
+
+// mmdbGeoIPProvider reads GeoLite2 City/Country and ASN databases.
+type mmdbGeoIPProvider struct {
+	city *MMDBReader
+	asn  *MMDBReader
+}
+
+func (p *mmdbGeoIPProvider) Lookup(_ context.Context, ip net.IP) (*GeoIPInfo, error) {
+	info := &GeoIPInfo{Source: "mmdb"}
+	if p.city != nil {
+		rec, err := p.city.Lookup(ip)
+		if err != nil {
+			return nil, err
+		}
+		country := mmdbPath(rec, "country")
+		if country == nil {
+			country = mmdbPath(rec, "registered_country")
+		}
+		info.CountryCode, _ = mmdbPath(country, "iso_code").(string)
+		info.Country, _ = mmdbPath(country, "names", "en").(string)
+		info.City, _ = mmdbPath(rec, "city", "names", "en").(string)
+		if subdivisions, ok := mmdbPath(rec, "subdivisions").([]any); ok && len(subdivisions) > 0 {
+			info.Region, _ = mmdbPath(subdivisions[0], "names", "en").(string)
+		}
+	}
+	if p.asn != nil {
+		rec, err := p.asn.Lookup(ip)
+		if err != nil {
+			return nil, err
+		}
+		if asn, ok := mmdbPath(rec, "autonomous_system_number").(uint64); ok {
+			info.ASN = uint(asn)
+		}
+		info.ISP, _ = mmdbPath(rec, "autonomous_system_organization").(string)
+	}
+	return info, nil
+}
+
+// mmdbPath walks nested maps of a decoded MaxMind DB record.
+func mmdbPath(value any, keys ...string) any {
+	for _, k := range keys {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = m[k]
+	}
+	return value
+}
+
+// onlineGeoIPProvider asks ip-api.com or ipinfo.io.
+type onlineGeoIPProvider struct {
+	name  string
+	token string
+}
+
+func (p *onlineGeoIPProvider) Lookup(ctx context.Context, ip net.IP) (*GeoIPInfo, error) {
+	cctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if p.name == "ipinfo" {
+		u := "https://ipinfo.io/" + url.PathEscape(ip.String()) + "/json"
+		if p.token != "" {
+			u += "?token=" + url.QueryEscape(p.token)
+		}
+		var resp struct {
+			City    string `json:"city"`
+			Region  string `json:"region"`
+			Country string `json:"country"`
+			Org     string `json:"org"` // e.g. "AS3320 Deutsche Telekom AG"
+		}
+		if err := geoIPFetch(cctx, u, &resp); err != nil {
+			return nil, err
+		}
+		info := &GeoIPInfo{Source: "ipinfo", CountryCode: resp.Country, Region: resp.Region, City: resp.City, ISP: resp.Org}
+		if as, org, ok := strings.Cut(resp.Org, " "); ok && strings.HasPrefix(as, "AS") {
+			if n, err := strconv.ParseUint(as[2:], 10, 32); err == nil {
+				info.ASN, info.ISP = uint(n), org
+			}
+		}
+		return info, nil
+	}
+
+	// The free ip-api.com endpoint is only served over plain HTTP
+	u := "http://ip-api.com/json/" + url.PathEscape(ip.String()) + "?fields=status,message,country,countryCode,regionName,city,isp,as"
+	var resp struct {
+		Status      string `json:"status"`
+		Message     string `json:"message"`
+		Country     string `json:"country"`
+		CountryCode string `json:"countryCode"`
+		RegionName  string `json:"regionName"`
+		City        string `json:"city"`
+		ISP         string `json:"isp"`
+		AS          string `json:"as"` // e.g. "AS3320 Deutsche Telekom AG"
+	}
+	if err := geoIPFetch(cctx, u, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != "success" {
+		return nil, fmt.Errorf("ip-api: %s", resp.Message)
+	}
+	info := &GeoIPInfo{Source: "ip-api", Country: resp.Country, CountryCode: resp.CountryCode, Region: resp.RegionName, City: resp.City, ISP: resp.ISP}
+	if as, _, ok := strings.Cut(resp.AS, " "); ok && strings.HasPrefix(as, "AS") {
+		if n, err := strconv.ParseUint(as[2:], 10, 32); err == nil {
+			info.ASN = uint(n)
+		}
+	}
+	return info, nil
+}
+
+// geoIPFetch fetches and decodes a JSON lookup.
+func geoIPFetch(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	req.Header.Set("Accept", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return errors.New("geoip http status " + res.Status)
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, 64<<10)).Decode(v)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"log"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -16,7 +15,8 @@ var githubCache = &GitHubCache{}
 
 // githubHTTPClient is an HTTP client with proper timeouts for GitHub API requests
 var githubHTTPClient = &http.Client{
-	Timeout: 15 * time.Second,
+	Timeout:   15 * time.Second,
+	Transport: TimedTransport("github", nil),
 }
 
 // makeGitHubRequest creates and executes a GitHub API request with proper headers
@@ -51,11 +51,13 @@ func FetchGitHubRepos(ctx context.Context) (GitHubUserRepos, GitHubOrgRepos, err
 	}
 
 	if hasCachedData && timeSinceLastFetch < minWaitTime {
+		RecordTiming(ctx, "github", 0, TimingHit)
 		return cachedUserRepos, cachedOrgRepos, nil
 	}
 
 	if timeSinceLastFetch < 5*time.Minute {
 		if hasCachedData {
+			RecordTiming(ctx, "github", 0, TimingHit)
 			return cachedUserRepos, cachedOrgRepos, nil
 		}
 		return GitHubUserRepos{Error: "Rate limited. Please wait a few minutes."},
@@ -695,7 +697,7 @@ func FetchGitHubIssues(ctx context.Context, name, accountType, token, sort, orde
 	}
 
 	u := "https://api.github.com/search/issues?q=" + searchQuery + "&sort=" + sort + "&order=" + order + "&per_page=100"
-	log.Printf("[github] Fetching issues from: %s", u)
+	Logger("github").Debug("fetching issues", "url", u)
 	req, _ := http.NewRequestWithContext(cctx, http.MethodGet, u, nil)
 	req.Header.Set("User-Agent", "lan-index/1.0")
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
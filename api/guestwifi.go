@@ -0,0 +1,355 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// guestWiFiFile holds the guest network credentials across restarts.
+const guestWiFiFile = "guest-wifi.json"
+
+// guestPasswordAlphabet leaves out characters that are easy to misread (0/O, 1/l/I).
+const guestPasswordAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// defaultGuestPasswordLength is the length of generated guest passwords.
+const defaultGuestPasswordLength = 12
+
+// GuestWiFiConfig configures automatic rotation of the guest network password.
+type GuestWiFiConfig struct {
+	Rotate         string                  `json:"rotate,omitempty"` // daily, weekly, monthly or a duration such as "72h" or "14d"
+	PasswordLength int                     `json:"passwordLength,omitempty"`
+	OpenWrt        *GuestWiFiOpenWrtConfig `json:"openwrt,omitempty"`
+	UniFi          *GuestWiFiUniFiConfig   `json:"unifi,omitempty"`
+	// Public also shows the credentials to clients outside the local network (default: local only)
+	Public bool `json:"public,omitempty"`
+}
+
+// GuestWiFiOpenWrtConfig sets the key of a wifi-iface section on an OpenWrt router.
+type GuestWiFiOpenWrtConfig struct {
+	OpenWrtConfig
+	Section string `json:"section"` // wifi-iface section in /etc/config/wireless, e.g. "guest"
+}
+
+// GuestWiFiUniFiConfig sets the passphrase of a UniFi wireless network.
+type GuestWiFiUniFiConfig struct {
+	UniFiConfig
+	WLAN string `json:"wlan"` // Wireless network ID or SSID
+}
+
+// Validate checks the rotation schedule and router integration.
+func (c GuestWiFiConfig) Validate() error {
+	if c.OpenWrt != nil && c.UniFi != nil {
+		return fmt.Errorf("guestWifi: configure either openwrt or unifi, not both")
+	}
+	if c.OpenWrt != nil {
+		if err := c.OpenWrt.Validate(); err != nil {
+			return fmt.Errorf("guestWifi.openwrt: %w", err)
+		}
+		if c.OpenWrt.Section == "" {
+			return fmt.Errorf("guestWifi.openwrt: section is required")
+		}
+	}
+	if c.UniFi != nil {
+		if err := c.UniFi.Validate(); err != nil {
+			return fmt.Errorf("guestWifi.unifi: %w", err)
+		}
+		if c.UniFi.WLAN == "" {
+			return fmt.Errorf("guestWifi.unifi: wlan is required")
+		}
+	}
+	if c.Rotate != "" {
+		if c.OpenWrt == nil && c.UniFi == nil {
+			return fmt.Errorf("guestWifi: rotation needs an openwrt or unifi router")
+		}
+		if _, err := c.rotateInterval(); err != nil {
+			return err
+		}
+	}
+	if c.PasswordLength != 0 && (c.PasswordLength < 8 || c.PasswordLength > 63) {
+		return fmt.Errorf("guestWifi: passwordLength must be between 8 and 63")
+	}
+	return nil
+}
+
+// rotateInterval returns the rotation period, or 0 if rotation is disabled.
+func (c GuestWiFiConfig) rotateInterval() (time.Duration, error) {
+	switch c.Rotate {
+	case "":
+		return 0, nil
+	case "daily":
+		return 24 * time.Hour, nil
+	case "weekly":
+		return 7 * 24 * time.Hour, nil
+	case "monthly":
+		return 30 * 24 * time.Hour, nil
+	}
+	d, err := ParseHistoryRange(c.Rotate)
+	if err != nil || d < time.Hour {
+		return 0, fmt.Errorf("guestWifi: rotate must be daily, weekly, monthly or a duration of at least 1h")
+	}
+	return d, nil
+}
+
+// GuestWiFi holds the guest network credentials shown to visitors.
+type GuestWiFi struct {
+	SSID      string     `json:"ssid"`
+	Password  string     `json:"password,omitempty"`
+	Security  string     `json:"security"` // WPA (WPA/WPA2/WPA3), WEP or nopass
+	Hidden    bool       `json:"hidden,omitempty"`
+	RotatedAt *time.Time `json:"rotatedAt,omitempty"`
+}
+
+// escapeWiFiQR escapes the special characters of the WIFI: QR code format.
+func escapeWiFiQR(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, `:`, `\:`, `"`, `\"`).Replace(s)
+}
+
+// QRPayload returns the WIFI: string understood by phone cameras.
+func (g GuestWiFi) QRPayload() string {
+	if g.SSID == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("WIFI:T:" + g.Security + ";S:" + escapeWiFiQR(g.SSID) + ";")
+	if g.Security != "nopass" {
+		b.WriteString("P:" + escapeWiFiQR(g.Password) + ";")
+	}
+	if g.Hidden {
+		b.WriteString("H:true;")
+	}
+	b.WriteString(";")
+	return b.String()
+}
+
+// Validate checks the credentials and normalizes the security type.
+func (g *GuestWiFi) Validate() error {
+	g.SSID = strings.TrimSpace(g.SSID)
+	if g.SSID == "" || len(g.SSID) > 32 {
+		return fmt.Errorf("SSID must be 1-32 characters")
+	}
+	switch strings.ToUpper(g.Security) {
+	case "", "WPA", "WPA2", "WPA3":
+		g.Security = "WPA"
+	case "WEP":
+		g.Security = "WEP"
+	case "NOPASS", "NONE", "OPEN":
+		g.Security = "nopass"
+		g.Password = ""
+		return nil
+	default:
+		return fmt.Errorf("unknown security type %q (use WPA, WEP or nopass)", g.Security)
+	}
+	if g.Security == "WPA" && (len(g.Password) < 8 || len(g.Password) > 63) {
+		return fmt.Errorf("WPA passwords must be 8-63 characters")
+	}
+	return nil
+}
+
+// GuestWiFiManager stores the guest network and rotates its password through the router.
+type GuestWiFiManager struct {
+	mu     sync.Mutex
+	config GuestWiFiConfig
+	wifi   GuestWiFi
+	loaded bool
+	// retryAt delays the next scheduled attempt after a failed rotation
+	retryAt time.Time
+}
+
+// Global guest Wi-Fi manager instance
+var guestWiFiManager = &GuestWiFiManager{}
+
+// GetGuestWiFiManager returns the global guest Wi-Fi manager instance.
+func GetGuestWiFiManager() *GuestWiFiManager {
+	return guestWiFiManager
+}
+
+// Configure sets the rotation schedule and router integration.
+func (gm *GuestWiFiManager) Configure(cfg GuestWiFiConfig) {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	gm.config = cfg
+}
+
+// load reads the credentials file. Caller must hold mu.
+func (gm *GuestWiFiManager) load() {
+	if gm.loaded {
+		return
+	}
+	gm.loaded = true
+	data, err := os.ReadFile(guestWiFiFile)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &gm.wifi); err != nil {
+		GetDebugLogger().Logf("guestwifi", "failed to parse %s: %v", guestWiFiFile, err)
+	}
+}
+
+// save writes the credentials file. Caller must hold mu.
+func (gm *GuestWiFiManager) save() error {
+	data, err := json.MarshalIndent(gm.wifi, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(guestWiFiFile, data, 0600)
+}
+
+// Visible reports whether the credentials may be shown to the client of a request.
+// Clients signed in with an API token count as local.
+func (gm *GuestWiFiManager) Visible(r *http.Request) bool {
+	trusted := IsLocalRequest(r) || !RequestIdentity(r).Anonymous
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	return trusted || gm.config.Public
+}
+
+// Get returns the guest network credentials.
+func (gm *GuestWiFiManager) Get() GuestWiFi {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	gm.load()
+	return gm.wifi
+}
+
+// NextRotation returns when the password will be rotated next, if rotation is enabled.
+func (gm *GuestWiFiManager) NextRotation() *time.Time {
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	gm.load()
+	interval, _ := gm.config.rotateInterval()
+	if interval == 0 || gm.wifi.SSID == "" || gm.wifi.Security == "nopass" {
+		return nil
+	}
+	next := time.Now()
+	if gm.wifi.RotatedAt != nil {
+		next = gm.wifi.RotatedAt.Add(interval)
+	}
+	if gm.retryAt.After(next) {
+		next = gm.retryAt
+	}
+	return &next
+}
+
+// Set stores new credentials. They are only pushed to the router by Rotate.
+func (gm *GuestWiFiManager) Set(wifi GuestWiFi) (GuestWiFi, error) {
+	if err := wifi.Validate(); err != nil {
+		return GuestWiFi{}, err
+	}
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	gm.load()
+	wifi.RotatedAt = gm.wifi.RotatedAt
+	gm.wifi = wifi
+	return gm.wifi, gm.save()
+}
+
+// generateGuestPassword returns a random password without ambiguous characters.
+func generateGuestPassword(length int) (string, error) {
+	if length == 0 {
+		length = defaultGuestPasswordLength
+	}
+	max := big.NewInt(int64(len(guestPasswordAlphabet)))
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = guestPasswordAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// Rotate generates a new password, applies it on the router and stores it.
+// The stored password is only changed when the router accepted it.
+func (gm *GuestWiFiManager) Rotate(ctx context.Context) (GuestWiFi, error) {
+	gm.mu.Lock()
+	gm.load()
+	cfg := gm.config
+	current := gm.wifi
+	gm.mu.Unlock()
+
+	if current.SSID == "" {
+		return GuestWiFi{}, fmt.Errorf("guest network is not set up")
+	}
+	if current.Security == "nopass" {
+		return GuestWiFi{}, fmt.Errorf("guest network has no password")
+	}
+	if cfg.OpenWrt == nil && cfg.UniFi == nil {
+		return GuestWiFi{}, fmt.Errorf("no router integration configured")
+	}
+
+	password, err := generateGuestPassword(cfg.PasswordLength)
+	if err != nil {
+		return GuestWiFi{}, err
+	}
+	if err := applyGuestPassword(ctx, cfg, password); err != nil {
+		return GuestWiFi{}, err
+	}
+
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	now := time.Now()
+	gm.wifi.Password = password
+	gm.wifi.RotatedAt = &now
+	if err := gm.save(); err != nil {
+		return GuestWiFi{}, err
+	}
+	GetDebugLogger().Logf("guestwifi", "rotated password of %s", gm.wifi.SSID)
+	GetWSManager().BroadcastTopic(TopicForTimer("guestwifi"), map[string]interface{}{
+		"type":      "refresh",
+		"module":    "guestwifi",
+		"timestamp": now.Unix(),
+	})
+	return gm.wifi, nil
+}
+
+// applyGuestPassword sets the password on the configured router.
+func applyGuestPassword(ctx context.Context, cfg GuestWiFiConfig, password string) error {
+	switch {
+	case cfg.OpenWrt != nil:
+		ubus := NewUbusClient(cfg.OpenWrt.OpenWrtConfig)
+		if err := ubus.Call(ctx, "uci", "set", map[string]any{
+			"config":  "wireless",
+			"section": cfg.OpenWrt.Section,
+			"values":  map[string]any{"key": password},
+		}, nil); err != nil {
+			return err
+		}
+		if err := ubus.Call(ctx, "uci", "commit", map[string]any{"config": "wireless"}, nil); err != nil {
+			return err
+		}
+		return ubus.Call(ctx, "network", "reload", nil, nil)
+	case cfg.UniFi != nil:
+		return NewUniFiClient(cfg.UniFi.UniFiConfig).SetWLANPassphrase(ctx, cfg.UniFi.WLAN, password)
+	}
+	return nil
+}
+
+// Start rotates the password whenever the rotation interval has passed.
+func (gm *GuestWiFiManager) Start() {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		next := gm.NextRotation()
+		if next == nil || now.Before(*next) {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		if _, err := gm.Rotate(ctx); err != nil {
+			GetDebugLogger().Logf("guestwifi", "rotation failed: %v", err)
+			gm.mu.Lock()
+			gm.retryAt = now.Add(15 * time.Minute)
+			gm.mu.Unlock()
+		}
+		cancel()
+	}
+}
@@ -1,32 +1,66 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"math"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
 	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/skip2/go-qrcode"
 )
 
 // Handler holds the dependencies for API handlers.
 type Handler struct {
-	Config Config
+	mu     sync.RWMutex
+	config Config
 }
 
 // NewHandler creates a new API handler with the given configuration.
 func NewHandler(cfg Config) *Handler {
-	return &Handler{Config: cfg}
+	return &Handler{config: cfg}
+}
+
+// Config returns the handler's configuration.
+func (h *Handler) Config() Config {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.config
+}
+
+// SetConfig replaces the configuration, e.g. when the config file is reloaded.
+func (h *Handler) SetConfig(cfg Config) {
+	h.mu.Lock()
+	h.config = cfg
+	h.mu.Unlock()
+}
+
+// Title returns the page title: the one of the settings, then the one older dashboards saved
+// in Preferences, then the config's.
+func (h *Handler) Title() string {
+	if title := CurrentSettings().Title; title != "" {
+		return title
+	}
+	var title string
+	if GetStorage().GetAs("pageTitle", &title) && strings.TrimSpace(title) != "" {
+		return strings.TrimSpace(title)
+	}
+	return h.Config().Title
 }
 
 // RegisterHandlers registers all API handlers on the given mux.
@@ -35,57 +69,168 @@ func (h *Handler) RegisterHandlers(mux *http.ServeMux) {
 	mux.HandleFunc("/api/system", h.HandleSystem)
 	mux.HandleFunc("/api/disks", h.HandleDisks)
 	mux.HandleFunc("/api/disk", h.HandleDisk)
+	mux.HandleFunc("/api/shares", h.HandleShares)
+	mux.HandleFunc("/api/embed", h.HandleEmbed)
+	mux.HandleFunc("/api/oob", h.HandleOOB)
+	mux.HandleFunc("/api/oob/power", RequireCapability("oob.power", h.HandleOOBPower))
+	mux.HandleFunc("/api/command", h.HandleCommands)
+	mux.HandleFunc("/api/command/{name}", RequireWriteCapability("command.run", h.HandleCommand))
+	mux.HandleFunc("/api/exposure", RequireCapability("exposure.view", h.HandleExposure))
 	mux.HandleFunc("/api/cpuid", h.HandleCPUID)
 	mux.HandleFunc("/api/raminfo", h.HandleRAMInfo)
 	mux.HandleFunc("/api/firmware", h.HandleFirmware)
 	mux.HandleFunc("/api/systeminfo", h.HandleSystemInfo)
 	mux.HandleFunc("/api/baseboard", h.HandleBaseboard)
-	mux.HandleFunc("/api/weather", h.HandleWeather)
+	mux.HandleFunc("/api/timesync", h.HandleTimeSync)
+	mux.HandleFunc("/api/uptime/history", h.HandleUptimeHistory)
+	mux.HandleFunc("/api/timeline", RequireWriteCapability("timeline.write", h.HandleTimeline))
+	mux.HandleFunc("/api/auth", h.HandleAuth)
+	mux.HandleFunc("/api/auth/login", h.HandleAuthLogin)
+	mux.HandleFunc("/api/auth/oidc/login", h.HandleOIDCLogin)
+	mux.HandleFunc("/api/auth/oidc/callback", h.HandleOIDCCallback)
+	mux.HandleFunc("/api/sessions", h.HandleSessions)
+	mux.HandleFunc("/api/capabilities", h.HandleCapabilities)
+	mux.HandleFunc("/api/audit", RequireCapability("audit.view", h.HandleAudit))
+	mux.HandleFunc("/api/retention", RequireWriteCapability("retention.prune", h.HandleRetention))
+	mux.HandleFunc("/api/tokens", RequireCapability("tokens.manage", h.HandleTokens))
+	mux.HandleFunc("/api/webhooks", RequireCapability("webhooks.manage", h.HandleWebhooks))
+	mux.HandleFunc("/api/webhooks/in/{token}", h.HandleWebhookIn)
+	mux.HandleFunc("/api/banners", RequireWriteCapability("banners.dismiss", h.HandleBanners))
+	mux.HandleFunc("/api/presence", ModuleTracked("presence", h.HandlePresence))
+	mux.HandleFunc("/api/presence/owntracks", h.HandleOwnTracks)
+	mux.HandleFunc("/api/guest-wifi", RequireWriteCapability("guestwifi.edit", h.HandleGuestWiFi))
+	mux.HandleFunc("/api/guest-wifi/rotate", RequireCapability("guestwifi.rotate", h.HandleGuestWiFiRotate))
+	mux.HandleFunc("/api/guest-wifi/qr.png", h.HandleGuestWiFiQR)
+	mux.HandleFunc("/api/router", ModuleTracked("router", h.HandleRouter))
+	mux.HandleFunc("/api/virt", ModuleTracked("virt", h.HandleVirt))
+	mux.HandleFunc("/api/ups", h.HandleUPS)
+	mux.HandleFunc("/api/climate", h.HandleClimate)
+	mux.HandleFunc("/api/mqtt", h.HandleMQTT)
+	mux.HandleFunc("/api/quotes", OfflineCached("quotes", ModuleTracked("quotes", h.HandleQuotes)))
+	mux.HandleFunc("/api/currency", OfflineCached("currency", ModuleTracked("currency", h.HandleCurrency)))
+	mux.HandleFunc("/api/currency/convert", h.HandleCurrencyConvert)
+	mux.HandleFunc("/api/weather", OfflineCached("weather", ModuleTracked("weather", h.HandleWeather)))
+	mux.HandleFunc("/api/weather/air", OfflineCached("weather", h.HandleWeatherAir))
+	mux.HandleFunc("/api/weather/hourly", OfflineCached("weather", h.HandleWeatherHourly))
+	mux.HandleFunc("/api/locale", h.HandleLocale)
+	mux.HandleFunc("/api/settings", RequireWriteCapability("settings.write", h.HandleSettings))
+	mux.HandleFunc("/api/mdns", h.HandleMDNS)
+	mux.HandleFunc("/api/astro", ModuleTracked("astro", h.HandleAstro))
+	mux.HandleFunc("/api/garden", OfflineCached("garden", ModuleTracked("garden", h.HandleGarden)))
 	mux.HandleFunc("/api/search-engines", h.HandleSearchEngines)
+	mux.HandleFunc("/api/search-engines/add", RequireCapability("settings.write", h.HandleSearchEngineAdd))
+	mux.HandleFunc("/api/search-engines/update", RequireCapability("settings.write", h.HandleSearchEngineUpdate))
+	mux.HandleFunc("/api/search-engines/delete", RequireCapability("settings.write", h.HandleSearchEngineDelete))
+	mux.HandleFunc("/api/drop", RequireWriteCapability("drop.share", h.HandleDrop))
+	mux.HandleFunc("/api/drop/{id}", h.HandleDropGet)
+	mux.HandleFunc("/api/drop/delete", RequireCapability("drop.share", h.HandleDropDelete))
+	mux.HandleFunc("/api/notes", h.HandleNotes)
+	mux.HandleFunc("/api/notes/add", RequireCapability("settings.write", h.HandleNoteAdd))
+	mux.HandleFunc("/api/notes/update", RequireCapability("settings.write", h.HandleNoteUpdate))
+	mux.HandleFunc("/api/notes/delete", RequireCapability("settings.write", h.HandleNoteDelete))
+	mux.HandleFunc("/api/search/resolve", h.HandleSearchResolve)
+	mux.HandleFunc("/api/search/history", RequireWriteCapability("settings.write", h.HandleSearchHistory))
+	mux.HandleFunc("/api/search/history/purge", RequireCapability("settings.write", h.HandleSearchHistoryPurge))
 	mux.HandleFunc("/api/search/history/filter", h.HandleSearchHistoryFilter)
 	mux.HandleFunc("/api/search/autocomplete", h.HandleSearchAutocomplete)
+	mux.HandleFunc("/api/search/local", h.HandleSearchLocal)
 	mux.HandleFunc("/api/bookmarks", h.HandleBookmarks)
+	mux.HandleFunc("/api/bookmarks/import", RequireCapability("settings.write", h.HandleBookmarkImport))
+	mux.HandleFunc("/api/bookmarks/add", RequireCapability("settings.write", h.HandleBookmarkAdd))
+	mux.HandleFunc("/api/bookmarks/update", RequireCapability("settings.write", h.HandleBookmarkUpdate))
+	mux.HandleFunc("/api/bookmarks/delete", RequireCapability("settings.write", h.HandleBookmarkDelete))
+	mux.HandleFunc("/api/bookmarks/folders", RequireWriteCapability("settings.write", h.HandleBookmarkFolders))
+	mux.HandleFunc("/api/tools/password", h.HandleToolsPassword)
+	mux.HandleFunc("/api/tools/hash", h.HandleToolsHash)
+	mux.HandleFunc("/api/tools/time", h.HandleToolsTime)
+	mux.HandleFunc("/api/tools/format", h.HandleToolsFormat)
+	mux.HandleFunc("/api/tools/httpreq", RequireCapability("tools.httpreq", h.HandleToolsHTTPRequest))
 	mux.HandleFunc("/api/modules", h.HandleModules)
+	mux.HandleFunc("/api/modules/health", h.HandleModuleHealth)
+	mux.HandleFunc("/api/modules/instances", h.HandleModuleInstances)
+	mux.HandleFunc("/api/modules/schema", h.HandleModuleSchema)
+	mux.HandleFunc("/api/connectivity", h.HandleConnectivity)
 	mux.HandleFunc("/api/calendar/process", h.HandleCalendarProcess)
 	mux.HandleFunc("/api/calendar/month", h.HandleCalendarMonth)
 	mux.HandleFunc("/api/calendar/week", h.HandleCalendarWeek)
 	mux.HandleFunc("/api/calendar/events-for-date", h.HandleCalendarEventsForDate)
-	mux.HandleFunc("/api/calendar/ics", h.HandleICSCalendars)
-	mux.HandleFunc("/api/calendar/ics/fetch", h.HandleICSFetch)
-	mux.HandleFunc("/api/calendar/ics/refresh", h.HandleICSRefresh)
+	mux.HandleFunc("/api/calendar/ics", RequireWriteCapability("settings.write", h.HandleICSCalendars))
+	mux.HandleFunc("/api/calendar/ics/fetch", RateLimited(RateLimitICS, h.HandleICSFetch))
+	mux.HandleFunc("/api/calendar/ics/refresh", ModuleTracked("calendar", h.HandleICSRefresh))
+	mux.HandleFunc("/api/calendar/birthdays", RequireWriteCapability("settings.write", ModuleTracked("calendar", h.HandleCalendarBirthdays)))
+	mux.HandleFunc("/api/clocks", h.HandleClocks)
+	mux.HandleFunc("/api/clocks/zones", h.HandleClockZones)
 	mux.HandleFunc("/api/todos/process", h.HandleTodosProcess)
 	mux.HandleFunc("/api/geocode", h.HandleGeocode)
-	mux.HandleFunc("/api/github", h.HandleGitHub)
-	mux.HandleFunc("/api/github/repos", h.HandleGitHubRepos)
-	mux.HandleFunc("/api/github/prs", h.HandleGitHubPRs)
-	mux.HandleFunc("/api/github/commits", h.HandleGitHubCommits)
-	mux.HandleFunc("/api/github/issues", h.HandleGitHubIssues)
-	mux.HandleFunc("/api/github/stats", h.HandleGitHubStats)
-	mux.HandleFunc("/api/ip", h.HandleIP)
-	mux.HandleFunc("/api/favicon", h.HandleFavicon)
-	mux.HandleFunc("/api/monitor", h.HandleMonitor)
-	mux.HandleFunc("/api/snmp", h.HandleSNMP)
-	mux.HandleFunc("/api/speedplane", h.HandleSpeedplane)
-	mux.HandleFunc("/api/dnsplane", h.HandleDNSplane)
-	mux.HandleFunc("/api/rss", h.HandleRSS)
-	mux.HandleFunc("/api/config/upload", h.HandleConfigUpload)
-	mux.HandleFunc("/api/config/list", h.HandleConfigList)
-	mux.HandleFunc("/api/config/download", h.HandleConfigDownload)
-	mux.HandleFunc("/api/config/delete", h.HandleConfigDelete)
-	mux.HandleFunc("/api/storage/sync", h.HandleStorageSync)
+	mux.HandleFunc("/api/github", RateLimited(RateLimitGitHub, OfflineCached("github", ModuleTracked("github", h.HandleGitHub))))
+	mux.HandleFunc("/api/github/repos", RateLimited(RateLimitGitHub, OfflineCached("github", ModuleTracked("github", h.HandleGitHubRepos))))
+	mux.HandleFunc("/api/github/prs", RateLimited(RateLimitGitHub, OfflineCached("github", ModuleTracked("github", h.HandleGitHubPRs))))
+	mux.HandleFunc("/api/changelog", RateLimited(RateLimitGitHub, h.HandleChangelog))
+	mux.HandleFunc("/api/github/commits", RateLimited(RateLimitGitHub, OfflineCached("github", ModuleTracked("github", h.HandleGitHubCommits))))
+	mux.HandleFunc("/api/github/issues", RateLimited(RateLimitGitHub, OfflineCached("github", ModuleTracked("github", h.HandleGitHubIssues))))
+	mux.HandleFunc("/api/github/stats", RateLimited(RateLimitGitHub, OfflineCached("github", ModuleTracked("github", h.HandleGitHubStats))))
+	mux.HandleFunc("/api/ip", OfflineCached("ip", h.HandleIP))
+	mux.HandleFunc("/api/ip/history", h.HandlePublicIPHistory)
+	mux.HandleFunc("/api/favicon", RateLimited(RateLimitFavicon, h.HandleFavicon))
+	mux.HandleFunc("/api/favicon/img", RateLimited(RateLimitFavicon, h.HandleFaviconImage))
+	mux.HandleFunc("/api/icons", h.HandleIcons)
+	mux.HandleFunc("/api/icons/{slug}", RateLimited(RateLimitFavicon, h.HandleIcon))
+	mux.HandleFunc("/api/identify", RateLimited(RateLimitFavicon, h.HandleIdentify))
+	mux.HandleFunc("/api/monitor", RateLimited(RateLimitMonitor, h.HandleMonitor))
+	mux.HandleFunc("/api/snmp", RateLimited(RateLimitSNMP, ModuleTracked("snmp", h.HandleSNMP)))
+	mux.HandleFunc("/api/snmp/interfaces", RateLimited(RateLimitSNMP, ModuleTracked("snmp", h.HandleSNMPInterfaces)))
+	mux.HandleFunc("/api/snmp/profiles", RequireCapability("snmp.profiles", h.HandleSNMPProfiles))
+	mux.HandleFunc("/api/speedplane", ModuleTracked("speedplane", h.HandleSpeedplane))
+	mux.HandleFunc("/api/dnsplane", ModuleTracked("dnsplane", h.HandleDNSplane))
+	mux.HandleFunc("/api/rss", RateLimited(RateLimitRSS, OfflineCached("rss", ModuleTracked("rss", h.HandleRSS))))
+	mux.HandleFunc("/api/config/upload", RequireCapability("configs.manage", h.HandleConfigUpload))
+	mux.HandleFunc("/api/config/list", RequireCapability("configs.manage", h.HandleConfigList))
+	mux.HandleFunc("/api/config/download", RequireCapability("configs.manage", h.HandleConfigDownload))
+	mux.HandleFunc("/api/config/delete", RequireCapability("configs.manage", h.HandleConfigDelete))
+	mux.HandleFunc("/api/config/lint", RateLimited(RateLimitICS, h.HandleConfigLint))
+	mux.HandleFunc("/api/storage/sync", RequireCapability("settings.write", h.HandleStorageSync))
 	mux.HandleFunc("/api/storage/get", h.HandleStorageGet)
 	mux.HandleFunc("/api/storage/get-all", h.HandleStorageGetAll)
 	mux.HandleFunc("/api/storage/status", h.HandleStorageStatus)
+	mux.HandleFunc("/api/profiles", RequireWriteCapability("profiles.manage", h.HandleProfiles))
 	mux.HandleFunc("/api/layout/validate", h.HandleLayoutValidate)
 	mux.HandleFunc("/api/layout/process", h.HandleLayoutProcess)
+	mux.HandleFunc("/api/layout/resolve", h.HandleLayoutResolve)
+	mux.HandleFunc("/api/pages", h.HandlePages)
+	mux.HandleFunc("/api/bootstrap", BootstrapHandler(mux))
+	mux.HandleFunc("/api/pages/create", RequireCapability("settings.write", h.HandlePageCreate))
+	mux.HandleFunc("/api/pages/clone", RequireCapability("settings.write", h.HandlePageClone))
+	mux.HandleFunc("/api/pages/switch", RequireCapability("settings.write", h.HandlePageSwitch))
+	mux.HandleFunc("/api/pages/delete", RequireCapability("settings.write", h.HandlePageDelete))
+	mux.HandleFunc("/api/layout/history", h.HandleLayoutHistory)
+	mux.HandleFunc("/api/layout/rollback", RequireCapability("settings.write", h.HandleLayoutRollback))
 	mux.HandleFunc("/api/modules/process-prefs", h.HandleModulePrefsProcess)
 	mux.HandleFunc("/api/modules/batch", h.HandleModulesBatch)
 	mux.HandleFunc("/api/modules/config", h.HandleModuleConfig)
 	mux.HandleFunc("/api/graphs/aggregate", h.HandleGraphHistoryAggregate)
+	mux.HandleFunc("/api/graphs/history", h.HandleGraphHistory)
+	mux.HandleFunc("/api/graphs/export", h.HandleGraphExport)
+	mux.HandleFunc("/api/graphs/import", RequireCapability("graphs.import", h.HandleGraphImport))
 	mux.HandleFunc("/api/storage/process", h.HandleStorageProcess)
 	mux.HandleFunc("/api/utils/validate-url", h.HandleValidateURL)
 	mux.HandleFunc("/api/utils/normalize-url", h.HandleNormalizeURL)
 	mux.HandleFunc("/api/utils/validate-input", h.HandleValidateInput)
+	mux.HandleFunc("/api/push/vapid-public-key", h.HandlePushVAPIDKey)
+	mux.HandleFunc("/api/push/subscribe", RequireWriteCapability("notifications.subscribe", h.HandlePushSubscribe))
+	mux.HandleFunc("/api/push/test", RequireCapability("notifications.test", h.HandlePushTest))
+	mux.HandleFunc("/api/alerts", h.HandleAlerts)
+	mux.HandleFunc("/api/smtp", h.HandleSMTPStatus)
+	mux.HandleFunc("/api/smtp/test", RequireCapability("notifications.test", h.HandleSMTPTest))
+	mux.HandleFunc("/api/digest", RequireWriteCapability("digest.send", h.HandleDigest))
+	mux.HandleFunc("/api/brief", h.HandleBrief)
+	mux.HandleFunc("/api/brief/audio", h.HandleBriefAudio)
+	mux.HandleFunc("/eink", h.HandleEink)
+	mux.HandleFunc("/api/stats", RequireWriteCapability("stats.manage", h.HandleStats))
+	mux.HandleFunc("/api/stats/usage", h.HandleUsageStats)
+	mux.HandleFunc("/api/store", h.HandleStore)
+	mux.HandleFunc("/api/notifications", h.HandleNotifications)
+	mux.HandleFunc("/api/monitor/history", h.HandleMonitorHistory)
+	mux.HandleFunc("/api/monitor/import", RequireCapability("settings.write", h.HandleMonitorImport))
 	mux.HandleFunc("/healthz", h.HandleHealthz)
 }
 
@@ -130,34 +275,60 @@ func (h *Handler) HandleSummary(w http.ResponseWriter, r *http.Request) {
 		},
 		Public: PublicIPInfo{},
 		Weather: WeatherInfo{
-			Enabled: h.Config.Weather.Enabled,
+			Enabled: h.Config().Weather.Enabled,
 		},
 	}
 
-	// Public IP
-	ip, err := PublicIP(ctx, h.Config.PublicIPTimeout)
-	if err != nil {
-		resp.Public.Error = err.Error()
-	} else {
-		resp.Public.IP = ip
-		resp.Public.PTR = ReverseDNS(ip, "1.1.1.1")
-	}
+	// The public IP, weather and metrics are fetched side by side, each sandboxed so a
+	// hanging or failing source only leaves its own part empty
+	var wg sync.WaitGroup
 
-	// Weather
-	if h.Config.Weather.Enabled && h.Config.Weather.Lat != "" && h.Config.Weather.Lon != "" {
-		wd, err := OpenMeteoSummary(ctx, h.Config.Weather.Lat, h.Config.Weather.Lon)
+	// Public IP
+	wg.Go(func() {
+		public, err := Sandboxed(ctx, "ip", func(ctx context.Context) (PublicIPInfo, error) {
+			ip, err := PublicIP(ctx, h.Config().PublicIPTimeout)
+			if err != nil {
+				return PublicIPInfo{}, err
+			}
+			GetPublicIPWatcher().Observe(ip)
+			return PublicIPInfo{IP: ip, PTR: ReverseDNS(ip, "1.1.1.1"), Geo: LookupGeoIP(ctx, ip)}, nil
+		})
 		if err != nil {
-			resp.Weather.Error = err.Error()
+			resp.Public.Error = err.Error()
 		} else {
-			resp.Weather.Summary = wd.Summary
-			resp.Weather.Forecast = wd.Forecast
+			resp.Public = public
 		}
-	} else if h.Config.Weather.Enabled {
+	})
+
+	// Weather
+	weather := h.Config().Weather
+	if weather.Enabled && weather.Lat != "" && weather.Lon != "" {
+		wg.Go(func() {
+			wd, err := Sandboxed(ctx, "weather", func(ctx context.Context) (WeatherData, error) {
+				return GetWeatherProvider(weather.Provider).Summary(ctx, weather.Lat, weather.Lon, weather.APIKey)
+			})
+			if err != nil {
+				resp.Weather.Error = err.Error()
+			} else {
+				resp.Weather.Summary = wd.Summary
+				resp.Weather.Forecast = wd.Forecast
+			}
+		})
+	} else if weather.Enabled {
 		resp.Weather.Summary = "Set your location in Preferences to enable weather."
 	}
 
 	// System metrics
-	resp.System = GetSystemMetrics(ctx)
+	wg.Go(func() {
+		system, err := Sandboxed(ctx, "system", func(ctx context.Context) (SystemMetrics, error) {
+			return GetMetricsCollector().Current(ctx), nil
+		})
+		if err == nil {
+			resp.System = system
+		}
+	})
+	wg.Wait()
+	ClientLocaleFromRequest(r).LocalizeWeather(&resp.Weather)
 
 	WriteJSON(w, resp)
 }
@@ -165,7 +336,7 @@ func (h *Handler) HandleSummary(w http.ResponseWriter, r *http.Request) {
 // HandleSystem returns system metrics.
 func (h *Handler) HandleSystem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	resp := GetSystemMetrics(ctx)
+	resp := GetMetricsCollector().Current(ctx)
 	WriteJSON(w, resp)
 }
 
@@ -265,29 +436,16 @@ func (h *Handler) HandleWeather(w http.ResponseWriter, r *http.Request) {
 	lat := r.URL.Query().Get("lat")
 	lon := r.URL.Query().Get("lon")
 
+	weather := h.Config().Weather
 	if lat == "" || lon == "" {
-		lat = h.Config.Weather.Lat
-		lon = h.Config.Weather.Lon
+		lat = weather.Lat
+		lon = weather.Lon
 	}
 
 	if lat != "" && lon != "" {
-		var wd WeatherData
-		var err error
-
-		provider := h.Config.Weather.Provider
-		if provider == "" {
-			provider = "openmeteo"
-		}
-
-		switch provider {
-		case "openweathermap":
-			wd, err = OpenWeatherMapSummary(ctx, lat, lon, h.Config.Weather.APIKey)
-		case "weatherapi":
-			wd, err = WeatherAPISummary(ctx, lat, lon, h.Config.Weather.APIKey)
-		default:
-			wd, err = OpenMeteoSummary(ctx, lat, lon)
-		}
-
+		done := StartTiming(ctx, "weather")
+		wd, err := FetchWeather(ctx, weather, lat, lon)
+		done("")
 		if err != nil {
 			resp.Error = err.Error()
 		} else {
@@ -296,13 +454,142 @@ func (h *Handler) HandleWeather(w http.ResponseWriter, r *http.Request) {
 			resp.Current = wd.Current
 			resp.Today = wd.Today
 			resp.Tomorrow = wd.Tomorrow
+			resp.Nowcast = wd.Nowcast
 		}
 	} else {
 		resp.Summary = "Set your location in Preferences to enable weather."
 	}
+	ClientLocaleFromRequest(r).LocalizeWeather(&resp)
+	WriteJSON(w, resp)
+}
+
+// HandleWeatherAir returns the air quality and pollen now and the worst of today and
+// tomorrow, for ?lat= and ?lon= or the configured location.
+func (h *Handler) HandleWeatherAir(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	latText := r.URL.Query().Get("lat")
+	lonText := r.URL.Query().Get("lon")
+	if latText == "" || lonText == "" {
+		latText, lonText = h.Config().Weather.Lat, h.Config().Weather.Lon
+	}
+	if latText == "" || lonText == "" {
+		WriteJSON(w, map[string]string{"error": "Set your location in Preferences to see the air quality."})
+		return
+	}
+	lat, errLat := strconv.ParseFloat(latText, 64)
+	lon, errLon := strconv.ParseFloat(lonText, 64)
+	if errLat != nil || errLon != nil || math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		WriteJSON(w, map[string]string{"error": "invalid lat or lon"})
+		return
+	}
+	air, err := CachedAir(r.Context(), strconv.FormatFloat(lat, 'f', -1, 64), strconv.FormatFloat(lon, 'f', -1, 64))
+	if err != nil {
+		WriteJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, air)
+}
+
+// HandleWeatherHourly returns the hourly forecast of the next ?hours= (default 24, at most
+// 48) for ?lat= and ?lon= or the configured location.
+func (h *Handler) HandleWeatherHourly(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	hours := 24
+	if text := r.URL.Query().Get("hours"); text != "" {
+		n, err := strconv.Atoi(text)
+		if err != nil || n < 1 || n > hourlyHours {
+			WriteJSON(w, map[string]string{"error": "hours must be between 1 and 48"})
+			return
+		}
+		hours = n
+	}
+	latText := r.URL.Query().Get("lat")
+	lonText := r.URL.Query().Get("lon")
+	if latText == "" || lonText == "" {
+		latText, lonText = h.Config().Weather.Lat, h.Config().Weather.Lon
+	}
+	if latText == "" || lonText == "" {
+		WriteJSON(w, map[string]string{"error": "Set your location in Preferences to see the forecast."})
+		return
+	}
+	lat, errLat := strconv.ParseFloat(latText, 64)
+	lon, errLon := strconv.ParseFloat(lonText, 64)
+	if errLat != nil || errLon != nil || math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		WriteJSON(w, map[string]string{"error": "invalid lat or lon"})
+		return
+	}
+	wd, err := CachedWeather(r.Context(), h.Config().Weather, strconv.FormatFloat(lat, 'f', -1, 64), strconv.FormatFloat(lon, 'f', -1, 64))
+	if err != nil {
+		WriteJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	hourly := wd.Hourly.Next(time.Now(), hours)
+	if hourly == nil {
+		WriteJSON(w, map[string]string{"error": "no hourly forecast available"})
+		return
+	}
+	WriteJSON(w, ClientLocaleFromRequest(r).LocalizeHourly(hourly))
+}
+
+// HandleSettings serves /api/settings: GET returns the general settings, POST changes the
+// fields of the body and leaves the others.
+func (h *Handler) HandleSettings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		s := CurrentSettings()
+		if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+			WriteJSON(w, map[string]string{"error": "Invalid JSON: " + err.Error()})
+			return
+		}
+		if err := SaveSettings(s); err != nil {
+			WriteJSON(w, map[string]string{"error": err.Error()})
+			return
+		}
+		Audit(r, "settings.update", "")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := h.settingsResponse()
+	if r.Method == http.MethodPost {
+		GetWSManager().Broadcast(map[string]interface{}{"type": "settings", "settings": resp})
+	}
 	WriteJSON(w, resp)
 }
 
+// settingsResponse returns the stored and effective settings.
+func (h *Handler) settingsResponse() SettingsResponse {
+	s := CurrentSettings()
+	effective := s
+	effective.Title = h.Title()
+	effective.FirstDayOfWeek = strings.ToLower(s.FirstWeekday().String())
+	if effective.TemperatureUnit == "" {
+		effective.TemperatureUnit = "celsius"
+	}
+	if effective.ByteUnits == "" {
+		effective.ByteUnits = "binary"
+	}
+	return SettingsResponse{Settings: s, Effective: effective, FirstDay: int(s.FirstWeekday())}
+}
+
+// HandleLocale returns the units, locale and time zone resolved for the request.
+func (h *Handler) HandleLocale(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, ClientLocaleFromRequest(r))
+}
+
+// HandleMDNS returns the name the dashboard is announced under on the LAN.
+func (h *Handler) HandleMDNS(w http.ResponseWriter, _ *http.Request) {
+	WriteJSON(w, GetMDNS().Status())
+}
+
 // HandleGeocode handles geocoding requests.
 func (h *Handler) HandleGeocode(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -339,12 +626,20 @@ func (h *Handler) HandleGitHub(w http.ResponseWriter, r *http.Request) {
 	WriteJSON(w, resp)
 }
 
+// githubToken returns the GitHub token of a request, the configured one when it has none.
+func (h *Handler) githubToken(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+	return h.Config().GitHubToken
+}
+
 // HandleGitHubRepos returns repos for a specific user/org.
 func (h *Handler) HandleGitHubRepos(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	name := r.URL.Query().Get("name")
 	repoType := r.URL.Query().Get("type")
-	token := r.URL.Query().Get("token")
+	token := h.githubToken(r)
 	sort := r.URL.Query().Get("sort")
 	order := r.URL.Query().Get("order")
 
@@ -375,7 +670,7 @@ func (h *Handler) HandleGitHubPRs(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	name := r.URL.Query().Get("name")
 	accountType := r.URL.Query().Get("type")
-	token := r.URL.Query().Get("token")
+	token := h.githubToken(r)
 	sort := r.URL.Query().Get("sort")
 	order := r.URL.Query().Get("order")
 
@@ -403,7 +698,7 @@ func (h *Handler) HandleGitHubCommits(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	name := r.URL.Query().Get("name")
 	accountType := r.URL.Query().Get("type")
-	token := r.URL.Query().Get("token")
+	token := h.githubToken(r)
 	sort := r.URL.Query().Get("sort")
 	order := r.URL.Query().Get("order")
 
@@ -431,7 +726,7 @@ func (h *Handler) HandleGitHubIssues(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	name := r.URL.Query().Get("name")
 	accountType := r.URL.Query().Get("type")
-	token := r.URL.Query().Get("token")
+	token := h.githubToken(r)
 	sort := r.URL.Query().Get("sort")
 	order := r.URL.Query().Get("order")
 
@@ -459,7 +754,7 @@ func (h *Handler) HandleGitHubStats(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	name := r.URL.Query().Get("name")
 	accountType := r.URL.Query().Get("type")
-	token := r.URL.Query().Get("token")
+	token := h.githubToken(r)
 
 	GetDebugLogger().Logf("api", "HandleGitHubStats called - name:%s, type:%s", name, accountType)
 
@@ -511,59 +806,90 @@ func (h *Handler) HandleIP(w http.ResponseWriter, r *http.Request) {
 		Public: PublicIPInfo{},
 	}
 
-	ip, err := PublicIP(ctx, h.Config.PublicIPTimeout)
+	ip, err := PublicIP(ctx, h.Config().PublicIPTimeout)
 	if err != nil {
 		resp.Public.Error = err.Error()
 	} else {
 		resp.Public.IP = ip
 		resp.Public.PTR = ReverseDNS(ip, "1.1.1.1")
+		resp.Public.Geo = LookupGeoIP(ctx, ip)
+		GetPublicIPWatcher().Observe(ip)
 	}
 	WriteJSON(w, resp)
 }
 
-// HandleFavicon fetches a favicon for a URL.
+// HandleFavicon serves GET /api/favicon?url=: the favicon of the URL's site as a data: URL,
+// from the favicon cache. refresh=1 fetches it again.
 func (h *Handler) HandleFavicon(w http.ResponseWriter, r *http.Request) {
 	targetURL := r.URL.Query().Get("url")
-	log.Printf("[favicon] Request for URL: %s", targetURL)
+	logger := Logger("favicon")
+	logger.Debug("favicon request", "url", targetURL)
 
 	if targetURL == "" {
-		log.Printf("[favicon] Error: Missing 'url' parameter")
 		WriteJSON(w, map[string]string{"error": "Missing 'url' parameter"})
 		return
 	}
 
-	parsed, err := url.Parse(targetURL)
+	origin, err := FaviconOrigin(targetURL)
 	if err != nil {
-		log.Printf("[favicon] Error parsing URL: %v", err)
+		logger.Debug("invalid favicon URL", "url", targetURL, "error", err)
 		WriteJSON(w, map[string]string{"error": "Invalid URL"})
 		return
 	}
-	origin := parsed.Scheme + "://" + parsed.Host
-	log.Printf("[favicon] Origin: %s", origin)
 
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(OutboundContext(r), 5*time.Second)
 	defer cancel()
 
-	faviconData, contentType, err := FetchFavicon(ctx, origin)
+	icon, err := GetFaviconCache().Get(ctx, origin, r.URL.Query().Get("refresh") == "1")
 	if err != nil {
-		log.Printf("[favicon] Error fetching favicon: %v", err)
+		logger.Debug("favicon not found", "origin", origin, "error", err)
 		WriteJSON(w, map[string]string{"error": err.Error()})
 		return
 	}
 
-	log.Printf("[favicon] Success! Got %d bytes, type: %s", len(faviconData), contentType)
+	logger.Debug("favicon served", "origin", origin, "bytes", len(icon.Data), "type", icon.ContentType)
+	WriteJSON(w, map[string]string{"favicon": icon.DataURL()})
+}
+
+// HandleFaviconImage serves GET /api/favicon/img?url=: the favicon of the URL's site as an
+// image from the favicon cache, for use as an <img> source. Browsers revalidate it with its
+// ETag. refresh=1 fetches it again.
+func (h *Handler) HandleFaviconImage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	origin, err := FaviconOrigin(r.URL.Query().Get("url"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	base64Data := base64.StdEncoding.EncodeToString(faviconData)
-	dataURL := "data:" + contentType + ";base64," + base64Data
+	ctx, cancel := context.WithTimeout(OutboundContext(r), 5*time.Second)
+	defer cancel()
+
+	icon, err := GetFaviconCache().Get(ctx, origin, r.URL.Query().Get("refresh") == "1")
+	if err != nil {
+		Logger("favicon").Debug("favicon not found", "origin", origin, "error", err)
+		w.Header().Set("Cache-Control", "private, max-age=3600")
+		http.Error(w, "Favicon not found", http.StatusNotFound)
+		return
+	}
 
-	WriteJSON(w, map[string]string{"favicon": dataURL})
+	w.Header().Set("Content-Type", icon.ContentType)
+	w.Header().Set("ETag", icon.ETag)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// SVG favicons are documents of another site; keep scripts in them from running
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	http.ServeContent(w, r, "", icon.Fetched, bytes.NewReader(icon.Data))
 }
 
 // HandleMonitor handles service monitoring requests.
 func (h *Handler) HandleMonitor(w http.ResponseWriter, r *http.Request) {
 	monType := r.URL.Query().Get("type")
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(OutboundContext(r), 10*time.Second)
 	defer cancel()
 
 	var result MonitorResult
@@ -626,114 +952,441 @@ func (h *Handler) HandleMonitor(w http.ResponseWriter, r *http.Request) {
 
 	default:
 		result.Error = "Invalid monitor type"
+		WriteJSON(w, result)
+		return
+	}
+
+	// Record state changes of stored monitors on the timeline and notify push subscribers
+	monitorKey := MonitorKey(r.URL.Query())
+	if name, stored := StoredMonitorName(monitorKey); stored {
+		GetTimeline().RecordMonitorState(monitorKey, name, result.Success, result.Error)
+		GetStore().RecordMonitorResult(monitorKey, name, result)
 	}
 
 	WriteJSON(w, result)
 }
 
-// HandleSNMP handles SNMP query requests.
-func (h *Handler) HandleSNMP(w http.ResponseWriter, r *http.Request) {
-	host := r.URL.Query().Get("host")
-	port := r.URL.Query().Get("port")
-	community := r.URL.Query().Get("community")
-	oid := r.URL.Query().Get("oid")
+// HandleTimeSync returns NTP synchronization state, offset and drift.
+func (h *Handler) HandleTimeSync(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
 
-	if host == "" || port == "" || community == "" || oid == "" {
-		WriteJSON(w, map[string]any{
-			"success": false,
-			"error":   "Missing required parameters: host, port, community, oid",
-		})
+	WriteJSON(w, GetTimeSyncStatus(ctx))
+}
+
+// HandleUptimeHistory returns recorded reboots and uptime milestones.
+func (h *Handler) HandleUptimeHistory(w http.ResponseWriter, _ *http.Request) {
+	WriteJSON(w, GetBootTracker().History())
+}
+
+// HandleTimeline returns the event timeline (GET) or records an incident (POST).
+func (h *Handler) HandleTimeline(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+
+		var since time.Time
+		if s := q.Get("since"); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				WriteJSON(w, map[string]any{"error": "Invalid 'since' parameter, expected RFC3339"})
+				return
+			}
+			since = t
+		}
+
+		var sources []string
+		if s := q.Get("source"); s != "" {
+			sources = strings.Split(s, ",")
+		}
+
+		limit := 100
+		if l, err := strconv.Atoi(q.Get("limit")); err == nil && l > 0 && l <= maxTimelineEvents {
+			limit = l
+		}
+
+		WriteJSON(w, map[string]any{"events": GetTimeline().Events(since, sources, limit)})
+
+	case http.MethodPost:
+		var ev TimelineEvent
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			WriteJSON(w, map[string]any{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		if strings.TrimSpace(ev.Title) == "" {
+			WriteJSON(w, map[string]any{"error": "Missing 'title'"})
+			return
+		}
+		switch ev.Severity {
+		case "", "info", "warning", "critical", "ok":
+		default:
+			WriteJSON(w, map[string]any{"error": "Invalid severity (info, warning, critical, ok)"})
+			return
+		}
+		ev.Source = TimelineSourceIncident
+		ev.Time = time.Time{}
+		WriteJSON(w, map[string]any{"success": true, "event": GetTimeline().Add(ev)})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleWebhooks lists (GET), creates (POST) or deletes (DELETE) incoming webhook tokens.
+func (h *Handler) HandleWebhooks(w http.ResponseWriter, r *http.Request) {
+	wm := GetWebhookManager()
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, map[string]any{"webhooks": wm.Tokens()})
+
+	case http.MethodPost:
+		var req struct {
+			Name   string `json:"name"`
+			Banner bool   `json:"banner"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteJSON(w, map[string]any{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		t, err := wm.CreateToken(strings.TrimSpace(req.Name), req.Banner)
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		Audit(r, "webhook.create", t.Name)
+		WriteJSON(w, map[string]any{"success": true, "webhook": t, "url": "/api/webhooks/in/" + t.Token})
+
+	case http.MethodDelete:
+		found, err := wm.DeleteToken(r.URL.Query().Get("token"))
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		if !found {
+			WriteJSON(w, map[string]any{"error": "Webhook not found"})
+			return
+		}
+		Audit(r, "webhook.delete", "")
+		WriteJSON(w, map[string]any{"success": true})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleWebhookIn receives an external event (generic JSON, GitHub, Grafana or Uptime Kuma).
+func (h *Handler) HandleWebhookIn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-	defer cancel()
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
 
-	result, err := QuerySNMP(ctx, host, port, community, oid)
+	ev, err := GetWebhookManager().Receive(r.PathValue("token"), r.Header, body)
 	if err != nil {
-		WriteJSON(w, map[string]any{
-			"success": false,
-			"error":   err.Error(),
-		})
+		GetDebugLogger().Logf("webhooks", "rejected webhook: %v", err)
+		if errors.Is(err, ErrUnknownWebhookToken) {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		WriteJSON(w, map[string]any{"error": err.Error()})
 		return
 	}
+	WriteJSON(w, map[string]any{"success": true, "event": ev})
+}
 
-	WriteJSON(w, map[string]any{
-		"success": true,
-		"value":   result,
-	})
+// HandleBanners lists (GET) or dismisses (DELETE) dashboard banners.
+func (h *Handler) HandleBanners(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, map[string]any{"banners": GetWebhookManager().Banners()})
+	case http.MethodDelete:
+		if !GetWebhookManager().DismissBanner(r.URL.Query().Get("id")) {
+			WriteJSON(w, map[string]any{"error": "Banner not found"})
+			return
+		}
+		WriteJSON(w, map[string]any{"success": true})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
 }
 
-// HandleSpeedplane handles Speedplane API requests.
-func (h *Handler) HandleSpeedplane(w http.ResponseWriter, r *http.Request) {
-	host := r.URL.Query().Get("host")
-	port := r.URL.Query().Get("port")
+// HandleSNMP handles SNMP query requests. op is get (default, one or more comma-separated
+// OIDs), bulk (one GETBULK request) or walk (the subtree below oid). Credentials come
+// from a saved profile or, for SNMPv2c, the community parameter.
+func (h *Handler) HandleSNMP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	host := q.Get("host")
+	port := q.Get("port")
+	oid := q.Get("oid")
+	op := q.Get("op")
 
-	if host == "" || port == "" {
+	if host == "" || oid == "" || (q.Get("community") == "" && q.Get("profile") == "") {
 		WriteJSON(w, map[string]any{
 			"success": false,
-			"error":   "Missing required parameters: host, port",
+			"error":   "Missing required parameters: host, oid and community or profile",
 		})
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
-	defer cancel()
+	profile, err := snmpProfileFromQuery(q)
+	if err != nil {
+		WriteJSON(w, map[string]any{"success": false, "error": err.Error()})
+		return
+	}
 
-	// Build the API URL
-	apiURL := fmt.Sprintf("http://%s:%s/api/export/current.json", host, port)
+	var oids []string
+	for _, o := range strings.Split(oid, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			oids = append(oids, o)
+		}
+	}
 
-	// Create HTTP client
-	client := &http.Client{
-		Timeout: 15 * time.Second,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+	timeout := 10 * time.Second
+	if op == "walk" {
+		timeout = 30 * time.Second
 	}
+	ctx, cancel := context.WithTimeout(OutboundContext(r), timeout)
+	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	var vars []SNMPVariable
+	var truncated bool
+	switch op {
+	case "", "get":
+		vars, err = SNMPGet(ctx, host, port, profile, oids)
+	case "bulk":
+		repetitions, _ := strconv.Atoi(q.Get("maxRepetitions"))
+		vars, err = SNMPBulk(ctx, host, port, profile, oids, repetitions)
+	case "walk":
+		if len(oids) != 1 {
+			err = errors.New("walk takes a single root OID")
+			break
+		}
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		vars, truncated, err = SNMPWalk(ctx, host, port, profile, oids[0], limit)
+	default:
+		err = fmt.Errorf("unknown op %q (use get, bulk or walk)", op)
+	}
 	if err != nil {
 		WriteJSON(w, map[string]any{
 			"success": false,
-			"error":   fmt.Sprintf("Failed to create request: %v", err),
+			"error":   err.Error(),
 		})
 		return
 	}
-	req.Header.Set("User-Agent", "lan-index/1.0")
 
-	resp, err := client.Do(req)
-	if err != nil {
-		WriteJSON(w, map[string]any{
-			"success": false,
-			"error":   fmt.Sprintf("Failed to fetch data: %v", err),
-		})
-		return
+	resp := map[string]any{
+		"success":   true,
+		"variables": vars,
 	}
-	defer func() {
-		if closeErr := resp.Body.Close(); closeErr != nil {
-			log.Printf("Error closing Speedplane response body: %v", closeErr)
+	if op == "walk" {
+		resp["truncated"] = truncated
+	}
+	// Single GETs keep the plain value of the original API
+	if (op == "" || op == "get") && len(vars) == 1 {
+		if vars[0].Type == "NoSuchObject" || vars[0].Type == "NoSuchInstance" {
+			WriteJSON(w, map[string]any{"success": false, "error": "OID not found"})
+			return
 		}
-	}()
+		resp["value"] = vars[0].Value
+		if vars[0].Mbps != nil {
+			resp["mbps"] = *vars[0].Mbps
+		}
+	}
+	WriteJSON(w, resp)
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		WriteJSON(w, map[string]any{
-			"success": false,
-			"error":   fmt.Sprintf("HTTP error: %s", resp.Status),
-		})
-		return
+// snmpProfileFromQuery returns the saved profile named by ?profile= or a v2c profile
+// with the ?community= string.
+func snmpProfileFromQuery(q url.Values) (SNMPProfile, error) {
+	name := q.Get("profile")
+	if name == "" {
+		return SNMPProfile{Version: "2c", Community: q.Get("community")}, nil
+	}
+	p, ok := GetSNMPProfiles().Get(name)
+	if !ok {
+		return SNMPProfile{}, errors.New("Unknown SNMP profile: " + name)
 	}
+	return p, nil
+}
 
-	// Decode JSON response
-	var data map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+// HandleSNMPInterfaces returns the interface table of a device with the throughput of
+// each interface since the previous request for the same device. ?interface= selects
+// interfaces by index, name or alias (comma-separated) and ?history= adds the rates of
+// earlier requests within a range (e.g. 1h) for graphing.
+func (h *Handler) HandleSNMPInterfaces(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	host := q.Get("host")
+	port := q.Get("port")
+	if host == "" || (q.Get("community") == "" && q.Get("profile") == "") {
 		WriteJSON(w, map[string]any{
 			"success": false,
-			"error":   fmt.Sprintf("Failed to parse JSON: %v", err),
+			"error":   "Missing required parameters: host and community or profile",
 		})
 		return
 	}
+	profile, err := snmpProfileFromQuery(q)
+	if err != nil {
+		WriteJSON(w, map[string]any{"success": false, "error": err.Error()})
+		return
+	}
+	var historyRange time.Duration
+	if hs := q.Get("history"); hs != "" {
+		historyRange, err = ParseHistoryRange(hs)
+		if err != nil || historyRange <= 0 {
+			WriteJSON(w, map[string]any{"success": false, "error": "Invalid 'history' parameter (e.g. 15m, 1h, 12h)"})
+			return
+		}
+	}
 
-	WriteJSON(w, map[string]any{
+	ctx, cancel := context.WithTimeout(OutboundContext(r), 30*time.Second)
+	defer cancel()
+	ifaces, err := SNMPInterfaces(ctx, host, port, profile)
+	if err != nil {
+		WriteJSON(w, map[string]any{"success": false, "error": err.Error()})
+		return
+	}
+
+	if sel := q.Get("interface"); sel != "" {
+		wanted := make(map[string]bool)
+		for _, s := range strings.Split(sel, ",") {
+			wanted[strings.ToLower(strings.TrimSpace(s))] = true
+		}
+		filtered := ifaces[:0]
+		for _, iface := range ifaces {
+			if wanted[strconv.Itoa(iface.Index)] || wanted[strings.ToLower(iface.Name)] || (iface.Alias != "" && wanted[strings.ToLower(iface.Alias)]) {
+				filtered = append(filtered, iface)
+			}
+		}
+		ifaces = filtered
+	}
+	if historyRange > 0 {
+		SNMPInterfaceHistory(host, port, ifaces, time.Now().Add(-historyRange))
+	}
+
+	WriteJSON(w, map[string]any{
+		"success":    true,
+		"interfaces": ifaces,
+		"time":       time.Now().Unix(),
+	})
+}
+
+// HandleSNMPProfiles lists the saved SNMP credential profiles without their secrets (GET),
+// saves a profile (POST) or deletes one (DELETE ?name=).
+func (h *Handler) HandleSNMPProfiles(w http.ResponseWriter, r *http.Request) {
+	store := GetSNMPProfiles()
+	switch r.Method {
+	case http.MethodGet:
+		profiles := []map[string]any{}
+		for _, p := range store.List() {
+			profiles = append(profiles, p.Redacted())
+		}
+		WriteJSON(w, map[string]any{"profiles": profiles})
+
+	case http.MethodPost:
+		var p SNMPProfile
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&p); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		saved, err := store.Set(p)
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		WriteJSON(w, map[string]any{"success": true, "profile": saved.Redacted()})
+
+	case http.MethodDelete:
+		found, err := store.Delete(r.URL.Query().Get("name"))
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		if !found {
+			WriteJSON(w, map[string]any{"error": "Profile not found"})
+			return
+		}
+		WriteJSON(w, map[string]any{"success": true})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleSpeedplane handles Speedplane API requests.
+func (h *Handler) HandleSpeedplane(w http.ResponseWriter, r *http.Request) {
+	host := r.URL.Query().Get("host")
+	port := r.URL.Query().Get("port")
+
+	if host == "" || port == "" {
+		WriteJSON(w, map[string]any{
+			"success": false,
+			"error":   "Missing required parameters: host, port",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(OutboundContext(r), 15*time.Second)
+	defer cancel()
+
+	// Build the API URL
+	apiURL := fmt.Sprintf("http://%s:%s/api/export/current.json", host, port)
+
+	// Create HTTP client
+	client := &http.Client{
+		Timeout:   15 * time.Second,
+		Transport: NewOutboundTransport(&tls.Config{InsecureSkipVerify: true}),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		WriteJSON(w, map[string]any{
+			"success": false,
+			"error":   fmt.Sprintf("Failed to create request: %v", err),
+		})
+		return
+	}
+	req.Header.Set("User-Agent", "lan-index/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		WriteJSON(w, map[string]any{
+			"success": false,
+			"error":   fmt.Sprintf("Failed to fetch data: %v", err),
+		})
+		return
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			Logger("speedplane").Warn("closing response body", "error", closeErr)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		WriteJSON(w, map[string]any{
+			"success": false,
+			"error":   fmt.Sprintf("HTTP error: %s", resp.Status),
+		})
+		return
+	}
+
+	// Decode JSON response
+	var data map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		WriteJSON(w, map[string]any{
+			"success": false,
+			"error":   fmt.Sprintf("Failed to parse JSON: %v", err),
+		})
+		return
+	}
+
+	WriteJSON(w, map[string]any{
 		"success": true,
 		"data":    data,
 	})
@@ -752,16 +1405,14 @@ func (h *Handler) HandleDNSplane(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(OutboundContext(r), 15*time.Second)
 	defer cancel()
 
 	apiURL := fmt.Sprintf("http://%s:%s/stats/dashboard/data", host, port)
 
 	client := &http.Client{
-		Timeout: 15 * time.Second,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+		Timeout:   15 * time.Second,
+		Transport: NewOutboundTransport(&tls.Config{InsecureSkipVerify: true}),
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
@@ -784,7 +1435,7 @@ func (h *Handler) HandleDNSplane(w http.ResponseWriter, r *http.Request) {
 	}
 	defer func() {
 		if closeErr := resp.Body.Close(); closeErr != nil {
-			log.Printf("Error closing DNSplane response body: %v", closeErr)
+			Logger("dnsplane").Warn("closing response body", "error", closeErr)
 		}
 	}()
 
@@ -828,7 +1479,7 @@ func (h *Handler) HandleRSS(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(OutboundContext(r), 15*time.Second)
 	defer cancel()
 
 	items, err := FetchRSSFeed(ctx, feedURL, count)
@@ -870,7 +1521,7 @@ func (h *Handler) HandleConfigUpload(w http.ResponseWriter, r *http.Request) {
 
 	configsDir := "configs"
 	if err := os.MkdirAll(configsDir, 0755); err != nil {
-		log.Printf("Failed to create configs directory: %v", err)
+		Logger("config").Error("failed to create configs directory", "error", err)
 		WriteJSON(w, map[string]string{"error": "Failed to save config"})
 		return
 	}
@@ -883,11 +1534,13 @@ func (h *Handler) HandleConfigUpload(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := os.WriteFile(configPath, configJSON, 0644); err != nil {
-		log.Printf("Failed to write config file: %v", err)
+		Logger("config").Error("failed to write config file", "path", configPath, "error", err)
 		WriteJSON(w, map[string]string{"error": "Failed to save config"})
 		return
 	}
 
+	GetTimeline().Add(TimelineEvent{Source: TimelineSourceConfig, Title: "Configuration saved", Detail: name})
+	Audit(r, "config.upload", name)
 	WriteJSON(w, map[string]string{"success": "Config uploaded successfully"})
 }
 
@@ -972,6 +1625,8 @@ func (h *Handler) HandleConfigDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	GetTimeline().Add(TimelineEvent{Source: TimelineSourceConfig, Title: "Configuration deleted", Detail: name})
+	Audit(r, "config.delete", name)
 	WriteJSON(w, map[string]string{"success": "Config deleted successfully"})
 }
 
@@ -999,11 +1654,28 @@ func (h *Handler) HandleStorageSync(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Process and validate data based on key type
+	profile := ProfileFromRequest(r)
+	storageKey := ProfileStorageKey(profile, syncData.Key)
+
+	// Process and validate data based on key type; the layouts of pages are layouts too
 	var processedValue interface{} = syncData.Value
 	var processingErrors []string
+	kind := syncData.Key
+	if page, isPage := LayoutPageOfKey(kind); isPage {
+		if !PageExists(profile, page) {
+			WriteJSON(w, map[string]any{"error": "Unknown page " + page})
+			return
+		}
+		kind = "layoutConfig"
+	}
+	if moduleType, isStore := moduleInstanceStoreType(kind); isStore {
+		if err := validateModuleInstances(moduleType, syncData.Value); err != nil {
+			WriteJSON(w, map[string]any{"error": "Invalid " + moduleType + " modules: " + err.Error()})
+			return
+		}
+	}
 
-	switch syncData.Key {
+	switch kind {
 	case "layoutConfig":
 		var layoutConfig LayoutConfig
 		configJSON, err := json.Marshal(syncData.Value)
@@ -1021,7 +1693,7 @@ func (h *Handler) HandleStorageSync(w http.ResponseWriter, r *http.Request) {
 				// Process (remove disabled modules)
 				storage := GetStorage()
 				var modulePrefs map[string]interface{}
-				if item, exists := storage.Get("modulePrefs"); exists {
+				if item, exists := storage.GetForProfile(profile, "modulePrefs"); exists {
 					if prefs, ok := item.Value.(map[string]interface{}); ok {
 						modulePrefs = prefs
 					}
@@ -1089,10 +1761,10 @@ func (h *Handler) HandleStorageSync(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Store processed value in backend storage
-	globalStorage.Set(syncData.Key, processedValue, syncData.Version)
+	globalStorage.Set(storageKey, processedValue, syncData.Version)
 
 	// Get the stored item to return the actual version (in case of conflict resolution)
-	item, exists := globalStorage.Get(syncData.Key)
+	item, exists := globalStorage.Get(storageKey)
 	if !exists {
 		WriteJSON(w, map[string]string{"error": "Failed to store data"})
 		return
@@ -1117,7 +1789,7 @@ func (h *Handler) HandleStorageGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	item, exists := globalStorage.Get(key)
+	item, exists := globalStorage.GetForProfile(ProfileFromRequest(r), key)
 	if !exists {
 		WriteJSON(w, map[string]string{"error": "Key not found"})
 		return
@@ -1132,8 +1804,8 @@ func (h *Handler) HandleStorageGet(w http.ResponseWriter, r *http.Request) {
 }
 
 // HandleStorageGetAll handles requests to get all stored items.
-func (h *Handler) HandleStorageGetAll(w http.ResponseWriter, _ *http.Request) {
-	allItems := globalStorage.GetAll()
+func (h *Handler) HandleStorageGetAll(w http.ResponseWriter, r *http.Request) {
+	allItems := globalStorage.GetAllForProfile(ProfileFromRequest(r))
 
 	items := make([]map[string]interface{}, 0, len(allItems))
 	for key, item := range allItems {
@@ -1150,6 +1822,24 @@ func (h *Handler) HandleStorageGetAll(w http.ResponseWriter, _ *http.Request) {
 	})
 }
 
+// HandleProfiles lists dashboard profiles (GET) or deletes a profile's settings (DELETE ?name=).
+func (h *Handler) HandleProfiles(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, map[string]any{"profiles": globalStorage.Profiles(), "default": DefaultProfile})
+	case http.MethodDelete:
+		name := r.URL.Query().Get("name")
+		if name == DefaultProfile || !ValidProfileName(name) {
+			WriteJSON(w, map[string]any{"error": "Invalid profile name"})
+			return
+		}
+		GetLayoutHistory().DeleteProfile(name)
+		WriteJSON(w, map[string]any{"success": true, "removed": globalStorage.DeleteProfile(name)})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
 // HandleStorageStatus returns the status of the storage system.
 func (h *Handler) HandleStorageStatus(w http.ResponseWriter, _ *http.Request) {
 	allItems := globalStorage.GetAll()
@@ -1175,11 +1865,11 @@ type SearchHistoryItem struct {
 	Timestamp string `json:"timestamp"`
 }
 
-// HandleSearchHistoryFilter filters search history based on a filter term.
+// HandleSearchHistoryFilter filters search history based on a filter term. The history is
+// the one in the body, or the server-side history when it is enabled.
 func (h *Handler) HandleSearchHistoryFilter(w http.ResponseWriter, r *http.Request) {
-	var history []SearchHistoryItem
-	if err := json.NewDecoder(r.Body).Decode(&history); err != nil {
-		WriteJSON(w, map[string]any{"error": "Invalid request body: " + err.Error()})
+	history, ok := searchHistoryFromRequest(w, r)
+	if !ok {
 		return
 	}
 
@@ -1201,105 +1891,92 @@ func (h *Handler) HandleSearchHistoryFilter(w http.ResponseWriter, r *http.Reque
 	WriteJSON(w, map[string]any{"history": filtered})
 }
 
-// HandleSearchAutocomplete returns autocomplete suggestions from search history and bookmarks.
-func (h *Handler) HandleSearchAutocomplete(w http.ResponseWriter, r *http.Request) {
+// searchHistoryFromRequest returns the server-side search history when it is enabled, or
+// else the history a browser sent in the body. It writes the error and returns false when
+// the body is not a history.
+func searchHistoryFromRequest(w http.ResponseWriter, r *http.Request) ([]SearchHistoryItem, bool) {
 	var history []SearchHistoryItem
-	if err := json.NewDecoder(r.Body).Decode(&history); err != nil {
+	err := json.NewDecoder(r.Body).Decode(&history)
+	if GetSearchHistoryStore().Enabled() {
+		return GetSearchHistoryStore().History(), true
+	}
+	if err != nil {
 		WriteJSON(w, map[string]any{"error": "Invalid request body: " + err.Error()})
+		return nil, false
+	}
+	return history, true
+}
+
+// HandleSearchAutocomplete returns autocomplete suggestions from search history and bookmarks.
+// Bookmarks come from the local search index; the history in the body is indexed the same
+// way, so both match by word prefix and tolerate typos.
+func (h *Handler) HandleSearchAutocomplete(w http.ResponseWriter, r *http.Request) {
+	history, ok := searchHistoryFromRequest(w, r)
+	if !ok {
 		return
 	}
 
-	term := strings.ToLower(r.URL.Query().Get("term"))
+	term := strings.TrimSpace(r.URL.Query().Get("term"))
 	if term == "" {
 		WriteJSON(w, map[string]any{"suggestions": []SearchHistoryItem{}})
 		return
 	}
 
-	// Filter history items where term contains the search term (case-insensitive)
-	matched := make([]SearchHistoryItem, 0)
-	for _, item := range history {
-		if item.Term != "" && strings.Contains(strings.ToLower(item.Term), term) {
-			matched = append(matched, item)
-		}
+	// Combine: bookmarks first (up to 5), then history (up to 7), total max 10
+	suggestions := make([]SearchHistoryItem, 0, 10)
+	bookmarks := GetSearchIndex(ProfileFromRequest(r)).Search(term, []string{SearchKindBookmark}, 5)
+	for _, b := range bookmarks {
+		// Use bookmark title as the term, and mark it as a bookmark
+		suggestions = append(suggestions, SearchHistoryItem{
+			Term:      b.Title,
+			Engine:    "Bookmark",
+			Timestamp: b.URL, // Store URL in timestamp field
+		})
 	}
+	GetDebugLogger().Logf("bookmarks", "Added %d bookmark items to autocomplete results for '%s'", len(bookmarks), term)
 
-	// Remove duplicates (by term, case-insensitive) and reverse to show newest first
-	historyItems := make([]SearchHistoryItem, 0)
-	seen := make(map[string]bool)
-	for i := len(matched) - 1; i >= 0; i-- {
-		item := matched[i]
-		key := strings.ToLower(item.Term)
-		if !seen[key] {
-			seen[key] = true
-			historyItems = append(historyItems, item)
-		}
+	// The newest search of each term is the one suggested
+	latest := make(map[string]SearchHistoryItem, len(history))
+	for _, item := range history {
+		latest[strings.ToLower(item.Term)] = item
 	}
-
-	// Get and filter bookmarks
-	bookmarkItems := make([]SearchHistoryItem, 0)
-	// Detect browser from User-Agent to prioritize that browser's bookmarks
-	userAgent := r.Header.Get("User-Agent")
-	preferredBrowser := DetectBrowserFromUserAgent(userAgent)
-	GetDebugLogger().Logf("bookmarks", "User-Agent: %s", userAgent)
-	GetDebugLogger().Logf("bookmarks", "Detected browser: %s", preferredBrowser)
-	
-	bookmarks, err := GetBookmarks(preferredBrowser)
-	GetDebugLogger().Logf("bookmarks", "GetBookmarks result: count=%d, error=%v", len(bookmarks), err)
-	
-	if err == nil && len(bookmarks) > 0 {
-		filteredBookmarks := FilterBookmarks(bookmarks, term)
-		GetDebugLogger().Logf("bookmarks", "After filtering with term '%s': %d bookmarks match", term, len(filteredBookmarks))
-		
-		// Convert bookmarks to SearchHistoryItem format
-		for _, bookmark := range filteredBookmarks {
-			// Use bookmark title as the term, and mark it as a bookmark
-			bookmarkItem := SearchHistoryItem{
-				Term:      bookmark.Title,
-				Engine:    "Bookmark",
-				Timestamp: bookmark.URL, // Store URL in timestamp field
-			}
-			// Check if we already have this exact bookmark URL in history to avoid duplicates
-			// Use URL as key since titles might be duplicated across different URLs
-			key := strings.ToLower(bookmark.URL)
-			if !seen[key] {
-				seen[key] = true
-				bookmarkItems = append(bookmarkItems, bookmarkItem)
-			}
-		}
-		GetDebugLogger().Logf("bookmarks", "Added %d bookmark items to autocomplete results", len(bookmarkItems))
-	} else {
-		if err != nil {
-			GetDebugLogger().Logf("bookmarks", "Error loading bookmarks: %v", err)
-		} else {
-			GetDebugLogger().Logf("bookmarks", "No bookmarks found (count: %d)", len(bookmarks))
+	for _, item := range NewSearchIndex(HistoryDocuments(history)).Search(term, nil, 7) {
+		if len(suggestions) >= 10 {
+			break
 		}
+		suggestions = append(suggestions, latest[strings.ToLower(item.Title)])
 	}
 
-	// Combine results: prioritize bookmarks, then history
-	// Limit history to 7 items to ensure bookmarks can appear
-	maxHistory := 7
-	if len(historyItems) > maxHistory {
-		historyItems = historyItems[:maxHistory]
-	}
+	WriteJSON(w, map[string]any{"suggestions": suggestions})
+}
 
-	// Combine: bookmarks first (up to 5), then history (up to 7), total max 10
-	uniqueItems := make([]SearchHistoryItem, 0)
-	maxBookmarks := 5
-	if len(bookmarkItems) > maxBookmarks {
-		bookmarkItems = bookmarkItems[:maxBookmarks]
+// HandleSearchLocal searches the local index of bookmarks, quick links, RSS items and
+// search history. ?q= is the query, ?kind= a comma-separated list of kinds to return and
+// ?limit= the number of results (default 20, max 100).
+func (h *Handler) HandleSearchLocal(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		WriteJSON(w, map[string]any{"error": "Missing required parameter: q"})
+		return
 	}
-	uniqueItems = append(uniqueItems, bookmarkItems...)
-	uniqueItems = append(uniqueItems, historyItems...)
-
-	// Final limit to 10 items
-	if len(uniqueItems) > 10 {
-		uniqueItems = uniqueItems[:10]
+	var kinds []string
+	if k := r.URL.Query().Get("kind"); k != "" {
+		kinds = strings.Split(k, ",")
+	}
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
 	}
 
-	WriteJSON(w, map[string]any{"suggestions": uniqueItems})
+	index := GetSearchIndex(ProfileFromRequest(r))
+	results := index.Search(query, kinds, limit)
+	WriteJSON(w, map[string]any{"query": query, "results": results, "indexed": index.Len()})
 }
 
-// HandleBookmarks returns all bookmarks for debugging purposes.
+// HandleBookmarks returns the bookmarks kept by the dashboard merged with those of the
+// browsers on the server. ?folder= limits them to a folder and its subfolders.
 func (h *Handler) HandleBookmarks(w http.ResponseWriter, r *http.Request) {
 	// Optionally filter by browser from query parameter or User-Agent
 	preferredBrowser := r.URL.Query().Get("browser")
@@ -1308,12 +1985,18 @@ func (h *Handler) HandleBookmarks(w http.ResponseWriter, r *http.Request) {
 		preferredBrowser = DetectBrowserFromUserAgent(userAgent)
 	}
 
+	imported := GetBookmarkStore().Tagged()
 	bookmarks, err := GetBookmarks(preferredBrowser)
+	bookmarks = MergeBookmarks(append(imported, bookmarks...))
+	if folder := cleanBookmarkFolder(r.URL.Query().Get("folder")); folder != "" {
+		bookmarks = slices.DeleteFunc(bookmarks, func(b Bookmark) bool { return !inBookmarkFolder(b.Folder, folder) })
+	}
 	if err != nil {
 		WriteJSON(w, map[string]any{
 			"error":            err.Error(),
-			"bookmarks":        []Bookmark{},
-			"count":            0,
+			"bookmarks":        bookmarks,
+			"count":            len(bookmarks),
+			"imported":         len(imported),
 			"preferredBrowser": preferredBrowser,
 		})
 		return
@@ -1322,6 +2005,7 @@ func (h *Handler) HandleBookmarks(w http.ResponseWriter, r *http.Request) {
 	WriteJSON(w, map[string]any{
 		"bookmarks":        bookmarks,
 		"count":            len(bookmarks),
+		"imported":         len(imported),
 		"error":            nil,
 		"preferredBrowser": preferredBrowser,
 	})
@@ -1333,6 +2017,44 @@ func (h *Handler) HandleModules(w http.ResponseWriter, _ *http.Request) {
 	WriteJSON(w, map[string]any{"modules": modules})
 }
 
+// HandleModuleInstances serves GET /api/modules/instances?type=&id=: the instances of the
+// module types that can be placed more than once, or one instance by ID.
+func (h *Handler) HandleModuleInstances(w http.ResponseWriter, r *http.Request) {
+	if id := r.URL.Query().Get("id"); id != "" {
+		inst, err := FindModuleInstance(ProfileFromRequest(r), id)
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		WriteJSON(w, map[string]any{"instance": inst})
+		return
+	}
+	moduleType := r.URL.Query().Get("type")
+	if moduleType != "" {
+		if _, ok := moduleInstanceStores[moduleType]; !ok {
+			WriteJSON(w, map[string]any{"error": moduleType + " cannot be placed more than once"})
+			return
+		}
+	}
+	instances := ModuleInstances(ProfileFromRequest(r), moduleType)
+	WriteJSON(w, map[string]any{"instances": instances, "count": len(instances)})
+}
+
+// HandleModuleSchema serves GET /api/modules/schema?type=: the config schemas of the module
+// types, or of one type, for rendering their edit forms.
+func (h *Handler) HandleModuleSchema(w http.ResponseWriter, r *http.Request) {
+	if moduleType := r.URL.Query().Get("type"); moduleType != "" {
+		schema, ok := ModuleSchemaFor(moduleType)
+		if !ok {
+			WriteJSON(w, map[string]any{"error": "Unknown module type: " + moduleType})
+			return
+		}
+		WriteJSON(w, map[string]any{"schema": schema, "defaults": schema.Defaults()})
+		return
+	}
+	WriteJSON(w, map[string]any{"schemas": ModuleSchemas()})
+}
+
 // HandleCalendarProcess processes calendar events and returns calculated data.
 func (h *Handler) HandleCalendarProcess(w http.ResponseWriter, r *http.Request) {
 	var events []CalendarEvent
@@ -1344,12 +2066,15 @@ func (h *Handler) HandleCalendarProcess(w http.ResponseWriter, r *http.Request)
 	// Get ICS calendars and fetch their events
 	icsCalendars, err := GetICSCalendars()
 	if err == nil {
-		icsEvents, err := GetICSEvents(icsCalendars, false)
+		icsEvents, err := GetICSEvents(r.Context(), icsCalendars, false)
 		if err == nil {
 			// Merge local events with ICS events
 			events = MergeCalendarEvents(events, icsEvents)
 		}
 	}
+	// Event times are kept in the server's zone, shift them to the client's
+	cl := ClientLocaleFromRequest(r)
+	cl.LocalizeEvents(events)
 
 	count := 5
 	if countStr := r.URL.Query().Get("count"); countStr != "" {
@@ -1358,7 +2083,27 @@ func (h *Handler) HandleCalendarProcess(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	processed := ProcessCalendarEvents(events, count)
+	now := cl.Now()
+	events = append(events, GeneratedCalendarEvents(r.Context(), now, now.Add(generatedEventsAhead))...)
+
+	processed := ProcessCalendarEvents(events, count, cl)
+
+	// Annotate upcoming events with the forecast for their date
+	if weather := h.Config().Weather; weather.Enabled {
+		lat, lon, _ := SavedWeatherLocation(weather)
+		if lat != "" && lon != "" {
+			ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+			wd, err := CachedWeather(ctx, weather, lat, lon)
+			cancel()
+			if err != nil {
+				GetDebugLogger().Logf("calendar", "weather annotation skipped: %v", err)
+			} else {
+				AnnotateEventsWithWeather(processed.UpcomingEvents, wd)
+				cl.LocalizeEventWeather(processed.UpcomingEvents)
+			}
+		}
+	}
+
 	WriteJSON(w, processed)
 }
 
@@ -1376,14 +2121,17 @@ func (h *Handler) HandleCalendarMonth(w http.ResponseWriter, r *http.Request) {
 	// Get ICS calendars and fetch their events
 	icsCalendars, err := GetICSCalendars()
 	if err == nil {
-		icsEvents, err := GetICSEvents(icsCalendars, false)
+		icsEvents, err := GetICSEvents(r.Context(), icsCalendars, false)
 		if err == nil {
 			// Merge local events with ICS events
 			events = MergeCalendarEvents(events, icsEvents)
 		}
 	}
+	// Event times are kept in the server's zone, shift them to the client's
+	cl := ClientLocaleFromRequest(r)
+	cl.LocalizeEvents(events)
 
-	now := time.Now()
+	now := cl.Now()
 	year := now.Year()
 	month := int(now.Month()) - 1
 
@@ -1398,7 +2146,11 @@ func (h *Handler) HandleCalendarMonth(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	data := GetMonthCalendarData(year, month, events)
+	// Holidays and birthdays are whole days, so they are merged after the events are localized
+	monthStart := time.Date(year, time.Month(month+1), 1, 0, 0, 0, 0, time.UTC)
+	events = append(events, GeneratedCalendarEvents(r.Context(), monthStart, monthStart.AddDate(0, 1, -1))...)
+
+	data := GetMonthCalendarData(year, month, events, cl)
 	WriteJSON(w, data)
 }
 
@@ -1413,16 +2165,19 @@ func (h *Handler) HandleCalendarWeek(w http.ResponseWriter, r *http.Request) {
 	// Get ICS calendars and fetch their events
 	icsCalendars, err := GetICSCalendars()
 	if err == nil {
-		icsEvents, err := GetICSEvents(icsCalendars, false)
+		icsEvents, err := GetICSEvents(r.Context(), icsCalendars, false)
 		if err == nil {
 			// Merge local events with ICS events
 			events = MergeCalendarEvents(events, icsEvents)
 		}
 	}
+	// Event times are kept in the server's zone, shift them to the client's
+	cl := ClientLocaleFromRequest(r)
+	cl.LocalizeEvents(events)
 
 	weekStartStr := r.URL.Query().Get("weekStart")
 	workWeekOnly := r.URL.Query().Get("workWeekOnly") == "true"
-	startDay := 1 // Default Monday
+	startDay := cl.FirstDay // First day of the week of the settings
 	if startDayStr := r.URL.Query().Get("startDay"); startDayStr != "" {
 		if parsed, err := strconv.Atoi(startDayStr); err == nil && parsed >= 0 && parsed <= 6 {
 			startDay = parsed
@@ -1435,13 +2190,16 @@ func (h *Handler) HandleCalendarWeek(w http.ResponseWriter, r *http.Request) {
 		if err == nil {
 			weekStart = parsed
 		} else {
-			weekStart = time.Now()
+			weekStart = cl.Now()
 		}
 	} else {
-		weekStart = time.Now()
+		weekStart = cl.Now()
 	}
 
-	data := GetWeekCalendarData(weekStart, workWeekOnly, startDay, events)
+	// The shown week starts up to six days before weekStart
+	events = append(events, GeneratedCalendarEvents(r.Context(), weekStart.AddDate(0, 0, -7), weekStart.AddDate(0, 0, 7))...)
+
+	data := GetWeekCalendarData(weekStart, workWeekOnly, startDay, events, cl)
 	WriteJSON(w, data)
 }
 
@@ -1462,17 +2220,132 @@ func (h *Handler) HandleCalendarEventsForDate(w http.ResponseWriter, r *http.Req
 	// Get ICS calendars and fetch their events
 	icsCalendars, err := GetICSCalendars()
 	if err == nil {
-		icsEvents, err := GetICSEvents(icsCalendars, false)
+		icsEvents, err := GetICSEvents(r.Context(), icsCalendars, false)
 		if err == nil {
 			// Merge local events with ICS events
 			events = MergeCalendarEvents(events, icsEvents)
 		}
 	}
+	// Event times are kept in the server's zone, shift them to the client's
+	cl := ClientLocaleFromRequest(r)
+	cl.LocalizeEvents(events)
+
+	if date, err := time.Parse("2006-01-02", dateStr); err == nil {
+		events = append(events, GeneratedCalendarEvents(r.Context(), date, date)...)
+	}
 
 	dayEvents := GetEventsForDate(events, dateStr)
 	WriteJSON(w, map[string]any{"events": dayEvents})
 }
 
+// HandleCalendarBirthdays serves /api/calendar/birthdays: GET lists the birthdays of the
+// address books and uploaded vCard files with their next date and age, POST replaces the
+// uploaded birthdays with those of the vCard file in the body (?name= names it) and DELETE
+// removes them.
+func (h *Handler) HandleCalendarBirthdays(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		type birthdayInfo struct {
+			Birthday
+			Next string `json:"next"`          // YYYY-MM-DD
+			Age  int    `json:"age,omitempty"` // Age reached on the next birthday
+		}
+		today := ClientLocaleFromRequest(r).Now()
+		birthdays := []birthdayInfo{}
+		for _, b := range GetBirthdayService().Birthdays(r.Context()) {
+			next := b.Next(today)
+			birthdays = append(birthdays, birthdayInfo{Birthday: b, Next: next.Format("2006-01-02"), Age: b.AgeIn(next.Year())})
+		}
+		slices.SortStableFunc(birthdays, func(a, b birthdayInfo) int { return strings.Compare(a.Next, b.Next) })
+		WriteJSON(w, map[string]any{"birthdays": birthdays})
+	case http.MethodPost:
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 16<<20))
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": "Failed to read vCard file: " + err.Error()})
+			return
+		}
+		source := r.URL.Query().Get("name")
+		if source == "" {
+			source = "Contacts"
+		}
+		birthdays := ParseVCardBirthdays(string(data), source)
+		if len(birthdays) == 0 {
+			WriteJSON(w, map[string]any{"error": "No contacts with a birthday found"})
+			return
+		}
+		SaveUploadedBirthdays(birthdays)
+		WriteJSON(w, map[string]any{"success": true, "count": len(birthdays)})
+	case http.MethodDelete:
+		SaveUploadedBirthdays([]Birthday{})
+		WriteJSON(w, map[string]any{"success": true})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleClocks serves /api/clocks: the World clock zones and the countdowns of the profile
+// (GET), or those in the body (POST {"zones", "countdowns", "events"}), worked out with the
+// server's zone database relative to ?tz= (default the client's zone). ?zones= replaces the
+// zones with a comma-separated list.
+func (h *Handler) HandleClocks(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Zones      []WorldClockZone `json:"zones"`
+		Countdowns []Countdown      `json:"countdowns"`
+		Events     []CalendarEvent  `json:"events"` // Events of the browser, instead of the stored ones
+	}
+	profile := ProfileFromRequest(r)
+	switch r.Method {
+	case http.MethodGet:
+		GetStorage().GetAsForProfile(profile, "worldClockZones", &req.Zones)
+		GetStorage().GetAsForProfile(profile, "clockCountdowns", &req.Countdowns)
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			WriteJSON(w, map[string]any{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ref := ClientLocaleFromRequest(r).Location
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": "unknown time zone " + tz})
+			return
+		}
+		ref = loc
+	}
+	if zones := r.URL.Query().Get("zones"); zones != "" {
+		req.Zones = nil
+		for _, name := range strings.Split(zones, ",") {
+			req.Zones = append(req.Zones, WorldClockZone{TimeZone: strings.TrimSpace(name)})
+		}
+	}
+
+	// Calendar events, with those of ICS calendars, only when a countdown refers to one
+	var events []CalendarEvent
+	if slices.ContainsFunc(req.Countdowns, func(c Countdown) bool { return c.Event != "" }) {
+		events = req.Events
+		if events == nil {
+			GetStorage().GetAsForProfile(profile, "calendarEvents", &events)
+		}
+		if calendars, err := GetICSCalendars(); err == nil {
+			if icsEvents, err := GetICSEvents(r.Context(), calendars, false); err == nil {
+				events = MergeCalendarEvents(events, icsEvents)
+			}
+		}
+	}
+
+	WriteJSON(w, BuildClocks(time.Now(), ref, req.Zones, req.Countdowns, events))
+}
+
+// HandleClockZones lists the IANA time zones of the server's zone database.
+func (h *Handler) HandleClockZones(w http.ResponseWriter, _ *http.Request) {
+	WriteJSON(w, map[string]any{"zones": TimeZoneNames()})
+}
+
 // HandleICSCalendars handles CRUD operations for ICS calendars.
 func (h *Handler) HandleICSCalendars(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodGet {
@@ -1520,7 +2393,10 @@ func (h *Handler) HandleICSFetch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	content, err := FetchICSCalendar(url)
+	ctx, cancel := context.WithTimeout(OutboundContext(r), 30*time.Second)
+	defer cancel()
+
+	content, err := FetchICSCalendar(ctx, url)
 	if err != nil {
 		WriteJSON(w, map[string]any{"error": err.Error(), "valid": false})
 		return
@@ -1573,7 +2449,7 @@ func (h *Handler) HandleICSRefresh(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Force refresh by passing true
-	events, err := GetICSEvents(calendars, true)
+	events, err := GetICSEvents(r.Context(), calendars, true)
 	if err != nil {
 		GetDebugLogger().Logf("calendar", "HandleICSRefresh: Error fetching events: %v", err)
 		WriteJSON(w, map[string]any{"error": err.Error()})
@@ -1590,13 +2466,21 @@ func (h *Handler) HandleICSRefresh(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// HandleTodosProcess processes todos and returns sorted/prioritized todos.
+// HandleTodosProcess processes todos and returns sorted/prioritized todos. The tasks of the
+// ICS calendars follow the posted todos unless ?ics=false.
 func (h *Handler) HandleTodosProcess(w http.ResponseWriter, r *http.Request) {
 	var todos []Todo
 	if err := json.NewDecoder(r.Body).Decode(&todos); err != nil {
 		WriteJSON(w, map[string]any{"error": "Invalid request body: " + err.Error()})
 		return
 	}
+	if r.URL.Query().Get("ics") != "false" {
+		if calendars, err := GetICSCalendars(); err == nil && len(calendars) > 0 {
+			if imported, err := GetICSTodos(r.Context(), calendars, false); err == nil {
+				todos = append(todos, imported...)
+			}
+		}
+	}
 
 	count := 5
 	if countStr := r.URL.Query().Get("count"); countStr != "" {
@@ -1636,10 +2520,21 @@ func (h *Handler) HandleNormalizeURL(w http.ResponseWriter, r *http.Request) {
 	WriteJSON(w, map[string]any{"normalized": normalized, "input": input})
 }
 
-// LayoutConfig represents the layout configuration structure.
+// LayoutConfig represents the layout configuration structure: the flat list of modules the
+// dashboard places in columns, or the rows of older versions. The flat list is the desktop
+// layout; Breakpoints holds the grids saved for narrower screens.
 type LayoutConfig struct {
-	MaxWidth int          `json:"maxWidth"`
-	Rows     []LayoutRow  `json:"rows"`
+	MaxWidth    int                   `json:"maxWidth"`
+	Columns     int                   `json:"columns,omitempty"`
+	Modules     []LayoutModule        `json:"modules,omitempty"`
+	Breakpoints map[string]LayoutGrid `json:"breakpoints,omitempty"` // "mobile" and "tablet"
+	Rows        []LayoutRow           `json:"rows,omitempty"`
+}
+
+// LayoutModule is a module of a flat layout and the number of columns it spans.
+type LayoutModule struct {
+	ID   string `json:"id"`
+	Span int    `json:"span"`
 }
 
 // LayoutRow represents a row in the layout.
@@ -1655,6 +2550,10 @@ func ValidateLayoutConfig(config LayoutConfig) (bool, string) {
 		return false, "maxWidth must be between 0 and 100"
 	}
 
+	if config.Modules != nil {
+		return validateFlatLayout(config)
+	}
+
 	// Validate rows
 	if len(config.Rows) == 0 {
 		return false, "layout must have at least one row"
@@ -1707,6 +2606,14 @@ func ValidateLayoutConfig(config LayoutConfig) (bool, string) {
 	return true, ""
 }
 
+// validateFlatLayout validates a layout that lists its modules.
+func validateFlatLayout(config LayoutConfig) (bool, string) {
+	if valid, msg := validateLayoutGrid(config.Columns, config.Modules); !valid {
+		return false, msg
+	}
+	return validateLayoutBreakpoints(config.Breakpoints)
+}
+
 // HandleLayoutValidate validates a layout configuration.
 func (h *Handler) HandleLayoutValidate(w http.ResponseWriter, r *http.Request) {
 	var config LayoutConfig
@@ -1744,12 +2651,8 @@ func ValidateInput(req InputValidationRequest) (bool, string) {
 		return validateCalendarEvent(req.Data)
 	case "todo":
 		return validateTodo(req.Data)
-	case "monitoring":
-		return validateMonitoring(req.Data)
-	case "speedplane":
-		return validateSpeedplane(req.Data)
-	case "dnsplane":
-		return validateDNSplane(req.Data)
+	case "monitoring", "speedplane", "dnsplane":
+		return ValidateModuleConfig(req.Type, req.Data)
 	default:
 		return false, "Unknown validation type: " + req.Type
 	}
@@ -1816,99 +2719,15 @@ func validateTodo(data map[string]interface{}) (bool, string) {
 	return true, ""
 }
 
-// validateSpeedplane validates a speedplane instance.
-func validateSpeedplane(data map[string]interface{}) (bool, string) {
-	host, ok := data["host"].(string)
-	if !ok || strings.TrimSpace(host) == "" {
-		return false, "Host is required"
-	}
-	port, ok := data["port"]
-	if !ok {
-		return false, "Port is required"
-	}
-	portNum, ok := port.(float64)
-	if !ok {
-		portNumInt, ok := port.(int)
-		if !ok {
-			return false, "Port must be a number"
-		}
-		portNum = float64(portNumInt)
-	}
-	if portNum < 1 || portNum > 65535 {
-		return false, "Port must be between 1 and 65535"
-	}
-	return true, ""
-}
-
-// validateDNSplane validates DNSPlane host/port (same rules as Speedplane).
-func validateDNSplane(data map[string]interface{}) (bool, string) {
-	return validateSpeedplane(data)
-}
-
-// validateMonitoring validates a monitoring item.
-func validateMonitoring(data map[string]interface{}) (bool, string) {
-	name, ok := data["name"].(string)
-	if !ok || strings.TrimSpace(name) == "" {
-		return false, "Name is required"
-	}
-
-	monType, ok := data["type"].(string)
-	if !ok {
-		return false, "Type is required"
-	}
-
-	validTypes := map[string]bool{"http": true, "port": true, "ping": true}
-	if !validTypes[monType] {
-		return false, "Type must be 'http', 'port', or 'ping'"
-	}
-
-	switch monType {
-	case "http":
-		url, ok := data["url"].(string)
-		if !ok || strings.TrimSpace(url) == "" {
-			return false, "URL is required for HTTP monitoring"
-		}
-		// Basic URL validation
-		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-			return false, "URL must start with http:// or https://"
-		}
-	case "port", "ping":
-		host, ok := data["host"].(string)
-		if !ok || strings.TrimSpace(host) == "" {
-			return false, "Host is required for " + monType + " monitoring"
-		}
-		if monType == "port" {
-			port, ok := data["port"]
-			if !ok {
-				return false, "Port is required for port monitoring"
-			}
-			portNum, ok := port.(float64) // JSON numbers come as float64
-			if !ok {
-				// Try as int
-				if portInt, ok := port.(int); ok {
-					portNum = float64(portInt)
-				} else {
-					return false, "Port must be a number"
-				}
-			}
-			if portNum < 1 || portNum > 65535 {
-				return false, "Port must be between 1 and 65535"
-			}
-		}
-	}
-
-	return true, ""
-}
-
-// HandleValidateInput validates user input.
-func (h *Handler) HandleValidateInput(w http.ResponseWriter, r *http.Request) {
-	var req InputValidationRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteJSON(w, map[string]any{
-			"valid": false,
-			"error": "Invalid JSON: " + err.Error(),
-		})
-		return
+// HandleValidateInput validates user input.
+func (h *Handler) HandleValidateInput(w http.ResponseWriter, r *http.Request) {
+	var req InputValidationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteJSON(w, map[string]any{
+			"valid": false,
+			"error": "Invalid JSON: " + err.Error(),
+		})
+		return
 	}
 
 	if req.Type == "" {
@@ -1956,11 +2775,14 @@ func ProcessLayoutConfig(config LayoutConfig, modulePrefs map[string]interface{}
 		}
 	}
 
-	// Helper function to check if module is enabled
+	// Helper function to check if module is enabled; instances also need their type enabled
 	isModuleEnabled := func(moduleID string) bool {
 		if moduleID == "" {
 			return false
 		}
+		if moduleType := ModuleType(moduleID); moduleType != moduleID && !enabledModules[moduleType] {
+			return false
+		}
 		if enabled, exists := enabledModules[moduleID]; exists {
 			return enabled
 		}
@@ -1968,6 +2790,35 @@ func ProcessLayoutConfig(config LayoutConfig, modulePrefs map[string]interface{}
 		return true
 	}
 
+	mw := config.MaxWidth
+	if mw < 1 || mw > 100 {
+		mw = 80
+	}
+
+	// Flat layouts: keep enabled modules of this version, and make the breakpoint grids
+	// place the same ones
+	if config.Modules != nil {
+		modules := make([]LayoutModule, 0, len(config.Modules))
+		for _, m := range config.Modules {
+			if KnownModuleID(m.ID) && isModuleEnabled(m.ID) {
+				modules = append(modules, m)
+			}
+		}
+		var breakpoints map[string]LayoutGrid
+		for name, grid := range config.Breakpoints {
+			if breakpoints == nil {
+				breakpoints = make(map[string]LayoutGrid)
+			}
+			breakpoints[name] = reconcileLayoutGrid(grid, modules)
+		}
+		return LayoutConfig{
+			MaxWidth:    mw,
+			Columns:     config.Columns,
+			Modules:     modules,
+			Breakpoints: breakpoints,
+		}
+	}
+
 	// Process rows
 	processedRows := make([]LayoutRow, 0, len(config.Rows))
 	for _, row := range config.Rows {
@@ -2011,10 +2862,6 @@ func ProcessLayoutConfig(config LayoutConfig, modulePrefs map[string]interface{}
 		})
 	}
 
-	mw := config.MaxWidth
-	if mw < 1 || mw > 100 {
-		mw = 80
-	}
 	return LayoutConfig{
 		MaxWidth: mw,
 		Rows:     processedRows,
@@ -2037,7 +2884,7 @@ func (h *Handler) HandleLayoutProcess(w http.ResponseWriter, r *http.Request) {
 		if err := json.Unmarshal([]byte(prefsStr), &modulePrefs); err != nil {
 			// Try to get from storage
 			storage := GetStorage()
-			if item, exists := storage.Get("modulePrefs"); exists {
+			if item, exists := storage.GetForProfile(ProfileFromRequest(r), "modulePrefs"); exists {
 				if prefs, ok := item.Value.(map[string]interface{}); ok {
 					modulePrefs = prefs
 				}
@@ -2046,7 +2893,7 @@ func (h *Handler) HandleLayoutProcess(w http.ResponseWriter, r *http.Request) {
 	} else {
 		// Try to get from storage
 		storage := GetStorage()
-		if item, exists := storage.Get("modulePrefs"); exists {
+		if item, exists := storage.GetForProfile(ProfileFromRequest(r), "modulePrefs"); exists {
 			if prefs, ok := item.Value.(map[string]interface{}); ok {
 				modulePrefs = prefs
 			}
@@ -2070,18 +2917,25 @@ func ProcessModulePrefs(prefs map[string]interface{}) (map[string]interface{}, [
 			continue
 		}
 
-		// Check if module exists in metadata
-		modMeta, exists := metadata[moduleKey]
-		if !exists {
+		// Check if module exists in metadata; instances use their type's
+		modMeta, exists := metadata[ModuleType(moduleKey)]
+		if !exists || !KnownModuleID(moduleKey) {
 			errors = append(errors, fmt.Sprintf("Unknown module '%s'", moduleKey))
 			continue
 		}
+		if err := ValidModuleID(moduleKey); err != nil {
+			errors = append(errors, fmt.Sprintf("Module '%s': %v", moduleKey, err))
+			continue
+		}
+		isInstance := isModuleInstanceID(moduleKey)
 
 		processedPref := make(map[string]interface{})
 
 		// Validate enabled flag
 		if enabledVal, ok := prefMap["enabled"].(bool); ok {
 			processedPref["enabled"] = enabledVal
+		} else if isInstance {
+			processedPref["enabled"] = true // Instances follow their type
 		} else {
 			processedPref["enabled"] = modMeta.Enabled // Use default
 		}
@@ -2099,7 +2953,8 @@ func ProcessModulePrefs(prefs map[string]interface{}) (map[string]interface{}, [
 					errors = append(errors, fmt.Sprintf("Module '%s': interval too large, capped at 86400", moduleKey))
 				}
 				processedPref["interval"] = interval
-			} else {
+			} else if !isInstance {
+				// Instances without an interval of their own refresh with their type
 				processedPref["interval"] = int64(modMeta.DefaultInterval)
 			}
 		}
@@ -2110,6 +2965,194 @@ func ProcessModulePrefs(prefs map[string]interface{}) (map[string]interface{}, [
 	return processed, errors
 }
 
+// HandleLayoutResolve serves GET /api/layout/resolve?breakpoint=&profile=&page=: the grid of
+// a page's layout for a breakpoint (mobile, tablet or desktop), or for a viewport ?width=.
+// Breakpoints without a saved grid get one derived from the desktop layout.
+func (h *Handler) HandleLayoutResolve(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	breakpoint := q.Get("breakpoint")
+	if width := q.Get("width"); width != "" && breakpoint == "" {
+		px, err := strconv.Atoi(width)
+		if err != nil || px < 1 {
+			WriteJSON(w, map[string]any{"error": "Invalid width"})
+			return
+		}
+		breakpoint = LayoutBreakpointFor(px)
+	}
+	if breakpoint == "" {
+		breakpoint = LayoutBreakpointDesktop
+	}
+	if !ValidLayoutBreakpoint(breakpoint) {
+		WriteJSON(w, map[string]any{"error": "Invalid breakpoint, must be mobile, tablet or desktop"})
+		return
+	}
+
+	profile := ProfileFromRequest(r)
+	page, ok := PageFromRequest(r, profile)
+	if !ok {
+		WriteJSON(w, map[string]any{"error": ErrPageNotFound.Error()})
+		return
+	}
+	var config LayoutConfig
+	if !globalStorage.GetAsForProfile(profile, LayoutPageKey(page), &config) || config.Modules == nil {
+		WriteJSON(w, map[string]any{"error": "No layout saved"})
+		return
+	}
+	grid, derived := config.ForBreakpoint(breakpoint)
+	WriteJSON(w, map[string]any{
+		"breakpoint": breakpoint,
+		"derived":    derived,
+		"maxWidth":   config.MaxWidth,
+		"columns":    grid.Columns,
+		"modules":    grid.Modules,
+	})
+}
+
+// HandlePages serves GET /api/pages?profile=: the pages of the profile, home first, with
+// their path, and the page the dashboard opens at.
+func (h *Handler) HandlePages(w http.ResponseWriter, r *http.Request) {
+	profile := ProfileFromRequest(r)
+	pages, active := Pages(profile)
+	list := make([]map[string]any, len(pages))
+	for i, p := range pages {
+		list[i] = map[string]any{"id": p.ID, "name": p.Name, "modules": p.Modules, "path": PagePath(profile, p.ID)}
+	}
+	WriteJSON(w, map[string]any{"profile": profile, "pages": list, "active": active})
+}
+
+// pageRequest is the body of the page endpoints.
+type pageRequest struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Modules []string `json:"modules"`
+}
+
+// decodePageRequest reads the body of a POST to a page endpoint, reporting errors to w.
+func decodePageRequest(w http.ResponseWriter, r *http.Request) (pageRequest, bool) {
+	var req pageRequest
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return req, false
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 16<<10)).Decode(&req); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return req, false
+	}
+	return req, true
+}
+
+// HandlePageCreate serves POST /api/pages/create?profile=: {"name", "modules"} adds a page
+// showing the listed modules.
+func (h *Handler) HandlePageCreate(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodePageRequest(w, r)
+	if !ok {
+		return
+	}
+	profile := ProfileFromRequest(r)
+	page, err := CreatePage(profile, req.Name, req.Modules)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	Audit(r, "pages.create", profile+" "+page.ID)
+	WriteJSON(w, map[string]any{"success": true, "page": page, "path": PagePath(profile, page.ID)})
+}
+
+// HandlePageClone serves POST /api/pages/clone?profile=: {"id", "name"} adds a page with a
+// copy of the layout of page id.
+func (h *Handler) HandlePageClone(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodePageRequest(w, r)
+	if !ok {
+		return
+	}
+	profile := ProfileFromRequest(r)
+	page, err := ClonePage(profile, req.ID, req.Name)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	Audit(r, "pages.clone", profile+" "+req.ID+" "+page.ID)
+	WriteJSON(w, map[string]any{"success": true, "page": page, "path": PagePath(profile, page.ID)})
+}
+
+// HandlePageSwitch serves POST /api/pages/switch?profile=: {"id"} makes the dashboard open
+// at page id when its path names no page.
+func (h *Handler) HandlePageSwitch(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodePageRequest(w, r)
+	if !ok {
+		return
+	}
+	profile := ProfileFromRequest(r)
+	if err := SwitchPage(profile, req.ID); err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	Audit(r, "pages.switch", profile+" "+req.ID)
+	WriteJSON(w, map[string]any{"success": true, "active": req.ID, "path": PagePath(profile, req.ID)})
+}
+
+// HandlePageDelete serves POST /api/pages/delete?profile=: {"id"} removes a page and its
+// layout.
+func (h *Handler) HandlePageDelete(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodePageRequest(w, r)
+	if !ok {
+		return
+	}
+	profile := ProfileFromRequest(r)
+	if err := DeletePage(profile, req.ID); err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	Audit(r, "pages.delete", profile+" "+req.ID)
+	WriteJSON(w, map[string]any{"success": true})
+}
+
+// HandleLayoutHistory serves GET /api/layout/history?profile=&page=: the earlier layouts of
+// a page, newest first, and the storage version of the current one.
+func (h *Handler) HandleLayoutHistory(w http.ResponseWriter, r *http.Request) {
+	profile := ProfileFromRequest(r)
+	page, ok := PageFromRequest(r, profile)
+	if !ok {
+		WriteJSON(w, map[string]any{"error": ErrPageNotFound.Error()})
+		return
+	}
+	var current int64
+	if item, exists := globalStorage.GetForProfile(profile, LayoutPageKey(page)); exists {
+		current = item.Version
+	}
+	versions := GetLayoutHistory().Versions(profile, page)
+	WriteJSON(w, map[string]any{"profile": profile, "page": page, "current": current, "versions": versions, "count": len(versions)})
+}
+
+// HandleLayoutRollback serves POST /api/layout/rollback?profile=&page=: {"id"} makes an
+// earlier layout the page's current one on every device.
+func (h *Handler) HandleLayoutRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	profile := ProfileFromRequest(r)
+	page, ok := PageFromRequest(r, profile)
+	if !ok {
+		WriteJSON(w, map[string]any{"error": ErrPageNotFound.Error()})
+		return
+	}
+	version, err := GetLayoutHistory().Rollback(profile, page, req.ID)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	Audit(r, "layout.rollback", profile+" "+page+" "+req.ID)
+	WriteJSON(w, map[string]any{"success": true, "version": version})
+}
+
 // HandleModulePrefsProcess processes and validates module preferences.
 func (h *Handler) HandleModulePrefsProcess(w http.ResponseWriter, r *http.Request) {
 	var prefs map[string]interface{}
@@ -2176,6 +3219,44 @@ func AggregateGraphHistory(data GraphHistoryData) GraphHistoryData {
 	return result
 }
 
+// HandleGraphHistory returns server-side metric history for a metric and range.
+func (h *Handler) HandleGraphHistory(w http.ResponseWriter, r *http.Request) {
+	history := GetMetricsHistory()
+	metric := r.URL.Query().Get("metric")
+	if metric == "" {
+		WriteJSON(w, map[string]any{"metrics": history.Metrics()})
+		return
+	}
+
+	rng := time.Hour
+	if rs := r.URL.Query().Get("range"); rs != "" {
+		parsed, err := ParseHistoryRange(rs)
+		if err != nil || parsed <= 0 {
+			WriteJSON(w, map[string]any{"error": "Invalid 'range' parameter (e.g. 15m, 6h, 7d)"})
+			return
+		}
+		rng = parsed
+	}
+
+	maxPoints := 0
+	if mp, err := strconv.Atoi(r.URL.Query().Get("points")); err == nil && mp > 0 {
+		maxPoints = mp
+	}
+
+	points, resolution, ok := history.Query(metric, rng, maxPoints)
+	if !ok {
+		WriteJSON(w, map[string]any{"error": "Unknown metric: " + metric, "metrics": history.Metrics()})
+		return
+	}
+
+	WriteJSON(w, map[string]any{
+		"metric":     metric,
+		"range":      rng.String(),
+		"resolution": resolution,
+		"points":     points,
+	})
+}
+
 // HandleGraphHistoryAggregate aggregates graph history data.
 func (h *Handler) HandleGraphHistoryAggregate(w http.ResponseWriter, r *http.Request) {
 	var data GraphHistoryData
@@ -2257,7 +3338,7 @@ func (h *Handler) HandleStorageProcess(w http.ResponseWriter, r *http.Request) {
 		// Get module preferences from storage
 		storage := GetStorage()
 		var modulePrefs map[string]interface{}
-		if item, exists := storage.Get("modulePrefs"); exists {
+		if item, exists := storage.GetForProfile(ProfileFromRequest(r), "modulePrefs"); exists {
 			if prefs, ok := item.Value.(map[string]interface{}); ok {
 				modulePrefs = prefs
 			}
@@ -2340,47 +3421,18 @@ func (h *Handler) HandleModuleConfig(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Get from storage
-		storage := GetStorage()
-		var configs interface{}
-		switch configType {
-		case "github":
-			if item, exists := storage.Get("githubModules"); exists {
-				configs = item.Value
-			}
-		case "rss":
-			if item, exists := storage.Get("rssModules"); exists {
-				configs = item.Value
-			}
-		case "disk":
-			if item, exists := storage.Get("diskModules"); exists {
-				configs = item.Value
-			}
-		case "monitoring":
-			if item, exists := storage.Get("monitors"); exists {
-				configs = item.Value
-			}
-		case "snmp":
-			if item, exists := storage.Get("snmpQueries"); exists {
-				configs = item.Value
-			}
-		case "speedplane":
-			if item, exists := storage.Get("speedplaneConfig"); exists {
-				configs = item.Value
-			}
-		case "dnsplane":
-			if item, exists := storage.Get("dnsplaneConfig"); exists {
-				configs = item.Value
-			}
-		case "quicklinks":
-			if item, exists := storage.Get("quickLinks"); exists {
-				configs = item.Value
-			}
-		default:
+		schema, ok := ModuleSchemaFor(configType)
+		if !ok || schema.StorageKey == "" {
 			WriteJSON(w, map[string]any{"error": "Invalid module type"})
 			return
 		}
 
+		// Get from storage
+		var configs interface{}
+		if item, exists := GetStorage().GetForProfile(ProfileFromRequest(r), schema.StorageKey); exists {
+			configs = item.Value
+		}
+
 		WriteJSON(w, map[string]any{"configs": configs})
 		return
 	}
@@ -2397,35 +3449,12 @@ func (h *Handler) HandleModuleConfig(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate module type
-		validTypes := map[string]bool{
-			"github": true, "rss": true, "disk": true,
-			"monitoring": true, "snmp": true, "speedplane": true, "dnsplane": true, "quicklinks": true,
-		}
-	if !validTypes[req.Type] {
+	schema, ok := ModuleSchemaFor(req.Type)
+	if !ok || schema.StorageKey == "" {
 		WriteJSON(w, map[string]any{"error": "Invalid module type"})
 		return
 	}
-
-	// Get storage key for this module type
-	var storageKey string
-	switch req.Type {
-	case "github":
-		storageKey = "githubModules"
-	case "rss":
-		storageKey = "rssModules"
-	case "disk":
-		storageKey = "diskModules"
-	case "monitoring":
-		storageKey = "monitors"
-	case "snmp":
-		storageKey = "snmpQueries"
-	case "speedplane":
-		storageKey = "speedplaneConfig"
-	case "dnsplane":
-		storageKey = "dnsplaneConfig"
-	case "quicklinks":
-		storageKey = "quickLinks"
-	}
+	storageKey := schema.StorageKey
 
 	storage := GetStorage()
 
@@ -2441,7 +3470,7 @@ func (h *Handler) HandleModuleConfig(w http.ResponseWriter, r *http.Request) {
 
 	case "list":
 		// List all configs for this type
-		if item, exists := storage.Get(storageKey); exists {
+		if item, exists := storage.GetForProfile(ProfileFromRequest(r), storageKey); exists {
 			WriteJSON(w, map[string]any{"configs": item.Value})
 		} else {
 			WriteJSON(w, map[string]any{"configs": []interface{}{}})
@@ -2462,97 +3491,20 @@ func (h *Handler) HandleModuleConfig(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// ValidateModuleConfig validates a module configuration based on type.
+// ValidateModuleConfig validates a module configuration against the schema of its type.
 func ValidateModuleConfig(moduleType string, data interface{}) (bool, string) {
 	dataMap, ok := data.(map[string]interface{})
 	if !ok {
 		return false, "Invalid data format"
 	}
 
-	switch moduleType {
-	case "github":
-		repo, _ := dataMap["repo"].(string)
-		if repo == "" {
-			return false, "Repository is required"
-		}
-	case "rss":
-		url, _ := dataMap["url"].(string)
-		if url == "" {
-			return false, "URL is required"
-		}
-		if valid := IsValidURLOrIP(url); !valid {
-			return false, "Invalid URL"
-		}
-	case "disk":
-		mountPoint, _ := dataMap["mountPoint"].(string)
-		if mountPoint == "" {
-			return false, "Mount point is required"
-		}
-	case "monitoring":
-		return validateMonitoring(dataMap)
-	case "snmp":
-		host, _ := dataMap["host"].(string)
-		oid, _ := dataMap["oid"].(string)
-		if host == "" {
-			return false, "Host is required"
-		}
-		if oid == "" {
-			return false, "OID is required"
-		}
-	case "speedplane":
-		host, _ := dataMap["host"].(string)
-		port, _ := dataMap["port"]
-		if host == "" {
-			return false, "Host is required"
-		}
-		var portNum float64
-		var ok bool
-		portNum, ok = port.(float64)
-		if !ok {
-			portInt, ok := port.(int)
-			if !ok {
-				return false, "Port must be a number"
-			}
-			portNum = float64(portInt)
-		}
-		if portNum < 1 || portNum > 65535 {
-			return false, "Port must be between 1 and 65535"
-		}
-	case "dnsplane":
-		host, _ := dataMap["host"].(string)
-		port, _ := dataMap["port"]
-		if host == "" {
-			return false, "Host is required"
-		}
-		var portNum float64
-		var ok bool
-		portNum, ok = port.(float64)
-		if !ok {
-			portInt, ok := port.(int)
-			if !ok {
-				return false, "Port must be a number"
-			}
-			portNum = float64(portInt)
-		}
-		if portNum < 1 || portNum > 65535 {
-			return false, "Port must be between 1 and 65535"
-		}
-	case "quicklinks":
-		url, _ := dataMap["url"].(string)
-		title, _ := dataMap["title"].(string)
-		if url == "" {
-			return false, "URL is required"
-		}
-		if title == "" {
-			return false, "Title is required"
-		}
-		if valid := IsValidURLOrIP(url); !valid {
-			return false, "Invalid URL"
-		}
-	default:
+	schema, ok := ModuleSchemaFor(moduleType)
+	if !ok {
 		return false, "Unknown module type"
 	}
-
+	if err := schema.Validate(dataMap); err != nil {
+		return false, err.Error()
+	}
 	return true, ""
 }
 
@@ -2572,58 +3524,24 @@ func (h *Handler) HandleModulesBatch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	storage := GetStorage()
+	profile := ProfileFromRequest(r)
 	result := make(map[string]interface{})
 
 	// If specific types requested, only return those
+	schemas := ModuleSchemas()
 	if len(req.Types) > 0 {
-		for _, moduleType := range req.Types {
-			var storageKey string
-			switch moduleType {
-			case "github":
-				storageKey = "githubModules"
-			case "rss":
-				storageKey = "rssModules"
-			case "disk":
-				storageKey = "diskModules"
-			case "monitoring":
-				storageKey = "monitors"
-			case "snmp":
-				storageKey = "snmpQueries"
-			case "speedplane":
-				storageKey = "speedplaneConfig"
-			case "dnsplane":
-				storageKey = "dnsplaneConfig"
-			case "quicklinks":
-				storageKey = "quickLinks"
-			default:
-				continue
-			}
-
-			if item, exists := storage.Get(storageKey); exists {
-				result[moduleType] = item.Value
-			} else {
-				result[moduleType] = []interface{}{}
-			}
+		schemas = slices.DeleteFunc(schemas, func(schema ModuleSchema) bool {
+			return !slices.Contains(req.Types, schema.Type)
+		})
+	}
+	for _, schema := range schemas {
+		if schema.StorageKey == "" {
+			continue
 		}
-	} else {
-		// Return all module configs
-		moduleTypes := map[string]string{
-			"github":     "githubModules",
-			"rss":        "rssModules",
-			"disk":       "diskModules",
-			"monitoring": "monitors",
-			"snmp":       "snmpQueries",
-			"speedplane": "speedplaneConfig",
-			"dnsplane":   "dnsplaneConfig",
-			"quicklinks": "quickLinks",
-		}
-
-		for moduleType, storageKey := range moduleTypes {
-			if item, exists := storage.Get(storageKey); exists {
-				result[moduleType] = item.Value
-			} else {
-				result[moduleType] = []interface{}{}
-			}
+		if item, exists := storage.GetForProfile(profile, schema.StorageKey); exists {
+			result[schema.Type] = item.Value
+		} else {
+			result[schema.Type] = []interface{}{}
 		}
 	}
 
@@ -2631,9 +3549,1987 @@ func (h *Handler) HandleModulesBatch(w http.ResponseWriter, r *http.Request) {
 }
 
 // HandleHealthz is the health check endpoint.
+// Failing notification delivery is reported as degraded without failing the check.
 func (h *Handler) HandleHealthz(w http.ResponseWriter, _ *http.Request) {
+	body := "ok"
+	if ok, lastErr := GetMailer().Healthy(); !ok {
+		body = "degraded\nsmtp: " + lastErr
+	}
 	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write([]byte("ok")); err != nil {
-		log.Printf("Error writing healthz response: %v", err)
+	if _, err := w.Write([]byte(body)); err != nil {
+		Logger("healthz").Warn("writing response", "error", err)
+	}
+}
+
+// HandlePushVAPIDKey returns the VAPID public key used as applicationServerKey.
+func (h *Handler) HandlePushVAPIDKey(w http.ResponseWriter, _ *http.Request) {
+	key, err := GetPushManager().PublicKey()
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"publicKey": key})
+}
+
+// HandlePushSubscribe stores (POST) or removes (DELETE) a push subscription.
+func (h *Handler) HandlePushSubscribe(w http.ResponseWriter, r *http.Request) {
+	var sub PushSubscription
+	switch r.Method {
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+			WriteJSON(w, map[string]any{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		if err := GetPushManager().Subscribe(sub); err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		GetDebugLogger().Logf("push", "subscription added: %s", sub.Endpoint)
+		WriteJSON(w, map[string]any{"success": true, "subscriptions": GetPushManager().Count()})
+
+	case http.MethodDelete:
+		if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+			WriteJSON(w, map[string]any{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		if err := GetPushManager().Unsubscribe(sub.Endpoint); err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		GetDebugLogger().Logf("push", "subscription removed: %s", sub.Endpoint)
+		WriteJSON(w, map[string]any{"success": true, "subscriptions": GetPushManager().Count()})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandlePushTest sends a test notification to all subscriptions.
+func (h *Handler) HandlePushTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	sent, err := GetPushManager().Send(ctx, PushMessage{
+		Title: h.Title(),
+		Body:  "Push notifications are working",
+		URL:   "/",
+		Tag:   "push-test",
+	})
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"success": true, "sent": sent})
+}
+
+// HandleAlerts returns the alert channels with their quiet hours state and queued alerts.
+func (h *Handler) HandleAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	WriteJSON(w, map[string]any{"channels": GetAlertManager().Status()})
+}
+
+// HandleDigest previews the daily digest (GET) or sends it now (POST).
+func (h *Handler) HandleDigest(w http.ResponseWriter, r *http.Request) {
+	scheduler := GetDigestScheduler()
+	switch r.Method {
+	case http.MethodGet:
+		d := scheduler.Build(r.Context())
+		WriteJSON(w, map[string]any{"digest": d, "text": d.Text(), "schedule": scheduler.Config()})
+	case http.MethodPost:
+		d := scheduler.Send(r.Context())
+		WriteJSON(w, map[string]any{"success": true, "digest": d})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleStore returns the storage driver, schema version and row counts.
+func (h *Handler) HandleStore(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, GetStore().Status())
+}
+
+// HandleNotifications returns recently dispatched alerts (SQLite store only).
+func (h *Handler) HandleNotifications(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	notifications, err := GetStore().Notifications(r.Context(), limit)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"notifications": notifications})
+}
+
+// HandleMonitorHistory returns stored results of a monitor (SQLite store only).
+func (h *Handler) HandleMonitorHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("type") == "" {
+		WriteJSON(w, map[string]any{"error": "Missing query parameter 'type'"})
+		return
+	}
+	key := MonitorKey(q)
+	rng := 24 * time.Hour
+	if v := q.Get("range"); v != "" {
+		d, err := ParseHistoryRange(v)
+		if err != nil || d <= 0 {
+			WriteJSON(w, map[string]any{"error": "Invalid range"})
+			return
+		}
+		rng = d
+	}
+	results, err := GetStore().MonitorResults(r.Context(), key, time.Now().Add(-rng))
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"key": key, "results": results})
+}
+
+// HandleSMTPStatus returns the SMTP configuration and last delivery result.
+func (h *Handler) HandleSMTPStatus(w http.ResponseWriter, _ *http.Request) {
+	WriteJSON(w, GetMailer().Status())
+}
+
+// HandleSMTPTest sends a test mail to the configured recipients (or the "to" list in the body).
+func (h *Handler) HandleSMTPTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		To []string `json:"to"`
+	}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteJSON(w, map[string]any{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	if err := GetMailer().Send(ctx, req.To, h.Title()+" test mail", "SMTP delivery is working."); err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"success": true})
+}
+
+// HandleAuth returns whether authentication is enabled, the identity of the caller and
+// the single sign-on providers.
+func (h *Handler) HandleAuth(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, map[string]any{
+		"enabled":   GetTokenManager().Enabled(),
+		"identity":  RequestIdentity(r),
+		"providers": GetOIDCManager().Providers(),
+	})
+}
+
+// HandleAuthLogin stores a token in a cookie for browsers (POST) or removes it and ends a
+// single sign-on session (DELETE).
+func (h *Handler) HandleAuthLogin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteJSON(w, map[string]any{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		t, ok := GetTokenManager().Lookup(strings.TrimSpace(req.Token))
+		if !ok {
+			WriteJSON(w, map[string]any{"error": ErrInvalidToken.Error()})
+			return
+		}
+		// The cookie holds a session of this device rather than the token itself
+		session, secret, err := GetTokenManager().CreateTokenSession(t, DeviceOf(r))
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     authCookieName,
+			Value:    secret,
+			Path:     "/",
+			MaxAge:   int(time.Until(session.ExpiresAt).Seconds()),
+			HttpOnly: true,
+			Secure:   IsSecureRequest(r),
+			SameSite: http.SameSiteStrictMode,
+		})
+		GetAuditLog().Record(AuditEntry{Action: "auth.login", Actor: t.Name, Client: GetClientIP(r), Detail: DescribeUserAgent(r.UserAgent())})
+		WriteJSON(w, map[string]any{"success": true, "identity": Identity{Role: t.Role, TokenID: t.ID, Name: t.Name, Profile: t.Profile, SessionID: session.ID}})
+
+	case http.MethodDelete:
+		if c, err := r.Cookie(authCookieName); err == nil {
+			if err := GetTokenManager().EndSession(c.Value); err != nil {
+				Logger("auth").Warn("failed to end session", "error", err)
+			}
+			Audit(r, "auth.logout", "")
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     authCookieName,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+		WriteJSON(w, map[string]any{"success": true})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleTokens lists (GET), creates (POST) or deletes (DELETE) API tokens.
+func (h *Handler) HandleTokens(w http.ResponseWriter, r *http.Request) {
+	tm := GetTokenManager()
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, map[string]any{"tokens": tm.Tokens()})
+
+	case http.MethodPost:
+		var req struct {
+			Name    string `json:"name"`
+			Role    string `json:"role"`
+			Profile string `json:"profile"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteJSON(w, map[string]any{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		role, err := ParseRole(req.Role)
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		t, token, err := tm.CreateToken(strings.TrimSpace(req.Name), role, strings.ToLower(strings.TrimSpace(req.Profile)))
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		Audit(r, "token.create", t.Name+" ("+string(t.Role)+")")
+		WriteJSON(w, map[string]any{"success": true, "token": t, "secret": token})
+
+	case http.MethodDelete:
+		found, err := tm.DeleteToken(r.URL.Query().Get("id"))
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		if !found {
+			WriteJSON(w, map[string]any{"error": "Token not found"})
+			return
+		}
+		Audit(r, "token.delete", r.URL.Query().Get("id"))
+		WriteJSON(w, map[string]any{"success": true})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandlePresence returns who is home. Only local clients see it unless presence is public.
+func (h *Handler) HandlePresence(w http.ResponseWriter, r *http.Request) {
+	pm := GetPresenceManager()
+	if !pm.Enabled() {
+		WriteJSON(w, map[string]any{"enabled": false, "people": []Person{}})
+		return
+	}
+	people, err := pm.People(r)
+	if err != nil {
+		WriteJSON(w, map[string]any{"enabled": true, "error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"enabled": true, "people": people, "lastError": pm.LastError()})
+}
+
+// HandleOwnTracks receives OwnTracks HTTP mode updates (?token=; the user is taken from the
+// X-Limit-U header or ?user=). OwnTracks expects a JSON array in response.
+func (h *Handler) HandleOwnTracks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var msg OwnTracksMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&msg); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	user := r.Header.Get("X-Limit-U")
+	if user == "" {
+		user = r.URL.Query().Get("user")
+	}
+	if err := GetPresenceManager().HandleOwnTracks(r.URL.Query().Get("token"), user, msg); err != nil {
+		if errors.Is(err, ErrUnknownWebhookToken) {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, []any{})
+}
+
+// HandleGuestWiFi returns the guest network with its QR payload (GET) or stores new
+// credentials (POST). Only local clients see the credentials unless guestWifi.public is set.
+func (h *Handler) HandleGuestWiFi(w http.ResponseWriter, r *http.Request) {
+	gm := GetGuestWiFiManager()
+	switch r.Method {
+	case http.MethodGet:
+		if !gm.Visible(r) {
+			WriteJSON(w, map[string]any{"error": "Guest Wi-Fi is only visible on the local network"})
+			return
+		}
+		wifi := gm.Get()
+		WriteJSON(w, map[string]any{
+			"configured":   wifi.SSID != "",
+			"wifi":         wifi,
+			"qr":           wifi.QRPayload(),
+			"nextRotation": gm.NextRotation(),
+		})
+
+	case http.MethodPost:
+		var wifi GuestWiFi
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&wifi); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		saved, err := gm.Set(wifi)
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		WriteJSON(w, map[string]any{"success": true, "wifi": saved, "qr": saved.QRPayload()})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleGuestWiFiRotate generates a new guest password and applies it on the router.
+func (h *Handler) HandleGuestWiFiRotate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+	wifi, err := GetGuestWiFiManager().Rotate(ctx)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"success": true, "wifi": wifi, "qr": wifi.QRPayload()})
+}
+
+// HandleGuestWiFiQR renders the guest network QR code as a PNG image.
+func (h *Handler) HandleGuestWiFiQR(w http.ResponseWriter, r *http.Request) {
+	gm := GetGuestWiFiManager()
+	if !gm.Visible(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	payload := gm.Get().QRPayload()
+	if payload == "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	size := 256
+	if s, err := strconv.Atoi(r.URL.Query().Get("size")); err == nil && s >= 64 && s <= 1024 {
+		size = s
+	}
+	png, err := qrcode.Encode(payload, qrcode.Medium, size)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(png)
+}
+
+// HandleRouter returns WAN status, DHCP leases and wireless client counts from the
+// OpenWrt router. Only local clients see it unless router.public is set.
+func (h *Handler) HandleRouter(w http.ResponseWriter, r *http.Request) {
+	rm := GetRouterMonitor()
+	if !rm.Enabled() {
+		WriteJSON(w, map[string]any{"enabled": false})
+		return
+	}
+	if !rm.Visible(r) {
+		WriteJSON(w, map[string]any{"enabled": true, "error": "Router status is only visible on the local network"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+	status, err := rm.Status(ctx)
+	if err != nil {
+		WriteJSON(w, map[string]any{"enabled": true, "error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"enabled": true, "router": status})
+}
+
+// HandleBrief renders today's events, due todos, weather and monitor states as a compact
+// printable page (format=html, the default), a PDF (format=pdf, paper=a4|letter) or JSON.
+func (h *Handler) HandleBrief(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+	brief := BuildBrief(ctx, h.Config().Weather)
+
+	q := r.URL.Query()
+	switch q.Get("format") {
+	case "", "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := brief.WriteHTML(w); err != nil {
+			GetDebugLogger().Logf("brief", "render HTML: %v", err)
+		}
+	case "pdf":
+		paper := "A4"
+		switch strings.ToLower(q.Get("paper")) {
+		case "", "a4":
+		case "letter":
+			paper = "Letter"
+		default:
+			http.Error(w, "paper must be a4 or letter", http.StatusBadRequest)
+			return
+		}
+		var buf bytes.Buffer
+		if err := brief.WritePDF(&buf, paper); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="brief-%s.pdf"`, brief.Date))
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(buf.Bytes())
+	case "json":
+		WriteJSON(w, brief)
+	default:
+		http.Error(w, "format must be html, pdf or json", http.StatusBadRequest)
+	}
+}
+
+// HandleEink serves the static e-ink view: grayscale HTML without scripts that reloads
+// itself with a meta refresh (?refresh= seconds, default 300). ?modules= selects the
+// sections, ?w= and ?h= fix the page size for the display and ?invert=1 inverts it.
+func (h *Handler) HandleEink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	opts, err := ParseEinkOptions(r.URL.Query().Get)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+	view := BuildEinkView(ctx, h.Config().Weather, opts)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := view.WriteHTML(w); err != nil {
+		Logger("eink").Error("render failed", "error", err)
+	}
+}
+
+// HandleStats returns request counters per route and the busiest clients (GET) or
+// resets them (DELETE). Counting is enabled with requestLog.stats in the config.
+func (h *Handler) HandleStats(w http.ResponseWriter, r *http.Request) {
+	stats := GetRequestStats()
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, stats.Snapshot())
+	case http.MethodDelete:
+		stats.Reset()
+		WriteJSON(w, map[string]any{"success": true})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleBriefAudio speaks the daily brief (weather, events, todos and alerts) with the
+// configured text-to-speech engine. ?format=text returns the script instead.
+func (h *Handler) HandleBriefAudio(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	text := BuildBrief(ctx, h.Config().Weather).SpeechText()
+	cancel()
+
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		io.WriteString(w, text+"\n")
+		return
+	}
+	speaker := GetSpeaker()
+	if !speaker.Enabled() {
+		http.Error(w, "Text-to-speech is not configured (set tts in the config file)", http.StatusServiceUnavailable)
+		return
+	}
+	audio, contentType, err := speaker.Synthesize(r.Context(), text)
+	if err != nil {
+		Logger("tts").Warn("synthesis failed", "error", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(audio)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(audio)
+}
+
+// HandleCapabilities returns the caller's role and which actions it may run, so that the
+// UI only offers buttons that will work.
+func (h *Handler) HandleCapabilities(w http.ResponseWriter, r *http.Request) {
+	id := RequestIdentity(r)
+	WriteJSON(w, map[string]any{
+		"authEnabled":  GetTokenManager().Enabled(),
+		"identity":     id,
+		"profile":      ProfileFromRequest(r),
+		"capabilities": CapabilitiesFor(id.Role),
+		"actions":      Capabilities,
+	})
+}
+
+// HandleMQTT returns the MQTT connection state with the value of every sensor. With
+// ?topic= (wildcards allowed) it also returns the cached messages of matching topics.
+func (h *Handler) HandleMQTT(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	mm := GetMQTTManager()
+	status := mm.Status()
+	if !status.Enabled {
+		WriteJSON(w, map[string]any{"enabled": false})
+		return
+	}
+	resp := map[string]any{
+		"enabled":   true,
+		"broker":    status.Broker,
+		"connected": status.Connected,
+		"sensors":   status.Sensors,
+		"topics":    status.Topics,
+	}
+	if status.Error != "" {
+		resp["error"] = status.Error
+	}
+	if topic := r.URL.Query().Get("topic"); topic != "" {
+		resp["messages"] = mm.Messages(topic)
+	}
+	WriteJSON(w, resp)
+}
+
+// HandleQuotes returns stock and cryptocurrency prices with their change. ?stocks= and
+// ?crypto= (comma-separated) replace the configured lists; ?refresh=1 skips the cache.
+func (h *Handler) HandleQuotes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	cfg := GetQuoteService().Config()
+	q := r.URL.Query()
+	lists := [][]string{cfg.Stocks, cfg.Crypto}
+	for i, name := range []string{"stocks", "crypto"} {
+		if !q.Has(name) {
+			continue
+		}
+		lists[i] = nil
+		for s := range strings.SplitSeq(q.Get(name), ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			if !quoteSymbolPattern.MatchString(s) {
+				WriteJSON(w, map[string]string{"error": "invalid symbol " + strconv.Quote(s)})
+				return
+			}
+			lists[i] = append(lists[i], s)
+		}
+		if len(lists[i]) > maxQuoteSymbols {
+			WriteJSON(w, map[string]string{"error": fmt.Sprintf("at most %d %s", maxQuoteSymbols, name)})
+			return
+		}
+	}
+	if len(lists[0]) == 0 && len(lists[1]) == 0 {
+		WriteJSON(w, QuotesResult{Quotes: []Quote{}})
+		return
+	}
+	WriteJSON(w, GetQuoteService().Quotes(r.Context(), lists[0], lists[1], q.Get("refresh") == "1"))
+}
+
+// currencyParam returns a currency code of the query, upper-cased, or def when it is empty.
+func currencyParam(r *http.Request, name, def string) (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get(name)))
+	if code == "" {
+		return def, nil
+	}
+	if !currencyCodePattern.MatchString(code) {
+		return "", fmt.Errorf("invalid currency %q", code)
+	}
+	return code, nil
+}
+
+// HandleCurrency returns the exchange rates of the base currency to the targets. ?base=
+// and ?targets= (comma-separated) replace the configured ones.
+func (h *Handler) HandleCurrency(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	cs := GetCurrencyService()
+	base, targets := cs.Defaults()
+	base, err := currencyParam(r, "base", base)
+	if err != nil {
+		WriteJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	if list := r.URL.Query().Get("targets"); list != "" {
+		targets = nil
+		for code := range strings.SplitSeq(strings.ToUpper(list), ",") {
+			if code = strings.TrimSpace(code); !currencyCodePattern.MatchString(code) {
+				WriteJSON(w, map[string]string{"error": fmt.Sprintf("invalid currency %q", code)})
+				return
+			}
+			if code != base {
+				targets = append(targets, code)
+			}
+		}
+	}
+	WriteJSON(w, cs.Rates(r.Context(), base, targets))
+}
+
+// HandleCurrencyConvert converts ?amount= (default 1) from ?from= (default the base) to ?to=.
+func (h *Handler) HandleCurrencyConvert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	cs := GetCurrencyService()
+	base, _ := cs.Defaults()
+	from, err := currencyParam(r, "from", base)
+	if err != nil {
+		WriteJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	to, err := currencyParam(r, "to", "")
+	if err == nil && to == "" {
+		err = fmt.Errorf("missing parameter 'to'")
+	}
+	if err != nil {
+		WriteJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	amount := 1.0
+	if text := r.URL.Query().Get("amount"); text != "" {
+		amount, err = strconv.ParseFloat(text, 64)
+		if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+			WriteJSON(w, map[string]string{"error": "invalid amount"})
+			return
+		}
+	}
+	conv, err := cs.Convert(r.Context(), from, to, amount)
+	if err != nil {
+		WriteJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, conv)
+}
+
+// HandleOIDCLogin redirects the browser to a single sign-on provider (?provider=). After
+// the login it returns to ?return= (a local path, default /).
+func (h *Handler) HandleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+	target, state, err := GetOIDCManager().LoginURL(ctx, r.URL.Query().Get("provider"), r.URL.Query().Get("return"))
+	if err != nil {
+		Logger("auth").Warn("single sign-on failed", "error", err)
+		http.Error(w, "Sign-in failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	// Lax, because the provider sends the browser back with a cross-site redirect
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int(oidcLoginTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   IsSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// HandleOIDCCallback completes a single sign-on login, stores the session in the login
+// cookie and returns to the page the login started from.
+func (h *Handler) HandleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+	if e := q.Get("error"); e != "" {
+		if desc := q.Get("error_description"); desc != "" {
+			e = desc
+		}
+		http.Error(w, "Sign-in failed: "+e, http.StatusForbidden)
+		return
+	}
+	state := q.Get("state")
+	if c, err := r.Cookie(oidcStateCookie); err != nil || state == "" || c.Value != state {
+		http.Error(w, "Sign-in failed: the login was started in another browser or has expired, please try again", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+	session, secret, returnTo, err := GetOIDCManager().Callback(ctx, state, q.Get("code"), DeviceOf(r))
+	if err != nil {
+		Logger("auth").Warn("single sign-on failed", "error", err)
+		http.Error(w, "Sign-in failed: "+err.Error(), http.StatusForbidden)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     authCookieName,
+		Value:    secret,
+		Path:     "/",
+		MaxAge:   int(time.Until(session.ExpiresAt).Seconds()),
+		HttpOnly: true,
+		Secure:   IsSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	GetAuditLog().Record(AuditEntry{Action: "auth.login", Actor: session.Name, Client: GetClientIP(r), Detail: session.Provider + ", " + string(session.Role)})
+	http.Redirect(w, r, returnTo, http.StatusFound)
+}
+
+// HandleSessions lists the signed-in devices of the caller (GET) or signs one out (DELETE
+// ?id=, or ?others=1 for all but the current one). With the sessions.manage capability it
+// lists and signs out the devices of every user (?all=1).
+func (h *Handler) HandleSessions(w http.ResponseWriter, r *http.Request) {
+	tm := GetTokenManager()
+	id := RequestIdentity(r)
+	all := r.URL.Query().Get("all") == "1" && id.Role.Allows(CapabilityRole("sessions.manage"))
+	if id.Anonymous && !all {
+		WriteJSON(w, map[string]any{"error": "Sign in to see your devices"})
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, map[string]any{"sessions": tm.Sessions(id, all), "all": all})
+
+	case http.MethodDelete:
+		q := r.URL.Query()
+		others := q.Get("others") == "1"
+		if q.Get("id") == "" && !others {
+			WriteJSON(w, map[string]any{"error": "Missing 'id' parameter"})
+			return
+		}
+		removed, err := tm.RevokeSessions(id, all, q.Get("id"), others)
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		if removed == 0 && !others {
+			WriteJSON(w, map[string]any{"error": "Session not found"})
+			return
+		}
+		Logger("auth").Info("signed out devices", "by", id.Name, "count", removed)
+		Audit(r, "session.revoke", fmt.Sprintf("%d session(s)", removed))
+		WriteJSON(w, map[string]any{"success": true, "removed": removed})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleAudit serves GET /api/audit: the audit log newest first (?limit=, default 100,
+// and ?action= to filter, e.g. auth.login).
+func (h *Handler) HandleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			WriteJSON(w, map[string]any{"error": "Invalid 'limit' parameter"})
+			return
+		}
+		limit = n
+	}
+	log := GetAuditLog()
+	WriteJSON(w, map[string]any{"entries": log.Entries(limit, r.URL.Query().Get("action")), "total": log.Len()})
+}
+
+// HandleRetention serves /api/retention: GET returns the retention policies, the pruning
+// job and the storage footprint; POST prunes now.
+func (h *Handler) HandleRetention(w http.ResponseWriter, r *http.Request) {
+	rm := GetRetentionManager()
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, rm.Status())
+
+	case http.MethodPost:
+		run := rm.Prune()
+		Audit(r, "retention.prune", "")
+		WriteJSON(w, map[string]any{"success": true, "run": run})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleGraphExport serves GET /api/graphs/export: the server-side metric history as a
+// JSON download for /api/graphs/import on another instance.
+func (h *Handler) HandleGraphExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	export := GetMetricsHistory().Export()
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="metrics-history-%s-%s.json"`, export.Host, export.Exported.Format("2006-01-02")))
+	WriteJSON(w, export)
+}
+
+// HandleGraphImport serves POST /api/graphs/import: merges a metric history exported by
+// /api/graphs/export into this instance's history.
+func (h *Handler) HandleGraphImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var export MetricsHistoryExport
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<20)).Decode(&export); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid JSON: " + err.Error()})
+		return
+	}
+	result, err := GetMetricsHistory().Import(export)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	Logger("graphs").Info("imported metric history", "from", export.Host, "metrics", result.Metrics, "fine", result.Fine, "hourly", result.Hourly)
+	Audit(r, "graphs.import", export.Host)
+	WriteJSON(w, map[string]any{"success": true, "imported": result})
+}
+
+// HandleVirt serves GET /api/virt: nodes and guests of the configured Proxmox VE cluster
+// and local libvirt daemon.
+func (h *Handler) HandleVirt(w http.ResponseWriter, r *http.Request) {
+	vm := GetVirtMonitor()
+	if !vm.Enabled() {
+		WriteJSON(w, map[string]any{"enabled": false})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+	status, err := vm.Status(ctx)
+	if err != nil {
+		WriteJSON(w, map[string]any{"enabled": true, "error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"enabled": true, "virt": status})
+}
+
+// HandleModuleHealth serves GET /api/modules/health: the fetch success rate, last error
+// and degraded state of every module that fetched since startup (?module= for one).
+func (h *Handler) HandleModuleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tracker := GetModuleHealth()
+	if name := r.URL.Query().Get("module"); name != "" {
+		health, ok := tracker.Get(name)
+		if !ok {
+			WriteJSON(w, map[string]any{"error": "No fetches recorded for module: " + name})
+			return
+		}
+		WriteJSON(w, health)
+		return
+	}
+	modules := tracker.All()
+	degraded := []string{}
+	for _, m := range modules {
+		if m.Degraded {
+			degraded = append(degraded, m.Module)
+		}
+	}
+	WriteJSON(w, map[string]any{"modules": modules, "degraded": degraded})
+}
+
+// HandleUPS serves GET /api/ups: battery charge, runtime, load and power source of the
+// configured UPSes from the last poll.
+func (h *Handler) HandleUPS(w http.ResponseWriter, r *http.Request) {
+	um := GetUPSMonitor()
+	if !um.Enabled() {
+		WriteJSON(w, map[string]any{"enabled": false})
+		return
+	}
+	status, updated := um.Status()
+	onBattery := false
+	for _, s := range status {
+		onBattery = onBattery || s.OnBattery
+	}
+	WriteJSON(w, map[string]any{"enabled": true, "ups": status, "onBattery": onBattery, "updated": updated})
+}
+
+// HandleConfigLint serves GET /api/config/lint: problems in the stored configuration of
+// the request's profile, with what to change for each.
+func (h *Handler) HandleConfigLint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	profile := ProfileFromRequest(r)
+	warnings := LintConfig(r.Context(), profile, h.Config().ListenAddr, r.Host)
+	WriteJSON(w, map[string]any{"profile": profile, "warnings": warnings, "count": len(warnings)})
+}
+
+// HandleShares serves GET /api/shares: capacity of the mounted NFS and SMB shares and
+// whether their servers are reachable, separately from local disks.
+func (h *Handler) HandleShares(w http.ResponseWriter, r *http.Request) {
+	sm := GetShareMonitor()
+	if !sm.Enabled() {
+		WriteJSON(w, map[string]any{"enabled": false})
+		return
+	}
+	shares, err := sm.Status(r.Context())
+	if err != nil {
+		WriteJSON(w, map[string]any{"enabled": true, "error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"enabled": true, "shares": shares})
+}
+
+// HandleEmbed serves GET /api/embed: the Embed card's framed pages and sanitized HTML and
+// Markdown snippets.
+func (h *Handler) HandleEmbed(w http.ResponseWriter, r *http.Request) {
+	es := GetEmbedService()
+	if !es.Enabled() {
+		WriteJSON(w, map[string]any{"enabled": false})
+		return
+	}
+	WriteJSON(w, map[string]any{"enabled": true, "widgets": es.Items()})
+}
+
+// HandleMonitorImport serves POST /api/monitor/import: creates monitors in bulk from an nmap
+// XML scan or a CSV of hosts in the body. ?dryRun=1 returns the monitors without saving.
+func (h *Handler) HandleMonitorImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 16<<20))
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": "Failed to read import: " + err.Error()})
+		return
+	}
+	parsed, err := ParseMonitorImport(data)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	dryRun := r.URL.Query().Get("dryRun") == "1" || r.URL.Query().Get("dryRun") == "true"
+	if q := r.URL.Query().Get("identify"); q != "0" && q != "false" {
+		ctx, cancel := context.WithTimeout(OutboundContext(r), 30*time.Second)
+		parsed = IdentifyImport(ctx, parsed)
+		cancel()
+	}
+	result := ImportMonitors(ProfileFromRequest(r), parsed, dryRun)
+	if !dryRun && len(result.Added) > 0 {
+		Logger("monitor").Info("imported monitors", "format", result.Format, "added", len(result.Added), "skipped", len(result.Skipped))
+	}
+	WriteJSON(w, map[string]any{"success": true, "dryRun": dryRun, "format": result.Format, "added": result.Added, "skipped": result.Skipped})
+}
+
+// HandlePublicIPHistory returns the public IP addresses seen, newest first, and the state of
+// the dynamic DNS records.
+func (h *Handler) HandlePublicIPHistory(w http.ResponseWriter, _ *http.Request) {
+	WriteJSON(w, GetPublicIPWatcher().History())
+}
+
+// HandleIdentify fetches a page and recognizes the service behind it from its title and
+// favicon, to label a monitor and suggest the module that integrates with it.
+func (h *Handler) HandleIdentify(w http.ResponseWriter, r *http.Request) {
+	targetURL := r.URL.Query().Get("url")
+	if targetURL == "" {
+		WriteJSON(w, map[string]string{"error": "Missing 'url' parameter"})
+		return
+	}
+	if !strings.HasPrefix(targetURL, "http://") && !strings.HasPrefix(targetURL, "https://") {
+		WriteJSON(w, map[string]string{"error": "Invalid URL"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(OutboundContext(r), 10*time.Second)
+	defer cancel()
+	WriteJSON(w, IdentifyService(ctx, targetURL))
+}
+
+// HandleOOB returns the power state, sensors and event log of the servers read through
+// their BMCs.
+func (h *Handler) HandleOOB(w http.ResponseWriter, r *http.Request) {
+	om := GetOOBMonitor()
+	if !om.Enabled() {
+		WriteJSON(w, map[string]any{"enabled": false})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	WriteJSON(w, map[string]any{"enabled": true, "servers": om.Status(ctx)})
+}
+
+// HandleOOBPower serves POST /api/oob/power: {"server": name, "action": "on|off|force-off|reset|cycle"}.
+// Actions come only from local clients unless oob.remotePower is set.
+func (h *Handler) HandleOOBPower(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	om := GetOOBMonitor()
+	if !om.Enabled() {
+		WriteJSON(w, map[string]any{"enabled": false})
+		return
+	}
+	if !IsLocalRequest(r) && !om.RemotePower() {
+		http.Error(w, "Power actions are only allowed from the local network", http.StatusForbidden)
+		return
+	}
+	var req struct {
+		Server string `json:"server"`
+		Action string `json:"action"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	if err := om.Power(ctx, req.Server, req.Action); err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	Audit(r, "oob.power", req.Server+": "+req.Action)
+	WriteJSON(w, map[string]any{"success": true})
+}
+
+// HandleCommands serves GET /api/command: every configured command with the output of
+// its last run.
+func (h *Handler) HandleCommands(w http.ResponseWriter, r *http.Request) {
+	cr := GetCommandRunner()
+	if !cr.Enabled() {
+		WriteJSON(w, map[string]any{"enabled": false})
+		return
+	}
+	WriteJSON(w, map[string]any{"enabled": true, "commands": cr.Results()})
+}
+
+// HandleCommand serves /api/command/{name}: GET returns the last run of a command, POST
+// runs it now and returns the new result.
+func (h *Handler) HandleCommand(w http.ResponseWriter, r *http.Request) {
+	cr := GetCommandRunner()
+	if !cr.Enabled() {
+		WriteJSON(w, map[string]any{"enabled": false})
+		return
+	}
+	name := r.PathValue("name")
+	switch r.Method {
+	case http.MethodGet:
+		result, err := cr.Result(name)
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		WriteJSON(w, map[string]any{"enabled": true, "command": result})
+	case http.MethodPost:
+		result, err := cr.Run(r.Context(), name)
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		Audit(r, "command.run", name)
+		WriteJSON(w, map[string]any{"enabled": true, "command": result})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleExposure returns the listening sockets of the last scan (GET) or scans now (POST).
+func (h *Handler) HandleExposure(w http.ResponseWriter, r *http.Request) {
+	es := GetExposureScanner()
+	if !es.Enabled() {
+		WriteJSON(w, map[string]any{"enabled": false})
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, map[string]any{"enabled": true, "report": es.Report()})
+	case http.MethodPost:
+		WriteJSON(w, map[string]any{"enabled": true, "report": es.Scan(r.Context())})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleBookmarkImport serves POST /api/bookmarks/import: stores the bookmarks of an uploaded
+// Chrome Bookmarks JSON file or HTML export for autocomplete on every client. ?replace=1
+// replaces the stored bookmarks instead of merging by URL; DELETE removes them all.
+func (h *Handler) HandleBookmarkImport(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		if err := GetBookmarkStore().Clear(); err != nil {
+			WriteJSON(w, map[string]any{"error": "Failed to clear bookmarks: " + err.Error()})
+			return
+		}
+		WriteJSON(w, map[string]any{"success": true})
+		return
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 32<<20))
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": "Failed to read import: " + err.Error()})
+		return
+	}
+	bookmarks, format, err := ParseBookmarkImport(data)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	replace := r.URL.Query().Get("replace") == "1" || r.URL.Query().Get("replace") == "true"
+	result, err := GetBookmarkStore().Import(bookmarks, replace)
+	result.Format = format
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": "Failed to save bookmarks: " + err.Error()})
+		return
+	}
+	Logger("bookmarks").Info("imported bookmarks", "format", format, "added", result.Added, "updated", result.Updated, "total", result.Total)
+	WriteJSON(w, map[string]any{"success": true, "result": result})
+}
+
+// HandleBookmarkAdd serves POST /api/bookmarks/add: {"title", "url", "folder", "icon"}.
+func (h *Handler) HandleBookmarkAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var b Bookmark
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&b); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	b, err := GetBookmarkStore().Add(b)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"success": true, "bookmark": b})
+}
+
+// HandleBookmarkUpdate serves POST /api/bookmarks/update: {"id", "title", "url", "folder", "icon"}.
+// Only bookmarks kept by the dashboard have an ID and can be changed.
+func (h *Handler) HandleBookmarkUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var b Bookmark
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&b); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	b, err := GetBookmarkStore().Update(b)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"success": true, "bookmark": b})
+}
+
+// HandleBookmarkDelete serves POST /api/bookmarks/delete: {"id"} removes a bookmark and
+// {"folder"} removes every bookmark in a folder and its subfolders.
+func (h *Handler) HandleBookmarkDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ID     string `json:"id"`
+		Folder string `json:"folder"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	if req.ID == "" {
+		removed, err := GetBookmarkStore().DeleteFolder(req.Folder)
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		WriteJSON(w, map[string]any{"success": true, "removed": removed})
+		return
+	}
+	if err := GetBookmarkStore().Delete(req.ID); err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"success": true, "removed": 1})
+}
+
+// HandleBookmarkFolders lists the bookmark folders (GET) or renames and moves a folder of
+// dashboard bookmarks (POST {"from": "Dev", "to": "Work/Dev"}).
+func (h *Handler) HandleBookmarkFolders(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		preferredBrowser := r.URL.Query().Get("browser")
+		if preferredBrowser == "" {
+			preferredBrowser = DetectBrowserFromUserAgent(r.Header.Get("User-Agent"))
+		}
+		bookmarks, _ := GetBookmarks(preferredBrowser)
+		bookmarks = MergeBookmarks(append(GetBookmarkStore().Tagged(), bookmarks...))
+		WriteJSON(w, map[string]any{"folders": BookmarkFolders(bookmarks)})
+	case http.MethodPost:
+		var req struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+			WriteJSON(w, map[string]any{"error": "Invalid request body"})
+			return
+		}
+		moved, err := GetBookmarkStore().RenameFolder(req.From, req.To)
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		WriteJSON(w, map[string]any{"success": true, "moved": moved})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleToolsPassword generates passwords or diceware passphrases. The policy comes from
+// the query string (GET) or a JSON PasswordPolicy (POST); see PasswordPolicyFromQuery.
+func (h *Handler) HandleToolsPassword(w http.ResponseWriter, r *http.Request) {
+	var policy PasswordPolicy
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		policy, err = PasswordPolicyFromQuery(r.URL.Query())
+	case http.MethodPost:
+		policy = DefaultPasswordPolicy()
+		if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&policy); err != nil {
+			WriteJSON(w, map[string]any{"error": "Invalid request body"})
+			return
+		}
+		err = policy.Validate()
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	result, err := GeneratePasswords(policy)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	WriteJSON(w, result)
+}
+
+// HandleToolsHash hashes, encodes or decodes text: POST {"op": "sha256", "text": "..."} or
+// GET ?op=&text=. Without an op every hash and encoding of the text is returned; a GET
+// without text lists the operations.
+func (h *Handler) HandleToolsHash(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Op   string `json:"op"`
+		Text string `json:"text"`
+	}
+	switch r.Method {
+	case http.MethodGet:
+		if !r.URL.Query().Has("text") {
+			WriteJSON(w, map[string]any{"operations": HashOperations})
+			return
+		}
+		req.Op = r.URL.Query().Get("op")
+		req.Text = r.URL.Query().Get("text")
+	case http.MethodPost:
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxHashInput+4096)).Decode(&req); err != nil {
+			WriteJSON(w, map[string]any{"error": "Invalid request body"})
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if len(req.Text) > maxHashInput {
+		WriteJSON(w, map[string]any{"error": "text is too long"})
+		return
+	}
+
+	if req.Op == "" {
+		results := make(map[string]string)
+		for _, op := range HashOperations {
+			if strings.HasSuffix(op, "-decode") {
+				continue
+			}
+			results[op], _ = HashText(op, req.Text)
+		}
+		WriteJSON(w, map[string]any{"results": results})
+		return
+	}
+	result, err := HashText(req.Op, req.Text)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"op": req.Op, "result": result})
+}
+
+// HandleSearchEngineAdd serves POST /api/search-engines/add: {"name", "url", "icon",
+// "category", "bang"}, with %s in the URL where the query goes.
+func (h *Handler) HandleSearchEngineAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var e SearchEngine
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&e); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	e, err := GetSearchEngineStore().Add(e)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"success": true, "engine": e})
+}
+
+// HandleSearchEngineUpdate serves POST /api/search-engines/update: the fields of a
+// user-defined engine with its "id". Built-in engines cannot be changed.
+func (h *Handler) HandleSearchEngineUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var e SearchEngine
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&e); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	e, err := GetSearchEngineStore().Update(e)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"success": true, "engine": e})
+}
+
+// HandleSearchEngineDelete serves POST /api/search-engines/delete: {"id"}.
+func (h *Handler) HandleSearchEngineDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	if err := GetSearchEngineStore().Delete(req.ID); err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"success": true})
+}
+
+// HandleDrop serves /api/drop, a clipboard shared by the devices on the LAN. GET lists the
+// drops not yet expired with the limits; POST adds text as JSON {"text", "ttl"} or a file
+// in the "file" field of a multipart form, with an optional "ttl" field such as "1h".
+func (h *Handler) HandleDrop(w http.ResponseWriter, r *http.Request) {
+	ds := GetDropStore()
+	if !ds.Enabled() {
+		WriteJSON(w, map[string]any{"enabled": false})
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, map[string]any{"status": ds.Status(), "drops": ds.List()})
+	case http.MethodPost:
+		status := ds.Status()
+		r.Body = http.MaxBytesReader(w, r.Body, status.MaxSize+64<<10)
+		device := DescribeUserAgent(r.UserAgent())
+		var drop Drop
+		var err error
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			file, header, ferr := r.FormFile("file")
+			if ferr != nil {
+				WriteJSON(w, map[string]any{"error": fmt.Sprintf("A file of up to %d MB is required in the \"file\" field", status.MaxSize>>20)})
+				return
+			}
+			defer file.Close()
+			ttl, _ := ParseHistoryRange(r.FormValue("ttl"))
+			content, rerr := io.ReadAll(io.LimitReader(file, status.MaxSize+1))
+			if rerr != nil {
+				WriteJSON(w, map[string]any{"error": "Failed to read the file"})
+				return
+			}
+			drop, err = ds.AddFile(header.Filename, header.Header.Get("Content-Type"), content, ttl, device)
+		} else {
+			var req struct {
+				Text string `json:"text"`
+				TTL  string `json:"ttl"`
+			}
+			if jerr := json.NewDecoder(r.Body).Decode(&req); jerr != nil {
+				WriteJSON(w, map[string]any{"error": "Invalid request body"})
+				return
+			}
+			ttl, _ := ParseHistoryRange(req.TTL)
+			drop, err = ds.AddText(req.Text, ttl, device)
+		}
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		WriteJSON(w, map[string]any{"success": true, "drop": drop})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleDropGet serves GET /api/drop/{id}: the text or file of a drop, as a download
+// unless ?inline=1 is set.
+func (h *Handler) HandleDropGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ds := GetDropStore()
+	if !ds.Enabled() {
+		http.NotFound(w, r)
+		return
+	}
+	drop, path, err := ds.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	w.Header().Set("Cache-Control", "no-store")
+	if drop.Kind == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(drop.Text))
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		http.Error(w, "Drop content is missing", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+	disposition := "attachment"
+	if r.URL.Query().Get("inline") == "1" {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", drop.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": drop.Name}))
+	http.ServeContent(w, r, "", drop.Created, file)
+}
+
+// HandleDropDelete serves POST /api/drop/delete: {"id"}.
+func (h *Handler) HandleDropDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	if err := GetDropStore().Delete(req.ID); err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"success": true})
+}
+
+// HandleNotes serves GET /api/notes: every note with its Markdown rendered to sanitized
+// HTML, or the note of ?id=.
+func (h *Handler) HandleNotes(w http.ResponseWriter, r *http.Request) {
+	if id := r.URL.Query().Get("id"); id != "" {
+		note, err := GetNoteStore().Get(id)
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		WriteJSON(w, map[string]any{"note": note})
+		return
+	}
+	notes := GetNoteStore().All()
+	WriteJSON(w, map[string]any{"notes": notes, "count": len(notes)})
+}
+
+// HandleNoteAdd serves POST /api/notes/add: {"name", "content"} with Markdown content.
+func (h *Handler) HandleNoteAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var n Note
+	if err := json.NewDecoder(io.LimitReader(r.Body, 2*maxNoteContent+4096)).Decode(&n); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	n, err := GetNoteStore().Add(n)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"success": true, "note": n})
+}
+
+// HandleNoteUpdate serves POST /api/notes/update: {"id", "name", "content", "modified"}.
+// With "modified", the last-modified time the client loaded, a note saved since then
+// elsewhere is not overwritten.
+func (h *Handler) HandleNoteUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var n Note
+	if err := json.NewDecoder(io.LimitReader(r.Body, 2*maxNoteContent+4096)).Decode(&n); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	n, err := GetNoteStore().Update(n)
+	if err != nil {
+		resp := map[string]any{"error": err.Error()}
+		if errors.Is(err, ErrNoteChanged) {
+			resp["conflict"] = true
+		}
+		WriteJSON(w, resp)
+		return
+	}
+	WriteJSON(w, map[string]any{"success": true, "note": n})
+}
+
+// HandleNoteDelete serves POST /api/notes/delete: {"id"}.
+func (h *Handler) HandleNoteDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	if err := GetNoteStore().Delete(req.ID); err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"success": true})
+}
+
+// HandleSearchResolve returns the URL a query searches, following bangs such as "!gh"
+// (?q=) and otherwise using the engine named by ?engine=.
+func (h *Handler) HandleSearchResolve(w http.ResponseWriter, r *http.Request) {
+	res, err := ResolveSearch(r.URL.Query().Get("q"), r.URL.Query().Get("engine"))
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, res)
+}
+
+// HandleToolsTime converts timestamps (?value=, a Unix timestamp or a date, default now)
+// between formats and the time zones in ?zones=, or explains a cron expression (?cron=)
+// with its next ?count= runs. ?tz= is the zone dates and cron schedules are read in,
+// default the server's.
+func (h *Handler) HandleToolsTime(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	loc := time.Local
+	if tz := q.Get("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			WriteJSON(w, map[string]any{"error": fmt.Sprintf("unknown time zone %q", tz)})
+			return
+		}
+	}
+
+	if expr := q.Get("cron"); expr != "" {
+		count := 5
+		if c, err := strconv.Atoi(q.Get("count")); err == nil && c > 0 && c <= 20 {
+			count = c
+		}
+		result, err := ExplainCron(expr, loc, count, time.Now())
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		WriteJSON(w, map[string]any{"cron": result})
+		return
+	}
+
+	var zones []string
+	for _, z := range strings.Split(q.Get("zones"), ",") {
+		if z = strings.TrimSpace(z); z != "" && len(zones) < 20 {
+			zones = append(zones, z)
+		}
+	}
+	result, err := ConvertTimestamp(q.Get("value"), loc, zones, time.Now())
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"time": result})
+}
+
+// HandleToolsFormat serves POST /api/tools/format: {"text", "from", "to", "indent",
+// "minify"}. The document is validated and returned pretty-printed or converted between
+// JSON and YAML; nothing is stored.
+func (h *Handler) HandleToolsFormat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Text string `json:"text"`
+		FormatOptions
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 2*maxFormatInput)).Decode(&req); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	result, err := FormatDocument(req.Text, req.FormatOptions)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error(), "valid": false})
+		return
+	}
+	WriteJSON(w, map[string]any{"valid": true, "result": result})
+}
+
+// HandleToolsHTTPRequest serves POST /api/tools/httpreq: {"method", "url", "headers",
+// "body", "timeout", "followRedirects", "insecure"}. The request is sent from the server
+// under the outbound policy.
+func (h *Handler) HandleToolsHTTPRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var spec HTTPRequestSpec
+	if err := json.NewDecoder(io.LimitReader(r.Body, 2*maxHTTPRequestBody)).Decode(&spec); err != nil {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	if err := spec.Validate(); err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	// Requests that may change something on the target are audited
+	if spec.Method != http.MethodGet && spec.Method != http.MethodHead && spec.Method != http.MethodOptions {
+		Audit(r, "tools.httpreq", spec.Method+" "+spec.URL)
+	}
+	result, err := SendHTTPRequest(OutboundContext(r), spec)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	WriteJSON(w, map[string]any{"response": result})
+}
+
+// HandleSearchHistory serves the server-side search history. GET returns the searches
+// newest first, with ?filter= to match terms, ?device= for one device and ?limit= (default
+// 100, max 1000), and the number of searches per device. POST records a search:
+// {"term", "engine"}, attributed to the device of the request.
+func (h *Handler) HandleSearchHistory(w http.ResponseWriter, r *http.Request) {
+	store := GetSearchHistoryStore()
+	switch r.Method {
+	case http.MethodGet:
+		if !store.Enabled() {
+			WriteJSON(w, map[string]any{"enabled": false, "history": []SearchHistoryEntry{}})
+			return
+		}
+		q := r.URL.Query()
+		limit := 100
+		if l, err := strconv.Atoi(q.Get("limit")); err == nil && l > 0 && l <= 1000 {
+			limit = l
+		}
+		WriteJSON(w, map[string]any{
+			"enabled": true,
+			"history": store.Entries(q.Get("filter"), q.Get("device"), limit),
+			"devices": store.Devices(),
+			"total":   store.Len(),
+		})
+	case http.MethodPost:
+		var req struct {
+			Term   string `json:"term"`
+			Engine string `json:"engine"`
+		}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil {
+			WriteJSON(w, map[string]any{"error": "Invalid request body"})
+			return
+		}
+		if !store.Enabled() {
+			WriteJSON(w, map[string]any{"enabled": false})
+			return
+		}
+		entry, err := store.Add(req.Term, req.Engine, DescribeUserAgent(r.UserAgent()))
+		if err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		WriteJSON(w, map[string]any{"enabled": true, "entry": entry})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleSearchHistoryPurge serves POST /api/search/history/purge: {"term", "engine",
+// "device", "before"} select the searches to remove; an empty body removes them all.
+func (h *Handler) HandleSearchHistoryPurge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var p SearchHistoryPurge
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&p); err != nil && err != io.EOF {
+		WriteJSON(w, map[string]any{"error": "Invalid request body"})
+		return
+	}
+	removed, err := GetSearchHistoryStore().Purge(p)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	GetDebugLogger().Logf("search", "purged %d searches from the server-side history", removed)
+	WriteJSON(w, map[string]any{"removed": removed})
+}
+
+// HandleClimate serves GET /api/climate: temperature and humidity of each room from the
+// last poll, with today's extremes and where they fall within the comfort band.
+func (h *Handler) HandleClimate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	cm := GetClimateMonitor()
+	if !cm.Enabled() {
+		WriteJSON(w, map[string]any{"enabled": false})
+		return
+	}
+	rooms, updated := cm.Status()
+	if rooms == nil {
+		rooms = []ClimateRoomStatus{}
+	}
+	WriteJSON(w, map[string]any{"enabled": true, "rooms": rooms, "updated": updated})
+}
+
+// HandleAstro serves GET /api/astro?lat=&lon=: tonight's dark hours, the naked-eye planets
+// and the coming ISS passes at the location, by default the weather location.
+func (h *Handler) HandleAstro(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	latText, lonText := r.URL.Query().Get("lat"), r.URL.Query().Get("lon")
+	if latText == "" || lonText == "" {
+		latText, lonText, _ = SavedWeatherLocation(h.Config().Weather)
+	}
+	if latText == "" || lonText == "" {
+		WriteJSON(w, map[string]any{"location": false})
+		return
+	}
+	lat, errLat := strconv.ParseFloat(latText, 64)
+	lon, errLon := strconv.ParseFloat(lonText, 64)
+	if errLat != nil || errLon != nil || math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		WriteJSON(w, map[string]any{"error": "Invalid coordinates"})
+		return
+	}
+	WriteJSON(w, GetAstroService().Report(r.Context(), lat, lon))
+}
+
+// HandleGarden serves GET /api/garden?lat=&lon=: whether to water the garden, from the
+// rain of the past days and the forecast, by default at the saved weather location.
+func (h *Handler) HandleGarden(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	latText, lonText := r.URL.Query().Get("lat"), r.URL.Query().Get("lon")
+	if latText == "" || lonText == "" {
+		latText, lonText, _ = SavedWeatherLocation(h.Config().Weather)
+	}
+	if latText == "" || lonText == "" {
+		WriteJSON(w, map[string]any{"location": false})
+		return
+	}
+	lat, errLat := strconv.ParseFloat(latText, 64)
+	lon, errLon := strconv.ParseFloat(lonText, 64)
+	if errLat != nil || errLon != nil || math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		WriteJSON(w, map[string]any{"error": "Invalid coordinates"})
+		return
+	}
+	report, err := GetGardenService().Report(r.Context(), lat, lon)
+	if err != nil {
+		WriteJSON(w, map[string]any{"error": err.Error()})
+		return
+	}
+	if ClientLocaleFromRequest(r).Imperial() {
+		report = report.Imperial()
+	}
+	WriteJSON(w, report)
+}
+
+// HandleIcons serves GET /api/icons: the slugs of the dashboard icons in the cache directory.
+func (h *Handler) HandleIcons(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	icons := GetIconPack().Cached()
+	slices.Sort(icons)
+	WriteJSON(w, map[string]any{"icons": icons})
+}
+
+// HandleIcon serves GET /api/icons/{slug} as an image from the dashboard-icons set, e.g.
+// /api/icons/proxmox or /api/icons/proxmox.png. ?format= picks svg, png or webp.
+func (h *Handler) HandleIcon(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	slug, format := r.PathValue("slug"), r.URL.Query().Get("format")
+	for _, f := range iconFormats {
+		if trimmed, ok := strings.CutSuffix(slug, "."+f.ext); ok && format == "" {
+			slug, format = trimmed, f.ext
+			break
+		}
+	}
+	if format != "" && format != "svg" && format != "png" && format != "webp" {
+		http.Error(w, "format must be svg, png or webp", http.StatusBadRequest)
+		return
+	}
+	if !IsValidIconSlug(slug) {
+		http.Error(w, "Invalid icon slug", http.StatusBadRequest)
+		return
+	}
+
+	// The pack's URL comes from the config, so a mirror on the LAN may be reached
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	icon, err := GetIconPack().Get(ctx, slug, format)
+	if err != nil {
+		Logger("icons").Debug("icon not found", "slug", slug, "error", err)
+		w.Header().Set("Cache-Control", "private, max-age=3600")
+		http.Error(w, "Icon not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", icon.ContentType)
+	w.Header().Set("ETag", icon.ETag)
+	w.Header().Set("Cache-Control", "private, max-age=604800")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	http.ServeContent(w, r, "", icon.Modified, bytes.NewReader(icon.Data))
+}
+
+// HandleChangelog serves GET /api/changelog: the dashboard's release notes, with the
+// entries newer than the running version marked new and, given ?since=<version>, the
+// ones added since the client last looked marked unseen. refresh=1 fetches them again.
+func (h *Handler) HandleChangelog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	since := r.URL.Query().Get("since")
+	WriteJSON(w, GetChangelogService().Changelog(r.Context(), since, r.URL.Query().Get("refresh") == "1"))
+}
+
+// HandleUsageStats serves /api/stats/usage, the local usage statistics: GET reports them,
+// POST adds the renders and clicks a browser counted and DELETE purges everything.
+func (h *Handler) HandleUsageStats(w http.ResponseWriter, r *http.Request) {
+	usage := GetUsageStats()
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, usage.Report())
+	case http.MethodPost:
+		var events UsageEvents
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&events); err != nil {
+			WriteJSON(w, map[string]any{"error": "Invalid JSON: " + err.Error()})
+			return
+		}
+		if err := usage.RecordEvents(events); err != nil {
+			WriteJSON(w, map[string]any{"error": err.Error()})
+			return
+		}
+		WriteJSON(w, map[string]any{"success": true})
+	case http.MethodDelete:
+		RequireCapability("stats.manage", func(w http.ResponseWriter, r *http.Request) {
+			usage.Purge()
+			Audit(r, "usage.purge", "")
+			WriteJSON(w, map[string]any{"success": true})
+		})(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleConnectivity serves GET /api/connectivity: whether the internet is reachable, from
+// the last check (?check=1 checks now).
+func (h *Handler) HandleConnectivity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Query().Get("check") == "1" {
+		WriteJSON(w, GetConnectivity().Check(r.Context()))
+		return
 	}
+	WriteJSON(w, GetConnectivity().Status())
 }
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
@@ -96,15 +97,24 @@ func IsLocalRequest(r *http.Request) bool {
 	return false
 }
 
-// GetClientIP extracts the client IP from the request.
+// GetClientIP extracts the client IP from the request. Proxy headers are only
+// honoured when the request comes from a trusted proxy (see SetTrustedProxies).
 func GetClientIP(r *http.Request) string {
-	// Check for X-Forwarded-For header (proxy/load balancer)
+	remote := remoteIP(r)
+	if !isTrustedProxy(remote) {
+		return remote
+	}
+
+	// Check for X-Forwarded-For header (proxy/load balancer). Walk it from the
+	// right, skipping our own proxies, so a client cannot spoof its address.
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP in the list
 		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			ip := strings.TrimSpace(ips[0])
-			if ip != "" {
+		for i := len(ips) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(ips[i])
+			if ip == "" {
+				continue
+			}
+			if i == 0 || !isTrustedProxy(ip) {
 				return ip
 			}
 		}
@@ -115,13 +125,7 @@ func GetClientIP(r *http.Request) string {
 		return strings.TrimSpace(xri)
 	}
 
-	// Fall back to RemoteAddr
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		// RemoteAddr might not have a port
-		return r.RemoteAddr
-	}
-	return host
+	return remote
 }
 
 // ReverseDNS performs a reverse DNS lookup for the given IP address.
@@ -266,6 +270,26 @@ func Dedup(in []string) []string {
 	return out
 }
 
+// ResolveSecret returns a secret read from a file or environment variable if one is named,
+// otherwise the inline value.
+func ResolveSecret(value, file, env string) (string, error) {
+	switch {
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case env != "":
+		v, ok := os.LookupEnv(env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", env)
+		}
+		return v, nil
+	}
+	return value, nil
+}
+
 // WithSecurityHeaders wraps an HTTP handler with security headers.
 func WithSecurityHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -273,7 +297,14 @@ func WithSecurityHeaders(next http.Handler) http.Handler {
 		w.Header().Set("X-Frame-Options", "DENY")
 		w.Header().Set("Referrer-Policy", "no-referrer")
 		w.Header().Set("Permissions-Policy", "geolocation=(), microphone=(), camera=()")
-		w.Header().Set("Content-Security-Policy", "default-src 'self'; style-src 'self' 'unsafe-inline' https://cdnjs.cloudflare.com; script-src 'self' 'unsafe-inline'; connect-src 'self' https: ws: wss:; img-src 'self' data:; font-src 'self' https://cdnjs.cloudflare.com data:;")
+		// Frames and images of the Embed card's widgets are allowed from their origins
+		frames, images := GetEmbedService().Origins()
+		frameSrc := ""
+		if len(frames) > 0 {
+			frameSrc = " frame-src " + strings.Join(frames, " ") + ";"
+		}
+		imgSrc := strings.Join(append([]string{"'self'", "data:"}, images...), " ")
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; style-src 'self' 'unsafe-inline' https://cdnjs.cloudflare.com; script-src 'self' 'unsafe-inline'; connect-src 'self' https: ws: wss:; img-src "+imgSrc+"; font-src 'self' https://cdnjs.cloudflare.com data:;"+frameSrc)
 		next.ServeHTTP(w, r)
 	})
 }
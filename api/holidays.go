@@ -0,0 +1,180 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the public holidays.
+const (
+	defaultHolidaysURL = "https://date.nager.at/api/v3"
+	// Holidays of a year rarely change once published
+	holidaysTTL = 7 * 24 * time.Hour
+	// A failed fetch is not repeated sooner
+	holidaysRetry = time.Hour
+)
+
+var (
+	holidayCountryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+	holidayRegionPattern  = regexp.MustCompile(`^[A-Z]{2}-[A-Z0-9]{1,3}$`)
+)
+
+// HolidaysConfig sets the country whose public holidays are marked in the calendar.
+type HolidaysConfig struct {
+	Country string `json:"country"`          // ISO 3166-1 code, e.g. "DE"
+	Region  string `json:"region,omitempty"` // ISO 3166-2 code, e.g. "DE-BY", for regional holidays
+	URL     string `json:"url,omitempty"`    // Nager.Date-compatible API, default "https://date.nager.at/api/v3"
+}
+
+// Validate checks the country, region and API URL.
+func (c HolidaysConfig) Validate() error {
+	if !holidayCountryPattern.MatchString(c.Country) {
+		return fmt.Errorf("holidays: country must be an uppercase ISO 3166-1 code such as \"DE\"")
+	}
+	if c.Region != "" && (!holidayRegionPattern.MatchString(c.Region) || !strings.HasPrefix(c.Region, c.Country+"-")) {
+		return fmt.Errorf("holidays: region must be an ISO 3166-2 code of %s such as \"%s-BY\"", c.Country, c.Country)
+	}
+	if c.URL != "" {
+		if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("holidays: url must be an http(s) URL")
+		}
+	}
+	return nil
+}
+
+// Holiday is a public holiday as published by Nager.Date.
+type Holiday struct {
+	Date      string   `json:"date"` // YYYY-MM-DD
+	LocalName string   `json:"localName"`
+	Name      string   `json:"name"`     // English name
+	Global    bool     `json:"global"`   // Observed in the whole country
+	Counties  []string `json:"counties"` // Regions observing it when not global
+}
+
+// holidaysCacheEntry is the holidays of one country and year.
+type holidaysCacheEntry struct {
+	holidays []Holiday
+	fetched  time.Time
+	tried    time.Time
+}
+
+// HolidayService fetches the public holidays of the configured country a year at a time.
+type HolidayService struct {
+	mu     sync.Mutex
+	config HolidaysConfig
+	cache  map[string]*holidaysCacheEntry
+}
+
+// Global holiday service instance
+var holidayService = &HolidayService{cache: make(map[string]*holidaysCacheEntry)}
+
+// GetHolidayService returns the global holiday service instance.
+func GetHolidayService() *HolidayService {
+	return holidayService
+}
+
+// Configure sets the country, region and API.
+func (hs *HolidayService) Configure(cfg HolidaysConfig) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if cfg.URL != hs.config.URL {
+		clear(hs.cache)
+	}
+	hs.config = cfg
+}
+
+// Events returns the holidays between two dates as calendar events of type "holiday", none
+// without a configured country. The country's holidays are kept for the whole year; those of
+// other regions are left out.
+func (hs *HolidayService) Events(ctx context.Context, from, to time.Time) []CalendarEvent {
+	hs.mu.Lock()
+	cfg := hs.config
+	hs.mu.Unlock()
+	if cfg.Country == "" {
+		return nil
+	}
+	first, last := from.Format(time.DateOnly), to.Format(time.DateOnly)
+	var events []CalendarEvent
+	for year := from.Year(); year <= to.Year(); year++ {
+		for _, h := range hs.year(ctx, cfg, year) {
+			if h.Date < first || h.Date > last || (!h.Global && !slices.Contains(h.Counties, cfg.Region)) {
+				continue
+			}
+			events = append(events, CalendarEvent{
+				ID:    "holiday_" + cfg.Country + "_" + h.Date,
+				Title: cmp.Or(h.LocalName, h.Name),
+				Date:  h.Date,
+				Type:  EventTypeHoliday,
+			})
+		}
+	}
+	return events
+}
+
+// year returns the holidays of a year, fetching them when a week old. When a fetch fails
+// the last holidays are kept.
+func (hs *HolidayService) year(ctx context.Context, cfg HolidaysConfig, year int) []Holiday {
+	key := cfg.Country + "|" + strconv.Itoa(year)
+	hs.mu.Lock()
+	e, ok := hs.cache[key]
+	if !ok {
+		e = &holidaysCacheEntry{}
+		hs.cache[key] = e
+	}
+	if time.Since(e.fetched) < holidaysTTL || time.Since(e.tried) < holidaysRetry {
+		RecordTiming(ctx, "holidays", 0, TimingHit)
+		defer hs.mu.Unlock()
+		return e.holidays
+	}
+	e.tried = time.Now()
+	hs.mu.Unlock()
+
+	done := StartTiming(ctx, "holidays")
+	holidays, err := fetchHolidays(ctx, cmp.Or(cfg.URL, defaultHolidaysURL), cfg.Country, year)
+	done(TimingMiss)
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if err != nil {
+		GetDebugLogger().Logf("calendar", "fetching %s holidays of %d failed: %v", cfg.Country, year, err)
+	} else {
+		e.holidays, e.fetched = holidays, time.Now()
+	}
+	return e.holidays
+}
+
+// fetchHolidays reads the public holidays of a country and year from a Nager.Date-compatible
+// API.
+func fetchHolidays(ctx context.Context, apiURL, country string, year int) ([]Holiday, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	u := fmt.Sprintf("%s/PublicHolidays/%d/%s", strings.TrimSuffix(apiURL, "/"), year, url.PathEscape(country))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch holidays: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("holidays: HTTP %s", resp.Status)
+	}
+	var holidays []Holiday
+	if err := json.NewDecoder(resp.Body).Decode(&holidays); err != nil {
+		return nil, fmt.Errorf("invalid holidays: %w", err)
+	}
+	return holidays, nil
+}
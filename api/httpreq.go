@@ -0,0 +1,238 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits of the HTTP request tester.
+const (
+	maxHTTPRequestBody    = 1 << 20
+	maxHTTPResponseBody   = 1 << 20
+	maxHTTPRequestHeaders = 50
+	maxHTTPRequestTimeout = 60
+	httpRequestRedirects  = 10
+)
+
+// HTTPRequestMethods are the methods the HTTP request tester sends.
+var HTTPRequestMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// HTTPHeader is a header of a composed request; a list keeps the order and repeated names.
+type HTTPHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HTTPRequestSpec is a request composed in the HTTP request tester.
+type HTTPRequestSpec struct {
+	Method          string       `json:"method"` // Default GET
+	URL             string       `json:"url"`
+	Headers         []HTTPHeader `json:"headers"`
+	Body            string       `json:"body"`
+	Timeout         int          `json:"timeout"`         // Seconds, default 15
+	FollowRedirects bool         `json:"followRedirects"` // Otherwise a redirect is the response
+	Insecure        bool         `json:"insecure"`        // Skip TLS certificate verification
+}
+
+// HTTPTiming breaks down the time a request took, in milliseconds. Phases that did not
+// happen, such as DNS for an IP address or TLS for plain HTTP, are zero. With redirects
+// followed, the phases are those of the last request.
+type HTTPTiming struct {
+	DNS       float64 `json:"dns"`
+	Connect   float64 `json:"connect"`
+	TLS       float64 `json:"tls"`
+	FirstByte float64 `json:"firstByte"` // From sending the request to the first response byte
+	Download  float64 `json:"download"`
+	Total     float64 `json:"total"`
+}
+
+// HTTPResponseTLS describes the TLS connection of a response.
+type HTTPResponseTLS struct {
+	Version string    `json:"version"`
+	Subject string    `json:"subject,omitempty"`
+	Issuer  string    `json:"issuer,omitempty"`
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+// HTTPRequestResult is the response to a request of the HTTP request tester.
+type HTTPRequestResult struct {
+	Status        string              `json:"status"`
+	StatusCode    int                 `json:"statusCode"`
+	Proto         string              `json:"proto"`
+	URL           string              `json:"url"` // After redirects
+	RemoteAddr    string              `json:"remoteAddr,omitempty"`
+	Headers       map[string][]string `json:"headers"`
+	Body          string              `json:"body,omitempty"`
+	BodyBase64    string              `json:"bodyBase64,omitempty"` // Bodies that are not UTF-8 text
+	BodySize      int64               `json:"bodySize"`
+	BodyTruncated bool                `json:"bodyTruncated,omitempty"`
+	TLS           *HTTPResponseTLS    `json:"tls,omitempty"`
+	Timing        HTTPTiming          `json:"timing"`
+}
+
+// Validate checks a composed request and fills in its defaults.
+func (s *HTTPRequestSpec) Validate() error {
+	s.Method = strings.ToUpper(strings.TrimSpace(s.Method))
+	if s.Method == "" {
+		s.Method = http.MethodGet
+	}
+	if !slices.Contains(HTTPRequestMethods, s.Method) {
+		return fmt.Errorf("method must be one of %s", strings.Join(HTTPRequestMethods, ", "))
+	}
+	s.URL = strings.TrimSpace(s.URL)
+	if s.URL == "" {
+		return errors.New("a URL is required")
+	}
+	if !strings.Contains(s.URL, "://") {
+		s.URL = "http://" + s.URL
+	}
+	if len(s.Headers) > maxHTTPRequestHeaders {
+		return fmt.Errorf("at most %d headers are allowed", maxHTTPRequestHeaders)
+	}
+	if len(s.Body) > maxHTTPRequestBody {
+		return errors.New("the body is too long")
+	}
+	if s.Timeout <= 0 {
+		s.Timeout = 15
+	}
+	s.Timeout = min(s.Timeout, maxHTTPRequestTimeout)
+	return nil
+}
+
+// SendHTTPRequest sends a composed request under the outbound policy and returns the
+// response with a breakdown of where the time went. ctx decides whether private addresses
+// may be reached (see OutboundContext).
+func SendHTTPRequest(ctx context.Context, spec HTTPRequestSpec) (*HTTPRequestResult, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if err := CheckOutboundURL(ctx, spec.URL); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(spec.Timeout)*time.Second)
+	defer cancel()
+	var body io.Reader
+	if spec.Body != "" {
+		body = strings.NewReader(spec.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, spec.Method, spec.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	for _, h := range spec.Headers {
+		name := strings.TrimSpace(h.Name)
+		if name == "" {
+			continue
+		}
+		if strings.EqualFold(name, "Host") {
+			req.Host = h.Value
+			continue
+		}
+		if strings.EqualFold(name, "User-Agent") {
+			req.Header.Del(name)
+		}
+		req.Header.Add(name, h.Value)
+	}
+
+	// Transports are not shared, so every request connects anew and every phase is timed
+	transport := NewOutboundTransport(&tls.Config{InsecureSkipVerify: spec.Insecure})
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Transport: transport, CheckRedirect: OutboundCheckRedirect(httpRequestRedirects)}
+	if !spec.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	}
+
+	var dnsStart, dnsDone, connectStart, connectDone, tlsStart, tlsDone, wrote, firstByte time.Time
+	var remoteAddr string
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { dnsDone = time.Now() },
+		ConnectStart:         func(string, string) { connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { connectDone = time.Now() },
+		TLSHandshakeStart:    func() { tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { tlsDone = time.Now() },
+		GotConn:              func(info httptrace.GotConnInfo) { remoteAddr = info.Conn.RemoteAddr().String() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { wrote = time.Now() },
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+	req = req.WithContext(httptrace.WithClientTrace(ctx, trace))
+
+	start := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("no response within %d seconds", spec.Timeout)
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxHTTPResponseBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading the response: %w", err)
+	}
+	done := time.Now()
+
+	result := &HTTPRequestResult{
+		Status:     res.Status,
+		StatusCode: res.StatusCode,
+		Proto:      res.Proto,
+		URL:        res.Request.URL.String(),
+		RemoteAddr: remoteAddr,
+		Headers:    res.Header,
+		BodySize:   int64(len(data)),
+	}
+	if len(data) > maxHTTPResponseBody {
+		data = data[:maxHTTPResponseBody]
+		result.BodySize = int64(len(data))
+		result.BodyTruncated = true
+		// The rest of the body is not read, but its length may be known
+		if res.ContentLength > 0 {
+			result.BodySize = res.ContentLength
+		}
+	}
+	if utf8.Valid(data) {
+		result.Body = string(data)
+	} else {
+		result.BodyBase64 = base64.StdEncoding.EncodeToString(data)
+	}
+	if state := res.TLS; state != nil {
+		result.TLS = &HTTPResponseTLS{Version: tls.VersionName(state.Version)}
+		if len(state.PeerCertificates) > 0 {
+			cert := state.PeerCertificates[0]
+			result.TLS.Subject = cert.Subject.String()
+			result.TLS.Issuer = cert.Issuer.String()
+			result.TLS.Expires = cert.NotAfter
+		}
+	}
+
+	result.Timing = HTTPTiming{
+		DNS:       elapsedMillis(dnsStart, dnsDone),
+		Connect:   elapsedMillis(connectStart, connectDone),
+		TLS:       elapsedMillis(tlsStart, tlsDone),
+		FirstByte: elapsedMillis(wrote, firstByte),
+		Download:  elapsedMillis(firstByte, done),
+		Total:     elapsedMillis(start, done),
+	}
+	return result, nil
+}
+
+// elapsedMillis returns the milliseconds from start to end, or zero if either is unset.
+func elapsedMillis(start, end time.Time) float64 {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return math.Round(float64(end.Sub(start).Microseconds())/10) / 100
+}
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the dashboard icon pack.
+const (
+	defaultIconPackURL      = "https://cdn.jsdelivr.net/gh/walkxcode/dashboard-icons@main"
+	defaultIconPackCacheDir = "icon-cache"
+	// Slugs the pack does not have are asked for again after an hour
+	iconPackMissingTTL = time.Hour
+	maxIconSize        = 512 * 1024
+)
+
+// ErrIconNotFound is returned for icon slugs the pack does not have.
+var ErrIconNotFound = errors.New("icon not found")
+
+// iconSlugPattern matches the file names of the dashboard-icons set, e.g. "home-assistant".
+var iconSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// iconFormats are the formats of the pack with their content types, in the order tried.
+var iconFormats = []struct{ ext, contentType string }{
+	{"svg", "image/svg+xml"},
+	{"png", "image/png"},
+	{"webp", "image/webp"},
+}
+
+// IconPackConfig configures the dashboard icons served from /api/icons/{slug}.
+type IconPackConfig struct {
+	URL      string `json:"url,omitempty"`      // Base URL of the pack with svg/, png/ and webp/ directories, default the walkxcode/dashboard-icons repository on jsDelivr
+	CacheDir string `json:"cacheDir,omitempty"` // Default "icon-cache"; icons placed here are served without downloading
+	Offline  bool   `json:"offline,omitempty"`  // Only serve icons already in the cache directory
+}
+
+// Validate checks the base URL.
+func (c IconPackConfig) Validate() error {
+	if c.URL != "" && !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+		return fmt.Errorf("icons: url must start with http:// or https://")
+	}
+	return nil
+}
+
+// IsValidIconSlug reports whether s can name an icon of the pack.
+func IsValidIconSlug(s string) bool {
+	return iconSlugPattern.MatchString(s)
+}
+
+// Icon is an icon of the pack.
+type Icon struct {
+	Slug        string
+	ContentType string
+	ETag        string
+	Modified    time.Time
+	Data        []byte
+}
+
+// IconPack serves icons of the dashboard-icons set by slug, downloading each once and
+// keeping it on disk. Icons do not change once published, so cached ones never expire.
+type IconPack struct {
+	mu       sync.Mutex
+	baseURL  string
+	dir      string
+	offline  bool
+	missing  map[string]time.Time // Slug and format of icons the pack does not have
+	fetching map[string]chan struct{}
+}
+
+// Global icon pack instance
+var iconPack = &IconPack{
+	baseURL:  defaultIconPackURL,
+	dir:      defaultIconPackCacheDir,
+	missing:  make(map[string]time.Time),
+	fetching: make(map[string]chan struct{}),
+}
+
+// GetIconPack returns the global icon pack instance.
+func GetIconPack() *IconPack {
+	return iconPack
+}
+
+// Configure sets the pack's base URL and cache directory.
+func (ip *IconPack) Configure(cfg IconPackConfig) {
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+	if cfg.URL != "" {
+		ip.baseURL = strings.TrimRight(cfg.URL, "/")
+	}
+	if cfg.CacheDir != "" {
+		ip.dir = cfg.CacheDir
+	}
+	ip.offline = cfg.Offline
+	ip.missing = make(map[string]time.Time)
+}
+
+// Get returns the icon of a slug in the requested format (svg, png or webp), or in the
+// first format the pack has when format is empty.
+func (ip *IconPack) Get(ctx context.Context, slug, format string) (*Icon, error) {
+	if !IsValidIconSlug(slug) {
+		return nil, ErrIconNotFound
+	}
+	var lastErr error = ErrIconNotFound
+	for _, f := range iconFormats {
+		if format != "" && f.ext != format {
+			continue
+		}
+		icon, err := ip.get(ctx, slug, f.ext, f.contentType)
+		if err == nil {
+			return icon, nil
+		}
+		if !errors.Is(err, ErrIconNotFound) {
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
+
+// get returns an icon in one format from the cache directory, downloading it when it is
+// not there yet.
+func (ip *IconPack) get(ctx context.Context, slug, ext, contentType string) (*Icon, error) {
+	name := slug + "." + ext
+	if icon := ip.load(name, slug, contentType); icon != nil {
+		return icon, nil
+	}
+
+	ip.mu.Lock()
+	if ip.offline {
+		ip.mu.Unlock()
+		return nil, ErrIconNotFound
+	}
+	if at, ok := ip.missing[name]; ok && time.Since(at) < iconPackMissingTTL {
+		ip.mu.Unlock()
+		return nil, ErrIconNotFound
+	}
+	// Wait for a download of the same icon in progress, then read what it stored
+	if done, ok := ip.fetching[name]; ok {
+		ip.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if icon := ip.load(name, slug, contentType); icon != nil {
+			return icon, nil
+		}
+		return nil, ErrIconNotFound
+	}
+	done := make(chan struct{})
+	ip.fetching[name] = done
+	source := ip.baseURL + "/" + ext + "/" + name
+	ip.mu.Unlock()
+
+	defer func() {
+		ip.mu.Lock()
+		delete(ip.fetching, name)
+		ip.mu.Unlock()
+		close(done)
+	}()
+
+	data, err := downloadIcon(ctx, source)
+	if errors.Is(err, ErrIconNotFound) {
+		ip.mu.Lock()
+		ip.missing[name] = time.Now()
+		ip.mu.Unlock()
+		return nil, err
+	}
+	if err != nil {
+		Logger("icons").Debug("downloading icon failed", "icon", name, "error", err)
+		return nil, err
+	}
+	if err := ip.save(name, data); err != nil {
+		Logger("icons").Warn("caching icon failed", "icon", name, "error", err)
+	}
+	return newIcon(slug, contentType, data, time.Now()), nil
+}
+
+// downloadIcon fetches an icon file of the pack under the outbound policy.
+func downloadIcon(ctx context.Context, source string) ([]byte, error) {
+	if err := CheckOutboundURL(ctx, source); err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Timeout:       10 * time.Second,
+		Transport:     NewOutboundTransport(nil),
+		CheckRedirect: OutboundCheckRedirect(3),
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrIconNotFound
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("icon http status %s", res.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxIconSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxIconSize {
+		return nil, fmt.Errorf("icon larger than %d bytes", maxIconSize)
+	}
+	return data, nil
+}
+
+// load reads an icon from the cache directory, or nil if it is not there.
+func (ip *IconPack) load(name, slug, contentType string) *Icon {
+	ip.mu.Lock()
+	path := filepath.Join(ip.dir, name)
+	ip.mu.Unlock()
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Size() > maxIconSize {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return newIcon(slug, contentType, data, info.ModTime())
+}
+
+// save writes an icon to the cache directory.
+func (ip *IconPack) save(name string, data []byte) error {
+	ip.mu.Lock()
+	dir := ip.dir
+	ip.mu.Unlock()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, name))
+}
+
+func newIcon(slug, contentType string, data []byte, modified time.Time) *Icon {
+	sum := sha256.Sum256(data)
+	return &Icon{
+		Slug:        slug,
+		ContentType: contentType,
+		ETag:        `"` + hex.EncodeToString(sum[:8]) + `"`,
+		Modified:    modified,
+		Data:        data,
+	}
+}
+
+// Cached returns the slugs of the icons in the cache directory.
+func (ip *IconPack) Cached() []string {
+	ip.mu.Lock()
+	dir := ip.dir
+	ip.mu.Unlock()
+	entries, _ := os.ReadDir(dir)
+	seen := make(map[string]bool)
+	slugs := []string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		ext := filepath.Ext(e.Name())
+		slug := strings.TrimSuffix(e.Name(), ext)
+		if ext == "" || !IsValidIconSlug(slug) || seen[slug] {
+			continue
+		}
+		for _, f := range iconFormats {
+			if "."+f.ext == ext {
+				seen[slug] = true
+				slugs = append(slugs, slug)
+				break
+			}
+		}
+	}
+	return slugs
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,6 +11,9 @@ import (
 	"time"
 )
 
+// icsCalendarsKey is the storage key of the ICS calendar list.
+const icsCalendarsKey = "icsCalendars"
+
 // ICSCalendar represents an ICS calendar source.
 type ICSCalendar struct {
 	ID          string `json:"id"`
@@ -74,6 +78,7 @@ func ParseICS(content string, calendarID, color string) ([]ICSEvent, error) {
 		
 		key := strings.ToUpper(parts[0])
 		value := parts[1]
+		tzid := icsTZID(parts[0])
 		
 		// Remove parameters from key (e.g., "DTSTART;VALUE=DATE" -> "DTSTART")
 		if semicolonIdx := strings.Index(key, ";"); semicolonIdx > 0 {
@@ -114,7 +119,7 @@ func ParseICS(content string, calendarID, color string) ([]ICSEvent, error) {
 			}
 		case "DTSTART":
 			if currentEvent != nil {
-				start, err := parseICSTime(value)
+				start, err := parseICSTime(value, tzid)
 				if err == nil {
 					currentEvent.Start = start
 					// Check if it's an all-day event (date only, no time)
@@ -125,7 +130,7 @@ func ParseICS(content string, calendarID, color string) ([]ICSEvent, error) {
 			}
 		case "DTEND", "DUE":
 			if currentEvent != nil {
-				end, err := parseICSTime(value)
+				end, err := parseICSTime(value, tzid)
 				if err == nil {
 					currentEvent.End = end
 				} else {
@@ -156,6 +161,7 @@ func processICSLine(line string, event *ICSEvent) {
 	
 	key := strings.ToUpper(parts[0])
 	value := parts[1]
+	tzid := icsTZID(parts[0])
 	
 	if semicolonIdx := strings.Index(key, ";"); semicolonIdx > 0 {
 		key = key[:semicolonIdx]
@@ -169,7 +175,7 @@ func processICSLine(line string, event *ICSEvent) {
 	case "LOCATION":
 		event.Location = unescapeICS(value)
 	case "DTSTART":
-		start, err := parseICSTime(value)
+		start, err := parseICSTime(value, tzid)
 		if err == nil {
 			event.Start = start
 			if len(value) == 8 {
@@ -177,15 +183,36 @@ func processICSLine(line string, event *ICSEvent) {
 			}
 		}
 	case "DTEND", "DUE":
-		end, err := parseICSTime(value)
+		end, err := parseICSTime(value, tzid)
 		if err == nil {
 			event.End = end
 		}
 	}
 }
 
-// parseICSTime parses ICS time format (YYYYMMDDTHHMMSS or YYYYMMDD).
-func parseICSTime(value string) (time.Time, error) {
+// icsTZID returns the TZID parameter of a property name such as "DTSTART;TZID=Europe/Berlin".
+func icsTZID(name string) string {
+	for _, param := range strings.Split(name, ";")[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok && strings.EqualFold(k, "TZID") {
+			return strings.Trim(v, `"`)
+		}
+	}
+	return ""
+}
+
+// parseICSTime parses ICS time format (YYYYMMDDTHHMMSS or YYYYMMDD). Times are returned
+// in the server's zone: a "Z" suffix marks UTC and tzid names the zone of the value;
+// without either (or with an unknown zone) the time is read as the server's.
+func parseICSTime(value, tzid string) (time.Time, error) {
+	loc := time.Local
+	if strings.HasSuffix(value, "Z") {
+		loc = time.UTC
+	} else if tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+
 	// Remove timezone suffix if present (Z, +HHMM, -HHMM)
 	if idx := strings.IndexAny(value, "Z+-"); idx > 0 {
 		value = value[:idx]
@@ -204,7 +231,8 @@ func parseICSTime(value string) (time.Time, error) {
 			if format == "20060102" {
 				return t.UTC(), nil
 			}
-			return t, nil
+			t, _ = time.ParseInLocation(format, value, loc)
+			return t.In(time.Local), nil
 		}
 	}
 	
@@ -221,12 +249,21 @@ func unescapeICS(text string) string {
 }
 
 // FetchICSCalendar fetches and parses an ICS calendar from a URL.
-func FetchICSCalendar(url string) (string, error) {
+func FetchICSCalendar(ctx context.Context, url string) (string, error) {
+	if err := CheckOutboundURL(ctx, url); err != nil {
+		return "", err
+	}
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout:       30 * time.Second,
+		Transport:     NewOutboundTransport(nil),
+		CheckRedirect: OutboundCheckRedirect(5),
 	}
 	
-	resp, err := client.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch ICS: %w", err)
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch ICS: %w", err)
 	}
@@ -301,7 +338,7 @@ func MergeCalendarEvents(localEvents []CalendarEvent, icsEvents []CalendarEvent)
 // GetICSCalendars returns all ICS calendars from storage.
 func GetICSCalendars() ([]ICSCalendar, error) {
 	storage := GetStorage()
-	item, exists := storage.Get("icsCalendars")
+	item, exists := storage.Get(icsCalendarsKey)
 	if !exists {
 		GetDebugLogger().Logf("calendar", "GetICSCalendars: No calendars found in storage")
 		return []ICSCalendar{}, nil
@@ -330,19 +367,20 @@ func GetICSCalendars() ([]ICSCalendar, error) {
 func SaveICSCalendars(calendars []ICSCalendar) error {
 	storage := GetStorage()
 	// Get current version or use timestamp as version
-	item, exists := storage.Get("icsCalendars")
+	item, exists := storage.Get(icsCalendarsKey)
 	version := time.Now().Unix()
 	if exists {
 		version = item.Version + 1
 	}
-	storage.Set("icsCalendars", calendars, version)
+	storage.Set(icsCalendarsKey, calendars, version)
 	return nil
 }
 
-// ICSCache provides thread-safe caching for ICS calendar events.
+// ICSCache provides thread-safe caching for ICS calendar events and tasks.
 type ICSCache struct {
 	mu        sync.RWMutex
 	events    []CalendarEvent
+	todos     []Todo
 	lastFetch time.Time
 	hasData   bool
 }
@@ -374,7 +412,7 @@ func GetICSCacheTTL() time.Duration {
 
 // GetICSEvents fetches and parses events from all enabled ICS calendars.
 // Uses caching with configurable TTL. If forceRefresh is true, bypasses cache.
-func GetICSEvents(calendars []ICSCalendar, forceRefresh bool) ([]CalendarEvent, error) {
+func GetICSEvents(ctx context.Context, calendars []ICSCalendar, forceRefresh bool) ([]CalendarEvent, error) {
 	icsCache.mu.RLock()
 	timeSinceLastFetch := time.Since(icsCache.lastFetch)
 	hasCachedData := icsCache.hasData
@@ -386,12 +424,16 @@ func GetICSEvents(calendars []ICSCalendar, forceRefresh bool) ([]CalendarEvent,
 	// Return cached data if available and not expired (unless forced refresh)
 	if !forceRefresh && hasCachedData && timeSinceLastFetch < cacheTTL {
 		GetDebugLogger().Logf("calendar", "Returning cached ICS events (last fetch: %v ago, cache TTL: %v, events: %d)", timeSinceLastFetch, cacheTTL, len(cachedEvents))
+		RecordTiming(ctx, "ics", 0, TimingHit)
 		return cachedEvents, nil
 	}
+	done := StartTiming(ctx, "ics")
+	defer done(TimingMiss)
 
 	// Fetch fresh data
 	GetDebugLogger().Logf("calendar", "Fetching ICS events from %d enabled calendar(s)...", len(calendars))
 	var allICSEvents []ICSEvent
+	var allTodos []Todo
 	var fetchedCalendars []string
 	
 	for _, cal := range calendars {
@@ -402,7 +444,7 @@ func GetICSEvents(calendars []ICSCalendar, forceRefresh bool) ([]CalendarEvent,
 		GetDebugLogger().Logf("calendar", "Fetching ICS calendar: %s (%s)", cal.Name, cal.URL)
 		
 		// Fetch ICS content
-		content, err := FetchICSCalendar(cal.URL)
+		content, err := FetchICSCalendar(context.Background(), cal.URL)
 		if err != nil {
 			GetDebugLogger().Logf("calendar", "Failed to fetch ICS calendar %s (%s): %v", cal.Name, cal.URL, err)
 			continue
@@ -426,6 +468,12 @@ func GetICSEvents(calendars []ICSCalendar, forceRefresh bool) ([]CalendarEvent,
 		}
 		
 		allICSEvents = append(allICSEvents, events...)
+
+		// Tasks of the same calendar, e.g. from Nextcloud Tasks
+		if todos := ParseICSTodos(content, cal.ID); len(todos) > 0 {
+			GetDebugLogger().Logf("calendar", "Fetched %d tasks from ICS calendar: %s", len(todos), cal.Name)
+			allTodos = append(allTodos, ConvertICSTodos(todos, cal.Name)...)
+		}
 		fetchedCalendars = append(fetchedCalendars, cal.Name)
 	}
 	
@@ -437,6 +485,7 @@ func GetICSEvents(calendars []ICSCalendar, forceRefresh bool) ([]CalendarEvent,
 	// Update cache
 	icsCache.mu.Lock()
 	icsCache.events = calendarEvents
+	icsCache.todos = allTodos
 	icsCache.lastFetch = time.Now()
 	icsCache.hasData = true
 	icsCache.mu.Unlock()
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ICSTodo represents a task (VTODO) parsed from an ICS calendar, such as one of Nextcloud
+// Tasks.
+type ICSTodo struct {
+	UID        string    `json:"uid"`
+	Summary    string    `json:"summary"`
+	Due        time.Time `json:"due,omitzero"`
+	Priority   int       `json:"priority,omitempty"` // 1 (highest) to 9, 0 when unset
+	Completed  bool      `json:"completed"`
+	CalendarID string    `json:"calendarId"`
+}
+
+// unfoldICS returns the content lines of ICS content, with folded lines (continued on lines
+// starting with a space or tab) joined and empty lines dropped.
+func unfoldICS(content string) []string {
+	var lines []string
+	for line := range strings.SplitSeq(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// ParseICSTodos parses the tasks of ICS content. Cancelled tasks and tasks without a
+// summary are left out.
+func ParseICSTodos(content string, calendarID string) []ICSTodo {
+	var todos []ICSTodo
+	var current *ICSTodo
+	cancelled := false
+	// Components inside a task, such as its alarms, have properties of their own
+	nested := 0
+
+	for _, line := range unfoldICS(content) {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, _, _ := strings.Cut(strings.ToUpper(name), ";")
+		value = strings.TrimSpace(value)
+
+		switch {
+		case key == "BEGIN" && strings.EqualFold(value, "VTODO"):
+			current, cancelled, nested = &ICSTodo{CalendarID: calendarID}, false, 0
+			continue
+		case current == nil:
+			continue
+		case key == "BEGIN":
+			nested++
+			continue
+		case key == "END" && nested > 0:
+			nested--
+			continue
+		case key == "END" && strings.EqualFold(value, "VTODO"):
+			if current.Summary != "" && !cancelled {
+				todos = append(todos, *current)
+			}
+			current = nil
+			continue
+		case nested > 0:
+			continue
+		}
+
+		switch key {
+		case "UID":
+			current.UID = value
+		case "SUMMARY":
+			current.Summary = unescapeICS(value)
+		case "DUE":
+			if due, err := parseICSTime(value, icsTZID(name)); err == nil {
+				current.Due = due
+			} else {
+				GetDebugLogger().Logf("calendar", "Failed to parse DUE: %s, error: %v", value, err)
+			}
+		case "PRIORITY":
+			if p, err := strconv.Atoi(value); err == nil && p >= 0 && p <= 9 {
+				current.Priority = p
+			}
+		case "STATUS":
+			switch strings.ToUpper(value) {
+			case "COMPLETED":
+				current.Completed = true
+			case "CANCELLED":
+				cancelled = true
+			}
+		case "COMPLETED":
+			current.Completed = true
+		case "PERCENT-COMPLETE":
+			if value == "100" {
+				current.Completed = true
+			}
+		}
+	}
+	return todos
+}
+
+// icsTodoPriority maps an ICS priority to the todo priorities: 1-4 is high, 5 medium and
+// 6-9 low, as in RFC 5545.
+func icsTodoPriority(p int) string {
+	switch {
+	case p == 0:
+		return ""
+	case p <= 4:
+		return "high"
+	case p == 5:
+		return "medium"
+	}
+	return "low"
+}
+
+// ConvertICSTodos converts the tasks of a calendar to todos, which name the calendar as
+// their source.
+func ConvertICSTodos(icsTodos []ICSTodo, calendarName string) []Todo {
+	todos := make([]Todo, 0, len(icsTodos))
+	for _, t := range icsTodos {
+		todo := Todo{
+			ID:        fmt.Sprintf("ics_%s_%s", t.CalendarID, t.UID),
+			Title:     t.Summary,
+			Completed: t.Completed,
+			Priority:  icsTodoPriority(t.Priority),
+			Source:    calendarName,
+		}
+		if !t.Due.IsZero() {
+			todo.DueDate = t.Due.Format("2006-01-02")
+		}
+		todos = append(todos, todo)
+	}
+	return todos
+}
+
+// GetICSTodos returns the tasks of all enabled ICS calendars, fetched and cached along with
+// their events, by priority and then due date.
+func GetICSTodos(ctx context.Context, calendars []ICSCalendar, forceRefresh bool) ([]Todo, error) {
+	if _, err := GetICSEvents(ctx, calendars, forceRefresh); err != nil {
+		return nil, err
+	}
+	icsCache.mu.RLock()
+	todos := slices.Clone(icsCache.todos)
+	icsCache.mu.RUnlock()
+	slices.SortStableFunc(todos, compareTodos)
+	return todos, nil
+}
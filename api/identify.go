@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// identifyConcurrency caps the pages fetched at once when identifying many services.
+const identifyConcurrency = 8
+
+// titlePattern finds the page title.
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// serviceSignature recognizes a self-hosted service by its page. Markers are matched
+// case-insensitively against the page title, the favicon URL, the body and the Server header.
+type serviceSignature struct {
+	Service string
+	Module  string   // Dashboard module that integrates with the service, if any
+	Hint    string   // How to set the module up for the service
+	Titles  []string // Substrings of the page title
+	Icons   []string // Substrings of the favicon URL
+	Bodies  []string // Substrings of the page body
+	Servers []string // Substrings of the Server header
+}
+
+// serviceSignatures are checked in order, so more specific services come first.
+var serviceSignatures = []serviceSignature{
+	{Service: "Proxmox Backup Server", Titles: []string{"proxmox backup server"}},
+	{Service: "Proxmox VE", Module: "virt", Hint: "Add it under virt.proxmox in the config to list its guests",
+		Titles: []string{"proxmox virtual environment"}, Icons: []string{"/pve2/"}, Bodies: []string{"pve2/js/"}},
+	{Service: "Synology DSM", Module: "shares", Hint: "Mount its NFS or SMB shares and list them under shares in the config",
+		Titles: []string{"synology"}, Icons: []string{"/webman/"}, Bodies: []string{"syno.sds"}},
+	{Service: "TrueNAS", Module: "shares", Hint: "Mount its NFS or SMB shares and list them under shares in the config",
+		Titles: []string{"truenas", "freenas"}},
+	{Service: "QNAP QTS", Module: "shares", Hint: "Mount its NFS or SMB shares and list them under shares in the config",
+		Titles: []string{"qnap"}, Bodies: []string{"qnap"}},
+	{Service: "Unraid", Module: "shares", Hint: "Mount its NFS or SMB shares and list them under shares in the config",
+		Titles: []string{"unraid"}, Icons: []string{"unraid"}},
+	{Service: "Pi-hole", Titles: []string{"pi-hole"}, Icons: []string{"pihole", "pi-hole"}, Bodies: []string{"pi-hole"}},
+	{Service: "AdGuard Home", Titles: []string{"adguard home"}},
+	{Service: "DNSPlane", Module: "dnsplane", Hint: "Set its dashboard API URL in the DNSPlane card", Titles: []string{"dnsplane"}},
+	{Service: "Speedplane", Module: "speedplane", Hint: "Set its API URL in the Speedplane card", Titles: []string{"speedplane"}},
+	{Service: "OpenWrt", Module: "router", Hint: "Add it as router in the config to show WAN status and clients",
+		Titles: []string{"openwrt", "luci"}, Bodies: []string{"/luci-static/", "cgi-bin/luci"}},
+	{Service: "OPNsense", Module: "snmp", Hint: "Enable SNMP on it and add an SNMP query for its interfaces", Titles: []string{"opnsense"}},
+	{Service: "pfSense", Module: "snmp", Hint: "Enable SNMP on it and add an SNMP query for its interfaces", Titles: []string{"pfsense"}},
+	{Service: "UniFi Network", Module: "guestwifi", Hint: "Add it under guestWifi.unifi in the config to rotate the guest network password",
+		Titles: []string{"unifi"}, Icons: []string{"unifi"}},
+	{Service: "Home Assistant", Module: "presence", Hint: "Add it under presence.homeAssistant in the config to show who is home",
+		Titles: []string{"home assistant"}, Bodies: []string{"home-assistant"}},
+	{Service: "Portainer", Titles: []string{"portainer"}},
+	{Service: "Grafana", Titles: []string{"grafana"}},
+	{Service: "Jellyfin", Titles: []string{"jellyfin"}},
+	{Service: "Plex", Bodies: []string{"plex media server"}},
+	{Service: "Nextcloud", Titles: []string{"nextcloud"}},
+	{Service: "Gitea", Titles: []string{"gitea"}},
+	{Service: "Jenkins", Titles: []string{"jenkins"}, Servers: []string{"jetty"}},
+	{Service: "Uptime Kuma", Titles: []string{"uptime kuma"}},
+	{Service: "Vaultwarden", Titles: []string{"vaultwarden", "bitwarden"}},
+	{Service: "Nginx Proxy Manager", Titles: []string{"nginx proxy manager"}},
+	{Service: "CUPS", Titles: []string{"cups"}, Servers: []string{"cups"}},
+	{Service: "Webmin", Titles: []string{"webmin"}, Servers: []string{"miniserv"}},
+	{Service: "Cockpit", Titles: []string{"cockpit"}},
+}
+
+// ServiceIdentity is what a web page tells about the service behind it.
+type ServiceIdentity struct {
+	URL     string `json:"url"`
+	Title   string `json:"title,omitempty"`
+	Server  string `json:"server,omitempty"` // Server header
+	Favicon string `json:"favicon,omitempty"`
+	Service string `json:"service,omitempty"` // Recognized service, e.g. "Proxmox VE"
+	Module  string `json:"module,omitempty"`  // Suggested dashboard module
+	Hint    string `json:"hint,omitempty"`
+	Label   string `json:"label,omitempty"` // Suggested monitor name
+	Error   string `json:"error,omitempty"`
+}
+
+// IdentifyService fetches a page and recognizes the service behind it from its title,
+// favicon and Server header. The label falls back to the page title for unknown services.
+func IdentifyService(ctx context.Context, targetURL string) ServiceIdentity {
+	id := ServiceIdentity{URL: targetURL}
+	if err := CheckOutboundURL(ctx, targetURL); err != nil {
+		id.Error = err.Error()
+		return id
+	}
+	parsed, err := url.Parse(targetURL)
+	if err != nil {
+		id.Error = err.Error()
+		return id
+	}
+
+	client := &http.Client{
+		Timeout:       5 * time.Second,
+		Transport:     NewOutboundTransport(&tls.Config{InsecureSkipVerify: true}),
+		CheckRedirect: OutboundCheckRedirect(3),
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		id.Error = err.Error()
+		return id
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; lan-index/1.0)")
+	res, err := client.Do(req)
+	if err != nil {
+		id.Error = err.Error()
+		return id
+	}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 256*1024))
+	_ = res.Body.Close()
+
+	// Follow redirects for relative favicon links, e.g. / -> /admin/
+	origin := parsed.Scheme + "://" + parsed.Host
+	if res.Request != nil && res.Request.URL != nil {
+		origin = res.Request.URL.Scheme + "://" + res.Request.URL.Host
+	}
+	page := string(body)
+	id.Server = res.Header.Get("Server")
+	if m := titlePattern.FindStringSubmatch(page); m != nil {
+		id.Title = strings.Join(strings.Fields(html.UnescapeString(m[1])), " ")
+	}
+	id.Favicon = extractFaviconFromHTML(page, origin)
+
+	title := strings.ToLower(id.Title)
+	icon := strings.ToLower(id.Favicon)
+	lowerBody := strings.ToLower(page)
+	server := strings.ToLower(id.Server)
+	for _, sig := range serviceSignatures {
+		if matchesAny(title, sig.Titles) || matchesAny(icon, sig.Icons) ||
+			matchesAny(lowerBody, sig.Bodies) || matchesAny(server, sig.Servers) {
+			id.Service, id.Module, id.Hint = sig.Service, sig.Module, sig.Hint
+			break
+		}
+	}
+
+	switch {
+	case id.Service != "":
+		id.Label = id.Service
+	case id.Title != "" && len(id.Title) <= 60:
+		id.Label = id.Title
+	}
+	if id.Label != "" {
+		id.Label += " (" + parsed.Host + ")"
+	}
+	return id
+}
+
+// matchesAny reports whether s contains any of the lower-case markers.
+func matchesAny(s string, markers []string) bool {
+	if s == "" {
+		return false
+	}
+	for _, m := range markers {
+		if strings.Contains(s, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// IdentifyServices identifies many pages, a few at a time, in the order of urls.
+func IdentifyServices(ctx context.Context, urls []string) []ServiceIdentity {
+	result := make([]ServiceIdentity, len(urls))
+	sem := make(chan struct{}, identifyConcurrency)
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			result[i] = IdentifyService(ctx, u)
+		}()
+	}
+	wg.Wait()
+	return result
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+)
+
+// LayoutBreakpointDesktop names the layout of wide screens, the top level of LayoutConfig.
+const LayoutBreakpointDesktop = "desktop"
+
+// layoutBreakpoint is a screen size with a layout of its own.
+type layoutBreakpoint struct {
+	Name     string
+	MaxWidth int // Widest viewport, in CSS pixels, the breakpoint applies to
+	Columns  int // Columns of the grid derived from the desktop layout
+}
+
+// layoutBreakpoints are the narrower screens, narrowest first. Wider viewports use the
+// desktop layout. static/js/layout.js uses the same values.
+var layoutBreakpoints = []layoutBreakpoint{
+	{Name: "mobile", MaxWidth: 640, Columns: 1},
+	{Name: "tablet", MaxWidth: 1024, Columns: 2},
+}
+
+// LayoutGrid is the layout of one breakpoint: its columns and the modules in order.
+type LayoutGrid struct {
+	Columns int            `json:"columns"`
+	Modules []LayoutModule `json:"modules"`
+}
+
+// findLayoutBreakpoint returns a narrower breakpoint by name.
+func findLayoutBreakpoint(name string) (layoutBreakpoint, bool) {
+	i := slices.IndexFunc(layoutBreakpoints, func(b layoutBreakpoint) bool { return b.Name == name })
+	if i < 0 {
+		return layoutBreakpoint{}, false
+	}
+	return layoutBreakpoints[i], true
+}
+
+// LayoutBreakpointFor returns the breakpoint of a viewport width.
+func LayoutBreakpointFor(width int) string {
+	for _, b := range layoutBreakpoints {
+		if width <= b.MaxWidth {
+			return b.Name
+		}
+	}
+	return LayoutBreakpointDesktop
+}
+
+// ValidLayoutBreakpoint reports whether name is a breakpoint.
+func ValidLayoutBreakpoint(name string) bool {
+	_, ok := findLayoutBreakpoint(name)
+	return ok || name == LayoutBreakpointDesktop
+}
+
+// validateLayoutGrid checks the columns and module entries of a grid.
+func validateLayoutGrid(columns int, modules []LayoutModule) (bool, string) {
+	if columns < 1 || columns > 12 {
+		return false, "columns must be between 1 and 12"
+	}
+	seen := make(map[string]bool, len(modules))
+	for i, m := range modules {
+		if err := ValidModuleID(m.ID); err != nil {
+			return false, fmt.Sprintf("module %d: %v", i+1, err)
+		}
+		if seen[m.ID] {
+			return false, fmt.Sprintf("module %d: %s is placed twice", i+1, m.ID)
+		}
+		seen[m.ID] = true
+		if m.Span < 1 || m.Span > columns {
+			return false, fmt.Sprintf("module %d: span must be between 1 and %d", i+1, columns)
+		}
+	}
+	return true, ""
+}
+
+// validateLayoutBreakpoints checks the grids saved for narrower screens.
+func validateLayoutBreakpoints(breakpoints map[string]LayoutGrid) (bool, string) {
+	for name, grid := range breakpoints {
+		if _, ok := findLayoutBreakpoint(name); !ok {
+			return false, fmt.Sprintf("unknown breakpoint %q, must be mobile or tablet", name)
+		}
+		if valid, msg := validateLayoutGrid(grid.Columns, grid.Modules); !valid {
+			return false, name + ": " + msg
+		}
+	}
+	return true, ""
+}
+
+// reconcileLayoutGrid makes a saved breakpoint grid place the modules of the desktop
+// layout: modules the desktop no longer places are dropped and new ones are added at the
+// end, one column wide.
+func reconcileLayoutGrid(grid LayoutGrid, desktop []LayoutModule) LayoutGrid {
+	placed := make(map[string]bool, len(desktop))
+	for _, m := range desktop {
+		placed[m.ID] = true
+	}
+	modules := make([]LayoutModule, 0, len(desktop))
+	seen := make(map[string]bool, len(desktop))
+	for _, m := range grid.Modules {
+		if placed[m.ID] && !seen[m.ID] {
+			modules = append(modules, m)
+			seen[m.ID] = true
+		}
+	}
+	for _, m := range desktop {
+		if !seen[m.ID] {
+			modules = append(modules, LayoutModule{ID: m.ID, Span: 1})
+		}
+	}
+	return LayoutGrid{Columns: grid.Columns, Modules: modules}
+}
+
+// deriveLayoutGrid builds the grid of a breakpoint from the desktop layout: the modules
+// in the same order on fewer columns, each spanning at most all of them.
+func deriveLayoutGrid(b layoutBreakpoint, desktop LayoutConfig) LayoutGrid {
+	columns := min(b.Columns, max(desktop.Columns, 1))
+	modules := make([]LayoutModule, len(desktop.Modules))
+	for i, m := range desktop.Modules {
+		modules[i] = LayoutModule{ID: m.ID, Span: min(max(m.Span, 1), columns)}
+	}
+	return LayoutGrid{Columns: columns, Modules: modules}
+}
+
+// ForBreakpoint returns the grid of a flat layout for a breakpoint, and whether it was
+// derived from the desktop layout because none was saved for it.
+func (c LayoutConfig) ForBreakpoint(name string) (LayoutGrid, bool) {
+	b, ok := findLayoutBreakpoint(name)
+	if !ok {
+		return LayoutGrid{Columns: c.Columns, Modules: c.Modules}, false
+	}
+	if grid, saved := c.Breakpoints[name]; saved {
+		return reconcileLayoutGrid(grid, c.Modules), false
+	}
+	return deriveLayoutGrid(b, c), true
+}
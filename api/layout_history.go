@@ -0,0 +1,197 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// layoutHistoryFile holds the earlier layouts of every profile and page across restarts.
+const layoutHistoryFile = "layout_history.json"
+
+// maxLayoutVersions is the number of earlier layouts kept for each page of a profile.
+const maxLayoutVersions = 20
+
+// layoutSettleTime is how long a layout must have been in use to be kept when it is
+// replaced, so a drag-and-drop session leaves one version rather than one per move.
+const layoutSettleTime = time.Minute
+
+// ErrLayoutVersionNotFound is returned when a layout version ID is not in the history.
+var ErrLayoutVersionNotFound = errors.New("layout version not found")
+
+// LayoutVersion is an earlier layout of a page.
+type LayoutVersion struct {
+	ID       string    `json:"id"`
+	Saved    time.Time `json:"saved"`    // When the layout was stored
+	Replaced time.Time `json:"replaced"` // When a newer layout replaced it
+	Version  int64     `json:"version"`  // Storage version of the layout
+	Modules  int       `json:"modules"`  // Number of placed modules
+	Layout   any       `json:"layout"`
+}
+
+// LayoutHistory keeps the last layouts of each page of each profile, so an unwanted change
+// can be rolled back from any device.
+type LayoutHistory struct {
+	mu       sync.Mutex
+	profiles map[string][]LayoutVersion // By layoutHistoryKey, oldest first
+	loaded   bool
+}
+
+// layoutHistoryKey returns the history entry of a page: the profile name for the home page,
+// "{profile}/{page}" for the others.
+func layoutHistoryKey(profile, page string) string {
+	if page == "" || page == HomePage {
+		return profile
+	}
+	return profile + "/" + page
+}
+
+// Global layout history instance
+var layoutHistory = &LayoutHistory{}
+
+// GetLayoutHistory returns the global layout history instance.
+func GetLayoutHistory() *LayoutHistory {
+	return layoutHistory
+}
+
+// load reads the history file. Caller must hold mu.
+func (lh *LayoutHistory) load() {
+	if lh.loaded {
+		return
+	}
+	lh.loaded = true
+	lh.profiles = make(map[string][]LayoutVersion)
+	data, err := os.ReadFile(layoutHistoryFile)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &lh.profiles); err != nil {
+		GetDebugLogger().Logf("layout", "failed to parse %s: %v", layoutHistoryFile, err)
+		lh.profiles = make(map[string][]LayoutVersion)
+	}
+}
+
+// save writes the history file. Caller must hold mu.
+func (lh *LayoutHistory) save() {
+	data, err := json.Marshal(lh.profiles)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(layoutHistoryFile, data, 0644); err != nil {
+		GetDebugLogger().Logf("layout", "failed to write %s: %v", layoutHistoryFile, err)
+	}
+}
+
+// recordReplaced keeps a stored layout that a newer one replaced. Layouts replaced within
+// layoutSettleTime of being stored are skipped unless force is set.
+func (lh *LayoutHistory) recordReplaced(key string, item StorageItem, force bool) {
+	profile, base := ParseProfileStorageKey(key)
+	page, ok := LayoutPageOfKey(base)
+	if !ok {
+		return
+	}
+	hk := layoutHistoryKey(profile, page)
+	now := time.Now()
+	if !force && now.Sub(item.LastModified) < layoutSettleTime {
+		return
+	}
+
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+	lh.load()
+	versions := lh.profiles[hk]
+	if n := len(versions); n > 0 && versions[n-1].Version == item.Version {
+		return
+	}
+	versions = append(versions, LayoutVersion{
+		ID:       newStoreID(),
+		Saved:    item.LastModified.UTC().Truncate(time.Second),
+		Replaced: now.UTC().Truncate(time.Second),
+		Version:  item.Version,
+		Modules:  layoutModuleCount(item.Value),
+		Layout:   item.Value,
+	})
+	if len(versions) > maxLayoutVersions {
+		versions = slices.Clone(versions[len(versions)-maxLayoutVersions:])
+	}
+	lh.profiles[hk] = versions
+	lh.save()
+}
+
+// Versions returns the earlier layouts of a page of a profile, newest first.
+func (lh *LayoutHistory) Versions(profile, page string) []LayoutVersion {
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+	lh.load()
+	versions := slices.Clone(lh.profiles[layoutHistoryKey(profile, page)])
+	slices.Reverse(versions)
+	return versions
+}
+
+// Rollback stores an earlier layout of a page as its current one and returns the new
+// storage version. The layout it replaces is kept, so a rollback can be undone.
+func (lh *LayoutHistory) Rollback(profile, page, id string) (int64, error) {
+	hk := layoutHistoryKey(profile, page)
+	lh.mu.Lock()
+	lh.load()
+	i := slices.IndexFunc(lh.profiles[hk], func(v LayoutVersion) bool { return v.ID == id })
+	if id == "" || i < 0 {
+		lh.mu.Unlock()
+		return 0, ErrLayoutVersionNotFound
+	}
+	layout := lh.profiles[hk][i].Layout
+	lh.mu.Unlock()
+
+	key := ProfileStorageKey(profile, LayoutPageKey(page))
+	if current, exists := GetStorage().Get(key); exists {
+		lh.recordReplaced(key, *current, true)
+	}
+	GetStorage().SetNext(key, layout)
+	item, _ := GetStorage().Get(key)
+	return item.Version, nil
+}
+
+// DeleteProfile forgets the earlier layouts of every page of a profile.
+func (lh *LayoutHistory) DeleteProfile(profile string) {
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+	lh.load()
+	changed := false
+	for hk := range lh.profiles {
+		if hk == profile || strings.HasPrefix(hk, profile+"/") {
+			delete(lh.profiles, hk)
+			changed = true
+		}
+	}
+	if changed {
+		lh.save()
+	}
+}
+
+// DeletePage forgets the earlier layouts of a page.
+func (lh *LayoutHistory) DeletePage(profile, page string) {
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+	lh.load()
+	hk := layoutHistoryKey(profile, page)
+	if _, ok := lh.profiles[hk]; ok {
+		delete(lh.profiles, hk)
+		lh.save()
+	}
+}
+
+// layoutModuleCount returns the number of modules placed by a stored layout.
+func layoutModuleCount(value any) int {
+	var layout struct {
+		Modules []json.RawMessage `json:"modules"`
+	}
+	data, err := json.Marshal(value)
+	if err != nil || json.Unmarshal(data, &layout) != nil {
+		return 0
+	}
+	return len(layout.Modules)
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// ListenerConfig is an address the dashboard listens on besides the main ip and port,
+// e.g. a LAN port next to an admin port bound to localhost.
+type ListenerConfig struct {
+	Addr string `json:"addr"` // host:port, e.g. "192.168.1.10:8081" or ":80"
+	// Role is the highest role requests on this address get, whatever their token, e.g.
+	// "viewer" for a read-only LAN port. Empty leaves roles as they are
+	Role Role `json:"role,omitempty"`
+}
+
+// Validate checks the address and the role limit.
+func (c ListenerConfig) Validate() error {
+	host, port, err := net.SplitHostPort(c.Addr)
+	if err != nil {
+		return fmt.Errorf("addr must be host:port: %w", err)
+	}
+	if host != "" && net.ParseIP(host) == nil {
+		return fmt.Errorf("addr %q must use an IP address", c.Addr)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("addr %q must have a valid port number (1-65535)", c.Addr)
+	}
+	if c.Role != "" {
+		if _, err := ParseRole(string(c.Role)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listenerRoleKey is the context key of the role limit of the listener a connection came in on.
+type listenerRoleKey struct{}
+
+// roleListener marks the connections it accepts with a role limit.
+type roleListener struct {
+	net.Listener
+	role Role
+}
+
+// roleConn is a connection accepted by a roleListener.
+type roleConn struct {
+	net.Conn
+	role Role
+}
+
+func (l roleListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return roleConn{Conn: c, role: l.role}, nil
+}
+
+// LimitListener caps the role of the requests made on a listener's connections. The server
+// must use ConnContext for the limit to apply.
+func LimitListener(ln net.Listener, role Role) net.Listener {
+	if role == "" {
+		return ln
+	}
+	role, _ = ParseRole(string(role))
+	return roleListener{Listener: ln, role: role}
+}
+
+// ConnContext is the http.Server ConnContext that carries the role limit of a connection
+// from LimitListener to the auth middleware.
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	// TLS connections wrap the accepted one
+	if tc, ok := c.(interface{ NetConn() net.Conn }); ok {
+		c = tc.NetConn()
+	}
+	if rc, ok := c.(roleConn); ok {
+		return context.WithValue(ctx, listenerRoleKey{}, rc.role)
+	}
+	return ctx
+}
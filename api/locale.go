@@ -0,0 +1,348 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers a client sends to override the units, locale and time zone of its responses.
+const (
+	HeaderUnits    = "X-Units"     // "metric" or "imperial"
+	HeaderLocale   = "X-Locale"    // BCP 47 tag, e.g. "en-US"
+	HeaderTimeZone = "X-Time-Zone" // IANA zone, e.g. "America/New_York"
+)
+
+var localeTagPattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// Regions that write times on a 12-hour clock, and those that put the month first.
+var (
+	twelveHourRegions = map[string]bool{"US": true, "CA": true, "AU": true, "NZ": true, "IN": true, "PH": true}
+	monthFirstRegions = map[string]bool{"US": true, "PH": true}
+)
+
+// ClientLocale is how a client wants values shown. It comes from the request headers,
+// then the profile's "localePrefs" setting, then the general settings, then the server's
+// own zone and metric units.
+type ClientLocale struct {
+	Units       string         `json:"units"`                // "metric" or "imperial"
+	Temperature string         `json:"temperature"`          // "celsius" or "fahrenheit"
+	Wind        string         `json:"wind,omitempty"`       // "km/h", "m/s", "mph" or "kn"; empty keeps the provider's
+	Locale      string         `json:"locale,omitempty"`     // BCP 47 tag
+	DateFormat  string         `json:"dateFormat,omitempty"` // "iso", "dmy" or "mdy"; empty follows the locale
+	FirstDay    int            `json:"firstDay"`             // First day of the week, 0 = Sunday
+	TimeZone    string         `json:"timeZone"`
+	Source      string         `json:"source"` // Where the last override came from: "header", "profile", "settings" or "server"
+	Location    *time.Location `json:"-"`
+}
+
+// localePrefs is the "localePrefs" storage key of a profile.
+type localePrefs struct {
+	Units    string `json:"units"`
+	Locale   string `json:"locale"`
+	TimeZone string `json:"timeZone"`
+}
+
+// ClientLocaleFromRequest resolves the locale of a request. Invalid values are ignored.
+func ClientLocaleFromRequest(r *http.Request) ClientLocale {
+	settings := CurrentSettings()
+	cl := ClientLocale{
+		Units:       "metric",
+		Temperature: "celsius",
+		DateFormat:  settings.DateFormat,
+		FirstDay:    int(settings.FirstWeekday()),
+		TimeZone:    serverZone(),
+		Source:      "server",
+		Location:    time.Local,
+	}
+	if settings.TemperatureUnit != "" || settings.WindUnit != "" {
+		cl.Wind, cl.Source = settings.WindUnit, "settings"
+		if settings.TemperatureUnit != "" {
+			cl.Temperature = settings.TemperatureUnit
+		}
+	}
+	var prefs localePrefs
+	GetStorage().GetAsForProfile(ProfileFromRequest(r), "localePrefs", &prefs)
+
+	apply := func(units, locale, zone, source string) {
+		switch units {
+		case "metric":
+			cl.Units, cl.Temperature, cl.Wind, cl.Source = units, "celsius", "", source
+		case "imperial":
+			cl.Units, cl.Temperature, cl.Wind, cl.Source = units, "fahrenheit", "mph", source
+		}
+		if localeTagPattern.MatchString(locale) {
+			cl.Locale, cl.Source = locale, source
+		}
+		if zone != "" {
+			if loc, err := time.LoadLocation(zone); err == nil {
+				cl.TimeZone, cl.Location, cl.Source = loc.String(), loc, source
+			}
+		}
+	}
+	apply(prefs.Units, prefs.Locale, prefs.TimeZone, "profile")
+	apply(strings.ToLower(r.Header.Get(HeaderUnits)), r.Header.Get(HeaderLocale), r.Header.Get(HeaderTimeZone), "header")
+	return cl
+}
+
+// serverZone names the server's zone: $TZ, or the zoneinfo file /etc/localtime links to.
+func serverZone() string {
+	if tz := strings.TrimPrefix(os.Getenv("TZ"), ":"); tz != "" {
+		return tz
+	}
+	if target, err := os.Readlink("/etc/localtime"); err == nil {
+		if _, name, ok := strings.Cut(target, "zoneinfo/"); ok {
+			return name
+		}
+	}
+	return time.Local.String()
+}
+
+// Imperial reports whether values are converted to imperial units.
+func (cl ClientLocale) Imperial() bool {
+	return cl.Units == "imperial"
+}
+
+// Fahrenheit reports whether temperatures are converted to Fahrenheit.
+func (cl ClientLocale) Fahrenheit() bool {
+	return cl.Temperature == "fahrenheit"
+}
+
+// Now returns the current time in the client's zone.
+func (cl ClientLocale) Now() time.Time {
+	return time.Now().In(cl.Location)
+}
+
+// region returns the upper-case region of the locale, e.g. "US" for "en-US".
+func (cl ClientLocale) region() string {
+	parts := strings.Split(cl.Locale, "-")
+	for _, part := range parts[1:] {
+		if len(part) == 2 {
+			return strings.ToUpper(part)
+		}
+	}
+	return ""
+}
+
+// FormatEventDate formats an event's date and HH:MM time for the locale: "Mon, Jan 2 3:04 PM"
+// where the month comes first and the clock has 12 hours, "Mon, 2 Jan 15:04" elsewhere. The
+// date format of the settings wins over the locale's. Without either it is FormatEventDate's
+// format.
+func (cl ClientLocale) FormatEventDate(dateStr, timeStr string) string {
+	if cl.Locale == "" && cl.DateFormat == "" {
+		return FormatEventDate(dateStr, timeStr)
+	}
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return dateStr
+	}
+	region := cl.region()
+	layout := "Mon, 2 Jan"
+	switch {
+	case cl.DateFormat == "iso":
+		layout = "2006-01-02"
+	case cl.DateFormat == "mdy", cl.DateFormat == "" && monthFirstRegions[region]:
+		layout = "Mon, Jan 2"
+	}
+	formatted := date.Format(layout)
+	if timeStr == "" {
+		return formatted
+	}
+	if t, err := time.Parse("15:04", timeStr); err == nil && twelveHourRegions[region] {
+		return formatted + " " + t.Format("3:04 PM")
+	}
+	return formatted + " " + timeStr
+}
+
+// LocalizeEvents moves timed events from the server's zone to the client's, which may
+// change their date. All-day events keep their date.
+func (cl ClientLocale) LocalizeEvents(events []CalendarEvent) {
+	if cl.Location.String() == time.Local.String() {
+		return
+	}
+	for i, evt := range events {
+		if evt.Time == "" {
+			continue
+		}
+		t, err := time.ParseInLocation("2006-01-02 15:04", evt.Date+" "+evt.Time, time.Local)
+		if err != nil {
+			continue
+		}
+		t = t.In(cl.Location)
+		events[i].Date, events[i].Time = t.Format("2006-01-02"), t.Format("15:04")
+	}
+}
+
+// windToMS converts wind speeds to metres per second.
+var windToMS = map[string]float64{"km/h": 1 / 3.6, "m/s": 1, "mph": 0.44704, "kn": 0.514444}
+
+// convertWind converts a wind speed given in unit to the client's wind unit and returns the
+// new unit. Unknown units are left alone, as is everything for clients keeping the
+// provider's unit.
+func (cl ClientLocale) convertWind(v *float64, unit string) string {
+	from, okFrom := windToMS[strings.TrimSpace(unit)]
+	to, okTo := windToMS[cl.Wind]
+	if !okFrom || !okTo || strings.TrimSpace(unit) == cl.Wind {
+		return unit
+	}
+	*v = *v * from / to
+	return cl.Wind
+}
+
+// celsiusToFahrenheit converts a temperature.
+func celsiusToFahrenheit(c float64) float64 { return c*9/5 + 32 }
+
+// convertTemp converts a temperature given in unit to Fahrenheit and returns the new unit.
+// Zero stays zero, as it marks a value the provider did not send.
+func convertTemp(v *float64, unit string) string {
+	if !strings.HasSuffix(unit, "C") {
+		return unit
+	}
+	if *v != 0 {
+		*v = celsiusToFahrenheit(*v)
+	}
+	return "°F"
+}
+
+// LocalizeWeather converts the weather to the client's units. Values come in metric
+// (temperatures in °C, wind in km/h or m/s, pressure in hPa, visibility in km and
+// precipitation in mm); nothing changes for metric clients keeping the provider's wind unit.
+func (cl ClientLocale) LocalizeWeather(info *WeatherInfo) {
+	if !cl.Fahrenheit() && cl.Wind == "" && !cl.Imperial() {
+		return
+	}
+	if c := info.Current; c != nil {
+		current := *c
+		if cl.Fahrenheit() {
+			unit := current.TempUnit
+			current.TempUnit = convertTemp(&current.Temperature, unit)
+			convertTemp(&current.FeelsLike, unit)
+			convertTemp(&current.DewPoint, unit)
+		}
+		current.WindUnit = cl.convertWind(&current.WindSpeed, current.WindUnit)
+		if cl.Imperial() && current.Pressure != 0 {
+			current.Pressure *= 0.02953
+			current.PressureUnit = "inHg"
+		}
+		if cl.Imperial() && current.Visibility != 0 {
+			current.Visibility /= 1.609344
+			current.VisibilityUnit = "mi"
+		}
+		info.Current = &current
+	}
+	if cl.Fahrenheit() {
+		info.Today = localizeWeatherDay(info.Today)
+		info.Tomorrow = localizeWeatherDay(info.Tomorrow)
+	}
+	if n := info.Nowcast; n != nil && n.Unit == "mm" && cl.Imperial() {
+		nowcast := *n
+		nowcast.Points = make([]NowcastPoint, len(n.Points))
+		for i, p := range n.Points {
+			p.Precipitation /= 25.4
+			nowcast.Points[i] = p
+		}
+		nowcast.Unit = "in"
+		info.Nowcast = &nowcast
+	}
+	info.Summary = cl.convertText(info.Summary)
+	for i, line := range info.Forecast {
+		info.Forecast[i] = cl.convertText(line)
+	}
+}
+
+// localizeWeatherDay returns a Fahrenheit copy of a forecast day.
+func localizeWeatherDay(d *WeatherDay) *WeatherDay {
+	if d == nil {
+		return nil
+	}
+	day := *d
+	unit := day.TempUnit
+	day.TempUnit = convertTemp(&day.TempMax, unit)
+	convertTemp(&day.TempMin, unit)
+	return &day
+}
+
+// LocalizeHourly returns the hourly forecast in the client's units. Unlike the current
+// weather every hour has a temperature, so 0°C is converted too.
+func (cl ClientLocale) LocalizeHourly(h *WeatherHourly) *WeatherHourly {
+	if h == nil || (!cl.Fahrenheit() && cl.Wind == "" && !cl.Imperial()) {
+		return h
+	}
+	hourly := *h
+	hourly.Hours = make([]WeatherHour, len(h.Hours))
+	celsius := cl.Fahrenheit() && strings.HasSuffix(h.TempUnit, "C")
+	inches := cl.Imperial() && h.PrecipitationUnit == "mm"
+	wind := 1.0
+	hourly.WindUnit = cl.convertWind(&wind, h.WindUnit)
+	for i, hour := range h.Hours {
+		if celsius {
+			hour.Temperature = celsiusToFahrenheit(hour.Temperature)
+		}
+		hour.WindSpeed *= wind
+		if inches {
+			hour.Precipitation /= 25.4
+		}
+		hourly.Hours[i] = hour
+	}
+	if celsius {
+		hourly.TempUnit = "°F"
+	}
+	if inches {
+		hourly.PrecipitationUnit = "in"
+	}
+	return &hourly
+}
+
+// LocalizeEventWeather converts the forecasts set on events.
+func (cl ClientLocale) LocalizeEventWeather(events []CalendarEvent) {
+	if !cl.Fahrenheit() {
+		return
+	}
+	for i := range events {
+		events[i].Weather = localizeWeatherDay(events[i].Weather)
+	}
+}
+
+// degreesPattern finds Celsius temperatures in the summary texts: "12.3°C", "12.3°".
+var degreesPattern = regexp.MustCompile(`(-?\d+(?:\.\d+)?)°C?`)
+
+// windPattern finds the wind speed of the "Now:" summary: "wind 12.3km/h", "wind 3.4 m/s".
+var windPattern = regexp.MustCompile(`wind (\d+(?:\.\d+)?)( ?)(km/h|m/s)`)
+
+// convertText rewrites the Celsius temperatures of a text in Fahrenheit, and the wind
+// speed of the "Now:" summary in the client's wind unit.
+func (cl ClientLocale) convertText(text string) string {
+	if cl.Fahrenheit() {
+		text = convertDegrees(text)
+	}
+	return windPattern.ReplaceAllStringFunc(text, func(m string) string {
+		parts := windPattern.FindStringSubmatch(m)
+		speed, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return m
+		}
+		unit := cl.convertWind(&speed, parts[3])
+		if unit == parts[3] {
+			return m
+		}
+		return "wind " + Format1(speed) + parts[2] + unit
+	})
+}
+
+// convertDegrees rewrites the Celsius temperatures of a text in Fahrenheit.
+func convertDegrees(text string) string {
+	return degreesPattern.ReplaceAllStringFunc(text, func(m string) string {
+		unit := "°"
+		if strings.HasSuffix(m, "C") {
+			unit = "°F"
+		}
+		c, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSuffix(m, "C"), "°"), 64)
+		if err != nil {
+			return m
+		}
+		return Format1(celsiusToFahrenheit(c)) + unit
+	})
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LoggingConfig configures the structured server log.
+type LoggingConfig struct {
+	Level    string   `json:"level,omitempty"`    // debug, info (default), warn or error
+	Format   string   `json:"format,omitempty"`   // text (default) or json
+	MaxSize  int      `json:"maxSize,omitempty"`  // Rotate the log file at this size in MB (default 10)
+	MaxFiles int      `json:"maxFiles,omitempty"` // Rotated files to keep (default 5)
+	Modules  []string `json:"modules,omitempty"`  // Components whose debug messages are always logged
+}
+
+// Validate checks the level and format.
+func (c LoggingConfig) Validate() error {
+	if _, err := parseLogLevel(c.Level); err != nil {
+		return err
+	}
+	switch c.Format {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("logging: format must be text or json")
+	}
+	if c.MaxSize < 0 || c.MaxFiles < 0 {
+		return fmt.Errorf("logging: maxSize and maxFiles must not be negative")
+	}
+	return nil
+}
+
+func parseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("logging: level must be debug, info, warn or error")
+}
+
+// logLevel is the minimum level logged for all components.
+var logLevel = new(slog.LevelVar)
+
+// logBase is the handler all loggers write through. Its own level is debug; filtering
+// happens in componentHandler.
+var logBase = struct {
+	mu      sync.RWMutex
+	handler slog.Handler
+}{handler: slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})}
+
+// componentHandler filters records by the global level and lets debug records of a
+// component through when debugging is enabled for it (preferences or logging.modules).
+type componentHandler struct {
+	component string
+	attrs     []slog.Attr
+	groups    []string
+}
+
+func (h *componentHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if level >= logLevel.Level() {
+		return true
+	}
+	return h.component != "" && GetDebugLogger().IsEnabled(h.component)
+}
+
+func (h *componentHandler) Handle(ctx context.Context, r slog.Record) error {
+	logBase.mu.RLock()
+	handler := logBase.handler
+	logBase.mu.RUnlock()
+	if h.component != "" {
+		handler = handler.WithAttrs([]slog.Attr{slog.String("component", h.component)})
+	}
+	if len(h.attrs) > 0 {
+		handler = handler.WithAttrs(h.attrs)
+	}
+	for _, g := range h.groups {
+		handler = handler.WithGroup(g)
+	}
+	return handler.Handle(ctx, r)
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &c
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.groups = append(append([]string{}, h.groups...), name)
+	return &c
+}
+
+// Logger returns the structured logger of a component, e.g. Logger("favicon").
+func Logger(component string) *slog.Logger {
+	return slog.New(&componentHandler{component: component})
+}
+
+// ConfigureLogging sets up the default logger: level, text or JSON output to stderr, and
+// a rotated log file when logPath is set. The standard log package writes through it too.
+func ConfigureLogging(cfg LoggingConfig, logPath string, debug bool) error {
+	level, err := parseLogLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	if debug && level > slog.LevelDebug {
+		level = slog.LevelDebug
+	}
+
+	var out io.Writer = os.Stderr
+	if logPath != "" {
+		file, err := NewRotatingFile(logPath, cfg.MaxSize, cfg.MaxFiles)
+		if err != nil {
+			return err
+		}
+		out = io.MultiWriter(os.Stderr, file)
+	}
+
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var handler slog.Handler
+	if cfg.Format == "json" {
+		handler = slog.NewJSONHandler(out, opts)
+	} else {
+		handler = slog.NewTextHandler(out, opts)
+	}
+
+	logLevel.Set(level)
+	logBase.mu.Lock()
+	logBase.handler = handler
+	logBase.mu.Unlock()
+	GetDebugLogger().SetModules(cfg.Modules)
+	slog.SetDefault(slog.New(&componentHandler{}))
+	return nil
+}
+
+// SetLogLevel changes the level and the modules whose debug messages are always logged,
+// keeping the log output. The config file reloader uses it; the format and log file are
+// only set up at startup.
+func SetLogLevel(cfg LoggingConfig, debug bool) error {
+	level, err := parseLogLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	if debug && level > slog.LevelDebug {
+		level = slog.LevelDebug
+	}
+	logLevel.Set(level)
+	GetDebugLogger().SetModules(cfg.Modules)
+	return nil
+}
+
+// RotatingFile is a log file that is renamed to path.1 (shifting older files up to
+// path.N) once it reaches its maximum size.
+type RotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+// NewRotatingFile opens a log file for appending. maxSizeMB and maxFiles default to 10
+// and 5. A directory path (existing, or ending in a separator) gets homepage.log in it.
+func NewRotatingFile(path string, maxSizeMB, maxFiles int) (*RotatingFile, error) {
+	if info, err := os.Stat(path); (err == nil && info.IsDir()) || strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator)) {
+		path = filepath.Join(path, "homepage.log")
+	}
+	if maxSizeMB <= 0 {
+		maxSizeMB = 10
+	}
+	if maxFiles <= 0 {
+		maxFiles = 5
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	rf := &RotatingFile{path: path, maxSize: int64(maxSizeMB) << 20, maxFiles: maxFiles}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// open opens the current file. Caller must hold mu (or own rf exclusively).
+func (rf *RotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	rf.file = file
+	rf.size = info.Size()
+	return nil
+}
+
+// rotate shifts the rotated files and starts a new one. Caller must hold mu.
+func (rf *RotatingFile) rotate() error {
+	rf.file.Close()
+	os.Remove(rf.path + "." + strconv.Itoa(rf.maxFiles))
+	for i := rf.maxFiles - 1; i >= 1; i-- {
+		os.Rename(rf.path+"."+strconv.Itoa(i), rf.path+"."+strconv.Itoa(i+1))
+	}
+	if err := os.Rename(rf.path, rf.path+".1"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return rf.open()
+}
+
+// Write appends to the file, rotating it first if the write would exceed the maximum size.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.file.Close()
+}
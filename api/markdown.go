@@ -0,0 +1,293 @@
+package api
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// sanitizeTags are the elements kept by SanitizeHTML, with the attributes each may carry.
+// Every other element is dropped and its text kept.
+var sanitizeTags = map[string][]string{
+	"a": {"href"}, "abbr": nil, "b": nil, "blockquote": nil, "br": nil, "caption": nil,
+	"code": nil, "dd": nil, "del": nil, "div": nil, "dl": nil, "dt": nil, "em": nil,
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil, "hr": nil, "i": nil,
+	"img": {"src", "alt", "width", "height"}, "kbd": nil, "li": nil, "mark": nil,
+	"ol": {"start"}, "p": nil, "pre": nil, "s": nil, "small": nil, "span": nil,
+	"strong": nil, "sub": nil, "sup": nil, "table": nil, "tbody": nil,
+	"td": {"colspan", "rowspan"}, "tfoot": nil, "th": {"colspan", "rowspan"}, "thead": nil,
+	"tr": nil, "u": nil, "ul": nil,
+}
+
+// sanitizeDropped are the elements removed with everything inside them.
+var sanitizeDropped = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true, "noscript": true,
+	"template": true, "textarea": true, "select": true, "svg": true, "math": true, "head": true, "title": true,
+}
+
+// sanitizeVoid are the kept elements without an end tag.
+var sanitizeVoid = map[string]bool{"br": true, "hr": true, "img": true}
+
+// safeURL reports whether a link or image URL is relative or uses http, https or mailto.
+func safeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// SanitizeHTML keeps the formatting, links, images and tables of an HTML snippet and drops
+// scripts, styles, event handlers, forms and frames. Links open in a new tab without a
+// referrer, and unclosed elements are closed.
+func SanitizeHTML(input string) string {
+	var b strings.Builder
+	var open []string
+	skip := 0
+	z := xhtml.NewTokenizer(strings.NewReader(input))
+	for {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			break
+		}
+		tok := z.Token()
+		name := tok.Data
+		switch tt {
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			if sanitizeDropped[name] {
+				if tt == xhtml.StartTagToken {
+					skip++
+				}
+				continue
+			}
+			allowed, ok := sanitizeTags[name]
+			if skip > 0 || !ok {
+				continue
+			}
+			b.WriteString("<" + name)
+			for _, attr := range tok.Attr {
+				if !containsString(allowed, attr.Key) {
+					continue
+				}
+				if (attr.Key == "href" || attr.Key == "src") && !safeURL(attr.Val) {
+					continue
+				}
+				b.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+			}
+			if name == "a" {
+				b.WriteString(` target="_blank" rel="noopener noreferrer"`)
+			}
+			b.WriteString(">")
+			if !sanitizeVoid[name] {
+				open = append(open, name)
+			}
+		case xhtml.EndTagToken:
+			if sanitizeDropped[name] {
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			// Close the element and any left open inside it
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != name {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		case xhtml.TextToken:
+			if skip == 0 {
+				b.WriteString(html.EscapeString(tok.Data))
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return b.String()
+}
+
+// containsString reports whether list holds s.
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Inline Markdown, matched on escaped text.
+var (
+	mdCode      = regexp.MustCompile("`([^`]+)`")
+	mdImage     = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+	mdLink      = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdAutoLink  = regexp.MustCompile(`(^|[\s(])(https?://[^\s<)]+)`)
+	mdBold      = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdItalic    = regexp.MustCompile(`\*([^*\s][^*]*)\*|\b_([^_\s][^_]*)_\b`)
+	mdStrike    = regexp.MustCompile(`~~([^~]+)~~`)
+	mdHeading   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	mdBullet    = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	mdNumbered  = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+	mdTask      = regexp.MustCompile(`^\[([ xX])\]\s+(.*)$`)
+	mdRule      = regexp.MustCompile(`^(\*\s*){3,}$|^(-\s*){3,}$|^(_\s*){3,}$`)
+	mdCodeFence = regexp.MustCompile("^(```|~~~)")
+)
+
+// markdownInline renders the inline Markdown of one line: code, images, links, bold,
+// italics and strikethrough. HTML in the text is escaped.
+func markdownInline(text string) string {
+	text = html.EscapeString(text)
+	// Keep code spans out of the other rules
+	var codes []string
+	text = mdCode.ReplaceAllStringFunc(text, func(m string) string {
+		codes = append(codes, "<code>"+mdCode.FindStringSubmatch(m)[1]+"</code>")
+		return "\x00" + strconv.Itoa(len(codes)-1) + "\x00"
+	})
+	text = mdImage.ReplaceAllString(text, `<img src="$2" alt="$1">`)
+	text = mdLink.ReplaceAllString(text, `<a href="$2">$1</a>`)
+	text = mdAutoLink.ReplaceAllString(text, `$1<a href="$2">$2</a>`)
+	text = mdBold.ReplaceAllString(text, "<strong>$1$2</strong>")
+	text = mdItalic.ReplaceAllString(text, "<em>$1$2</em>")
+	text = mdStrike.ReplaceAllString(text, "<del>$1</del>")
+	for i, code := range codes {
+		text = strings.Replace(text, "\x00"+strconv.Itoa(i)+"\x00", code, 1)
+	}
+	return text
+}
+
+// RenderMarkdown renders Markdown to sanitized HTML: headings, paragraphs, bullet, numbered
+// and task lists, block quotes, fenced code, rules and the inline rules of markdownInline.
+// HTML in the source is shown as text.
+func RenderMarkdown(source string) string {
+	var b strings.Builder
+	var paragraph []string
+	list := ""
+	quote := false
+	fence := ""
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + strings.Join(paragraph, "<br>") + "</p>")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			b.WriteString("</" + list + ">")
+			list = ""
+		}
+	}
+	closeQuote := func() {
+		if quote {
+			flushParagraph()
+			b.WriteString("</blockquote>")
+			quote = false
+		}
+	}
+
+	for _, raw := range strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n") {
+		if fence != "" {
+			if strings.HasPrefix(strings.TrimSpace(raw), fence) {
+				b.WriteString("</code></pre>")
+				fence = ""
+			} else {
+				b.WriteString(html.EscapeString(raw) + "\n")
+			}
+			continue
+		}
+		line := strings.TrimSpace(raw)
+
+		if m := mdCodeFence.FindString(line); m != "" {
+			flushParagraph()
+			closeList()
+			closeQuote()
+			fence = m
+			b.WriteString("<pre><code>")
+			continue
+		}
+		if strings.HasPrefix(line, ">") {
+			closeList()
+			if !quote {
+				flushParagraph()
+				b.WriteString("<blockquote>")
+				quote = true
+			}
+			if text := strings.TrimSpace(strings.TrimPrefix(line, ">")); text != "" {
+				paragraph = append(paragraph, markdownInline(text))
+			} else {
+				flushParagraph()
+			}
+			continue
+		}
+		closeQuote()
+
+		if line == "" {
+			flushParagraph()
+			closeList()
+			continue
+		}
+		if mdRule.MatchString(line) {
+			flushParagraph()
+			closeList()
+			b.WriteString("<hr>")
+			continue
+		}
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			flushParagraph()
+			closeList()
+			level := strconv.Itoa(len(m[1]))
+			b.WriteString("<h" + level + ">" + markdownInline(m[2]) + "</h" + level + ">")
+			continue
+		}
+		kind, item := "", ""
+		if m := mdBullet.FindStringSubmatch(line); m != nil {
+			kind, item = "ul", m[1]
+		} else if m := mdNumbered.FindStringSubmatch(line); m != nil {
+			kind, item = "ol", m[1]
+		}
+		if kind != "" {
+			flushParagraph()
+			if list != kind {
+				closeList()
+				b.WriteString("<" + kind + ">")
+				list = kind
+			}
+			if m := mdTask.FindStringSubmatch(item); m != nil {
+				box := "☐ "
+				if m[1] != " " {
+					box = "☑ "
+				}
+				item = m[2]
+				b.WriteString("<li>" + box + markdownInline(item) + "</li>")
+			} else {
+				b.WriteString("<li>" + markdownInline(item) + "</li>")
+			}
+			continue
+		}
+		closeList()
+		paragraph = append(paragraph, markdownInline(line))
+	}
+	if fence != "" {
+		b.WriteString("</code></pre>")
+	}
+	flushParagraph()
+	closeList()
+	closeQuote()
+	return SanitizeHTML(b.String())
+}
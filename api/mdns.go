@@ -0,0 +1,529 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/ipv4"
+)
+
+// Multicast DNS (RFC 6762) and DNS-SD (RFC 6763) settings.
+const (
+	mdnsPort       = 5353
+	mdnsHostTTL    = 120  // Seconds for the address records
+	mdnsServiceTTL = 4500 // Seconds for the service records
+	mdnsLegacyTTL  = 10   // Seconds in answers to one-shot (non-5353) queries
+	mdnsProbeWait  = 250 * time.Millisecond
+	// A name is renamed at most this often before announcing is given up
+	mdnsMaxRenames = 16
+	// Class bit of a unique record, telling caches to drop older data of the name
+	mdnsUniqueBit   = 1 << 15
+	mdnsServiceEnum = "_services._dns-sd._udp.local."
+)
+
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: mdnsPort}
+
+// MDNSConfig configures the announcement of the dashboard on the LAN by multicast DNS.
+type MDNSConfig struct {
+	Hostname  string `json:"hostname,omitempty"`  // Announced as <hostname>.local, default "homepage"
+	Name      string `json:"name,omitempty"`      // Service name shown by browsers, default the dashboard title
+	Interface string `json:"interface,omitempty"` // Network interface announced on, default the system's multicast interface
+	Disabled  bool   `json:"disabled,omitempty"`  // Do not announce
+}
+
+// Validate checks the host name, service name and interface.
+func (c MDNSConfig) Validate() error {
+	if c.Hostname != "" && !validMDNSHostname(c.Hostname) {
+		return fmt.Errorf("mdns: hostname must be a single DNS label of letters, digits and dashes")
+	}
+	if len(c.Name) > 63 || strings.ContainsAny(c.Name, ".\\") {
+		return fmt.Errorf("mdns: name must be at most 63 characters without dots or backslashes")
+	}
+	if c.Interface != "" {
+		if _, err := net.InterfaceByName(c.Interface); err != nil {
+			return fmt.Errorf("mdns: interface %q: %w", c.Interface, err)
+		}
+	}
+	return nil
+}
+
+// validMDNSHostname reports whether name is a valid host label.
+func validMDNSHostname(name string) bool {
+	if len(name) == 0 || len(name) > 63 || name[0] == '-' || name[len(name)-1] == '-' {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// MDNSStatus is the current announcement.
+type MDNSStatus struct {
+	Enabled   bool   `json:"enabled"`
+	Announced bool   `json:"announced"`
+	Hostname  string `json:"hostname,omitempty"` // e.g. "homepage.local", "homepage-2.local" after a conflict
+	Name      string `json:"name,omitempty"`     // Service instance name
+	Service   string `json:"service,omitempty"`  // "_http._tcp" or "_https._tcp"
+	URL       string `json:"url,omitempty"`
+	Renames   int    `json:"renames"` // Name conflicts resolved since the start
+	Error     string `json:"error,omitempty"`
+}
+
+// MDNSResponder announces the dashboard as <hostname>.local and as an HTTP service, and
+// answers the queries for them. Names taken by another device are probed for before use
+// and renamed ("homepage-2", "LAN Index (2)") on a conflict.
+type MDNSResponder struct {
+	mu        sync.Mutex
+	config    *MDNSConfig
+	port      int
+	service   string // e.g. "_http._tcp.local."
+	path      string
+	listenIP  net.IP
+	hostBase  string
+	nameBase  string
+	hostN     int // Suffix number of the host name, 1 for none
+	nameN     int
+	renamed   chan struct{} // Signalled when another device answers for our names
+	announced bool
+	renames   int
+	err       string
+	conn      *net.UDPConn
+}
+
+// Global mDNS responder instance
+var mdnsResponder = &MDNSResponder{}
+
+// GetMDNS returns the global mDNS responder instance.
+func GetMDNS() *MDNSResponder {
+	return mdnsResponder
+}
+
+// Configure sets the names and the served endpoint. listenAddr is the HTTP listen address;
+// a specific IP there is the only address announced.
+func (m *MDNSResponder) Configure(cfg MDNSConfig, title, listenAddr string, tls bool, basePath string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cfg.Hostname == "" {
+		cfg.Hostname = "homepage"
+	}
+	if cfg.Name == "" {
+		cfg.Name = strings.NewReplacer(".", " ", "\\", " ").Replace(title)
+	}
+	if cfg.Name == "" {
+		cfg.Name = "Homepage"
+	}
+	m.config = &cfg
+	host, portText, _ := net.SplitHostPort(listenAddr)
+	m.port, _ = strconv.Atoi(portText)
+	if m.port == 0 {
+		m.port = 8080
+	}
+	m.listenIP = net.ParseIP(host)
+	if m.listenIP != nil && m.listenIP.IsUnspecified() {
+		m.listenIP = nil
+	}
+	m.service = "_http._tcp.local."
+	if tls {
+		m.service = "_https._tcp.local."
+	}
+	m.path = basePath + "/"
+	m.hostBase, m.nameBase = strings.ToLower(cfg.Hostname), cfg.Name
+	m.hostN, m.nameN = 1, 1
+	m.renamed = make(chan struct{}, 1)
+}
+
+// names returns the current host name and service instance name as fully qualified names.
+func (m *MDNSResponder) names() (string, string) {
+	host := m.hostBase
+	if m.hostN > 1 {
+		host += "-" + strconv.Itoa(m.hostN)
+	}
+	return host + ".local.", mdnsEscape(m.instanceName()) + "." + m.service
+}
+
+// instanceName returns the current service instance name.
+func (m *MDNSResponder) instanceName() string {
+	if m.nameN > 1 {
+		return m.nameBase + " (" + strconv.Itoa(m.nameN) + ")"
+	}
+	return m.nameBase
+}
+
+// mdnsEscape escapes a service instance label for a domain name in presentation format.
+func mdnsEscape(label string) string {
+	return strings.NewReplacer(" ", "\\ ", "(", "\\(", ")", "\\)", ";", "\\;", "\"", "\\\"", "@", "\\@", "$", "\\$").Replace(label)
+}
+
+// Status returns the current announcement.
+func (m *MDNSResponder) Status() MDNSStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.config == nil || m.config.Disabled {
+		return MDNSStatus{}
+	}
+	host, _ := m.names()
+	status := MDNSStatus{
+		Enabled:   true,
+		Announced: m.announced,
+		Hostname:  strings.TrimSuffix(host, "."),
+		Name:      m.instanceName(),
+		Service:   strings.TrimSuffix(m.service, ".local."),
+		Renames:   m.renames,
+		Error:     m.err,
+	}
+	scheme := strings.TrimPrefix(strings.TrimSuffix(m.service, "._tcp.local."), "_")
+	status.URL = scheme + "://" + status.Hostname + ":" + strconv.Itoa(m.port) + m.path
+	return status
+}
+
+// Start joins the mDNS group, claims the names and keeps answering queries.
+func (m *MDNSResponder) Start() {
+	m.mu.Lock()
+	cfg := m.config
+	m.mu.Unlock()
+	if cfg == nil || cfg.Disabled {
+		return
+	}
+	var ifi *net.Interface
+	if cfg.Interface != "" {
+		var err error
+		if ifi, err = net.InterfaceByName(cfg.Interface); err != nil {
+			m.fail(err)
+			return
+		}
+	}
+	conn, err := net.ListenMulticastUDP("udp4", ifi, mdnsGroup)
+	if err != nil {
+		m.fail(err)
+		return
+	}
+	// Hear the other responders of this host too, such as a second dashboard; our own
+	// packets come back as well and match our records
+	if err := ipv4.NewPacketConn(conn).SetMulticastLoopback(true); err != nil {
+		GetDebugLogger().Logf("mdns", "multicast loopback: %v", err)
+	}
+	m.mu.Lock()
+	m.conn = conn
+	renamed := m.renamed
+	m.mu.Unlock()
+	go m.read(conn)
+
+	// The names are claimed again whenever another device answers for them
+	for m.claim() {
+		<-renamed
+		m.mu.Lock()
+		m.announced = false
+		m.mu.Unlock()
+		// Wait a second before probing again, as RFC 6762 asks after a conflict
+		time.Sleep(time.Second)
+	}
+}
+
+// fail records why announcing stopped.
+func (m *MDNSResponder) fail(err error) {
+	Logger("mdns").Warn("cannot announce on the LAN", "error", err)
+	m.mu.Lock()
+	m.err = err.Error()
+	m.mu.Unlock()
+}
+
+// claim probes for the names, renaming the conflicting ones, and announces them. It
+// returns false when no free name was found.
+func (m *MDNSResponder) claim() bool {
+	for !m.probe() {
+		m.mu.Lock()
+		renames := m.renames
+		m.mu.Unlock()
+		if renames > mdnsMaxRenames {
+			m.fail(fmt.Errorf("no free name after %d renames", mdnsMaxRenames))
+			return false
+		}
+		time.Sleep(time.Second)
+	}
+
+	m.mu.Lock()
+	m.announced = true
+	m.mu.Unlock()
+	// Announce twice, a second apart
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			time.Sleep(time.Second)
+		}
+		records := append(m.records(mdnsServiceEnum, dns.TypePTR, false), m.allRecords(false)...)
+		m.send(m.response(records, nil), mdnsGroup)
+	}
+	status := m.Status()
+	Logger("mdns").Info("announced on the LAN", "url", status.URL, "name", status.Name)
+	return true
+}
+
+// probe asks three times, 250ms apart, whether another device uses the names. A conflict
+// renames the conflicting name and returns false.
+func (m *MDNSResponder) probe() bool {
+	m.mu.Lock()
+	host, instance := m.names()
+	renamed := m.renamed
+	m.mu.Unlock()
+	query := new(dns.Msg)
+	query.Question = []dns.Question{
+		{Name: host, Qtype: dns.TypeANY, Qclass: dns.ClassINET},
+		{Name: instance, Qtype: dns.TypeANY, Qclass: dns.ClassINET},
+	}
+	query.Ns = m.allRecords(false)
+	for i := 0; i < 3; i++ {
+		m.send(query, mdnsGroup)
+		select {
+		case <-renamed:
+			return false
+		case <-time.After(mdnsProbeWait):
+		}
+	}
+	return true
+}
+
+// read answers queries and watches the answers of other devices for our names.
+func (m *MDNSResponder) read(conn *net.UDPConn) {
+	buf := make([]byte, 9000)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			m.fail(err)
+			return
+		}
+		msg := new(dns.Msg)
+		if msg.Unpack(buf[:n]) != nil || msg.Opcode != dns.OpcodeQuery {
+			continue
+		}
+		guardLoop("mdns", func() {
+			if msg.Response {
+				m.checkConflict(msg)
+			} else {
+				m.answer(msg, src)
+			}
+		})
+	}
+}
+
+// checkConflict renames a name another device answers for with other data than ours.
+// Our own answers come back over the multicast loopback and match.
+func (m *MDNSResponder) checkConflict(msg *dns.Msg) {
+	m.mu.Lock()
+	host, instance := m.names()
+	m.mu.Unlock()
+	ours := m.allRecords(false)
+	for _, rr := range append(msg.Answer, msg.Extra...) {
+		name := rr.Header().Name
+		if !strings.EqualFold(name, host) && !strings.EqualFold(name, instance) {
+			continue
+		}
+		if rr.Header().Rrtype != dns.TypeA && rr.Header().Rrtype != dns.TypeAAAA && rr.Header().Rrtype != dns.TypeSRV && rr.Header().Rrtype != dns.TypeTXT {
+			continue
+		}
+		if mdnsHasRecord(ours, rr) {
+			continue
+		}
+		m.mu.Lock()
+		if strings.EqualFold(name, host) {
+			m.hostN++
+		} else {
+			m.nameN++
+		}
+		m.renames++
+		newHost, _ := m.names()
+		newName := m.instanceName()
+		select {
+		case m.renamed <- struct{}{}:
+		default:
+		}
+		m.mu.Unlock()
+		Logger("mdns").Warn("name taken by another device, renaming", "taken", strings.TrimSuffix(name, "."), "host", strings.TrimSuffix(newHost, "."), "name", newName)
+		return
+	}
+}
+
+// mdnsHasRecord reports whether records hold rr, ignoring the TTL and class bits.
+func mdnsHasRecord(records []dns.RR, rr dns.RR) bool {
+	for _, own := range records {
+		if own.Header().Rrtype != rr.Header().Rrtype || !strings.EqualFold(own.Header().Name, rr.Header().Name) {
+			continue
+		}
+		a, b := dns.Copy(own), dns.Copy(rr)
+		a.Header().Ttl, b.Header().Ttl = 0, 0
+		a.Header().Class, b.Header().Class = dns.ClassINET, dns.ClassINET
+		if dns.IsDuplicate(a, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// answer replies to the questions for our names over multicast, or to the sender when it
+// is a one-shot resolver that did not send from port 5353. Questions asking for a unicast
+// answer get a multicast one too: another responder sharing port 5353 on the asking host
+// could receive a unicast answer in its place.
+func (m *MDNSResponder) answer(query *dns.Msg, src *net.UDPAddr) {
+	m.mu.Lock()
+	announced := m.announced
+	m.mu.Unlock()
+	if !announced {
+		return
+	}
+	legacy := src.Port != mdnsPort
+	var answers []dns.RR
+	for _, q := range query.Question {
+		answers = append(answers, m.records(q.Name, q.Qtype, legacy)...)
+	}
+	if len(answers) == 0 {
+		return
+	}
+	// Add the records a resolver needs next: the service's SRV and TXT, then the addresses
+	var extra []dns.RR
+	pending := append([]dns.RR(nil), answers...)
+	for len(pending) > 0 {
+		rr := pending[0]
+		pending = pending[1:]
+		before := len(extra)
+		switch rec := rr.(type) {
+		case *dns.PTR:
+			if rec.Ptr != m.service {
+				extra = append(extra, m.records(rec.Ptr, dns.TypeANY, legacy)...)
+			}
+		case *dns.SRV:
+			extra = append(extra, m.records(rec.Target, dns.TypeANY, legacy)...)
+		}
+		pending = append(pending, extra[before:]...)
+	}
+	reply := m.response(answers, extra)
+	if legacy {
+		// One-shot resolvers match the answer to their query like a unicast DNS reply
+		reply.Id = query.Id
+		reply.Question = query.Question
+		m.send(reply, src)
+		return
+	}
+	m.send(reply, mdnsGroup)
+}
+
+// response builds an authoritative mDNS response.
+func (m *MDNSResponder) response(answers, extra []dns.RR) *dns.Msg {
+	msg := new(dns.Msg)
+	msg.Response = true
+	msg.Authoritative = true
+	msg.Answer = answers
+	msg.Extra = extra
+	return msg
+}
+
+// send writes a message to addr.
+func (m *MDNSResponder) send(msg *dns.Msg, addr *net.UDPAddr) {
+	m.mu.Lock()
+	conn := m.conn
+	m.mu.Unlock()
+	buf, err := msg.Pack()
+	if err != nil || conn == nil {
+		return
+	}
+	if _, err := conn.WriteToUDP(buf, addr); err != nil {
+		GetDebugLogger().Logf("mdns", "send to %s failed: %v", addr, err)
+	}
+}
+
+// allRecords returns every record of ours.
+func (m *MDNSResponder) allRecords(legacy bool) []dns.RR {
+	m.mu.Lock()
+	host, instance := m.names()
+	m.mu.Unlock()
+	var records []dns.RR
+	for _, name := range []string{m.service, instance, host} {
+		records = append(records, m.records(name, dns.TypeANY, legacy)...)
+	}
+	return records
+}
+
+// records returns our records of a name and type (or all types for ANY).
+func (m *MDNSResponder) records(name string, qtype uint16, legacy bool) []dns.RR {
+	m.mu.Lock()
+	host, instance := m.names()
+	service, port, path, listenIP := m.service, m.port, m.path, m.listenIP
+	m.mu.Unlock()
+
+	header := func(rrtype uint16, ttl uint32, unique bool) dns.RR_Header {
+		class := uint16(dns.ClassINET)
+		if unique && !legacy {
+			class |= mdnsUniqueBit
+		}
+		if legacy {
+			ttl = min(ttl, mdnsLegacyTTL)
+		}
+		return dns.RR_Header{Name: name, Rrtype: rrtype, Class: class, Ttl: ttl}
+	}
+	wants := func(rrtype uint16) bool {
+		return qtype == rrtype || qtype == dns.TypeANY
+	}
+
+	var records []dns.RR
+	switch {
+	case strings.EqualFold(name, mdnsServiceEnum):
+		if wants(dns.TypePTR) {
+			records = append(records, &dns.PTR{Hdr: header(dns.TypePTR, mdnsServiceTTL, false), Ptr: service})
+		}
+	case strings.EqualFold(name, service):
+		if wants(dns.TypePTR) {
+			records = append(records, &dns.PTR{Hdr: header(dns.TypePTR, mdnsServiceTTL, false), Ptr: instance})
+		}
+	case strings.EqualFold(name, instance):
+		if wants(dns.TypeSRV) {
+			records = append(records, &dns.SRV{Hdr: header(dns.TypeSRV, mdnsHostTTL, true), Target: host, Port: uint16(port)})
+		}
+		if wants(dns.TypeTXT) {
+			records = append(records, &dns.TXT{Hdr: header(dns.TypeTXT, mdnsServiceTTL, true), Txt: []string{"path=" + path}})
+		}
+	case strings.EqualFold(name, host):
+		for _, ip := range mdnsAddresses(listenIP) {
+			if ip4 := ip.To4(); ip4 != nil && wants(dns.TypeA) {
+				records = append(records, &dns.A{Hdr: header(dns.TypeA, mdnsHostTTL, true), A: ip4})
+			} else if ip4 == nil && wants(dns.TypeAAAA) {
+				records = append(records, &dns.AAAA{Hdr: header(dns.TypeAAAA, mdnsHostTTL, true), AAAA: ip})
+			}
+		}
+	}
+	return records
+}
+
+// mdnsAddresses returns the addresses announced for the host name: the listen IP, or the
+// addresses of the interfaces that are up, without loopback and IPv6 link-local ones.
+func mdnsAddresses(listenIP net.IP) []net.IP {
+	if listenIP != nil {
+		return []net.IP{listenIP}
+	}
+	var ips []net.IP
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLoopback() || (ipNet.IP.To4() == nil && ipNet.IP.IsLinkLocalUnicast()) {
+				continue
+			}
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	return ips
+}
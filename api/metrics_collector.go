@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// metricsInterval is how often the collector samples system metrics.
+const metricsInterval = 5 * time.Second
+
+// MetricsCollector samples system metrics once per interval and fans them out
+// to all WebSocket clients subscribed to the system topic.
+type MetricsCollector struct {
+	mu        sync.RWMutex
+	latest    SystemMetrics
+	sampledAt time.Time
+	stopCh    chan struct{}
+	running   bool
+}
+
+// NewMetricsCollector creates a new metrics collector.
+func NewMetricsCollector() *MetricsCollector {
+	return &MetricsCollector{
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Global metrics collector instance
+var metricsCollector = NewMetricsCollector()
+
+// GetMetricsCollector returns the global metrics collector instance.
+func GetMetricsCollector() *MetricsCollector {
+	return metricsCollector
+}
+
+// Start runs the collector loop until Stop is called.
+func (mc *MetricsCollector) Start() {
+	mc.mu.Lock()
+	if mc.running {
+		mc.mu.Unlock()
+		return
+	}
+	mc.running = true
+	mc.mu.Unlock()
+
+	ticker := time.NewTicker(metricsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mc.stopCh:
+			return
+		case <-ticker.C:
+			mc.collect()
+		}
+	}
+}
+
+// Stop stops the collector loop.
+func (mc *MetricsCollector) Stop() {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if !mc.running {
+		return
+	}
+	mc.running = false
+	close(mc.stopCh)
+}
+
+// collect takes one sample, records it in the metric history and broadcasts it to system subscribers.
+func (mc *MetricsCollector) collect() {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsInterval)
+	defer cancel()
+
+	metrics := GetSystemMetrics(ctx)
+	now := time.Now()
+
+	mc.mu.Lock()
+	mc.latest = metrics
+	mc.sampledAt = now
+	mc.mu.Unlock()
+
+	GetMetricsHistory().Record(ctx, metrics, now)
+
+	if !GetWSManager().HasSubscribers(WSTopicSystem) {
+		return
+	}
+
+	uptimeSec := GetSystemUptime()
+	GetWSManager().BroadcastTopic(WSTopicSystem, map[string]interface{}{
+		"type":   "system",
+		"system": metrics,
+		"server": ServerInfo{
+			Time:            now.Format(time.RFC3339),
+			UptimeSec:       uptimeSec,
+			UptimeFormatted: FmtUptime(uptimeSec),
+		},
+	})
+}
+
+// Latest returns the most recent sample and when it was taken.
+func (mc *MetricsCollector) Latest() (SystemMetrics, time.Time) {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	return mc.latest, mc.sampledAt
+}
+
+// Current returns the latest sample if it is fresh, otherwise takes a new one.
+func (mc *MetricsCollector) Current(ctx context.Context) SystemMetrics {
+	if metrics, at := mc.Latest(); !at.IsZero() && time.Since(at) < metricsInterval {
+		return metrics
+	}
+
+	metrics := GetSystemMetrics(ctx)
+	mc.mu.Lock()
+	mc.latest = metrics
+	mc.sampledAt = time.Now()
+	mc.mu.Unlock()
+	return metrics
+}
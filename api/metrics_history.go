@@ -0,0 +1,489 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// metricsHistoryFile holds the hourly history across restarts.
+const metricsHistoryFile = "metrics-history.json"
+
+// Default retention for the metric history ring buffers.
+const (
+	DefaultHistoryRetention       = 24 * time.Hour
+	DefaultHistoryHourlyRetention = 30 * 24 * time.Hour
+)
+
+// MetricPoint is a single sample in a metric history.
+type MetricPoint struct {
+	T int64   `json:"t"` // Unix seconds
+	V float64 `json:"v"`
+}
+
+// metricRing is a fixed-capacity ring buffer of metric points.
+type metricRing struct {
+	points []MetricPoint
+	start  int
+	count  int
+}
+
+func newMetricRing(capacity int) *metricRing {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &metricRing{points: make([]MetricPoint, capacity)}
+}
+
+func (r *metricRing) add(p MetricPoint) {
+	idx := (r.start + r.count) % len(r.points)
+	r.points[idx] = p
+	if r.count < len(r.points) {
+		r.count++
+	} else {
+		r.start = (r.start + 1) % len(r.points)
+	}
+}
+
+// since returns points with T >= from in chronological order.
+func (r *metricRing) since(from int64) []MetricPoint {
+	result := make([]MetricPoint, 0)
+	for i := 0; i < r.count; i++ {
+		p := r.points[(r.start+i)%len(r.points)]
+		if p.T >= from {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// metricSeries holds the full-resolution and hourly history of one metric.
+type metricSeries struct {
+	fine   *metricRing
+	hourly *metricRing
+	// Accumulator for the hour currently being downsampled
+	hour  int64
+	sum   float64
+	count int
+}
+
+// MetricsHistory keeps server-side ring buffers for system metrics.
+type MetricsHistory struct {
+	mu              sync.RWMutex
+	series          map[string]*metricSeries
+	fineCapacity    int
+	hourlyCapacity  int
+	lastNet         *net.IOCountersStat
+	lastNetAt       time.Time
+	hourlyLoaded    bool
+	fineRetention   time.Duration
+	hourlyRetention time.Duration
+}
+
+// NewMetricsHistory creates a metrics history with the given retention at the collector interval.
+func NewMetricsHistory(retention, hourlyRetention time.Duration) *MetricsHistory {
+	if retention <= 0 {
+		retention = DefaultHistoryRetention
+	}
+	if hourlyRetention <= 0 {
+		hourlyRetention = DefaultHistoryHourlyRetention
+	}
+	return &MetricsHistory{
+		series:          make(map[string]*metricSeries),
+		fineCapacity:    int(retention / metricsInterval),
+		hourlyCapacity:  int(hourlyRetention / time.Hour),
+		fineRetention:   retention,
+		hourlyRetention: hourlyRetention,
+	}
+}
+
+// Global metrics history instance
+var metricsHistory = NewMetricsHistory(DefaultHistoryRetention, DefaultHistoryHourlyRetention)
+
+// GetMetricsHistory returns the global metrics history instance.
+func GetMetricsHistory() *MetricsHistory {
+	return metricsHistory
+}
+
+// ConfigureMetricsHistory replaces the global history with one using the given retention.
+// It must be called before the metrics collector is started.
+func ConfigureMetricsHistory(retention, hourlyRetention time.Duration) {
+	metricsHistory = NewMetricsHistory(retention, hourlyRetention)
+}
+
+// seriesFor returns the series for a metric, creating it if needed. Caller must hold mu.
+func (mh *MetricsHistory) seriesFor(name string) *metricSeries {
+	s, ok := mh.series[name]
+	if !ok {
+		s = &metricSeries{
+			fine:   newMetricRing(mh.fineCapacity),
+			hourly: newMetricRing(mh.hourlyCapacity),
+		}
+		mh.series[name] = s
+	}
+	return s
+}
+
+// add records a value for a metric and rolls the hourly average. Caller must hold mu.
+// It reports whether an hourly point was completed.
+func (mh *MetricsHistory) add(name string, t time.Time, v float64) bool {
+	s := mh.seriesFor(name)
+	s.fine.add(MetricPoint{T: t.Unix(), V: v})
+
+	hour := t.Truncate(time.Hour).Unix()
+	completed := false
+	if s.count > 0 && hour != s.hour {
+		s.hourly.add(MetricPoint{T: s.hour, V: s.sum / float64(s.count)})
+		s.sum, s.count = 0, 0
+		completed = true
+	}
+	s.hour = hour
+	s.sum += v
+	s.count++
+	return completed
+}
+
+// Record stores one sample of system metrics plus per-disk and network usage.
+func (mh *MetricsHistory) Record(ctx context.Context, metrics SystemMetrics, t time.Time) {
+	values := make(map[string]float64)
+	if metrics.CPU.Error == "" {
+		values["cpu"] = metrics.CPU.Usage
+	}
+	if metrics.RAM.Error == "" {
+		values["ram"] = metrics.RAM.Percent
+	}
+
+	if partitions, err := disk.PartitionsWithContext(ctx, false); err == nil {
+		for _, p := range partitions {
+			if usage, err := disk.UsageWithContext(ctx, p.Mountpoint); err == nil && usage.Total > 0 {
+				values["disk:"+p.Mountpoint] = usage.UsedPercent
+			}
+		}
+	}
+
+	var netRates map[string]float64
+	if counters, err := net.IOCountersWithContext(ctx, false); err == nil && len(counters) > 0 {
+		current := counters[0]
+		mh.mu.Lock()
+		if mh.lastNet != nil {
+			if elapsed := t.Sub(mh.lastNetAt).Seconds(); elapsed > 0 && current.BytesRecv >= mh.lastNet.BytesRecv && current.BytesSent >= mh.lastNet.BytesSent {
+				netRates = map[string]float64{
+					"net:rx": float64(current.BytesRecv-mh.lastNet.BytesRecv) / elapsed,
+					"net:tx": float64(current.BytesSent-mh.lastNet.BytesSent) / elapsed,
+				}
+			}
+		}
+		mh.lastNet = &current
+		mh.lastNetAt = t
+		mh.mu.Unlock()
+	}
+	for k, v := range netRates {
+		values[k] = v
+	}
+
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+	mh.loadHourly()
+
+	completed := false
+	for name, v := range values {
+		if mh.add(name, t, v) {
+			completed = true
+		}
+	}
+	if completed {
+		mh.saveHourly()
+	}
+}
+
+// Metrics returns the names of all recorded metrics.
+func (mh *MetricsHistory) Metrics() []string {
+	mh.mu.RLock()
+	defer mh.mu.RUnlock()
+	names := make([]string, 0, len(mh.series))
+	for name := range mh.series {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Query returns the history of a metric over the given range. Ranges within the
+// full-resolution retention use raw samples, longer ranges use hourly averages.
+// If maxPoints is positive the result is averaged down to at most that many points.
+func (mh *MetricsHistory) Query(name string, rng time.Duration, maxPoints int) ([]MetricPoint, string, bool) {
+	mh.mu.RLock()
+	defer mh.mu.RUnlock()
+
+	s, ok := mh.series[name]
+	if !ok {
+		return nil, "", false
+	}
+
+	from := time.Now().Add(-rng).Unix()
+	resolution := metricsInterval.String()
+	var points []MetricPoint
+	if rng <= mh.fineRetention {
+		points = s.fine.since(from)
+	} else {
+		resolution = time.Hour.String()
+		points = s.hourly.since(from)
+		// Include the hour currently being accumulated
+		if s.count > 0 && s.hour >= from {
+			points = append(points, MetricPoint{T: s.hour, V: s.sum / float64(s.count)})
+		}
+	}
+
+	if maxPoints > 0 && len(points) > maxPoints {
+		points = downsamplePoints(points, maxPoints)
+	}
+	return points, resolution, true
+}
+
+// downsamplePoints averages consecutive points into at most n buckets.
+func downsamplePoints(points []MetricPoint, n int) []MetricPoint {
+	result := make([]MetricPoint, 0, n)
+	size := float64(len(points)) / float64(n)
+	for i := 0; i < n; i++ {
+		lo := int(float64(i) * size)
+		hi := int(float64(i+1) * size)
+		if hi <= lo {
+			continue
+		}
+		sum := 0.0
+		for _, p := range points[lo:hi] {
+			sum += p.V
+		}
+		result = append(result, MetricPoint{T: points[hi-1].T, V: sum / float64(hi-lo)})
+	}
+	return result
+}
+
+// metricsExportVersion is the format version of metric history exports.
+const metricsExportVersion = 1
+
+// MetricsHistoryExport is a metric history as exported by /api/graphs/export.
+type MetricsHistoryExport struct {
+	Version  int                      `json:"version"`
+	Host     string                   `json:"host"`
+	Exported time.Time                `json:"exported"`
+	Fine     map[string][]MetricPoint `json:"fine"`   // Full-resolution samples
+	Hourly   map[string][]MetricPoint `json:"hourly"` // Hourly averages, including the current hour
+}
+
+// MetricsImportResult reports what an import added.
+type MetricsImportResult struct {
+	Metrics int      `json:"metrics"`
+	Fine    int      `json:"fine"`              // Full-resolution samples added
+	Hourly  int      `json:"hourly"`            // Hourly averages added
+	Skipped []string `json:"skipped,omitempty"` // Metrics this host does not record
+}
+
+// metricsCollected are the metrics every host records. Other metrics, the disks, are only
+// imported for mount points this host has recorded.
+var metricsCollected = map[string]bool{"cpu": true, "ram": true, "net:rx": true, "net:tx": true}
+
+// Export returns the full-resolution and hourly history of every metric.
+func (mh *MetricsHistory) Export() MetricsHistoryExport {
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+	mh.loadHourly()
+
+	export := MetricsHistoryExport{
+		Version:  metricsExportVersion,
+		Host:     MustHostname(),
+		Exported: time.Now(),
+		Fine:     make(map[string][]MetricPoint, len(mh.series)),
+		Hourly:   make(map[string][]MetricPoint, len(mh.series)),
+	}
+	for name, s := range mh.series {
+		export.Fine[name] = s.fine.since(0)
+		hourly := s.hourly.since(0)
+		if s.count > 0 {
+			hourly = append(hourly, MetricPoint{T: s.hour, V: s.sum / float64(s.count)})
+		}
+		export.Hourly[name] = hourly
+	}
+	return export
+}
+
+// Import merges an exported history into the current one, e.g. after moving to new
+// hardware. Points at times already recorded are kept as they are, so importing the same
+// export twice adds nothing. Points past the retention are skipped.
+func (mh *MetricsHistory) Import(export MetricsHistoryExport) (MetricsImportResult, error) {
+	if export.Version != metricsExportVersion {
+		return MetricsImportResult{}, fmt.Errorf("unsupported export version %d", export.Version)
+	}
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+	mh.loadHourly()
+
+	now := time.Now()
+	fineCutoff := now.Add(-mh.fineRetention).Unix()
+	hourlyCutoff := now.Add(-mh.hourlyRetention).Unix()
+	result := MetricsImportResult{}
+
+	// Check the whole export first, so a rejected one adds nothing
+	skipped := make(map[string]bool)
+	check := func(series map[string][]MetricPoint, cutoff int64, capacity int, kind string) error {
+		for name, points := range series {
+			if _, known := mh.series[name]; !known && !metricsCollected[name] {
+				skipped[name] = true
+				continue
+			}
+			// A ring holds capacity points, plus the one at the edge of the retention
+			n := 0
+			for _, p := range points {
+				if p.T >= cutoff {
+					n++
+				}
+			}
+			if n > capacity+1 {
+				return fmt.Errorf("%s: %d %s points within the retention, at most %d", name, n, kind, capacity+1)
+			}
+		}
+		return nil
+	}
+	if err := check(export.Fine, fineCutoff, mh.fineCapacity, "fine"); err != nil {
+		return MetricsImportResult{}, err
+	}
+	if err := check(export.Hourly, hourlyCutoff, mh.hourlyCapacity, "hourly"); err != nil {
+		return MetricsImportResult{}, err
+	}
+	for name := range skipped {
+		result.Skipped = append(result.Skipped, name)
+	}
+	sort.Strings(result.Skipped)
+
+	metrics := make(map[string]bool)
+	for name, points := range export.Fine {
+		if skipped[name] {
+			continue
+		}
+		if n := mergeMetricRing(mh.seriesFor(name).fine, points, fineCutoff); n > 0 {
+			result.Fine += n
+			metrics[name] = true
+		}
+	}
+	for name, points := range export.Hourly {
+		if skipped[name] {
+			continue
+		}
+		s := mh.seriesFor(name)
+		// The hour being accumulated is completed by the live samples
+		if s.count > 0 {
+			kept := points[:0:0]
+			for _, p := range points {
+				if p.T != s.hour {
+					kept = append(kept, p)
+				}
+			}
+			points = kept
+		}
+		if n := mergeMetricRing(s.hourly, points, hourlyCutoff); n > 0 {
+			result.Hourly += n
+			metrics[name] = true
+		}
+	}
+	result.Metrics = len(metrics)
+	mh.saveHourly()
+	return result, nil
+}
+
+// mergeMetricRing adds points at times not yet in the ring and not before cutoff, keeping
+// the ring in chronological order. It returns the number of points added.
+func mergeMetricRing(r *metricRing, points []MetricPoint, cutoff int64) int {
+	existing := r.since(cutoff)
+	seen := make(map[int64]bool, len(existing))
+	for _, p := range existing {
+		seen[p.T] = true
+	}
+	merged := existing
+	for _, p := range points {
+		if p.T < cutoff || seen[p.T] {
+			continue
+		}
+		seen[p.T] = true
+		merged = append(merged, p)
+	}
+	added := len(merged) - len(existing)
+	if added == 0 {
+		return 0
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].T < merged[j].T })
+	if len(merged) > len(r.points) {
+		merged = merged[len(merged)-len(r.points):]
+	}
+	r.start, r.count = 0, 0
+	for _, p := range merged {
+		r.add(p)
+	}
+	return added
+}
+
+// loadHourly restores the hourly history from disk. Caller must hold mu.
+func (mh *MetricsHistory) loadHourly() {
+	if mh.hourlyLoaded {
+		return
+	}
+	mh.hourlyLoaded = true
+
+	cutoff := time.Now().Add(-mh.hourlyRetention).Unix()
+	var saved map[string][]MetricPoint
+	if store := GetStore(); store != nil {
+		saved = store.LoadMetricPoints(cutoff)
+	} else {
+		data, err := os.ReadFile(metricsHistoryFile)
+		if err != nil {
+			return
+		}
+		if err := json.Unmarshal(data, &saved); err != nil {
+			GetDebugLogger().Logf("graphs", "failed to parse %s: %v", metricsHistoryFile, err)
+			return
+		}
+	}
+	for name, points := range saved {
+		s := mh.seriesFor(name)
+		for _, p := range points {
+			if p.T >= cutoff {
+				s.hourly.add(p)
+			}
+		}
+	}
+}
+
+// saveHourly writes the hourly history to disk or the SQLite store. Caller must hold mu.
+func (mh *MetricsHistory) saveHourly() {
+	saved := make(map[string][]MetricPoint, len(mh.series))
+	for name, s := range mh.series {
+		saved[name] = s.hourly.since(0)
+	}
+	if store := GetStore(); store != nil {
+		store.SaveMetricPoints(saved)
+		return
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(metricsHistoryFile, data, 0644); err != nil {
+		GetDebugLogger().Logf("graphs", "failed to write %s: %v", metricsHistoryFile, err)
+	}
+}
+
+// ParseHistoryRange parses a range such as "15m", "6h" or "7d".
+func ParseHistoryRange(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		d, err := time.ParseDuration(days + "h")
+		return d * 24, err
+	}
+	return time.ParseDuration(s)
+}
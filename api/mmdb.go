@@ -0,0 +1,276 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"os"
+)
+
+// mmdbMetadataMarker starts the metadata section at the end of a MaxMind DB file.
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// mmdbDataSeparator is the gap of zero bytes between the search tree and the data section.
+const mmdbDataSeparator = 16
+
+// MMDBReader looks up IP addresses in a MaxMind DB file (GeoLite2, DB-IP and the like).
+type MMDBReader struct {
+	DatabaseType string
+	buf          []byte
+	data         []byte // Data section
+	nodeCount    uint
+	recordSize   uint
+	ipVersion    uint
+	ipv4Start    uint // Node reached after the 96 zero bits of an IPv4-mapped address
+}
+
+// OpenMMDB reads a MaxMind DB file into memory.
+func OpenMMDB(path string) (*MMDBReader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewMMDBReader(buf)
+}
+
+// NewMMDBReader parses a MaxMind DB file.
+func NewMMDBReader(buf []byte) (*MMDBReader, error) {
+	i := bytes.LastIndex(buf, mmdbMetadataMarker)
+	if i < 0 {
+		return nil, errors.New("mmdb: metadata not found")
+	}
+	meta := buf[i+len(mmdbMetadataMarker):]
+	value, _, err := mmdbDecode(meta, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("mmdb: invalid metadata: %w", err)
+	}
+	m, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("mmdb: invalid metadata")
+	}
+
+	r := &MMDBReader{buf: buf}
+	r.DatabaseType, _ = m["database_type"].(string)
+	nodeCount, _ := m["node_count"].(uint64)
+	recordSize, _ := m["record_size"].(uint64)
+	ipVersion, _ := m["ip_version"].(uint64)
+	r.nodeCount, r.recordSize, r.ipVersion = uint(nodeCount), uint(recordSize), uint(ipVersion)
+	switch r.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("mmdb: unsupported record size %d", r.recordSize)
+	}
+
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+mmdbDataSeparator > uint(i) {
+		return nil, errors.New("mmdb: search tree larger than the file")
+	}
+	r.data = buf[treeSize+mmdbDataSeparator : i]
+
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for n := 0; n < 96 && node < r.nodeCount; n++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of a search tree node.
+func (r *MMDBReader) record(node, bit uint) uint {
+	switch r.recordSize {
+	case 24:
+		b := r.buf[node*6+bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := r.buf[node*7:]
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	}
+	return uint(binary.BigEndian.Uint32(r.buf[node*8+bit*4:]))
+}
+
+// Lookup returns the record of an IP address, or nil when the database has none.
+func (r *MMDBReader) Lookup(ip net.IP) (map[string]any, error) {
+	node := uint(0)
+	addr := ip.To16()
+	if v4 := ip.To4(); v4 != nil {
+		addr = v4
+		if r.ipVersion == 6 {
+			node = r.ipv4Start
+		}
+	} else if r.ipVersion == 4 {
+		return nil, errors.New("mmdb: IPv6 lookup in an IPv4 database")
+	}
+	if addr == nil {
+		return nil, errors.New("mmdb: invalid IP address")
+	}
+
+	for i := 0; i < len(addr)*8 && node < r.nodeCount; i++ {
+		bit := uint(addr[i/8]>>(7-i%8)) & 1
+		node = r.record(node, bit)
+	}
+	if node == r.nodeCount {
+		return nil, nil
+	}
+	if node < r.nodeCount {
+		return nil, errors.New("mmdb: invalid search tree")
+	}
+	offset := node - r.nodeCount - mmdbDataSeparator
+	if offset >= uint(len(r.data)) {
+		return nil, errors.New("mmdb: invalid data pointer")
+	}
+	value, _, err := mmdbDecode(r.data, offset, 0)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := value.(map[string]any)
+	return m, nil
+}
+
+// mmdbDecode decodes the data field at offset and returns it with the offset after it.
+// Maps become map[string]any, arrays []any and numbers uint64, int64 or float64.
+func mmdbDecode(data []byte, offset uint, depth int) (any, uint, error) {
+	if depth > 32 {
+		return nil, 0, errors.New("data nested too deeply")
+	}
+	if offset >= uint(len(data)) {
+		return nil, 0, errors.New("data offset out of range")
+	}
+	ctrl := data[offset]
+	offset++
+	kind := uint(ctrl >> 5)
+
+	if kind == 1 {
+		// Pointer into the data section; decoding continues after the pointer itself
+		ss, vvv := uint(ctrl>>3)&0x3, uint(ctrl&0x7)
+		n := ss + 1
+		if offset+n > uint(len(data)) {
+			return nil, 0, errors.New("pointer out of range")
+		}
+		b := data[offset : offset+n]
+		var p uint
+		switch ss {
+		case 0:
+			p = vvv<<8 | uint(b[0])
+		case 1:
+			p = (vvv<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+		case 2:
+			p = (vvv<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+		default:
+			p = uint(binary.BigEndian.Uint32(b))
+		}
+		value, _, err := mmdbDecode(data, p, depth+1)
+		return value, offset + n, err
+	}
+
+	if kind == 0 {
+		if offset >= uint(len(data)) {
+			return nil, 0, errors.New("extended type out of range")
+		}
+		kind = 7 + uint(data[offset])
+		offset++
+	}
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(data)) {
+			return nil, 0, errors.New("size out of range")
+		}
+		var extra uint
+		for _, c := range data[offset : offset+n] {
+			extra = extra<<8 | uint(c)
+		}
+		offset += n
+		switch size {
+		case 29:
+			size = 29 + extra
+		case 30:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+
+	switch kind {
+	case 7: // map
+		m := make(map[string]any, size)
+		for range size {
+			key, next, err := mmdbDecode(data, offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+			value, next, err := mmdbDecode(data, next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[k] = value
+			offset = next
+		}
+		return m, offset, nil
+	case 11: // array
+		a := make([]any, 0, size)
+		for range size {
+			value, next, err := mmdbDecode(data, offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case 14: // boolean, the size is the value
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(data)) {
+		return nil, 0, errors.New("value out of range")
+	}
+	b := data[offset : offset+size]
+	offset += size
+	switch kind {
+	case 2: // UTF-8 string
+		return string(b), offset, nil
+	case 3: // double
+		if size != 8 {
+			return nil, 0, errors.New("invalid double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case 4: // bytes
+		return append([]byte(nil), b...), offset, nil
+	case 5, 6, 9: // uint16, uint32, uint64
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, offset, nil
+	case 8: // int32
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		if size == 4 {
+			return int64(int32(v)), offset, nil
+		}
+		return int64(v), offset, nil
+	case 10: // uint128
+		return new(big.Int).SetBytes(b), offset, nil
+	case 15: // float
+		if size != 4 {
+			return nil, 0, errors.New("invalid float size")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), offset, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported data type %d", kind)
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Error budget of a module: it is degraded after moduleDegradedStreak failures in a row,
+// or when less than moduleDegradedRate of its last moduleHealthWindow fetches succeeded.
+const (
+	moduleHealthWindow   = 20
+	moduleDegradedRate   = 0.5
+	moduleDegradedStreak = 3
+	moduleDegradedMin    = 5 // Fetches needed before the rate counts
+)
+
+// moduleResponseLimit is how much of a response is kept to look for an error.
+const moduleResponseLimit = 64 << 10
+
+// ModuleHealth is the fetch record of one module.
+type ModuleHealth struct {
+	Module      string     `json:"module"`
+	Successes   int64      `json:"successes"` // Since startup
+	Failures    int64      `json:"failures"`
+	SuccessRate float64    `json:"successRate"` // Percent of the recent fetches
+	Streak      int        `json:"consecutiveFailures"`
+	LastError   string     `json:"lastError,omitempty"`
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	Degraded    bool       `json:"degraded"`
+	CircuitOpen bool       `json:"circuitOpen"`       // Paused: fetches fail at once until RetryAt
+	RetryAt     *time.Time `json:"retryAt,omitempty"` // When the next fetch is let through
+	recent      []bool
+	cooldown    time.Duration
+	retrying    bool // A fetch was let through the open circuit
+}
+
+// ModuleHealthTracker keeps the fetch record of every module and broadcasts when a
+// module becomes degraded or recovers.
+type ModuleHealthTracker struct {
+	mu      sync.Mutex
+	modules map[string]*ModuleHealth
+}
+
+var moduleHealth = &ModuleHealthTracker{modules: make(map[string]*ModuleHealth)}
+
+// GetModuleHealth returns the global module health tracker.
+func GetModuleHealth() *ModuleHealthTracker {
+	return moduleHealth
+}
+
+// Record counts a fetch of a module; err is nil on success.
+func (mt *ModuleHealthTracker) Record(module string, err error) {
+	now := time.Now()
+	mt.mu.Lock()
+	m, ok := mt.modules[module]
+	if !ok {
+		m = &ModuleHealth{Module: module}
+		mt.modules[module] = m
+	}
+	m.recent = append(m.recent, err == nil)
+	if len(m.recent) > moduleHealthWindow {
+		m.recent = m.recent[len(m.recent)-moduleHealthWindow:]
+	}
+	wasOpen := m.CircuitOpen
+	if err == nil {
+		m.Successes++
+		m.Streak = 0
+		m.LastSuccess = &now
+		m.CircuitOpen, m.RetryAt, m.cooldown, m.retrying = false, nil, 0, false
+	} else {
+		m.Failures++
+		m.Streak++
+		m.LastError = err.Error()
+		m.LastErrorAt = &now
+		failures, cooldown := GetModuleSandbox().limits()
+		if (!m.CircuitOpen && m.Streak >= failures) || m.retrying {
+			// Open the circuit, or keep it open for twice as long after a failed retry
+			m.cooldown = min(max(m.cooldown*2, cooldown), maxModuleCooldown)
+			retryAt := now.Add(m.cooldown)
+			m.CircuitOpen, m.RetryAt, m.retrying = true, &retryAt, false
+		}
+	}
+	succeeded := 0
+	for _, ok := range m.recent {
+		if ok {
+			succeeded++
+		}
+	}
+	m.SuccessRate = float64(succeeded) / float64(len(m.recent)) * 100
+	wasDegraded := m.Degraded
+	m.Degraded = m.CircuitOpen || m.Streak >= moduleDegradedStreak ||
+		(len(m.recent) >= moduleDegradedMin && m.SuccessRate < moduleDegradedRate*100)
+	changed := m.Degraded != wasDegraded || m.CircuitOpen != wasOpen
+	snapshot := *m
+	mt.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	switch {
+	case snapshot.CircuitOpen && !wasOpen:
+		Logger("modules").Warn("module paused after repeated failures", "module", module, "retryAt", snapshot.RetryAt.Format(time.RFC3339), "error", snapshot.LastError)
+	case snapshot.Degraded && !wasDegraded:
+		Logger("modules").Warn("module degraded", "module", module, "successRate", snapshot.SuccessRate, "error", snapshot.LastError)
+	case !snapshot.Degraded && wasDegraded:
+		Logger("modules").Info("module recovered", "module", module)
+	}
+	snapshot.recent = nil
+	GetWSManager().Broadcast(map[string]interface{}{
+		"type":   "module-health",
+		"module": module,
+		"health": snapshot,
+	})
+}
+
+// Allow tells whether a module may fetch. While its circuit is open it may not until
+// RetryAt; then one fetch is let through and the next retry moved a cooldown ahead, so a
+// retry that never reports back cannot keep the module paused.
+func (mt *ModuleHealthTracker) Allow(module string) (time.Time, bool) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	m, ok := mt.modules[module]
+	if !ok || !m.CircuitOpen {
+		return time.Time{}, true
+	}
+	now := time.Now()
+	if now.Before(*m.RetryAt) {
+		return *m.RetryAt, false
+	}
+	retryAt := now.Add(m.cooldown)
+	m.RetryAt, m.retrying = &retryAt, true
+	return time.Time{}, true
+}
+
+// Get returns the record of a module.
+func (mt *ModuleHealthTracker) Get(module string) (ModuleHealth, bool) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	m, ok := mt.modules[module]
+	if !ok {
+		return ModuleHealth{}, false
+	}
+	result := *m
+	result.recent = nil
+	return result, true
+}
+
+// All returns the record of every module that fetched since startup, by name.
+func (mt *ModuleHealthTracker) All() []ModuleHealth {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	result := make([]ModuleHealth, 0, len(mt.modules))
+	for _, m := range mt.modules {
+		h := *m
+		h.recent = nil
+		result = append(result, h)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Module < result[j].Module })
+	return result
+}
+
+// moduleErrorResponse is the part of a JSON response that tells whether it failed.
+type moduleErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// moduleRecorder keeps the status and the start of a response.
+type moduleRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (mr *moduleRecorder) WriteHeader(status int) {
+	if mr.status == 0 {
+		mr.status = status
+	}
+	mr.ResponseWriter.WriteHeader(status)
+}
+
+func (mr *moduleRecorder) Write(p []byte) (int, error) {
+	if mr.status == 0 {
+		mr.status = http.StatusOK
+	}
+	if room := moduleResponseLimit - mr.body.Len(); room > 0 {
+		mr.body.Write(p[:min(len(p), room)])
+	}
+	return mr.ResponseWriter.Write(p)
+}
+
+func (mr *moduleRecorder) Unwrap() http.ResponseWriter {
+	return mr.ResponseWriter
+}
+
+// ModuleTracked records each request of a module's endpoint in the module health: server
+// errors and JSON responses with a top-level "error" count as failures. The handler runs
+// sandboxed with the module's deadline and panic recovery; while the module is paused
+// after repeated failures requests fail at once with "circuitOpen".
+func ModuleTracked(module string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if retryAt, ok := GetModuleHealth().Allow(module); !ok {
+			WriteJSON(w, map[string]any{
+				"error":       module + " is paused after repeated failures, retrying at " + retryAt.Format(time.TimeOnly),
+				"circuitOpen": true,
+				"retryAt":     retryAt,
+			})
+			return
+		}
+		rec := &moduleRecorder{ResponseWriter: w}
+		start := time.Now()
+		if !serveSandboxed(module, rec, r, next) {
+			return
+		}
+		var err error
+		var resp moduleErrorResponse
+		if rec.status >= http.StatusInternalServerError {
+			err = errors.New(http.StatusText(rec.status))
+		} else if json.Unmarshal(rec.body.Bytes(), &resp) == nil && resp.Error != "" {
+			err = errors.New(resp.Error)
+		}
+		GetModuleHealth().Record(module, err)
+		GetUsageStats().RecordFetch(module, time.Since(start), err)
+	}
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// moduleInstanceSep separates the module type from the instance name in the ID of a module
+// instance: rss:news, disk:/mnt/media.
+const moduleInstanceSep = ":"
+
+// moduleInstanceStores are the storage keys holding the instances of the module types that
+// can be placed more than once: a list of objects, each the config of one instance.
+var moduleInstanceStores = map[string]string{
+	"disk":   "diskModules",
+	"github": "githubModules",
+	"rss":    "rssModules",
+}
+
+// moduleInstanceNamePattern matches instance names. Mount points keep their slashes.
+var moduleInstanceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.~/-]{1,64}$`)
+
+// ErrModuleInstanceNotFound is returned when an instance ID is not in its type's store.
+var ErrModuleInstanceNotFound = errors.New("module instance not found")
+
+// ModuleInstance is one card of a module type that can be placed more than once.
+type ModuleInstance struct {
+	ID       string         `json:"id"`
+	Type     string         `json:"type"`
+	Name     string         `json:"name"`
+	Enabled  bool           `json:"enabled"`
+	Interval int64          `json:"interval,omitempty"` // Own refresh interval in seconds, 0 to follow the type's
+	Legacy   bool           `json:"legacy,omitempty"`   // ID from before instance IDs, e.g. rss-1712345678
+	Config   map[string]any `json:"config"`
+}
+
+// ModuleInstanceID returns the ID of an instance of a module type.
+func ModuleInstanceID(moduleType, name string) string {
+	return moduleType + moduleInstanceSep + name
+}
+
+// ParseModuleID splits a module ID into the module type and the instance name, which is ""
+// for modules placed once. IDs of instances created before instance IDs (rss-1712345678,
+// disk-_mnt_media) are instances of their type too.
+func ParseModuleID(id string) (string, string) {
+	if moduleType, name, ok := strings.Cut(id, moduleInstanceSep); ok {
+		return moduleType, name
+	}
+	if _, known := GetModuleMetadata()[id]; known {
+		return id, ""
+	}
+	for moduleType := range moduleInstanceStores {
+		if name, ok := strings.CutPrefix(id, moduleType+"-"); ok && name != "" {
+			return moduleType, name
+		}
+	}
+	return id, ""
+}
+
+// ModuleType returns the module type of a module or instance ID.
+func ModuleType(id string) string {
+	moduleType, _ := ParseModuleID(id)
+	return moduleType
+}
+
+// isModuleInstanceID reports whether id names an instance rather than a module placed once.
+func isModuleInstanceID(id string) bool {
+	_, name := ParseModuleID(id)
+	return name != ""
+}
+
+// ValidModuleID checks the form of a module or instance ID: instances are only allowed for
+// types with Instances set, and their names are limited to letters, digits and _.~/-.
+// Whether the module type exists is left to the caller, as layouts may name modules of
+// older versions.
+func ValidModuleID(id string) error {
+	if id == "" {
+		return errors.New("module ID cannot be empty string")
+	}
+	moduleType, name, isInstance := strings.Cut(id, moduleInstanceSep)
+	if !isInstance {
+		return nil
+	}
+	meta, known := GetModuleMetadata()[moduleType]
+	if !known {
+		return fmt.Errorf("unknown module type %q", moduleType)
+	}
+	if !meta.Instances {
+		return fmt.Errorf("%s cannot be placed more than once", moduleType)
+	}
+	if !moduleInstanceNamePattern.MatchString(name) {
+		return fmt.Errorf("instance name %q must be 1-64 letters, digits or _.~/-", name)
+	}
+	return nil
+}
+
+// KnownModuleID reports whether id is a module of this version or an instance of one.
+func KnownModuleID(id string) bool {
+	meta, known := GetModuleMetadata()[ModuleType(id)]
+	return known && (!isModuleInstanceID(id) || meta.Instances)
+}
+
+// ModuleInstances returns the instances of a module type, or of every type for "", with
+// their own refresh intervals from the module preferences of a profile.
+func ModuleInstances(profile, moduleType string) []ModuleInstance {
+	var prefs map[string]any
+	GetStorage().GetAsForProfile(profile, "modulePrefs", &prefs)
+
+	types := make([]string, 0, len(moduleInstanceStores))
+	for t := range moduleInstanceStores {
+		if moduleType == "" || t == moduleType {
+			types = append(types, t)
+		}
+	}
+	slices.Sort(types)
+
+	instances := []ModuleInstance{}
+	for _, t := range types {
+		var configs []map[string]any
+		GetStorage().GetAsForProfile(profile, moduleInstanceStores[t], &configs)
+		for _, config := range configs {
+			id, _ := config["id"].(string)
+			if id == "" {
+				continue
+			}
+			inst := ModuleInstance{
+				ID:      id,
+				Type:    t,
+				Name:    moduleInstanceName(t, config),
+				Enabled: config["enabled"] != false,
+				Legacy:  !strings.Contains(id, moduleInstanceSep),
+				Config:  config,
+			}
+			if pref, ok := prefs[id].(map[string]any); ok {
+				if interval, ok := pref["interval"].(float64); ok {
+					inst.Interval = int64(interval)
+				}
+			}
+			instances = append(instances, inst)
+		}
+	}
+	return instances
+}
+
+// FindModuleInstance returns an instance of a profile by ID.
+func FindModuleInstance(profile, id string) (ModuleInstance, error) {
+	instances := ModuleInstances(profile, ModuleType(id))
+	i := slices.IndexFunc(instances, func(inst ModuleInstance) bool { return inst.ID == id })
+	if i < 0 {
+		return ModuleInstance{}, ErrModuleInstanceNotFound
+	}
+	return instances[i], nil
+}
+
+// moduleInstanceName returns the display name of an instance config.
+func moduleInstanceName(moduleType string, config map[string]any) string {
+	if name, _ := config["name"].(string); name != "" {
+		return name
+	}
+	if moduleType == "disk" {
+		if mount, _ := config["mountPoint"].(string); mount != "" && mount != "/" {
+			return "Disk " + mount
+		}
+		return "Disk"
+	}
+	return GetModuleMetadata()[moduleType].Name
+}
+
+// validateModuleInstances checks the instance IDs of a module type's store before it is
+// saved: each must be an instance of that type, and only once.
+func validateModuleInstances(moduleType string, value any) error {
+	list, ok := value.([]any)
+	if !ok {
+		return errors.New("expected a list of instances")
+	}
+	seen := make(map[string]bool, len(list))
+	for i, entry := range list {
+		config, ok := entry.(map[string]any)
+		if !ok {
+			return fmt.Errorf("instance %d: expected an object", i+1)
+		}
+		id, _ := config["id"].(string)
+		if err := ValidModuleID(id); err != nil {
+			return fmt.Errorf("instance %d: %w", i+1, err)
+		}
+		if t, name := ParseModuleID(id); t != moduleType || name == "" {
+			return fmt.Errorf("instance %d: %s is not a %s instance ID", i+1, id, moduleType)
+		}
+		if seen[id] {
+			return fmt.Errorf("instance %d: %s is used twice", i+1, id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// moduleInstanceStoreType returns the module type whose instances a storage key holds.
+func moduleInstanceStoreType(key string) (string, bool) {
+	for moduleType, storeKey := range moduleInstanceStores {
+		if storeKey == key {
+			return moduleType, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Defaults of the module sandbox.
+const (
+	defaultModuleTimeout  = 20 * time.Second
+	defaultModuleFailures = 5
+	defaultModuleCooldown = time.Minute
+	// An open circuit waits twice as long after each failed retry, up to this
+	maxModuleCooldown = 30 * time.Minute
+)
+
+// ModuleSandboxConfig configures the deadlines and circuit breaking of module fetches.
+type ModuleSandboxConfig struct {
+	Timeout  string            `json:"timeout,omitempty"`  // Deadline of one fetch, default "20s"
+	Timeouts map[string]string `json:"timeouts,omitempty"` // Per-module deadlines, e.g. {"snmp": "45s"}
+	Failures int               `json:"failures,omitempty"` // Failures in a row that pause a module, default 5
+	Cooldown string            `json:"cooldown,omitempty"` // First pause, doubled on each failed retry up to 30m, default "1m"
+}
+
+// Validate checks the durations and failure count.
+func (c ModuleSandboxConfig) Validate() error {
+	durations := map[string]string{"timeout": c.Timeout, "cooldown": c.Cooldown}
+	for module, timeout := range c.Timeouts {
+		durations["timeouts."+module] = timeout
+	}
+	for name, value := range durations {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d < time.Second {
+			return fmt.Errorf("moduleSandbox: %s must be a duration of at least 1s", name)
+		}
+	}
+	if c.Failures < 0 {
+		return fmt.Errorf("moduleSandbox: failures cannot be negative")
+	}
+	return nil
+}
+
+// errModulePanic is recorded when a module's fetch panicked.
+var errModulePanic = errors.New("internal error")
+
+// ModuleSandbox runs module fetches with a deadline and recovers their panics, so a hanging
+// or crashing integration only fails its own module.
+type ModuleSandbox struct {
+	mu       sync.RWMutex
+	timeout  time.Duration
+	timeouts map[string]time.Duration
+	failures int
+	cooldown time.Duration
+}
+
+// Global module sandbox instance with the default limits
+var moduleSandbox = &ModuleSandbox{
+	timeout:  defaultModuleTimeout,
+	failures: defaultModuleFailures,
+	cooldown: defaultModuleCooldown,
+}
+
+// GetModuleSandbox returns the global module sandbox instance.
+func GetModuleSandbox() *ModuleSandbox {
+	return moduleSandbox
+}
+
+// Configure replaces the deadlines and circuit breaker limits.
+func (ms *ModuleSandbox) Configure(cfg ModuleSandboxConfig) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if d, err := time.ParseDuration(cfg.Timeout); err == nil && d > 0 {
+		ms.timeout = d
+	}
+	ms.timeouts = make(map[string]time.Duration)
+	for module, value := range cfg.Timeouts {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			ms.timeouts[module] = d
+		}
+	}
+	if cfg.Failures > 0 {
+		ms.failures = cfg.Failures
+	}
+	if d, err := time.ParseDuration(cfg.Cooldown); err == nil && d > 0 {
+		ms.cooldown = d
+	}
+}
+
+// Timeout returns the deadline of one fetch of a module.
+func (ms *ModuleSandbox) Timeout(module string) time.Duration {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	if d, ok := ms.timeouts[module]; ok {
+		return d
+	}
+	return ms.timeout
+}
+
+// limits returns the failures that open a circuit and its first pause.
+func (ms *ModuleSandbox) limits() (int, time.Duration) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.failures, ms.cooldown
+}
+
+// logPanic logs a recovered panic with its stack.
+func logPanic(component string, value any) {
+	Logger(component).Error("recovered from panic", "panic", fmt.Sprint(value), "stack", string(debug.Stack()))
+}
+
+// Sandboxed runs fn for a module with the module's deadline, turning a panic into an error.
+// A paused module fails at once. When the deadline passes fn is left to finish in the
+// background and its result is dropped. The outcome is recorded in the module health.
+func Sandboxed[T any](ctx context.Context, module string, fn func(context.Context) (T, error)) (T, error) {
+	var zero T
+	if retryAt, ok := GetModuleHealth().Allow(module); !ok {
+		return zero, fmt.Errorf("%s is paused after repeated failures, retrying at %s", module, retryAt.Format(time.TimeOnly))
+	}
+	timeout := GetModuleSandbox().Timeout(module)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				logPanic(module, p)
+				done <- result{err: errModulePanic}
+			}
+		}()
+		value, err := fn(ctx)
+		done <- result{value, err}
+	}()
+
+	start := time.Now()
+	var res result
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		if err := context.Cause(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			// The caller gave up, which says nothing about the module
+			return zero, err
+		}
+		res.err = fmt.Errorf("timed out after %s", timeout)
+	}
+	GetModuleHealth().Record(module, res.err)
+	RecordTiming(ctx, module, time.Since(start), "")
+	return res.value, res.err
+}
+
+// serveSandboxed runs a module's handler with the module's deadline. The handler writes
+// into a held response that is only sent when it finishes in time and without a panic;
+// otherwise the client gets an error in the usual {"error": ...} form. It returns false
+// when the client went away before the handler finished.
+func serveSandboxed(module string, w http.ResponseWriter, r *http.Request, next http.HandlerFunc) bool {
+	timeout := GetModuleSandbox().Timeout(module)
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	held := &offlineRecorder{header: make(http.Header)}
+	done := make(chan bool, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				logPanic(module, p)
+				done <- false
+			}
+		}()
+		next(held, r.WithContext(ctx))
+		done <- true
+	}()
+
+	select {
+	case ok := <-done:
+		if ok {
+			held.flush(w)
+		} else {
+			WriteJSON(w, map[string]any{"error": module + " failed: " + errModulePanic.Error()})
+		}
+	case <-ctx.Done():
+		if r.Context().Err() != nil {
+			return false
+		}
+		GetDebugLogger().Logf("modules", "%s %s timed out after %s", module, r.URL.Path, timeout)
+		WriteJSON(w, map[string]any{"error": fmt.Sprintf("%s timed out after %s", module, timeout)})
+	}
+	return true
+}
+
+// guardLoop runs one step of a background loop, recovering a panic so the loop keeps going.
+func guardLoop(component string, step func()) {
+	defer func() {
+		if p := recover(); p != nil {
+			logPanic(component, p)
+		}
+	}()
+	step()
+}
@@ -0,0 +1,336 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Field types of a module config schema. They match the field types of the module edit
+// dialog, so a schema can be rendered as a form as is.
+const (
+	ModuleFieldText     = "text"
+	ModuleFieldNumber   = "number"
+	ModuleFieldSelect   = "select"
+	ModuleFieldCheckbox = "checkbox"
+)
+
+// Formats a text field can be checked against.
+const (
+	ModuleFormatURL      = "url"       // URL, host name or IP, see IsValidURLOrIP
+	ModuleFormatHTTP     = "http"      // http:// or https:// URL
+	ModuleFormatIconSlug = "icon-slug" // Dashboard icon slug, see IsValidIconSlug
+)
+
+// ModuleFieldOption is one choice of a select field.
+type ModuleFieldOption struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// ModuleFieldCondition limits a field to configs where another field has one of the values,
+// e.g. the port of a monitor only applies to port checks.
+type ModuleFieldCondition struct {
+	Field  string   `json:"field"`
+	Values []string `json:"values"`
+}
+
+// ModuleConfigField describes one field of a module config.
+type ModuleConfigField struct {
+	ID          string                `json:"id"`
+	Label       string                `json:"label"`
+	Type        string                `json:"type"`
+	Required    bool                  `json:"required,omitempty"`
+	Default     any                   `json:"default,omitempty"`
+	Min         float64               `json:"min,omitempty"` // Number range, checked when Max > Min
+	Max         float64               `json:"max,omitempty"`
+	Options     []ModuleFieldOption   `json:"options,omitempty"`
+	Placeholder string                `json:"placeholder,omitempty"`
+	Format      string                `json:"format,omitempty"`
+	When        *ModuleFieldCondition `json:"when,omitempty"`
+}
+
+// ModuleSchema describes the config of a module type: its fields, and the storage key the
+// configs are kept under. List is set when the key holds a list of configs rather than one.
+type ModuleSchema struct {
+	Type       string              `json:"type"`
+	StorageKey string              `json:"storageKey,omitempty"`
+	List       bool                `json:"list,omitempty"`
+	Fields     []ModuleConfigField `json:"fields"`
+}
+
+var (
+	moduleSchemasMu sync.RWMutex
+	moduleSchemas   = indexModuleSchemas(builtinModuleSchemas())
+)
+
+// builtinModuleSchemas returns the config schemas of the modules that ship with homepage.
+func builtinModuleSchemas() []ModuleSchema {
+	return []ModuleSchema{
+		{Type: "rss", StorageKey: "rssModules", List: true, Fields: []ModuleConfigField{
+			{ID: "name", Label: "Name", Type: ModuleFieldText, Placeholder: "e.g., Tech News"},
+			{ID: "url", Label: "Feed URL", Type: ModuleFieldText, Required: true, Format: ModuleFormatURL, Placeholder: "https://example.com/feed.xml"},
+			{ID: "maxItems", Label: "Articles", Type: ModuleFieldNumber, Default: 5, Min: 1, Max: 20},
+			{ID: "showTitle", Label: "Show Title", Type: ModuleFieldCheckbox, Default: true},
+			{ID: "showText", Label: "Show Text", Type: ModuleFieldCheckbox, Default: true},
+			{ID: "showDate", Label: "Show Date", Type: ModuleFieldCheckbox, Default: true},
+		}},
+		{Type: "disk", StorageKey: "diskModules", List: true, Fields: []ModuleConfigField{
+			{ID: "mountPoint", Label: "Mount point", Type: ModuleFieldText, Required: true, Placeholder: "/"},
+		}},
+		{Type: "github", StorageKey: "githubModules", List: true, Fields: []ModuleConfigField{
+			{ID: "url", Label: "GitHub URL", Type: ModuleFieldText, Required: true, Placeholder: "https://github.com/user"},
+			{ID: "accountType", Label: "Account Type", Type: ModuleFieldSelect, Default: "user", Options: []ModuleFieldOption{
+				{Value: "user", Label: "User"}, {Value: "org", Label: "Organization"}, {Value: "repo", Label: "Repository"},
+			}},
+			{ID: "displayType", Label: "Display", Type: ModuleFieldSelect, Default: "repos", Options: []ModuleFieldOption{
+				{Value: "repos", Label: "Repositories"}, {Value: "prs", Label: "Pull Requests"}, {Value: "commits", Label: "Commits"},
+				{Value: "issues", Label: "Issues"}, {Value: "stats", Label: "Stats"},
+			}},
+			{ID: "maxItems", Label: "Items", Type: ModuleFieldNumber, Default: 5, Min: 1, Max: 20},
+			{ID: "sort", Label: "Sort By", Type: ModuleFieldSelect, Default: "created", Options: []ModuleFieldOption{
+				{Value: "created", Label: "Created Date"}, {Value: "updated", Label: "Updated Date"}, {Value: "pushed", Label: "Last Push"},
+				{Value: "full_name", Label: "Name"}, {Value: "popularity", Label: "Popularity"}, {Value: "long-running", Label: "Long Running"},
+				{Value: "date", Label: "Commit Date"}, {Value: "comments", Label: "Comments"},
+			}},
+			{ID: "order", Label: "Order", Type: ModuleFieldSelect, Default: "desc", Options: []ModuleFieldOption{
+				{Value: "desc", Label: "Descending"}, {Value: "asc", Label: "Ascending"},
+			}},
+		}},
+		{Type: "monitoring", StorageKey: "monitors", List: true, Fields: []ModuleConfigField{
+			{ID: "name", Label: "Name", Type: ModuleFieldText, Required: true, Placeholder: "e.g., Web Server"},
+			{ID: "type", Label: "Type", Type: ModuleFieldSelect, Required: true, Default: "http", Options: []ModuleFieldOption{
+				{Value: "http", Label: "HTTP Check"}, {Value: "port", Label: "Port Check"}, {Value: "ping", Label: "Ping"},
+			}},
+			{ID: "url", Label: "URL", Type: ModuleFieldText, Required: true, Format: ModuleFormatHTTP, Placeholder: "e.g., https://example.com",
+				When: &ModuleFieldCondition{Field: "type", Values: []string{"http"}}},
+			{ID: "host", Label: "Host", Type: ModuleFieldText, Required: true, Placeholder: "e.g., 192.168.1.1",
+				When: &ModuleFieldCondition{Field: "type", Values: []string{"port", "ping"}}},
+			{ID: "port", Label: "Port", Type: ModuleFieldNumber, Required: true, Min: 1, Max: 65535, Placeholder: "e.g., 443",
+				When: &ModuleFieldCondition{Field: "type", Values: []string{"port"}}},
+			{ID: "iconSlug", Label: "Dashboard icon", Type: ModuleFieldText, Format: ModuleFormatIconSlug, Placeholder: "e.g., proxmox (instead of the favicon)"},
+		}},
+		{Type: "snmp", StorageKey: "snmpQueries", List: true, Fields: []ModuleConfigField{
+			{ID: "title", Label: "Title", Type: ModuleFieldText, Placeholder: "e.g., Router Uptime"},
+			{ID: "host", Label: "Host", Type: ModuleFieldText, Required: true, Placeholder: "192.168.1.1"},
+			{ID: "port", Label: "Port", Type: ModuleFieldNumber, Min: 1, Max: 65535},
+			{ID: "community", Label: "Community", Type: ModuleFieldText, Placeholder: "public"},
+			{ID: "profile", Label: "Credential Profile", Type: ModuleFieldText, Placeholder: "Saved profile (SNMPv3), instead of community"},
+			{ID: "oid", Label: "OID", Type: ModuleFieldText, Required: true, Placeholder: "1.3.6.1.2.1.1.3.0"},
+			{ID: "displayType", Label: "Display Type", Type: ModuleFieldSelect, Default: "show", Options: []ModuleFieldOption{
+				{Value: "show", Label: "Show"}, {Value: "diff", Label: "Diff"}, {Value: "period-diff", Label: "Period Diff"},
+				{Value: "mbps", Label: "Rate (Mbps)"}, {Value: "interface", Label: "Interface Traffic (OID = interface name or index)"},
+			}},
+			{ID: "prefix", Label: "Prefix", Type: ModuleFieldText, Placeholder: "e.g., Speed: "},
+			{ID: "suffix", Label: "Suffix", Type: ModuleFieldText, Placeholder: "e.g., bps"},
+			{ID: "divisor", Label: "Divisor", Type: ModuleFieldNumber, Min: 1, Max: 1e12},
+			{ID: "siUnits", Label: "SI units", Type: ModuleFieldCheckbox},
+		}},
+		{Type: "speedplane", StorageKey: "speedplaneConfig", Fields: []ModuleConfigField{
+			{ID: "name", Label: "Name", Type: ModuleFieldText},
+			{ID: "host", Label: "Host", Type: ModuleFieldText, Required: true, Placeholder: "192.168.1.1"},
+			{ID: "port", Label: "Port", Type: ModuleFieldNumber, Required: true, Min: 1, Max: 65535},
+		}},
+		{Type: "dnsplane", StorageKey: "dnsplaneConfig", Fields: []ModuleConfigField{
+			{ID: "name", Label: "Name", Type: ModuleFieldText, Placeholder: "e.g. Home DNS"},
+			{ID: "host", Label: "Host", Type: ModuleFieldText, Required: true, Placeholder: "192.168.1.1"},
+			{ID: "port", Label: "Port", Type: ModuleFieldNumber, Required: true, Min: 1, Max: 65535},
+		}},
+		{Type: "quicklinks", StorageKey: "quicklinks", List: true, Fields: []ModuleConfigField{
+			{ID: "title", Label: "Title", Type: ModuleFieldText, Required: true, Placeholder: "e.g., Router"},
+			{ID: "url", Label: "URL", Type: ModuleFieldText, Required: true, Format: ModuleFormatURL, Placeholder: "e.g., 192.168.1.1 or https://example.com"},
+			{ID: "icon", Label: "Icon", Type: ModuleFieldText},
+			{ID: "iconSlug", Label: "Dashboard icon", Type: ModuleFieldText, Format: ModuleFormatIconSlug, Placeholder: "e.g., proxmox (overrides the icon)"},
+		}},
+	}
+}
+
+// indexModuleSchemas maps schemas by module type.
+func indexModuleSchemas(schemas []ModuleSchema) map[string]ModuleSchema {
+	index := make(map[string]ModuleSchema, len(schemas))
+	for _, schema := range schemas {
+		index[schema.Type] = schema
+	}
+	return index
+}
+
+// RegisterModuleSchema adds the config schema of a module type, so its configs are
+// validated like those of the built-in modules and its edit form can be rendered from
+// /api/modules/schema. A module type can only be registered once.
+func RegisterModuleSchema(schema ModuleSchema) error {
+	if schema.Type == "" {
+		return errors.New("module schema needs a type")
+	}
+	seen := make(map[string]bool, len(schema.Fields))
+	for _, field := range schema.Fields {
+		if field.ID == "" {
+			return fmt.Errorf("%s: field without ID", schema.Type)
+		}
+		if seen[field.ID] {
+			return fmt.Errorf("%s: field %s is declared twice", schema.Type, field.ID)
+		}
+		seen[field.ID] = true
+		switch field.Type {
+		case ModuleFieldText, ModuleFieldNumber, ModuleFieldCheckbox:
+		case ModuleFieldSelect:
+			if len(field.Options) == 0 {
+				return fmt.Errorf("%s: select field %s has no options", schema.Type, field.ID)
+			}
+		default:
+			return fmt.Errorf("%s: field %s has unknown type %q", schema.Type, field.ID, field.Type)
+		}
+	}
+	for _, field := range schema.Fields {
+		if field.When != nil && !seen[field.When.Field] {
+			return fmt.Errorf("%s: field %s depends on unknown field %s", schema.Type, field.ID, field.When.Field)
+		}
+	}
+
+	moduleSchemasMu.Lock()
+	defer moduleSchemasMu.Unlock()
+	if _, exists := moduleSchemas[schema.Type]; exists {
+		return fmt.Errorf("module schema %s is already registered", schema.Type)
+	}
+	moduleSchemas[schema.Type] = schema
+	return nil
+}
+
+// ModuleSchemaFor returns the config schema of a module type.
+func ModuleSchemaFor(moduleType string) (ModuleSchema, bool) {
+	moduleSchemasMu.RLock()
+	defer moduleSchemasMu.RUnlock()
+	schema, ok := moduleSchemas[moduleType]
+	return schema, ok
+}
+
+// ModuleSchemas returns the config schemas of all module types, sorted by type.
+func ModuleSchemas() []ModuleSchema {
+	moduleSchemasMu.RLock()
+	defer moduleSchemasMu.RUnlock()
+	schemas := make([]ModuleSchema, 0, len(moduleSchemas))
+	for _, moduleType := range slices.Sorted(maps.Keys(moduleSchemas)) {
+		schemas = append(schemas, moduleSchemas[moduleType])
+	}
+	return schemas
+}
+
+// Defaults returns the default values of the fields that have one, the config of a new
+// module before the user fills it in.
+func (s ModuleSchema) Defaults() map[string]any {
+	defaults := map[string]any{}
+	for _, field := range s.Fields {
+		if field.Default != nil {
+			defaults[field.ID] = field.Default
+		}
+	}
+	return defaults
+}
+
+// Validate checks a config against the schema. Fields the schema does not declare are left
+// alone, as are fields whose condition does not hold. Numbers may be given as strings, as
+// form inputs return them.
+func (s ModuleSchema) Validate(config map[string]any) error {
+	for _, field := range s.Fields {
+		if field.When != nil && !slices.Contains(field.When.Values, moduleConfigString(config[field.When.Field])) {
+			continue
+		}
+		value, set := config[field.ID]
+		if str, ok := value.(string); ok && strings.TrimSpace(str) == "" {
+			set = false
+		}
+		if !set || value == nil {
+			if field.Required {
+				return fmt.Errorf("%s is required", field.Label)
+			}
+			continue
+		}
+		if err := field.validate(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks a value that is set against the field's type, options, range and format.
+func (f ModuleConfigField) validate(value any) error {
+	switch f.Type {
+	case ModuleFieldCheckbox:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be true or false", f.Label)
+		}
+	case ModuleFieldNumber:
+		n, ok := moduleConfigNumber(value)
+		if !ok {
+			return fmt.Errorf("%s must be a number", f.Label)
+		}
+		if f.Max > f.Min && (n < f.Min || n > f.Max) {
+			return fmt.Errorf("%s must be between %s and %s", f.Label, formatSchemaNumber(f.Min), formatSchemaNumber(f.Max))
+		}
+	case ModuleFieldSelect:
+		str := moduleConfigString(value)
+		if !slices.ContainsFunc(f.Options, func(o ModuleFieldOption) bool { return o.Value == str }) {
+			values := make([]string, len(f.Options))
+			for i, o := range f.Options {
+				values[i] = o.Value
+			}
+			return fmt.Errorf("%s must be one of %s", f.Label, strings.Join(values, ", "))
+		}
+	default:
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be text", f.Label)
+		}
+		switch f.Format {
+		case ModuleFormatURL:
+			if !IsValidURLOrIP(str) {
+				return fmt.Errorf("%s is not a valid URL", f.Label)
+			}
+		case ModuleFormatHTTP:
+			if !strings.HasPrefix(str, "http://") && !strings.HasPrefix(str, "https://") {
+				return fmt.Errorf("%s must start with http:// or https://", f.Label)
+			}
+		case ModuleFormatIconSlug:
+			if !IsValidIconSlug(str) {
+				return fmt.Errorf("%s must be lowercase letters, digits and dashes, e.g. home-assistant", f.Label)
+			}
+		}
+	}
+	return nil
+}
+
+// moduleConfigNumber reads a number from a decoded JSON value or a numeric string.
+func moduleConfigNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// moduleConfigString returns a select value as the string its options are compared with.
+func moduleConfigString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return formatSchemaNumber(v)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+func formatSchemaNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
@@ -9,6 +9,7 @@ type ModuleMetadata struct {
 	TimerKey      string `json:"timerKey,omitempty"`
 	DefaultInterval int  `json:"defaultInterval,omitempty"`
 	Enabled       bool   `json:"enabled"` // Default enabled state (user can override in localStorage)
+	Instances     bool   `json:"instances,omitempty"` // Can be placed more than once, each instance with its own config (see ModuleInstance)
 }
 
 // GetModuleMetadata returns metadata for all available modules.
@@ -100,6 +101,7 @@ func GetModuleMetadata() map[string]ModuleMetadata {
 			TimerKey:       "disk",
 			DefaultInterval: 15,
 			Enabled:        true,
+			Instances:      true,
 		},
 		"links": {
 			Name:     "Quick Links",
@@ -134,6 +136,7 @@ func GetModuleMetadata() map[string]ModuleMetadata {
 			TimerKey:        "github",
 			DefaultInterval: 300,
 			Enabled:         true,
+			Instances:       true,
 		},
 		"rss": {
 			Name:            "RSS",
@@ -143,6 +146,7 @@ func GetModuleMetadata() map[string]ModuleMetadata {
 			TimerKey:        "rss",
 			DefaultInterval: 300,
 			Enabled:         true,
+			Instances:       true,
 		},
 		"calendar": {
 			Name:     "Calendar",
@@ -172,6 +176,20 @@ func GetModuleMetadata() map[string]ModuleMetadata {
 			HasTimer: false,
 			Enabled:  true,
 		},
+		"notes": {
+			Name:     "Notes",
+			Icon:     "fa-sticky-note",
+			Desc:     "Markdown notes kept on the server and shared by every device",
+			HasTimer: false,
+			Enabled:  true,
+		},
+		"drop": {
+			Name:     "Drop",
+			Icon:     "fa-exchange-alt",
+			Desc:     "Text and files shared between the devices on the LAN until they expire",
+			HasTimer: false,
+			Enabled:  true,
+		},
 		"speedplane": {
 			Name:            "Speedplane",
 			Icon:            "fa-tachometer-alt",
@@ -190,6 +208,132 @@ func GetModuleMetadata() map[string]ModuleMetadata {
 			DefaultInterval: 60,
 			Enabled:         true,
 		},
+		"presence": {
+			Name:            "Presence",
+			Icon:            "fa-house-user",
+			Desc:            "Who is home, from Home Assistant or OwnTracks",
+			HasTimer:        true,
+			TimerKey:        "presence",
+			DefaultInterval: 60,
+			Enabled:         true,
+		},
+		"guestwifi": {
+			Name:            "Guest Wi-Fi",
+			Icon:            "fa-wifi",
+			Desc:            "Guest network credentials and QR code for visitors",
+			HasTimer:        true,
+			TimerKey:        "guestwifi",
+			DefaultInterval: 300,
+			Enabled:         true,
+		},
+		"router": {
+			Name:            "Router",
+			Icon:            "fa-network-wired",
+			Desc:            "OpenWrt WAN status, DHCP leases and wireless clients",
+			HasTimer:        true,
+			TimerKey:        "router",
+			DefaultInterval: 60,
+			Enabled:         true,
+		},
+		"virt": {
+			Name:            "Virtualization",
+			Icon:            "fa-server",
+			Desc:            "Proxmox VE and libvirt virtual machines and containers",
+			HasTimer:        true,
+			TimerKey:        "virt",
+			DefaultInterval: 60,
+			Enabled:         true,
+		},
+		"ups": {
+			Name:            "UPS",
+			Icon:            "fa-car-battery",
+			Desc:            "Battery charge, runtime and load of NUT and apcupsd UPSes",
+			HasTimer:        true,
+			TimerKey:        "ups",
+			DefaultInterval: 30,
+			Enabled:         true,
+		},
+		"climate": {
+			Name:            "Climate",
+			Icon:            "fa-thermometer-half",
+			Desc:            "Temperature and humidity per room with today's range and comfort",
+			HasTimer:        true,
+			TimerKey:        "climate",
+			DefaultInterval: 60,
+			Enabled:         true,
+		},
+		"astro": {
+			Name:            "Night Sky",
+			Icon:            "fa-satellite",
+			Desc:            "ISS passes and visible planets at the weather location",
+			HasTimer:        true,
+			TimerKey:        "astro",
+			DefaultInterval: 900,
+			Enabled:         true,
+		},
+		"garden": {
+			Name:            "Garden",
+			Icon:            "fa-seedling",
+			Desc:            "Whether to water the garden, from recent rain and the forecast",
+			HasTimer:        true,
+			TimerKey:        "garden",
+			DefaultInterval: 1800,
+			Enabled:         true,
+		},
+		"shares": {
+			Name:            "Shares",
+			Icon:            "fa-folder-open",
+			Desc:            "Capacity and reachability of NFS and SMB shares",
+			HasTimer:        true,
+			TimerKey:        "shares",
+			DefaultInterval: 60,
+			Enabled:         true,
+		},
+		"oob": {
+			Name:            "Servers",
+			Icon:            "fa-power-off",
+			Desc:            "Power state, sensors and event log of servers via Redfish or IPMI",
+			HasTimer:        true,
+			TimerKey:        "oob",
+			DefaultInterval: 60,
+			Enabled:         true,
+		},
+		"command": {
+			Name:            "Commands",
+			Icon:            "fa-terminal",
+			Desc:            "Output of whitelisted commands such as zpool status or sensors",
+			HasTimer:        true,
+			TimerKey:        "command",
+			DefaultInterval: 60,
+			Enabled:         true,
+		},
+		"mqtt": {
+			Name:            "MQTT",
+			Icon:            "fa-broadcast-tower",
+			Desc:            "Sensor values from an MQTT broker (Home Assistant, Tasmota)",
+			HasTimer:        true,
+			TimerKey:        "mqtt",
+			DefaultInterval: 60,
+			Enabled:         true,
+		},
+		"quotes": {
+			Name:            "Quotes",
+			Icon:            "fa-chart-line",
+			Desc:            "Stock and cryptocurrency prices with their daily change",
+			HasTimer:        true,
+			TimerKey:        "quotes",
+			DefaultInterval: 300,
+			Enabled:         true,
+		},
+		"currency": {
+			Name:            "Currency",
+			Icon:            "fa-money-bill-wave",
+			Desc:            "Exchange rates and a currency converter",
+			HasTimer:        true,
+			TimerKey:        "currency",
+			DefaultInterval: 3600,
+			Enabled:         true,
+		},
 		"worldclock": {
 			Name:     "World clock",
 			Icon:     "fa-globe",
@@ -197,5 +341,26 @@ func GetModuleMetadata() map[string]ModuleMetadata {
 			HasTimer: false,
 			Enabled:  true,
 		},
+		"countdown": {
+			Name:     "Countdown",
+			Icon:     "fa-hourglass-half",
+			Desc:     "Time left until dates and calendar events, in any time zone",
+			HasTimer: false,
+			Enabled:  true,
+		},
+		"embed": {
+			Name:     "Embed",
+			Icon:     "fa-window-maximize",
+			Desc:     "Framed pages and sanitized HTML or Markdown snippets for services without an API",
+			HasTimer: false,
+			Enabled:  true,
+		},
+		"tools": {
+			Name:     "Tools",
+			Icon:     "fa-toolbox",
+			Desc:     "Password generator, text hashing and encoding, time and cron conversion, JSON and YAML formatting and an HTTP request tester",
+			HasTimer: false,
+			Enabled:  true,
+		},
 	}
 }
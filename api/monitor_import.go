@@ -0,0 +1,336 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxImportedMonitors caps the monitors created by one import.
+const maxImportedMonitors = 500
+
+// importHTTPPorts are open ports that get an HTTP monitor instead of a port monitor.
+var importHTTPPorts = map[int]string{80: "http", 8080: "http", 8000: "http", 443: "https", 8443: "https"}
+
+// ImportedMonitor is a monitor created by an import, in the format the dashboard stores.
+type ImportedMonitor struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"` // http, port or ping
+	URL  string `json:"url,omitempty"`
+	Host string `json:"host,omitempty"`
+	Port int    `json:"port,omitempty"`
+	// Service and Module are the recognized web service and the dashboard module for it
+	Service string `json:"service,omitempty"`
+	Module  string `json:"module,omitempty"`
+	named   bool   // Name given in the import, kept over the recognized service
+}
+
+// MonitorImportResult describes the outcome of an import.
+type MonitorImportResult struct {
+	Format  string            `json:"format"` // nmap or csv
+	Added   []ImportedMonitor `json:"added"`
+	Skipped []string          `json:"skipped"` // Entries not imported, with the reason
+}
+
+// nmapRun is the part of nmap's XML output (-oX) the import reads.
+type nmapRun struct {
+	Hosts []struct {
+		Status struct {
+			State string `xml:"state,attr"`
+		} `xml:"status"`
+		Addresses []struct {
+			Addr     string `xml:"addr,attr"`
+			AddrType string `xml:"addrtype,attr"`
+		} `xml:"address"`
+		Hostnames []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"hostnames>hostname"`
+		Ports []struct {
+			Protocol string `xml:"protocol,attr"`
+			PortID   int    `xml:"portid,attr"`
+			State    struct {
+				State string `xml:"state,attr"`
+			} `xml:"state"`
+			Service struct {
+				Name   string `xml:"name,attr"`
+				Tunnel string `xml:"tunnel,attr"`
+			} `xml:"service"`
+		} `xml:"ports>port"`
+	} `xml:"host"`
+}
+
+// ParseMonitorImport turns an nmap XML scan or a CSV of hosts into monitors. CSV rows are
+// host:port, host (ping) or a URL (HTTP), or columns named by a header row with name,
+// host, port, type and url.
+func ParseMonitorImport(data []byte) (MonitorImportResult, error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		result, err := parseNmapImport(trimmed)
+		result.Format = "nmap"
+		return result, err
+	}
+	result, err := parseCSVImport(trimmed)
+	result.Format = "csv"
+	return result, err
+}
+
+// parseNmapImport creates a monitor per open TCP port of every host that is up, or a ping
+// monitor for hosts without open ports.
+func parseNmapImport(data []byte) (MonitorImportResult, error) {
+	result := MonitorImportResult{Added: []ImportedMonitor{}, Skipped: []string{}}
+	var run nmapRun
+	if err := xml.Unmarshal(data, &run); err != nil {
+		return result, fmt.Errorf("invalid nmap XML: %w", err)
+	}
+	for _, h := range run.Hosts {
+		var addr string
+		for _, a := range h.Addresses {
+			if a.AddrType == "ipv4" || (a.AddrType == "ipv6" && addr == "") {
+				addr = a.Addr
+			}
+		}
+		if addr == "" {
+			continue
+		}
+		label := addr
+		if len(h.Hostnames) > 0 && h.Hostnames[0].Name != "" {
+			label = h.Hostnames[0].Name
+		}
+		if h.Status.State != "" && h.Status.State != "up" {
+			result.Skipped = append(result.Skipped, label+": host is "+h.Status.State)
+			continue
+		}
+		open := 0
+		for _, p := range h.Ports {
+			if p.Protocol != "tcp" || p.State.State != "open" {
+				continue
+			}
+			open++
+			service := p.Service.Name
+			if p.Service.Tunnel == "ssl" && service == "http" {
+				service = "https"
+			}
+			result.Added = append(result.Added, importedPortMonitor(label, addr, p.PortID, service))
+		}
+		if open == 0 {
+			result.Added = append(result.Added, ImportedMonitor{Name: label, Type: "ping", Host: addr})
+		}
+	}
+	return result, nil
+}
+
+// importedPortMonitor creates an HTTP monitor for web ports and a port monitor otherwise.
+func importedPortMonitor(label, host string, port int, service string) ImportedMonitor {
+	scheme := importHTTPPorts[port]
+	if service == "http" || service == "https" {
+		scheme = service
+	}
+	name := net.JoinHostPort(label, strconv.Itoa(port))
+	if service != "" && service != "unknown" {
+		name += " (" + service + ")"
+	}
+	if scheme != "" {
+		u := scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port)) + "/"
+		if (scheme == "http" && port == 80) || (scheme == "https" && port == 443) {
+			u = scheme + "://" + host + "/"
+			if strings.Contains(host, ":") {
+				u = scheme + "://[" + host + "]/"
+			}
+		}
+		return ImportedMonitor{Name: name, Type: "http", URL: u}
+	}
+	return ImportedMonitor{Name: name, Type: "port", Host: host, Port: port}
+}
+
+// parseCSVImport reads host:port pairs, hosts or URLs, one per row, or named columns.
+func parseCSVImport(data []byte) (MonitorImportResult, error) {
+	result := MonitorImportResult{Added: []ImportedMonitor{}, Skipped: []string{}}
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	var columns map[string]int
+	for line := 1; ; line++ {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if len(row) == 0 || (len(row) == 1 && strings.TrimSpace(row[0]) == "") {
+			continue
+		}
+		if line == 1 && columns == nil && csvHeader(row) {
+			columns = make(map[string]int)
+			for i, name := range row {
+				columns[strings.ToLower(strings.TrimSpace(name))] = i
+			}
+			continue
+		}
+		cols := columns
+		if cols == nil {
+			// Without a header: host:port, URL or host, then an optional port and name
+			cols = map[string]int{"host": 0, "name": 1}
+			if len(row) > 1 {
+				if _, err := strconv.Atoi(strings.TrimSpace(row[1])); err == nil {
+					cols = map[string]int{"host": 0, "port": 1, "name": 2}
+				}
+			}
+		}
+		field := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		target := field("url")
+		if target == "" {
+			target = field("host")
+		}
+		m, err := csvMonitor(target, field("port"), field("type"))
+		if err != nil {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("line %d: %v", line, err))
+			continue
+		}
+		if name := field("name"); name != "" {
+			m.Name, m.named = name, true
+		}
+		result.Added = append(result.Added, m)
+	}
+	return result, nil
+}
+
+// csvHeader reports whether a row names the columns instead of holding a host.
+func csvHeader(row []string) bool {
+	for _, name := range row {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "host", "url", "port", "name", "type":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// csvMonitor creates a monitor from a URL, host:port or host, with an optional port and type.
+func csvMonitor(target, port, monType string) (ImportedMonitor, error) {
+	if target == "" {
+		return ImportedMonitor{}, errors.New("missing host")
+	}
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		return ImportedMonitor{Name: target, Type: "http", URL: target}, nil
+	}
+	host := target
+	if h, p, err := net.SplitHostPort(target); err == nil {
+		host, port = h, p
+	}
+	if port == "" {
+		if monType != "" && monType != "ping" {
+			return ImportedMonitor{}, fmt.Errorf("%s: %s monitor needs a port", target, monType)
+		}
+		return ImportedMonitor{Name: host, Type: "ping", Host: host}, nil
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return ImportedMonitor{}, fmt.Errorf("%s: invalid port %q", target, port)
+	}
+	switch monType {
+	case "", "port", "http", "https":
+		m := importedPortMonitor(host, host, n, monType)
+		if monType == "port" && m.Type == "http" {
+			m = ImportedMonitor{Name: m.Name, Type: "port", Host: host, Port: n}
+		}
+		return m, nil
+	}
+	return ImportedMonitor{}, fmt.Errorf("%s: unknown type %q", target, monType)
+}
+
+// IdentifyImport recognizes the services behind the imported HTTP monitors and labels
+// monitors without a given name after them.
+func IdentifyImport(ctx context.Context, result MonitorImportResult) MonitorImportResult {
+	var urls []string
+	var index []int
+	for i, m := range result.Added {
+		if m.Type == "http" && len(urls) < maxImportedMonitors {
+			urls = append(urls, m.URL)
+			index = append(index, i)
+		}
+	}
+	for j, id := range IdentifyServices(ctx, urls) {
+		m := &result.Added[index[j]]
+		m.Service, m.Module = id.Service, id.Module
+		if !m.named && id.Label != "" {
+			m.Name = id.Label
+		}
+	}
+	return result
+}
+
+// ImportMonitors adds parsed monitors to the stored monitors of a profile, skipping targets
+// that are already monitored. With dryRun nothing is stored.
+func ImportMonitors(profile string, result MonitorImportResult, dryRun bool) MonitorImportResult {
+	storage := GetStorage()
+	var existing []any
+	var version int64
+	if item, ok := storage.GetForProfile(profile, "monitors"); ok {
+		version = item.Version
+		existing, _ = item.Value.([]any)
+	}
+	var current []lintMonitor
+	lintGet(profile, "monitors", &current)
+	seen := make(map[string]bool, len(current))
+	for _, m := range current {
+		_, _, norm := m.target()
+		seen[norm] = true
+	}
+
+	base := time.Now().UnixMilli()
+	added := []ImportedMonitor{}
+	for i, m := range result.Added {
+		_, _, norm := lintMonitor{Type: m.Type, URL: m.URL, Host: m.Host, Port: float64(m.Port)}.target()
+		if seen[norm] {
+			result.Skipped = append(result.Skipped, m.Name+": already monitored")
+			continue
+		}
+		if len(added) >= maxImportedMonitors {
+			result.Skipped = append(result.Skipped, m.Name+": import limit reached")
+			continue
+		}
+		seen[norm] = true
+		m.ID = fmt.Sprintf("mon-%d-%d", base, i)
+		added = append(added, m)
+	}
+	result.Added = added
+
+	if dryRun || len(added) == 0 {
+		return result
+	}
+	monitors := make([]any, 0, len(existing)+len(added))
+	monitors = append(monitors, existing...)
+	for _, m := range added {
+		entry := map[string]any{"id": m.ID, "name": m.Name, "type": m.Type}
+		switch m.Type {
+		case "http":
+			entry["url"] = m.URL
+		case "port":
+			entry["host"], entry["port"] = m.Host, float64(m.Port)
+		case "ping":
+			entry["host"] = m.Host
+		}
+		monitors = append(monitors, entry)
+	}
+	storage.Set(ProfileStorageKey(profile, "monitors"), monitors, version+1)
+	return result
+}
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"net/http"
 	"net/url"
@@ -14,10 +13,40 @@ import (
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/gosnmp/gosnmp"
 )
 
+// MonitorKey identifies a monitor by its type and target query parameters.
+func MonitorKey(q url.Values) string {
+	return q.Get("type") + ":" + q.Get("url") + q.Get("host") + ":" + q.Get("port")
+}
+
+// StoredMonitorName returns the name of the stored monitor with a key, and whether one is
+// stored. Checks of other targets are answered but not recorded, so clients cannot add
+// monitors to the timeline or send their alerts.
+func StoredMonitorName(key string) (string, bool) {
+	var monitors []lintMonitor
+	GetStorage().GetAs("monitors", &monitors)
+	for _, m := range monitors {
+		// The query the Monitoring card sends for the monitor
+		q := url.Values{"type": {m.Type}}
+		switch m.Type {
+		case "http":
+			q.Set("url", m.URL)
+		case "port":
+			q.Set("host", m.Host)
+			if m.Port != nil {
+				q.Set("port", fmt.Sprint(m.Port))
+			}
+		default:
+			q.Set("host", m.Host)
+		}
+		if MonitorKey(q) == key {
+			return m.Name, true
+		}
+	}
+	return "", false
+}
+
 // CheckHTTP performs an HTTP check and returns latency in ms and SSL info.
 func CheckHTTP(ctx context.Context, targetURL string) (*HTTPCheckResult, error) {
 	result := &HTTPCheckResult{}
@@ -26,18 +55,15 @@ func CheckHTTP(ctx context.Context, targetURL string) (*HTTPCheckResult, error)
 	if err != nil {
 		return nil, err
 	}
+	if err := CheckOutboundURL(ctx, targetURL); err != nil {
+		return nil, err
+	}
 
 	tlsConfig := &tls.Config{InsecureSkipVerify: true}
-	transport := &http.Transport{TLSClientConfig: tlsConfig}
 	client := &http.Client{
-		Timeout:   10 * time.Second,
-		Transport: transport,
-		CheckRedirect: func(_ *http.Request, via []*http.Request) error {
-			if len(via) >= 5 {
-				return errors.New("too many redirects")
-			}
-			return nil
-		},
+		Timeout:       10 * time.Second,
+		Transport:     NewOutboundTransport(tlsConfig),
+		CheckRedirect: OutboundCheckRedirect(5),
 	}
 
 	start := time.Now()
@@ -53,7 +79,7 @@ func CheckHTTP(ctx context.Context, targetURL string) (*HTTPCheckResult, error)
 	}
 	defer func() {
 		if closeErr := res.Body.Close(); closeErr != nil {
-			log.Printf("Error closing HTTP response body: %v", closeErr)
+			Logger("monitor").Warn("closing HTTP response body", "error", closeErr)
 		}
 	}()
 
@@ -81,21 +107,21 @@ func CheckSSLCert(ctx context.Context, host string) (*time.Time, error) {
 		host = host + ":443"
 	}
 
-	dialer := &tls.Dialer{
-		NetDialer: &net.Dialer{Timeout: 5 * time.Second},
-		Config:    &tls.Config{InsecureSkipVerify: true},
-	}
-	conn, err := dialer.DialContext(ctx, "tcp", host)
+	serverName, _, _ := net.SplitHostPort(host)
+	rawConn, err := OutboundDialContext(&net.Dialer{Timeout: 5 * time.Second})(ctx, "tcp", host)
 	if err != nil {
 		return nil, err
 	}
+	tlsConn := tls.Client(rawConn, &tls.Config{InsecureSkipVerify: true, ServerName: serverName})
 	defer func() {
-		if closeErr := conn.Close(); closeErr != nil {
-			log.Printf("Error closing TLS connection: %v", closeErr)
+		if closeErr := tlsConn.Close(); closeErr != nil {
+			Logger("monitor").Warn("closing TLS connection", "error", closeErr)
 		}
 	}()
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
 
-	tlsConn := conn.(*tls.Conn)
 	certs := tlsConn.ConnectionState().PeerCertificates
 	if len(certs) == 0 {
 		return nil, errors.New("no certificates found")
@@ -115,13 +141,13 @@ func CheckPort(ctx context.Context, host, port string) (int64, error) {
 		Timeout: 10 * time.Second,
 	}
 
-	conn, err := dialer.DialContext(ctx, "tcp", address)
+	conn, err := OutboundDialContext(dialer)(ctx, "tcp", address)
 	if err != nil {
 		return 0, err
 	}
 	defer func() {
 		if closeErr := conn.Close(); closeErr != nil {
-			log.Printf("Error closing TCP connection: %v", closeErr)
+			Logger("monitor").Warn("closing TCP connection", "error", closeErr)
 		}
 	}()
 
@@ -141,8 +167,10 @@ func CheckPing(ctx context.Context, host string) (int64, error) {
 	}
 
 	start := time.Now()
-	_, err := net.LookupHost(host)
-	if err != nil {
+	if _, err := ResolveOutboundHost(ctx, host); err != nil {
+		if errors.Is(err, ErrOutboundBlocked) {
+			return 0, err
+		}
 		return 0, errors.New("host unreachable")
 	}
 	latency := time.Since(start).Milliseconds()
@@ -150,54 +178,6 @@ func CheckPing(ctx context.Context, host string) (int64, error) {
 	return latency, nil
 }
 
-// QuerySNMP performs an SNMP query.
-func QuerySNMP(ctx context.Context, host, port, community, oid string) (string, error) {
-	snmp := &gosnmp.GoSNMP{
-		Target:    host,
-		Port:      parsePort(port),
-		Community: community,
-		Version:   gosnmp.Version2c,
-		Timeout:   time.Duration(5) * time.Second,
-		Retries:   1,
-		Context:   ctx,
-	}
-
-	err := snmp.Connect()
-	if err != nil {
-		return "", errors.New("SNMP connect failed: " + err.Error())
-	}
-	defer func() {
-		if closeErr := snmp.Conn.Close(); closeErr != nil {
-			log.Printf("Error closing SNMP connection: %v", closeErr)
-		}
-	}()
-
-	result, err := snmp.Get([]string{oid})
-	if err != nil {
-		return "", errors.New("SNMP GET failed: " + err.Error())
-	}
-
-	if len(result.Variables) == 0 {
-		return "", errors.New("no SNMP variables returned")
-	}
-
-	variable := result.Variables[0]
-	if variable.Type == gosnmp.NoSuchObject || variable.Type == gosnmp.NoSuchInstance {
-		return "", errors.New("OID not found")
-	}
-
-	switch variable.Type {
-	case gosnmp.OctetString:
-		return string(variable.Value.([]byte)), nil
-	case gosnmp.Integer, gosnmp.Counter32, gosnmp.Counter64, gosnmp.Gauge32, gosnmp.TimeTicks, gosnmp.Uinteger32:
-		return fmt.Sprintf("%v", variable.Value), nil
-	case gosnmp.IPAddress:
-		return variable.Value.(string), nil
-	default:
-		return fmt.Sprintf("%v", variable.Value), nil
-	}
-}
-
 func parsePort(portStr string) uint16 {
 	port := 161
 	if p, err := strconv.Atoi(portStr); err == nil && p > 0 && p < 65536 {
@@ -206,22 +186,21 @@ func parsePort(portStr string) uint16 {
 	return uint16(port)
 }
 
+// ErrFaviconNotFound is returned when a site has no favicon that could be downloaded.
+var ErrFaviconNotFound = errors.New("favicon not found")
+
 // FetchFavicon tries to fetch a favicon from a site.
 func FetchFavicon(ctx context.Context, origin string) ([]byte, string, error) {
-	log.Printf("[favicon] fetchFavicon called for origin: %s", origin)
+	logger := Logger("favicon")
+	if err := CheckOutboundURL(ctx, origin); err != nil {
+		return nil, "", err
+	}
 
 	tlsConfig := &tls.Config{InsecureSkipVerify: true}
-	transport := &http.Transport{TLSClientConfig: tlsConfig}
-
 	client := &http.Client{
-		Timeout:   5 * time.Second,
-		Transport: transport,
-		CheckRedirect: func(_ *http.Request, via []*http.Request) error {
-			if len(via) >= 3 {
-				return errors.New("too many redirects")
-			}
-			return nil
-		},
+		Timeout:       5 * time.Second,
+		Transport:     NewOutboundTransport(tlsConfig),
+		CheckRedirect: OutboundCheckRedirect(3),
 	}
 
 	faviconPaths := []string{
@@ -231,7 +210,6 @@ func FetchFavicon(ctx context.Context, origin string) ([]byte, string, error) {
 		"/apple-touch-icon-precomposed.png",
 	}
 
-	log.Printf("[favicon] Fetching HTML from %s", origin)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin, nil)
 	if err != nil {
 		return nil, "", err
@@ -239,53 +217,46 @@ func FetchFavicon(ctx context.Context, origin string) ([]byte, string, error) {
 	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; lan-index/1.0)")
 	res, err := client.Do(req)
 	if err != nil {
-		log.Printf("[favicon] Error fetching HTML: %v", err)
+		logger.Debug("fetching page failed", "origin", origin, "error", err)
 	} else if res.StatusCode >= 200 && res.StatusCode < 300 {
 		defer func() {
 			if closeErr := res.Body.Close(); closeErr != nil {
-				log.Printf("Error closing favicon response body: %v", closeErr)
+				logger.Warn("closing response body", "error", closeErr)
 			}
 		}()
-		log.Printf("[favicon] Got HTML response, status: %d", res.StatusCode)
 		body, err := io.ReadAll(io.LimitReader(res.Body, 100*1024))
 		if err == nil {
-			log.Printf("[favicon] Read %d bytes of HTML", len(body))
 			faviconURL := extractFaviconFromHTML(string(body), origin)
 			if faviconURL != "" {
-				log.Printf("[favicon] Found favicon URL in HTML: %s", faviconURL)
 				data, contentType, err := downloadFavicon(ctx, client, faviconURL)
 				if err == nil {
-					log.Printf("[favicon] Successfully downloaded favicon from HTML link")
+					logger.Debug("favicon found in page", "origin", origin, "url", faviconURL)
 					return data, contentType, nil
 				}
-				log.Printf("[favicon] Failed to download from HTML link: %v", err)
+				logger.Debug("downloading linked favicon failed", "url", faviconURL, "error", err)
 			} else {
-				log.Printf("[favicon] No favicon URL found in HTML")
+				logger.Debug("no favicon link in page", "origin", origin)
 			}
 		} else {
-			log.Printf("[favicon] Error reading HTML body: %v", err)
+			logger.Debug("reading page failed", "origin", origin, "error", err)
 		}
 	} else if res != nil {
-		log.Printf("[favicon] HTML response status: %d", res.StatusCode)
+		logger.Debug("page request failed", "origin", origin, "status", res.StatusCode)
 		if closeErr := res.Body.Close(); closeErr != nil {
-			log.Printf("Error closing favicon HTML response body: %v", closeErr)
+			logger.Warn("closing response body", "error", closeErr)
 		}
 	}
 
-	log.Printf("[favicon] Trying common favicon paths...")
 	for _, path := range faviconPaths {
-		faviconURL := origin + path
-		log.Printf("[favicon] Trying: %s", faviconURL)
-		data, contentType, err := downloadFavicon(ctx, client, faviconURL)
+		data, contentType, err := downloadFavicon(ctx, client, origin+path)
 		if err == nil {
-			log.Printf("[favicon] Success with %s", path)
+			logger.Debug("favicon found at common path", "origin", origin, "path", path)
 			return data, contentType, nil
 		}
-		log.Printf("[favicon] Failed %s: %v", path, err)
+		logger.Debug("common favicon path failed", "origin", origin, "path", path, "error", err)
 	}
 
-	log.Printf("[favicon] All attempts failed for %s", origin)
-	return nil, "", errors.New("favicon not found")
+	return nil, "", ErrFaviconNotFound
 }
 
 func extractFaviconFromHTML(html, origin string) string {
@@ -327,7 +298,7 @@ func downloadFavicon(ctx context.Context, client *http.Client, faviconURL string
 	}
 	defer func() {
 		if closeErr := res.Body.Close(); closeErr != nil {
-			log.Printf("Error closing response body: %v", closeErr)
+			Logger("favicon").Warn("closing response body", "error", closeErr)
 		}
 	}()
 
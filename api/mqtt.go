@@ -0,0 +1,471 @@
+package api
+
+import (
+	"cmp"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// mqttMaxTopics caps the number of cached topics, so a "#" subscription on a busy broker
+// cannot grow the cache without bound.
+const mqttMaxTopics = 5000
+
+// MQTTConfig configures the MQTT broker connection.
+type MQTTConfig struct {
+	Broker   string `json:"broker"` // e.g. tcp://mqtt.lan:1883, ssl://mqtt.lan:8883 or ws://mqtt.lan:9001
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// PasswordFile and PasswordEnv read the password from a secret file or environment variable
+	PasswordFile string   `json:"passwordFile,omitempty"`
+	PasswordEnv  string   `json:"passwordEnv,omitempty"`
+	ClientID     string   `json:"clientId,omitempty"` // Default: homepage-<hostname>
+	Topics       []string `json:"topics,omitempty"`   // Extra subscriptions whose values are cached, wildcards allowed
+	// DiscoveryPrefix enables Home Assistant MQTT discovery of sensors (usually "homeassistant")
+	DiscoveryPrefix string       `json:"discoveryPrefix,omitempty"`
+	Sensors         []MQTTSensor `json:"sensors,omitempty"` // Values shown as dashboard cards
+	Insecure        bool         `json:"insecure,omitempty"`
+}
+
+// MQTTSensor is a value shown on the MQTT card: a topic and, for JSON payloads such as
+// Tasmota's tele/<device>/SENSOR, the dot-separated path of the field (e.g. ENERGY.Power).
+type MQTTSensor struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Topic       string `json:"topic"`
+	Field       string `json:"field,omitempty"`
+	Unit        string `json:"unit,omitempty"`
+	DeviceClass string `json:"deviceClass,omitempty"` // e.g. temperature, power, humidity
+	Discovered  bool   `json:"discovered,omitempty"`  // Found through Home Assistant discovery
+}
+
+// Validate checks the broker URL, subscriptions and sensors.
+func (c MQTTConfig) Validate() error {
+	scheme, _, ok := strings.Cut(c.Broker, "://")
+	switch {
+	case !ok:
+		return fmt.Errorf("mqtt: broker must be a URL such as tcp://host:1883")
+	case scheme != "tcp" && scheme != "ssl" && scheme != "tls" && scheme != "mqtt" && scheme != "mqtts" && scheme != "ws" && scheme != "wss":
+		return fmt.Errorf("mqtt: unsupported broker scheme %q (use tcp, ssl, ws or wss)", scheme)
+	}
+	for _, t := range c.Topics {
+		if t == "" {
+			return fmt.Errorf("mqtt: empty topic")
+		}
+	}
+	if len(c.Topics) == 0 && len(c.Sensors) == 0 && c.DiscoveryPrefix == "" {
+		return fmt.Errorf("mqtt: configure topics, sensors or discoveryPrefix")
+	}
+	for i, s := range c.Sensors {
+		if s.Name == "" || s.Topic == "" {
+			return fmt.Errorf("mqtt.sensors[%d]: name and topic are required", i)
+		}
+		if strings.ContainsAny(s.Topic, "+#") {
+			return fmt.Errorf("mqtt.sensors[%d]: topic must not contain wildcards", i)
+		}
+	}
+	return nil
+}
+
+// MQTTMessage is the last message received on a topic.
+type MQTTMessage struct {
+	Topic    string    `json:"topic"`
+	Payload  string    `json:"payload"`
+	Retained bool      `json:"retained,omitempty"`
+	Updated  time.Time `json:"updated"`
+}
+
+// MQTTSensorValue is a sensor with its current value.
+type MQTTSensorValue struct {
+	MQTTSensor
+	Value   any        `json:"value"`
+	Updated *time.Time `json:"updated,omitempty"`
+}
+
+// MQTTManager keeps the broker connection and the last value of each topic.
+type MQTTManager struct {
+	mu         sync.Mutex
+	config     *MQTTConfig
+	client     mqtt.Client
+	connected  bool
+	lastError  string
+	messages   map[string]MQTTMessage
+	discovered map[string]MQTTSensor // By discovery config topic
+}
+
+var mqttManager = &MQTTManager{
+	messages:   make(map[string]MQTTMessage),
+	discovered: make(map[string]MQTTSensor),
+}
+
+// GetMQTTManager returns the global MQTT client.
+func GetMQTTManager() *MQTTManager {
+	return mqttManager
+}
+
+// Configure sets the broker and sensors. Start connects.
+func (mm *MQTTManager) Configure(cfg MQTTConfig) {
+	for i := range cfg.Sensors {
+		if cfg.Sensors[i].ID == "" {
+			cfg.Sensors[i].ID = cfg.Sensors[i].Topic + "|" + cfg.Sensors[i].Field
+		}
+	}
+	mm.mu.Lock()
+	mm.config = &cfg
+	mm.mu.Unlock()
+}
+
+// Enabled reports whether a broker is configured.
+func (mm *MQTTManager) Enabled() bool {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return mm.config != nil
+}
+
+// Start connects to the broker. The client reconnects and resubscribes on its own.
+func (mm *MQTTManager) Start() {
+	mm.mu.Lock()
+	cfg := mm.config
+	mm.mu.Unlock()
+	if cfg == nil {
+		return
+	}
+	password, err := ResolveSecret(cfg.Password, cfg.PasswordFile, cfg.PasswordEnv)
+	if err != nil {
+		Logger("mqtt").Error("cannot read broker password", "error", err)
+		return
+	}
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = "homepage-" + MustHostname()
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(clientID).
+		SetUsername(cfg.Username).
+		SetPassword(password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetMaxReconnectInterval(time.Minute).
+		SetOnConnectHandler(mm.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			Logger("mqtt").Warn("connection lost", "broker", cfg.Broker, "error", err)
+			mm.mu.Lock()
+			mm.connected = false
+			mm.lastError = err.Error()
+			mm.mu.Unlock()
+			GetModuleHealth().Record("mqtt", err)
+		})
+	if cfg.Insecure {
+		opts.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+	}
+	client := mqtt.NewClient(opts)
+	mm.mu.Lock()
+	mm.client = client
+	mm.mu.Unlock()
+	client.Connect()
+}
+
+// subscriptions returns the topics to subscribe to.
+func (cfg MQTTConfig) subscriptions() []string {
+	seen := make(map[string]bool)
+	var topics []string
+	add := func(t string) {
+		if !seen[t] {
+			seen[t] = true
+			topics = append(topics, t)
+		}
+	}
+	for _, t := range cfg.Topics {
+		add(t)
+	}
+	for _, s := range cfg.Sensors {
+		add(s.Topic)
+	}
+	if cfg.DiscoveryPrefix != "" {
+		add(cfg.DiscoveryPrefix + "/+/+/config")
+		add(cfg.DiscoveryPrefix + "/+/+/+/config")
+	}
+	return topics
+}
+
+func (mm *MQTTManager) onConnect(client mqtt.Client) {
+	mm.mu.Lock()
+	cfg := *mm.config
+	mm.connected = true
+	mm.lastError = ""
+	GetModuleHealth().Record("mqtt", nil)
+	filters := make(map[string]byte)
+	for _, t := range cfg.subscriptions() {
+		filters[t] = 0
+	}
+	// Sensors discovered before a reconnect
+	for _, s := range mm.discovered {
+		if !mm.subscribedLocked(s.Topic) {
+			filters[s.Topic] = 0
+		}
+	}
+	mm.mu.Unlock()
+
+	token := client.SubscribeMultiple(filters, mm.onMessage)
+	go func() {
+		if token.WaitTimeout(30*time.Second) && token.Error() != nil {
+			Logger("mqtt").Error("subscribe failed", "error", token.Error())
+		}
+	}()
+	Logger("mqtt").Info("connected", "broker", cfg.Broker, "subscriptions", len(filters))
+}
+
+func (mm *MQTTManager) onMessage(_ mqtt.Client, msg mqtt.Message) {
+	topic := msg.Topic()
+	mm.mu.Lock()
+	cfg := mm.config
+	if cfg.DiscoveryPrefix != "" && strings.HasPrefix(topic, cfg.DiscoveryPrefix+"/") && strings.HasSuffix(topic, "/config") {
+		mm.mu.Unlock()
+		mm.discover(topic, msg.Payload())
+		return
+	}
+	if _, ok := mm.messages[topic]; !ok && len(mm.messages) >= mqttMaxTopics {
+		mm.mu.Unlock()
+		return
+	}
+	m := MQTTMessage{Topic: topic, Payload: string(msg.Payload()), Retained: msg.Retained(), Updated: time.Now()}
+	mm.messages[topic] = m
+	sensors := mm.sensorsLocked()
+	mm.mu.Unlock()
+
+	var updates []MQTTSensorValue
+	for _, s := range sensors {
+		if s.Topic == topic {
+			updates = append(updates, MQTTSensorValue{MQTTSensor: s, Value: mqttValue(m.Payload, s.Field), Updated: &m.Updated})
+		}
+	}
+	if GetWSManager().HasSubscribers(WSTopicMQTT) {
+		GetWSManager().BroadcastTopic(WSTopicMQTT, map[string]interface{}{
+			"type":    "mqtt",
+			"message": m,
+			"sensors": updates,
+		})
+	}
+}
+
+// haValueTemplate matches the common "{{ value_json.a.b }}" and "{{ value_json['a']['b'] }}"
+// templates of Home Assistant discovery configs.
+var haValueTemplate = regexp.MustCompile(`^\{\{\s*value_json((?:\.[A-Za-z0-9_]+|\[['"][^'"]+['"]\])+)\s*(?:\|[^}]*)?\}\}$`)
+
+// discover records a sensor announced through Home Assistant MQTT discovery. An empty
+// payload removes it.
+func (mm *MQTTManager) discover(topic string, payload []byte) {
+	if len(payload) == 0 {
+		mm.mu.Lock()
+		delete(mm.discovered, topic)
+		mm.mu.Unlock()
+		return
+	}
+	var cfg struct {
+		Name          *string `json:"name"`
+		StateTopic    string  `json:"state_topic"`
+		Unit          string  `json:"unit_of_measurement"`
+		DeviceClass   string  `json:"device_class"`
+		ValueTemplate string  `json:"value_template"`
+		UniqueID      string  `json:"unique_id"`
+		ObjectID      string  `json:"object_id"`
+		Device        struct {
+			Name string `json:"name"`
+		} `json:"device"`
+		// Abbreviated keys used by Tasmota and ESPHome
+		StateTopicAbbr    string `json:"stat_t"`
+		UnitAbbr          string `json:"unit_of_meas"`
+		DeviceClassAbbr   string `json:"dev_cla"`
+		ValueTemplateAbbr string `json:"val_tpl"`
+		UniqueIDAbbr      string `json:"uniq_id"`
+		BaseTopic         string `json:"~"`
+	}
+	if err := json.Unmarshal(payload, &cfg); err != nil {
+		Logger("mqtt").Debug("ignoring discovery config", "topic", topic, "error", err)
+		return
+	}
+	state := cmp.Or(cfg.StateTopic, cfg.StateTopicAbbr)
+	if state == "" || !strings.Contains(topic, "/sensor/") && !strings.Contains(topic, "/binary_sensor/") {
+		return
+	}
+	// "~" abbreviates the base topic
+	if cfg.BaseTopic != "" {
+		state = strings.Replace(state, "~", cfg.BaseTopic, 1)
+	}
+	name := cfg.ObjectID
+	if cfg.Name != nil && *cfg.Name != "" {
+		name = *cfg.Name
+		if cfg.Device.Name != "" && !strings.HasPrefix(name, cfg.Device.Name) {
+			name = cfg.Device.Name + " " + name
+		}
+	} else if cfg.Device.Name != "" {
+		name = cfg.Device.Name
+	}
+	var field string
+	if m := haValueTemplate.FindStringSubmatch(strings.TrimSpace(cmp.Or(cfg.ValueTemplate, cfg.ValueTemplateAbbr))); m != nil {
+		field = strings.NewReplacer("['", ".", "[\"", ".", "']", "", "\"]", "").Replace(m[1])
+		field = strings.TrimPrefix(field, ".")
+	}
+	id := cmp.Or(cfg.UniqueID, cfg.UniqueIDAbbr)
+	if id == "" {
+		id = topic
+	}
+	sensor := MQTTSensor{
+		ID:          id,
+		Name:        cmp.Or(name, id),
+		Topic:       state,
+		Field:       field,
+		Unit:        cmp.Or(cfg.Unit, cfg.UnitAbbr),
+		DeviceClass: cmp.Or(cfg.DeviceClass, cfg.DeviceClassAbbr),
+		Discovered:  true,
+	}
+
+	mm.mu.Lock()
+	_, known := mm.discovered[topic]
+	if !known && len(mm.discovered) >= mqttMaxTopics {
+		mm.mu.Unlock()
+		return
+	}
+	subscribed := mm.subscribedLocked(state)
+	if !subscribed {
+		for _, s := range mm.discovered {
+			subscribed = subscribed || s.Topic == state
+		}
+	}
+	mm.discovered[topic] = sensor
+	client := mm.client
+	mm.mu.Unlock()
+
+	if !subscribed && client != nil {
+		client.Subscribe(state, 0, mm.onMessage)
+	}
+	if !known {
+		Logger("mqtt").Debug("discovered sensor", "name", sensor.Name, "topic", state)
+	}
+}
+
+// subscribedLocked reports whether a topic is covered by the configured subscriptions.
+// Caller must hold mu.
+func (mm *MQTTManager) subscribedLocked(topic string) bool {
+	for _, filter := range mm.config.subscriptions() {
+		if mqttTopicMatches(filter, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// mqttTopicMatches reports whether a topic matches a subscription filter with + and # wildcards.
+func mqttTopicMatches(filter, topic string) bool {
+	f := strings.Split(filter, "/")
+	t := strings.Split(topic, "/")
+	for i, part := range f {
+		if part == "#" {
+			return true
+		}
+		if i >= len(t) || (part != "+" && part != t[i]) {
+			return false
+		}
+	}
+	return len(f) == len(t)
+}
+
+// sensorsLocked returns the configured and discovered sensors. Caller must hold mu.
+func (mm *MQTTManager) sensorsLocked() []MQTTSensor {
+	sensors := append([]MQTTSensor(nil), mm.config.Sensors...)
+	discovered := make([]MQTTSensor, 0, len(mm.discovered))
+	for _, s := range mm.discovered {
+		discovered = append(discovered, s)
+	}
+	sort.Slice(discovered, func(i, j int) bool { return discovered[i].Name < discovered[j].Name })
+	return append(sensors, discovered...)
+}
+
+// MQTTStatus is the state of the connection and the cached values.
+type MQTTStatus struct {
+	Enabled   bool              `json:"enabled"`
+	Broker    string            `json:"broker,omitempty"`
+	Connected bool              `json:"connected"`
+	Error     string            `json:"error,omitempty"`
+	Sensors   []MQTTSensorValue `json:"sensors"`
+	Topics    int               `json:"topics"` // Number of cached topics
+}
+
+// Status returns the connection state and the current value of every sensor.
+func (mm *MQTTManager) Status() MQTTStatus {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if mm.config == nil {
+		return MQTTStatus{Sensors: []MQTTSensorValue{}}
+	}
+	status := MQTTStatus{
+		Enabled:   true,
+		Broker:    mm.config.Broker,
+		Connected: mm.connected,
+		Error:     mm.lastError,
+		Sensors:   []MQTTSensorValue{},
+		Topics:    len(mm.messages),
+	}
+	for _, s := range mm.sensorsLocked() {
+		v := MQTTSensorValue{MQTTSensor: s}
+		if m, ok := mm.messages[s.Topic]; ok {
+			v.Value = mqttValue(m.Payload, s.Field)
+			v.Updated = &m.Updated
+		}
+		status.Sensors = append(status.Sensors, v)
+	}
+	return status
+}
+
+// Messages returns the cached messages of topics matching a filter (all with "#").
+func (mm *MQTTManager) Messages(filter string) []MQTTMessage {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	result := []MQTTMessage{}
+	for topic, m := range mm.messages {
+		if mqttTopicMatches(filter, topic) {
+			result = append(result, m)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Topic < result[j].Topic })
+	return result
+}
+
+// mqttValue extracts a sensor value from a payload: the field of a JSON object, a number,
+// or the payload as text.
+func mqttValue(payload, field string) any {
+	if field == "" {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(payload), 64); err == nil {
+			return f
+		}
+		var v any
+		if json.Unmarshal([]byte(payload), &v) == nil {
+			if _, isObject := v.(map[string]any); !isObject {
+				return v
+			}
+		}
+		return payload
+	}
+	var v any
+	if err := json.Unmarshal([]byte(payload), &v); err != nil {
+		return nil
+	}
+	for _, key := range strings.Split(field, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = obj[key]
+	}
+	return v
+}
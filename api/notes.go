@@ -0,0 +1,214 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// notesFile holds the notes across restarts.
+const notesFile = "notes.json"
+
+// Limits of the notes store.
+const (
+	maxNotes       = 100
+	maxNoteContent = 64 << 10
+)
+
+// ErrNoteNotFound is returned when a note ID is not in the store.
+var ErrNoteNotFound = errors.New("note not found")
+
+// ErrNoteChanged is returned when a note was saved elsewhere since the client loaded it.
+var ErrNoteChanged = errors.New("the note was changed on another device, reload it first")
+
+// Note is a named Markdown note. HTML is rendered from the content when the note is read.
+type Note struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Content  string    `json:"content"` // Markdown
+	HTML     string    `json:"html,omitempty"`
+	Created  time.Time `json:"created"`
+	Modified time.Time `json:"modified"`
+}
+
+// NoteStore keeps the notes on the server, shared by every client and profile, and tells
+// every open tab when one changes.
+type NoteStore struct {
+	mu     sync.Mutex
+	notes  []Note
+	loaded bool
+}
+
+// Global note store instance
+var noteStore = &NoteStore{}
+
+// GetNoteStore returns the global note store instance.
+func GetNoteStore() *NoteStore {
+	return noteStore
+}
+
+// load reads the notes file. Caller must hold mu.
+func (ns *NoteStore) load() {
+	if ns.loaded {
+		return
+	}
+	ns.loaded = true
+	data, err := os.ReadFile(notesFile)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &ns.notes); err != nil {
+		GetDebugLogger().Logf("notes", "failed to parse %s: %v", notesFile, err)
+		ns.notes = nil
+	}
+}
+
+// save writes the notes file. Caller must hold mu.
+func (ns *NoteStore) save() error {
+	data, err := json.Marshal(ns.notes)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(notesFile, data, 0644)
+}
+
+// rendered returns a note with its content rendered to sanitized HTML.
+func (n Note) rendered() Note {
+	n.HTML = RenderMarkdown(n.Content)
+	return n
+}
+
+// All returns the notes in the order they were added, rendered.
+func (ns *NoteStore) All() []Note {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.load()
+	notes := make([]Note, len(ns.notes))
+	for i, n := range ns.notes {
+		notes[i] = n.rendered()
+	}
+	return notes
+}
+
+// Get returns a note by ID, rendered.
+func (ns *NoteStore) Get(id string) (Note, error) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.load()
+	i := slices.IndexFunc(ns.notes, func(n Note) bool { return n.ID == id })
+	if id == "" || i < 0 {
+		return Note{}, ErrNoteNotFound
+	}
+	return ns.notes[i].rendered(), nil
+}
+
+// Add stores a new note and returns it with its ID.
+func (ns *NoteStore) Add(n Note) (Note, error) {
+	if err := normalizeNote(&n); err != nil {
+		return Note{}, err
+	}
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.load()
+	if len(ns.notes) >= maxNotes {
+		return Note{}, fmt.Errorf("note limit of %d reached", maxNotes)
+	}
+	n.ID = newStoreID()
+	if err := ns.checkUnique(n); err != nil {
+		return Note{}, err
+	}
+	n.Created = time.Now().UTC().Truncate(time.Millisecond)
+	n.Modified = n.Created
+	ns.notes = append(ns.notes, n)
+	if err := ns.save(); err != nil {
+		return Note{}, err
+	}
+	n = n.rendered()
+	notifyNote("add", n)
+	return n, nil
+}
+
+// Update replaces the name and content of a note. When the note's modified time is set,
+// the note must not have been saved since then, so two devices do not overwrite each other.
+func (ns *NoteStore) Update(n Note) (Note, error) {
+	modified := n.Modified
+	if err := normalizeNote(&n); err != nil {
+		return Note{}, err
+	}
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.load()
+	i := slices.IndexFunc(ns.notes, func(existing Note) bool { return existing.ID == n.ID })
+	if n.ID == "" || i < 0 {
+		return Note{}, ErrNoteNotFound
+	}
+	if !modified.IsZero() && !modified.Equal(ns.notes[i].Modified) {
+		return Note{}, ErrNoteChanged
+	}
+	if err := ns.checkUnique(n); err != nil {
+		return Note{}, err
+	}
+	n.Created = ns.notes[i].Created
+	n.Modified = time.Now().UTC().Truncate(time.Millisecond)
+	ns.notes[i] = n
+	if err := ns.save(); err != nil {
+		return Note{}, err
+	}
+	n = n.rendered()
+	notifyNote("update", n)
+	return n, nil
+}
+
+// Delete removes a note by ID.
+func (ns *NoteStore) Delete(id string) error {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.load()
+	i := slices.IndexFunc(ns.notes, func(n Note) bool { return n.ID == id })
+	if id == "" || i < 0 {
+		return ErrNoteNotFound
+	}
+	ns.notes = slices.Delete(ns.notes, i, i+1)
+	if err := ns.save(); err != nil {
+		return err
+	}
+	notifyNote("delete", Note{ID: id})
+	return nil
+}
+
+// checkUnique rejects a note whose name another note has. Caller must hold mu.
+func (ns *NoteStore) checkUnique(n Note) error {
+	for _, other := range ns.notes {
+		if other.ID != n.ID && strings.EqualFold(other.Name, n.Name) {
+			return fmt.Errorf("a note named %q already exists", other.Name)
+		}
+	}
+	return nil
+}
+
+// normalizeNote checks a note from a client.
+func normalizeNote(n *Note) error {
+	n.Name = strings.TrimSpace(n.Name)
+	if n.Name == "" || len(n.Name) > 80 {
+		return errors.New("a name of up to 80 characters is required")
+	}
+	if len(n.Content) > maxNoteContent {
+		return fmt.Errorf("a note can hold up to %d KB", maxNoteContent>>10)
+	}
+	n.HTML = ""
+	return nil
+}
+
+// notifyNote tells every open tab that a note was added, changed or deleted.
+func notifyNote(action string, n Note) {
+	GetWSManager().Broadcast(map[string]interface{}{
+		"type":   "note",
+		"action": action,
+		"note":   n,
+	})
+}
@@ -0,0 +1,394 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// oidcLoginTimeout is how long a user has to complete the login at the provider.
+const oidcLoginTimeout = 10 * time.Minute
+
+// oidcMaxPending caps the logins in progress, so unauthenticated clients cannot fill
+// memory by starting logins they never finish.
+const oidcMaxPending = 1000
+
+// oidcStateCookie binds a login in progress to the browser that started it.
+const oidcStateCookie = "homepage_oidc_state"
+
+// OIDCConfig configures an OpenID Connect provider for single sign-on. Users get the
+// highest role of their groups and their user entry, or DefaultRole if nothing matches;
+// users without any role cannot sign in.
+type OIDCConfig struct {
+	ID           string `json:"id"`             // Used in login URLs, e.g. "authelia"
+	Name         string `json:"name,omitempty"` // Button label, default: the ID
+	Issuer       string `json:"issuer"`         // e.g. https://auth.example.org or https://accounts.google.com
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+	// ClientSecretFile and ClientSecretEnv read the client secret from a secret file or environment variable
+	ClientSecretFile string `json:"clientSecretFile,omitempty"`
+	ClientSecretEnv  string `json:"clientSecretEnv,omitempty"`
+	// RedirectURL is the callback registered at the provider: <dashboard URL>/api/auth/oidc/callback
+	RedirectURL string   `json:"redirectUrl"`
+	Scopes      []string `json:"scopes,omitempty"` // Default: openid, profile, email
+	// GroupsClaim is the claim listing the user's groups, a dot path for nested claims such
+	// as Keycloak's realm_access.roles. Default: groups
+	GroupsClaim string            `json:"groupsClaim,omitempty"`
+	Roles       map[string]Role   `json:"roles,omitempty"`    // Group -> role
+	Users       map[string]Role   `json:"users,omitempty"`    // Verified email or subject (sub) -> role
+	Profiles    map[string]string `json:"profiles,omitempty"` // Group -> dashboard profile
+	DefaultRole Role              `json:"defaultRole,omitempty"`
+	// SessionDuration is how long a login lasts. Default: 720h
+	SessionDuration string `json:"sessionDuration,omitempty"`
+}
+
+// Validate checks the provider settings and the role and profile mappings.
+func (c OIDCConfig) Validate() error {
+	if c.ID == "" || strings.ContainsAny(c.ID, "/?&#% ") {
+		return fmt.Errorf("id is required and must not contain URL characters")
+	}
+	if u, err := url.Parse(c.Issuer); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("issuer must be an http(s) URL")
+	}
+	if c.ClientID == "" {
+		return fmt.Errorf("clientId is required")
+	}
+	if u, err := url.Parse(c.RedirectURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("redirectUrl must be the absolute URL of /api/auth/oidc/callback")
+	}
+	for group, role := range c.Roles {
+		if _, ok := roleRank[role]; !ok {
+			return fmt.Errorf("roles.%s: unknown role %q", group, role)
+		}
+	}
+	for user, role := range c.Users {
+		if _, ok := roleRank[role]; !ok {
+			return fmt.Errorf("users.%s: unknown role %q", user, role)
+		}
+	}
+	if _, ok := roleRank[c.DefaultRole]; c.DefaultRole != "" && !ok {
+		return fmt.Errorf("unknown defaultRole %q", c.DefaultRole)
+	}
+	if len(c.Roles) == 0 && len(c.Users) == 0 && c.DefaultRole == "" {
+		return fmt.Errorf("set roles, users or defaultRole, otherwise nobody can sign in")
+	}
+	for group, profile := range c.Profiles {
+		if !ValidProfileName(profile) {
+			return fmt.Errorf("profiles.%s: invalid profile name %q", group, profile)
+		}
+	}
+	if c.SessionDuration != "" {
+		if d, err := time.ParseDuration(c.SessionDuration); err != nil || d <= 0 {
+			return fmt.Errorf("invalid sessionDuration %q", c.SessionDuration)
+		}
+	}
+	return nil
+}
+
+func (c OIDCConfig) sessionDuration() time.Duration {
+	if d, err := time.ParseDuration(c.SessionDuration); err == nil && d > 0 {
+		return d
+	}
+	return 30 * 24 * time.Hour
+}
+
+// OIDCProviderInfo is a provider as offered on the sign-in form.
+type OIDCProviderInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// oidcPending is a login in progress, keyed by its state parameter.
+type oidcPending struct {
+	provider string
+	nonce    string
+	verifier string // PKCE code verifier
+	returnTo string
+	expires  time.Time
+}
+
+// oidcProvider is a configured provider with its discovery document, once fetched.
+type oidcProvider struct {
+	config   OIDCConfig
+	provider *oidc.Provider
+}
+
+// OIDCManager runs the OpenID Connect authorization code flow.
+type OIDCManager struct {
+	mu        sync.Mutex
+	providers map[string]*oidcProvider
+	order     []string
+	pending   map[string]oidcPending
+}
+
+var oidcManager = &OIDCManager{
+	providers: make(map[string]*oidcProvider),
+	pending:   make(map[string]oidcPending),
+}
+
+// GetOIDCManager returns the global single sign-on manager.
+func GetOIDCManager() *OIDCManager {
+	return oidcManager
+}
+
+// Configure sets the providers. Their discovery documents are fetched on first use.
+func (om *OIDCManager) Configure(configs []OIDCConfig) {
+	providers := make(map[string]*oidcProvider, len(configs))
+	var order []string
+	for _, c := range configs {
+		providers[c.ID] = &oidcProvider{config: c}
+		order = append(order, c.ID)
+	}
+	om.mu.Lock()
+	om.providers = providers
+	om.order = order
+	om.mu.Unlock()
+}
+
+// Providers returns the configured providers in config order.
+func (om *OIDCManager) Providers() []OIDCProviderInfo {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	result := []OIDCProviderInfo{}
+	for _, id := range om.order {
+		c := om.providers[id].config
+		result = append(result, OIDCProviderInfo{ID: c.ID, Name: cmp.Or(c.Name, c.ID)})
+	}
+	return result
+}
+
+// discover returns the provider and its OAuth2 settings, fetching the discovery document
+// if needed. Failed fetches are retried on the next login.
+func (om *OIDCManager) discover(ctx context.Context, id string) (*oidc.Provider, *oauth2.Config, OIDCConfig, error) {
+	om.mu.Lock()
+	p, ok := om.providers[id]
+	var provider *oidc.Provider
+	if ok {
+		provider = p.provider
+	}
+	om.mu.Unlock()
+	if !ok {
+		return nil, nil, OIDCConfig{}, fmt.Errorf("unknown sign-in provider %q", id)
+	}
+	if provider == nil {
+		var err error
+		provider, err = oidc.NewProvider(ctx, p.config.Issuer)
+		if err != nil {
+			return nil, nil, p.config, fmt.Errorf("%s: %w", p.config.ID, err)
+		}
+		om.mu.Lock()
+		p.provider = provider
+		om.mu.Unlock()
+	}
+	secret, err := ResolveSecret(p.config.ClientSecret, p.config.ClientSecretFile, p.config.ClientSecretEnv)
+	if err != nil {
+		return nil, nil, p.config, fmt.Errorf("%s: %w", p.config.ID, err)
+	}
+	scopes := p.config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	oauthConfig := &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: secret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  p.config.RedirectURL,
+		Scopes:       scopes,
+	}
+	return provider, oauthConfig, p.config, nil
+}
+
+// oidcRandom returns n random bytes as hex.
+func oidcRandom(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// LoginURL starts a login at a provider and returns the URL to send the browser to and
+// the state to bind to the browser. returnTo is the local path to go to afterwards.
+func (om *OIDCManager) LoginURL(ctx context.Context, id, returnTo string) (string, string, error) {
+	_, oauthConfig, _, err := om.discover(ctx, id)
+	if err != nil {
+		return "", "", err
+	}
+	// Only local paths, so the login cannot be used to redirect elsewhere
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+		returnTo = "/"
+	}
+	state, nonce, verifier := oidcRandom(16), oidcRandom(16), oauth2.GenerateVerifier()
+
+	now := time.Now()
+	om.mu.Lock()
+	for s, p := range om.pending {
+		if now.After(p.expires) {
+			delete(om.pending, s)
+		}
+	}
+	if len(om.pending) >= oidcMaxPending {
+		om.mu.Unlock()
+		return "", "", errors.New("too many sign-ins in progress, try again later")
+	}
+	om.pending[state] = oidcPending{provider: id, nonce: nonce, verifier: verifier, returnTo: returnTo, expires: now.Add(oidcLoginTimeout)}
+	om.mu.Unlock()
+
+	return oauthConfig.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), state, nil
+}
+
+// Callback completes a login from a device: it exchanges the code, verifies the ID token
+// and maps the user's claims to a role and profile. It returns the new session, its secret and the
+// path to return to.
+func (om *OIDCManager) Callback(ctx context.Context, state, code string, device SessionDevice) (LoginSession, string, string, error) {
+	om.mu.Lock()
+	pending, ok := om.pending[state]
+	delete(om.pending, state)
+	om.mu.Unlock()
+	if !ok || time.Now().After(pending.expires) {
+		return LoginSession{}, "", "", errors.New("sign-in expired or was started in another browser, please try again")
+	}
+
+	provider, oauthConfig, cfg, err := om.discover(ctx, pending.provider)
+	if err != nil {
+		return LoginSession{}, "", "", err
+	}
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(pending.verifier))
+	if err != nil {
+		return LoginSession{}, "", "", fmt.Errorf("code exchange failed: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return LoginSession{}, "", "", errors.New("provider returned no ID token")
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return LoginSession{}, "", "", fmt.Errorf("invalid ID token: %w", err)
+	}
+	if idToken.Nonce != pending.nonce {
+		return LoginSession{}, "", "", errors.New("invalid ID token: nonce mismatch")
+	}
+	claims := make(map[string]any)
+	if err := idToken.Claims(&claims); err != nil {
+		return LoginSession{}, "", "", fmt.Errorf("invalid ID token claims: %w", err)
+	}
+	// Some providers (e.g. Authelia) only return groups and profile claims from userinfo
+	if info, err := provider.UserInfo(ctx, oauth2.StaticTokenSource(token)); err == nil && info.Subject == idToken.Subject {
+		extra := make(map[string]any)
+		if info.Claims(&extra) == nil {
+			for k, v := range extra {
+				if _, exists := claims[k]; !exists {
+					claims[k] = v
+				}
+			}
+		}
+	}
+
+	role, profile := cfg.mapClaims(claims)
+	username := oidcClaimString(claims, "preferred_username")
+	email := oidcClaimString(claims, "email")
+	name := cmp.Or(oidcClaimString(claims, "name"), username, email, idToken.Subject)
+	if role == "" {
+		Logger("auth").Warn("single sign-on refused: no role for user", "provider", cfg.ID, "user", name)
+		return LoginSession{}, "", "", fmt.Errorf("%s is not allowed to use this dashboard", name)
+	}
+
+	session, secret, err := GetTokenManager().CreateSession(LoginSession{
+		Provider:      cfg.ID,
+		Subject:       idToken.Subject,
+		Name:          name,
+		Email:         email,
+		Role:          role,
+		Profile:       profile,
+		SessionDevice: device,
+	}, cfg.sessionDuration())
+	if err != nil {
+		return LoginSession{}, "", "", err
+	}
+	Logger("auth").Info("signed in", "provider", cfg.ID, "user", name, "role", role)
+	return session, secret, pending.returnTo, nil
+}
+
+// mapClaims returns the highest role granted by the user's groups, verified email or
+// subject (DefaultRole if none), and the profile of the first matching group. Usernames
+// are not matched, as many providers let users change theirs.
+func (c OIDCConfig) mapClaims(claims map[string]any) (Role, string) {
+	var role Role
+	grant := func(r Role) {
+		if roleRank[r] > roleRank[role] {
+			role = r
+		}
+	}
+	groups := oidcClaimStrings(claims, cmp.Or(c.GroupsClaim, "groups"))
+	sort.Strings(groups)
+	for _, g := range groups {
+		if r, ok := c.Roles[g]; ok {
+			grant(r)
+		}
+	}
+	if email := oidcClaimString(claims, "email"); email != "" && claims["email_verified"] == true {
+		for user, r := range c.Users {
+			if strings.EqualFold(user, email) {
+				grant(r)
+			}
+		}
+	}
+	if subject := oidcClaimString(claims, "sub"); subject != "" {
+		if r, ok := c.Users[subject]; ok {
+			grant(r)
+		}
+	}
+	if role == "" {
+		role = c.DefaultRole
+	}
+
+	var profile string
+	for _, g := range groups {
+		if p, ok := c.Profiles[g]; ok {
+			profile = p
+			break
+		}
+	}
+	return role, profile
+}
+
+// oidcClaimStrings returns a claim at a dot path as a list of strings. A single string
+// value becomes a list of one.
+func oidcClaimStrings(claims map[string]any, path string) []string {
+	var v any = claims
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = obj[key]
+	}
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// oidcClaimString returns a top-level string claim.
+func oidcClaimString(claims map[string]any, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
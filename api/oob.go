@@ -0,0 +1,605 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oobCacheTTL is how long BMC readings are reused between requests. BMCs are slow and
+// some lock up when polled too often.
+const oobCacheTTL = 30 * time.Second
+
+// OOBPowerActions are the power actions accepted by /api/oob/power.
+var OOBPowerActions = map[string]struct{ Redfish, IPMI string }{
+	"on":        {"On", "on"},
+	"off":       {"GracefulShutdown", "soft"},
+	"force-off": {"ForceOff", "off"},
+	"reset":     {"ForceRestart", "reset"},
+	"cycle":     {"PowerCycle", "cycle"},
+}
+
+// OOBConfig configures the baseboard management controllers behind /api/oob.
+type OOBConfig struct {
+	Servers []OOBServer `json:"servers"`
+	// RemotePower allows power actions from clients outside the local network. They always
+	// need the oob.power capability and a server with powerActions set
+	RemotePower bool `json:"remotePower,omitempty"`
+}
+
+// OOBServer is one server reached through its BMC with Redfish or IPMI (ipmitool).
+type OOBServer struct {
+	Name   string `json:"name"`
+	Driver string `json:"driver"` // "redfish" or "ipmi"
+	// Address is the Redfish base URL (e.g. https://idrac.lan) or the IPMI host. Without it,
+	// ipmi uses the BMC of the local machine
+	Address  string `json:"address,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// PasswordFile and PasswordEnv read the password from a secret file or environment variable
+	PasswordFile string `json:"passwordFile,omitempty"`
+	PasswordEnv  string `json:"passwordEnv,omitempty"`
+	Insecure     bool   `json:"insecure,omitempty"`     // Accept the BMC's self-signed certificate (redfish)
+	PowerActions bool   `json:"powerActions,omitempty"` // Allow power actions on this server
+}
+
+// Validate checks the configured servers.
+func (c OOBConfig) Validate() error {
+	if len(c.Servers) == 0 {
+		return fmt.Errorf("oob: at least one server is required")
+	}
+	seen := make(map[string]bool)
+	for i, s := range c.Servers {
+		if s.Name == "" {
+			return fmt.Errorf("oob: servers[%d]: name is required", i)
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("oob: servers[%d]: duplicate name %s", i, s.Name)
+		}
+		seen[s.Name] = true
+		switch s.Driver {
+		case "redfish":
+			if !strings.HasPrefix(s.Address, "http://") && !strings.HasPrefix(s.Address, "https://") {
+				return fmt.Errorf("oob: servers[%d]: redfish address must start with http:// or https://", i)
+			}
+		case "ipmi":
+		default:
+			return fmt.Errorf("oob: servers[%d]: driver must be redfish or ipmi", i)
+		}
+		if s.Address != "" && s.Username == "" {
+			return fmt.Errorf("oob: servers[%d]: username is required", i)
+		}
+		if _, err := ResolveSecret(s.Password, s.PasswordFile, s.PasswordEnv); err != nil {
+			return fmt.Errorf("oob: servers[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// OOBSensor is one sensor reading of a server.
+type OOBSensor struct {
+	Name    string  `json:"name"`
+	Type    string  `json:"type"` // temperature, fan, voltage, power or other
+	Reading float64 `json:"reading"`
+	Unit    string  `json:"unit,omitempty"`   // e.g. "C", "RPM", "V", "W"
+	Status  string  `json:"status,omitempty"` // ok, warning or critical
+}
+
+// OOBEventLog counts the entries of the system event log (SEL).
+type OOBEventLog struct {
+	Entries  int `json:"entries"`
+	Warning  int `json:"warning,omitempty"`
+	Critical int `json:"critical,omitempty"`
+}
+
+// OOBStatus is the state of one server as reported by its BMC.
+type OOBStatus struct {
+	Name         string       `json:"name"`
+	Driver       string       `json:"driver"`
+	Model        string       `json:"model,omitempty"`
+	Manufacturer string       `json:"manufacturer,omitempty"`
+	PowerState   string       `json:"powerState"`       // on, off or unknown
+	Health       string       `json:"health,omitempty"` // ok, warning or critical
+	Sensors      []OOBSensor  `json:"sensors"`
+	EventLog     *OOBEventLog `json:"eventLog,omitempty"`
+	PowerActions bool         `json:"powerActions"`
+	Error        string       `json:"error,omitempty"`
+	Updated      time.Time    `json:"updated"`
+}
+
+// OOBMonitor reads the configured BMCs and runs power actions.
+type OOBMonitor struct {
+	mu      sync.Mutex
+	config  *OOBConfig
+	cached  []OOBStatus
+	fetched time.Time
+}
+
+// Global out-of-band monitor instance
+var oobMonitor = &OOBMonitor{}
+
+// GetOOBMonitor returns the global out-of-band monitor instance.
+func GetOOBMonitor() *OOBMonitor {
+	return oobMonitor
+}
+
+// Configure sets the servers to read.
+func (om *OOBMonitor) Configure(cfg OOBConfig) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.config = &cfg
+	om.cached = nil
+}
+
+// Enabled reports whether any server is configured.
+func (om *OOBMonitor) Enabled() bool {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	return om.config != nil && len(om.config.Servers) > 0
+}
+
+// RemotePower reports whether power actions are allowed from outside the local network.
+func (om *OOBMonitor) RemotePower() bool {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	return om.config != nil && om.config.RemotePower
+}
+
+// Status reads every server, reusing readings younger than oobCacheTTL.
+func (om *OOBMonitor) Status(ctx context.Context) []OOBStatus {
+	om.mu.Lock()
+	if om.cached != nil && time.Since(om.fetched) < oobCacheTTL {
+		status := om.cached
+		om.mu.Unlock()
+		return status
+	}
+	servers := append([]OOBServer(nil), om.config.Servers...)
+	om.mu.Unlock()
+
+	status := make([]OOBStatus, len(servers))
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status[i] = readOOB(ctx, s)
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, s := range status {
+		if s.Error != "" {
+			errs = append(errs, fmt.Errorf("%s: %s", s.Name, s.Error))
+		}
+	}
+	GetModuleHealth().Record("oob", errors.Join(errs...))
+
+	om.mu.Lock()
+	om.cached = status
+	om.fetched = time.Now()
+	om.mu.Unlock()
+	return status
+}
+
+// Power runs a power action on a server that allows them.
+func (om *OOBMonitor) Power(ctx context.Context, name, action string) error {
+	actions, ok := OOBPowerActions[action]
+	if !ok {
+		return fmt.Errorf("unknown action %q", action)
+	}
+	om.mu.Lock()
+	var server *OOBServer
+	if om.config != nil {
+		for i := range om.config.Servers {
+			if om.config.Servers[i].Name == name {
+				server = &om.config.Servers[i]
+			}
+		}
+	}
+	om.mu.Unlock()
+	if server == nil {
+		return fmt.Errorf("unknown server %q", name)
+	}
+	if !server.PowerActions {
+		return fmt.Errorf("power actions are not enabled for %s", name)
+	}
+
+	var err error
+	if server.Driver == "redfish" {
+		err = redfishPower(ctx, *server, actions.Redfish)
+	} else {
+		_, err = runIPMITool(ctx, *server, "chassis", "power", actions.IPMI)
+	}
+	if err != nil {
+		return err
+	}
+	Logger("oob").Warn("power action sent", "server", name, "action", action)
+	GetTimeline().Add(TimelineEvent{
+		Source:   TimelineSourcePower,
+		Title:    "Power " + action + " sent to " + name,
+		Severity: "warning",
+	})
+
+	// Read the new power state on the next request
+	om.mu.Lock()
+	om.cached = nil
+	om.mu.Unlock()
+	return nil
+}
+
+// readOOB reads one server.
+func readOOB(ctx context.Context, s OOBServer) OOBStatus {
+	status := OOBStatus{Name: s.Name, Driver: s.Driver, PowerState: "unknown", Sensors: []OOBSensor{}, PowerActions: s.PowerActions, Updated: time.Now()}
+	var err error
+	if s.Driver == "redfish" {
+		err = readRedfish(ctx, s, &status)
+	} else {
+		err = readIPMI(ctx, s, &status)
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// redfishClient talks to one Redfish service with basic authentication.
+type redfishClient struct {
+	server   OOBServer
+	password string
+	client   *http.Client
+}
+
+// newRedfishClient creates a client for a server.
+func newRedfishClient(s OOBServer) (*redfishClient, error) {
+	password, err := ResolveSecret(s.Password, s.PasswordFile, s.PasswordEnv)
+	if err != nil {
+		return nil, err
+	}
+	return &redfishClient{
+		server:   s,
+		password: password,
+		client: &http.Client{
+			Timeout:   20 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: s.Insecure}},
+		},
+	}, nil
+}
+
+// do sends a request to a Redfish path (e.g. /redfish/v1/Systems) and decodes the reply.
+func (rc *redfishClient) do(ctx context.Context, method, path string, body, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(rc.server.Address, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(rc.server.Username, rc.password)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := rc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("redfish %s: %s", path, res.Status)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, 4<<20)).Decode(result)
+}
+
+// redfishLink is an @odata.id reference.
+type redfishLink struct {
+	ID string `json:"@odata.id"`
+}
+
+// redfishCollection is a Redfish collection.
+type redfishCollection struct {
+	Members []redfishLink `json:"Members"`
+	Count   *int          `json:"Members@odata.count"`
+}
+
+// redfishHealth is the Status object of a Redfish resource.
+type redfishHealth struct {
+	Health string `json:"Health"` // OK, Warning or Critical
+	State  string `json:"State"`  // e.g. Enabled, Absent
+}
+
+// first returns the first member of a collection.
+func (rc *redfishClient) first(ctx context.Context, path string) (string, error) {
+	var col redfishCollection
+	if err := rc.do(ctx, http.MethodGet, path, nil, &col); err != nil {
+		return "", err
+	}
+	if len(col.Members) == 0 {
+		return "", fmt.Errorf("redfish %s: no members", path)
+	}
+	return col.Members[0].ID, nil
+}
+
+// readRedfish reads the power state, health, thermal and power sensors and the event log.
+func readRedfish(ctx context.Context, s OOBServer, status *OOBStatus) error {
+	rc, err := newRedfishClient(s)
+	if err != nil {
+		return err
+	}
+	systemPath, err := rc.first(ctx, "/redfish/v1/Systems")
+	if err != nil {
+		return err
+	}
+	var system struct {
+		Manufacturer string        `json:"Manufacturer"`
+		Model        string        `json:"Model"`
+		PowerState   string        `json:"PowerState"`
+		Status       redfishHealth `json:"Status"`
+	}
+	if err := rc.do(ctx, http.MethodGet, systemPath, nil, &system); err != nil {
+		return err
+	}
+	status.Manufacturer, status.Model = system.Manufacturer, system.Model
+	status.PowerState = strings.ToLower(system.PowerState)
+	status.Health = redfishStatus(system.Status.Health)
+
+	// Sensors and the event log are best effort: not every BMC has them
+	if chassisPath, err := rc.first(ctx, "/redfish/v1/Chassis"); err == nil {
+		var thermal struct {
+			Temperatures []struct {
+				Name           string        `json:"Name"`
+				ReadingCelsius *float64      `json:"ReadingCelsius"`
+				Status         redfishHealth `json:"Status"`
+			} `json:"Temperatures"`
+			Fans []struct {
+				Name         string        `json:"Name"`
+				FanName      string        `json:"FanName"`
+				Reading      *float64      `json:"Reading"`
+				ReadingUnits string        `json:"ReadingUnits"`
+				Status       redfishHealth `json:"Status"`
+			} `json:"Fans"`
+		}
+		if err := rc.do(ctx, http.MethodGet, chassisPath+"/Thermal", nil, &thermal); err == nil {
+			for _, t := range thermal.Temperatures {
+				if t.ReadingCelsius != nil && t.Status.State != "Absent" {
+					status.Sensors = append(status.Sensors, OOBSensor{Name: t.Name, Type: "temperature", Reading: *t.ReadingCelsius, Unit: "C", Status: redfishStatus(t.Status.Health)})
+				}
+			}
+			for _, f := range thermal.Fans {
+				if f.Reading == nil || f.Status.State == "Absent" {
+					continue
+				}
+				name := f.Name
+				if name == "" {
+					name = f.FanName
+				}
+				unit := "RPM"
+				if f.ReadingUnits == "Percent" {
+					unit = "%"
+				}
+				status.Sensors = append(status.Sensors, OOBSensor{Name: name, Type: "fan", Reading: *f.Reading, Unit: unit, Status: redfishStatus(f.Status.Health)})
+			}
+		}
+		var power struct {
+			PowerControl []struct {
+				Name               string   `json:"Name"`
+				PowerConsumedWatts *float64 `json:"PowerConsumedWatts"`
+			} `json:"PowerControl"`
+			Voltages []struct {
+				Name         string        `json:"Name"`
+				ReadingVolts *float64      `json:"ReadingVolts"`
+				Status       redfishHealth `json:"Status"`
+			} `json:"Voltages"`
+		}
+		if err := rc.do(ctx, http.MethodGet, chassisPath+"/Power", nil, &power); err == nil {
+			for _, p := range power.PowerControl {
+				if p.PowerConsumedWatts != nil {
+					name := p.Name
+					if name == "" {
+						name = "Power consumption"
+					}
+					status.Sensors = append(status.Sensors, OOBSensor{Name: name, Type: "power", Reading: *p.PowerConsumedWatts, Unit: "W", Status: "ok"})
+				}
+			}
+			for _, v := range power.Voltages {
+				if v.ReadingVolts != nil && v.Status.State != "Absent" {
+					status.Sensors = append(status.Sensors, OOBSensor{Name: v.Name, Type: "voltage", Reading: *v.ReadingVolts, Unit: "V", Status: redfishStatus(v.Status.Health)})
+				}
+			}
+		}
+	}
+	if managerPath, err := rc.first(ctx, "/redfish/v1/Managers"); err == nil {
+		status.EventLog = rc.eventLog(ctx, managerPath)
+	}
+	return nil
+}
+
+// eventLog counts the entries of the manager's SEL (Dell "Sel", HPE "IEL", others "SEL").
+func (rc *redfishClient) eventLog(ctx context.Context, managerPath string) *OOBEventLog {
+	var services redfishCollection
+	if err := rc.do(ctx, http.MethodGet, managerPath+"/LogServices", nil, &services); err != nil {
+		return nil
+	}
+	for _, m := range services.Members {
+		id := m.ID[strings.LastIndex(m.ID, "/")+1:]
+		if !strings.EqualFold(id, "sel") && !strings.EqualFold(id, "iel") {
+			continue
+		}
+		var entries struct {
+			Members []struct {
+				Severity string `json:"Severity"` // OK, Warning or Critical
+			} `json:"Members"`
+			Count *int `json:"Members@odata.count"`
+		}
+		if err := rc.do(ctx, http.MethodGet, m.ID+"/Entries", nil, &entries); err != nil {
+			return nil
+		}
+		log := &OOBEventLog{Entries: len(entries.Members)}
+		if entries.Count != nil {
+			log.Entries = *entries.Count
+		}
+		// Severities are counted on the first page of entries
+		for _, e := range entries.Members {
+			switch e.Severity {
+			case "Warning":
+				log.Warning++
+			case "Critical":
+				log.Critical++
+			}
+		}
+		return log
+	}
+	return nil
+}
+
+// redfishPower sends a ComputerSystem.Reset action.
+func redfishPower(ctx context.Context, s OOBServer, resetType string) error {
+	rc, err := newRedfishClient(s)
+	if err != nil {
+		return err
+	}
+	systemPath, err := rc.first(ctx, "/redfish/v1/Systems")
+	if err != nil {
+		return err
+	}
+	return rc.do(ctx, http.MethodPost, systemPath+"/Actions/ComputerSystem.Reset", map[string]string{"ResetType": resetType}, nil)
+}
+
+// redfishStatus turns a Redfish health into ok, warning or critical.
+func redfishStatus(health string) string {
+	switch strings.ToLower(health) {
+	case "ok":
+		return "ok"
+	case "warning":
+		return "warning"
+	case "critical":
+		return "critical"
+	}
+	return ""
+}
+
+// runIPMITool runs ipmitool against a server. The password is passed in the environment
+// so that it does not show up in the process list.
+func runIPMITool(ctx context.Context, s OOBServer, args ...string) ([]byte, error) {
+	var base []string
+	env := os.Environ()
+	if s.Address != "" {
+		password, err := ResolveSecret(s.Password, s.PasswordFile, s.PasswordEnv)
+		if err != nil {
+			return nil, err
+		}
+		base = []string{"-I", "lanplus", "-H", s.Address, "-U", s.Username, "-E"}
+		env = append(env, "IPMI_PASSWORD="+password)
+	}
+	cmd := exec.CommandContext(ctx, "ipmitool", append(base, args...)...)
+	cmd.Env = env
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("ipmitool: %s", msg)
+		}
+		return nil, fmt.Errorf("ipmitool: %w", err)
+	}
+	return out, nil
+}
+
+// readIPMI reads the chassis power state, the sensors and the SEL with ipmitool.
+func readIPMI(ctx context.Context, s OOBServer, status *OOBStatus) error {
+	out, err := runIPMITool(ctx, s, "chassis", "power", "status")
+	if err != nil {
+		return err
+	}
+	// "Chassis Power is on"
+	fields := strings.Fields(string(out))
+	if len(fields) > 0 {
+		status.PowerState = strings.ToLower(fields[len(fields)-1])
+	}
+
+	if out, err := runIPMITool(ctx, s, "sensor"); err == nil {
+		status.Sensors = parseIPMISensors(out)
+		status.Health = "ok"
+		for _, sensor := range status.Sensors {
+			if sensor.Status == "critical" || (sensor.Status == "warning" && status.Health == "ok") {
+				status.Health = sensor.Status
+			}
+		}
+	}
+	if out, err := runIPMITool(ctx, s, "sel", "info"); err == nil {
+		scanner := bufio.NewScanner(bytes.NewReader(out))
+		for scanner.Scan() {
+			key, value, ok := strings.Cut(scanner.Text(), ":")
+			if ok && strings.TrimSpace(key) == "Entries" {
+				if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+					status.EventLog = &OOBEventLog{Entries: n}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parseIPMISensors parses `ipmitool sensor`: name | reading | unit | status | thresholds.
+func parseIPMISensors(out []byte) []OOBSensor {
+	sensors := []OOBSensor{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		cols := strings.Split(scanner.Text(), "|")
+		if len(cols) < 4 {
+			continue
+		}
+		for i := range cols {
+			cols[i] = strings.TrimSpace(cols[i])
+		}
+		reading, err := strconv.ParseFloat(cols[1], 64)
+		if err != nil {
+			continue // "na" for absent sensors, or discrete sensors
+		}
+		sensor := OOBSensor{Name: cols[0], Reading: reading, Type: "other", Unit: cols[2]}
+		switch cols[2] {
+		case "degrees C":
+			sensor.Type, sensor.Unit = "temperature", "C"
+		case "RPM":
+			sensor.Type = "fan"
+		case "percent":
+			sensor.Unit = "%"
+		case "Volts":
+			sensor.Type, sensor.Unit = "voltage", "V"
+		case "Watts":
+			sensor.Type, sensor.Unit = "power", "W"
+		case "Amps":
+			sensor.Unit = "A"
+		}
+		switch cols[3] {
+		case "ok":
+			sensor.Status = "ok"
+		case "nc":
+			sensor.Status = "warning"
+		case "cr", "nr":
+			sensor.Status = "critical"
+		}
+		sensors = append(sensors, sensor)
+	}
+	return sensors
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ubusNullSession is the anonymous session used to log in.
+const ubusNullSession = "00000000000000000000000000000000"
+
+// ErrUbusAccessDenied is returned when the ubus session is missing or expired.
+var ErrUbusAccessDenied = errors.New("ubus: access denied")
+
+// ErrUbusLogin is returned when the router rejects the configured credentials.
+var ErrUbusLogin = errors.New("ubus login failed")
+
+// OpenWrtConfig configures access to an OpenWrt router through rpcd/ubus over HTTP (uhttpd-mod-ubus).
+type OpenWrtConfig struct {
+	URL          string `json:"url"` // e.g. http://192.168.1.1 (the /ubus endpoint is appended)
+	Username     string `json:"username"`
+	Password     string `json:"password,omitempty"`
+	PasswordFile string `json:"passwordFile,omitempty"`
+	PasswordEnv  string `json:"passwordEnv,omitempty"`
+	Insecure     bool   `json:"insecure,omitempty"` // Skip TLS verification for self-signed router certificates
+}
+
+// Validate checks the OpenWrt configuration.
+func (c OpenWrtConfig) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if c.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if _, err := ResolveSecret(c.Password, c.PasswordFile, c.PasswordEnv); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UbusClient calls ubus methods on an OpenWrt router, logging in as needed.
+type UbusClient struct {
+	mu      sync.Mutex
+	config  OpenWrtConfig
+	client  *http.Client
+	session string
+	id      int
+}
+
+// NewUbusClient creates a ubus client for a router.
+func NewUbusClient(cfg OpenWrtConfig) *UbusClient {
+	return &UbusClient{
+		config: cfg,
+		client: &http.Client{
+			Timeout: 15 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.Insecure},
+			},
+		},
+	}
+}
+
+// rpc performs one JSON-RPC call and decodes the data of the ubus reply into result.
+func (c *UbusClient) rpc(ctx context.Context, session, object, method string, args any, result any) error {
+	if args == nil {
+		args = map[string]any{}
+	}
+	c.mu.Lock()
+	c.id++
+	id := c.id
+	c.mu.Unlock()
+
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  "call",
+		"params":  []any{session, object, method, args},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(c.config.URL, "/")+"/ubus", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return errors.New("ubus http status " + res.Status)
+	}
+
+	var reply struct {
+		Result []json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&reply); err != nil {
+		return err
+	}
+	if reply.Error != nil {
+		if reply.Error.Code == -32002 {
+			return ErrUbusAccessDenied
+		}
+		return fmt.Errorf("ubus %s.%s: %s", object, method, reply.Error.Message)
+	}
+	if len(reply.Result) == 0 {
+		return fmt.Errorf("ubus %s.%s: empty reply", object, method)
+	}
+	var status int
+	if err := json.Unmarshal(reply.Result[0], &status); err != nil {
+		return err
+	}
+	switch status {
+	case 0:
+	case 6:
+		return ErrUbusAccessDenied
+	default:
+		return fmt.Errorf("ubus %s.%s: status %d", object, method, status)
+	}
+	if result != nil && len(reply.Result) > 1 {
+		return json.Unmarshal(reply.Result[1], result)
+	}
+	return nil
+}
+
+// login creates a new ubus session.
+func (c *UbusClient) login(ctx context.Context) (string, error) {
+	password, err := ResolveSecret(c.config.Password, c.config.PasswordFile, c.config.PasswordEnv)
+	if err != nil {
+		return "", err
+	}
+	var reply struct {
+		Session string `json:"ubus_rpc_session"`
+	}
+	err = c.rpc(ctx, ubusNullSession, "session", "login", map[string]any{
+		"username": c.config.Username,
+		"password": password,
+	}, &reply)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUbusLogin, err)
+	}
+
+	c.mu.Lock()
+	c.session = reply.Session
+	c.mu.Unlock()
+	return reply.Session, nil
+}
+
+// Call invokes a ubus method, logging in first and again once if the session expired.
+func (c *UbusClient) Call(ctx context.Context, object, method string, args any, result any) error {
+	c.mu.Lock()
+	session := c.session
+	c.mu.Unlock()
+
+	if session == "" {
+		var err error
+		if session, err = c.login(ctx); err != nil {
+			return err
+		}
+	}
+	err := c.rpc(ctx, session, object, method, args, result)
+	if errors.Is(err, ErrUbusAccessDenied) {
+		if session, err = c.login(ctx); err != nil {
+			return err
+		}
+		err = c.rpc(ctx, session, object, method, args, result)
+	}
+	return err
+}
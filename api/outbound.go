@@ -0,0 +1,299 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Private address policies for outbound requests.
+const (
+	OutboundPrivateLocal = "local" // Private addresses only for local or token-authenticated clients
+	OutboundPrivateAllow = "allow"
+	OutboundPrivateDeny  = "deny"
+)
+
+// ErrOutboundBlocked is returned when a destination is not allowed by the outbound policy.
+var ErrOutboundBlocked = errors.New("destination not allowed")
+
+// OutboundConfig restricts the addresses that URL-fetching endpoints (favicons, RSS,
+// monitors, ICS, SNMP) may reach on behalf of clients.
+type OutboundConfig struct {
+	// Private is "local" (default), "allow" or "deny" for loopback, RFC1918, CGNAT and ULA addresses
+	Private string   `json:"private,omitempty"`
+	Allow   []string `json:"allow,omitempty"`   // CIDRs or host names that are always allowed
+	Deny    []string `json:"deny,omitempty"`    // CIDRs or host names that are always denied
+	Schemes []string `json:"schemes,omitempty"` // URL schemes, default http and https
+}
+
+// outboundPolicy is the parsed OutboundConfig.
+type outboundPolicy struct {
+	private    string
+	allowNets  []*net.IPNet
+	allowHosts []string
+	denyNets   []*net.IPNet
+	denyHosts  []string
+	schemes    []string
+}
+
+var outbound = struct {
+	mu     sync.RWMutex
+	policy outboundPolicy
+}{policy: outboundPolicy{private: OutboundPrivateLocal, schemes: []string{"http", "https"}}}
+
+// privateNets are reachable depending on the private policy.
+var privateNets = mustParseCIDRs("127.0.0.0/8", "::1/128", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7")
+
+// blockedNets are never reachable unless explicitly allowed: link-local (including cloud
+// metadata at 169.254.169.254), "this network", multicast and broadcast.
+var blockedNets = mustParseCIDRs("169.254.0.0/16", "fe80::/10", "0.0.0.0/8", "::/128", "224.0.0.0/4", "ff00::/8", "255.255.255.255/32")
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// parseOutboundList splits a list into networks (CIDRs or IPs) and lower-cased host names.
+func parseOutboundList(entries []string) ([]*net.IPNet, []string, error) {
+	var nets []*net.IPNet
+	var hosts []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") || net.ParseIP(entry) != nil {
+			parsed, err := ParseTrustedProxies([]string{entry})
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid address %q", entry)
+			}
+			nets = append(nets, parsed...)
+			continue
+		}
+		hosts = append(hosts, strings.ToLower(strings.TrimSuffix(entry, ".")))
+	}
+	return nets, hosts, nil
+}
+
+// Validate checks the outbound configuration.
+func (c OutboundConfig) Validate() error {
+	_, err := c.policy()
+	return err
+}
+
+// policy parses the configuration.
+func (c OutboundConfig) policy() (outboundPolicy, error) {
+	p := outboundPolicy{private: c.Private, schemes: slices.Clone(c.Schemes)}
+	switch p.private {
+	case "":
+		p.private = OutboundPrivateLocal
+	case OutboundPrivateLocal, OutboundPrivateAllow, OutboundPrivateDeny:
+	default:
+		return p, fmt.Errorf("outbound: private must be local, allow or deny")
+	}
+	if len(p.schemes) == 0 {
+		p.schemes = []string{"http", "https"}
+	}
+	for i, s := range p.schemes {
+		p.schemes[i] = strings.ToLower(s)
+	}
+	var err error
+	if p.allowNets, p.allowHosts, err = parseOutboundList(c.Allow); err != nil {
+		return p, fmt.Errorf("outbound.allow: %w", err)
+	}
+	if p.denyNets, p.denyHosts, err = parseOutboundList(c.Deny); err != nil {
+		return p, fmt.Errorf("outbound.deny: %w", err)
+	}
+	return p, nil
+}
+
+// ConfigureOutbound replaces the outbound policy.
+func ConfigureOutbound(cfg OutboundConfig) error {
+	p, err := cfg.policy()
+	if err != nil {
+		return err
+	}
+	outbound.mu.Lock()
+	outbound.policy = p
+	outbound.mu.Unlock()
+	return nil
+}
+
+// outboundUntrustedKey marks a context whose requests were made on behalf of a remote,
+// anonymous client.
+type outboundUntrustedKey struct{}
+
+// OutboundContext returns the request context, marked as untrusted when the client is
+// neither local nor signed in with an API token. Background jobs use contexts without the
+// mark and may reach private addresses unless the policy denies them.
+func OutboundContext(r *http.Request) context.Context {
+	if IsLocalRequest(r) || !RequestIdentity(r).Anonymous {
+		return r.Context()
+	}
+	return context.WithValue(r.Context(), outboundUntrustedKey{}, true)
+}
+
+// outboundTrusted reports whether a context may reach private addresses under the local policy.
+func outboundTrusted(ctx context.Context) bool {
+	untrusted, _ := ctx.Value(outboundUntrustedKey{}).(bool)
+	return !untrusted
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHost applies the host name lists; it returns true if the host is explicitly allowed.
+func (p outboundPolicy) checkHost(host string) (bool, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if slices.Contains(p.denyHosts, host) {
+		return false, fmt.Errorf("%w: %s", ErrOutboundBlocked, host)
+	}
+	return slices.Contains(p.allowHosts, host), nil
+}
+
+// checkIP applies the address rules to a resolved IP.
+func (p outboundPolicy) checkIP(ctx context.Context, ip net.IP) error {
+	if containsIP(p.denyNets, ip) {
+		return fmt.Errorf("%w: %s", ErrOutboundBlocked, ip)
+	}
+	if containsIP(p.allowNets, ip) {
+		return nil
+	}
+	if containsIP(blockedNets, ip) {
+		return fmt.Errorf("%w: %s is a link-local, multicast or unspecified address", ErrOutboundBlocked, ip)
+	}
+	if containsIP(privateNets, ip) {
+		switch {
+		case p.private == OutboundPrivateDeny,
+			p.private == OutboundPrivateLocal && !outboundTrusted(ctx):
+			return fmt.Errorf("%w: %s is a private address", ErrOutboundBlocked, ip)
+		}
+	}
+	return nil
+}
+
+func currentOutboundPolicy() outboundPolicy {
+	outbound.mu.RLock()
+	defer outbound.mu.RUnlock()
+	return outbound.policy
+}
+
+// CheckOutboundURL checks the scheme and host of a URL before it is fetched. The
+// resolved addresses are checked again when connecting (see OutboundDialContext).
+func CheckOutboundURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %v", err)
+	}
+	p := currentOutboundPolicy()
+	if !slices.Contains(p.schemes, strings.ToLower(u.Scheme)) {
+		return fmt.Errorf("%w: scheme %q", ErrOutboundBlocked, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("invalid URL: missing host")
+	}
+	allowed, err := p.checkHost(u.Hostname())
+	if err != nil || allowed {
+		return err
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		return p.checkIP(ctx, ip)
+	}
+	return nil
+}
+
+// ResolveOutboundHost resolves a host and returns the first address allowed by the policy.
+// Connecting to the returned address instead of the name prevents DNS rebinding.
+func ResolveOutboundHost(ctx context.Context, host string) (net.IP, error) {
+	p := currentOutboundPolicy()
+	explicit, err := p.checkHost(host)
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+	if explicit && len(ips) > 0 {
+		return ips[0], nil
+	}
+	var firstErr error
+	for _, ip := range ips {
+		if err := p.checkIP(ctx, ip); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		return ip, nil
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no addresses for %s", host)
+	}
+	return nil, firstErr
+}
+
+// OutboundDialContext returns a dial function that resolves the host itself and only
+// connects to addresses allowed by the policy.
+func OutboundDialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ip, err := ResolveOutboundHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		return dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+	}
+}
+
+// NewOutboundTransport returns an HTTP transport that enforces the outbound policy on
+// every connection, including redirects.
+func NewOutboundTransport(tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		TLSClientConfig:     tlsConfig,
+		DialContext:         OutboundDialContext(&net.Dialer{Timeout: 10 * time.Second}),
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
+
+// OutboundCheckRedirect limits redirects and applies the scheme and host rules to each target.
+func OutboundCheckRedirect(maxRedirects int) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return errors.New("too many redirects")
+		}
+		return CheckOutboundURL(req.Context(), req.URL.String())
+	}
+}
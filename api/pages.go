@@ -0,0 +1,281 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HomePage is the page every profile has. Its layout is the profile's layoutConfig.
+const HomePage = "home"
+
+// layoutPagesKey is the storage key of a profile's pages and the page it opens at.
+const layoutPagesKey = "layoutPages"
+
+// layoutPageKeyPrefix starts the storage keys of the layouts of pages other than home.
+const layoutPageKeyPrefix = "layoutConfig."
+
+// Limits of the pages of a profile.
+const (
+	maxLayoutPages    = 20
+	maxPageNameLength = 40
+)
+
+// ErrPageNotFound is returned when a page is not one of the profile's.
+var ErrPageNotFound = errors.New("page not found")
+
+// LayoutPage is a named layout of a profile, shown at /page/{id} (/p/{profile}/{id} for
+// named profiles) with its own set of modules.
+type LayoutPage struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Modules int    `json:"modules"` // Number of modules on the page, set when listed
+}
+
+// layoutPageList is the stored value of layoutPagesKey.
+type layoutPageList struct {
+	Pages  []LayoutPage `json:"pages"`            // Without home
+	Active string       `json:"active,omitempty"` // Page opened without one in the path
+}
+
+// pagesMu serializes changes of the page lists.
+var pagesMu sync.Mutex
+
+// LayoutPageKey returns the storage key of a page's layout.
+func LayoutPageKey(page string) string {
+	if page == "" || page == HomePage {
+		return "layoutConfig"
+	}
+	return layoutPageKeyPrefix + page
+}
+
+// LayoutPageOfKey returns the page of an unprefixed layout storage key.
+func LayoutPageOfKey(key string) (string, bool) {
+	if key == "layoutConfig" {
+		return HomePage, true
+	}
+	page, ok := strings.CutPrefix(key, layoutPageKeyPrefix)
+	return page, ok && ValidPageID(page)
+}
+
+// ValidPageID reports whether id can name a page.
+func ValidPageID(id string) bool {
+	return profileNamePattern.MatchString(id)
+}
+
+// loadPages returns the stored page list of a profile.
+func loadPages(profile string) layoutPageList {
+	var list layoutPageList
+	GetStorage().GetAsForProfile(profile, layoutPagesKey, &list)
+	return list
+}
+
+// savePages stores the page list of a profile. Open dashboards update their tabs on the
+// storage update.
+func savePages(profile string, list layoutPageList) {
+	GetStorage().SetNext(ProfileStorageKey(profile, layoutPagesKey), list)
+}
+
+// Pages returns the pages of a profile, home first, and the page it opens at.
+func Pages(profile string) ([]LayoutPage, string) {
+	list := loadPages(profile)
+	pages := append([]LayoutPage{{ID: HomePage, Name: "Home"}}, list.Pages...)
+	for i := range pages {
+		var layout LayoutConfig
+		if GetStorage().GetAsForProfile(profile, LayoutPageKey(pages[i].ID), &layout) {
+			pages[i].Modules = len(layout.Modules)
+		}
+	}
+	active := list.Active
+	if !PageExists(profile, active) {
+		active = HomePage
+	}
+	return pages, active
+}
+
+// PageExists reports whether a profile has a page.
+func PageExists(profile, id string) bool {
+	if id == HomePage {
+		return true
+	}
+	return slices.ContainsFunc(loadPages(profile).Pages, func(p LayoutPage) bool { return p.ID == id })
+}
+
+// ActivePage returns the page a profile opens at.
+func ActivePage(profile string) string {
+	_, active := Pages(profile)
+	return active
+}
+
+// CreatePage adds a page showing the given modules, laid out on the columns of the home
+// page. The ID is derived from the name.
+func CreatePage(profile, name string, modules []string) (LayoutPage, error) {
+	var home LayoutConfig
+	GetStorage().GetAsForProfile(profile, LayoutPageKey(HomePage), &home)
+	layout := LayoutConfig{MaxWidth: home.MaxWidth, Columns: home.Columns, Modules: []LayoutModule{}}
+	if layout.MaxWidth < 1 || layout.MaxWidth > 100 {
+		layout.MaxWidth = 80
+	}
+	if layout.Columns < 1 || layout.Columns > 12 {
+		layout.Columns = 3
+	}
+	for _, id := range modules {
+		id = strings.TrimSpace(id)
+		if id == "" || slices.ContainsFunc(layout.Modules, func(m LayoutModule) bool { return m.ID == id }) {
+			continue
+		}
+		if err := ValidModuleID(id); err != nil {
+			return LayoutPage{}, err
+		}
+		if !KnownModuleID(id) {
+			return LayoutPage{}, fmt.Errorf("unknown module %q", id)
+		}
+		layout.Modules = append(layout.Modules, LayoutModule{ID: id, Span: 1})
+	}
+	return addPage(profile, name, layout)
+}
+
+// ClonePage adds a page with a copy of the layout of another page.
+func ClonePage(profile, from, name string) (LayoutPage, error) {
+	if !PageExists(profile, from) {
+		return LayoutPage{}, ErrPageNotFound
+	}
+	item, exists := GetStorage().GetForProfile(profile, LayoutPageKey(from))
+	if !exists {
+		return LayoutPage{}, fmt.Errorf("page %s has no layout yet", from)
+	}
+	return addPage(profile, name, item.Value)
+}
+
+// addPage stores a new page and its layout.
+func addPage(profile, name string, layout any) (LayoutPage, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return LayoutPage{}, errors.New("name is required")
+	}
+	if len([]rune(name)) > maxPageNameLength {
+		return LayoutPage{}, fmt.Errorf("name must be at most %d characters", maxPageNameLength)
+	}
+
+	pagesMu.Lock()
+	defer pagesMu.Unlock()
+	list := loadPages(profile)
+	if len(list.Pages) >= maxLayoutPages-1 {
+		return LayoutPage{}, fmt.Errorf("page limit of %d reached", maxLayoutPages)
+	}
+	page := LayoutPage{ID: newPageID(name, list.Pages), Name: name}
+	GetStorage().SetNext(ProfileStorageKey(profile, LayoutPageKey(page.ID)), layout)
+	list.Pages = append(list.Pages, page)
+	page.Modules = layoutModuleCount(layout)
+	savePages(profile, list)
+	return page, nil
+}
+
+// newPageID derives a free page ID from a name: "Media Room" becomes media-room.
+func newPageID(name string, pages []LayoutPage) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteRune('-')
+		}
+	}
+	base := strings.Trim(b.String(), "-")
+	if len(base) > 28 {
+		base = strings.TrimRight(base[:28], "-")
+	}
+	if base == "" {
+		base = "page"
+	}
+	taken := func(id string) bool {
+		return id == HomePage || slices.ContainsFunc(pages, func(p LayoutPage) bool { return p.ID == id })
+	}
+	id := base
+	for n := 2; taken(id); n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+	return id
+}
+
+// SwitchPage makes a page the one the profile opens at when the path names none.
+func SwitchPage(profile, id string) error {
+	pagesMu.Lock()
+	defer pagesMu.Unlock()
+	if !PageExists(profile, id) {
+		return ErrPageNotFound
+	}
+	list := loadPages(profile)
+	if id == HomePage {
+		id = ""
+	}
+	list.Active = id
+	savePages(profile, list)
+	return nil
+}
+
+// DeletePage removes a page and its layout. The home page cannot be removed.
+func DeletePage(profile, id string) error {
+	if id == HomePage {
+		return errors.New("the home page cannot be deleted")
+	}
+	pagesMu.Lock()
+	defer pagesMu.Unlock()
+	list := loadPages(profile)
+	i := slices.IndexFunc(list.Pages, func(p LayoutPage) bool { return p.ID == id })
+	if i < 0 {
+		return ErrPageNotFound
+	}
+	list.Pages = slices.Delete(list.Pages, i, i+1)
+	if list.Active == id {
+		list.Active = ""
+	}
+	GetStorage().Delete(ProfileStorageKey(profile, LayoutPageKey(id)))
+	GetLayoutHistory().DeletePage(profile, id)
+	savePages(profile, list)
+	return nil
+}
+
+// PageFromRequest returns the page of a profile named by ?page=, home when there is none,
+// and whether the profile has it.
+func PageFromRequest(r *http.Request, profile string) (string, bool) {
+	page := r.URL.Query().Get("page")
+	if page == "" {
+		return HomePage, true
+	}
+	return page, ValidPageID(page) && PageExists(profile, page)
+}
+
+// DashboardPath returns the profile and page of a dashboard path: /, /page/{page},
+// /p/{profile} or /p/{profile}/{page}. The page is "" when the path names none.
+func DashboardPath(path string) (profile, page string, ok bool) {
+	path = strings.TrimSuffix(path, "/")
+	if path == "" {
+		return "", "", true
+	}
+	if rest, found := strings.CutPrefix(path, "/page/"); found {
+		return "", rest, ValidPageID(rest)
+	}
+	rest, found := strings.CutPrefix(path, "/p/")
+	if !found {
+		return "", "", false
+	}
+	profile, page, hasPage := strings.Cut(rest, "/")
+	if !ValidProfileName(profile) || (hasPage && !ValidPageID(page)) {
+		return "", "", false
+	}
+	return profile, page, true
+}
+
+// PagePath returns the dashboard path of a page of a profile.
+func PagePath(profile, page string) string {
+	if profile == "" || profile == DefaultProfile {
+		return "/page/" + page
+	}
+	return "/p/" + profile + "/" + page
+}
@@ -0,0 +1,347 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Presence states reported for a person.
+const (
+	PresenceHome    = "home"
+	PresenceAway    = "away"
+	PresenceUnknown = "unknown"
+)
+
+// defaultHomeRadius is the OwnTracks home radius in meters when none is configured.
+const defaultHomeRadius = 100
+
+// ErrPresenceNotLocal is returned when presence is requested from outside the local network.
+var ErrPresenceNotLocal = errors.New("presence is only visible on the local network")
+
+// PresenceConfig configures the presence widget.
+type PresenceConfig struct {
+	HomeAssistant *HomeAssistantPresenceConfig `json:"homeAssistant,omitempty"`
+	OwnTracks     *OwnTracksPresenceConfig     `json:"ownTracks,omitempty"`
+	// Public also shows presence to clients outside the local network (default: local only)
+	Public bool `json:"public,omitempty"`
+	// ShowZones shows zone and region names instead of only home/away
+	ShowZones bool `json:"showZones,omitempty"`
+	// Hidden lists people that are never shown (by name or entity/user ID)
+	Hidden []string `json:"hidden,omitempty"`
+}
+
+// HomeAssistantPresenceConfig polls person or device_tracker entities from Home Assistant.
+type HomeAssistantPresenceConfig struct {
+	URL       string   `json:"url"`
+	Token     string   `json:"token,omitempty"`
+	TokenFile string   `json:"tokenFile,omitempty"`
+	TokenEnv  string   `json:"tokenEnv,omitempty"`
+	Entities  []string `json:"entities,omitempty"` // Default: all person.* entities
+	Interval  string   `json:"interval,omitempty"` // Poll interval, default "1m"
+}
+
+// OwnTracksPresenceConfig accepts OwnTracks HTTP mode updates on /api/presence/owntracks.
+type OwnTracksPresenceConfig struct {
+	Token     string  `json:"token"` // Required as ?token= in the OwnTracks URL
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Radius    float64 `json:"radius,omitempty"` // Meters, default 100
+}
+
+// Validate checks the presence configuration.
+func (c PresenceConfig) Validate() error {
+	if ha := c.HomeAssistant; ha != nil {
+		if ha.URL == "" {
+			return fmt.Errorf("presence.homeAssistant: url is required")
+		}
+		if _, err := ResolveSecret(ha.Token, ha.TokenFile, ha.TokenEnv); err != nil {
+			return fmt.Errorf("presence.homeAssistant: %w", err)
+		}
+		if ha.Interval != "" {
+			if d, err := time.ParseDuration(ha.Interval); err != nil || d < 10*time.Second {
+				return fmt.Errorf("presence.homeAssistant: interval must be a duration of at least 10s")
+			}
+		}
+	}
+	if ot := c.OwnTracks; ot != nil {
+		if ot.Token == "" {
+			return fmt.Errorf("presence.ownTracks: token is required")
+		}
+		if ot.Radius < 0 {
+			return fmt.Errorf("presence.ownTracks: radius must not be negative")
+		}
+	}
+	return nil
+}
+
+// Person is the presence of one tracked person. Coordinates are never stored.
+type Person struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	State   string    `json:"state"` // home, away or unknown
+	Zone    string    `json:"zone,omitempty"`
+	Source  string    `json:"source"` // homeassistant or owntracks
+	Since   time.Time `json:"since"`
+	Updated time.Time `json:"updated"`
+}
+
+// PresenceManager tracks who is home from Home Assistant and OwnTracks.
+type PresenceManager struct {
+	mu      sync.Mutex
+	config  PresenceConfig
+	people  map[string]*Person
+	lastErr string
+}
+
+// Global presence manager instance
+var presenceManager = &PresenceManager{people: make(map[string]*Person)}
+
+// GetPresenceManager returns the global presence manager instance.
+func GetPresenceManager() *PresenceManager {
+	return presenceManager
+}
+
+// Configure sets the presence sources and privacy options.
+func (pm *PresenceManager) Configure(cfg PresenceConfig) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.config = cfg
+}
+
+// Enabled reports whether any presence source is configured.
+func (pm *PresenceManager) Enabled() bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return pm.config.HomeAssistant != nil || pm.config.OwnTracks != nil
+}
+
+// Start polls Home Assistant at the configured interval.
+func (pm *PresenceManager) Start() {
+	pm.mu.Lock()
+	ha := pm.config.HomeAssistant
+	pm.mu.Unlock()
+	if ha == nil {
+		return
+	}
+
+	interval := time.Minute
+	if d, err := time.ParseDuration(ha.Interval); err == nil {
+		interval = d
+	}
+
+	guardLoop("presence", func() { pm.pollHomeAssistant(*ha) })
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		guardLoop("presence", func() { pm.pollHomeAssistant(*ha) })
+	}
+}
+
+// pollHomeAssistant fetches entity states and updates the tracked people.
+func (pm *PresenceManager) pollHomeAssistant(cfg HomeAssistantPresenceConfig) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	people, err := fetchHomeAssistantPresence(ctx, cfg)
+	if err != nil {
+		GetDebugLogger().Logf("presence", "home assistant poll failed: %v", err)
+		pm.mu.Lock()
+		pm.lastErr = err.Error()
+		pm.mu.Unlock()
+		return
+	}
+
+	pm.mu.Lock()
+	pm.lastErr = ""
+	pm.mu.Unlock()
+	for _, p := range people {
+		pm.update(p)
+	}
+}
+
+// fetchHomeAssistantPresence reads person or device_tracker states from the Home Assistant REST API.
+func fetchHomeAssistantPresence(ctx context.Context, cfg HomeAssistantPresenceConfig) ([]Person, error) {
+	token, err := ResolveSecret(cfg.Token, cfg.TokenFile, cfg.TokenEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(cfg.URL, "/")+"/api/states", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, errors.New("home assistant http status " + res.Status)
+	}
+
+	var states []struct {
+		EntityID   string `json:"entity_id"`
+		State      string `json:"state"`
+		Attributes struct {
+			FriendlyName string `json:"friendly_name"`
+		} `json:"attributes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&states); err != nil {
+		return nil, err
+	}
+
+	var people []Person
+	for _, s := range states {
+		if len(cfg.Entities) > 0 {
+			if !slices.Contains(cfg.Entities, s.EntityID) {
+				continue
+			}
+		} else if !strings.HasPrefix(s.EntityID, "person.") {
+			continue
+		}
+		p := Person{ID: s.EntityID, Name: s.Attributes.FriendlyName, Source: "homeassistant"}
+		if p.Name == "" {
+			p.Name = s.EntityID
+		}
+		switch s.State {
+		case "home":
+			p.State = PresenceHome
+		case "not_home":
+			p.State = PresenceAway
+		case "unknown", "unavailable", "":
+			p.State = PresenceUnknown
+		default:
+			// Any other state is the name of a Home Assistant zone
+			p.State = PresenceAway
+			p.Zone = s.State
+		}
+		people = append(people, p)
+	}
+	return people, nil
+}
+
+// update stores a person's presence, keeping the time of the last state change.
+func (pm *PresenceManager) update(p Person) {
+	now := time.Now()
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	p.Updated = now
+	p.Since = now
+	if prev, ok := pm.people[p.ID]; ok && prev.State == p.State {
+		p.Since = prev.Since
+	}
+	pm.people[p.ID] = &p
+}
+
+// OwnTracksMessage is the subset of an OwnTracks HTTP payload used for presence.
+type OwnTracksMessage struct {
+	Type      string   `json:"_type"`
+	Latitude  float64  `json:"lat"`
+	Longitude float64  `json:"lon"`
+	InRegions []string `json:"inregions,omitempty"`
+	Event     string   `json:"event,omitempty"` // enter or leave for transitions
+	Desc      string   `json:"desc,omitempty"`  // Region of a transition
+}
+
+// HandleOwnTracks records a location or region transition for an OwnTracks user.
+// The position is only used to decide whether the user is home and is not kept.
+func (pm *PresenceManager) HandleOwnTracks(token, user string, msg OwnTracksMessage) error {
+	pm.mu.Lock()
+	ot := pm.config.OwnTracks
+	pm.mu.Unlock()
+	if ot == nil || token != ot.Token {
+		return ErrUnknownWebhookToken
+	}
+	if user == "" {
+		return fmt.Errorf("missing OwnTracks user")
+	}
+
+	p := Person{ID: "owntracks." + strings.ToLower(user), Name: user, Source: "owntracks"}
+	switch msg.Type {
+	case "location":
+		radius := ot.Radius
+		if radius == 0 {
+			radius = defaultHomeRadius
+		}
+		if haversineMeters(ot.Latitude, ot.Longitude, msg.Latitude, msg.Longitude) <= radius {
+			p.State = PresenceHome
+		} else {
+			p.State = PresenceAway
+			for _, region := range msg.InRegions {
+				if !strings.EqualFold(region, "home") {
+					p.Zone = region
+					break
+				}
+			}
+		}
+	case "transition":
+		if !strings.EqualFold(msg.Desc, "home") {
+			return nil
+		}
+		p.State = PresenceAway
+		if msg.Event == "enter" {
+			p.State = PresenceHome
+		}
+	default:
+		return nil
+	}
+	pm.update(p)
+	return nil
+}
+
+// haversineMeters returns the great-circle distance between two coordinates.
+func haversineMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371000.0
+	toRad := func(d float64) float64 { return d * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// People returns the visible people for a request, applying the privacy options.
+// Clients signed in with an API token count as local.
+func (pm *PresenceManager) People(r *http.Request) ([]Person, error) {
+	trusted := IsLocalRequest(r) || !RequestIdentity(r).Anonymous
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if !pm.config.Public && !trusted {
+		return nil, ErrPresenceNotLocal
+	}
+
+	people := make([]Person, 0, len(pm.people))
+	for _, p := range pm.people {
+		if slices.Contains(pm.config.Hidden, p.ID) || slices.Contains(pm.config.Hidden, p.Name) {
+			continue
+		}
+		person := *p
+		if !pm.config.ShowZones {
+			person.Zone = ""
+		}
+		people = append(people, person)
+	}
+	sort.Slice(people, func(i, j int) bool {
+		return people[i].Name < people[j].Name
+	})
+	return people, nil
+}
+
+// LastError returns the error of the last Home Assistant poll.
+func (pm *PresenceManager) LastError() string {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return pm.lastErr
+}
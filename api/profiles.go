@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile is the profile used when none is selected. Its keys are stored unprefixed.
+const DefaultProfile = "default"
+
+// profileKeyPrefix marks storage keys that belong to a named profile ("profile:<name>:<key>").
+const profileKeyPrefix = "profile:"
+
+// profileScopedKeys are the storage keys kept separately for each profile.
+// All other keys (todos, calendar, monitors, ...) are shared between profiles. The layouts
+// of pages (layoutConfig.{page}) are scoped too, see isProfileScopedKey.
+var profileScopedKeys = map[string]bool{
+	"layoutConfig":        true,
+	"layoutPages":         true,
+	"moduleOrder":         true,
+	"modulePrefs":         true,
+	"quicklinks":          true,
+	"quicklinksLayout":    true,
+	"quicklinksIconsOnly": true,
+	"quicklinksEqualSize": true,
+	"template":            true,
+	"scheme":              true,
+	"localePrefs":         true,
+}
+
+// profileNamePattern restricts profile names to URL and key friendly characters.
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// ValidProfileName reports whether name can be used as a profile name.
+func ValidProfileName(name string) bool {
+	return profileNamePattern.MatchString(name)
+}
+
+// isProfileScopedKey reports whether a storage key is kept separately for each profile.
+func isProfileScopedKey(key string) bool {
+	if profileScopedKeys[key] {
+		return true
+	}
+	_, isPage := LayoutPageOfKey(key)
+	return isPage
+}
+
+// ProfileFromPath returns the profile of a dashboard path of the form /p/{profile} or
+// /p/{profile}/{page}.
+func ProfileFromPath(path string) (string, bool) {
+	profile, _, ok := DashboardPath(path)
+	if !ok || profile == "" {
+		return "", false
+	}
+	return profile, true
+}
+
+// ProfileFromRequest returns the profile selected by the ?profile= parameter, the profile
+// bound to the request's API token, or the default profile.
+func ProfileFromRequest(r *http.Request) string {
+	name := strings.ToLower(r.URL.Query().Get("profile"))
+	if name == "" {
+		name = RequestIdentity(r).Profile
+	}
+	if name == "" || !ValidProfileName(name) {
+		return DefaultProfile
+	}
+	return name
+}
+
+// ProfileStorageKey returns the storage key for a key in a profile.
+func ProfileStorageKey(profile, key string) string {
+	if profile == DefaultProfile || profile == "" || !isProfileScopedKey(key) {
+		return key
+	}
+	return profileKeyPrefix + profile + ":" + key
+}
+
+// ParseProfileStorageKey splits a storage key into its profile and key.
+func ParseProfileStorageKey(stored string) (string, string) {
+	rest, ok := strings.CutPrefix(stored, profileKeyPrefix)
+	if !ok {
+		return DefaultProfile, stored
+	}
+	profile, key, ok := strings.Cut(rest, ":")
+	if !ok {
+		return DefaultProfile, stored
+	}
+	return profile, key
+}
+
+// GetForProfile retrieves a key for a profile, falling back to the default profile
+// for profile-scoped keys the profile has not set yet.
+func (s *Storage) GetForProfile(profile, key string) (*StorageItem, bool) {
+	if item, exists := s.Get(ProfileStorageKey(profile, key)); exists {
+		return item, true
+	}
+	if profile != DefaultProfile && isProfileScopedKey(key) {
+		return s.Get(key)
+	}
+	return nil, false
+}
+
+// GetAsForProfile decodes a key of a profile into v, like GetAs.
+func (s *Storage) GetAsForProfile(profile, key string, v any) bool {
+	item, exists := s.GetForProfile(profile, key)
+	if !exists {
+		return false
+	}
+	data, err := json.Marshal(item.Value)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// GetAllForProfile returns all items visible to a profile, keyed by their unprefixed key.
+func (s *Storage) GetAllForProfile(profile string) map[string]*StorageItem {
+	all := s.GetAll()
+	result := make(map[string]*StorageItem)
+	for stored, item := range all {
+		p, key := ParseProfileStorageKey(stored)
+		switch {
+		case p == profile:
+			result[key] = item
+		case p == DefaultProfile:
+			// Default values of scoped keys are used until the profile overrides them
+			if _, overridden := all[ProfileStorageKey(profile, key)]; !isProfileScopedKey(key) || !overridden {
+				result[key] = item
+			}
+		}
+	}
+	return result
+}
+
+// Profiles returns the names of all profiles that have stored settings, including the default.
+func (s *Storage) Profiles() []string {
+	seen := map[string]bool{DefaultProfile: true}
+	for stored := range s.GetAll() {
+		profile, _ := ParseProfileStorageKey(stored)
+		seen[profile] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DeleteProfile removes all settings of a named profile.
+func (s *Storage) DeleteProfile(profile string) int {
+	if profile == DefaultProfile {
+		return 0
+	}
+	removed := 0
+	for stored := range s.GetAll() {
+		if p, _ := ParseProfileStorageKey(stored); p == profile {
+			s.Delete(stored)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// trustedProxies holds the networks whose X-Forwarded-For, X-Real-IP and
+// X-Forwarded-Proto headers are honoured.
+var trustedProxies = struct {
+	mu   sync.RWMutex
+	nets []*net.IPNet
+}{nets: defaultTrustedProxies()}
+
+// defaultTrustedProxies trusts only loopback, i.e. a reverse proxy on the same host.
+func defaultTrustedProxies() []*net.IPNet {
+	_, v4, _ := net.ParseCIDR("127.0.0.0/8")
+	_, v6, _ := net.ParseCIDR("::1/128")
+	return []*net.IPNet{v4, v6}
+}
+
+// ParseTrustedProxies parses IP addresses and CIDR ranges of trusted reverse proxies.
+func ParseTrustedProxies(entries []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("trustedProxies: invalid IP address %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("trustedProxies: invalid CIDR %q", entry)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// SetTrustedProxies replaces the trusted proxy list. An empty list trusts no proxy headers.
+func SetTrustedProxies(entries []string) error {
+	nets, err := ParseTrustedProxies(entries)
+	if err != nil {
+		return err
+	}
+	trustedProxies.mu.Lock()
+	trustedProxies.nets = nets
+	trustedProxies.mu.Unlock()
+	return nil
+}
+
+// isTrustedProxy reports whether an IP address belongs to a trusted proxy.
+func isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	trustedProxies.mu.RLock()
+	defer trustedProxies.mu.RUnlock()
+	for _, n := range trustedProxies.nets {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP returns the IP address of the direct peer of a request.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// RemoteAddr might not have a port
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// IsSecureRequest reports whether the client connected over HTTPS, either directly or
+// through a trusted proxy that sets X-Forwarded-Proto.
+func IsSecureRequest(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return isTrustedProxy(remoteIP(r)) && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// NormalizeBasePath cleans a base path to the form "/dash" ("" for the root).
+func NormalizeBasePath(path string) (string, error) {
+	path = strings.TrimRight(strings.TrimSpace(path), "/")
+	if path == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if strings.ContainsAny(path, "?#\"'<> \\") || strings.Contains(path, "//") || strings.Contains(path, "/../") || strings.HasSuffix(path, "/..") {
+		return "", fmt.Errorf("basePath: invalid path %q", path)
+	}
+	return path, nil
+}
+
+// WithBasePath serves a handler under a path prefix, for reverse proxies that forward
+// /dash/... unchanged. Requests without the prefix (from proxies that strip it) are
+// served as they are, and the bare prefix redirects to the prefix with a trailing slash.
+func WithBasePath(basePath string, next http.Handler) http.Handler {
+	if basePath == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == basePath {
+			target := basePath + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, basePath+"/") {
+			http.StripPrefix(basePath, next).ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
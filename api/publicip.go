@@ -0,0 +1,454 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// publicIPHistoryFile holds the public IP addresses seen across restarts of the dashboard.
+const publicIPHistoryFile = "public-ip-history.json"
+
+// maxPublicIPRecords caps the number of addresses kept in the history file.
+const maxPublicIPRecords = 200
+
+// ddnsTimeout bounds one dynamic DNS update.
+const ddnsTimeout = 15 * time.Second
+
+// PublicIPConfig configures the background public IP watcher.
+type PublicIPConfig struct {
+	Interval string       `json:"interval,omitempty"` // Check interval, default "10m"
+	DDNS     []DDNSConfig `json:"ddns,omitempty"`     // Dynamic DNS records updated when the address changes
+}
+
+// DDNSConfig is one dynamic DNS record to update.
+type DDNSConfig struct {
+	Provider string `json:"provider"` // "duckdns", "cloudflare" or "url"
+	// Domains are the DuckDNS subdomains, e.g. ["myhome"] (duckdns only)
+	Domains []string `json:"domains,omitempty"`
+	// ZoneID and Record are the Cloudflare zone and the full record name, e.g. "home.example.com"
+	ZoneID string `json:"zoneId,omitempty"`
+	Record string `json:"record,omitempty"`
+	// URL is requested with {ip} replaced by the new address (url only)
+	URL string `json:"url,omitempty"`
+	// Token is the DuckDNS token or a Cloudflare API token with DNS edit permission.
+	// TokenFile and TokenEnv read it from a secret file or environment variable
+	Token     string `json:"token,omitempty"`
+	TokenFile string `json:"tokenFile,omitempty"`
+	TokenEnv  string `json:"tokenEnv,omitempty"`
+}
+
+// Validate checks the watcher settings and dynamic DNS records.
+func (c PublicIPConfig) Validate() error {
+	if c.Interval != "" {
+		if d, err := time.ParseDuration(c.Interval); err != nil || d < time.Minute {
+			return fmt.Errorf("publicIP: interval must be a duration of at least 1m")
+		}
+	}
+	for i, d := range c.DDNS {
+		hasToken := d.Token != "" || d.TokenFile != "" || d.TokenEnv != ""
+		switch d.Provider {
+		case "duckdns":
+			if len(d.Domains) == 0 || !hasToken {
+				return fmt.Errorf("publicIP: ddns[%d]: duckdns needs domains and a token", i)
+			}
+		case "cloudflare":
+			if d.ZoneID == "" || d.Record == "" || !hasToken {
+				return fmt.Errorf("publicIP: ddns[%d]: cloudflare needs zoneId, record and a token", i)
+			}
+		case "url":
+			u, err := url.Parse(d.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("publicIP: ddns[%d]: url must be an http or https URL", i)
+			}
+		default:
+			return fmt.Errorf("publicIP: ddns[%d]: provider must be duckdns, cloudflare or url", i)
+		}
+	}
+	return nil
+}
+
+// name identifies the record in logs and the status, e.g. "duckdns myhome".
+func (d DDNSConfig) name() string {
+	switch d.Provider {
+	case "duckdns":
+		return "duckdns " + strings.Join(d.Domains, ",")
+	case "cloudflare":
+		return "cloudflare " + d.Record
+	}
+	if u, err := url.Parse(d.URL); err == nil {
+		return "url " + u.Host
+	}
+	return d.Provider
+}
+
+// PublicIPRecord is one public IP address and when it was in use.
+type PublicIPRecord struct {
+	IP        string    `json:"ip"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// DDNSStatus is the result of the last update of a dynamic DNS record.
+type DDNSStatus struct {
+	Name    string     `json:"name"`
+	IP      string     `json:"ip,omitempty"` // Address the record was last set to
+	Updated *time.Time `json:"updated,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+// PublicIPHistory is the response for the public IP history endpoint.
+type PublicIPHistory struct {
+	Current string           `json:"current,omitempty"`
+	Checked *time.Time       `json:"checked,omitempty"`
+	Error   string           `json:"error,omitempty"`
+	Changes int              `json:"changes"`
+	History []PublicIPRecord `json:"history"` // Newest first
+	DDNS    []DDNSStatus     `json:"ddns,omitempty"`
+}
+
+// PublicIPWatcher keeps the history of the public IP address and updates dynamic DNS
+// records when it changes.
+type PublicIPWatcher struct {
+	mu       sync.Mutex
+	config   *PublicIPConfig
+	history  []PublicIPRecord
+	loaded   bool
+	checked  time.Time
+	lastErr  string
+	ddns     []DDNSStatus
+	updating bool
+	saved    time.Time // Last write of the history file
+}
+
+// Global public IP watcher instance
+var publicIPWatcher = &PublicIPWatcher{}
+
+// GetPublicIPWatcher returns the global public IP watcher instance.
+func GetPublicIPWatcher() *PublicIPWatcher {
+	return publicIPWatcher
+}
+
+// Configure sets the check interval and the dynamic DNS records.
+func (pw *PublicIPWatcher) Configure(cfg PublicIPConfig) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	pw.config = &cfg
+	pw.ddns = make([]DDNSStatus, len(cfg.DDNS))
+	for i, d := range cfg.DDNS {
+		pw.ddns[i] = DDNSStatus{Name: d.name()}
+	}
+}
+
+// Start checks the public IP address at the configured interval.
+func (pw *PublicIPWatcher) Start() {
+	pw.mu.Lock()
+	cfg := pw.config
+	pw.mu.Unlock()
+	if cfg == nil {
+		return
+	}
+
+	interval := 10 * time.Minute
+	if d, err := time.ParseDuration(cfg.Interval); err == nil {
+		interval = d
+	}
+
+	guardLoop("publicip", func() { pw.check() })
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		guardLoop("publicip", func() { pw.check() })
+	}
+}
+
+// check looks up the public IP address.
+func (pw *PublicIPWatcher) check() {
+	ip, err := PublicIP(context.Background(), 10*time.Second)
+	if err != nil {
+		pw.mu.Lock()
+		pw.checked = time.Now()
+		pw.lastErr = err.Error()
+		pw.mu.Unlock()
+		GetDebugLogger().Logf("publicip", "lookup failed: %v", err)
+		return
+	}
+	pw.Observe(ip)
+}
+
+// load reads the history file. Caller must hold mu.
+func (pw *PublicIPWatcher) load() {
+	if pw.loaded {
+		return
+	}
+	pw.loaded = true
+	data, err := os.ReadFile(publicIPHistoryFile)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &pw.history); err != nil {
+		GetDebugLogger().Logf("publicip", "failed to parse %s: %v", publicIPHistoryFile, err)
+		pw.history = nil
+	}
+}
+
+// save writes the history file. Caller must hold mu.
+func (pw *PublicIPWatcher) save() {
+	data, err := json.MarshalIndent(pw.history, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(publicIPHistoryFile, data, 0644); err != nil {
+		GetDebugLogger().Logf("publicip", "failed to write %s: %v", publicIPHistoryFile, err)
+		return
+	}
+	pw.saved = time.Now()
+}
+
+// Observe records a lookup of the public IP address, from the watcher or a request to
+// /api/ip. A new address is added to the history and the timeline, announced over the
+// WebSocket and pushed to the dynamic DNS records.
+func (pw *PublicIPWatcher) Observe(ip string) {
+	if ip == "" {
+		return
+	}
+	now := time.Now()
+	pw.mu.Lock()
+	pw.load()
+	pw.checked = now
+	pw.lastErr = ""
+
+	var previous string
+	n := len(pw.history)
+	if n > 0 && pw.history[n-1].IP == ip {
+		pw.history[n-1].LastSeen = now
+		// Only touch the file now and then for an unchanged address
+		if now.Sub(pw.saved) > time.Hour {
+			pw.save()
+		}
+	} else {
+		if n > 0 {
+			previous = pw.history[n-1].IP
+		}
+		pw.history = append(pw.history, PublicIPRecord{IP: ip, FirstSeen: now, LastSeen: now})
+		if len(pw.history) > maxPublicIPRecords {
+			pw.history = pw.history[len(pw.history)-maxPublicIPRecords:]
+		}
+		pw.save()
+	}
+	update := pw.ddnsDue(ip)
+	pw.mu.Unlock()
+
+	GetTimeline().RecordPublicIP(ip)
+	if previous != "" {
+		Logger("publicip").Warn("public IP changed", "ip", ip, "previous", previous)
+		GetWSManager().Broadcast(map[string]interface{}{
+			"type":      "public-ip",
+			"ip":        ip,
+			"previous":  previous,
+			"timestamp": now.Unix(),
+		})
+	}
+	if update {
+		go pw.updateDDNS(ip)
+	}
+}
+
+// ddnsDue reports whether a record still points elsewhere and no update is running, and
+// marks an update as running. Caller must hold mu.
+func (pw *PublicIPWatcher) ddnsDue(ip string) bool {
+	if pw.updating {
+		return false
+	}
+	for _, s := range pw.ddns {
+		if s.IP != ip {
+			pw.updating = true
+			return true
+		}
+	}
+	return false
+}
+
+// updateDDNS points every dynamic DNS record that is not yet up to date at ip. Failed
+// records are retried on the next lookup.
+func (pw *PublicIPWatcher) updateDDNS(ip string) {
+	pw.mu.Lock()
+	records := slices.Clone(pw.config.DDNS)
+	status := slices.Clone(pw.ddns)
+	pw.mu.Unlock()
+
+	for i, d := range records {
+		if status[i].IP == ip {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), ddnsTimeout)
+		err := d.update(ctx, ip)
+		cancel()
+		now := time.Now()
+		status[i].Updated = &now
+		if err != nil {
+			status[i].Error = err.Error()
+			Logger("publicip").Warn("dynamic DNS update failed", "record", d.name(), "error", err)
+			continue
+		}
+		status[i].IP, status[i].Error = ip, ""
+		Logger("publicip").Info("dynamic DNS updated", "record", d.name(), "ip", ip)
+	}
+
+	pw.mu.Lock()
+	pw.ddns = status
+	pw.updating = false
+	pw.mu.Unlock()
+}
+
+// History returns the recorded addresses, newest first, and the dynamic DNS status.
+func (pw *PublicIPWatcher) History() PublicIPHistory {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	pw.load()
+
+	result := PublicIPHistory{Error: pw.lastErr, History: make([]PublicIPRecord, 0, len(pw.history))}
+	if !pw.checked.IsZero() {
+		checked := pw.checked
+		result.Checked = &checked
+	}
+	if n := len(pw.history); n > 0 {
+		result.Current = pw.history[n-1].IP
+		result.Changes = n - 1
+	}
+	for i := len(pw.history) - 1; i >= 0; i-- {
+		result.History = append(result.History, pw.history[i])
+	}
+	result.DDNS = slices.Clone(pw.ddns)
+	return result
+}
+
+// update sets the dynamic DNS record to ip.
+func (d DDNSConfig) update(ctx context.Context, ip string) error {
+	token, err := ResolveSecret(d.Token, d.TokenFile, d.TokenEnv)
+	if err != nil {
+		return err
+	}
+	switch d.Provider {
+	case "duckdns":
+		return updateDuckDNS(ctx, d.Domains, token, ip)
+	case "cloudflare":
+		return updateCloudflareDNS(ctx, d.ZoneID, d.Record, token, ip)
+	}
+	target := strings.ReplaceAll(d.URL, "{ip}", url.QueryEscape(ip))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	_, err = ddnsDo(req)
+	return err
+}
+
+// updateDuckDNS sets DuckDNS subdomains to ip. DuckDNS answers OK or KO.
+func updateDuckDNS(ctx context.Context, domains []string, token, ip string) error {
+	q := url.Values{"domains": {strings.Join(domains, ",")}, "token": {token}}
+	if net.ParseIP(ip).To4() != nil {
+		q.Set("ip", ip)
+	} else {
+		q.Set("ipv6", ip)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://www.duckdns.org/update?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	body, err := ddnsDo(req)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(body)) != "OK" {
+		return fmt.Errorf("duckdns rejected the update")
+	}
+	return nil
+}
+
+// cloudflareResponse is the envelope of Cloudflare API responses.
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+// updateCloudflareDNS sets the A or AAAA record of a name in a Cloudflare zone to ip.
+func updateCloudflareDNS(ctx context.Context, zoneID, record, token, ip string) error {
+	recordType := "A"
+	if net.ParseIP(ip).To4() == nil {
+		recordType = "AAAA"
+	}
+	base := "https://api.cloudflare.com/client/v4/zones/" + url.PathEscape(zoneID) + "/dns_records"
+
+	var records []struct {
+		ID string `json:"id"`
+	}
+	q := url.Values{"type": {recordType}, "name": {record}}
+	if err := cloudflareCall(ctx, http.MethodGet, base+"?"+q.Encode(), token, nil, &records); err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("cloudflare: no %s record named %s", recordType, record)
+	}
+	body, _ := json.Marshal(map[string]string{"content": ip})
+	return cloudflareCall(ctx, http.MethodPatch, base+"/"+url.PathEscape(records[0].ID), token, body, nil)
+}
+
+// cloudflareCall makes a Cloudflare API request and decodes its result.
+func cloudflareCall(ctx context.Context, method, u, token string, body []byte, result any) error {
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	data, err := ddnsDo(req)
+	var resp cloudflareResponse
+	if jsonErr := json.Unmarshal(data, &resp); jsonErr != nil {
+		if err == nil {
+			err = fmt.Errorf("cloudflare: invalid response")
+		}
+		return err
+	}
+	if !resp.Success {
+		if len(resp.Errors) > 0 {
+			return fmt.Errorf("cloudflare: %s", resp.Errors[0].Message)
+		}
+		return fmt.Errorf("cloudflare: request failed")
+	}
+	if result != nil {
+		return json.Unmarshal(resp.Result, result)
+	}
+	return nil
+}
+
+// ddnsDo sends a dynamic DNS request and returns the body, failing on non-2xx statuses.
+func ddnsDo(req *http.Request) ([]byte, error) {
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return body, fmt.Errorf("http status %s", res.Status)
+	}
+	return body, nil
+}
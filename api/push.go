@@ -0,0 +1,451 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// pushStateFile holds the VAPID key pair and subscriptions across restarts.
+const pushStateFile = "push.json"
+
+// pushSubject is the contact sent to push services in the VAPID claims.
+const pushSubject = "mailto:homepage@localhost"
+
+// maxPushSubscriptions caps the stored subscriptions; further browsers are refused.
+const maxPushSubscriptions = 50
+
+// pushDeliveryTimeout bounds each delivery, so dead endpoints do not hold up an alert.
+const pushDeliveryTimeout = 5 * time.Second
+
+// PushSubscriptionKeys holds the client keys from PushSubscription.toJSON().
+type PushSubscriptionKeys struct {
+	P256dh string `json:"p256dh"`
+	Auth   string `json:"auth"`
+}
+
+// PushSubscription represents a browser push subscription.
+type PushSubscription struct {
+	Endpoint  string               `json:"endpoint"`
+	Keys      PushSubscriptionKeys `json:"keys"`
+	CreatedAt time.Time            `json:"createdAt"`
+}
+
+// PushMessage is the JSON payload delivered to the service worker.
+type PushMessage struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	URL   string `json:"url,omitempty"`
+	Tag   string `json:"tag,omitempty"`
+}
+
+// pushState is the on-disk representation of the push manager.
+type pushState struct {
+	PrivateKey    string             `json:"privateKey"`
+	Subscriptions []PushSubscription `json:"subscriptions"`
+}
+
+// PushManager manages VAPID keys, subscriptions and delivery of push messages.
+type PushManager struct {
+	mu            sync.RWMutex
+	key           *ecdsa.PrivateKey
+	subscriptions map[string]PushSubscription
+	client        *http.Client
+	loaded        bool
+}
+
+// NewPushManager creates a new push manager.
+func NewPushManager() *PushManager {
+	return &PushManager{
+		subscriptions: make(map[string]PushSubscription),
+		client: &http.Client{
+			Timeout:       pushDeliveryTimeout,
+			Transport:     NewOutboundTransport(nil),
+			CheckRedirect: OutboundCheckRedirect(3),
+		},
+	}
+}
+
+// Global push manager instance
+var pushManager = NewPushManager()
+
+// GetPushManager returns the global push manager instance.
+func GetPushManager() *PushManager {
+	return pushManager
+}
+
+// ensureLoaded loads the state file or generates a new VAPID key pair. Caller must hold mu.
+func (p *PushManager) ensureLoaded() error {
+	if p.loaded {
+		return nil
+	}
+
+	data, err := os.ReadFile(pushStateFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", pushStateFile, err)
+	}
+	if err == nil {
+		var state pushState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", pushStateFile, err)
+		}
+		raw, err := base64.RawURLEncoding.DecodeString(state.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("invalid VAPID private key: %w", err)
+		}
+		key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), raw)
+		if err != nil {
+			return fmt.Errorf("invalid VAPID private key: %w", err)
+		}
+		p.key = key
+		for _, sub := range state.Subscriptions {
+			p.subscriptions[sub.Endpoint] = sub
+		}
+		p.loaded = true
+		return nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate VAPID key: %w", err)
+	}
+	p.key = key
+	p.loaded = true
+	GetDebugLogger().Logf("push", "generated new VAPID key pair")
+	return p.save()
+}
+
+// save writes the current state to disk. Caller must hold mu.
+func (p *PushManager) save() error {
+	raw, err := p.key.Bytes()
+	if err != nil {
+		return err
+	}
+	state := pushState{
+		PrivateKey:    base64.RawURLEncoding.EncodeToString(raw),
+		Subscriptions: make([]PushSubscription, 0, len(p.subscriptions)),
+	}
+	for _, sub := range p.subscriptions {
+		state.Subscriptions = append(state.Subscriptions, sub)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(pushStateFile, data, 0600)
+}
+
+// PublicKey returns the VAPID application server key in base64url form.
+func (p *PushManager) PublicKey() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.ensureLoaded(); err != nil {
+		return "", err
+	}
+	pub, err := p.key.PublicKey.Bytes()
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(pub), nil
+}
+
+// Subscribe stores a subscription, replacing any existing one for the same endpoint.
+func (p *PushManager) Subscribe(sub PushSubscription) error {
+	if err := validatePushSubscription(sub); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.ensureLoaded(); err != nil {
+		return err
+	}
+	if existing, ok := p.subscriptions[sub.Endpoint]; ok {
+		sub.CreatedAt = existing.CreatedAt
+	} else if len(p.subscriptions) >= maxPushSubscriptions {
+		return fmt.Errorf("too many push subscriptions, at most %d", maxPushSubscriptions)
+	} else {
+		sub.CreatedAt = time.Now()
+	}
+	p.subscriptions[sub.Endpoint] = sub
+	return p.save()
+}
+
+// Unsubscribe removes the subscription for the given endpoint.
+func (p *PushManager) Unsubscribe(endpoint string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.ensureLoaded(); err != nil {
+		return err
+	}
+	if _, ok := p.subscriptions[endpoint]; !ok {
+		return nil
+	}
+	delete(p.subscriptions, endpoint)
+	return p.save()
+}
+
+// Count returns the number of stored subscriptions.
+func (p *PushManager) Count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.ensureLoaded(); err != nil {
+		return 0
+	}
+	return len(p.subscriptions)
+}
+
+// Send delivers a message to all subscriptions and returns the number of successful deliveries.
+// Subscriptions rejected by the push service as gone are removed.
+func (p *PushManager) Send(ctx context.Context, msg PushMessage) (int, error) {
+	p.mu.Lock()
+	if err := p.ensureLoaded(); err != nil {
+		p.mu.Unlock()
+		return 0, err
+	}
+	key := p.key
+	subs := make([]PushSubscription, 0, len(p.subscriptions))
+	for _, sub := range p.subscriptions {
+		subs = append(subs, sub)
+	}
+	p.mu.Unlock()
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		sent    int
+		expired []string
+	)
+	for _, sub := range subs {
+		wg.Go(func() {
+			status, err := p.deliver(ctx, key, sub, payload)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				GetDebugLogger().Logf("push", "delivery to %s failed: %v", sub.Endpoint, err)
+				if status == http.StatusNotFound || status == http.StatusGone {
+					expired = append(expired, sub.Endpoint)
+				}
+				return
+			}
+			sent++
+		})
+	}
+	wg.Wait()
+
+	if len(expired) > 0 {
+		p.mu.Lock()
+		for _, endpoint := range expired {
+			delete(p.subscriptions, endpoint)
+		}
+		_ = p.save()
+		p.mu.Unlock()
+	}
+
+	return sent, nil
+}
+
+// deliver encrypts the payload for a single subscription and posts it to the push service.
+// Push services are on the internet, so endpoints are held to the outbound policy of
+// untrusted clients: private addresses only when outbound.allow lists them.
+func (p *PushManager) deliver(ctx context.Context, key *ecdsa.PrivateKey, sub PushSubscription, payload []byte) (int, error) {
+	ctx = context.WithValue(ctx, outboundUntrustedKey{}, true)
+	if err := CheckOutboundURL(ctx, sub.Endpoint); err != nil {
+		return 0, err
+	}
+	body, err := encryptPushPayload(sub, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	u, err := url.Parse(sub.Endpoint)
+	if err != nil {
+		return 0, err
+	}
+	token, err := vapidToken(key, u.Scheme+"://"+u.Host)
+	if err != nil {
+		return 0, err
+	}
+	pub, err := key.PublicKey.Bytes()
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", "86400")
+	req.Header.Set("Urgency", "high")
+	req.Header.Set("Authorization", "vapid t="+token+", k="+base64.RawURLEncoding.EncodeToString(pub))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("push service returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// sendPushAlert is the alert channel for Web Push.
+func sendPushAlert(ctx context.Context, a Alert) error {
+	_, err := GetPushManager().Send(ctx, PushMessage{
+		Title: a.Title,
+		Body:  a.Body,
+		URL:   a.URL,
+		Tag:   a.Tag,
+	})
+	return err
+}
+
+// validatePushSubscription checks that a subscription has a usable endpoint and keys.
+func validatePushSubscription(sub PushSubscription) error {
+	u, err := url.Parse(sub.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("endpoint must be an https URL")
+	}
+	if err := CheckOutboundURL(context.WithValue(context.Background(), outboundUntrustedKey{}, true), sub.Endpoint); err != nil {
+		return fmt.Errorf("endpoint: %w", err)
+	}
+	pub, err := base64.RawURLEncoding.DecodeString(trimBase64Padding(sub.Keys.P256dh))
+	if err != nil || len(pub) != 65 {
+		return fmt.Errorf("invalid p256dh key")
+	}
+	auth, err := base64.RawURLEncoding.DecodeString(trimBase64Padding(sub.Keys.Auth))
+	if err != nil || len(auth) != 16 {
+		return fmt.Errorf("invalid auth secret")
+	}
+	return nil
+}
+
+// trimBase64Padding strips trailing '=' so padded and unpadded keys both decode.
+func trimBase64Padding(s string) string {
+	for len(s) > 0 && s[len(s)-1] == '=' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// vapidToken builds an ES256-signed JWT for the given push service audience.
+func vapidToken(key *ecdsa.PrivateKey, audience string) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]any{
+		"aud": audience,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": pushSubject,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// encryptPushPayload encrypts a payload with the aes128gcm content coding (RFC 8291).
+func encryptPushPayload(sub PushSubscription, payload []byte) ([]byte, error) {
+	uaPublic, err := base64.RawURLEncoding.DecodeString(trimBase64Padding(sub.Keys.P256dh))
+	if err != nil {
+		return nil, err
+	}
+	authSecret, err := base64.RawURLEncoding.DecodeString(trimBase64Padding(sub.Keys.Auth))
+	if err != nil {
+		return nil, err
+	}
+
+	curve := ecdh.P256()
+	uaKey, err := curve.NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	asKey, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asKey.PublicKey().Bytes()
+
+	sharedSecret, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublic...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, authSecret, string(keyInfo), 32)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Single record: payload followed by the last-record delimiter
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
+
+	header := make([]byte, 0, 21+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, 4096)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	return append(header, ciphertext...), nil
+}
@@ -0,0 +1,374 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the quotes.
+const (
+	defaultStockProvider  = "yahoo"
+	defaultCryptoProvider = "coingecko"
+	defaultQuoteCurrency  = "usd"
+	defaultQuoteTTL       = 5 * time.Minute
+	// A refresh is not passed to the providers sooner, so their free limits are not used up
+	minQuoteRefresh = 30 * time.Second
+	maxQuoteSymbols = 30
+)
+
+var (
+	quoteSymbolPattern   = regexp.MustCompile(`^[A-Za-z0-9.^=_-]{1,24}$`)
+	quoteCurrencyPattern = regexp.MustCompile(`^[a-z]{3,5}$`)
+)
+
+// QuotesConfig lists the stock tickers and cryptocurrencies of /api/quotes.
+type QuotesConfig struct {
+	Stocks         []string `json:"stocks,omitempty"`         // Tickers as the stock provider names them, e.g. "AAPL", "^GSPC", "VWCE.DE"
+	Crypto         []string `json:"crypto,omitempty"`         // CoinGecko IDs, e.g. "bitcoin", "ethereum"
+	Currency       string   `json:"currency,omitempty"`       // Currency of crypto prices, default "usd"
+	StockProvider  string   `json:"stockProvider,omitempty"`  // Default "yahoo"
+	CryptoProvider string   `json:"cryptoProvider,omitempty"` // Default "coingecko"
+	CacheTTL       string   `json:"cacheTTL,omitempty"`       // How long a price is reused, default "5m"
+}
+
+// Validate checks the symbols, providers and cache TTL.
+func (c QuotesConfig) Validate() error {
+	if len(c.Stocks) > maxQuoteSymbols || len(c.Crypto) > maxQuoteSymbols {
+		return fmt.Errorf("quotes: at most %d stocks and %d cryptocurrencies", maxQuoteSymbols, maxQuoteSymbols)
+	}
+	for _, s := range append(slices.Clone(c.Stocks), c.Crypto...) {
+		if !quoteSymbolPattern.MatchString(s) {
+			return fmt.Errorf("quotes: invalid symbol %q", s)
+		}
+	}
+	if c.Currency != "" && !quoteCurrencyPattern.MatchString(c.Currency) {
+		return fmt.Errorf("quotes: currency must be a lowercase code such as \"usd\"")
+	}
+	for _, p := range []string{c.StockProvider, c.CryptoProvider} {
+		if _, ok := quoteProviders[p]; p != "" && !ok {
+			return fmt.Errorf("quotes: unknown provider %q (use yahoo or coingecko)", p)
+		}
+	}
+	if c.CacheTTL != "" {
+		if d, err := ParseHistoryRange(c.CacheTTL); err != nil || d < time.Minute {
+			return fmt.Errorf("quotes: cacheTTL must be a duration of at least 1m")
+		}
+	}
+	return nil
+}
+
+// Quote is the price of a stock or cryptocurrency.
+type Quote struct {
+	Symbol        string    `json:"symbol"` // As configured
+	Name          string    `json:"name,omitempty"`
+	Kind          string    `json:"kind"` // "stock" or "crypto"
+	Price         float64   `json:"price"`
+	Previous      float64   `json:"previous"` // Previous close, or the price 24 hours ago for crypto
+	Change        float64   `json:"change"`
+	ChangePercent float64   `json:"changePercent"`
+	Currency      string    `json:"currency,omitempty"`
+	Provider      string    `json:"provider"`
+	Updated       time.Time `json:"updated,omitzero"` // When the provider last priced it
+	Fetched       time.Time `json:"fetched,omitzero"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// computeChange sets the change since the previous price.
+func (q *Quote) computeChange() {
+	if q.Previous == 0 {
+		return
+	}
+	q.Change = q.Price - q.Previous
+	q.ChangePercent = math.Round(q.Change/q.Previous*10000) / 100
+}
+
+// QuoteProvider fetches prices from one source. Symbols it does not know come back with an
+// Error; the returned error is for failures of the whole request.
+type QuoteProvider interface {
+	Name() string
+	Quotes(ctx context.Context, symbols []string, currency string) ([]Quote, error)
+}
+
+// Providers by config name
+var quoteProviders = map[string]QuoteProvider{
+	"yahoo":     yahooQuotes{},
+	"coingecko": coinGeckoQuotes{},
+}
+
+var quoteHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// getQuoteJSON decodes the JSON answer of a provider.
+func getQuoteJSON(ctx context.Context, u string, v any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; lan-index/1.0)")
+	req.Header.Set("Accept", "application/json")
+	resp, err := quoteHTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(v)
+}
+
+// yahooQuotes reads stock prices from Yahoo Finance's chart API, one request per ticker.
+type yahooQuotes struct{}
+
+func (yahooQuotes) Name() string { return "yahoo" }
+
+func (yahooQuotes) Quotes(ctx context.Context, symbols []string, _ string) ([]Quote, error) {
+	quotes := make([]Quote, len(symbols))
+	var wg sync.WaitGroup
+	for i, symbol := range symbols {
+		wg.Go(func() {
+			quotes[i] = yahooQuote(ctx, symbol)
+		})
+	}
+	wg.Wait()
+	return quotes, nil
+}
+
+func yahooQuote(ctx context.Context, symbol string) Quote {
+	q := Quote{Symbol: symbol, Kind: "stock", Provider: "yahoo"}
+	var data struct {
+		Chart struct {
+			Result []struct {
+				Meta struct {
+					Currency           string  `json:"currency"`
+					ShortName          string  `json:"shortName"`
+					LongName           string  `json:"longName"`
+					RegularMarketPrice float64 `json:"regularMarketPrice"`
+					RegularMarketTime  int64   `json:"regularMarketTime"`
+					ChartPreviousClose float64 `json:"chartPreviousClose"`
+					PreviousClose      float64 `json:"previousClose"`
+				} `json:"meta"`
+			} `json:"result"`
+			Error *struct {
+				Description string `json:"description"`
+			} `json:"error"`
+		} `json:"chart"`
+	}
+	status, err := getQuoteJSON(ctx, "https://query1.finance.yahoo.com/v8/finance/chart/"+url.PathEscape(symbol)+"?range=1d&interval=1d", &data)
+	switch {
+	case data.Chart.Error != nil:
+		q.Error = data.Chart.Error.Description
+	case err != nil:
+		q.Error = err.Error()
+	case status != http.StatusOK || len(data.Chart.Result) == 0:
+		q.Error = fmt.Sprintf("no quote (HTTP %d)", status)
+	}
+	if q.Error != "" {
+		return q
+	}
+	meta := data.Chart.Result[0].Meta
+	q.Name = cmp.Or(meta.LongName, meta.ShortName)
+	q.Price = meta.RegularMarketPrice
+	q.Previous = cmp.Or(meta.PreviousClose, meta.ChartPreviousClose)
+	q.Currency = meta.Currency
+	if meta.RegularMarketTime > 0 {
+		q.Updated = time.Unix(meta.RegularMarketTime, 0)
+	}
+	return q
+}
+
+// coinGeckoQuotes reads cryptocurrency prices from CoinGecko's markets API in one request.
+type coinGeckoQuotes struct{}
+
+func (coinGeckoQuotes) Name() string { return "coingecko" }
+
+func (coinGeckoQuotes) Quotes(ctx context.Context, ids []string, currency string) ([]Quote, error) {
+	var markets []struct {
+		ID             string    `json:"id"`
+		Symbol         string    `json:"symbol"`
+		Name           string    `json:"name"`
+		CurrentPrice   float64   `json:"current_price"`
+		PriceChange24h float64   `json:"price_change_24h"`
+		LastUpdated    time.Time `json:"last_updated"`
+	}
+	lower := make([]string, len(ids))
+	for i, id := range ids {
+		lower[i] = strings.ToLower(id)
+	}
+	u := "https://api.coingecko.com/api/v3/coins/markets?vs_currency=" + url.QueryEscape(currency) + "&ids=" + url.QueryEscape(strings.Join(lower, ","))
+	status, err := getQuoteJSON(ctx, u, &markets)
+	if status == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("coingecko: rate limited, try again in a minute")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("coingecko: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("coingecko: HTTP %d", status)
+	}
+	quotes := make([]Quote, len(ids))
+	for i, id := range ids {
+		q := Quote{Symbol: id, Kind: "crypto", Provider: "coingecko", Currency: strings.ToUpper(currency), Error: "unknown CoinGecko ID"}
+		for _, m := range markets {
+			if m.ID == lower[i] {
+				q.Name = m.Name + " (" + strings.ToUpper(m.Symbol) + ")"
+				q.Price = m.CurrentPrice
+				q.Previous = m.CurrentPrice - m.PriceChange24h
+				q.Updated = m.LastUpdated
+				q.Error = ""
+				break
+			}
+		}
+		quotes[i] = q
+	}
+	return quotes, nil
+}
+
+// quoteCacheEntry is the last successful price of a symbol.
+type quoteCacheEntry struct {
+	quote   Quote
+	fetched time.Time
+}
+
+// QuotesResult is the answer of /api/quotes.
+type QuotesResult struct {
+	Enabled bool    `json:"enabled"`
+	Quotes  []Quote `json:"quotes"`
+	Error   string  `json:"error,omitempty"` // A provider failed and nothing was cached
+}
+
+// QuoteService fetches prices and caches them per provider, currency and symbol.
+type QuoteService struct {
+	mu     sync.Mutex
+	config QuotesConfig
+	cache  map[string]quoteCacheEntry
+}
+
+// Global quote service instance
+var quoteService = &QuoteService{cache: make(map[string]quoteCacheEntry)}
+
+// GetQuoteService returns the global quote service instance.
+func GetQuoteService() *QuoteService {
+	return quoteService
+}
+
+// Configure sets the symbols and providers.
+func (qs *QuoteService) Configure(cfg QuotesConfig) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	qs.config = cfg
+}
+
+// Config returns the configured symbols and providers.
+func (qs *QuoteService) Config() QuotesConfig {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	return qs.config
+}
+
+// Quotes returns the prices of stocks and crypto, in that order. Prices younger than the
+// cache TTL are reused; refresh fetches them again unless they are only moments old. When a
+// provider fails, the last price it gave is returned instead.
+func (qs *QuoteService) Quotes(ctx context.Context, stocks, crypto []string, refresh bool) QuotesResult {
+	cfg := qs.Config()
+	ttl := defaultQuoteTTL
+	if d, err := ParseHistoryRange(cfg.CacheTTL); err == nil && d > 0 {
+		ttl = d
+	}
+	if refresh {
+		ttl = minQuoteRefresh
+	}
+	currency := cmp.Or(cfg.Currency, defaultQuoteCurrency)
+
+	result := QuotesResult{Enabled: true, Quotes: []Quote{}}
+	var errs []string
+	for _, group := range []struct {
+		kind     string
+		provider string
+		symbols  []string
+		currency string
+	}{
+		{"stock", cmp.Or(cfg.StockProvider, defaultStockProvider), stocks, ""},
+		{"crypto", cmp.Or(cfg.CryptoProvider, defaultCryptoProvider), crypto, currency},
+	} {
+		if len(group.symbols) == 0 {
+			continue
+		}
+		quotes, err := qs.fetch(ctx, quoteProviders[group.provider], group.kind, group.symbols, group.currency, ttl)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		result.Quotes = append(result.Quotes, quotes...)
+	}
+	if len(errs) > 0 {
+		result.Error = strings.Join(errs, "; ")
+	}
+	return result
+}
+
+// fetch returns the quotes of one provider, asking it only for symbols not cached.
+func (qs *QuoteService) fetch(ctx context.Context, p QuoteProvider, kind string, symbols []string, currency string, ttl time.Duration) ([]Quote, error) {
+	key := func(symbol string) string {
+		return p.Name() + "|" + currency + "|" + strings.ToUpper(symbol)
+	}
+	quotes := make([]Quote, len(symbols))
+	var missing []string
+	qs.mu.Lock()
+	for i, s := range symbols {
+		if e, ok := qs.cache[key(s)]; ok && time.Since(e.fetched) < ttl {
+			quotes[i] = e.quote
+			quotes[i].Symbol = s
+		} else {
+			missing = append(missing, s)
+		}
+	}
+	qs.mu.Unlock()
+	if len(missing) == 0 {
+		RecordTiming(ctx, p.Name(), 0, TimingHit)
+		return quotes, nil
+	}
+
+	done := StartTiming(ctx, p.Name())
+	fetched, err := p.Quotes(ctx, missing, currency)
+	done(TimingMiss)
+	now := time.Now()
+	byKey := make(map[string]Quote, len(fetched))
+	for _, q := range fetched {
+		if q.Error == "" {
+			q.Fetched = now
+			q.computeChange()
+		}
+		byKey[key(q.Symbol)] = q
+	}
+
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	failed := false
+	for i, s := range symbols {
+		if quotes[i].Symbol != "" {
+			continue
+		}
+		q, ok := byKey[key(s)]
+		if ok && q.Error == "" {
+			qs.cache[key(s)] = quoteCacheEntry{quote: q, fetched: now}
+		} else if e, cached := qs.cache[key(s)]; cached {
+			// The last price beats none
+			q = e.quote
+			q.Symbol = s
+		} else if !ok {
+			q = Quote{Symbol: s, Kind: kind, Provider: p.Name(), Error: "not fetched"}
+			failed = true
+		}
+		quotes[i] = q
+	}
+	if err != nil && failed {
+		return quotes, err
+	}
+	return quotes, nil
+}
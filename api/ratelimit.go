@@ -0,0 +1,174 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Rate limit groups of the endpoints that fetch from other hosts.
+const (
+	RateLimitFavicon = "favicon"
+	RateLimitRSS     = "rss"
+	RateLimitMonitor = "monitor"
+	RateLimitSNMP    = "snmp"
+	RateLimitGitHub  = "github"
+	RateLimitICS     = "ics"
+)
+
+// RateLimit is a token bucket: Rate requests per minute with bursts of up to Burst
+// (default half the rate). A rate of 0 disables the limit.
+type RateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst,omitempty"`
+}
+
+// defaultRateLimits are generous enough for a dashboard with many quick links, monitors
+// and feeds, but stop a single client from using the server to flood other hosts.
+var defaultRateLimits = map[string]RateLimit{
+	RateLimitFavicon: {Rate: 120, Burst: 60},
+	RateLimitRSS:     {Rate: 60, Burst: 30},
+	RateLimitMonitor: {Rate: 240, Burst: 120},
+	RateLimitSNMP:    {Rate: 120, Burst: 60},
+	RateLimitGitHub:  {Rate: 60, Burst: 30},
+	RateLimitICS:     {Rate: 30, Burst: 10},
+}
+
+// RateLimitConfig configures the per-client rate limits of outbound-fetching endpoints.
+type RateLimitConfig struct {
+	Disabled bool                 `json:"disabled,omitempty"`
+	Limits   map[string]RateLimit `json:"limits,omitempty"` // Per group: favicon, rss, monitor, snmp, github, ics
+	Exempt   []string             `json:"exempt,omitempty"` // IPs or CIDRs that are never limited
+}
+
+// Validate checks the groups, rates and exempt addresses.
+func (c RateLimitConfig) Validate() error {
+	for group, limit := range c.Limits {
+		if _, ok := defaultRateLimits[group]; !ok {
+			return fmt.Errorf("rateLimit: unknown group %q", group)
+		}
+		if limit.Rate < 0 || limit.Burst < 0 {
+			return fmt.Errorf("rateLimit.limits.%s: rate and burst must not be negative", group)
+		}
+	}
+	if _, err := ParseTrustedProxies(c.Exempt); err != nil {
+		return fmt.Errorf("rateLimit.exempt: %w", err)
+	}
+	return nil
+}
+
+// bucket is the token bucket of one client and group.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter keeps token buckets per client IP and endpoint group.
+type RateLimiter struct {
+	mu        sync.Mutex
+	disabled  bool
+	limits    map[string]RateLimit
+	exempt    []*net.IPNet
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+var rateLimiter = &RateLimiter{
+	limits:  defaultRateLimits,
+	buckets: make(map[string]*bucket),
+}
+
+// GetRateLimiter returns the global rate limiter.
+func GetRateLimiter() *RateLimiter {
+	return rateLimiter
+}
+
+// Configure applies the configured limits on top of the defaults.
+func (rl *RateLimiter) Configure(cfg RateLimitConfig) {
+	limits := make(map[string]RateLimit, len(defaultRateLimits))
+	for group, limit := range defaultRateLimits {
+		limits[group] = limit
+	}
+	for group, limit := range cfg.Limits {
+		if limit.Burst == 0 {
+			limit.Burst = max(1, int(limit.Rate/2))
+		}
+		limits[group] = limit
+	}
+	exempt, _ := ParseTrustedProxies(cfg.Exempt)
+
+	rl.mu.Lock()
+	rl.disabled = cfg.Disabled
+	rl.limits = limits
+	rl.exempt = exempt
+	rl.buckets = make(map[string]*bucket)
+	rl.mu.Unlock()
+}
+
+// Allow takes a token from the client's bucket for a group. When the bucket is empty it
+// returns false and how long until the next token is available.
+func (rl *RateLimiter) Allow(group, ip string) (bool, time.Duration) {
+	now := time.Now()
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	limit, ok := rl.limits[group]
+	if rl.disabled || !ok || limit.Rate == 0 {
+		return true, 0
+	}
+	if parsed := net.ParseIP(ip); parsed != nil && containsIP(rl.exempt, parsed) {
+		return true, 0
+	}
+	rl.sweep(now)
+
+	perSecond := limit.Rate / 60
+	key := group + "|" + ip
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		rl.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets of clients idle for ten minutes. Caller must hold mu.
+func (rl *RateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < time.Minute {
+		return
+	}
+	rl.lastSweep = now
+	for key, b := range rl.buckets {
+		if now.Sub(b.last) > 10*time.Minute {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// RateLimited wraps a handler so that each client IP is limited to the group's rate.
+// Requests over the limit get 429 Too Many Requests with a Retry-After header.
+func RateLimited(group string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := rateLimiter.Allow(group, GetClientIP(r))
+		if !ok {
+			retry := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retry))
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusTooManyRequests)
+			WriteJSON(w, map[string]any{"error": fmt.Sprintf("Too many requests, retry in %d seconds", retry), "retryAfter": retry})
+			Logger("ratelimit").Debug("request limited", "group", group, "client", GetClientIP(r), "path", r.URL.Path)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,291 @@
+package api
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits of the request statistics, so scans of random paths cannot grow them without bound.
+const (
+	requestStatsMaxRoutes  = 500
+	requestStatsMaxClients = 1000
+	requestStatsTopClients = 20
+)
+
+// RequestLogConfig enables the request middleware.
+type RequestLogConfig struct {
+	Log     bool     `json:"log"`               // Log method, path, status, duration and client IP of every request
+	Stats   bool     `json:"stats"`             // Aggregate counters per route and client for /api/stats
+	Exclude []string `json:"exclude,omitempty"` // Path prefixes that are not logged (still counted), e.g. "/static/"
+	// Add a Server-Timing header with upstream fetch times and cache hits to API responses
+	ServerTiming bool `json:"serverTiming,omitempty"`
+}
+
+// RouteStats are the counters of one method and route.
+type RouteStats struct {
+	Method     string           `json:"method"`
+	Path       string           `json:"path"`
+	Count      int64            `json:"count"`
+	Errors     int64            `json:"errors"` // 5xx responses
+	AvgMs      float64          `json:"avgMs"`
+	MaxMs      float64          `json:"maxMs"`
+	Status     map[string]int64 `json:"status"` // By class, e.g. "2xx"
+	LastStatus int              `json:"lastStatus"`
+	LastSeen   time.Time        `json:"lastSeen"`
+
+	totalDuration time.Duration
+}
+
+// ClientStats counts the requests of one client IP.
+type ClientStats struct {
+	IP       string    `json:"ip"`
+	Count    int64     `json:"count"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// RequestStatsSnapshot is a copy of the request statistics.
+type RequestStatsSnapshot struct {
+	Enabled bool          `json:"enabled"`
+	Since   time.Time     `json:"since"`
+	Total   int64         `json:"total"`
+	Errors  int64         `json:"errors"`
+	Routes  []RouteStats  `json:"routes"`
+	Clients []ClientStats `json:"clients"`
+}
+
+// RequestStats aggregates request counters since startup.
+type RequestStats struct {
+	mu      sync.Mutex
+	config  RequestLogConfig
+	since   time.Time
+	total   int64
+	errors  int64
+	routes  map[string]*RouteStats
+	clients map[string]*ClientStats
+}
+
+var requestStats = &RequestStats{
+	since:   time.Now(),
+	routes:  make(map[string]*RouteStats),
+	clients: make(map[string]*ClientStats),
+}
+
+// GetRequestStats returns the global request statistics.
+func GetRequestStats() *RequestStats {
+	return requestStats
+}
+
+// Configure sets which parts of the middleware are enabled.
+func (rs *RequestStats) Configure(cfg RequestLogConfig) {
+	rs.mu.Lock()
+	rs.config = cfg
+	rs.mu.Unlock()
+}
+
+// Config returns the current configuration.
+func (rs *RequestStats) Config() RequestLogConfig {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.config
+}
+
+// routeKey collapses the paths of static files and profile pages into one route each.
+func routeKey(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/static/"):
+		return "/static/*"
+	case strings.HasPrefix(path, "/p/"):
+		return "/p/{profile}"
+	case strings.HasPrefix(path, "/page/"):
+		return "/page/{page}"
+	}
+	return path
+}
+
+// Record adds a finished request to the counters.
+func (rs *RequestStats) Record(method, path string, status int, duration time.Duration, clientIP string) {
+	now := time.Now()
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.total++
+	if status >= 500 {
+		rs.errors++
+	}
+
+	key := method + " " + routeKey(path)
+	route, ok := rs.routes[key]
+	if !ok {
+		if len(rs.routes) >= requestStatsMaxRoutes {
+			key = method + " (other)"
+			route = rs.routes[key]
+		}
+		if route == nil {
+			route = &RouteStats{Method: method, Path: strings.TrimPrefix(key, method+" "), Status: make(map[string]int64)}
+			rs.routes[key] = route
+		}
+	}
+	route.Count++
+	if status >= 500 {
+		route.Errors++
+	}
+	route.totalDuration += duration
+	route.MaxMs = max(route.MaxMs, float64(duration.Microseconds())/1000)
+	route.Status[strconv.Itoa(status/100)+"xx"]++
+	route.LastStatus = status
+	route.LastSeen = now
+
+	client, ok := rs.clients[clientIP]
+	if !ok && len(rs.clients) < requestStatsMaxClients {
+		client = &ClientStats{IP: clientIP}
+		rs.clients[clientIP] = client
+	}
+	if client != nil {
+		client.Count++
+		client.LastSeen = now
+	}
+}
+
+// Snapshot returns the routes sorted by request count and the busiest clients.
+func (rs *RequestStats) Snapshot() RequestStatsSnapshot {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	snap := RequestStatsSnapshot{
+		Enabled: rs.config.Stats,
+		Since:   rs.since,
+		Total:   rs.total,
+		Errors:  rs.errors,
+		Routes:  make([]RouteStats, 0, len(rs.routes)),
+		Clients: make([]ClientStats, 0, len(rs.clients)),
+	}
+	for _, route := range rs.routes {
+		r := *route
+		r.Status = make(map[string]int64, len(route.Status))
+		for class, n := range route.Status {
+			r.Status[class] = n
+		}
+		r.AvgMs = float64(route.totalDuration.Microseconds()) / 1000 / float64(route.Count)
+		snap.Routes = append(snap.Routes, r)
+	}
+	sort.Slice(snap.Routes, func(i, j int) bool {
+		if snap.Routes[i].Count != snap.Routes[j].Count {
+			return snap.Routes[i].Count > snap.Routes[j].Count
+		}
+		return snap.Routes[i].Path < snap.Routes[j].Path
+	})
+	for _, client := range rs.clients {
+		snap.Clients = append(snap.Clients, *client)
+	}
+	sort.Slice(snap.Clients, func(i, j int) bool {
+		return snap.Clients[i].Count > snap.Clients[j].Count
+	})
+	if len(snap.Clients) > requestStatsTopClients {
+		snap.Clients = snap.Clients[:requestStatsTopClients]
+	}
+	return snap
+}
+
+// Reset clears the counters.
+func (rs *RequestStats) Reset() {
+	rs.mu.Lock()
+	rs.since = time.Now()
+	rs.total = 0
+	rs.errors = 0
+	rs.routes = make(map[string]*RouteStats)
+	rs.clients = make(map[string]*ClientStats)
+	rs.mu.Unlock()
+}
+
+// statusRecorder captures the status code of a response. It passes hijacking through
+// for WebSocket upgrades and exposes the wrapped writer to http.ResponseController.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(p []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	return sr.ResponseWriter.Write(p)
+}
+
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := sr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijacking not supported")
+	}
+	if sr.status == 0 {
+		sr.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// WithRequestLog logs and counts requests as configured in the request statistics, and
+// times API requests for the usage statistics. It does nothing while all are disabled.
+func WithRequestLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := requestStats.Config()
+		usage := usageStats.Enabled() && strings.HasPrefix(r.URL.Path, "/api/")
+		if !cfg.Log && !cfg.Stats && !usage {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		duration := time.Since(start)
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		clientIP := GetClientIP(r)
+
+		if cfg.Stats {
+			requestStats.Record(r.Method, r.URL.Path, status, duration, clientIP)
+		}
+		if usage {
+			usageStats.RecordAPI(r.Method, r.URL.Path, status, duration)
+		}
+		if cfg.Log && !hasAnyPrefix(r.URL.Path, cfg.Exclude) {
+			Logger("http").Info("request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", status,
+				"duration", duration.Round(time.Microsecond).String(),
+				"client", clientIP)
+		}
+	})
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if p != "" && strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// retentionInterval is how often the pruning job runs.
+const retentionInterval = time.Hour
+
+// RetentionRun describes one run of the pruning job.
+type RetentionRun struct {
+	Time       time.Time        `json:"time"`
+	DurationMs int64            `json:"durationMs"`
+	Removed    map[string]int64 `json:"removed"` // Entries removed per data set
+}
+
+// RetentionPolicy is how long a data set is kept.
+type RetentionPolicy struct {
+	Retention string `json:"retention"` // e.g. "30d"
+	Seconds   int64  `json:"seconds"`
+}
+
+// RetentionFile is a data file and its size.
+type RetentionFile struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+}
+
+// RetentionStatus describes the policies, the pruning job and the storage footprint.
+type RetentionStatus struct {
+	Driver     string                     `json:"driver"`
+	Policies   map[string]RetentionPolicy `json:"policies"`
+	LastRun    *RetentionRun              `json:"lastRun,omitempty"`
+	NextRun    *time.Time                 `json:"nextRun,omitempty"`
+	Files      []RetentionFile            `json:"files"`
+	TotalBytes int64                      `json:"totalBytes"`
+	Entries    map[string]int64           `json:"entries"` // Current entries per data set
+}
+
+// RetentionManager prunes stored data past its retention on a schedule.
+type RetentionManager struct {
+	mu        sync.Mutex
+	retention map[string]time.Duration
+	lastRun   *RetentionRun
+	nextRun   time.Time
+}
+
+var retentionManager = &RetentionManager{}
+
+// GetRetentionManager returns the global retention manager.
+func GetRetentionManager() *RetentionManager {
+	return retentionManager
+}
+
+// Configure sets the retention policies. Invalid values are rejected by config validation
+// and fall back to the defaults here.
+func (rm *RetentionManager) Configure(cfg StoreRetention) {
+	retention, err := cfg.Durations()
+	if err != nil {
+		retention, _ = StoreRetention{}.Durations()
+	}
+	rm.mu.Lock()
+	rm.retention = retention
+	rm.mu.Unlock()
+}
+
+// policies returns the configured retention, or the defaults before Configure.
+func (rm *RetentionManager) policies() map[string]time.Duration {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	if rm.retention == nil {
+		rm.retention, _ = StoreRetention{}.Durations()
+	}
+	return rm.retention
+}
+
+// Start prunes at startup and then every hour.
+func (rm *RetentionManager) Start() {
+	rm.Prune()
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		rm.Prune()
+	}
+}
+
+// Prune removes everything past its retention now and returns what was removed.
+func (rm *RetentionManager) Prune() RetentionRun {
+	retention := rm.policies()
+	start := time.Now()
+
+	removed := GetStore().Prune(retention)
+	add := func(name string, n int) {
+		if n > 0 {
+			removed[name] += int64(n)
+		}
+	}
+	add("timeline", GetTimeline().Prune(start.Add(-retention["timeline"])))
+	add("audit", GetAuditLog().Prune(start.Add(-retention["audit"])))
+	add("searchHistory", pruneSearchHistory(start.Add(-retention["search_history"]))+GetSearchHistoryStore().Prune(start.Add(-retention["search_history"])))
+	add("sessions", GetTokenManager().PruneSessions())
+	add("favicons", GetFaviconCache().Prune())
+	add("drops", GetDropStore().Prune())
+
+	run := RetentionRun{Time: start, DurationMs: time.Since(start).Milliseconds(), Removed: removed}
+	rm.mu.Lock()
+	rm.lastRun = &run
+	rm.nextRun = start.Add(retentionInterval)
+	rm.mu.Unlock()
+
+	var total int64
+	for _, n := range removed {
+		total += n
+	}
+	if total > 0 {
+		Logger("retention").Info("pruned expired data", "removed", total, "duration", time.Since(start).Round(time.Millisecond))
+	}
+	GetDebugLogger().Logf("retention", "pruned %v in %v", removed, time.Since(start))
+	return run
+}
+
+// pruneSearchHistory removes searches older than before from the synced search history
+// and returns how many were removed.
+func pruneSearchHistory(before time.Time) int {
+	item, ok := GetStorage().Get("searchHistory")
+	if !ok {
+		return 0
+	}
+	entries, ok := item.Value.([]any)
+	if !ok {
+		return 0
+	}
+	kept := make([]any, 0, len(entries))
+	for _, e := range entries {
+		if m, ok := e.(map[string]any); ok {
+			ts, _ := m["timestamp"].(string)
+			if t, err := time.Parse(time.RFC3339, ts); err == nil && t.Before(before) {
+				continue
+			}
+		}
+		kept = append(kept, e)
+	}
+	removed := len(entries) - len(kept)
+	if removed > 0 {
+		GetStorage().Set("searchHistory", kept, item.Version+1)
+	}
+	return removed
+}
+
+// retentionFiles are the data files counted in the storage footprint.
+var retentionFiles = []string{
+	tokensFile, sessionsFile, auditFile, timelineFile, metricsHistoryFile, alertQueueFile,
+	pushStateFile, webhooksFile, bootHistoryFile, guestWiFiFile, snmpProfilesFile,
+	searchHistoryFile,
+}
+
+// Status returns the policies, the last and next run and the current storage footprint.
+func (rm *RetentionManager) Status() RetentionStatus {
+	retention := rm.policies()
+	history := GetMetricsHistory()
+	store := GetStore().Status()
+
+	status := RetentionStatus{
+		Driver:   store.Driver,
+		Policies: make(map[string]RetentionPolicy),
+		Files:    []RetentionFile{},
+		Entries:  make(map[string]int64),
+	}
+	for name, d := range retention {
+		status.Policies[name] = retentionPolicy(d)
+	}
+	status.Policies["metrics"] = retentionPolicy(history.fineRetention)
+	status.Policies["metrics_hourly"] = retentionPolicy(history.hourlyRetention)
+
+	rm.mu.Lock()
+	if rm.lastRun != nil {
+		run := *rm.lastRun
+		next := rm.nextRun
+		status.LastRun, status.NextRun = &run, &next
+	}
+	rm.mu.Unlock()
+
+	files := retentionFiles
+	if store.Path != "" {
+		files = append(files[:len(files):len(files)], store.Path, store.Path+"-wal")
+	}
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		status.Files = append(status.Files, RetentionFile{Path: path, Bytes: info.Size()})
+		status.TotalBytes += info.Size()
+	}
+
+	for table, n := range store.Rows {
+		status.Entries[table] = n
+	}
+	status.Entries["timeline"] = int64(GetTimeline().Len())
+	status.Entries["audit"] = int64(GetAuditLog().Len())
+	status.Entries["sessions"] = int64(GetTokenManager().SessionCount())
+	var searches []any
+	if GetStorage().GetAs("searchHistory", &searches) {
+		status.Entries["searchHistory"] = int64(len(searches))
+	}
+	if GetSearchHistoryStore().Enabled() {
+		status.Entries["searchHistoryServer"] = int64(GetSearchHistoryStore().Len())
+	}
+	status.Entries["favicons"] = int64(GetFaviconCache().Len())
+	return status
+}
+
+// retentionPolicy describes a duration in days when it is a whole number of days.
+func retentionPolicy(d time.Duration) RetentionPolicy {
+	text := d.String()
+	if d >= 24*time.Hour && d%(24*time.Hour) == 0 {
+		text = fmt.Sprintf("%dd", d/(24*time.Hour))
+	} else if d%time.Hour == 0 {
+		text = fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return RetentionPolicy{Retention: text, Seconds: int64(d.Seconds())}
+}
@@ -0,0 +1,381 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// routerCacheTTL is how long router status is reused between requests.
+const routerCacheTTL = 15 * time.Second
+
+// RouterConfig configures the OpenWrt router integration behind /api/router.
+type RouterConfig struct {
+	OpenWrtConfig
+	WANInterface string `json:"wanInterface,omitempty"` // Logical WAN interface, default "wan"
+	// Public also shows leases and clients outside the local network (default: local only)
+	Public bool `json:"public,omitempty"`
+}
+
+// Validate checks the router configuration.
+func (c RouterConfig) Validate() error {
+	if err := c.OpenWrtConfig.Validate(); err != nil {
+		return fmt.Errorf("router: %w", err)
+	}
+	return nil
+}
+
+// RouterBoard is the router model and firmware.
+type RouterBoard struct {
+	Hostname string  `json:"hostname"`
+	Model    string  `json:"model"`
+	Release  string  `json:"release"`
+	Uptime   int64   `json:"uptime"`
+	Load     float64 `json:"load"` // 1 minute load average
+	MemTotal uint64  `json:"memTotal"`
+	MemFree  uint64  `json:"memFree"`
+}
+
+// RouterWAN is the state of the WAN interface.
+type RouterWAN struct {
+	Interface string   `json:"interface"`
+	Up        bool     `json:"up"`
+	Protocol  string   `json:"protocol"`
+	Device    string   `json:"device,omitempty"`
+	Uptime    int64    `json:"uptime"`
+	IPv4      []string `json:"ipv4,omitempty"`
+	IPv6      []string `json:"ipv6,omitempty"`
+	Gateway   string   `json:"gateway,omitempty"`
+	DNS       []string `json:"dns,omitempty"`
+}
+
+// RouterLease is a DHCP lease handed out by the router.
+type RouterLease struct {
+	Hostname string `json:"hostname,omitempty"`
+	IP       string `json:"ip"`
+	MAC      string `json:"mac,omitempty"`
+	Expires  int64  `json:"expires"` // Seconds until the lease expires, -1 for static leases
+}
+
+// RouterWireless is the number of clients on one wireless interface.
+type RouterWireless struct {
+	Device  string `json:"device"`
+	SSID    string `json:"ssid,omitempty"`
+	Band    string `json:"band,omitempty"`
+	Channel int    `json:"channel,omitempty"`
+	Clients int    `json:"clients"`
+}
+
+// RouterStatus is the combined router status returned by /api/router.
+type RouterStatus struct {
+	Board        *RouterBoard     `json:"board,omitempty"`
+	WAN          *RouterWAN       `json:"wan,omitempty"`
+	Leases       []RouterLease    `json:"leases"`
+	Wireless     []RouterWireless `json:"wireless"`
+	TotalClients int              `json:"totalClients"`
+	Errors       []string         `json:"errors,omitempty"` // Parts that could not be read (e.g. missing rpcd ACLs)
+	Updated      time.Time        `json:"updated"`
+}
+
+// RouterMonitor reads status from an OpenWrt router over ubus.
+type RouterMonitor struct {
+	mu      sync.Mutex
+	config  *RouterConfig
+	client  *UbusClient
+	cached  *RouterStatus
+	fetched time.Time
+}
+
+// Global router monitor instance
+var routerMonitor = &RouterMonitor{}
+
+// GetRouterMonitor returns the global router monitor instance.
+func GetRouterMonitor() *RouterMonitor {
+	return routerMonitor
+}
+
+// Configure sets the router to read from.
+func (rm *RouterMonitor) Configure(cfg RouterConfig) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.config = &cfg
+	rm.client = NewUbusClient(cfg.OpenWrtConfig)
+	rm.cached = nil
+}
+
+// Enabled reports whether a router is configured.
+func (rm *RouterMonitor) Enabled() bool {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return rm.config != nil
+}
+
+// Visible reports whether router details may be shown to the client of a request.
+// Clients signed in with an API token count as local.
+func (rm *RouterMonitor) Visible(r *http.Request) bool {
+	trusted := IsLocalRequest(r) || !RequestIdentity(r).Anonymous
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return trusted || (rm.config != nil && rm.config.Public)
+}
+
+// Status returns the router status, reading it again when the cached copy is stale.
+func (rm *RouterMonitor) Status(ctx context.Context) (*RouterStatus, error) {
+	rm.mu.Lock()
+	if rm.config == nil {
+		rm.mu.Unlock()
+		return nil, errors.New("no router configured")
+	}
+	if rm.cached != nil && time.Since(rm.fetched) < routerCacheTTL {
+		status := rm.cached
+		rm.mu.Unlock()
+		return status, nil
+	}
+	cfg := *rm.config
+	client := rm.client
+	rm.mu.Unlock()
+
+	status, err := fetchRouterStatus(ctx, client, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	rm.mu.Lock()
+	rm.cached = status
+	rm.fetched = time.Now()
+	rm.mu.Unlock()
+	return status, nil
+}
+
+// fetchRouterStatus reads the board, WAN, DHCP and wireless state. Only a failed
+// login is an error; parts the user has no access to are listed in Errors.
+func fetchRouterStatus(ctx context.Context, client *UbusClient, cfg RouterConfig) (*RouterStatus, error) {
+	status := &RouterStatus{Leases: []RouterLease{}, Wireless: []RouterWireless{}, Updated: time.Now()}
+
+	board, err := fetchRouterBoard(ctx, client)
+	if err != nil {
+		if errors.Is(err, ErrUbusLogin) {
+			return nil, err
+		}
+		status.Errors = append(status.Errors, "system: "+err.Error())
+	} else {
+		status.Board = board
+	}
+
+	wanIface := cfg.WANInterface
+	if wanIface == "" {
+		wanIface = "wan"
+	}
+	if wan, err := fetchRouterWAN(ctx, client, wanIface); err != nil {
+		status.Errors = append(status.Errors, "wan: "+err.Error())
+	} else {
+		status.WAN = wan
+	}
+
+	if leases, err := fetchRouterLeases(ctx, client); err != nil {
+		status.Errors = append(status.Errors, "dhcp: "+err.Error())
+	} else {
+		status.Leases = leases
+	}
+
+	if wireless, err := fetchRouterWireless(ctx, client); err != nil {
+		status.Errors = append(status.Errors, "wireless: "+err.Error())
+	} else {
+		status.Wireless = wireless
+		for _, w := range wireless {
+			status.TotalClients += w.Clients
+		}
+	}
+	return status, nil
+}
+
+// fetchRouterBoard reads system board and info.
+func fetchRouterBoard(ctx context.Context, client *UbusClient) (*RouterBoard, error) {
+	var board struct {
+		Hostname string `json:"hostname"`
+		Model    string `json:"model"`
+		Release  struct {
+			Description string `json:"description"`
+		} `json:"release"`
+	}
+	if err := client.Call(ctx, "system", "board", nil, &board); err != nil {
+		return nil, err
+	}
+	var info struct {
+		Uptime int64     `json:"uptime"`
+		Load   []float64 `json:"load"`
+		Memory struct {
+			Total uint64 `json:"total"`
+			Free  uint64 `json:"free"`
+		} `json:"memory"`
+	}
+	if err := client.Call(ctx, "system", "info", nil, &info); err != nil {
+		return nil, err
+	}
+	b := &RouterBoard{
+		Hostname: board.Hostname,
+		Model:    board.Model,
+		Release:  board.Release.Description,
+		Uptime:   info.Uptime,
+		MemTotal: info.Memory.Total,
+		MemFree:  info.Memory.Free,
+	}
+	if len(info.Load) > 0 {
+		// ubus reports load averages scaled by 65536
+		b.Load = info.Load[0] / 65536
+	}
+	return b, nil
+}
+
+// fetchRouterWAN reads the status of a logical network interface.
+func fetchRouterWAN(ctx context.Context, client *UbusClient, iface string) (*RouterWAN, error) {
+	type address struct {
+		Address string `json:"address"`
+		Mask    int    `json:"mask"`
+	}
+	var st struct {
+		Up       bool      `json:"up"`
+		Uptime   int64     `json:"uptime"`
+		Proto    string    `json:"proto"`
+		L3Device string    `json:"l3_device"`
+		IPv4     []address `json:"ipv4-address"`
+		IPv6     []address `json:"ipv6-address"`
+		DNS      []string  `json:"dns-server"`
+		Route    []struct {
+			Target  string `json:"target"`
+			Mask    int    `json:"mask"`
+			Nexthop string `json:"nexthop"`
+		} `json:"route"`
+	}
+	if err := client.Call(ctx, "network.interface."+iface, "status", nil, &st); err != nil {
+		return nil, err
+	}
+	wan := &RouterWAN{
+		Interface: iface,
+		Up:        st.Up,
+		Protocol:  st.Proto,
+		Device:    st.L3Device,
+		Uptime:    st.Uptime,
+		DNS:       st.DNS,
+	}
+	for _, a := range st.IPv4 {
+		wan.IPv4 = append(wan.IPv4, a.Address+"/"+strconv.Itoa(a.Mask))
+	}
+	for _, a := range st.IPv6 {
+		wan.IPv6 = append(wan.IPv6, a.Address+"/"+strconv.Itoa(a.Mask))
+	}
+	for _, r := range st.Route {
+		if r.Target == "0.0.0.0" && r.Mask == 0 {
+			wan.Gateway = r.Nexthop
+			break
+		}
+	}
+	return wan, nil
+}
+
+// fetchRouterLeases reads DHCP leases through luci-rpc, falling back to /tmp/dhcp.leases
+// (dnsmasq format) through the rpcd file plugin on routers without LuCI.
+func fetchRouterLeases(ctx context.Context, client *UbusClient) ([]RouterLease, error) {
+	var reply struct {
+		Leases []struct {
+			Hostname string `json:"hostname"`
+			IP       string `json:"ipaddr"`
+			MAC      string `json:"macaddr"`
+			Expires  int64  `json:"expires"`
+		} `json:"dhcp_leases"`
+	}
+	leases := []RouterLease{}
+	err := client.Call(ctx, "luci-rpc", "getDHCPLeases", map[string]any{"family": 4}, &reply)
+	if err == nil {
+		for _, l := range reply.Leases {
+			leases = append(leases, RouterLease{Hostname: l.Hostname, IP: l.IP, MAC: strings.ToLower(l.MAC), Expires: l.Expires})
+		}
+	} else {
+		var file struct {
+			Data string `json:"data"`
+		}
+		if ferr := client.Call(ctx, "file", "read", map[string]any{"path": "/tmp/dhcp.leases"}, &file); ferr != nil {
+			return nil, err
+		}
+		now := time.Now().Unix()
+		for _, line := range strings.Split(file.Data, "\n") {
+			// <expiry epoch> <mac> <ip> <hostname> <client id>
+			fields := strings.Fields(line)
+			if len(fields) < 4 {
+				continue
+			}
+			l := RouterLease{MAC: strings.ToLower(fields[1]), IP: fields[2], Expires: -1}
+			if fields[3] != "*" {
+				l.Hostname = fields[3]
+			}
+			if expiry, err := strconv.ParseInt(fields[0], 10, 64); err == nil && expiry > 0 {
+				l.Expires = max(expiry-now, 0)
+			}
+			leases = append(leases, l)
+		}
+	}
+	sort.Slice(leases, func(i, j int) bool {
+		a, aErr := netip.ParseAddr(leases[i].IP)
+		b, bErr := netip.ParseAddr(leases[j].IP)
+		if aErr != nil || bErr != nil {
+			return leases[i].IP < leases[j].IP
+		}
+		return a.Less(b)
+	})
+	return leases, nil
+}
+
+// fetchRouterWireless counts associated clients per wireless interface through iwinfo.
+func fetchRouterWireless(ctx context.Context, client *UbusClient) ([]RouterWireless, error) {
+	var devices struct {
+		Devices []string `json:"devices"`
+	}
+	if err := client.Call(ctx, "iwinfo", "devices", nil, &devices); err != nil {
+		return nil, err
+	}
+	wireless := []RouterWireless{}
+	for _, dev := range devices.Devices {
+		w := RouterWireless{Device: dev}
+		var info struct {
+			SSID      string  `json:"ssid"`
+			Channel   int     `json:"channel"`
+			Frequency float64 `json:"frequency"`
+		}
+		if err := client.Call(ctx, "iwinfo", "info", map[string]any{"device": dev}, &info); err == nil {
+			w.SSID = info.SSID
+			w.Channel = info.Channel
+			w.Band = wifiBand(info.Frequency)
+		}
+		var assoc struct {
+			Results []struct {
+				MAC string `json:"mac"`
+			} `json:"results"`
+		}
+		if err := client.Call(ctx, "iwinfo", "assoclist", map[string]any{"device": dev}, &assoc); err != nil {
+			return nil, err
+		}
+		w.Clients = len(assoc.Results)
+		wireless = append(wireless, w)
+	}
+	return wireless, nil
+}
+
+// wifiBand names the band of a frequency in MHz.
+func wifiBand(mhz float64) string {
+	switch {
+	case mhz >= 5925:
+		return "6 GHz"
+	case mhz >= 5000:
+		return "5 GHz"
+	case mhz >= 2400:
+		return "2.4 GHz"
+	}
+	return ""
+}
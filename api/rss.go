@@ -5,9 +5,7 @@ import (
 	"crypto/tls"
 	"encoding/xml"
 	"fmt"
-	"log"
 	"net/http"
-	"net/url"
 	"regexp"
 	"strings"
 	"time"
@@ -51,19 +49,14 @@ type MediaContent struct {
 
 // FetchRSSFeed fetches and parses an RSS feed.
 func FetchRSSFeed(ctx context.Context, feedURL string, count int) ([]RSSFeedItem, error) {
-	parsedURL, err := url.Parse(feedURL)
-	if err != nil {
-		return nil, fmt.Errorf("invalid URL: %v", err)
-	}
-	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return nil, fmt.Errorf("URL must be http or https")
+	if err := CheckOutboundURL(ctx, feedURL); err != nil {
+		return nil, err
 	}
 
 	client := &http.Client{
-		Timeout: 15 * time.Second,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+		Timeout:       15 * time.Second,
+		Transport:     TimedTransport("rss", NewOutboundTransport(&tls.Config{InsecureSkipVerify: true})),
+		CheckRedirect: OutboundCheckRedirect(5),
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
@@ -78,7 +71,7 @@ func FetchRSSFeed(ctx context.Context, feedURL string, count int) ([]RSSFeedItem
 	}
 	defer func() {
 		if closeErr := resp.Body.Close(); closeErr != nil {
-			log.Printf("Error closing RSS response body: %v", closeErr)
+			Logger("rss").Warn("closing response body", "error", closeErr)
 		}
 	}()
 
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// searchEnginesFile holds the user-defined search engines across restarts.
+const searchEnginesFile = "search-engines.json"
+
+// maxCustomSearchEngines caps the user-defined search engines.
+const maxCustomSearchEngines = 100
+
+// ErrSearchEngineNotFound is returned when a search engine ID is not in the store.
+var ErrSearchEngineNotFound = errors.New("search engine not found")
+
+// SearchEngineCategories are the categories the search engine menu groups engines by.
+var SearchEngineCategories = []string{"general", "llm", "social", "media", "shopping", "maps", "development", "custom"}
+
+// searchBangPattern restricts bangs to short lowercase words.
+var searchBangPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,19}$`)
+
+// searchIconPattern restricts icons to Font Awesome class names, e.g. "fab fa-github".
+var searchIconPattern = regexp.MustCompile(`^[a-z0-9 -]{1,60}$`)
+
+// SearchEngineStore keeps the search engines defined by the user, shared by every client.
+type SearchEngineStore struct {
+	mu      sync.Mutex
+	engines []SearchEngine
+	loaded  bool
+}
+
+// Global search engine store instance
+var searchEngineStore = &SearchEngineStore{}
+
+// GetSearchEngineStore returns the global search engine store instance.
+func GetSearchEngineStore() *SearchEngineStore {
+	return searchEngineStore
+}
+
+// load reads the search engines file. Caller must hold mu.
+func (ss *SearchEngineStore) load() {
+	if ss.loaded {
+		return
+	}
+	ss.loaded = true
+	data, err := os.ReadFile(searchEnginesFile)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &ss.engines); err != nil {
+		GetDebugLogger().Logf("search", "failed to parse %s: %v", searchEnginesFile, err)
+		ss.engines = nil
+	}
+	for i := range ss.engines {
+		ss.engines[i].Custom = true
+	}
+}
+
+// save writes the search engines file. Caller must hold mu.
+func (ss *SearchEngineStore) save() error {
+	data, err := json.Marshal(ss.engines)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(searchEnginesFile, data, 0644)
+}
+
+// All returns the user-defined search engines in the order they were added.
+func (ss *SearchEngineStore) All() []SearchEngine {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.load()
+	return slices.Clone(ss.engines)
+}
+
+// Add stores a new search engine and returns it with its ID.
+func (ss *SearchEngineStore) Add(e SearchEngine) (SearchEngine, error) {
+	if err := normalizeSearchEngine(&e); err != nil {
+		return SearchEngine{}, err
+	}
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.load()
+	if len(ss.engines) >= maxCustomSearchEngines {
+		return SearchEngine{}, fmt.Errorf("search engine limit of %d reached", maxCustomSearchEngines)
+	}
+	e.ID = newStoreID()
+	if err := ss.checkUnique(e); err != nil {
+		return SearchEngine{}, err
+	}
+	ss.engines = append(ss.engines, e)
+	return e, ss.save()
+}
+
+// Update replaces a user-defined search engine.
+func (ss *SearchEngineStore) Update(e SearchEngine) (SearchEngine, error) {
+	if err := normalizeSearchEngine(&e); err != nil {
+		return SearchEngine{}, err
+	}
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.load()
+	i := slices.IndexFunc(ss.engines, func(existing SearchEngine) bool { return existing.ID == e.ID })
+	if e.ID == "" || i < 0 {
+		return SearchEngine{}, ErrSearchEngineNotFound
+	}
+	if err := ss.checkUnique(e); err != nil {
+		return SearchEngine{}, err
+	}
+	ss.engines[i] = e
+	return e, ss.save()
+}
+
+// Delete removes a user-defined search engine by ID.
+func (ss *SearchEngineStore) Delete(id string) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.load()
+	i := slices.IndexFunc(ss.engines, func(e SearchEngine) bool { return e.ID == id })
+	if id == "" || i < 0 {
+		return ErrSearchEngineNotFound
+	}
+	ss.engines = slices.Delete(ss.engines, i, i+1)
+	return ss.save()
+}
+
+// checkUnique rejects an engine whose name or bang is taken by another engine, built-in
+// or user-defined. Caller must hold mu.
+func (ss *SearchEngineStore) checkUnique(e SearchEngine) error {
+	for _, other := range append(builtinSearchEngines(), ss.engines...) {
+		if other.ID == e.ID && other.Custom {
+			continue
+		}
+		if strings.EqualFold(other.Name, e.Name) {
+			return fmt.Errorf("a search engine named %q already exists", other.Name)
+		}
+		if e.Bang != "" && other.Bang == e.Bang {
+			return fmt.Errorf("!%s is already used by %s", e.Bang, other.Name)
+		}
+	}
+	return nil
+}
+
+// normalizeSearchEngine checks a search engine from a client and fills in its defaults.
+func normalizeSearchEngine(e *SearchEngine) error {
+	e.Name = strings.TrimSpace(e.Name)
+	if e.Name == "" || len(e.Name) > 50 {
+		return errors.New("a name of up to 50 characters is required")
+	}
+	e.URL = strings.TrimSpace(e.URL)
+	u, err := url.Parse(strings.ReplaceAll(e.URL, "%s", "test"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("the URL must be an http or https URL")
+	}
+	if strings.Count(e.URL, "%s") != 1 {
+		return errors.New("the URL must contain %s once, where the query goes")
+	}
+	e.Icon = strings.TrimSpace(e.Icon)
+	if e.Icon == "" {
+		e.Icon = "fas fa-search"
+	}
+	if !searchIconPattern.MatchString(e.Icon) {
+		return errors.New("the icon must be Font Awesome classes, e.g. \"fas fa-search\"")
+	}
+	e.Category = strings.ToLower(strings.TrimSpace(e.Category))
+	if e.Category == "" {
+		e.Category = "custom"
+	}
+	if !slices.Contains(SearchEngineCategories, e.Category) {
+		return fmt.Errorf("category must be one of %s", strings.Join(SearchEngineCategories, ", "))
+	}
+	e.Bang = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(e.Bang), "!"))
+	if e.Bang != "" && !searchBangPattern.MatchString(e.Bang) {
+		return errors.New("a bang is up to 20 lowercase letters, digits, '.', '_' or '-'")
+	}
+	e.Custom = true
+	return nil
+}
@@ -1,54 +1,125 @@
 package api
 
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+)
+
 // SearchEngine represents a search engine configuration.
 type SearchEngine struct {
+	ID       string `json:"id,omitempty"` // Set on user-defined engines
 	Name     string `json:"name"`
-	URL      string `json:"url"`
+	URL      string `json:"url"` // %s is replaced by the query
 	Icon     string `json:"icon"`
 	Category string `json:"category"`
+	Bang     string `json:"bang,omitempty"` // Shortcut without the "!", e.g. "yt" for "!yt cats"
+	Custom   bool   `json:"custom,omitempty"`
 }
 
-// GetSearchEngines returns the list of available search engines.
+// GetSearchEngines returns the built-in search engines followed by the user-defined ones.
 func GetSearchEngines() []SearchEngine {
+	return append(builtinSearchEngines(), GetSearchEngineStore().All()...)
+}
+
+// builtinSearchEngines returns the search engines that ship with the dashboard.
+func builtinSearchEngines() []SearchEngine {
 	return []SearchEngine{
 		// General Search Engines
-		{Name: "Google", URL: "https://www.google.com/search?q=%s", Icon: "fab fa-google", Category: "general"},
-		{Name: "DuckDuckGo", URL: "https://duckduckgo.com/?q=%s", Icon: "fas fa-duck", Category: "general"},
-		{Name: "Bing", URL: "https://www.bing.com/search?q=%s", Icon: "fab fa-microsoft", Category: "general"},
-		{Name: "Brave", URL: "https://search.brave.com/search?q=%s", Icon: "fas fa-shield-alt", Category: "general"},
-		{Name: "Startpage", URL: "https://www.startpage.com/sp/search?query=%s", Icon: "fas fa-search", Category: "general"},
-		{Name: "Ecosia", URL: "https://www.ecosia.org/search?q=%s", Icon: "fas fa-leaf", Category: "general"},
-		{Name: "Qwant", URL: "https://www.qwant.com/?q=%s", Icon: "fas fa-search", Category: "general"},
-		{Name: "SearXNG", URL: "https://searx.org/search?q=%s", Icon: "fas fa-search", Category: "general"},
-		{Name: "Wikipedia", URL: "https://en.wikipedia.org/w/index.php?search=%s", Icon: "fab fa-wikipedia-w", Category: "general"},
+		{Name: "Google", URL: "https://www.google.com/search?q=%s", Icon: "fab fa-google", Category: "general", Bang: "g"},
+		{Name: "DuckDuckGo", URL: "https://duckduckgo.com/?q=%s", Icon: "fas fa-duck", Category: "general", Bang: "ddg"},
+		{Name: "Bing", URL: "https://www.bing.com/search?q=%s", Icon: "fab fa-microsoft", Category: "general", Bang: "b"},
+		{Name: "Brave", URL: "https://search.brave.com/search?q=%s", Icon: "fas fa-shield-alt", Category: "general", Bang: "brave"},
+		{Name: "Startpage", URL: "https://www.startpage.com/sp/search?query=%s", Icon: "fas fa-search", Category: "general", Bang: "sp"},
+		{Name: "Ecosia", URL: "https://www.ecosia.org/search?q=%s", Icon: "fas fa-leaf", Category: "general", Bang: "eco"},
+		{Name: "Qwant", URL: "https://www.qwant.com/?q=%s", Icon: "fas fa-search", Category: "general", Bang: "qw"},
+		{Name: "SearXNG", URL: "https://searx.org/search?q=%s", Icon: "fas fa-search", Category: "general", Bang: "sx"},
+		{Name: "Wikipedia", URL: "https://en.wikipedia.org/w/index.php?search=%s", Icon: "fab fa-wikipedia-w", Category: "general", Bang: "w"},
 
 		// LLM / AI Search
-		{Name: "Perplexity", URL: "https://www.perplexity.ai/search?q=%s", Icon: "fas fa-brain", Category: "llm"},
-		{Name: "ChatGPT", URL: "https://chat.openai.com/?q=%s", Icon: "fas fa-robot", Category: "llm"},
-		{Name: "DeepSeek", URL: "https://www.deepseek.com/chat?q=%s", Icon: "fas fa-brain", Category: "llm"},
-		{Name: "Kimi", URL: "https://kimi.moonshot.cn/search?q=%s", Icon: "fas fa-sparkles", Category: "llm"},
-		{Name: "Claude", URL: "https://claude.ai/chat?q=%s", Icon: "fas fa-comments", Category: "llm"},
+		{Name: "Perplexity", URL: "https://www.perplexity.ai/search?q=%s", Icon: "fas fa-brain", Category: "llm", Bang: "pplx"},
+		{Name: "ChatGPT", URL: "https://chat.openai.com/?q=%s", Icon: "fas fa-robot", Category: "llm", Bang: "gpt"},
+		{Name: "DeepSeek", URL: "https://www.deepseek.com/chat?q=%s", Icon: "fas fa-brain", Category: "llm", Bang: "ds"},
+		{Name: "Kimi", URL: "https://kimi.moonshot.cn/search?q=%s", Icon: "fas fa-sparkles", Category: "llm", Bang: "kimi"},
+		{Name: "Claude", URL: "https://claude.ai/chat?q=%s", Icon: "fas fa-comments", Category: "llm", Bang: "claude"},
 
 		// Social
-		{Name: "Reddit", URL: "https://www.reddit.com/search/?q=%s", Icon: "fab fa-reddit", Category: "social"},
+		{Name: "Reddit", URL: "https://www.reddit.com/search/?q=%s", Icon: "fab fa-reddit", Category: "social", Bang: "r"},
 
 		// Media
-		{Name: "YouTube", URL: "https://www.youtube.com/results?search_query=%s", Icon: "fab fa-youtube", Category: "media"},
-		{Name: "Genius", URL: "https://genius.com/search?q=%s", Icon: "fas fa-music", Category: "media"},
-		{Name: "AZLyrics", URL: "https://search.azlyrics.com/search.php?q=%s", Icon: "fas fa-music", Category: "media"},
-		{Name: "Lyrics.com", URL: "https://www.lyrics.com/lyrics/%s", Icon: "fas fa-music", Category: "media"},
+		{Name: "YouTube", URL: "https://www.youtube.com/results?search_query=%s", Icon: "fab fa-youtube", Category: "media", Bang: "yt"},
+		{Name: "Genius", URL: "https://genius.com/search?q=%s", Icon: "fas fa-music", Category: "media", Bang: "genius"},
+		{Name: "AZLyrics", URL: "https://search.azlyrics.com/search.php?q=%s", Icon: "fas fa-music", Category: "media", Bang: "azl"},
+		{Name: "Lyrics.com", URL: "https://www.lyrics.com/lyrics/%s", Icon: "fas fa-music", Category: "media", Bang: "lyrics"},
 
 		// Shopping
-		{Name: "Skroutz", URL: "https://www.skroutz.gr/search?keyphrase=%s", Icon: "fas fa-shopping-bag", Category: "shopping"},
-		{Name: "Amazon", URL: "https://www.amazon.com/s?k=%s", Icon: "fab fa-amazon", Category: "shopping"},
-		{Name: "eBay", URL: "https://www.ebay.com/sch/i.html?_nkw=%s", Icon: "fab fa-ebay", Category: "shopping"},
+		{Name: "Skroutz", URL: "https://www.skroutz.gr/search?keyphrase=%s", Icon: "fas fa-shopping-bag", Category: "shopping", Bang: "sk"},
+		{Name: "Amazon", URL: "https://www.amazon.com/s?k=%s", Icon: "fab fa-amazon", Category: "shopping", Bang: "a"},
+		{Name: "eBay", URL: "https://www.ebay.com/sch/i.html?_nkw=%s", Icon: "fab fa-ebay", Category: "shopping", Bang: "e"},
 
 		// Maps
-		{Name: "Google Maps", URL: "https://www.google.com/maps/search/%s", Icon: "fas fa-map-marker-alt", Category: "maps"},
-		{Name: "OpenStreetMap", URL: "https://www.openstreetmap.org/search?query=%s", Icon: "fas fa-map", Category: "maps"},
+		{Name: "Google Maps", URL: "https://www.google.com/maps/search/%s", Icon: "fas fa-map-marker-alt", Category: "maps", Bang: "gm"},
+		{Name: "OpenStreetMap", URL: "https://www.openstreetmap.org/search?query=%s", Icon: "fas fa-map", Category: "maps", Bang: "osm"},
 
 		// Development
-		{Name: "GitHub", URL: "https://github.com/search?q=%s", Icon: "fab fa-github", Category: "development"},
-		{Name: "Stack Overflow", URL: "https://stackoverflow.com/search?q=%s", Icon: "fab fa-stack-overflow", Category: "development"},
+		{Name: "GitHub", URL: "https://github.com/search?q=%s", Icon: "fab fa-github", Category: "development", Bang: "gh"},
+		{Name: "Stack Overflow", URL: "https://stackoverflow.com/search?q=%s", Icon: "fab fa-stack-overflow", Category: "development", Bang: "so"},
+	}
+}
+
+// SearchResolution is the target of a query typed in the search box.
+type SearchResolution struct {
+	Engine string `json:"engine"`
+	Query  string `json:"query"` // The query without the bang
+	Bang   string `json:"bang,omitempty"`
+	URL    string `json:"url"`
+}
+
+// ResolveSearch returns the URL a query searches. A bang such as "!yt" at the start or end
+// of the query picks the engine with that shortcut; otherwise the engine named by
+// defaultEngine is used, or the first engine when it is unknown. A bang without a query
+// opens the engine's home page.
+func ResolveSearch(query, defaultEngine string) (SearchResolution, error) {
+	engines := GetSearchEngines()
+	if len(engines) == 0 {
+		return SearchResolution{}, errors.New("no search engines")
+	}
+
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return SearchResolution{}, errors.New("empty query")
+	}
+	var engine *SearchEngine
+	var bang string
+	for _, i := range []int{0, len(words) - 1} {
+		if !strings.HasPrefix(words[i], "!") {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(words[i], "!"))
+		if j := slices.IndexFunc(engines, func(e SearchEngine) bool { return e.Bang != "" && e.Bang == name }); j >= 0 {
+			engine, bang = &engines[j], name
+			words = slices.Delete(words, i, i+1)
+			break
+		}
+	}
+	if engine == nil {
+		engine = &engines[0]
+		if j := slices.IndexFunc(engines, func(e SearchEngine) bool { return strings.EqualFold(e.Name, defaultEngine) }); j >= 0 {
+			engine = &engines[j]
+		}
+	}
+
+	res := SearchResolution{Engine: engine.Name, Query: strings.Join(words, " "), Bang: bang}
+	if res.Query == "" {
+		u, err := url.Parse(strings.ReplaceAll(engine.URL, "%s", ""))
+		if err != nil {
+			return SearchResolution{}, err
+		}
+		res.URL = u.Scheme + "://" + u.Host + "/"
+		return res, nil
 	}
+	// Escape like encodeURIComponent, which the search box uses
+	res.URL = strings.Replace(engine.URL, "%s", strings.ReplaceAll(url.QueryEscape(res.Query), "+", "%20"), 1)
+	return res, nil
 }
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// searchHistoryFile holds the server-side search history across restarts.
+const searchHistoryFile = "search-history.json"
+
+// Limits of the server-side search history.
+const (
+	DefaultSearchHistoryEntries = 1000
+	maxSearchHistoryEntries     = 100000
+)
+
+// SearchHistoryConfig turns on the server-side search history, shared by every device
+// instead of kept in each browser. Searches are kept for the store's searchHistory
+// retention.
+type SearchHistoryConfig struct {
+	MaxEntries int `json:"maxEntries,omitempty"` // Newest searches kept, default 1000
+}
+
+// Validate checks the search history settings.
+func (c SearchHistoryConfig) Validate() error {
+	if c.MaxEntries < 0 || c.MaxEntries > maxSearchHistoryEntries {
+		return fmt.Errorf("searchHistory: maxEntries must be between 1 and %d", maxSearchHistoryEntries)
+	}
+	return nil
+}
+
+// SearchHistoryEntry is a search kept on the server with the device it was made on.
+type SearchHistoryEntry struct {
+	SearchHistoryItem
+	Device string `json:"device,omitempty"` // e.g. "Firefox on Android"
+}
+
+// SearchHistoryPurge selects the searches to remove. Empty fields match every search, so
+// the zero value removes the whole history.
+type SearchHistoryPurge struct {
+	Term   string    `json:"term,omitempty"`
+	Engine string    `json:"engine,omitempty"`
+	Device string    `json:"device,omitempty"`
+	Before time.Time `json:"before,omitzero"` // Only searches made before this time
+}
+
+// matches reports whether an entry is selected by the purge.
+func (p SearchHistoryPurge) matches(e SearchHistoryEntry) bool {
+	if p.Term != "" && !strings.EqualFold(p.Term, e.Term) {
+		return false
+	}
+	if p.Engine != "" && p.Engine != e.Engine {
+		return false
+	}
+	if p.Device != "" && p.Device != e.Device {
+		return false
+	}
+	if !p.Before.IsZero() {
+		t, err := time.Parse(time.RFC3339, e.Timestamp)
+		if err != nil || !t.Before(p.Before) {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchHistoryStore keeps the searches of every device, oldest first, when the
+// server-side search history is configured.
+type SearchHistoryStore struct {
+	mu         sync.Mutex
+	entries    []SearchHistoryEntry
+	loaded     bool
+	enabled    bool
+	maxEntries int
+}
+
+// Global search history store instance
+var searchHistoryStore = &SearchHistoryStore{}
+
+// GetSearchHistoryStore returns the global search history store instance.
+func GetSearchHistoryStore() *SearchHistoryStore {
+	return searchHistoryStore
+}
+
+// Configure turns the server-side search history on.
+func (hs *SearchHistoryStore) Configure(cfg SearchHistoryConfig) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.enabled = true
+	hs.maxEntries = cfg.MaxEntries
+	if hs.maxEntries == 0 {
+		hs.maxEntries = DefaultSearchHistoryEntries
+	}
+}
+
+// Enabled reports whether searches are kept on the server.
+func (hs *SearchHistoryStore) Enabled() bool {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	return hs.enabled
+}
+
+// load reads the search history file. Caller must hold mu.
+func (hs *SearchHistoryStore) load() {
+	if hs.loaded {
+		return
+	}
+	hs.loaded = true
+	data, err := os.ReadFile(searchHistoryFile)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &hs.entries); err != nil {
+		GetDebugLogger().Logf("search", "failed to parse %s: %v", searchHistoryFile, err)
+		hs.entries = nil
+	}
+}
+
+// save writes the search history file. Caller must hold mu.
+func (hs *SearchHistoryStore) save() error {
+	InvalidateSearchIndex()
+	data, err := json.Marshal(hs.entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(searchHistoryFile, data, 0600)
+}
+
+// Add records a search. An earlier search of the same term with the same engine is
+// replaced, and the oldest searches are dropped past the configured limit.
+func (hs *SearchHistoryStore) Add(term, engine, device string) (SearchHistoryEntry, error) {
+	term = strings.TrimSpace(term)
+	if term == "" || len(term) > 500 {
+		return SearchHistoryEntry{}, errors.New("a search term of up to 500 characters is required")
+	}
+	if len(engine) > 100 {
+		return SearchHistoryEntry{}, errors.New("the engine name is too long")
+	}
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if !hs.enabled {
+		return SearchHistoryEntry{}, errors.New("the server-side search history is not enabled")
+	}
+	hs.load()
+	e := SearchHistoryEntry{
+		SearchHistoryItem: SearchHistoryItem{Term: term, Engine: engine, Timestamp: time.Now().UTC().Format(time.RFC3339)},
+		Device:            device,
+	}
+	hs.entries = slices.DeleteFunc(hs.entries, func(old SearchHistoryEntry) bool {
+		return old.Term == e.Term && old.Engine == e.Engine
+	})
+	hs.entries = append(hs.entries, e)
+	if len(hs.entries) > hs.maxEntries {
+		hs.entries = slices.Clone(hs.entries[len(hs.entries)-hs.maxEntries:])
+	}
+	return e, hs.save()
+}
+
+// Entries returns up to limit searches newest first, optionally only those containing
+// filter or made on one device.
+func (hs *SearchHistoryStore) Entries(filter, device string, limit int) []SearchHistoryEntry {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.load()
+	filter = strings.ToLower(filter)
+	result := []SearchHistoryEntry{}
+	for i := len(hs.entries) - 1; i >= 0; i-- {
+		e := hs.entries[i]
+		if (filter != "" && !strings.Contains(strings.ToLower(e.Term), filter)) || (device != "" && e.Device != device) {
+			continue
+		}
+		result = append(result, e)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result
+}
+
+// History returns the searches oldest first, as browsers keep them.
+func (hs *SearchHistoryStore) History() []SearchHistoryItem {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.load()
+	history := make([]SearchHistoryItem, len(hs.entries))
+	for i, e := range hs.entries {
+		history[i] = e.SearchHistoryItem
+	}
+	return history
+}
+
+// Devices returns the number of searches made on each device.
+func (hs *SearchHistoryStore) Devices() map[string]int {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.load()
+	devices := make(map[string]int)
+	for _, e := range hs.entries {
+		devices[e.Device]++
+	}
+	return devices
+}
+
+// Purge removes the searches selected by p and returns how many were removed.
+func (hs *SearchHistoryStore) Purge(p SearchHistoryPurge) (int, error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.load()
+	before := len(hs.entries)
+	hs.entries = slices.DeleteFunc(hs.entries, p.matches)
+	removed := before - len(hs.entries)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, hs.save()
+}
+
+// Prune removes searches older than before and returns how many were removed.
+func (hs *SearchHistoryStore) Prune(before time.Time) int {
+	removed, err := hs.Purge(SearchHistoryPurge{Before: before})
+	if err != nil {
+		GetDebugLogger().Logf("search", "failed to write %s: %v", searchHistoryFile, err)
+	}
+	return removed
+}
+
+// Len returns the number of searches kept.
+func (hs *SearchHistoryStore) Len() int {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.load()
+	return len(hs.entries)
+}
@@ -0,0 +1,378 @@
+package api
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Kinds of documents in the local search index.
+const (
+	SearchKindBookmark  = "bookmark"
+	SearchKindQuickLink = "quicklink"
+	SearchKindRSS       = "rss"
+	SearchKindHistory   = "history"
+)
+
+// searchIndexTTL is how long an index is reused before the browsers' bookmark files are
+// read again. Changes of synced storage keys and dashboard bookmarks rebuild it sooner.
+const searchIndexTTL = 5 * time.Minute
+
+// searchIndexStorageKeys are the storage keys the index is built from.
+var searchIndexStorageKeys = []string{"quicklinks", "rssFeedCache", "rssModules", "searchHistory"}
+
+// searchKindWeights favor what the user saved over what they happened to read or type.
+var searchKindWeights = map[string]float64{
+	SearchKindQuickLink: 1.3,
+	SearchKindBookmark:  1.2,
+	SearchKindHistory:   1.0,
+	SearchKindRSS:       0.8,
+}
+
+// Weights of the ways a query word can match an indexed word.
+const (
+	searchExactWeight  = 1.0
+	searchPrefixWeight = 0.7
+	searchFuzzyWeight  = 0.4
+)
+
+// Fields of an indexed document; words in the title weigh more than those in the URL.
+const (
+	searchFieldTitle = iota
+	searchFieldOther
+)
+
+// SearchDocument is an entry of the local search index.
+type SearchDocument struct {
+	Kind   string `json:"kind"` // bookmark, quicklink, rss or history
+	Title  string `json:"title"`
+	URL    string `json:"url,omitempty"`
+	Detail string `json:"detail,omitempty"` // Bookmark folder, feed name or search engine
+}
+
+// SearchResult is a matching document with its score.
+type SearchResult struct {
+	SearchDocument
+	Score float64 `json:"score"`
+}
+
+// searchPosting records a document containing a word and the field it was in.
+type searchPosting struct {
+	doc   int
+	field int
+}
+
+// SearchIndex is an inverted index from lowercase words to the documents containing them.
+type SearchIndex struct {
+	docs     []SearchDocument
+	postings map[string][]searchPosting
+	words    []string // Sorted keys of postings, for prefix and fuzzy lookups
+	built    time.Time
+}
+
+// NewSearchIndex builds an index over documents.
+func NewSearchIndex(docs []SearchDocument) *SearchIndex {
+	idx := &SearchIndex{docs: docs, postings: make(map[string][]searchPosting), built: time.Now()}
+	for i, d := range docs {
+		seen := make(map[string]bool)
+		add := func(text string, field int) {
+			for _, w := range searchTokens(text) {
+				if seen[w] {
+					continue
+				}
+				seen[w] = true
+				idx.postings[w] = append(idx.postings[w], searchPosting{doc: i, field: field})
+			}
+		}
+		add(d.Title, searchFieldTitle)
+		add(searchURLText(d.URL), searchFieldOther)
+		add(d.Detail, searchFieldOther)
+	}
+	idx.words = make([]string, 0, len(idx.postings))
+	for w := range idx.postings {
+		idx.words = append(idx.words, w)
+	}
+	sort.Strings(idx.words)
+	return idx
+}
+
+// Len returns the number of indexed documents.
+func (idx *SearchIndex) Len() int {
+	return len(idx.docs)
+}
+
+// Search returns the documents matching every word of the query, best first. A query word
+// matches an indexed word that equals it, starts with it or, for words of four or more
+// letters, is within one typo (two from eight letters). kinds limits the document kinds
+// when not empty; limit caps the results when positive.
+func (idx *SearchIndex) Search(query string, kinds []string, limit int) []SearchResult {
+	terms := searchTokens(query)
+	if len(terms) == 0 {
+		return []SearchResult{}
+	}
+
+	var scores map[int]float64
+	for _, term := range terms {
+		termScores := make(map[int]float64)
+		for word, weight := range idx.lookup(term) {
+			for _, p := range idx.postings[word] {
+				s := weight
+				if p.field != searchFieldTitle {
+					s *= 0.5
+				}
+				termScores[p.doc] = max(termScores[p.doc], s)
+			}
+		}
+		// Every query word has to match
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for doc := range scores {
+			if s, ok := termScores[doc]; ok {
+				scores[doc] += s
+			} else {
+				delete(scores, doc)
+			}
+		}
+	}
+
+	phrase := strings.ToLower(strings.TrimSpace(query))
+	matched := slices.Sorted(maps.Keys(scores))
+	results := make([]SearchResult, 0, len(matched))
+	for _, doc := range matched {
+		score := scores[doc]
+		d := idx.docs[doc]
+		if len(kinds) > 0 && !slices.Contains(kinds, d.Kind) {
+			continue
+		}
+		title := strings.ToLower(d.Title)
+		switch {
+		case title == phrase:
+			score += 1
+		case strings.HasPrefix(title, phrase):
+			score += 0.5
+		}
+		score *= searchKindWeights[d.Kind]
+		results = append(results, SearchResult{SearchDocument: d, Score: float64(int(score*1000)) / 1000})
+	}
+	// Ties keep the order of the documents, e.g. the newest searches first
+	slices.SortStableFunc(results, func(a, b SearchResult) int { return cmp.Compare(b.Score, a.Score) })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// lookup returns the indexed words a query word matches with the weight of the match.
+func (idx *SearchIndex) lookup(term string) map[string]float64 {
+	matches := make(map[string]float64)
+	// Words starting with term are contiguous in the sorted word list
+	for i := sort.SearchStrings(idx.words, term); i < len(idx.words) && strings.HasPrefix(idx.words[i], term); i++ {
+		if idx.words[i] == term {
+			matches[term] = searchExactWeight
+		} else {
+			matches[idx.words[i]] = searchPrefixWeight
+		}
+	}
+
+	maxEdits := 0
+	switch n := len([]rune(term)); {
+	case n >= 8:
+		maxEdits = 2
+	case n >= 4:
+		maxEdits = 1
+	}
+	if maxEdits == 0 {
+		return matches
+	}
+	for _, word := range idx.words {
+		if _, ok := matches[word]; ok {
+			continue
+		}
+		if editDistanceWithin(term, word, maxEdits) {
+			matches[word] = searchFuzzyWeight
+		}
+	}
+	return matches
+}
+
+// searchTokens splits text into lowercase words of letters and digits.
+func searchTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchURLText returns the host and path of a URL for indexing, without the scheme and
+// the "www." that every URL shares.
+func searchURLText(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.") + " " + u.Path
+}
+
+// editDistanceWithin reports whether a and b are at most maxEdits insertions, deletions
+// or substitutions apart.
+func editDistanceWithin(a, b string, maxEdits int) bool {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > maxEdits || -diff > maxEdits {
+		return false
+	}
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		// Every later row is at least as far apart
+		if rowMin > maxEdits {
+			return false
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)] <= maxEdits
+}
+
+// HistoryDocuments turns search history, oldest first, into documents, newest first and
+// once per term.
+func HistoryDocuments(history []SearchHistoryItem) []SearchDocument {
+	docs := make([]SearchDocument, 0, len(history))
+	seen := make(map[string]bool)
+	for i := len(history) - 1; i >= 0; i-- {
+		item := history[i]
+		key := strings.ToLower(item.Term)
+		if item.Term == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		docs = append(docs, SearchDocument{Kind: SearchKindHistory, Title: item.Term, Detail: item.Engine})
+	}
+	return docs
+}
+
+// localSearchIndex caches the index over the dashboard's bookmarks, quick links, RSS items
+// and search history of each profile.
+type localSearchIndex struct {
+	mu      sync.Mutex
+	indexes map[string]*SearchIndex
+	// signatures identify the storage versions each profile's index was built from
+	signatures map[string]string
+}
+
+// Global local search index instance
+var localSearch = &localSearchIndex{}
+
+// GetSearchIndex returns the local search index of a profile, rebuilding it when its
+// sources changed.
+func GetSearchIndex(profile string) *SearchIndex {
+	signature := searchIndexSignature(profile)
+	ls := localSearch
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if idx, ok := ls.indexes[profile]; ok && ls.signatures[profile] == signature && time.Since(idx.built) < searchIndexTTL {
+		return idx
+	}
+	if ls.indexes == nil {
+		ls.indexes = make(map[string]*SearchIndex)
+		ls.signatures = make(map[string]string)
+	}
+	idx := NewSearchIndex(localSearchDocuments(profile))
+	ls.indexes[profile] = idx
+	ls.signatures[profile] = signature
+	GetDebugLogger().Logf("search", "built local search index of %d documents and %d words for profile %s", idx.Len(), len(idx.words), profile)
+	return idx
+}
+
+// InvalidateSearchIndex makes the next search rebuild the local search indexes.
+func InvalidateSearchIndex() {
+	localSearch.mu.Lock()
+	clear(localSearch.indexes)
+	localSearch.mu.Unlock()
+}
+
+// searchIndexSignature identifies the versions of the storage keys a profile's index is
+// built from.
+func searchIndexSignature(profile string) string {
+	var b strings.Builder
+	for _, key := range searchIndexStorageKeys {
+		if item, ok := GetStorage().GetForProfile(profile, key); ok {
+			fmt.Fprintf(&b, "%s=%d/%d;", key, item.Version, item.LastModified.UnixNano())
+		}
+	}
+	return b.String()
+}
+
+// localSearchDocuments collects the documents of the local search index.
+func localSearchDocuments(profile string) []SearchDocument {
+	var docs []SearchDocument
+
+	var links []struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+	}
+	GetStorage().GetAsForProfile(profile, "quicklinks", &links)
+	for _, l := range links {
+		if l.URL != "" {
+			docs = append(docs, SearchDocument{Kind: SearchKindQuickLink, Title: l.Title, URL: l.URL})
+		}
+	}
+
+	bookmarks, _ := GetBookmarks("")
+	for _, b := range MergeBookmarks(append(GetBookmarkStore().Tagged(), bookmarks...)) {
+		docs = append(docs, SearchDocument{Kind: SearchKindBookmark, Title: b.Title, URL: b.URL, Detail: b.Folder})
+	}
+
+	var feeds []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	GetStorage().GetAsForProfile(profile, "rssModules", &feeds)
+	feedNames := make(map[string]string, len(feeds))
+	for _, f := range feeds {
+		feedNames[f.ID] = f.Name
+	}
+	// The RSS module keeps the last items of each feed by module ID
+	var cache map[string]struct {
+		Data []RSSFeedItem `json:"data"`
+	}
+	GetStorage().GetAsForProfile(profile, "rssFeedCache", &cache)
+	seen := make(map[string]bool)
+	for id, cached := range cache {
+		name := feedNames[id]
+		for _, item := range cached.Data {
+			if item.Title == "" || seen[item.Link] {
+				continue
+			}
+			seen[item.Link] = true
+			docs = append(docs, SearchDocument{Kind: SearchKindRSS, Title: item.Title, URL: item.Link, Detail: name})
+		}
+	}
+
+	var history []SearchHistoryItem
+	GetStorage().GetAsForProfile(profile, "searchHistory", &history)
+	// The server-side history, when enabled, has the searches of every device
+	if GetSearchHistoryStore().Enabled() {
+		history = GetSearchHistoryStore().History()
+	}
+	return append(docs, HistoryDocuments(history)...)
+}
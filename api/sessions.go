@@ -0,0 +1,381 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sessionsFile holds the browser sessions created by signing in with a token or through
+// single sign-on. Like tokens, only the hashes of the session secrets are stored.
+const sessionsFile = "sessions.json"
+
+// sessionPrefix marks session secrets so they are not looked up as API tokens.
+const sessionPrefix = "hps_"
+
+// tokenSessionDuration is how long a browser stays signed in with a token.
+const tokenSessionDuration = 365 * 24 * time.Hour
+
+// SessionDevice describes the browser of a session as last seen.
+type SessionDevice struct {
+	UserAgent string `json:"userAgent,omitempty"`
+	IP        string `json:"ip,omitempty"`
+}
+
+// DeviceOf returns the device of a request.
+func DeviceOf(r *http.Request) SessionDevice {
+	return SessionDevice{UserAgent: r.UserAgent(), IP: GetClientIP(r)}
+}
+
+// LoginSession is a signed-in browser. Sessions of token sign-ins take their role from
+// the token and end when it is revoked; single sign-on sessions carry the role the
+// provider's claims mapped to.
+type LoginSession struct {
+	ID       string `json:"id"`
+	TokenID  string `json:"tokenId,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	Subject  string `json:"subject,omitempty"` // The provider's user ID (sub claim)
+	Name     string `json:"name"`
+	Email    string `json:"email,omitempty"`
+	Role     Role   `json:"role"`
+	Profile  string `json:"profile,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+	SessionDevice
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	LastSeen  *time.Time `json:"lastSeen,omitempty"`
+}
+
+// owner identifies the user a session belongs to: the token or the provider account.
+func (s LoginSession) owner() string {
+	if s.TokenID != "" {
+		return "token:" + s.TokenID
+	}
+	return s.Provider + ":" + s.Subject
+}
+
+// SessionInfo is a session as listed by /api/sessions.
+type SessionInfo struct {
+	LoginSession
+	Device  string `json:"device"`  // e.g. "Firefox on Android"
+	Current bool   `json:"current"` // The session of the request
+}
+
+// loadSessions reads the sessions file, dropping expired sessions. Caller must hold mu.
+func (tm *TokenManager) loadSessions() {
+	data, err := os.ReadFile(sessionsFile)
+	if err != nil {
+		return
+	}
+	var sessions []*LoginSession
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		GetDebugLogger().Logf("auth", "failed to parse %s: %v", sessionsFile, err)
+		return
+	}
+	now := time.Now()
+	for _, s := range sessions {
+		if now.Before(s.ExpiresAt) {
+			tm.sessions[s.Hash] = s
+		}
+	}
+}
+
+// saveSessions writes the sessions file. Caller must hold mu.
+func (tm *TokenManager) saveSessions() error {
+	sessions := make([]*LoginSession, 0, len(tm.sessions))
+	for _, s := range tm.sessions {
+		sessions = append(sessions, s)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(sessionsFile, data, 0600)
+}
+
+// tokenByID returns the token with an ID. Caller must hold mu.
+func (tm *TokenManager) tokenByID(id string) (*APIToken, bool) {
+	for _, t := range tm.tokens {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// CreateSession stores a session for a signed-in user and returns it with its secret.
+func (tm *TokenManager) CreateSession(s LoginSession, ttl time.Duration) (LoginSession, string, error) {
+	secret := make([]byte, 24)
+	id := make([]byte, 6)
+	if _, err := rand.Read(secret); err != nil {
+		return LoginSession{}, "", err
+	}
+	if _, err := rand.Read(id); err != nil {
+		return LoginSession{}, "", err
+	}
+	token := sessionPrefix + hex.EncodeToString(secret)
+	now := time.Now()
+	s.ID = hex.EncodeToString(id)
+	s.Hash = hashToken(token)
+	s.CreatedAt = now
+	s.ExpiresAt = now.Add(ttl)
+	s.LastSeen = &now
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.load()
+	for hash, old := range tm.sessions {
+		if now.After(old.ExpiresAt) {
+			delete(tm.sessions, hash)
+		}
+	}
+	tm.sessions[s.Hash] = &s
+	if err := tm.saveSessions(); err != nil {
+		return LoginSession{}, "", err
+	}
+	result := s
+	result.Hash = ""
+	return result, token, nil
+}
+
+// CreateTokenSession signs a browser in with an API token, so that the device can be
+// signed out on its own without revoking the token.
+func (tm *TokenManager) CreateTokenSession(t APIToken, device SessionDevice) (LoginSession, string, error) {
+	return tm.CreateSession(LoginSession{
+		TokenID:       t.ID,
+		Name:          t.Name,
+		Role:          t.Role,
+		Profile:       t.Profile,
+		SessionDevice: device,
+	}, tokenSessionDuration)
+}
+
+// lookupSession returns the unexpired session matching a secret and records the use by
+// the device. Sessions of revoked tokens are removed.
+func (tm *TokenManager) lookupSession(token string, device SessionDevice) (LoginSession, bool) {
+	hash := hashToken(token)
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.load()
+	s, ok := tm.sessions[hash]
+	if !ok {
+		return LoginSession{}, false
+	}
+	now := time.Now()
+	if now.After(s.ExpiresAt) {
+		delete(tm.sessions, hash)
+		tm.saveSessions()
+		return LoginSession{}, false
+	}
+	if s.TokenID != "" {
+		t, ok := tm.tokenByID(s.TokenID)
+		if !ok {
+			delete(tm.sessions, hash)
+			tm.saveSessions()
+			return LoginSession{}, false
+		}
+		s.Name, s.Role, s.Profile = t.Name, t.Role, t.Profile
+	}
+	// Like token use, persist the last activity at most hourly, or when the device changes
+	persist := s.LastSeen == nil || now.Sub(*s.LastSeen) > time.Hour || s.SessionDevice != device
+	s.LastSeen = &now
+	s.SessionDevice = device
+	if persist {
+		if err := tm.saveSessions(); err != nil {
+			GetDebugLogger().Logf("auth", "failed to write %s: %v", sessionsFile, err)
+		}
+	}
+	return *s, true
+}
+
+// EndSession removes the session of a secret, e.g. on sign out.
+func (tm *TokenManager) EndSession(token string) error {
+	if !strings.HasPrefix(token, sessionPrefix) {
+		return nil
+	}
+	hash := hashToken(token)
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.load()
+	if _, ok := tm.sessions[hash]; !ok {
+		return nil
+	}
+	delete(tm.sessions, hash)
+	return tm.saveSessions()
+}
+
+// identityOwner returns the owner key of the caller's sessions: that of its session, or
+// of its token when it sends the token itself. Anonymous callers own no sessions.
+// Caller must hold mu.
+func (tm *TokenManager) identityOwner(id Identity) string {
+	if id.SessionID != "" {
+		for _, s := range tm.sessions {
+			if s.ID == id.SessionID {
+				return s.owner()
+			}
+		}
+	}
+	if id.TokenID != "" {
+		return "token:" + id.TokenID
+	}
+	return ""
+}
+
+// Sessions lists the unexpired sessions of the caller, or of every user if all is set,
+// most recently used first.
+func (tm *TokenManager) Sessions(id Identity, all bool) []SessionInfo {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.load()
+	owner := tm.identityOwner(id)
+	now := time.Now()
+	result := []SessionInfo{}
+	for _, s := range tm.sessions {
+		if now.After(s.ExpiresAt) || (!all && (owner == "" || s.owner() != owner)) {
+			continue
+		}
+		info := SessionInfo{LoginSession: *s, Device: DescribeUserAgent(s.UserAgent), Current: s.ID == id.SessionID}
+		info.Hash = ""
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].LastSeen, result[j].LastSeen
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return a.After(*b)
+	})
+	return result
+}
+
+// RevokeSessions removes sessions by ID, or all of the caller's sessions except the
+// current one if others is set. Without all, only the caller's own sessions can be
+// revoked. It returns the number of sessions removed.
+func (tm *TokenManager) RevokeSessions(id Identity, all bool, sessionID string, others bool) (int, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.load()
+	owner := tm.identityOwner(id)
+	removed := 0
+	for hash, s := range tm.sessions {
+		mine := owner != "" && s.owner() == owner
+		switch {
+		case others && mine && s.ID != id.SessionID:
+		case !others && s.ID == sessionID && (mine || all):
+		default:
+			continue
+		}
+		delete(tm.sessions, hash)
+		removed++
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, tm.saveSessions()
+}
+
+// PruneSessions removes expired sessions and returns how many were removed.
+func (tm *TokenManager) PruneSessions() int {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.load()
+	now := time.Now()
+	removed := 0
+	for hash, s := range tm.sessions {
+		if now.After(s.ExpiresAt) {
+			delete(tm.sessions, hash)
+			removed++
+		}
+	}
+	if removed > 0 {
+		if err := tm.saveSessions(); err != nil {
+			GetDebugLogger().Logf("auth", "failed to write %s: %v", sessionsFile, err)
+		}
+	}
+	return removed
+}
+
+// SessionCount returns the number of stored sessions.
+func (tm *TokenManager) SessionCount() int {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.load()
+	return len(tm.sessions)
+}
+
+// removeTokenSessions drops the sessions signed in with a token. Caller must hold mu.
+func (tm *TokenManager) removeTokenSessions(tokenID string) {
+	changed := false
+	for hash, s := range tm.sessions {
+		if s.TokenID == tokenID {
+			delete(tm.sessions, hash)
+			changed = true
+		}
+	}
+	if changed {
+		if err := tm.saveSessions(); err != nil {
+			GetDebugLogger().Logf("auth", "failed to write %s: %v", sessionsFile, err)
+		}
+	}
+}
+
+// DescribeUserAgent returns a short browser and platform name for a User-Agent header,
+// e.g. "Firefox on Android".
+func DescribeUserAgent(ua string) string {
+	if ua == "" {
+		return "Unknown device"
+	}
+	browser := ""
+	for _, b := range []struct{ marker, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"SamsungBrowser/", "Samsung Internet"},
+		{"Chrome/", "Chrome"},
+		{"CriOS/", "Chrome"},
+		{"FxiOS/", "Firefox"},
+		{"Safari/", "Safari"},
+	} {
+		if strings.Contains(ua, b.marker) {
+			browser = b.name
+			break
+		}
+	}
+	platform := ""
+	for _, p := range []struct{ marker, name string }{
+		{"iPad", "iPad"},
+		{"iPhone", "iPhone"},
+		{"Android", "Android"},
+		{"CrOS", "ChromeOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, p.marker) {
+			platform = p.name
+			break
+		}
+	}
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+	// Command line clients and apps, e.g. "curl/8.5.0"
+	name, _, _ := strings.Cut(ua, "/")
+	name, _, _ = strings.Cut(name, " ")
+	return name
+}
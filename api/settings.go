@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// settingsKey is the storage key of the dashboard's general settings.
+const settingsKey = "settings"
+
+// maxTitleLength limits the page title.
+const maxTitleLength = 100
+
+// Values of the general settings; the empty value is the default.
+var (
+	settingsDateFormats = []string{"", "iso", "dmy", "mdy"}
+	settingsWeekdays    = map[string]time.Weekday{"": time.Monday, "monday": time.Monday, "sunday": time.Sunday, "saturday": time.Saturday}
+	settingsTempUnits   = []string{"", "celsius", "fahrenheit"}
+	settingsWindUnits   = []string{"", "km/h", "m/s", "mph", "kn"}
+	settingsByteUnits   = []string{"", "binary", "decimal"}
+)
+
+// Settings are the dashboard's general settings, applied by the server to what it formats:
+// the page title, event dates, the first day of the week and units. A profile's units and
+// the request headers (see ClientLocale) win over them.
+type Settings struct {
+	Title           string `json:"title,omitempty"`           // Page title, default the config's
+	DateFormat      string `json:"dateFormat,omitempty"`      // "iso" (2024-03-09), "dmy" (Sat, 9 Mar) or "mdy" (Sat, Mar 9); default by locale
+	FirstDayOfWeek  string `json:"firstDayOfWeek,omitempty"`  // "monday" (default), "sunday" or "saturday"
+	TemperatureUnit string `json:"temperatureUnit,omitempty"` // "celsius" (default) or "fahrenheit"
+	WindUnit        string `json:"windUnit,omitempty"`        // "km/h", "m/s", "mph" or "kn"; default the weather provider's
+	ByteUnits       string `json:"byteUnits,omitempty"`       // "binary" (default, 1 KB = 1024 B) or "decimal" (1 kB = 1000 B)
+}
+
+// Validate checks the values of the settings.
+func (s Settings) Validate() error {
+	if utf8.RuneCountInString(s.Title) > maxTitleLength {
+		return fmt.Errorf("title must be at most %d characters", maxTitleLength)
+	}
+	if !slices.Contains(settingsDateFormats, s.DateFormat) {
+		return fmt.Errorf("dateFormat must be iso, dmy or mdy")
+	}
+	if _, ok := settingsWeekdays[s.FirstDayOfWeek]; !ok {
+		return fmt.Errorf("firstDayOfWeek must be monday, sunday or saturday")
+	}
+	if !slices.Contains(settingsTempUnits, s.TemperatureUnit) {
+		return fmt.Errorf("temperatureUnit must be celsius or fahrenheit")
+	}
+	if !slices.Contains(settingsWindUnits, s.WindUnit) {
+		return fmt.Errorf("windUnit must be km/h, m/s, mph or kn")
+	}
+	if !slices.Contains(settingsByteUnits, s.ByteUnits) {
+		return fmt.Errorf("byteUnits must be binary or decimal")
+	}
+	return nil
+}
+
+// FirstWeekday returns the first day of the week.
+func (s Settings) FirstWeekday() time.Weekday {
+	return settingsWeekdays[s.FirstDayOfWeek]
+}
+
+// settingsCache keeps the decoded settings of a storage version, as byte counts are
+// formatted every second.
+var settingsCache struct {
+	mu       sync.Mutex
+	version  int64
+	modified time.Time
+	settings Settings
+}
+
+// CurrentSettings returns the stored general settings. Invalid stored values are dropped.
+func CurrentSettings() Settings {
+	item, exists := GetStorage().Get(settingsKey)
+	if !exists {
+		return Settings{}
+	}
+	settingsCache.mu.Lock()
+	defer settingsCache.mu.Unlock()
+	if item.Version == settingsCache.version && item.LastModified.Equal(settingsCache.modified) {
+		return settingsCache.settings
+	}
+
+	var s Settings
+	if data, err := json.Marshal(item.Value); err == nil {
+		_ = json.Unmarshal(data, &s)
+	}
+	if s.Validate() != nil {
+		GetDebugLogger().Logf("settings", "ignoring invalid stored settings")
+		s = Settings{}
+	}
+	settingsCache.version, settingsCache.modified, settingsCache.settings = item.Version, item.LastModified, s
+	return s
+}
+
+// SaveSettings validates and stores the general settings.
+func SaveSettings(s Settings) error {
+	s.Title = strings.TrimSpace(s.Title)
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	GetStorage().SetNext(settingsKey, s)
+	return nil
+}
+
+// SettingsResponse is the reply of /api/settings: the stored settings and the values in
+// effect, with the defaults filled in.
+type SettingsResponse struct {
+	Settings  Settings `json:"settings"`
+	Effective Settings `json:"effective"`
+	FirstDay  int      `json:"firstDay"` // First day of the week, 0 = Sunday
+}
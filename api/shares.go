@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// Timeouts of the share checks. A hung NFS or SMB server blocks statfs, so usage is read
+// in the background and reported as not responding when it takes longer.
+const (
+	shareStatTimeout  = 5 * time.Second
+	shareProbeTimeout = 3 * time.Second
+)
+
+// shareFSTypes maps the file system types of network shares to their protocol.
+var shareFSTypes = map[string]string{
+	"nfs":   "nfs",
+	"nfs4":  "nfs",
+	"cifs":  "smb",
+	"smb3":  "smb",
+	"smbfs": "smb",
+}
+
+// shareDefaultPorts are the server ports probed for reachability.
+var shareDefaultPorts = map[string]int{"nfs": 2049, "smb": 445}
+
+// SharesConfig configures the network shares behind /api/shares.
+type SharesConfig struct {
+	Mounts   []ShareMount `json:"mounts,omitempty"`
+	Discover bool         `json:"discover,omitempty"` // Also list every mounted NFS and SMB share
+}
+
+// ShareMount is a mounted NFS or SMB share.
+type ShareMount struct {
+	Name string `json:"name,omitempty"` // Display name, default: the mount point
+	Path string `json:"path"`           // Mount point, e.g. /mnt/media
+	Host string `json:"host,omitempty"` // Server probed for reachability, default: from the mount source
+	Port int    `json:"port,omitempty"` // Default 2049 for NFS and 445 for SMB
+}
+
+// Validate checks the configured shares.
+func (c SharesConfig) Validate() error {
+	if len(c.Mounts) == 0 && !c.Discover {
+		return fmt.Errorf("shares: mounts or discover is required")
+	}
+	seen := make(map[string]bool)
+	for i, m := range c.Mounts {
+		if !strings.HasPrefix(m.Path, "/") {
+			return fmt.Errorf("shares: mounts[%d]: path must be an absolute mount point", i)
+		}
+		if seen[m.Path] {
+			return fmt.Errorf("shares: mounts[%d]: duplicate path %s", i, m.Path)
+		}
+		seen[m.Path] = true
+		if m.Port < 0 || m.Port > 65535 {
+			return fmt.Errorf("shares: mounts[%d]: port must be between 1 and 65535", i)
+		}
+	}
+	return nil
+}
+
+// ShareStatus is the capacity and reachability of one share.
+type ShareStatus struct {
+	Name       string `json:"name"`
+	MountPoint string `json:"mountPoint"`
+	Source     string `json:"source,omitempty"`   // e.g. //nas/media or nas:/export/media
+	Protocol   string `json:"protocol,omitempty"` // nfs or smb
+	Host       string `json:"host,omitempty"`
+	Mounted    bool   `json:"mounted"`
+	Reachable  bool   `json:"reachable"`         // The server answered on its port
+	Latency    int64  `json:"latency,omitempty"` // Milliseconds to connect to the server
+	Responding bool   `json:"responding"`        // The mount answered statfs in time
+	DiskInfo
+}
+
+// ShareMonitor reads the capacity of network shares without blocking on hung servers.
+type ShareMonitor struct {
+	mu      sync.Mutex
+	config  *SharesConfig
+	pending map[string]bool // Mount points with a statfs still running
+}
+
+// Global share monitor instance
+var shareMonitor = &ShareMonitor{pending: make(map[string]bool)}
+
+// GetShareMonitor returns the global share monitor instance.
+func GetShareMonitor() *ShareMonitor {
+	return shareMonitor
+}
+
+// Configure sets the shares to report.
+func (sm *ShareMonitor) Configure(cfg SharesConfig) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.config = &cfg
+}
+
+// Enabled reports whether shares are configured.
+func (sm *ShareMonitor) Enabled() bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.config != nil
+}
+
+// Status returns the configured and, with discover, all mounted network shares.
+func (sm *ShareMonitor) Status(ctx context.Context) ([]ShareStatus, error) {
+	sm.mu.Lock()
+	cfg := sm.config
+	sm.mu.Unlock()
+	if cfg == nil {
+		return nil, fmt.Errorf("no shares configured")
+	}
+
+	partitions, err := disk.PartitionsWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	mounted := make(map[string]disk.PartitionStat)
+	for _, p := range partitions {
+		mounted[p.Mountpoint] = p
+	}
+
+	mounts := append([]ShareMount(nil), cfg.Mounts...)
+	if cfg.Discover {
+		listed := make(map[string]bool, len(mounts))
+		for _, m := range mounts {
+			listed[m.Path] = true
+		}
+		for _, p := range partitions {
+			if _, ok := shareFSTypes[p.Fstype]; ok && !listed[p.Mountpoint] {
+				mounts = append(mounts, ShareMount{Path: p.Mountpoint})
+				listed[p.Mountpoint] = true
+			}
+		}
+	}
+
+	result := make([]ShareStatus, len(mounts))
+	var wg sync.WaitGroup
+	for i, m := range mounts {
+		p, ok := mounted[m.Path]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result[i] = sm.check(ctx, m, p, ok)
+		}()
+	}
+	wg.Wait()
+	return result, nil
+}
+
+// check reads the usage of one share and probes its server.
+func (sm *ShareMonitor) check(ctx context.Context, m ShareMount, p disk.PartitionStat, mounted bool) ShareStatus {
+	s := ShareStatus{Name: m.Name, MountPoint: m.Path, Mounted: mounted, Host: m.Host}
+	if s.Name == "" {
+		s.Name = m.Path
+	}
+	if mounted {
+		s.Source = p.Device
+		s.Protocol = shareFSTypes[p.Fstype]
+		if s.Protocol == "" {
+			s.Protocol = p.Fstype
+		}
+		if s.Host == "" {
+			s.Host = shareHost(p.Device)
+		}
+	}
+
+	var wg sync.WaitGroup
+	if s.Host != "" {
+		port := m.Port
+		if port == 0 {
+			port = shareDefaultPorts[s.Protocol]
+		}
+		if port > 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				probeCtx, cancel := context.WithTimeout(ctx, shareProbeTimeout)
+				defer cancel()
+				if latency, err := CheckPort(probeCtx, s.Host, strconv.Itoa(port)); err == nil {
+					s.Reachable, s.Latency = true, latency
+				}
+			}()
+		}
+	}
+
+	if !mounted {
+		s.Error = "not mounted"
+	} else if usage, err := sm.usage(m.Path); err != nil {
+		s.Error = err.Error()
+	} else {
+		s.Responding = true
+		s.Total, s.Used, s.Free, s.Percent = usage.Total, usage.Used, usage.Free, usage.UsedPercent
+		s.TotalFormatted = FormatBytes(usage.Total)
+		s.UsedFormatted = FormatBytes(usage.Used)
+		s.FreeFormatted = FormatBytes(usage.Free)
+	}
+	wg.Wait()
+	return s
+}
+
+// usage runs statfs on a mount point, giving up after shareStatTimeout. A statfs that hangs
+// keeps running, and no new one is started for the mount point until it returns.
+func (sm *ShareMonitor) usage(path string) (*disk.UsageStat, error) {
+	sm.mu.Lock()
+	if sm.pending[path] {
+		sm.mu.Unlock()
+		return nil, fmt.Errorf("server not responding")
+	}
+	sm.pending[path] = true
+	sm.mu.Unlock()
+
+	type result struct {
+		usage *disk.UsageStat
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		usage, err := disk.Usage(path)
+		sm.mu.Lock()
+		delete(sm.pending, path)
+		sm.mu.Unlock()
+		done <- result{usage, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.usage, r.err
+	case <-time.After(shareStatTimeout):
+		GetDebugLogger().Logf("shares", "statfs of %s did not return within %v", path, shareStatTimeout)
+		return nil, fmt.Errorf("server not responding")
+	}
+}
+
+// shareHost returns the server of a mount source: //host/share for SMB, host:/path for NFS.
+func shareHost(source string) string {
+	if rest, ok := strings.CutPrefix(source, "//"); ok {
+		host, _, _ := strings.Cut(rest, "/")
+		return host
+	}
+	if host, _, ok := strings.Cut(source, ":/"); ok {
+		return strings.Trim(host, "[]")
+	}
+	return ""
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMTP connection security modes.
+const (
+	SMTPTLSStartTLS = "starttls"
+	SMTPTLSImplicit = "tls"
+	SMTPTLSNone     = "none"
+)
+
+// ErrSMTPNotConfigured is returned when mail is sent without an SMTP host configured.
+var ErrSMTPNotConfigured = errors.New("SMTP is not configured")
+
+// SMTPConfig configures the outgoing mail transport.
+type SMTPConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port,omitempty"` // Default: 587 (starttls), 465 (tls), 25 (none)
+	TLS      string `json:"tls,omitempty"`  // starttls (default), tls or none
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// PasswordFile and PasswordEnv read the password from a secret file or environment variable
+	PasswordFile string   `json:"passwordFile,omitempty"`
+	PasswordEnv  string   `json:"passwordEnv,omitempty"`
+	From         string   `json:"from"`
+	To           []string `json:"to"`
+}
+
+// Validate checks the SMTP configuration.
+func (c SMTPConfig) Validate() error {
+	if c.Host == "" {
+		return nil
+	}
+	switch c.TLS {
+	case "", SMTPTLSStartTLS, SMTPTLSImplicit, SMTPTLSNone:
+	default:
+		return fmt.Errorf("smtp: unknown tls mode %q (use starttls, tls or none)", c.TLS)
+	}
+	if c.Port < 0 || c.Port > 65535 {
+		return fmt.Errorf("smtp: port must be a valid port number (1-65535)")
+	}
+	if c.From == "" {
+		return fmt.Errorf("smtp: from address is required")
+	}
+	if len(c.To) == 0 {
+		return fmt.Errorf("smtp: at least one recipient is required")
+	}
+	if _, err := c.password(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// password resolves the password from the config, a secret file or the environment.
+func (c SMTPConfig) password() (string, error) {
+	password, err := ResolveSecret(c.Password, c.PasswordFile, c.PasswordEnv)
+	if err != nil {
+		return "", fmt.Errorf("smtp: %w", err)
+	}
+	return password, nil
+}
+
+// address returns host:port with the default port for the TLS mode.
+func (c SMTPConfig) address() string {
+	port := c.Port
+	if port == 0 {
+		switch c.TLS {
+		case SMTPTLSImplicit:
+			port = 465
+		case SMTPTLSNone:
+			port = 25
+		default:
+			port = 587
+		}
+	}
+	return net.JoinHostPort(c.Host, strconv.Itoa(port))
+}
+
+// SMTPStatus reports the configuration and last delivery result of the mailer.
+type SMTPStatus struct {
+	Configured  bool       `json:"configured"`
+	Host        string     `json:"host,omitempty"`
+	From        string     `json:"from,omitempty"`
+	To          []string   `json:"to,omitempty"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	LastFailure *time.Time `json:"lastFailure,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+}
+
+// Mailer sends mail over SMTP and tracks delivery failures.
+type Mailer struct {
+	mu          sync.Mutex
+	config      SMTPConfig
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastError   string
+}
+
+// Global mailer instance
+var mailer = &Mailer{}
+
+// GetMailer returns the global mailer instance.
+func GetMailer() *Mailer {
+	return mailer
+}
+
+// Configure sets the SMTP transport and registers the email alert channel.
+func (m *Mailer) Configure(cfg SMTPConfig) {
+	m.mu.Lock()
+	m.config = cfg
+	m.mu.Unlock()
+
+	if cfg.Host != "" {
+		GetAlertManager().RegisterChannel("email", m.SendAlert)
+	}
+}
+
+// Status returns the configuration and last delivery result.
+func (m *Mailer) Status() SMTPStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := SMTPStatus{
+		Configured: m.config.Host != "",
+		Host:       m.config.Host,
+		From:       m.config.From,
+		To:         m.config.To,
+		LastError:  m.lastError,
+	}
+	if !m.lastSuccess.IsZero() {
+		t := m.lastSuccess
+		status.LastSuccess = &t
+	}
+	if !m.lastFailure.IsZero() {
+		t := m.lastFailure
+		status.LastFailure = &t
+	}
+	return status
+}
+
+// Healthy reports whether the last delivery succeeded (or nothing has been sent yet).
+func (m *Mailer) Healthy() (bool, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lastFailure.IsZero() || m.lastSuccess.After(m.lastFailure) {
+		return true, ""
+	}
+	return false, m.lastError
+}
+
+// SendAlert is the alert channel for email.
+func (m *Mailer) SendAlert(ctx context.Context, a Alert) error {
+	subject := a.Title
+	if a.Severity == "critical" || a.Severity == "warning" {
+		subject = "[" + strings.ToUpper(a.Severity) + "] " + subject
+	}
+	return m.Send(ctx, nil, subject, a.Body)
+}
+
+// Send delivers a plain text message to the given recipients (the configured ones if empty).
+func (m *Mailer) Send(ctx context.Context, to []string, subject, body string) error {
+	m.mu.Lock()
+	cfg := m.config
+	m.mu.Unlock()
+
+	if cfg.Host == "" {
+		return ErrSMTPNotConfigured
+	}
+	if len(to) == 0 {
+		to = cfg.To
+	}
+
+	err := sendMail(ctx, cfg, to, subject, body)
+
+	m.mu.Lock()
+	if err != nil {
+		m.lastFailure = time.Now()
+		m.lastError = err.Error()
+	} else {
+		m.lastSuccess = time.Now()
+	}
+	m.mu.Unlock()
+
+	if err != nil {
+		GetDebugLogger().Logf("smtp", "delivery to %s failed: %v", strings.Join(to, ", "), err)
+	}
+	return err
+}
+
+// sendMail connects to the SMTP server and sends one message.
+func sendMail(ctx context.Context, cfg SMTPConfig, to []string, subject, body string) error {
+	password, err := cfg.password()
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	var conn net.Conn
+	if cfg.TLS == SMTPTLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: cfg.Host}}).DialContext(ctx, "tcp", cfg.address())
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", cfg.address())
+	}
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if cfg.TLS == "" || cfg.TLS == SMTPTLSStartTLS {
+		if err := client.StartTLS(&tls.Config{ServerName: cfg.Host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.Username, password, cfg.Host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(cfg.From); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("recipient %s: %w", rcpt, err)
+		}
+	}
+	wc, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := wc.Write(buildMailMessage(cfg.From, to, subject, body)); err != nil {
+		wc.Close()
+		return err
+	}
+	if err := wc.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMailMessage formats a plain text RFC 5322 message.
+func buildMailMessage(from string, to []string, subject, body string) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	b.WriteString("Subject: " + strings.NewReplacer("\r", " ", "\n", " ").Replace(subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
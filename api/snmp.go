@@ -0,0 +1,413 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const snmpProfilesFile = "snmp-profiles.json"
+
+// Limits of SNMP operations returning several OIDs.
+const (
+	snmpMaxOIDs           = 20
+	snmpDefaultWalkLimit  = 500
+	snmpMaxWalkLimit      = 5000
+	snmpDefaultRepetition = 10
+)
+
+var snmpProfileNameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+var snmpAuthProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+	"MD5":    gosnmp.MD5,
+	"SHA":    gosnmp.SHA,
+	"SHA224": gosnmp.SHA224,
+	"SHA256": gosnmp.SHA256,
+	"SHA384": gosnmp.SHA384,
+	"SHA512": gosnmp.SHA512,
+}
+
+var snmpPrivProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+	"DES":    gosnmp.DES,
+	"AES":    gosnmp.AES,
+	"AES192": gosnmp.AES192,
+	"AES256": gosnmp.AES256,
+}
+
+// SNMPProfile is a saved set of SNMP credentials, so that community strings and v3
+// passphrases stay on the server instead of in query URLs.
+type SNMPProfile struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`             // "2c" (default) or "3"
+	Community string `json:"community,omitempty"` // v2c
+	// v3 user-based security; the level follows from the protocols set (authPriv with both)
+	Username     string `json:"username,omitempty"`
+	AuthProtocol string `json:"authProtocol,omitempty"` // MD5, SHA, SHA224, SHA256, SHA384 or SHA512
+	AuthPassword string `json:"authPassword,omitempty"`
+	PrivProtocol string `json:"privProtocol,omitempty"` // DES, AES, AES192 or AES256
+	PrivPassword string `json:"privPassword,omitempty"`
+	ContextName  string `json:"contextName,omitempty"`
+}
+
+// Validate checks the profile and normalizes the version and protocol names.
+func (p *SNMPProfile) Validate() error {
+	if !snmpProfileNameRe.MatchString(p.Name) {
+		return fmt.Errorf("profile name must be 1-64 letters, digits, '.', '_' or '-'")
+	}
+	p.AuthProtocol = strings.ToUpper(strings.ReplaceAll(p.AuthProtocol, "-", ""))
+	p.PrivProtocol = strings.ToUpper(strings.ReplaceAll(p.PrivProtocol, "-", ""))
+	switch p.Version {
+	case "", "2c", "v2c":
+		p.Version = "2c"
+		if p.Community == "" {
+			return fmt.Errorf("community is required for SNMPv2c")
+		}
+	case "3", "v3":
+		p.Version = "3"
+		if p.Username == "" {
+			return fmt.Errorf("username is required for SNMPv3")
+		}
+		if p.AuthProtocol != "" {
+			if _, ok := snmpAuthProtocols[p.AuthProtocol]; !ok {
+				return fmt.Errorf("unknown authProtocol %q", p.AuthProtocol)
+			}
+			if len(p.AuthPassword) < 8 {
+				return fmt.Errorf("authPassword must be at least 8 characters")
+			}
+		}
+		if p.PrivProtocol != "" {
+			if _, ok := snmpPrivProtocols[p.PrivProtocol]; !ok {
+				return fmt.Errorf("unknown privProtocol %q", p.PrivProtocol)
+			}
+			if p.AuthProtocol == "" {
+				return fmt.Errorf("privacy requires an authProtocol")
+			}
+			if len(p.PrivPassword) < 8 {
+				return fmt.Errorf("privPassword must be at least 8 characters")
+			}
+		}
+	default:
+		return fmt.Errorf("version must be 2c or 3")
+	}
+	return nil
+}
+
+// SecurityLevel returns the SNMPv3 security level of the profile.
+func (p SNMPProfile) SecurityLevel() string {
+	switch {
+	case p.Version != "3":
+		return ""
+	case p.PrivProtocol != "":
+		return "authPriv"
+	case p.AuthProtocol != "":
+		return "authNoPriv"
+	}
+	return "noAuthNoPriv"
+}
+
+// Redacted returns the profile without its secrets, for listing to clients.
+func (p SNMPProfile) Redacted() map[string]any {
+	return map[string]any{
+		"name":          p.Name,
+		"version":       p.Version,
+		"username":      p.Username,
+		"authProtocol":  p.AuthProtocol,
+		"privProtocol":  p.PrivProtocol,
+		"contextName":   p.ContextName,
+		"securityLevel": p.SecurityLevel(),
+		"hasCommunity":  p.Community != "",
+		"hasAuthPass":   p.AuthPassword != "",
+		"hasPrivPass":   p.PrivPassword != "",
+	}
+}
+
+// SNMPProfileStore keeps the credential profiles in snmp-profiles.json.
+type SNMPProfileStore struct {
+	mu       sync.Mutex
+	profiles map[string]SNMPProfile
+	loaded   bool
+}
+
+var snmpProfiles = &SNMPProfileStore{}
+
+// GetSNMPProfiles returns the global SNMP credential store.
+func GetSNMPProfiles() *SNMPProfileStore {
+	return snmpProfiles
+}
+
+// load reads the profiles file. Caller must hold mu.
+func (s *SNMPProfileStore) load() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	s.profiles = make(map[string]SNMPProfile)
+	data, err := os.ReadFile(snmpProfilesFile)
+	if err != nil {
+		return
+	}
+	var list []SNMPProfile
+	if err := json.Unmarshal(data, &list); err != nil {
+		Logger("snmp").Warn("failed to parse profiles", "file", snmpProfilesFile, "error", err)
+		return
+	}
+	for _, p := range list {
+		s.profiles[p.Name] = p
+	}
+}
+
+// save writes the profiles file. Caller must hold mu.
+func (s *SNMPProfileStore) save() error {
+	list := make([]SNMPProfile, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(snmpProfilesFile, data, 0600)
+}
+
+// List returns the profiles sorted by name.
+func (s *SNMPProfileStore) List() []SNMPProfile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	list := make([]SNMPProfile, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns a profile by name.
+func (s *SNMPProfileStore) Get(name string) (SNMPProfile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	p, ok := s.profiles[name]
+	return p, ok
+}
+
+// Set stores a profile. Secrets left empty keep their saved value, so clients can
+// edit a profile without knowing its passwords.
+func (s *SNMPProfileStore) Set(p SNMPProfile) (SNMPProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	if old, ok := s.profiles[p.Name]; ok {
+		if p.Community == "" {
+			p.Community = old.Community
+		}
+		if p.AuthPassword == "" && p.AuthProtocol != "" {
+			p.AuthPassword = old.AuthPassword
+		}
+		if p.PrivPassword == "" && p.PrivProtocol != "" {
+			p.PrivPassword = old.PrivPassword
+		}
+	}
+	if err := p.Validate(); err != nil {
+		return SNMPProfile{}, err
+	}
+	if p.Version == "3" {
+		p.Community = ""
+	} else {
+		p.Username, p.AuthProtocol, p.AuthPassword, p.PrivProtocol, p.PrivPassword, p.ContextName = "", "", "", "", "", ""
+	}
+	s.profiles[p.Name] = p
+	return p, s.save()
+}
+
+// Delete removes a profile, reporting whether it existed.
+func (s *SNMPProfileStore) Delete(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	if _, ok := s.profiles[name]; !ok {
+		return false, nil
+	}
+	delete(s.profiles, name)
+	return true, s.save()
+}
+
+// SNMPVariable is one value returned by an SNMP operation.
+type SNMPVariable struct {
+	OID   string `json:"oid"`
+	Name  string `json:"name,omitempty"` // Symbolic name of well-known OIDs, e.g. ifHCInOctets.3
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	// Conversions of well-known interface and system counters
+	Mbps   *float64 `json:"mbps,omitempty"`   // Traffic rate since the previous query, or link speed
+	Uptime string   `json:"uptime,omitempty"` // TimeTicks as a duration
+}
+
+// snmpConnect opens a session to host:port with the profile's credentials.
+func snmpConnect(ctx context.Context, host, port string, profile SNMPProfile) (*gosnmp.GoSNMP, error) {
+	ip, err := ResolveOutboundHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	snmp := &gosnmp.GoSNMP{
+		Target:         ip.String(),
+		Port:           parsePort(port),
+		Community:      profile.Community,
+		Version:        gosnmp.Version2c,
+		Timeout:        time.Duration(5) * time.Second,
+		Retries:        1,
+		Context:        ctx,
+		MaxRepetitions: snmpDefaultRepetition,
+	}
+	if profile.Version == "3" {
+		params := &gosnmp.UsmSecurityParameters{UserName: profile.Username}
+		snmp.MsgFlags = gosnmp.NoAuthNoPriv
+		if profile.AuthProtocol != "" {
+			params.AuthenticationProtocol = snmpAuthProtocols[profile.AuthProtocol]
+			params.AuthenticationPassphrase = profile.AuthPassword
+			snmp.MsgFlags = gosnmp.AuthNoPriv
+		}
+		if profile.PrivProtocol != "" {
+			params.PrivacyProtocol = snmpPrivProtocols[profile.PrivProtocol]
+			params.PrivacyPassphrase = profile.PrivPassword
+			snmp.MsgFlags = gosnmp.AuthPriv
+		}
+		snmp.Version = gosnmp.Version3
+		snmp.SecurityModel = gosnmp.UserSecurityModel
+		snmp.SecurityParameters = params
+		snmp.ContextName = profile.ContextName
+		snmp.Community = ""
+	}
+	if err := snmp.Connect(); err != nil {
+		return nil, errors.New("SNMP connect failed: " + err.Error())
+	}
+	return snmp, nil
+}
+
+func snmpClose(snmp *gosnmp.GoSNMP) {
+	if err := snmp.Conn.Close(); err != nil {
+		Logger("snmp").Warn("closing SNMP connection", "error", err)
+	}
+}
+
+// SNMPGet reads the given OIDs in one request.
+func SNMPGet(ctx context.Context, host, port string, profile SNMPProfile, oids []string) ([]SNMPVariable, error) {
+	if len(oids) == 0 || len(oids) > snmpMaxOIDs {
+		return nil, fmt.Errorf("between 1 and %d OIDs are allowed", snmpMaxOIDs)
+	}
+	snmp, err := snmpConnect(ctx, host, port, profile)
+	if err != nil {
+		return nil, err
+	}
+	defer snmpClose(snmp)
+
+	result, err := snmp.Get(oids)
+	if err != nil {
+		return nil, errors.New("SNMP GET failed: " + err.Error())
+	}
+	if len(result.Variables) == 0 {
+		return nil, errors.New("no SNMP variables returned")
+	}
+	return snmpVariables(host, result.Variables), nil
+}
+
+// SNMPBulk performs one GETBULK request, returning up to maxRepetitions successors of each OID.
+func SNMPBulk(ctx context.Context, host, port string, profile SNMPProfile, oids []string, maxRepetitions int) ([]SNMPVariable, error) {
+	if len(oids) == 0 || len(oids) > snmpMaxOIDs {
+		return nil, fmt.Errorf("between 1 and %d OIDs are allowed", snmpMaxOIDs)
+	}
+	if maxRepetitions <= 0 {
+		maxRepetitions = snmpDefaultRepetition
+	}
+	snmp, err := snmpConnect(ctx, host, port, profile)
+	if err != nil {
+		return nil, err
+	}
+	defer snmpClose(snmp)
+
+	result, err := snmp.GetBulk(oids, 0, uint32(min(maxRepetitions, 100)))
+	if err != nil {
+		return nil, errors.New("SNMP GETBULK failed: " + err.Error())
+	}
+	return snmpVariables(host, result.Variables), nil
+}
+
+var errSNMPWalkLimit = errors.New("walk limit reached")
+
+// SNMPWalk returns the subtree below root using GETBULK, stopping after limit values.
+// The second result reports whether the walk was cut short by the limit.
+func SNMPWalk(ctx context.Context, host, port string, profile SNMPProfile, root string, limit int) ([]SNMPVariable, bool, error) {
+	if limit <= 0 {
+		limit = snmpDefaultWalkLimit
+	}
+	limit = min(limit, snmpMaxWalkLimit)
+	snmp, err := snmpConnect(ctx, host, port, profile)
+	if err != nil {
+		return nil, false, err
+	}
+	defer snmpClose(snmp)
+
+	var pdus []gosnmp.SnmpPDU
+	err = snmp.BulkWalk(root, func(pdu gosnmp.SnmpPDU) error {
+		if len(pdus) >= limit {
+			return errSNMPWalkLimit
+		}
+		pdus = append(pdus, pdu)
+		return nil
+	})
+	truncated := errors.Is(err, errSNMPWalkLimit)
+	if err != nil && !truncated {
+		return nil, false, errors.New("SNMP WALK failed: " + err.Error())
+	}
+	return snmpVariables(host, pdus), truncated, nil
+}
+
+// QuerySNMP performs an SNMPv2c GET of a single OID and returns its value as text.
+func QuerySNMP(ctx context.Context, host, port, community, oid string) (string, error) {
+	vars, err := SNMPGet(ctx, host, port, SNMPProfile{Version: "2c", Community: community}, []string{oid})
+	if err != nil {
+		return "", err
+	}
+	if vars[0].Type == "NoSuchObject" || vars[0].Type == "NoSuchInstance" {
+		return "", errors.New("OID not found")
+	}
+	return vars[0].Value, nil
+}
+
+// snmpVariables converts PDUs and adds the conversions of well-known counters.
+func snmpVariables(host string, pdus []gosnmp.SnmpPDU) []SNMPVariable {
+	now := time.Now()
+	vars := make([]SNMPVariable, 0, len(pdus))
+	for _, pdu := range pdus {
+		if pdu.Type == gosnmp.EndOfMibView {
+			continue
+		}
+		v := SNMPVariable{
+			OID:  strings.TrimPrefix(pdu.Name, "."),
+			Type: pdu.Type.String(),
+		}
+		switch pdu.Type {
+		case gosnmp.OctetString:
+			v.Value = string(pdu.Value.([]byte))
+		case gosnmp.IPAddress:
+			v.Value = pdu.Value.(string)
+		case gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.Null:
+			v.Value = ""
+		default:
+			v.Value = fmt.Sprintf("%v", pdu.Value)
+		}
+		snmpConvert(host, &v, pdu, now)
+		vars = append(vars, v)
+	}
+	return vars
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// IF-MIB columns read for the interface table.
+const (
+	oidIfDescr       = "1.3.6.1.2.1.2.2.1.2"
+	oidIfOperStatus  = "1.3.6.1.2.1.2.2.1.8"
+	oidIfInOctets    = "1.3.6.1.2.1.2.2.1.10"
+	oidIfOutOctets   = "1.3.6.1.2.1.2.2.1.16"
+	oidIfName        = "1.3.6.1.2.1.31.1.1.1.1"
+	oidIfHCInOctets  = "1.3.6.1.2.1.31.1.1.1.6"
+	oidIfHCOutOctets = "1.3.6.1.2.1.31.1.1.1.10"
+	oidIfHighSpeed   = "1.3.6.1.2.1.31.1.1.1.15"
+	oidIfAlias       = "1.3.6.1.2.1.31.1.1.1.18"
+)
+
+// Limits of the interface traffic history kept per interface.
+const (
+	snmpIfHistoryPoints = 720 // 12 hours at one sample per minute
+	snmpIfMaxInterfaces = 1024
+	snmpIfIdleTTL       = 24 * time.Hour // Devices not polled for this long are forgotten
+)
+
+// SNMPInterface is one row of a device's interface table with its current throughput.
+type SNMPInterface struct {
+	Index     int     `json:"index"`
+	Name      string  `json:"name"`
+	Descr     string  `json:"descr,omitempty"`
+	Alias     string  `json:"alias,omitempty"`
+	Up        bool    `json:"up"`
+	SpeedMbps float64 `json:"speedMbps,omitempty"`
+	InOctets  uint64  `json:"inOctets"`
+	OutOctets uint64  `json:"outOctets"`
+	Counter64 bool    `json:"counter64"` // False when the device only has 32-bit counters
+	// Rates since the previous poll of the device, missing on the first poll
+	InMbps  *float64 `json:"inMbps,omitempty"`
+	OutMbps *float64 `json:"outMbps,omitempty"`
+	InUtil  *float64 `json:"inUtil,omitempty"` // Percent of the link speed
+	OutUtil *float64 `json:"outUtil,omitempty"`
+	History *IfRates `json:"history,omitempty"` // Rates of earlier polls, when requested
+}
+
+// IfRates are the inbound and outbound rate histories of an interface in Mbps.
+type IfRates struct {
+	In  []MetricPoint `json:"in"`
+	Out []MetricPoint `json:"out"`
+}
+
+// snmpIfState is the last counter sample and rate history of one interface.
+type snmpIfState struct {
+	in, out uint64
+	at      time.Time
+	inRate  *metricRing
+	outRate *metricRing
+}
+
+// snmpIfDevice holds the interface samples of one device.
+type snmpIfDevice struct {
+	interfaces map[int]*snmpIfState
+	polled     time.Time
+}
+
+var (
+	snmpIfMu      sync.Mutex
+	snmpIfDevices = make(map[string]*snmpIfDevice)
+)
+
+// SNMPInterfaces walks the IF-MIB interface table of a device and computes each
+// interface's throughput from the previous sample of the same device. Samples and rate
+// histories are kept in memory so that the rates can be graphed directly.
+func SNMPInterfaces(ctx context.Context, host, port string, profile SNMPProfile) ([]SNMPInterface, error) {
+	snmp, err := snmpConnect(ctx, host, port, profile)
+	if err != nil {
+		return nil, err
+	}
+	defer snmpClose(snmp)
+
+	ifaces := make(map[int]*SNMPInterface)
+	get := func(index int) *SNMPInterface {
+		iface, ok := ifaces[index]
+		if !ok {
+			iface = &SNMPInterface{Index: index}
+			ifaces[index] = iface
+		}
+		return iface
+	}
+	walk := func(column string, fn func(iface *SNMPInterface, pdu gosnmp.SnmpPDU)) error {
+		return snmp.BulkWalk(column, func(pdu gosnmp.SnmpPDU) error {
+			index, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(pdu.Name, "."), column+"."))
+			if err != nil {
+				return nil
+			}
+			if _, ok := ifaces[index]; !ok && len(ifaces) >= snmpIfMaxInterfaces {
+				return errSNMPWalkLimit
+			}
+			fn(get(index), pdu)
+			return nil
+		})
+	}
+	text := func(pdu gosnmp.SnmpPDU) string {
+		if b, ok := pdu.Value.([]byte); ok {
+			return string(b)
+		}
+		return ""
+	}
+
+	if err := walk(oidIfDescr, func(iface *SNMPInterface, pdu gosnmp.SnmpPDU) { iface.Descr = text(pdu) }); err != nil && !errors.Is(err, errSNMPWalkLimit) {
+		return nil, errors.New("SNMP WALK failed: " + err.Error())
+	}
+	if len(ifaces) == 0 {
+		return nil, errors.New("device has no IF-MIB interface table")
+	}
+	walk(oidIfOperStatus, func(iface *SNMPInterface, pdu gosnmp.SnmpPDU) {
+		iface.Up = gosnmp.ToBigInt(pdu.Value).Int64() == 1
+	})
+	// ifXTable columns are optional on older devices
+	walk(oidIfName, func(iface *SNMPInterface, pdu gosnmp.SnmpPDU) { iface.Name = text(pdu) })
+	walk(oidIfAlias, func(iface *SNMPInterface, pdu gosnmp.SnmpPDU) { iface.Alias = text(pdu) })
+	walk(oidIfHighSpeed, func(iface *SNMPInterface, pdu gosnmp.SnmpPDU) {
+		iface.SpeedMbps = float64(gosnmp.ToBigInt(pdu.Value).Uint64())
+	})
+	walk(oidIfHCInOctets, func(iface *SNMPInterface, pdu gosnmp.SnmpPDU) {
+		iface.InOctets = gosnmp.ToBigInt(pdu.Value).Uint64()
+		iface.Counter64 = true
+	})
+	walk(oidIfHCOutOctets, func(iface *SNMPInterface, pdu gosnmp.SnmpPDU) {
+		iface.OutOctets = gosnmp.ToBigInt(pdu.Value).Uint64()
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	// Fall back to the 32-bit counters of the ifTable
+	var legacy bool
+	for _, iface := range ifaces {
+		if !iface.Counter64 {
+			legacy = true
+			break
+		}
+	}
+	if legacy {
+		walk(oidIfInOctets, func(iface *SNMPInterface, pdu gosnmp.SnmpPDU) {
+			if !iface.Counter64 {
+				iface.InOctets = gosnmp.ToBigInt(pdu.Value).Uint64()
+			}
+		})
+		walk(oidIfOutOctets, func(iface *SNMPInterface, pdu gosnmp.SnmpPDU) {
+			if !iface.Counter64 {
+				iface.OutOctets = gosnmp.ToBigInt(pdu.Value).Uint64()
+			}
+		})
+	}
+
+	result := make([]SNMPInterface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		if iface.Name == "" {
+			iface.Name = iface.Descr
+		}
+		result = append(result, *iface)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Index < result[j].Index })
+	recordSNMPInterfaces(host+":"+strconv.Itoa(int(parsePort(port))), result, time.Now())
+	return result, nil
+}
+
+// recordSNMPInterfaces computes the rates against the previous sample of the device and
+// adds them to the interfaces' histories.
+func recordSNMPInterfaces(device string, ifaces []SNMPInterface, now time.Time) {
+	snmpIfMu.Lock()
+	defer snmpIfMu.Unlock()
+	for key, d := range snmpIfDevices {
+		if now.Sub(d.polled) > snmpIfIdleTTL {
+			delete(snmpIfDevices, key)
+		}
+	}
+	d, ok := snmpIfDevices[device]
+	if !ok {
+		d = &snmpIfDevice{interfaces: make(map[int]*snmpIfState)}
+		snmpIfDevices[device] = d
+	}
+	d.polled = now
+
+	for i := range ifaces {
+		iface := &ifaces[i]
+		state, ok := d.interfaces[iface.Index]
+		if !ok {
+			state = &snmpIfState{inRate: newMetricRing(snmpIfHistoryPoints), outRate: newMetricRing(snmpIfHistoryPoints)}
+			d.interfaces[iface.Index] = state
+		}
+		elapsed := now.Sub(state.at)
+		// Skip polls that follow too closely to give a meaningful rate, keeping the older sample
+		if ok && elapsed < time.Second {
+			continue
+		}
+		if ok {
+			bits := 32
+			if iface.Counter64 {
+				bits = 64
+			}
+			in := OctetsToMbps(CounterDelta(state.in, iface.InOctets, bits), elapsed)
+			out := OctetsToMbps(CounterDelta(state.out, iface.OutOctets, bits), elapsed)
+			// A counter reset (device reboot) looks like a wrap; drop rates above the link speed
+			if iface.SpeedMbps == 0 || (in <= iface.SpeedMbps*1.1 && out <= iface.SpeedMbps*1.1) {
+				iface.InMbps, iface.OutMbps = &in, &out
+				if iface.SpeedMbps > 0 {
+					inUtil, outUtil := in/iface.SpeedMbps*100, out/iface.SpeedMbps*100
+					iface.InUtil, iface.OutUtil = &inUtil, &outUtil
+				}
+				state.inRate.add(MetricPoint{T: now.Unix(), V: in})
+				state.outRate.add(MetricPoint{T: now.Unix(), V: out})
+			}
+		}
+		state.in, state.out, state.at = iface.InOctets, iface.OutOctets, now
+	}
+}
+
+// SNMPInterfaceHistory adds the rate histories since from to the interfaces of a device.
+func SNMPInterfaceHistory(host, port string, ifaces []SNMPInterface, from time.Time) {
+	snmpIfMu.Lock()
+	defer snmpIfMu.Unlock()
+	d, ok := snmpIfDevices[host+":"+strconv.Itoa(int(parsePort(port)))]
+	if !ok {
+		return
+	}
+	for i := range ifaces {
+		if state, ok := d.interfaces[ifaces[i].Index]; ok {
+			ifaces[i].History = &IfRates{
+				In:  state.inRate.since(from.Unix()),
+				Out: state.outRate.since(from.Unix()),
+			}
+		}
+	}
+}
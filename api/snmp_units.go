@@ -0,0 +1,123 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// snmpWellKnown names common interface and system OIDs and how their values are converted.
+var snmpWellKnown = []struct {
+	oid, name string
+	kind      string // "octets32", "octets64", "bps", "mbps" or "ticks"
+}{
+	{"1.3.6.1.2.1.1.3", "sysUpTime", "ticks"},
+	{"1.3.6.1.2.1.2.2.1.5", "ifSpeed", "bps"},
+	{"1.3.6.1.2.1.2.2.1.10", "ifInOctets", "octets32"},
+	{"1.3.6.1.2.1.2.2.1.16", "ifOutOctets", "octets32"},
+	{"1.3.6.1.2.1.31.1.1.1.6", "ifHCInOctets", "octets64"},
+	{"1.3.6.1.2.1.31.1.1.1.10", "ifHCOutOctets", "octets64"},
+	{"1.3.6.1.2.1.31.1.1.1.15", "ifHighSpeed", "mbps"},
+	{"1.3.6.1.2.1.25.1.1", "hrSystemUptime", "ticks"},
+}
+
+// snmpCounterSample is the last value read of a counter, for rates between queries.
+type snmpCounterSample struct {
+	value uint64
+	at    time.Time
+}
+
+var (
+	snmpCountersMu    sync.Mutex
+	snmpCounters      = make(map[string]snmpCounterSample)
+	snmpCountersSwept time.Time
+)
+
+// snmpCounterTTL drops samples of counters that are no longer queried.
+const snmpCounterTTL = time.Hour
+
+// CounterDelta returns the increase of a counter that may have wrapped at 2^bits.
+func CounterDelta(prev, cur uint64, bits int) uint64 {
+	if cur >= prev {
+		return cur - prev
+	}
+	if bits >= 64 {
+		return cur + (^uint64(0) - prev) + 1
+	}
+	return cur + (uint64(1)<<bits - prev)
+}
+
+// OctetsToMbps converts an octet count over a period to megabits per second.
+func OctetsToMbps(octets uint64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(octets) * 8 / elapsed.Seconds() / 1e6
+}
+
+// TimeTicksToDuration converts SNMP TimeTicks (hundredths of a second) to a duration.
+func TimeTicksToDuration(ticks uint64) time.Duration {
+	return time.Duration(ticks) * 10 * time.Millisecond
+}
+
+// snmpOIDName returns the symbolic name of a well-known OID with its index, and its kind.
+func snmpOIDName(oid string) (string, string) {
+	for _, wk := range snmpWellKnown {
+		if oid == wk.oid {
+			return wk.name, wk.kind
+		}
+		if rest, ok := strings.CutPrefix(oid, wk.oid+"."); ok {
+			return wk.name + "." + rest, wk.kind
+		}
+	}
+	return "", ""
+}
+
+// snmpConvert names well-known OIDs and adds their converted values: link speeds and
+// the traffic rate of octet counters since the previous query of the same counter.
+func snmpConvert(host string, v *SNMPVariable, pdu gosnmp.SnmpPDU, now time.Time) {
+	name, kind := snmpOIDName(v.OID)
+	if name == "" {
+		return
+	}
+	v.Name = name
+	n, err := strconv.ParseUint(v.Value, 10, 64)
+	if err != nil {
+		return
+	}
+	switch kind {
+	case "ticks":
+		v.Uptime = FmtUptime(int64(TimeTicksToDuration(n).Seconds()))
+	case "bps":
+		mbps := float64(n) / 1e6
+		v.Mbps = &mbps
+	case "mbps":
+		mbps := float64(n)
+		v.Mbps = &mbps
+	case "octets32", "octets64":
+		bits := 64
+		if kind == "octets32" || pdu.Type == gosnmp.Counter32 {
+			bits = 32
+		}
+		key := host + "|" + v.OID
+		snmpCountersMu.Lock()
+		prev, ok := snmpCounters[key]
+		snmpCounters[key] = snmpCounterSample{value: n, at: now}
+		if now.Sub(snmpCountersSwept) > time.Minute {
+			snmpCountersSwept = now
+			for k, s := range snmpCounters {
+				if now.Sub(s.at) > snmpCounterTTL {
+					delete(snmpCounters, k)
+				}
+			}
+		}
+		snmpCountersMu.Unlock()
+		if ok && now.Sub(prev.at) >= time.Second {
+			mbps := OctetsToMbps(CounterDelta(prev.value, n, bits), now.Sub(prev.at))
+			v.Mbps = &mbps
+		}
+	}
+}
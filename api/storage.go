@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"sync"
 	"time"
 )
@@ -31,12 +32,17 @@ func (s *Storage) Set(key string, value interface{}, version int64) {
 	existing, exists := s.items[key]
 	shouldUpdate := !exists || version > existing.Version
 	var storedVersion int64
+	var stored, previous StorageItem
 	if shouldUpdate {
+		if exists {
+			previous = *existing
+		}
 		s.items[key] = &StorageItem{
 			Value:        value,
 			Version:      version,
 			LastModified: time.Now(),
 		}
+		stored = *s.items[key]
 		storedVersion = version
 	} else {
 		// Keep existing version if not updating
@@ -46,6 +52,14 @@ func (s *Storage) Set(key string, value interface{}, version int64) {
 
 	// Broadcast update if data was actually updated
 	if shouldUpdate {
+		GetStore().SaveStorageItem(key, stored)
+
+		// Record edits of existing configuration keys on the timeline, and keep replaced layouts
+		if exists {
+			GetTimeline().RecordConfigEdit(key)
+			GetLayoutHistory().recordReplaced(key, previous, false)
+		}
+
 		GetWSManager().BroadcastStorageUpdate(key, storedVersion)
 		
 		// Update debug logger preferences if debugPrefs changed
@@ -55,6 +69,16 @@ func (s *Storage) Set(key string, value interface{}, version int64) {
 	}
 }
 
+// SetNext stores a value one version above the stored one, as a server-side change that
+// clients syncing the key pick up.
+func (s *Storage) SetNext(key string, value interface{}) {
+	var version int64 = 1
+	if item, exists := s.Get(key); exists {
+		version = item.Version + 1
+	}
+	s.Set(key, value, version)
+}
+
 // Get retrieves a value by key.
 func (s *Storage) Get(key string) (*StorageItem, bool) {
 	s.mu.RLock()
@@ -73,6 +97,19 @@ func (s *Storage) Get(key string) (*StorageItem, bool) {
 	}, true
 }
 
+// GetAs decodes a stored value into v, reporting whether the key exists and decoded.
+func (s *Storage) GetAs(key string, v any) bool {
+	item, exists := s.Get(key)
+	if !exists {
+		return false
+	}
+	data, err := json.Marshal(item.Value)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
 // GetAll returns all stored items.
 func (s *Storage) GetAll() map[string]*StorageItem {
 	s.mu.RLock()
@@ -94,6 +131,16 @@ func (s *Storage) Delete(key string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	delete(s.items, key)
+	GetStore().DeleteStorageItem(key)
+}
+
+// Restore loads previously persisted items without broadcasting updates.
+func (s *Storage) Restore(items map[string]*StorageItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range items {
+		s.items[k] = v
+	}
 }
 
 // Global storage instance
@@ -0,0 +1,442 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Store drivers.
+const (
+	StoreDriverJSON   = "json"
+	StoreDriverSQLite = "sqlite"
+)
+
+// Default retention for the stored data.
+const (
+	DefaultMonitorResultRetention = 30 * 24 * time.Hour
+	DefaultSearchHistoryRetention = 365 * 24 * time.Hour
+	DefaultNotificationRetention  = 90 * 24 * time.Hour
+	DefaultTimelineRetention      = 90 * 24 * time.Hour
+	DefaultAuditRetention         = 365 * 24 * time.Hour
+)
+
+// StoreConfig selects where long-term data is kept.
+type StoreConfig struct {
+	Driver    string         `json:"driver"`         // "json" (default) or "sqlite"
+	Path      string         `json:"path,omitempty"` // SQLite database file (default: homepage.db)
+	Retention StoreRetention `json:"retention,omitempty"`
+}
+
+// StoreRetention holds how long data is kept, e.g. "30d". Monitor results and
+// notifications are only stored by the sqlite driver; the others apply to both drivers.
+type StoreRetention struct {
+	MonitorResults string `json:"monitorResults,omitempty"`
+	SearchHistory  string `json:"searchHistory,omitempty"`
+	Notifications  string `json:"notifications,omitempty"`
+	Timeline       string `json:"timeline,omitempty"`
+	Audit          string `json:"audit,omitempty"`
+}
+
+// Validate checks the driver and retention values.
+func (c StoreConfig) Validate() error {
+	switch c.Driver {
+	case "", StoreDriverJSON, StoreDriverSQLite:
+	default:
+		return fmt.Errorf("store: unknown driver %q (use json or sqlite)", c.Driver)
+	}
+	_, err := c.Retention.Durations()
+	return err
+}
+
+// Durations parses the retention values keyed by data set (monitor_results,
+// search_history, notifications, timeline, audit), falling back to the defaults.
+func (c StoreRetention) Durations() (map[string]time.Duration, error) {
+	values := []struct {
+		name  string
+		value string
+		def   time.Duration
+	}{
+		{"monitor_results", c.MonitorResults, DefaultMonitorResultRetention},
+		{"search_history", c.SearchHistory, DefaultSearchHistoryRetention},
+		{"notifications", c.Notifications, DefaultNotificationRetention},
+		{"timeline", c.Timeline, DefaultTimelineRetention},
+		{"audit", c.Audit, DefaultAuditRetention},
+	}
+	result := make(map[string]time.Duration, len(values))
+	for _, v := range values {
+		if v.value == "" {
+			result[v.name] = v.def
+			continue
+		}
+		d, err := ParseHistoryRange(v.value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("store: invalid retention %q (use e.g. 24h or 30d)", v.value)
+		}
+		result[v.name] = d
+	}
+	return result, nil
+}
+
+// storeMigrations are applied in order; the schema version is the number applied.
+var storeMigrations = []string{
+	`CREATE TABLE storage_items (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		modified INTEGER NOT NULL
+	);
+	CREATE TABLE monitor_results (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		monitor TEXT NOT NULL,
+		name TEXT NOT NULL,
+		success INTEGER NOT NULL,
+		latency_ms INTEGER NOT NULL,
+		error TEXT NOT NULL,
+		checked_at INTEGER NOT NULL
+	);
+	CREATE INDEX idx_monitor_results_monitor ON monitor_results (monitor, checked_at);
+	CREATE TABLE metric_points (
+		metric TEXT NOT NULL,
+		t INTEGER NOT NULL,
+		v REAL NOT NULL,
+		PRIMARY KEY (metric, t)
+	);
+	CREATE TABLE search_history (
+		term TEXT NOT NULL,
+		engine TEXT NOT NULL,
+		searched_at TEXT NOT NULL,
+		PRIMARY KEY (term, engine, searched_at)
+	);
+	CREATE TABLE notifications (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		body TEXT NOT NULL,
+		severity TEXT NOT NULL,
+		tag TEXT NOT NULL,
+		sent_at INTEGER NOT NULL
+	);
+	CREATE INDEX idx_notifications_sent_at ON notifications (sent_at);`,
+}
+
+// MonitorResultRecord is a stored monitor check result.
+type MonitorResultRecord struct {
+	Time    time.Time `json:"time"`
+	Success bool      `json:"success"`
+	Latency int64     `json:"latency,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// StoreStatus describes the store for the status endpoint.
+type StoreStatus struct {
+	Driver        string           `json:"driver"`
+	Path          string           `json:"path,omitempty"`
+	SchemaVersion int              `json:"schemaVersion,omitempty"`
+	Rows          map[string]int64 `json:"rows,omitempty"`
+}
+
+// SQLiteStore keeps long-term data in an embedded SQLite database.
+// All methods are safe to call on a nil store and do nothing.
+type SQLiteStore struct {
+	db   *sql.DB
+	path string
+}
+
+// Global store instance (nil unless the sqlite driver is configured)
+var sqliteStore *SQLiteStore
+
+// GetStore returns the global SQLite store, or nil when the JSON files are used.
+func GetStore() *SQLiteStore {
+	return sqliteStore
+}
+
+// OpenStore opens the configured store and applies pending migrations.
+func OpenStore(cfg StoreConfig) error {
+	if cfg.Driver != StoreDriverSQLite {
+		return nil
+	}
+	path := cfg.Path
+	if path == "" {
+		path = "homepage.db"
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	db.SetMaxOpenConns(1)
+
+	s := &SQLiteStore{db: db, path: path}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to migrate %s: %w", path, err)
+	}
+	sqliteStore = s
+	return nil
+}
+
+// migrate applies the migrations that have not been applied yet.
+func (s *SQLiteStore) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+	version := s.schemaVersion()
+	for i := version; i < len(storeMigrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(storeMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, i+1, time.Now().Unix()); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		GetDebugLogger().Logf("store", "applied migration %d", i+1)
+	}
+	return nil
+}
+
+// schemaVersion returns the number of applied migrations.
+func (s *SQLiteStore) schemaVersion() int {
+	var version int
+	_ = s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version
+}
+
+// exec runs a statement and logs failures.
+func (s *SQLiteStore) exec(query string, args ...any) {
+	if _, err := s.db.Exec(query, args...); err != nil {
+		GetDebugLogger().Logf("store", "query failed: %v", err)
+	}
+}
+
+// Prune deletes rows older than the retention of their table and returns the number of
+// rows removed per table. Metric points follow the hourly metric history retention.
+func (s *SQLiteStore) Prune(retention map[string]time.Duration) map[string]int64 {
+	removed := make(map[string]int64)
+	if s == nil {
+		return removed
+	}
+	now := time.Now()
+	for _, q := range []struct {
+		table string
+		query string
+		arg   any
+	}{
+		{"monitor_results", `DELETE FROM monitor_results WHERE checked_at < ?`, now.Add(-retention["monitor_results"]).Unix()},
+		{"notifications", `DELETE FROM notifications WHERE sent_at < ?`, now.Add(-retention["notifications"]).Unix()},
+		{"search_history", `DELETE FROM search_history WHERE searched_at < ?`, now.Add(-retention["search_history"]).UTC().Format(time.RFC3339)},
+		{"metric_points", `DELETE FROM metric_points WHERE t < ?`, now.Add(-GetMetricsHistory().hourlyRetention).Unix()},
+	} {
+		res, err := s.db.Exec(q.query, q.arg)
+		if err != nil {
+			GetDebugLogger().Logf("store", "failed to prune %s: %v", q.table, err)
+			continue
+		}
+		if n, err := res.RowsAffected(); err == nil && n > 0 {
+			removed[q.table] = n
+		}
+	}
+	return removed
+}
+
+// Status returns the driver, schema version and row counts.
+func (s *SQLiteStore) Status() StoreStatus {
+	if s == nil {
+		return StoreStatus{Driver: StoreDriverJSON}
+	}
+	status := StoreStatus{
+		Driver:        StoreDriverSQLite,
+		Path:          s.path,
+		SchemaVersion: s.schemaVersion(),
+		Rows:          make(map[string]int64),
+	}
+	for _, table := range []string{"storage_items", "monitor_results", "metric_points", "search_history", "notifications"} {
+		var n int64
+		if err := s.db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err == nil {
+			status.Rows[table] = n
+		}
+	}
+	return status
+}
+
+// SaveStorageItem persists a storage item and, for search history, appends new searches.
+func (s *SQLiteStore) SaveStorageItem(key string, item StorageItem) {
+	if s == nil {
+		return
+	}
+	data, err := json.Marshal(item.Value)
+	if err != nil {
+		return
+	}
+	s.exec(`INSERT INTO storage_items (key, value, version, modified) VALUES (?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, version = excluded.version, modified = excluded.modified`,
+		key, string(data), item.Version, item.LastModified.Unix())
+
+	if key == "searchHistory" {
+		var entries []struct {
+			Term      string `json:"term"`
+			Engine    string `json:"engine"`
+			Timestamp string `json:"timestamp"`
+		}
+		if json.Unmarshal(data, &entries) != nil {
+			return
+		}
+		for _, e := range entries {
+			s.exec(`INSERT OR IGNORE INTO search_history (term, engine, searched_at) VALUES (?, ?, ?)`, e.Term, e.Engine, e.Timestamp)
+		}
+	}
+}
+
+// DeleteStorageItem removes a persisted storage item.
+func (s *SQLiteStore) DeleteStorageItem(key string) {
+	if s == nil {
+		return
+	}
+	s.exec(`DELETE FROM storage_items WHERE key = ?`, key)
+}
+
+// LoadStorageItems returns all persisted storage items.
+func (s *SQLiteStore) LoadStorageItems() map[string]*StorageItem {
+	result := make(map[string]*StorageItem)
+	if s == nil {
+		return result
+	}
+	rows, err := s.db.Query(`SELECT key, value, version, modified FROM storage_items`)
+	if err != nil {
+		GetDebugLogger().Logf("store", "failed to load storage items: %v", err)
+		return result
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, value string
+		var version, modified int64
+		if err := rows.Scan(&key, &value, &version, &modified); err != nil {
+			continue
+		}
+		var v any
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			continue
+		}
+		result[key] = &StorageItem{Value: v, Version: version, LastModified: time.Unix(modified, 0)}
+	}
+	return result
+}
+
+// RecordMonitorResult stores the result of a monitor check.
+func (s *SQLiteStore) RecordMonitorResult(key, name string, result MonitorResult) {
+	if s == nil {
+		return
+	}
+	s.exec(`INSERT INTO monitor_results (monitor, name, success, latency_ms, error, checked_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		key, name, result.Success, result.Latency, result.Error, time.Now().Unix())
+}
+
+// MonitorResults returns the results of a monitor since the given time, oldest first.
+func (s *SQLiteStore) MonitorResults(ctx context.Context, key string, since time.Time) ([]MonitorResultRecord, error) {
+	result := []MonitorResultRecord{}
+	if s == nil {
+		return result, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT success, latency_ms, error, checked_at FROM monitor_results
+		WHERE monitor = ? AND checked_at >= ? ORDER BY checked_at`, key, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rec MonitorResultRecord
+		var at int64
+		if err := rows.Scan(&rec.Success, &rec.Latency, &rec.Error, &at); err != nil {
+			return nil, err
+		}
+		rec.Time = time.Unix(at, 0)
+		result = append(result, rec)
+	}
+	return result, rows.Err()
+}
+
+// SaveMetricPoints stores hourly metric averages.
+func (s *SQLiteStore) SaveMetricPoints(points map[string][]MetricPoint) {
+	if s == nil {
+		return
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return
+	}
+	for metric, list := range points {
+		for _, p := range list {
+			if _, err := tx.Exec(`INSERT OR REPLACE INTO metric_points (metric, t, v) VALUES (?, ?, ?)`, metric, p.T, p.V); err != nil {
+				tx.Rollback()
+				GetDebugLogger().Logf("store", "failed to save metric points: %v", err)
+				return
+			}
+		}
+	}
+	_ = tx.Commit()
+}
+
+// LoadMetricPoints returns the hourly metric averages since the given Unix time.
+func (s *SQLiteStore) LoadMetricPoints(since int64) map[string][]MetricPoint {
+	result := make(map[string][]MetricPoint)
+	if s == nil {
+		return result
+	}
+	rows, err := s.db.Query(`SELECT metric, t, v FROM metric_points WHERE t >= ? ORDER BY t`, since)
+	if err != nil {
+		GetDebugLogger().Logf("store", "failed to load metric points: %v", err)
+		return result
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var metric string
+		var p MetricPoint
+		if err := rows.Scan(&metric, &p.T, &p.V); err == nil {
+			result[metric] = append(result[metric], p)
+		}
+	}
+	return result
+}
+
+// RecordNotification stores an alert that was dispatched.
+func (s *SQLiteStore) RecordNotification(a Alert) {
+	if s == nil {
+		return
+	}
+	s.exec(`INSERT INTO notifications (title, body, severity, tag, sent_at) VALUES (?, ?, ?, ?, ?)`,
+		a.Title, a.Body, a.Severity, a.Tag, a.Time.Unix())
+}
+
+// Notifications returns the most recent dispatched alerts, newest first.
+func (s *SQLiteStore) Notifications(ctx context.Context, limit int) ([]Alert, error) {
+	result := []Alert{}
+	if s == nil {
+		return result, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT title, body, severity, tag, sent_at FROM notifications ORDER BY sent_at DESC, id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a Alert
+		var at int64
+		if err := rows.Scan(&a.Title, &a.Body, &a.Severity, &a.Tag, &at); err != nil {
+			return nil, err
+		}
+		a.Time = time.Unix(at, 0)
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
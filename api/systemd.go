@@ -0,0 +1,99 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// sdListenFDsStart is the first file descriptor systemd passes to a socket-activated service.
+const sdListenFDsStart = 3
+
+// Listen returns the socket systemd passed to the service when it was socket-activated,
+// so connections wait in the socket's queue across restarts, and otherwise listens on addr.
+// activated reports which one it is.
+func Listen(addr string) (ln net.Listener, activated bool, err error) {
+	listeners, err := systemdListeners()
+	if err != nil {
+		return nil, false, err
+	}
+	if len(listeners) > 0 {
+		for _, extra := range listeners[1:] {
+			Logger("systemd").Warn("ignoring extra socket passed by systemd", "addr", extra.Addr().String())
+			extra.Close()
+		}
+		return listeners[0], true, nil
+	}
+	ln, err = net.Listen("tcp", addr)
+	return ln, false, err
+}
+
+// systemdListeners returns the listening sockets of $LISTEN_FDS when $LISTEN_PID is this
+// process. The variables are cleared so child processes do not take the sockets.
+func systemdListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	var listeners []net.Listener
+	for fd := sdListenFDsStart; fd < sdListenFDsStart+count; fd++ {
+		file := os.NewFile(uintptr(fd), "systemd-socket-"+strconv.Itoa(fd))
+		// FileListener works on a duplicate, so the descriptor itself is closed
+		ln, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("systemd socket %d is not a stream socket: %w", fd, err)
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, nil
+}
+
+// SdNotify sends a state such as "READY=1" or "STOPPING=1" to the service manager over
+// $NOTIFY_SOCKET. It does nothing when the service is not run by systemd as Type=notify.
+func SdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// A leading @ names a socket in the abstract namespace
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// SdWatchdog pings the systemd watchdog at half the interval of $WATCHDOG_USEC, so a hung
+// server is restarted. It returns at once when the watchdog is off.
+func SdWatchdog() {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return
+	}
+	if pid, err := strconv.Atoi(os.Getenv("WATCHDOG_PID")); err == nil && pid != os.Getpid() {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(usec) * time.Microsecond / 2)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := SdNotify("WATCHDOG=1"); err != nil {
+			GetDebugLogger().Logf("systemd", "watchdog ping failed: %v", err)
+		}
+	}
+}
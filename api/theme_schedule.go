@@ -0,0 +1,223 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sunriseAltitude is the sun's altitude at sunrise and sunset, allowing for refraction and
+// the size of its disc.
+const sunriseAltitude = -0.833
+
+// ThemeScheduleConfig switches the dashboard between a light and a dark scheme.
+type ThemeScheduleConfig struct {
+	Mode        string   `json:"mode"`               // "times" (fixed times) or "sun" (sunrise and sunset at the weather location)
+	Template    string   `json:"template,omitempty"` // Template switched to with the schemes, default the profile's own
+	LightScheme string   `json:"lightScheme"`        // Scheme of the day
+	DarkScheme  string   `json:"darkScheme"`         // Scheme of the night
+	LightAt     string   `json:"lightAt,omitempty"`  // HH:MM the light scheme starts in "times" mode, default "07:00"
+	DarkAt      string   `json:"darkAt,omitempty"`   // HH:MM the dark scheme starts in "times" mode, default "19:00"
+	Profiles    []string `json:"profiles,omitempty"` // Dashboard profiles switched, default only the default profile
+}
+
+// Validate checks the mode, schemes, times and profiles.
+func (c ThemeScheduleConfig) Validate() error {
+	if c.Mode != "times" && c.Mode != "sun" {
+		return fmt.Errorf("themeSchedule: mode must be times or sun")
+	}
+	if c.LightScheme == "" || c.DarkScheme == "" || c.LightScheme == c.DarkScheme {
+		return fmt.Errorf("themeSchedule: lightScheme and darkScheme must name two schemes")
+	}
+	for name, value := range map[string]string{"lightAt": c.LightAt, "darkAt": c.DarkAt} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", value); err != nil {
+			return fmt.Errorf("themeSchedule: %s must be HH:MM", name)
+		}
+	}
+	for _, profile := range c.Profiles {
+		if !ValidProfileName(profile) {
+			return fmt.Errorf("themeSchedule: invalid profile %q", profile)
+		}
+	}
+	return nil
+}
+
+// ThemeScheduleStatus is the current phase of the theme schedule.
+type ThemeScheduleStatus struct {
+	Enabled  bool       `json:"enabled"`
+	Mode     string     `json:"mode,omitempty"`
+	Phase    string     `json:"phase,omitempty"`  // "light" or "dark"
+	Scheme   string     `json:"scheme,omitempty"` // Scheme of the phase
+	Next     *time.Time `json:"next,omitempty"`   // When the phase changes
+	Location bool       `json:"location"`         // Whether "sun" mode has a weather location
+}
+
+// ThemeScheduler switches the scheme of the configured profiles at the phase changes and
+// tells every open tab over the WebSocket.
+type ThemeScheduler struct {
+	mu      sync.Mutex
+	config  *ThemeScheduleConfig
+	weather WeatherConfig
+	phase   string
+}
+
+// Global theme scheduler instance
+var themeScheduler = &ThemeScheduler{}
+
+// GetThemeScheduler returns the global theme scheduler instance.
+func GetThemeScheduler() *ThemeScheduler {
+	return themeScheduler
+}
+
+// Configure sets the schedule and the weather location used in "sun" mode.
+func (ts *ThemeScheduler) Configure(cfg ThemeScheduleConfig, weather WeatherConfig) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if cfg.LightAt == "" {
+		cfg.LightAt = "07:00"
+	}
+	if cfg.DarkAt == "" {
+		cfg.DarkAt = "19:00"
+	}
+	if len(cfg.Profiles) == 0 {
+		cfg.Profiles = []string{DefaultProfile}
+	}
+	ts.config = &cfg
+	ts.weather = weather
+}
+
+// Start checks the phase every minute. The first check only corrects profiles already
+// showing one of the two schemes, so a restart does not undo a manual choice.
+func (ts *ThemeScheduler) Start() {
+	ts.mu.Lock()
+	enabled := ts.config != nil
+	ts.mu.Unlock()
+	if !enabled {
+		return
+	}
+	guardLoop("themes", ts.check)
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		guardLoop("themes", ts.check)
+	}
+}
+
+// location returns the weather location in degrees.
+func (ts *ThemeScheduler) location() (float64, float64, bool) {
+	latText, lonText, _ := SavedWeatherLocation(ts.weather)
+	lat, errLat := strconv.ParseFloat(latText, 64)
+	lon, errLon := strconv.ParseFloat(lonText, 64)
+	return lat, lon, errLat == nil && errLon == nil
+}
+
+// phaseAt returns "light" or "dark" at t, or "" in "sun" mode without a location.
+func (ts *ThemeScheduler) phaseAt(cfg ThemeScheduleConfig, t time.Time) string {
+	if cfg.Mode == "sun" {
+		lat, lon, ok := ts.location()
+		if !ok {
+			return ""
+		}
+		if alt, _ := sunHorizontal(t, lat, lon); alt > sunriseAltitude {
+			return "light"
+		}
+		return "dark"
+	}
+	now := t.Format("15:04")
+	if cfg.LightAt < cfg.DarkAt {
+		if now >= cfg.LightAt && now < cfg.DarkAt {
+			return "light"
+		}
+		return "dark"
+	}
+	// The light phase wraps past midnight
+	if now >= cfg.DarkAt && now < cfg.LightAt {
+		return "dark"
+	}
+	return "light"
+}
+
+// nextChange finds the next phase change within two days, to the minute.
+func (ts *ThemeScheduler) nextChange(cfg ThemeScheduleConfig, now time.Time, phase string) *time.Time {
+	t := now.Truncate(time.Minute)
+	for i := 0; i < 2*24*60; i++ {
+		t = t.Add(time.Minute)
+		if ts.phaseAt(cfg, t) != phase {
+			return &t
+		}
+	}
+	return nil
+}
+
+// Status returns the phase now and when it changes.
+func (ts *ThemeScheduler) Status() ThemeScheduleStatus {
+	ts.mu.Lock()
+	cfg := ts.config
+	ts.mu.Unlock()
+	if cfg == nil {
+		return ThemeScheduleStatus{}
+	}
+	now := time.Now()
+	status := ThemeScheduleStatus{Enabled: true, Mode: cfg.Mode, Location: true}
+	status.Phase = ts.phaseAt(*cfg, now)
+	if status.Phase == "" {
+		status.Location = false
+		return status
+	}
+	status.Scheme = cfg.LightScheme
+	if status.Phase == "dark" {
+		status.Scheme = cfg.DarkScheme
+	}
+	status.Next = ts.nextChange(*cfg, now, status.Phase)
+	return status
+}
+
+// check applies the scheme of the phase when the phase changed.
+func (ts *ThemeScheduler) check() {
+	ts.mu.Lock()
+	cfg := ts.config
+	last := ts.phase
+	ts.mu.Unlock()
+	if cfg == nil {
+		return
+	}
+	phase := ts.phaseAt(*cfg, time.Now())
+	if phase == "" || phase == last {
+		return
+	}
+	ts.mu.Lock()
+	ts.phase = phase
+	ts.mu.Unlock()
+
+	scheme := cfg.LightScheme
+	if phase == "dark" {
+		scheme = cfg.DarkScheme
+	}
+	storage := GetStorage()
+	for _, profile := range cfg.Profiles {
+		var current string
+		storage.GetAsForProfile(profile, "scheme", &current)
+		if current == scheme || (last == "" && current != cfg.LightScheme && current != cfg.DarkScheme) {
+			continue
+		}
+		template := cfg.Template
+		if template != "" {
+			storage.SetNext(ProfileStorageKey(profile, "template"), template)
+		} else {
+			storage.GetAsForProfile(profile, "template", &template)
+		}
+		storage.SetNext(ProfileStorageKey(profile, "scheme"), scheme)
+		Logger("themes").Info("scheduled scheme switch", "profile", profile, "phase", phase, "scheme", scheme)
+		GetWSManager().Broadcast(map[string]interface{}{
+			"type":     "theme-change",
+			"profile":  profile,
+			"template": template,
+			"scheme":   scheme,
+			"phase":    phase,
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timelineFile holds timeline events and the last observed state across restarts.
+const timelineFile = "timeline.json"
+
+// maxTimelineEvents caps the number of events kept on the timeline.
+const maxTimelineEvents = 500
+
+// configEditCoalesce is the window in which repeated edits of the same key are merged.
+const configEditCoalesce = time.Minute
+
+// Timeline event sources.
+const (
+	TimelineSourceMonitor  = "monitor"
+	TimelineSourceIP       = "ip"
+	TimelineSourceHardware = "hardware"
+	TimelineSourceReboot   = "reboot"
+	TimelineSourceIncident = "incident"
+	TimelineSourceConfig   = "config"
+	TimelineSourceWebhook  = "webhook"
+	TimelineSourcePower    = "power"
+	TimelineSourceExposure = "exposure"
+)
+
+// timelineConfigKeys are the storage keys whose edits are recorded on the timeline.
+var timelineConfigKeys = map[string]bool{
+	"layoutConfig":     true,
+	"modulePrefs":      true,
+	"githubModules":    true,
+	"rssModules":       true,
+	"diskModules":      true,
+	"monitors":         true,
+	"snmpQueries":      true,
+	"speedplaneConfig": true,
+	"dnsplaneConfig":   true,
+	"quicklinks":       true,
+	icsCalendarsKey:    true,
+}
+
+// TimelineEvent represents a single entry on the timeline.
+type TimelineEvent struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	Title    string    `json:"title"`
+	Detail   string    `json:"detail,omitempty"`
+	Severity string    `json:"severity"` // info, warning, critical, ok
+}
+
+// timelineState is the on-disk representation of the timeline.
+type timelineState struct {
+	Events   []TimelineEvent   `json:"events"`
+	PublicIP string            `json:"publicIP,omitempty"`
+	Hardware map[string]string `json:"hardware,omitempty"`
+}
+
+// MonitorState is the last known result of a monitor.
+type MonitorState struct {
+	Key     string    `json:"key"`
+	Name    string    `json:"name"`
+	Up      bool      `json:"up"`
+	Error   string    `json:"error,omitempty"`
+	Checked time.Time `json:"checked"`
+}
+
+// Timeline aggregates events from all subsystems into a chronological feed.
+type Timeline struct {
+	mu       sync.Mutex
+	state    timelineState
+	monitors map[string]*MonitorState
+	nextID   int64
+	loaded   bool
+}
+
+// Global timeline instance
+var timeline = &Timeline{monitors: make(map[string]*MonitorState)}
+
+// GetTimeline returns the global timeline instance.
+func GetTimeline() *Timeline {
+	return timeline
+}
+
+// load reads the timeline file. Caller must hold mu.
+func (t *Timeline) load() {
+	if t.loaded {
+		return
+	}
+	t.loaded = true
+	if data, err := os.ReadFile(timelineFile); err == nil {
+		if err := json.Unmarshal(data, &t.state); err != nil {
+			GetDebugLogger().Logf("timeline", "failed to parse %s: %v", timelineFile, err)
+			t.state = timelineState{}
+		}
+	}
+	t.nextID = time.Now().UnixNano()
+}
+
+// save writes the timeline file. Caller must hold mu.
+func (t *Timeline) save() {
+	data, err := json.MarshalIndent(t.state, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(timelineFile, data, 0644); err != nil {
+		GetDebugLogger().Logf("timeline", "failed to write %s: %v", timelineFile, err)
+	}
+}
+
+// add appends an event. Caller must hold mu.
+func (t *Timeline) add(ev TimelineEvent) TimelineEvent {
+	t.load()
+	t.nextID++
+	ev.ID = strconv.FormatInt(t.nextID, 36)
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	if ev.Severity == "" {
+		ev.Severity = "info"
+	}
+	t.state.Events = append(t.state.Events, ev)
+	if len(t.state.Events) > maxTimelineEvents {
+		t.state.Events = t.state.Events[len(t.state.Events)-maxTimelineEvents:]
+	}
+	t.save()
+	GetDebugLogger().Logf("timeline", "[%s] %s", ev.Source, ev.Title)
+	return ev
+}
+
+// Add records an event on the timeline and returns it with its assigned ID.
+func (t *Timeline) Add(ev TimelineEvent) TimelineEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.add(ev)
+}
+
+// Events returns events newest first, optionally filtered by time and source.
+func (t *Timeline) Events(since time.Time, sources []string, limit int) []TimelineEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.load()
+
+	sourceSet := make(map[string]bool, len(sources))
+	for _, s := range sources {
+		sourceSet[s] = true
+	}
+
+	result := []TimelineEvent{}
+	for i := len(t.state.Events) - 1; i >= 0; i-- {
+		ev := t.state.Events[i]
+		if !since.IsZero() && ev.Time.Before(since) {
+			continue
+		}
+		if len(sourceSet) > 0 && !sourceSet[ev.Source] {
+			continue
+		}
+		result = append(result, ev)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result
+}
+
+// Prune removes events older than before and returns how many were removed.
+func (t *Timeline) Prune(before time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.load()
+	kept := t.state.Events[:0]
+	for _, ev := range t.state.Events {
+		if !ev.Time.Before(before) {
+			kept = append(kept, ev)
+		}
+	}
+	removed := len(t.state.Events) - len(kept)
+	t.state.Events = kept
+	if removed > 0 {
+		t.save()
+	}
+	return removed
+}
+
+// Len returns the number of events on the timeline.
+func (t *Timeline) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.load()
+	return len(t.state.Events)
+}
+
+// RecordMonitorState tracks a monitor result and records up/down transitions.
+// The first observation of a monitor only records its state.
+func (t *Timeline) RecordMonitorState(key, name string, up bool, errMsg string) {
+	if name == "" {
+		name = key
+	}
+	t.mu.Lock()
+	prev, seen := t.monitors[key]
+	t.monitors[key] = &MonitorState{Key: key, Name: name, Up: up, Error: errMsg, Checked: time.Now()}
+	if !seen || prev.Up == up {
+		t.mu.Unlock()
+		return
+	}
+	ev := TimelineEvent{Source: TimelineSourceMonitor, Title: name + " is back up", Severity: "ok"}
+	if !up {
+		ev.Title = name + " went down"
+		ev.Detail = errMsg
+		ev.Severity = "critical"
+	}
+	t.add(ev)
+	t.mu.Unlock()
+
+	GetAlertManager().NotifyMonitorChange(key, name, up, errMsg)
+}
+
+// MonitorStates returns the last known state of every monitor checked since startup,
+// down monitors first.
+func (t *Timeline) MonitorStates() []MonitorState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	states := make([]MonitorState, 0, len(t.monitors))
+	for _, s := range t.monitors {
+		states = append(states, *s)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Up != states[j].Up {
+			return !states[i].Up
+		}
+		return states[i].Name < states[j].Name
+	})
+	return states
+}
+
+// RecordPublicIP records a change of the public IP address.
+func (t *Timeline) RecordPublicIP(ip string) {
+	if ip == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.load()
+
+	prev := t.state.PublicIP
+	if prev == ip {
+		return
+	}
+	t.state.PublicIP = ip
+	if prev == "" {
+		t.save()
+		return
+	}
+	t.add(TimelineEvent{
+		Source:   TimelineSourceIP,
+		Title:    "Public IP changed to " + ip,
+		Detail:   "Previous address: " + prev,
+		Severity: "warning",
+	})
+}
+
+// RecordConfigEdit records an edit of a configuration storage key.
+// Repeated edits of the same key within a minute are merged into one event.
+func (t *Timeline) RecordConfigEdit(key string) {
+	_, base := ParseProfileStorageKey(key)
+	if _, isPage := LayoutPageOfKey(base); !timelineConfigKeys[base] && !isPage {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.load()
+
+	now := time.Now()
+	for i := len(t.state.Events) - 1; i >= 0; i-- {
+		ev := &t.state.Events[i]
+		if now.Sub(ev.Time) > configEditCoalesce {
+			break
+		}
+		if ev.Source == TimelineSourceConfig && ev.Detail == key {
+			ev.Time = now
+			t.save()
+			return
+		}
+	}
+	t.add(TimelineEvent{
+		Source: TimelineSourceConfig,
+		Title:  "Configuration changed",
+		Detail: key,
+	})
+}
+
+// CheckHardware compares the current hardware inventory with the last one seen
+// and records any differences.
+func (t *Timeline) CheckHardware(ctx context.Context) {
+	current := hardwareSnapshot(ctx)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.load()
+
+	prev := t.state.Hardware
+	t.state.Hardware = current
+	if prev == nil {
+		t.save()
+		return
+	}
+
+	var changes []string
+	for key, value := range current {
+		if old, ok := prev[key]; ok && old != value {
+			changes = append(changes, key+": "+old+" → "+value)
+		}
+	}
+	if len(changes) == 0 {
+		t.save()
+		return
+	}
+	t.add(TimelineEvent{
+		Source:   TimelineSourceHardware,
+		Title:    "Hardware changed",
+		Detail:   strings.Join(changes, "; "),
+		Severity: "warning",
+	})
+}
+
+// hardwareSnapshot returns a flat description of the host hardware used for change detection.
+func hardwareSnapshot(ctx context.Context) map[string]string {
+	snapshot := make(map[string]string)
+
+	cpu := GetCPUDetails(ctx)
+	snapshot["cpu"] = cpu.Name
+	snapshot["cores"] = strconv.Itoa(cpu.VirtualCores)
+
+	if ram := GetSMBIOSRAMInfo(ctx); ram.Error == "" {
+		snapshot["ram"] = ram.TotalSizeString
+		snapshot["ramModules"] = strconv.Itoa(len(ram.Modules))
+	}
+	if fw := GetSMBIOSFirmwareInfo(ctx); fw.Error == "" {
+		snapshot["firmware"] = strings.TrimSpace(fw.Vendor + " " + fw.Version)
+	}
+	if sys := GetSMBIOSSystemInfo(ctx); sys.Error == "" {
+		snapshot["system"] = strings.TrimSpace(sys.Manufacturer + " " + sys.ProductName)
+	}
+	if bb := GetSMBIOSBaseboardInfo(ctx); bb.Error == "" {
+		snapshot["baseboard"] = strings.TrimSpace(bb.Manufacturer + " " + bb.Product + " " + bb.SerialNumber)
+	}
+
+	return snapshot
+}
@@ -0,0 +1,23 @@
+package api
+
+import "testing"
+
+// TestTimelineConfigKeys checks that the keys recorded on the timeline are storage keys the
+// dashboard writes, so a misspelt key does not silently drop its edits.
+func TestTimelineConfigKeys(t *testing.T) {
+	known := map[string]bool{icsCalendarsKey: true}
+	for key := range profileScopedKeys {
+		known[key] = true
+	}
+	for _, key := range moduleInstanceStores {
+		known[key] = true
+	}
+	for _, schema := range ModuleSchemas() {
+		known[schema.StorageKey] = true
+	}
+	for key := range timelineConfigKeys {
+		if !known[key] {
+			t.Errorf("timeline key %q is not a storage key", key)
+		}
+	}
+}
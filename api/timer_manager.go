@@ -58,11 +58,13 @@ func (tm *TimerManager) Start() {
 			case <-tm.stopCh:
 				return
 			case <-ticker.C:
-				tm.checkTimers()
+				guardLoop("timer", tm.checkTimers)
 			case <-prefTicker.C:
-				tm.loadPreferences()
-				// Also update debug preferences periodically
-				GetDebugLogger().UpdatePrefs()
+				guardLoop("timer", func() {
+					tm.loadPreferences()
+					// Also update debug preferences periodically
+					GetDebugLogger().UpdatePrefs()
+				})
 			}
 		}
 }
@@ -78,6 +80,12 @@ func (tm *TimerManager) Stop() {
 	close(tm.stopCh)
 }
 
+// Reload reads the module refresh intervals from the preferences now rather than at the
+// next periodic check.
+func (tm *TimerManager) Reload() {
+	tm.loadPreferences()
+}
+
 // loadPreferences loads module preferences from storage and updates timers
 func (tm *TimerManager) loadPreferences() {
 	storage := GetStorage()
@@ -100,6 +108,16 @@ func (tm *TimerManager) loadPreferences() {
 
 	// Get module metadata to map module keys to timer keys
 	metadata := GetModuleMetadata()
+	typeEnabled := func(moduleType string) bool {
+		if prefMap, ok := prefs[moduleType].(map[string]interface{}); ok {
+			if enabledVal, ok := prefMap["enabled"].(bool); ok {
+				return enabledVal
+			}
+		}
+		return metadata[moduleType].Enabled
+	}
+	// Instances with an interval of their own get a timer keyed by their ID
+	instanceTimers := make(map[string]bool)
 
 	// Update timers based on preferences
 	for moduleKey, prefData := range prefs {
@@ -109,15 +127,24 @@ func (tm *TimerManager) loadPreferences() {
 		}
 
 		// Find the timer key for this module
-		modMeta, exists := metadata[moduleKey]
+		moduleType := ModuleType(moduleKey)
+		modMeta, exists := metadata[moduleType]
 		if !exists || !modMeta.HasTimer || modMeta.TimerKey == "" {
 			continue
 		}
 
 		timerKey := modMeta.TimerKey
 		enabled := true
+		if isModuleInstanceID(moduleKey) {
+			if _, ok := prefMap["interval"].(float64); !ok || !modMeta.Instances {
+				continue
+			}
+			timerKey = moduleKey
+			instanceTimers[moduleKey] = true
+			enabled = typeEnabled(moduleType)
+		}
 		if enabledVal, ok := prefMap["enabled"].(bool); ok {
-			enabled = enabledVal
+			enabled = enabled && enabledVal
 		}
 
 		interval := int64(modMeta.DefaultInterval)
@@ -145,6 +172,13 @@ func (tm *TimerManager) loadPreferences() {
 		}
 	}
 
+	// Drop the timers of instances that no longer have an interval of their own
+	for timerKey := range tm.timers {
+		if isModuleInstanceID(timerKey) && !instanceTimers[timerKey] {
+			delete(tm.timers, timerKey)
+		}
+	}
+
 	// Also add any timers from metadata that don't have preferences yet
 	for moduleKey, modMeta := range metadata {
 		if !modMeta.HasTimer || modMeta.TimerKey == "" {
@@ -227,8 +261,8 @@ func (tm *TimerManager) checkTimers() {
 		intervalDuration := time.Duration(timer.Interval) * time.Second
 
 		if elapsed >= intervalDuration {
-			// Send refresh notification via WebSocket
-			wsManager.Broadcast(map[string]interface{}{
+			// Send refresh notification to clients subscribed to the module's topic
+			wsManager.BroadcastTopic(TopicForTimer(timerKey), map[string]interface{}{
 				"type":      "refresh",
 				"module":    timerKey,
 				"timestamp": now.Unix(),
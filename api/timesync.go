@@ -0,0 +1,158 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockSkewThreshold is the offset beyond which the clock is flagged as skewed.
+// TLS validation and calendar logic start misbehaving well before this, but
+// smaller offsets are normal while an NTP client is still converging.
+const clockSkewThreshold = 500 * time.Millisecond
+
+// TimeSyncInfo contains NTP synchronization state for the host.
+type TimeSyncInfo struct {
+	Source           string  `json:"source"`
+	Synchronized     bool    `json:"synchronized"`
+	NTPEnabled       bool    `json:"ntpEnabled"`
+	Server           string  `json:"server,omitempty"`
+	Stratum          int     `json:"stratum,omitempty"`
+	OffsetMs         float64 `json:"offsetMs"`
+	LastOffsetMs     float64 `json:"lastOffsetMs,omitempty"`
+	DriftPPM         float64 `json:"driftPpm"`
+	RootDelayMs      float64 `json:"rootDelayMs,omitempty"`
+	RootDispersionMs float64 `json:"rootDispersionMs,omitempty"`
+	LeapStatus       string  `json:"leapStatus,omitempty"`
+	LastSync         string  `json:"lastSync,omitempty"`
+	Timezone         string  `json:"timezone,omitempty"`
+	Skewed           bool    `json:"skewed"`
+	Warning          string  `json:"warning,omitempty"`
+	Error            string  `json:"error,omitempty"`
+}
+
+// GetTimeSyncStatus reports NTP status using chrony, falling back to timedatectl.
+func GetTimeSyncStatus(ctx context.Context) TimeSyncInfo {
+	info := TimeSyncInfo{Source: "none"}
+
+	if runtime.GOOS != "linux" {
+		info.Error = "time sync status is not available on " + runtime.GOOS
+		return info
+	}
+
+	// timedatectl provides the enabled/synchronized flags even when chrony is in use
+	timedatectlErr := readTimedatectl(ctx, &info)
+
+	if err := readChronyTracking(ctx, &info); err == nil {
+		info.Source = "chrony"
+	} else if timedatectlErr == nil {
+		info.Source = "timedatectl"
+		readTimesyncd(ctx, &info)
+	} else {
+		info.Error = "neither chronyc nor timedatectl is available"
+		return info
+	}
+
+	if math.Abs(info.OffsetMs) > float64(clockSkewThreshold/time.Millisecond) {
+		info.Skewed = true
+		info.Warning = "clock is off by " + Format1(info.OffsetMs) + "ms"
+	} else if !info.Synchronized {
+		info.Warning = "clock is not synchronized"
+	}
+
+	GetDebugLogger().Logf("timesync", "source=%s synced=%v offset=%.3fms drift=%.3fppm", info.Source, info.Synchronized, info.OffsetMs, info.DriftPPM)
+	return info
+}
+
+// readChronyTracking parses the CSV output of `chronyc -c tracking`.
+func readChronyTracking(ctx context.Context, info *TimeSyncInfo) error {
+	out, err := exec.CommandContext(ctx, "chronyc", "-c", "tracking").Output()
+	if err != nil {
+		return err
+	}
+
+	fields := strings.Split(strings.TrimSpace(string(out)), ",")
+	if len(fields) < 14 {
+		return fmt.Errorf("unexpected chronyc output")
+	}
+
+	num := func(i int) float64 {
+		v, _ := strconv.ParseFloat(fields[i], 64)
+		return v
+	}
+
+	info.Server = fields[1]
+	info.Stratum = int(num(2))
+	if refTime := num(3); refTime > 0 {
+		sec, frac := math.Modf(refTime)
+		info.LastSync = time.Unix(int64(sec), int64(frac*1e9)).Format(time.RFC3339)
+	}
+	info.OffsetMs = num(4) * 1000
+	info.LastOffsetMs = num(5) * 1000
+	info.DriftPPM = num(7)
+	info.RootDelayMs = num(10) * 1000
+	info.RootDispersionMs = num(11) * 1000
+	info.LeapStatus = fields[13]
+	info.Synchronized = info.LeapStatus != "Not synchronised" && info.Stratum > 0 && info.Stratum < 16
+	return nil
+}
+
+// readTimedatectl parses `timedatectl show` for the NTP and timezone properties.
+func readTimedatectl(ctx context.Context, info *TimeSyncInfo) error {
+	props, err := timedatectlProperties(ctx, "show")
+	if err != nil {
+		return err
+	}
+	info.NTPEnabled = props["NTP"] == "yes"
+	info.Synchronized = props["NTPSynchronized"] == "yes"
+	info.Timezone = props["Timezone"]
+	return nil
+}
+
+// readTimesyncd fills in server details reported by systemd-timesyncd.
+func readTimesyncd(ctx context.Context, info *TimeSyncInfo) {
+	props, err := timedatectlProperties(ctx, "show-timesync", "--all")
+	if err != nil {
+		return
+	}
+	info.Server = props["ServerName"]
+
+	// NTPMessage={ ... Stratum=2, ... Offset=+1.234ms, ... }
+	msg := props["NTPMessage"]
+	for _, part := range strings.Split(strings.Trim(msg, "{} "), ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "Stratum":
+			info.Stratum, _ = strconv.Atoi(v)
+		case "Offset":
+			if d, err := time.ParseDuration(strings.TrimPrefix(v, "+")); err == nil {
+				info.OffsetMs = float64(d) / float64(time.Millisecond)
+			}
+		}
+	}
+}
+
+// timedatectlProperties runs timedatectl with the given arguments and returns its key=value output.
+func timedatectlProperties(ctx context.Context, args ...string) (map[string]string, error) {
+	out, err := exec.CommandContext(ctx, "timedatectl", args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		if k, v, ok := strings.Cut(scanner.Text(), "="); ok {
+			props[k] = v
+		}
+	}
+	return props, nil
+}
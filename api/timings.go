@@ -0,0 +1,186 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache states of a timing.
+const (
+	TimingHit  = "hit"  // Answered from a cache
+	TimingMiss = "miss" // Fetched because the cache had nothing fresh
+)
+
+// Timing is how long one upstream fetch of a request took.
+type Timing struct {
+	Name  string  `json:"name"` // e.g. "weather", "github", "ics"
+	Ms    float64 `json:"ms"`
+	Cache string  `json:"cache,omitempty"` // "hit" or "miss", empty for uncached fetches
+}
+
+// RequestTimings collects the upstream fetches of a request for the Server-Timing header
+// and the "timings" block of the response.
+type RequestTimings struct {
+	mu      sync.Mutex
+	start   time.Time
+	entries []Timing
+}
+
+// timingsKey is the context key of the request's timings.
+type timingsKey struct{}
+
+// RecordTiming adds an upstream fetch to the timings of the request ctx belongs to. It does
+// nothing outside a request.
+func RecordTiming(ctx context.Context, name string, d time.Duration, cache string) {
+	rt, ok := ctx.Value(timingsKey{}).(*RequestTimings)
+	if !ok {
+		return
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.entries = append(rt.entries, Timing{Name: name, Ms: math.Round(float64(d)/float64(time.Millisecond)*10) / 10, Cache: cache})
+}
+
+// StartTiming starts timing an upstream fetch; the returned function records it with its
+// cache state.
+func StartTiming(ctx context.Context, name string) func(cache string) {
+	start := time.Now()
+	return func(cache string) {
+		RecordTiming(ctx, name, time.Since(start), cache)
+	}
+}
+
+// snapshot returns the fetches so far and the time since the request started.
+func (rt *RequestTimings) snapshot() ([]Timing, float64) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	total := math.Round(float64(time.Since(rt.start))/float64(time.Millisecond)*10) / 10
+	return append([]Timing(nil), rt.entries...), total
+}
+
+// header formats the timings as a Server-Timing header value.
+func (rt *RequestTimings) header() string {
+	entries, total := rt.snapshot()
+	parts := make([]string, 0, len(entries)+1)
+	for _, t := range entries {
+		part := fmt.Sprintf("%s;dur=%g", t.Name, t.Ms)
+		if t.Cache != "" {
+			part += `;desc="` + t.Cache + `"`
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(append(parts, fmt.Sprintf("total;dur=%g", total)), ", ")
+}
+
+// timingWriter sets the Server-Timing header before the response is written.
+type timingWriter struct {
+	http.ResponseWriter
+	timings *RequestTimings
+	written bool
+}
+
+func (tw *timingWriter) WriteHeader(status int) {
+	if !tw.written {
+		tw.written = true
+		tw.Header().Set("Server-Timing", tw.timings.header())
+	}
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *timingWriter) Write(p []byte) (int, error) {
+	if !tw.written {
+		tw.WriteHeader(http.StatusOK)
+	}
+	return tw.ResponseWriter.Write(p)
+}
+
+func (tw *timingWriter) Flush() {
+	if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (tw *timingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := tw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijacking not supported")
+	}
+	return h.Hijack()
+}
+
+func (tw *timingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// WithTimings times the upstream fetches of API requests. With requestLog.serverTiming
+// responses carry a Server-Timing header; ?timings=1 adds it and a "timings" block with
+// the total and every fetch to JSON responses.
+func WithTimings(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		block := r.URL.Query().Get("timings") == "1"
+		if !strings.HasPrefix(r.URL.Path, "/api/") || (!block && !requestStats.Config().ServerTiming) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rt := &RequestTimings{start: time.Now()}
+		r = r.WithContext(context.WithValue(r.Context(), timingsKey{}, rt))
+		tw := &timingWriter{ResponseWriter: w, timings: rt}
+		if !block {
+			next.ServeHTTP(tw, r)
+			return
+		}
+
+		// The response is held to add the block
+		held := &offlineRecorder{header: make(http.Header)}
+		next.ServeHTTP(held, r)
+		var fields map[string]json.RawMessage
+		if !strings.HasPrefix(held.header.Get("Content-Type"), "application/json") || json.Unmarshal(held.body.Bytes(), &fields) != nil || fields == nil {
+			held.flush(tw)
+			return
+		}
+		entries, total := rt.snapshot()
+		if entries == nil {
+			entries = []Timing{}
+		}
+		fields["timings"], _ = json.Marshal(map[string]any{"totalMs": total, "fetches": entries})
+		for k, v := range held.header {
+			w.Header()[k] = v
+		}
+		w.Header().Del("Content-Length")
+		if held.status != 0 && held.status != http.StatusOK {
+			tw.WriteHeader(held.status)
+		}
+		WriteJSON(tw, fields)
+	})
+}
+
+// timedTransport records every round trip of a client as an upstream fetch.
+type timedTransport struct {
+	name string
+	base http.RoundTripper
+}
+
+// TimedTransport wraps base (nil for http.DefaultTransport) so requests made with a
+// request's context show up in its timings under name.
+func TimedTransport(name string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &timedTransport{name: name, base: base}
+}
+
+func (t *timedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	done := StartTiming(req.Context(), t.name)
+	resp, err := t.base.RoundTrip(req)
+	done("")
+	return resp, err
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Default paths of the generated self-signed certificate.
+const (
+	DefaultTLSCertFile = "homepage.crt"
+	DefaultTLSKeyFile  = "homepage.key"
+)
+
+// selfSignedValidity is how long a generated certificate is valid.
+const selfSignedValidity = 825 * 24 * time.Hour
+
+// TLSConfig configures the HTTPS listener.
+type TLSConfig struct {
+	Cert string `json:"cert,omitempty"` // PEM certificate (chain) path
+	Key  string `json:"key,omitempty"`  // PEM private key path
+	// SelfSigned generates a certificate at cert/key (default homepage.crt/homepage.key)
+	// on first run and when it has expired
+	SelfSigned bool        `json:"selfSigned,omitempty"`
+	ACME       *ACMEConfig `json:"acme,omitempty"`
+}
+
+// ACMEConfig obtains certificates from Let's Encrypt for public domains.
+type ACMEConfig struct {
+	Domains  []string `json:"domains"`
+	Email    string   `json:"email,omitempty"`
+	CacheDir string   `json:"cacheDir,omitempty"` // Default: autocert-cache
+	// HTTPAddr serves HTTP-01 challenges and redirects to https (e.g. ":80"). Without it
+	// only TLS-ALPN-01 is used, which needs the dashboard to be reachable on port 443.
+	HTTPAddr string `json:"httpAddr,omitempty"`
+}
+
+// Validate checks the TLS configuration.
+func (c TLSConfig) Validate() error {
+	if c.ACME != nil {
+		if c.Cert != "" || c.Key != "" || c.SelfSigned {
+			return fmt.Errorf("tls: acme cannot be combined with cert/key or selfSigned")
+		}
+		if len(c.ACME.Domains) == 0 {
+			return fmt.Errorf("tls.acme: at least one domain is required")
+		}
+		return nil
+	}
+	if (c.Cert == "") != (c.Key == "") {
+		return fmt.Errorf("tls: cert and key must both be set")
+	}
+	if c.Cert == "" && !c.SelfSigned {
+		return fmt.Errorf("tls: set cert and key, selfSigned or acme")
+	}
+	return nil
+}
+
+// Paths returns the certificate and key paths, using the defaults for self-signed certificates.
+func (c TLSConfig) Paths() (string, string) {
+	cert, key := c.Cert, c.Key
+	if cert == "" {
+		cert = DefaultTLSCertFile
+	}
+	if key == "" {
+		key = DefaultTLSKeyFile
+	}
+	return cert, key
+}
+
+// ServerTLS prepares the certificates and returns the server TLS configuration. For ACME
+// it also returns the handler for HTTP-01 challenges, to be served on ACMEConfig.HTTPAddr.
+func (c TLSConfig) ServerTLS() (*tls.Config, http.Handler, error) {
+	if c.ACME != nil {
+		cacheDir := c.ACME.CacheDir
+		if cacheDir == "" {
+			cacheDir = "autocert-cache"
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(cacheDir),
+			HostPolicy: autocert.HostWhitelist(c.ACME.Domains...),
+			Email:      c.ACME.Email,
+		}
+		return m.TLSConfig(), m.HTTPHandler(nil), nil
+	}
+
+	certFile, keyFile := c.Paths()
+	if c.SelfSigned {
+		if err := EnsureSelfSignedCert(certFile, keyFile); err != nil {
+			return nil, nil, err
+		}
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tls: %w", err)
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}, nil, nil
+}
+
+// EnsureSelfSignedCert generates a self-signed certificate for the host name, localhost and
+// the local IP addresses if the files do not exist or the certificate has expired.
+func EnsureSelfSignedCert(certFile, keyFile string) error {
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil && time.Now().Before(leaf.NotAfter) {
+			return nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("tls: existing certificate is unusable: %w", err)
+	}
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	hostname := MustHostname()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: hostname, Organization: []string{"Homepage Dashboard"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{hostname, "localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && !ipNet.IP.IsLinkLocalUnicast() {
+				template.IPAddresses = append(template.IPAddresses, ipNet.IP)
+			}
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return err
+	}
+	GetDebugLogger().Logf("tls", "generated self-signed certificate %s for %s", certFile, hostname)
+	return nil
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -12,7 +13,8 @@ type Todo struct {
 	Title     string `json:"title"`
 	Completed bool   `json:"completed"`
 	Priority  string `json:"priority,omitempty"` // 'low', 'medium', 'high'
-	DueDate   string `json:"dueDate,omitempty"` // YYYY-MM-DD
+	DueDate   string `json:"dueDate,omitempty"`  // YYYY-MM-DD
+	Source    string `json:"source,omitempty"`   // ICS calendar of an imported task, which is read-only
 }
 
 // TodoProcessed represents a processed todo with formatted date.
@@ -34,37 +36,8 @@ func ProcessTodos(todos []Todo, count int, includeCompleted bool, preserveOrder
 	}
 
 	if !preserveOrder {
-		// Sort by: priority (high > medium > low), then due date (earliest first)
-		priorityOrder := map[string]int{
-			"high":   3,
-			"medium": 2,
-			"low":    1,
-		}
-
 		sort.Slice(filtered, func(i, j int) bool {
-			a := filtered[i]
-			b := filtered[j]
-
-			// Priority first
-			aPriority := priorityOrder[a.Priority]
-			bPriority := priorityOrder[b.Priority]
-			if aPriority != bPriority {
-				return aPriority > bPriority
-			}
-
-			// Then due date (earliest first)
-			if a.DueDate != "" && b.DueDate != "" {
-				return a.DueDate < b.DueDate
-			}
-			if a.DueDate != "" {
-				return true
-			}
-			if b.DueDate != "" {
-				return false
-			}
-
-			// Finally by creation order (ID contains timestamp)
-			return a.ID < b.ID
+			return compareTodos(filtered[i], filtered[j]) < 0
 		})
 	}
 
@@ -77,7 +50,7 @@ func ProcessTodos(todos []Todo, count int, includeCompleted bool, preserveOrder
 	result := make([]TodoProcessed, len(filtered))
 	for i, todo := range filtered {
 		result[i] = TodoProcessed{
-			Todo:            todo,
+			Todo:             todo,
 			FormattedDueDate: FormatTodoDate(todo.DueDate),
 		}
 	}
@@ -85,6 +58,37 @@ func ProcessTodos(todos []Todo, count int, includeCompleted bool, preserveOrder
 	return result
 }
 
+// todoPriorityOrder ranks the priorities, unset lowest.
+var todoPriorityOrder = map[string]int{
+	"high":   3,
+	"medium": 2,
+	"low":    1,
+}
+
+// compareTodos orders todos by priority (high > medium > low), then due date (earliest
+// first, undated last), then id.
+func compareTodos(a, b Todo) int {
+	// Priority first
+	if pa, pb := todoPriorityOrder[a.Priority], todoPriorityOrder[b.Priority]; pa != pb {
+		return pb - pa
+	}
+
+	// Then due date (earliest first)
+	switch {
+	case a.DueDate != "" && b.DueDate != "":
+		if c := strings.Compare(a.DueDate, b.DueDate); c != 0 {
+			return c
+		}
+	case a.DueDate != "":
+		return -1
+	case b.DueDate != "":
+		return 1
+	}
+
+	// Finally by creation order (ID contains timestamp)
+	return strings.Compare(a.ID, b.ID)
+}
+
 // FormatTodoDate formats a date string for display.
 func FormatTodoDate(dateStr string) string {
 	if dateStr == "" {
@@ -119,5 +123,11 @@ func FormatTodoDate(dateStr string) string {
 		return fmt.Sprintf("In %d days", diffDays)
 	}
 
+	switch CurrentSettings().DateFormat {
+	case "iso":
+		return date.Format("2006-01-02")
+	case "dmy":
+		return date.Format("2 Jan")
+	}
 	return date.Format("Jan 2")
 }
@@ -0,0 +1,479 @@
+package api
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Character classes of generated passwords.
+const (
+	passwordLower   = "abcdefghijklmnopqrstuvwxyz"
+	passwordUpper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	passwordDigits  = "0123456789"
+	passwordSymbols = "!#$%&*+-=?@^_~.,:;"
+	// passwordAmbiguous are characters that are easily mistaken for one another
+	passwordAmbiguous = "0O1lI|.,:;"
+)
+
+// maxGeneratedPasswords caps the passwords returned by one request.
+const maxGeneratedPasswords = 20
+
+// passphraseWordlist is a list of 2048 short, common English words, so every word of a
+// passphrase adds 11 bits of entropy.
+//
+//go:embed wordlist.txt
+var passphraseWordlist string
+
+// passphraseWords is the parsed word list.
+var passphraseWords = strings.Fields(passphraseWordlist)
+
+// PasswordPolicy describes the passwords or passphrases to generate.
+type PasswordPolicy struct {
+	Mode  string `json:"mode"`  // password or passphrase
+	Count int    `json:"count"` // Number to generate, 1 to 20
+
+	// Password settings: the length and the character classes to use, at least one of each
+	Length           int  `json:"length,omitempty"` // 8 to 128, default 20
+	Lower            bool `json:"lower,omitempty"`
+	Upper            bool `json:"upper,omitempty"`
+	Digits           bool `json:"digits,omitempty"`
+	Symbols          bool `json:"symbols,omitempty"`
+	ExcludeAmbiguous bool `json:"excludeAmbiguous,omitempty"` // Leave out 0, O, 1, l, I and the like
+
+	// Passphrase settings
+	Words      int    `json:"words,omitempty"`     // 3 to 20, default 6
+	Separator  string `json:"separator,omitempty"` // Default "-"
+	Capitalize bool   `json:"capitalize,omitempty"`
+	Number     bool   `json:"number,omitempty"` // Append a digit to one of the words
+}
+
+// DefaultPasswordPolicy is a 20 character password of every character class.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{Mode: "password", Count: 1, Length: 20, Lower: true, Upper: true, Digits: true, Symbols: true, Words: 6, Separator: "-"}
+}
+
+// PasswordPolicyFromQuery reads a policy from query parameters, starting from the default
+// policy: mode, count, length, lower, upper, digits, symbols, ambiguous=0, words, separator,
+// capitalize and number.
+func PasswordPolicyFromQuery(q url.Values) (PasswordPolicy, error) {
+	p := DefaultPasswordPolicy()
+	if v := q.Get("mode"); v != "" {
+		p.Mode = v
+	}
+	ints := map[string]*int{"count": &p.Count, "length": &p.Length, "words": &p.Words}
+	for name, dst := range ints {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return p, fmt.Errorf("invalid '%s' parameter", name)
+			}
+			*dst = n
+		}
+	}
+	bools := map[string]*bool{"lower": &p.Lower, "upper": &p.Upper, "digits": &p.Digits, "symbols": &p.Symbols, "capitalize": &p.Capitalize, "number": &p.Number}
+	for name, dst := range bools {
+		if v := q.Get(name); v != "" {
+			*dst = v == "1" || v == "true"
+		}
+	}
+	if v := q.Get("ambiguous"); v != "" {
+		p.ExcludeAmbiguous = v == "0" || v == "false"
+	}
+	if q.Has("separator") {
+		p.Separator = q.Get("separator")
+	}
+	return p, p.Validate()
+}
+
+// Validate checks the policy limits.
+func (p PasswordPolicy) Validate() error {
+	if p.Count < 1 || p.Count > maxGeneratedPasswords {
+		return fmt.Errorf("count must be between 1 and %d", maxGeneratedPasswords)
+	}
+	switch p.Mode {
+	case "password":
+		if p.Length < 8 || p.Length > 128 {
+			return errors.New("length must be between 8 and 128")
+		}
+		classes := p.classes()
+		if len(classes) == 0 {
+			return errors.New("at least one character class is required")
+		}
+		if len(classes) > p.Length {
+			return errors.New("length is shorter than the number of character classes")
+		}
+	case "passphrase":
+		if p.Words < 3 || p.Words > 20 {
+			return errors.New("words must be between 3 and 20")
+		}
+		if len(p.Separator) > 3 {
+			return errors.New("separator must be at most 3 characters")
+		}
+	default:
+		return errors.New("mode must be password or passphrase")
+	}
+	return nil
+}
+
+// classes returns the enabled character classes.
+func (p PasswordPolicy) classes() []string {
+	var classes []string
+	for _, c := range []struct {
+		on    bool
+		chars string
+	}{{p.Lower, passwordLower}, {p.Upper, passwordUpper}, {p.Digits, passwordDigits}, {p.Symbols, passwordSymbols}} {
+		if !c.on {
+			continue
+		}
+		chars := c.chars
+		if p.ExcludeAmbiguous {
+			chars = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(passwordAmbiguous, r) {
+					return -1
+				}
+				return r
+			}, chars)
+		}
+		classes = append(classes, chars)
+	}
+	return classes
+}
+
+// GeneratedPasswords are passwords or passphrases with the entropy of each.
+type GeneratedPasswords struct {
+	Mode      string   `json:"mode"`
+	Passwords []string `json:"passwords"`
+	Entropy   float64  `json:"entropy"`  // Bits
+	Strength  string   `json:"strength"` // weak, fair, strong or very strong
+}
+
+// GeneratePasswords generates passwords or diceware-style passphrases from a cryptographic
+// random source.
+func GeneratePasswords(p PasswordPolicy) (GeneratedPasswords, error) {
+	if err := p.Validate(); err != nil {
+		return GeneratedPasswords{}, err
+	}
+	result := GeneratedPasswords{Mode: p.Mode, Passwords: make([]string, 0, p.Count)}
+	for range p.Count {
+		var s string
+		var err error
+		if p.Mode == "passphrase" {
+			s, err = generatePassphrase(p)
+		} else {
+			s, err = generatePassword(p)
+		}
+		if err != nil {
+			return GeneratedPasswords{}, err
+		}
+		result.Passwords = append(result.Passwords, s)
+	}
+
+	if p.Mode == "passphrase" {
+		result.Entropy = float64(p.Words) * math.Log2(float64(len(passphraseWords)))
+		if p.Number {
+			result.Entropy += math.Log2(10) + math.Log2(float64(p.Words))
+		}
+	} else {
+		alphabet := strings.Join(p.classes(), "")
+		result.Entropy = float64(p.Length) * math.Log2(float64(len(alphabet)))
+	}
+	result.Entropy = math.Round(result.Entropy*10) / 10
+	switch {
+	case result.Entropy < 50:
+		result.Strength = "weak"
+	case result.Entropy < 70:
+		result.Strength = "fair"
+	case result.Entropy < 100:
+		result.Strength = "strong"
+	default:
+		result.Strength = "very strong"
+	}
+	return result, nil
+}
+
+// generatePassword draws characters from the enabled classes until every class is present.
+// Redrawing keeps every valid password equally likely.
+func generatePassword(p PasswordPolicy) (string, error) {
+	classes := p.classes()
+	alphabet := strings.Join(classes, "")
+	b := make([]byte, p.Length)
+	for {
+		for i := range b {
+			n, err := randomIndex(len(alphabet))
+			if err != nil {
+				return "", err
+			}
+			b[i] = alphabet[n]
+		}
+		complete := true
+		for _, class := range classes {
+			if !strings.ContainsAny(string(b), class) {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			return string(b), nil
+		}
+	}
+}
+
+// generatePassphrase picks words from the word list.
+func generatePassphrase(p PasswordPolicy) (string, error) {
+	words := make([]string, p.Words)
+	for i := range words {
+		n, err := randomIndex(len(passphraseWords))
+		if err != nil {
+			return "", err
+		}
+		words[i] = passphraseWords[n]
+		if p.Capitalize {
+			r := []rune(words[i])
+			r[0] = unicode.ToUpper(r[0])
+			words[i] = string(r)
+		}
+	}
+	if p.Number {
+		i, err := randomIndex(len(words))
+		if err != nil {
+			return "", err
+		}
+		d, err := randomIndex(10)
+		if err != nil {
+			return "", err
+		}
+		words[i] += strconv.Itoa(d)
+	}
+	return strings.Join(words, p.Separator), nil
+}
+
+// randomIndex returns a uniformly random number in [0, n).
+func randomIndex(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+// maxHashInput caps the text accepted by the hash and encoding tool.
+const maxHashInput = 1 << 20
+
+// HashOperations are the operations of the hash and encoding tool, in the order they are
+// listed.
+var HashOperations = []string{"md5", "sha1", "sha256", "sha512", "base64", "base64-decode", "base64url", "base64url-decode", "url", "url-decode", "hex", "hex-decode"}
+
+// HashText applies a hash or encoding operation to text. Decoding operations fail on
+// malformed input or when the decoded bytes are not valid UTF-8 text.
+func HashText(op, text string) (string, error) {
+	var decoded []byte
+	var err error
+	switch op {
+	case "md5":
+		sum := md5.Sum([]byte(text))
+		return hex.EncodeToString(sum[:]), nil
+	case "sha1":
+		sum := sha1.Sum([]byte(text))
+		return hex.EncodeToString(sum[:]), nil
+	case "sha256":
+		sum := sha256.Sum256([]byte(text))
+		return hex.EncodeToString(sum[:]), nil
+	case "sha512":
+		sum := sha512.Sum512([]byte(text))
+		return hex.EncodeToString(sum[:]), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString([]byte(text)), nil
+	case "base64url":
+		return base64.RawURLEncoding.EncodeToString([]byte(text)), nil
+	case "url":
+		return url.QueryEscape(text), nil
+	case "hex":
+		return hex.EncodeToString([]byte(text)), nil
+	case "base64-decode":
+		// Accept input with or without padding
+		decoded, err = base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+		if err != nil {
+			decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(strings.TrimSpace(text), "="))
+		}
+	case "base64url-decode":
+		decoded, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(strings.TrimSpace(text), "="))
+	case "url-decode":
+		var s string
+		s, err = url.QueryUnescape(text)
+		decoded = []byte(s)
+	case "hex-decode":
+		decoded, err = hex.DecodeString(strings.TrimSpace(text))
+	default:
+		return "", fmt.Errorf("unknown operation %q", op)
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid input for %s: %w", op, err)
+	}
+	if !utf8.Valid(decoded) {
+		return "", errors.New("decoded data is binary, not text")
+	}
+	return string(decoded), nil
+}
+
+// timestampLayouts are the date and time formats ConvertTimestamp accepts besides Unix
+// timestamps, tried in order.
+var timestampLayouts = []struct {
+	name   string
+	layout string
+}{
+	{"RFC 3339", time.RFC3339Nano},
+	{"RFC 1123", time.RFC1123Z},
+	{"RFC 1123", time.RFC1123},
+	{"RFC 850", time.RFC850},
+	{"ANSI C", time.ANSIC},
+	{"date and time", "2006-01-02 15:04:05"},
+	{"date and time", "2006-01-02T15:04:05"},
+	{"date and time", "2006-01-02 15:04"},
+	{"date", "2006-01-02"},
+}
+
+// ZonedTime is a time shown in a time zone.
+type ZonedTime struct {
+	Zone   string `json:"zone"`
+	Time   string `json:"time"`   // RFC 3339
+	Abbr   string `json:"abbr"`   // e.g. EEST
+	Offset string `json:"offset"` // e.g. +03:00
+}
+
+// ConvertedTimestamp is a point in time in the formats the time tool shows.
+type ConvertedTimestamp struct {
+	Input     string      `json:"input"`
+	Format    string      `json:"format"` // How the input was read, e.g. "Unix milliseconds"
+	Unix      int64       `json:"unix"`
+	UnixMilli int64       `json:"unixMilli"`
+	UTC       string      `json:"utc"` // RFC 3339
+	RFC1123   string      `json:"rfc1123"`
+	Relative  string      `json:"relative"` // e.g. "3 hours ago"
+	Zones     []ZonedTime `json:"zones"`
+}
+
+// ConvertTimestamp reads a Unix timestamp in seconds, milliseconds, microseconds or
+// nanoseconds (told apart by their size), a date in one of the common formats or "now",
+// and shows it in UTC and each of zones. Dates without an offset are read in loc.
+func ConvertTimestamp(input string, loc *time.Location, zones []string, now time.Time) (ConvertedTimestamp, error) {
+	input = strings.TrimSpace(input)
+	result := ConvertedTimestamp{Input: input}
+	var t time.Time
+	if input == "" || strings.EqualFold(input, "now") {
+		t, result.Format = now, "now"
+	} else if n, err := strconv.ParseInt(input, 10, 64); err == nil {
+		abs := n
+		if abs < 0 {
+			abs = -abs
+		}
+		switch {
+		case abs >= 1e17:
+			t, result.Format = time.Unix(0, n), "Unix nanoseconds"
+		case abs >= 1e14:
+			t, result.Format = time.UnixMicro(n), "Unix microseconds"
+		case abs >= 1e11:
+			t, result.Format = time.UnixMilli(n), "Unix milliseconds"
+		default:
+			t, result.Format = time.Unix(n, 0), "Unix seconds"
+		}
+	} else if f, err := strconv.ParseFloat(input, 64); err == nil {
+		sec, frac := math.Modf(f)
+		t, result.Format = time.Unix(int64(sec), int64(frac*1e9)), "Unix seconds"
+	} else {
+		for _, l := range timestampLayouts {
+			if parsed, err := time.ParseInLocation(l.layout, input, loc); err == nil {
+				t, result.Format = parsed, l.name
+				break
+			}
+		}
+		if result.Format == "" {
+			return result, errors.New("unrecognized date; use a Unix timestamp, RFC 3339 or YYYY-MM-DD HH:MM:SS")
+		}
+	}
+
+	result.Unix = t.Unix()
+	result.UnixMilli = t.UnixMilli()
+	result.UTC = t.UTC().Format(time.RFC3339Nano)
+	result.RFC1123 = t.UTC().Format(time.RFC1123)
+	result.Relative = relativeTime(t, now)
+	result.Zones = make([]ZonedTime, 0, len(zones)+1)
+	for _, name := range append([]string{loc.String()}, zones...) {
+		zl, err := time.LoadLocation(name)
+		if err != nil {
+			return result, fmt.Errorf("unknown time zone %q", name)
+		}
+		zt := t.In(zl)
+		abbr, _ := zt.Zone()
+		result.Zones = append(result.Zones, ZonedTime{Zone: name, Time: zt.Format(time.RFC3339), Abbr: abbr, Offset: zt.Format("-07:00")})
+	}
+	return result, nil
+}
+
+// relativeTime describes how far t is from now, e.g. "in 2 days" or "5 minutes ago".
+func relativeTime(t, now time.Time) string {
+	d := t.Sub(now)
+	future := d > 0
+	if d < 0 {
+		d = -d
+	}
+	var text string
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		text = plural(int(d/time.Minute), "minute")
+	case d < 48*time.Hour:
+		text = plural(int(d/time.Hour), "hour")
+	case d < 60*24*time.Hour:
+		text = plural(int(d/(24*time.Hour)), "day")
+	case d < 2*365*24*time.Hour:
+		text = plural(int(d/(30*24*time.Hour)), "month")
+	default:
+		text = plural(int(d/(365*24*time.Hour)), "year")
+	}
+	if future {
+		return "in " + text
+	}
+	return text + " ago"
+}
+
+// CronExplanation describes a cron expression and when it runs next.
+type CronExplanation struct {
+	Expression  string   `json:"expression"`
+	Description string   `json:"description"`
+	Zone        string   `json:"zone"`
+	Next        []string `json:"next"` // RFC 3339
+}
+
+// ExplainCron describes a cron expression and lists its next count runs after now in loc.
+func ExplainCron(expr string, loc *time.Location, count int, now time.Time) (CronExplanation, error) {
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		return CronExplanation{}, err
+	}
+	result := CronExplanation{Expression: schedule.Expression, Description: schedule.Describe(), Zone: loc.String(), Next: []string{}}
+	t := now.In(loc)
+	for range count {
+		if t, err = schedule.Next(t); err != nil {
+			return result, err
+		}
+		result.Next = append(result.Next, t.Format(time.RFC3339))
+	}
+	return result, nil
+}
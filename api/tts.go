@@ -0,0 +1,212 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ttsCacheTTL is how long the last generated audio is reused for the same text.
+const ttsCacheTTL = 10 * time.Minute
+
+// ErrTTSNotConfigured is returned when no text-to-speech engine is configured.
+var ErrTTSNotConfigured = errors.New("text-to-speech is not configured")
+
+// TTSConfig selects the text-to-speech engine for spoken briefings: a local command that
+// reads text on stdin and writes audio to stdout (e.g. piper or espeak-ng), or an
+// OpenAI-compatible /v1/audio/speech API (OpenAI, openedai-speech, Kokoro-FastAPI, ...).
+type TTSConfig struct {
+	Command []string `json:"command,omitempty"` // e.g. ["espeak-ng", "--stdout"]
+	URL     string   `json:"url,omitempty"`     // e.g. "http://tts.lan:8000/v1/audio/speech"
+	Model   string   `json:"model,omitempty"`   // Default: tts-1
+	Voice   string   `json:"voice,omitempty"`   // Default: alloy
+	APIKey  string   `json:"apiKey,omitempty"`
+	// APIKeyFile and APIKeyEnv read the API key from a secret file or environment variable
+	APIKeyFile string `json:"apiKeyFile,omitempty"`
+	APIKeyEnv  string `json:"apiKeyEnv,omitempty"`
+	Format     string `json:"format,omitempty"`  // Audio format: wav (default for commands) or mp3 (default for APIs)
+	Timeout    string `json:"timeout,omitempty"` // Default: 60s
+}
+
+// Validate checks that exactly one engine is set and the format and timeout are valid.
+func (c TTSConfig) Validate() error {
+	if len(c.Command) > 0 && c.URL != "" {
+		return fmt.Errorf("tts: set either command or url, not both")
+	}
+	if len(c.Command) == 0 && c.URL == "" {
+		return fmt.Errorf("tts: command or url is required")
+	}
+	if c.URL != "" && !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+		return fmt.Errorf("tts: url must start with http:// or https://")
+	}
+	if _, ok := ttsContentTypes[c.format()]; !ok {
+		return fmt.Errorf("tts: format must be wav, mp3, ogg, opus, flac or aac")
+	}
+	if c.Timeout != "" {
+		if d, err := time.ParseDuration(c.Timeout); err != nil || d <= 0 {
+			return fmt.Errorf("tts: invalid timeout %q", c.Timeout)
+		}
+	}
+	return nil
+}
+
+var ttsContentTypes = map[string]string{
+	"wav":  "audio/wav",
+	"mp3":  "audio/mpeg",
+	"ogg":  "audio/ogg",
+	"opus": "audio/ogg",
+	"flac": "audio/flac",
+	"aac":  "audio/aac",
+}
+
+// format returns the audio format, defaulting by engine.
+func (c TTSConfig) format() string {
+	switch {
+	case c.Format != "":
+		return strings.ToLower(c.Format)
+	case len(c.Command) > 0:
+		return "wav"
+	}
+	return "mp3"
+}
+
+func (c TTSConfig) timeout() time.Duration {
+	if d, err := time.ParseDuration(c.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return time.Minute
+}
+
+// Speaker turns text into audio with the configured engine and caches the last result.
+type Speaker struct {
+	mu     sync.Mutex
+	config *TTSConfig
+
+	cachedText  string
+	cachedAudio []byte
+	cachedAt    time.Time
+}
+
+var speaker = &Speaker{}
+
+// GetSpeaker returns the global text-to-speech engine.
+func GetSpeaker() *Speaker {
+	return speaker
+}
+
+// Configure sets the engine.
+func (s *Speaker) Configure(cfg TTSConfig) {
+	s.mu.Lock()
+	s.config = &cfg
+	s.cachedText, s.cachedAudio = "", nil
+	s.mu.Unlock()
+}
+
+// Enabled reports whether an engine is configured.
+func (s *Speaker) Enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config != nil
+}
+
+// Synthesize returns the audio for text and its content type.
+func (s *Speaker) Synthesize(ctx context.Context, text string) ([]byte, string, error) {
+	s.mu.Lock()
+	if s.config == nil {
+		s.mu.Unlock()
+		return nil, "", ErrTTSNotConfigured
+	}
+	cfg := *s.config
+	contentType := ttsContentTypes[cfg.format()]
+	if s.cachedText == text && time.Since(s.cachedAt) < ttsCacheTTL {
+		audio := s.cachedAudio
+		s.mu.Unlock()
+		return audio, contentType, nil
+	}
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.timeout())
+	defer cancel()
+	var audio []byte
+	var err error
+	if len(cfg.Command) > 0 {
+		audio, err = synthesizeCommand(ctx, cfg, text)
+	} else {
+		audio, err = synthesizeAPI(ctx, cfg, text)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if len(audio) == 0 {
+		return nil, "", errors.New("text-to-speech engine returned no audio")
+	}
+
+	s.mu.Lock()
+	s.cachedText, s.cachedAudio, s.cachedAt = text, audio, time.Now()
+	s.mu.Unlock()
+	return audio, contentType, nil
+}
+
+// synthesizeCommand runs the command with the text on stdin and returns its stdout.
+func synthesizeCommand(ctx context.Context, cfg TTSConfig, text string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, cfg.Command[0], cfg.Command[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %v: %s", cfg.Command[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %v", cfg.Command[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// synthesizeAPI posts the text to an OpenAI-compatible speech endpoint.
+func synthesizeAPI(ctx context.Context, cfg TTSConfig, text string) ([]byte, error) {
+	apiKey, err := ResolveSecret(cfg.APIKey, cfg.APIKeyFile, cfg.APIKeyEnv)
+	if err != nil {
+		return nil, fmt.Errorf("tts: %w", err)
+	}
+	model, voice := cfg.Model, cfg.Voice
+	if model == "" {
+		model = "tts-1"
+	}
+	if voice == "" {
+		voice = "alloy"
+	}
+	body, _ := json.Marshal(map[string]string{
+		"model":           model,
+		"voice":           voice,
+		"input":           text,
+		"response_format": cfg.format(),
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("tts: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 50<<20))
+}
@@ -52,9 +52,10 @@ type HostIPInfo struct {
 
 // PublicIPInfo contains information about the public IP address.
 type PublicIPInfo struct {
-	IP    string `json:"ip"`
-	PTR   string `json:"ptr,omitempty"`
-	Error string `json:"error,omitempty"`
+	IP    string     `json:"ip"`
+	PTR   string     `json:"ptr,omitempty"`
+	Geo   *GeoIPInfo `json:"geo,omitempty"`
+	Error string     `json:"error,omitempty"`
 }
 
 // WeatherInfo contains weather data and forecast information.
@@ -65,41 +66,46 @@ type WeatherInfo struct {
 	Current  *WeatherCurrent `json:"current,omitempty"`
 	Today    *WeatherDay     `json:"today,omitempty"`
 	Tomorrow *WeatherDay     `json:"tomorrow,omitempty"`
+	Nowcast  *WeatherNowcast `json:"nowcast,omitempty"`
 	Error    string          `json:"error,omitempty"`
 }
 
 // WeatherCurrent contains current weather conditions.
 type WeatherCurrent struct {
-	Temperature       float64 `json:"temperature"`
-	TempUnit          string  `json:"tempUnit"`
-	FeelsLike         float64 `json:"feelsLike,omitempty"`
-	Humidity          float64 `json:"humidity"`
-	WindSpeed         float64 `json:"windSpeed"`
-	WindUnit          string  `json:"windUnit"`
-	WindDirection     int     `json:"windDirection,omitempty"`
-	Pressure          float64 `json:"pressure,omitempty"`
-	UVIndex           float64 `json:"uvIndex,omitempty"`
-	CloudCover        float64 `json:"cloudCover,omitempty"`
-	Visibility        float64 `json:"visibility,omitempty"`
-	DewPoint          float64 `json:"dewPoint,omitempty"`
-	PrecipitationProb float64 `json:"precipitationProb,omitempty"`
-	WeatherCode       int     `json:"weatherCode"`
-	Icon              string  `json:"icon,omitempty"`
-	IconDescription   string  `json:"iconDescription,omitempty"`
+	Temperature       float64     `json:"temperature"`
+	TempUnit          string      `json:"tempUnit"`
+	FeelsLike         float64     `json:"feelsLike,omitempty"`
+	Humidity          float64     `json:"humidity"`
+	WindSpeed         float64     `json:"windSpeed"`
+	WindUnit          string      `json:"windUnit"`
+	WindDirection     int         `json:"windDirection,omitempty"`
+	Pressure          float64     `json:"pressure,omitempty"`
+	PressureUnit      string      `json:"pressureUnit,omitempty"` // Set when not hPa
+	UVIndex           float64     `json:"uvIndex,omitempty"`
+	CloudCover        float64     `json:"cloudCover,omitempty"`
+	Visibility        float64     `json:"visibility,omitempty"`
+	VisibilityUnit    string      `json:"visibilityUnit,omitempty"` // Set when not km
+	DewPoint          float64     `json:"dewPoint,omitempty"`
+	PrecipitationProb float64     `json:"precipitationProb,omitempty"`
+	WeatherCode       int         `json:"weatherCode"`
+	Icon              string      `json:"icon,omitempty"`
+	IconDescription   string      `json:"iconDescription,omitempty"`
+	Air               *AirQuality `json:"air,omitempty"` // Air quality and pollen, nil if unavailable
 }
 
 // WeatherDay contains weather forecast for a single day.
 type WeatherDay struct {
-	TempMax           float64 `json:"tempMax"`
-	TempMin           float64 `json:"tempMin"`
-	TempUnit          string  `json:"tempUnit"`
-	PrecipitationProb float64 `json:"precipitationProb,omitempty"`
-	UVIndexMax        float64 `json:"uvIndexMax,omitempty"`
-	WeatherCode       int     `json:"weatherCode"`
-	Icon              string  `json:"icon,omitempty"`
-	IconDescription   string  `json:"iconDescription,omitempty"`
-	Sunrise           string  `json:"sunrise,omitempty"`
-	Sunset            string  `json:"sunset,omitempty"`
+	TempMax           float64     `json:"tempMax"`
+	TempMin           float64     `json:"tempMin"`
+	TempUnit          string      `json:"tempUnit"`
+	PrecipitationProb float64     `json:"precipitationProb,omitempty"`
+	UVIndexMax        float64     `json:"uvIndexMax,omitempty"`
+	WeatherCode       int         `json:"weatherCode"`
+	Icon              string      `json:"icon,omitempty"`
+	IconDescription   string      `json:"iconDescription,omitempty"`
+	Sunrise           string      `json:"sunrise,omitempty"`
+	Sunset            string      `json:"sunset,omitempty"`
+	Air               *AirQuality `json:"air,omitempty"` // Worst air quality and pollen of the day
 }
 
 // WeatherData contains parsed weather data from API responses.
@@ -109,6 +115,8 @@ type WeatherData struct {
 	Current  *WeatherCurrent
 	Today    *WeatherDay
 	Tomorrow *WeatherDay
+	Nowcast  *WeatherNowcast // Precipitation for the next two hours, nil if unavailable
+	Hourly   *WeatherHourly  // Forecast for the next 48 hours, nil if unavailable
 }
 
 // GitHubInfo contains GitHub repository information.
@@ -466,6 +474,7 @@ type Config struct {
 	Title           string
 	PublicIPTimeout time.Duration
 	Weather         WeatherConfig
+	GitHubToken     string // Used when a request carries no token
 }
 
 // WeatherConfig holds weather service configuration.
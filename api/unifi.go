@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"time"
+)
+
+// UniFiConfig configures access to a UniFi Network controller.
+type UniFiConfig struct {
+	URL          string `json:"url"` // e.g. https://192.168.1.1 (UniFi OS) or https://controller:8443
+	Username     string `json:"username"`
+	Password     string `json:"password,omitempty"`
+	PasswordFile string `json:"passwordFile,omitempty"`
+	PasswordEnv  string `json:"passwordEnv,omitempty"`
+	Site         string `json:"site,omitempty"`    // Default: default
+	UniFiOS      bool   `json:"unifiOS,omitempty"` // UniFi OS consoles (UDM, Cloud Key Gen2+) use /api/auth/login and /proxy/network
+	Insecure     bool   `json:"insecure,omitempty"`
+}
+
+// Validate checks the UniFi configuration.
+func (c UniFiConfig) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if c.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if _, err := ResolveSecret(c.Password, c.PasswordFile, c.PasswordEnv); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UniFiClient talks to the UniFi Network controller API.
+type UniFiClient struct {
+	config UniFiConfig
+	client *http.Client
+	csrf   string
+}
+
+// NewUniFiClient creates a UniFi client with its own cookie jar.
+func NewUniFiClient(cfg UniFiConfig) *UniFiClient {
+	jar, _ := cookiejar.New(nil)
+	return &UniFiClient{
+		config: cfg,
+		client: &http.Client{
+			Timeout: 15 * time.Second,
+			Jar:     jar,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.Insecure},
+			},
+		},
+	}
+}
+
+// do sends a JSON request to the controller.
+func (c *UniFiClient) do(ctx context.Context, method, path string, body any, result any) error {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.config.URL, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	if c.csrf != "" {
+		req.Header.Set("X-CSRF-Token", c.csrf)
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if token := res.Header.Get("X-CSRF-Token"); token != "" {
+		c.csrf = token
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return errors.New("unifi http status " + res.Status)
+	}
+	if result != nil {
+		return json.NewDecoder(res.Body).Decode(result)
+	}
+	return nil
+}
+
+// apiPath returns the path of a site API endpoint.
+func (c *UniFiClient) apiPath(path string) string {
+	site := c.config.Site
+	if site == "" {
+		site = "default"
+	}
+	prefix := ""
+	if c.config.UniFiOS {
+		prefix = "/proxy/network"
+	}
+	return prefix + "/api/s/" + site + path
+}
+
+// Login authenticates with the controller.
+func (c *UniFiClient) Login(ctx context.Context) error {
+	password, err := ResolveSecret(c.config.Password, c.config.PasswordFile, c.config.PasswordEnv)
+	if err != nil {
+		return err
+	}
+	path := "/api/login"
+	if c.config.UniFiOS {
+		path = "/api/auth/login"
+	}
+	if err := c.do(ctx, http.MethodPost, path, map[string]any{"username": c.config.Username, "password": password}, nil); err != nil {
+		return fmt.Errorf("unifi login: %w", err)
+	}
+	return nil
+}
+
+// SetWLANPassphrase changes the passphrase of a wireless network by its ID or SSID.
+func (c *UniFiClient) SetWLANPassphrase(ctx context.Context, wlan, passphrase string) error {
+	if err := c.Login(ctx); err != nil {
+		return err
+	}
+
+	var list struct {
+		Data []struct {
+			ID   string `json:"_id"`
+			Name string `json:"name"`
+		} `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.apiPath("/rest/wlanconf"), nil, &list); err != nil {
+		return err
+	}
+	id := ""
+	for _, w := range list.Data {
+		if w.ID == wlan || w.Name == wlan {
+			id = w.ID
+			break
+		}
+	}
+	if id == "" {
+		return fmt.Errorf("unifi: wireless network %q not found", wlan)
+	}
+	return c.do(ctx, http.MethodPut, c.apiPath("/rest/wlanconf/"+id), map[string]any{"x_passphrase": passphrase}, nil)
+}
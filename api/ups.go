@@ -0,0 +1,466 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default ports of the UPS daemons.
+const (
+	nutDefaultPort     = "3493"
+	apcupsdDefaultPort = "3551"
+)
+
+// UPSConfig configures the UPSes behind /api/ups.
+type UPSConfig struct {
+	Devices  []UPSDevice `json:"devices"`
+	Interval string      `json:"interval,omitempty"` // Poll interval, default "30s"
+}
+
+// UPSDevice is one UPS served by a NUT server (upsd) or apcupsd.
+type UPSDevice struct {
+	Name    string `json:"name,omitempty"`    // Display name, default: the NUT or apcupsd UPS name
+	Driver  string `json:"driver"`            // "nut" or "apcupsd"
+	Address string `json:"address,omitempty"` // host[:port], default localhost and the daemon's port
+	UPS     string `json:"ups,omitempty"`     // NUT UPS name, e.g. "ups" (nut only)
+}
+
+// Validate checks the configured UPSes.
+func (c UPSConfig) Validate() error {
+	if len(c.Devices) == 0 {
+		return fmt.Errorf("ups: at least one device is required")
+	}
+	seen := make(map[string]bool)
+	for i, d := range c.Devices {
+		switch d.Driver {
+		case "nut":
+			if d.UPS == "" {
+				return fmt.Errorf("ups: devices[%d]: ups (the NUT UPS name) is required", i)
+			}
+		case "apcupsd":
+		default:
+			return fmt.Errorf("ups: devices[%d]: driver must be nut or apcupsd", i)
+		}
+		if seen[d.id()] {
+			return fmt.Errorf("ups: devices[%d]: duplicate device %s", i, d.id())
+		}
+		seen[d.id()] = true
+	}
+	if c.Interval != "" {
+		if d, err := time.ParseDuration(c.Interval); err != nil || d < 5*time.Second {
+			return fmt.Errorf("ups: interval must be a duration of at least 5s")
+		}
+	}
+	return nil
+}
+
+// address returns the daemon address with the default host and port filled in.
+func (d UPSDevice) address() string {
+	port := nutDefaultPort
+	if d.Driver == "apcupsd" {
+		port = apcupsdDefaultPort
+	}
+	if d.Address == "" {
+		return net.JoinHostPort("localhost", port)
+	}
+	if _, _, err := net.SplitHostPort(d.Address); err != nil {
+		return net.JoinHostPort(d.Address, port)
+	}
+	return d.Address
+}
+
+// id identifies the device, e.g. "nut/ups@nas.lan:3493".
+func (d UPSDevice) id() string {
+	if d.Driver == "nut" {
+		return "nut/" + d.UPS + "@" + d.address()
+	}
+	return "apcupsd/" + d.address()
+}
+
+// UPSStatus is the state of one UPS. Readings the UPS does not report are omitted.
+type UPSStatus struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Driver       string    `json:"driver"`
+	Model        string    `json:"model,omitempty"`
+	Status       string    `json:"status"`          // online, battery or unknown
+	Flags        []string  `json:"flags,omitempty"` // Raw status, e.g. ["OL", "CHRG"] or ["ONBATT"]
+	OnBattery    bool      `json:"onBattery"`
+	LowBattery   bool      `json:"lowBattery"`
+	Charging     bool      `json:"charging"`
+	Charge       *float64  `json:"charge,omitempty"`       // Battery charge in percent
+	Runtime      *int64    `json:"runtime,omitempty"`      // Seconds of runtime left on battery
+	Load         *float64  `json:"load,omitempty"`         // Percent of the UPS capacity
+	InputVoltage *float64  `json:"inputVoltage,omitempty"` // Volts
+	Error        string    `json:"error,omitempty"`
+	Updated      time.Time `json:"updated"`
+}
+
+// UPSMonitor polls the configured UPSes and raises alerts when one switches to battery.
+type UPSMonitor struct {
+	mu      sync.Mutex
+	config  *UPSConfig
+	status  []UPSStatus
+	updated time.Time
+	// Last successful reading of each UPS, to detect switches across failed polls
+	known map[string]UPSStatus
+}
+
+// Global UPS monitor instance
+var upsMonitor = &UPSMonitor{known: make(map[string]UPSStatus)}
+
+// GetUPSMonitor returns the global UPS monitor instance.
+func GetUPSMonitor() *UPSMonitor {
+	return upsMonitor
+}
+
+// Configure sets the UPSes to poll.
+func (um *UPSMonitor) Configure(cfg UPSConfig) {
+	um.mu.Lock()
+	defer um.mu.Unlock()
+	um.config = &cfg
+	um.status = nil
+	um.known = make(map[string]UPSStatus)
+}
+
+// Enabled reports whether any UPS is configured.
+func (um *UPSMonitor) Enabled() bool {
+	um.mu.Lock()
+	defer um.mu.Unlock()
+	return um.config != nil && len(um.config.Devices) > 0
+}
+
+// Start polls the UPSes at the configured interval.
+func (um *UPSMonitor) Start() {
+	um.mu.Lock()
+	cfg := um.config
+	um.mu.Unlock()
+	if cfg == nil {
+		return
+	}
+
+	interval := 30 * time.Second
+	if d, err := time.ParseDuration(cfg.Interval); err == nil {
+		interval = d
+	}
+
+	guardLoop("ups", func() { um.poll(*cfg) })
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		guardLoop("ups", func() { um.poll(*cfg) })
+	}
+}
+
+// Status returns the state of every UPS from the last poll.
+func (um *UPSMonitor) Status() ([]UPSStatus, time.Time) {
+	um.mu.Lock()
+	defer um.mu.Unlock()
+	return slices.Clone(um.status), um.updated
+}
+
+// poll reads every UPS and records switches to and from battery.
+func (um *UPSMonitor) poll(cfg UPSConfig) {
+	status := make([]UPSStatus, 0, len(cfg.Devices))
+	var errs []error
+	for _, d := range cfg.Devices {
+		s := readUPS(d)
+		if s.Error != "" {
+			errs = append(errs, fmt.Errorf("%s: %s", s.Name, s.Error))
+		}
+		status = append(status, s)
+	}
+	GetModuleHealth().Record("ups", errors.Join(errs...))
+
+	um.mu.Lock()
+	prev := make(map[string]UPSStatus, len(um.status))
+	for _, s := range um.status {
+		prev[s.ID] = s
+	}
+	var switched [][2]UPSStatus
+	for _, s := range status {
+		if s.Error != "" {
+			continue
+		}
+		// The first reading of a UPS only records its state
+		if old, ok := um.known[s.ID]; ok && (s.OnBattery != old.OnBattery || s.LowBattery != old.LowBattery) {
+			switched = append(switched, [2]UPSStatus{s, old})
+		}
+		um.known[s.ID] = s
+	}
+	um.status = status
+	um.updated = time.Now()
+	um.mu.Unlock()
+
+	for _, sw := range switched {
+		notifyUPSChange(sw[0], sw[1])
+	}
+	changed := false
+	for _, s := range status {
+		old, ok := prev[s.ID]
+		if !ok || s.Status != old.Status || s.LowBattery != old.LowBattery || s.Error != old.Error {
+			changed = true
+		}
+	}
+	if changed {
+		GetWSManager().BroadcastTopic(TopicForTimer("ups"), map[string]interface{}{
+			"type":      "refresh",
+			"module":    "ups",
+			"timestamp": time.Now().Unix(),
+		})
+	}
+}
+
+// notifyUPSChange records a switch to or from battery, or a low battery, on the timeline
+// and dispatches an alert.
+func notifyUPSChange(s, old UPSStatus) {
+	ev := TimelineEvent{Source: TimelineSourcePower, Title: "UPS " + s.Name + " is back on mains power", Severity: "ok"}
+	switch {
+	case s.LowBattery && !old.LowBattery:
+		ev.Title = "UPS " + s.Name + " battery is low"
+		ev.Severity = "critical"
+	case s.OnBattery && !old.OnBattery:
+		ev.Title = "UPS " + s.Name + " switched to battery"
+		ev.Severity = "critical"
+	case s.OnBattery:
+		// Battery no longer low while still on battery
+		return
+	}
+	if s.OnBattery {
+		ev.Detail = upsBatteryDetail(s)
+	}
+	GetTimeline().Add(ev)
+	if ev.Severity == "ok" {
+		Logger("ups").Info(ev.Title, "ups", s.ID)
+	} else {
+		Logger("ups").Warn(ev.Title, "ups", s.ID, "detail", ev.Detail)
+	}
+	GetAlertManager().Dispatch(Alert{
+		Title:    ev.Title,
+		Body:     ev.Detail,
+		Severity: ev.Severity,
+		URL:      "/",
+		Tag:      "ups-" + s.ID,
+	})
+}
+
+// upsBatteryDetail describes the battery charge and runtime left.
+func upsBatteryDetail(s UPSStatus) string {
+	var parts []string
+	if s.Charge != nil {
+		parts = append(parts, fmt.Sprintf("Battery at %.0f%%", *s.Charge))
+	}
+	if s.Runtime != nil {
+		parts = append(parts, fmt.Sprintf("%d minutes of runtime left", *s.Runtime/60))
+	}
+	if s.Load != nil {
+		parts = append(parts, fmt.Sprintf("load %.0f%%", *s.Load))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// readUPS reads one UPS. Failures are reported in the status.
+func readUPS(d UPSDevice) UPSStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var (
+		s   UPSStatus
+		err error
+	)
+	if d.Driver == "apcupsd" {
+		var vars map[string]string
+		if vars, err = fetchApcupsd(ctx, d.address()); err == nil {
+			s = apcupsdStatus(vars)
+		}
+	} else {
+		var vars map[string]string
+		if vars, err = fetchNUT(ctx, d.address(), d.UPS); err == nil {
+			s = nutStatus(vars)
+		}
+	}
+	s.ID = d.id()
+	s.Driver = d.Driver
+	s.Updated = time.Now()
+	switch {
+	case d.Name != "":
+		s.Name = d.Name
+	case d.UPS != "":
+		s.Name = d.UPS
+	case s.Name == "":
+		s.Name = d.address()
+	}
+	if err != nil {
+		GetDebugLogger().Logf("ups", "reading %s failed: %v", s.ID, err)
+		s.Status = "unknown"
+		s.Error = err.Error()
+	}
+	return s
+}
+
+// dialUPS connects to a UPS daemon with the context deadline applied to the connection.
+func dialUPS(ctx context.Context, address string) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	return conn, nil
+}
+
+// fetchNUT lists the variables of a UPS from a NUT server.
+func fetchNUT(ctx context.Context, address, ups string) (map[string]string, error) {
+	conn, err := dialUPS(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintf(conn, "LIST VAR %s\n", ups); err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string)
+	prefix := "VAR " + ups + " "
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "ERR "):
+			return nil, errors.New("nut: " + strings.TrimPrefix(line, "ERR "))
+		case strings.HasPrefix(line, "END LIST VAR"):
+			_, _ = io.WriteString(conn, "LOGOUT\n")
+			return vars, nil
+		case strings.HasPrefix(line, prefix):
+			key, value, ok := strings.Cut(strings.TrimPrefix(line, prefix), " ")
+			if !ok {
+				continue
+			}
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			} else {
+				value = strings.Trim(value, `"`)
+			}
+			vars[key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("nut: connection closed before the end of the variable list")
+}
+
+// nutStatus turns NUT variables into a UPS status.
+func nutStatus(vars map[string]string) UPSStatus {
+	s := UPSStatus{
+		Flags:        strings.Fields(vars["ups.status"]),
+		Charge:       upsFloat(vars["battery.charge"]),
+		Load:         upsFloat(vars["ups.load"]),
+		InputVoltage: upsFloat(vars["input.voltage"]),
+	}
+	if rt := upsFloat(vars["battery.runtime"]); rt != nil {
+		seconds := int64(*rt)
+		s.Runtime = &seconds
+	}
+	s.Model = strings.TrimSpace(vars["ups.mfr"] + " " + vars["ups.model"])
+	if s.Model == "" {
+		s.Model = strings.TrimSpace(vars["device.mfr"] + " " + vars["device.model"])
+	}
+	s.OnBattery = slices.Contains(s.Flags, "OB")
+	s.LowBattery = slices.Contains(s.Flags, "LB")
+	s.Charging = slices.Contains(s.Flags, "CHRG")
+	s.Status = upsStatusName(s.OnBattery, s.OnBattery || slices.Contains(s.Flags, "OL"))
+	return s
+}
+
+// fetchApcupsd reads the status of apcupsd over its network information server (NIS).
+// Messages in both directions are prefixed with their length as two bytes.
+func fetchApcupsd(ctx context.Context, address string) (map[string]string, error) {
+	conn, err := dialUPS(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	cmd := []byte("status")
+	if _, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(cmd))), cmd...)); err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string)
+	r := bufio.NewReader(conn)
+	var size [2]byte
+	for {
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return nil, err
+		}
+		n := binary.BigEndian.Uint16(size[:])
+		if n == 0 {
+			return vars, nil
+		}
+		line := make([]byte, n)
+		if _, err := io.ReadFull(r, line); err != nil {
+			return nil, err
+		}
+		if key, value, ok := strings.Cut(string(line), ":"); ok {
+			vars[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+}
+
+// apcupsdStatus turns apcupsd status lines into a UPS status. Readings carry their unit,
+// e.g. "100.0 Percent" or "45.0 Minutes".
+func apcupsdStatus(vars map[string]string) UPSStatus {
+	s := UPSStatus{
+		Name:         vars["UPSNAME"],
+		Model:        vars["MODEL"],
+		Flags:        strings.Fields(vars["STATUS"]),
+		Charge:       upsFloat(vars["BCHARGE"]),
+		Load:         upsFloat(vars["LOADPCT"]),
+		InputVoltage: upsFloat(vars["LINEV"]),
+	}
+	if rt := upsFloat(vars["TIMELEFT"]); rt != nil {
+		seconds := int64(*rt * 60)
+		s.Runtime = &seconds
+	}
+	s.OnBattery = slices.Contains(s.Flags, "ONBATT")
+	s.LowBattery = slices.Contains(s.Flags, "LOWBATT")
+	s.Charging = !s.OnBattery && s.Charge != nil && *s.Charge < 100
+	s.Status = upsStatusName(s.OnBattery, s.OnBattery || slices.Contains(s.Flags, "ONLINE"))
+	return s
+}
+
+// upsStatusName summarizes the power source.
+func upsStatusName(onBattery, known bool) string {
+	switch {
+	case onBattery:
+		return "battery"
+	case known:
+		return "online"
+	}
+	return "unknown"
+}
+
+// upsFloat parses the leading number of a reading, nil when there is none.
+func upsFloat(value string) *float64 {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil
+	}
+	f, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// bootHistoryFile holds the recorded boots across restarts of the dashboard.
+const bootHistoryFile = "boot-history.json"
+
+// maxBootRecords caps the number of boots kept in the history file.
+const maxBootRecords = 100
+
+// uptimeMilestones are the uptimes (in days) reported as milestones.
+var uptimeMilestones = []int64{1, 7, 30, 100, 365, 1000}
+
+// BootRecord represents a single boot of the host.
+type BootRecord struct {
+	BootTime time.Time `json:"bootTime"`
+	LastSeen time.Time `json:"lastSeen"`
+	// DurationSec is how long the host stayed up (until last seen for past boots).
+	DurationSec int64 `json:"durationSec"`
+	// DowntimeSec is the gap between the previous boot's last sighting and this boot.
+	DowntimeSec int64 `json:"downtimeSec,omitempty"`
+}
+
+// UptimeHistory is the response for the uptime history endpoint.
+type UptimeHistory struct {
+	BootTime         string       `json:"bootTime"`
+	UptimeSec        int64        `json:"uptimeSec"`
+	UptimeFormatted  string       `json:"uptimeFormatted"`
+	Milestones       []int64      `json:"milestones"`
+	NextMilestone    int64        `json:"nextMilestone,omitempty"`
+	NextMilestoneAt  string       `json:"nextMilestoneAt,omitempty"`
+	LongestUptimeSec int64        `json:"longestUptimeSec"`
+	TotalBoots       int          `json:"totalBoots"`
+	Boots            []BootRecord `json:"boots"`
+}
+
+// BootTracker detects reboots by observing changes in the host boot time.
+type BootTracker struct {
+	mu     sync.Mutex
+	boots  []BootRecord
+	loaded bool
+}
+
+// Global boot tracker instance
+var bootTracker = &BootTracker{}
+
+// GetBootTracker returns the global boot tracker instance.
+func GetBootTracker() *BootTracker {
+	return bootTracker
+}
+
+// Start records the current boot and refreshes its last-seen time every minute.
+func (bt *BootTracker) Start() {
+	bt.observe()
+
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		bt.observe()
+	}
+}
+
+// load reads the history file. Caller must hold mu.
+func (bt *BootTracker) load() {
+	if bt.loaded {
+		return
+	}
+	bt.loaded = true
+	data, err := os.ReadFile(bootHistoryFile)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &bt.boots); err != nil {
+		GetDebugLogger().Logf("uptime", "failed to parse %s: %v", bootHistoryFile, err)
+		bt.boots = nil
+	}
+}
+
+// save writes the history file. Caller must hold mu.
+func (bt *BootTracker) save() {
+	data, err := json.MarshalIndent(bt.boots, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(bootHistoryFile, data, 0644); err != nil {
+		GetDebugLogger().Logf("uptime", "failed to write %s: %v", bootHistoryFile, err)
+	}
+}
+
+// observe compares the host boot time with the last recorded boot and records a new one on reset.
+func (bt *BootTracker) observe() {
+	bootUnix, err := host.BootTime()
+	if err != nil {
+		return
+	}
+	bootTime := time.Unix(int64(bootUnix), 0)
+	now := time.Now()
+
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+	bt.load()
+
+	// Boot time jitters by a second or two between reads, so only treat larger jumps as a reboot
+	if n := len(bt.boots); n > 0 {
+		last := &bt.boots[n-1]
+		if diff := bootTime.Sub(last.BootTime); diff > -time.Minute && diff < time.Minute {
+			last.LastSeen = now
+			last.DurationSec = int64(now.Sub(last.BootTime).Seconds())
+			bt.save()
+			return
+		}
+	}
+
+	record := BootRecord{
+		BootTime:    bootTime,
+		LastSeen:    now,
+		DurationSec: int64(now.Sub(bootTime).Seconds()),
+	}
+	if n := len(bt.boots); n > 0 {
+		if gap := bootTime.Sub(bt.boots[n-1].LastSeen); gap > 0 {
+			record.DowntimeSec = int64(gap.Seconds())
+		}
+		GetDebugLogger().Logf("uptime", "reboot detected: previous boot %s, new boot %s", bt.boots[n-1].BootTime.Format(time.RFC3339), bootTime.Format(time.RFC3339))
+		GetTimeline().Add(TimelineEvent{
+			Time:     bootTime,
+			Source:   TimelineSourceReboot,
+			Title:    "Host rebooted",
+			Detail:   "Down for " + FmtUptime(record.DowntimeSec) + " after " + FmtUptime(bt.boots[n-1].DurationSec) + " uptime",
+			Severity: "warning",
+		})
+	}
+
+	bt.boots = append(bt.boots, record)
+	if len(bt.boots) > maxBootRecords {
+		bt.boots = bt.boots[len(bt.boots)-maxBootRecords:]
+	}
+	bt.save()
+}
+
+// History returns the boot history, newest first, with uptime milestones for the current boot.
+func (bt *BootTracker) History() UptimeHistory {
+	bt.observe()
+
+	bt.mu.Lock()
+	boots := make([]BootRecord, len(bt.boots))
+	copy(boots, bt.boots)
+	bt.mu.Unlock()
+
+	uptimeSec := GetSystemUptime()
+	bootTime := time.Now().Add(-time.Duration(uptimeSec) * time.Second)
+
+	result := UptimeHistory{
+		BootTime:        bootTime.Format(time.RFC3339),
+		UptimeSec:       uptimeSec,
+		UptimeFormatted: FmtUptime(uptimeSec),
+		Milestones:      []int64{},
+		TotalBoots:      len(boots),
+	}
+
+	for _, days := range uptimeMilestones {
+		if uptimeSec >= days*86400 {
+			result.Milestones = append(result.Milestones, days)
+			continue
+		}
+		result.NextMilestone = days
+		result.NextMilestoneAt = bootTime.Add(time.Duration(days) * 24 * time.Hour).Format(time.RFC3339)
+		break
+	}
+
+	for _, b := range boots {
+		if b.DurationSec > result.LongestUptimeSec {
+			result.LongestUptimeSec = b.DurationSec
+		}
+	}
+
+	sort.Slice(boots, func(i, j int) bool {
+		return boots[i].BootTime.After(boots[j].BootTime)
+	})
+	result.Boots = boots
+
+	return result
+}
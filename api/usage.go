@@ -0,0 +1,321 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Usage statistics are kept in usage.json next to the other state files and never leave
+// the server.
+const (
+	usageFile      = "usage.json"
+	usageMaxRoutes = 300
+	// A module rendered without a click for this long is suggested for removal
+	usageIdleAfter = 14 * 24 * time.Hour
+	usageSaveEvery = time.Minute
+)
+
+// UsageConfig configures the local usage statistics of /api/stats/usage.
+type UsageConfig struct {
+	Disabled bool `json:"disabled,omitempty"` // Do not count anything
+}
+
+// ModuleUsage counts how a module is used.
+type ModuleUsage struct {
+	Module          string     `json:"module"`
+	Name            string     `json:"name,omitempty"`
+	Renders         int64      `json:"renders"`      // Refreshes of its card reported by browsers
+	Interactions    int64      `json:"interactions"` // Clicks in its card
+	Fetches         int64      `json:"fetches"`      // Server fetches of its data
+	FetchErrors     int64      `json:"fetchErrors"`
+	AvgFetchMs      float64    `json:"avgFetchMs"`
+	MaxFetchMs      float64    `json:"maxFetchMs"`
+	LastRender      *time.Time `json:"lastRender,omitempty"`
+	LastInteraction *time.Time `json:"lastInteraction,omitempty"`
+	// Rendered but not clicked for two weeks, or ever: a candidate to switch off
+	Idle bool `json:"idle"`
+
+	FetchMsTotal float64 `json:"fetchMsTotal"`
+}
+
+// RouteUsage is the latency record of one API route.
+type RouteUsage struct {
+	Method  string  `json:"method"`
+	Path    string  `json:"path"`
+	Count   int64   `json:"count"`
+	Errors  int64   `json:"errors"` // 5xx responses
+	AvgMs   float64 `json:"avgMs"`
+	MaxMs   float64 `json:"maxMs"`
+	TotalMs float64 `json:"totalMs"`
+}
+
+// UsageReport is the answer of /api/stats/usage.
+type UsageReport struct {
+	Enabled bool          `json:"enabled"`
+	Since   time.Time     `json:"since"`
+	Modules []ModuleUsage `json:"modules"`
+	Routes  []RouteUsage  `json:"routes"`
+	Idle    []string      `json:"idle"` // Modules suggested for removal
+}
+
+// usageState is what usage.json holds.
+type usageState struct {
+	Since   time.Time               `json:"since"`
+	Modules map[string]*ModuleUsage `json:"modules"`
+	Routes  map[string]*RouteUsage  `json:"routes"`
+}
+
+// UsageStats counts module renders, clicks and fetches and API latencies locally.
+type UsageStats struct {
+	mu       sync.Mutex
+	disabled bool
+	state    usageState
+	loaded   bool
+	dirty    bool
+}
+
+// Global usage statistics instance
+var usageStats = &UsageStats{}
+
+// GetUsageStats returns the global usage statistics instance.
+func GetUsageStats() *UsageStats {
+	return usageStats
+}
+
+// Configure turns counting on or off.
+func (us *UsageStats) Configure(cfg UsageConfig) {
+	us.mu.Lock()
+	us.disabled = cfg.Disabled
+	us.mu.Unlock()
+}
+
+// Enabled reports whether usage is counted.
+func (us *UsageStats) Enabled() bool {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	return !us.disabled
+}
+
+// Start saves the counters every minute while they change.
+func (us *UsageStats) Start() {
+	ticker := time.NewTicker(usageSaveEvery)
+	defer ticker.Stop()
+	for range ticker.C {
+		us.mu.Lock()
+		if us.dirty {
+			us.save()
+		}
+		us.mu.Unlock()
+	}
+}
+
+// load reads usage.json. Caller must hold mu.
+func (us *UsageStats) load() {
+	if us.loaded {
+		return
+	}
+	us.loaded = true
+	if data, err := os.ReadFile(usageFile); err == nil {
+		if err := json.Unmarshal(data, &us.state); err != nil {
+			GetDebugLogger().Logf("usage", "failed to parse %s: %v", usageFile, err)
+			us.state = usageState{}
+		}
+	}
+	if us.state.Since.IsZero() {
+		us.state.Since = time.Now()
+	}
+	if us.state.Modules == nil {
+		us.state.Modules = make(map[string]*ModuleUsage)
+	}
+	if us.state.Routes == nil {
+		us.state.Routes = make(map[string]*RouteUsage)
+	}
+}
+
+// save writes usage.json. Caller must hold mu.
+func (us *UsageStats) save() {
+	data, err := json.MarshalIndent(us.state, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(usageFile, data, 0644); err != nil {
+		GetDebugLogger().Logf("usage", "failed to write %s: %v", usageFile, err)
+		return
+	}
+	us.dirty = false
+}
+
+// module returns the counters of a module. Caller must hold mu and have loaded the state.
+func (us *UsageStats) module(name string) *ModuleUsage {
+	m, ok := us.state.Modules[name]
+	if !ok {
+		m = &ModuleUsage{Module: name}
+		us.state.Modules[name] = m
+	}
+	return m
+}
+
+// RecordFetch counts a server fetch of a module's data; err is nil on success.
+func (us *UsageStats) RecordFetch(module string, duration time.Duration, err error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	if us.disabled {
+		return
+	}
+	us.load()
+	m := us.module(module)
+	ms := float64(duration.Microseconds()) / 1000
+	m.Fetches++
+	if err != nil {
+		m.FetchErrors++
+	}
+	m.FetchMsTotal += ms
+	m.MaxFetchMs = max(m.MaxFetchMs, ms)
+	us.dirty = true
+}
+
+// RecordAPI counts a request to an API route.
+func (us *UsageStats) RecordAPI(method, path string, status int, duration time.Duration) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	if us.disabled {
+		return
+	}
+	us.load()
+	key := method + " " + routeKey(path)
+	route, ok := us.state.Routes[key]
+	if !ok {
+		if len(us.state.Routes) >= usageMaxRoutes {
+			key = method + " (other)"
+			route = us.state.Routes[key]
+		}
+		if route == nil {
+			route = &RouteUsage{Method: method, Path: strings.TrimPrefix(key, method+" ")}
+			us.state.Routes[key] = route
+		}
+	}
+	ms := float64(duration.Microseconds()) / 1000
+	route.Count++
+	if status >= 500 {
+		route.Errors++
+	}
+	route.TotalMs += ms
+	route.MaxMs = max(route.MaxMs, ms)
+	us.dirty = true
+}
+
+// UsageEvents are the renders and clicks per module a browser counted since it last reported.
+type UsageEvents struct {
+	Renders      map[string]int64 `json:"renders"`
+	Interactions map[string]int64 `json:"interactions"`
+}
+
+// RecordEvents adds what a browser reported. Modules are named by their key or timer key;
+// unknown names are ignored, so clients cannot grow the statistics.
+func (us *UsageStats) RecordEvents(events UsageEvents) error {
+	known := make(map[string]string)
+	for key, meta := range GetModuleMetadata() {
+		known[key] = key
+		if meta.TimerKey != "" {
+			if _, ok := known[meta.TimerKey]; !ok {
+				known[meta.TimerKey] = key
+			}
+		}
+	}
+	now := time.Now()
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	if us.disabled {
+		return errors.New("usage statistics are disabled")
+	}
+	us.load()
+	for name, n := range events.Renders {
+		if module, ok := known[name]; ok && n > 0 && n < 10000 {
+			m := us.module(module)
+			m.Renders += n
+			m.LastRender = &now
+		}
+	}
+	for name, n := range events.Interactions {
+		if module, ok := known[name]; ok && n > 0 && n < 10000 {
+			m := us.module(module)
+			m.Interactions += n
+			m.LastInteraction = &now
+		}
+	}
+	us.dirty = true
+	return nil
+}
+
+// Report returns the modules by use, most used first, and the API routes by total time.
+func (us *UsageStats) Report() UsageReport {
+	metadata := GetModuleMetadata()
+	now := time.Now()
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	us.load()
+	report := UsageReport{
+		Enabled: !us.disabled,
+		Since:   us.state.Since,
+		Modules: make([]ModuleUsage, 0, len(us.state.Modules)),
+		Routes:  make([]RouteUsage, 0, len(us.state.Routes)),
+		Idle:    []string{},
+	}
+	for key, m := range us.state.Modules {
+		u := *m
+		u.Name = metadata[key].Name
+		if u.Fetches > 0 {
+			u.AvgFetchMs = u.FetchMsTotal / float64(u.Fetches)
+		}
+		lastUse := u.LastInteraction
+		if lastUse == nil {
+			lastUse = &us.state.Since
+		}
+		u.Idle = u.Renders > 0 && now.Sub(*lastUse) >= usageIdleAfter
+		if u.Idle {
+			report.Idle = append(report.Idle, key)
+		}
+		report.Modules = append(report.Modules, u)
+	}
+	sort.Slice(report.Modules, func(i, j int) bool {
+		a, b := report.Modules[i], report.Modules[j]
+		if a.Interactions != b.Interactions {
+			return a.Interactions > b.Interactions
+		}
+		if a.Renders != b.Renders {
+			return a.Renders > b.Renders
+		}
+		return a.Module < b.Module
+	})
+	sort.Strings(report.Idle)
+	for _, r := range us.state.Routes {
+		route := *r
+		route.AvgMs = route.TotalMs / float64(route.Count)
+		report.Routes = append(report.Routes, route)
+	}
+	sort.Slice(report.Routes, func(i, j int) bool {
+		return report.Routes[i].TotalMs > report.Routes[j].TotalMs
+	})
+	return report
+}
+
+// Purge deletes every counter and usage.json.
+func (us *UsageStats) Purge() {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	us.loaded = true
+	us.state = usageState{
+		Since:   time.Now(),
+		Modules: make(map[string]*ModuleUsage),
+		Routes:  make(map[string]*RouteUsage),
+	}
+	us.dirty = false
+	if err := os.Remove(usageFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		GetDebugLogger().Logf("usage", "failed to remove %s: %v", usageFile, err)
+	}
+}
@@ -10,13 +10,18 @@ import (
 	"strings"
 )
 
-// FormatBytes formats a byte count into a human-readable string (e.g., "1.5 GB").
+// FormatBytes formats a byte count into a human-readable string (e.g., "1.5 GB"), in steps
+// of 1024 or, with decimal byte units in the settings, 1000.
 func FormatBytes(bytes uint64) string {
 	if bytes == 0 {
 		return "0 B"
 	}
-	const k = 1024
+	k := 1024.0
 	sizes := []string{"B", "KB", "MB", "GB", "TB"}
+	if CurrentSettings().ByteUnits == "decimal" {
+		k = 1000
+		sizes = []string{"B", "kB", "MB", "GB", "TB"}
+	}
 	i := int(math.Floor(math.Log(float64(bytes)) / math.Log(k)))
 	if i >= len(sizes) {
 		i = len(sizes) - 1
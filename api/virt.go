@@ -0,0 +1,406 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// virtCacheTTL is how long hypervisor status is reused between requests.
+const virtCacheTTL = 15 * time.Second
+
+// VirtConfig configures the hypervisors behind /api/virt: a Proxmox VE cluster, the
+// local libvirt daemon, or both.
+type VirtConfig struct {
+	Proxmox *ProxmoxConfig `json:"proxmox,omitempty"`
+	Libvirt *LibvirtConfig `json:"libvirt,omitempty"`
+}
+
+// ProxmoxConfig configures access to the Proxmox VE API with an API token.
+type ProxmoxConfig struct {
+	URL     string `json:"url"`     // e.g. https://pve.lan:8006
+	TokenID string `json:"tokenId"` // e.g. "homepage@pve!dashboard"
+	Secret  string `json:"secret,omitempty"`
+	// SecretFile and SecretEnv read the token secret from a secret file or environment variable
+	SecretFile string `json:"secretFile,omitempty"`
+	SecretEnv  string `json:"secretEnv,omitempty"`
+	Node       string `json:"node,omitempty"` // Only list guests on this node
+	Insecure   bool   `json:"insecure,omitempty"`
+}
+
+// LibvirtConfig configures the local libvirt daemon, read with virsh.
+type LibvirtConfig struct {
+	URI string `json:"uri,omitempty"` // Default: qemu:///system
+}
+
+// Validate checks the configured hypervisors.
+func (c VirtConfig) Validate() error {
+	if c.Proxmox == nil && c.Libvirt == nil {
+		return fmt.Errorf("virt: proxmox or libvirt is required")
+	}
+	if p := c.Proxmox; p != nil {
+		if !strings.HasPrefix(p.URL, "http://") && !strings.HasPrefix(p.URL, "https://") {
+			return fmt.Errorf("virt: proxmox url must start with http:// or https://")
+		}
+		if !strings.Contains(p.TokenID, "!") {
+			return fmt.Errorf("virt: proxmox tokenId must look like user@realm!name")
+		}
+		if _, err := ResolveSecret(p.Secret, p.SecretFile, p.SecretEnv); err != nil {
+			return fmt.Errorf("virt: %w", err)
+		}
+	}
+	return nil
+}
+
+// VirtGuest is a virtual machine or container.
+type VirtGuest struct {
+	ID      string  `json:"id"` // e.g. "proxmox/qemu/100" or "libvirt/web"
+	Name    string  `json:"name"`
+	Kind    string  `json:"kind"`   // "vm" or "container"
+	Source  string  `json:"source"` // "proxmox" or "libvirt"
+	Node    string  `json:"node,omitempty"`
+	Status  string  `json:"status"` // e.g. running, stopped, paused
+	CPU     float64 `json:"cpu"`    // Percent of the guest's CPUs
+	CPUs    int     `json:"cpus,omitempty"`
+	Mem     uint64  `json:"mem"`    // Bytes in use
+	MaxMem  uint64  `json:"maxMem"` // Bytes assigned
+	Uptime  int64   `json:"uptime,omitempty"`
+	Running bool    `json:"running"`
+}
+
+// VirtNode is a hypervisor host.
+type VirtNode struct {
+	Name   string  `json:"name"`
+	Source string  `json:"source"`
+	Status string  `json:"status"`
+	CPU    float64 `json:"cpu"` // Percent
+	CPUs   int     `json:"cpus,omitempty"`
+	Mem    uint64  `json:"mem"`
+	MaxMem uint64  `json:"maxMem"`
+	Uptime int64   `json:"uptime,omitempty"`
+}
+
+// VirtStatus is the combined hypervisor status returned by /api/virt.
+type VirtStatus struct {
+	Nodes   []VirtNode  `json:"nodes"`
+	Guests  []VirtGuest `json:"guests"`
+	Running int         `json:"running"`
+	Errors  []string    `json:"errors,omitempty"` // Hypervisors that could not be read
+	Updated time.Time   `json:"updated"`
+}
+
+// libvirtCPUSample is the CPU time of a libvirt domain at one point in time.
+type libvirtCPUSample struct {
+	cpuTime uint64 // Nanoseconds
+	at      time.Time
+}
+
+// VirtMonitor reads guests from the configured hypervisors.
+type VirtMonitor struct {
+	mu      sync.Mutex
+	config  *VirtConfig
+	client  *http.Client
+	cached  *VirtStatus
+	fetched time.Time
+	// Previous libvirt CPU times, to turn them into usage
+	cpuSamples map[string]libvirtCPUSample
+}
+
+// Global virtualization monitor instance
+var virtMonitor = &VirtMonitor{cpuSamples: make(map[string]libvirtCPUSample)}
+
+// GetVirtMonitor returns the global virtualization monitor instance.
+func GetVirtMonitor() *VirtMonitor {
+	return virtMonitor
+}
+
+// Configure sets the hypervisors to read from.
+func (vm *VirtMonitor) Configure(cfg VirtConfig) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.config = &cfg
+	vm.cached = nil
+	vm.client = nil
+	if cfg.Proxmox != nil {
+		vm.client = &http.Client{
+			Timeout: 15 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.Proxmox.Insecure},
+			},
+		}
+	}
+}
+
+// Enabled reports whether a hypervisor is configured.
+func (vm *VirtMonitor) Enabled() bool {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	return vm.config != nil
+}
+
+// Status returns the guests of every hypervisor, reading them again when the cached
+// copy is stale. Hypervisors that fail are listed in Errors.
+func (vm *VirtMonitor) Status(ctx context.Context) (*VirtStatus, error) {
+	vm.mu.Lock()
+	if vm.config == nil {
+		vm.mu.Unlock()
+		return nil, errors.New("no hypervisor configured")
+	}
+	if vm.cached != nil && time.Since(vm.fetched) < virtCacheTTL {
+		status := vm.cached
+		vm.mu.Unlock()
+		return status, nil
+	}
+	cfg := *vm.config
+	client := vm.client
+	vm.mu.Unlock()
+
+	status := &VirtStatus{Nodes: []VirtNode{}, Guests: []VirtGuest{}, Updated: time.Now()}
+	if cfg.Proxmox != nil {
+		nodes, guests, err := fetchProxmox(ctx, client, *cfg.Proxmox)
+		if err != nil {
+			status.Errors = append(status.Errors, "proxmox: "+err.Error())
+		}
+		status.Nodes = append(status.Nodes, nodes...)
+		status.Guests = append(status.Guests, guests...)
+	}
+	if cfg.Libvirt != nil {
+		guests, err := vm.fetchLibvirt(ctx, *cfg.Libvirt)
+		if err != nil {
+			status.Errors = append(status.Errors, "libvirt: "+err.Error())
+		}
+		status.Guests = append(status.Guests, guests...)
+	}
+	if len(status.Errors) > 0 && len(status.Guests) == 0 && len(status.Nodes) == 0 {
+		return nil, errors.New(strings.Join(status.Errors, "; "))
+	}
+
+	// Running guests first, then by name
+	sort.SliceStable(status.Guests, func(i, j int) bool {
+		a, b := status.Guests[i], status.Guests[j]
+		if a.Running != b.Running {
+			return a.Running
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+	for _, g := range status.Guests {
+		if g.Running {
+			status.Running++
+		}
+	}
+
+	vm.mu.Lock()
+	vm.cached = status
+	vm.fetched = time.Now()
+	vm.mu.Unlock()
+	return status, nil
+}
+
+// proxmoxResource is an entry of /cluster/resources.
+type proxmoxResource struct {
+	ID       string  `json:"id"`
+	Type     string  `json:"type"` // node, qemu, lxc, storage, ...
+	VMID     int     `json:"vmid"`
+	Name     string  `json:"name"`
+	Node     string  `json:"node"`
+	Status   string  `json:"status"`
+	CPU      float64 `json:"cpu"` // Fraction of maxcpu
+	MaxCPU   int     `json:"maxcpu"`
+	Mem      float64 `json:"mem"` // Bytes
+	MaxMem   float64 `json:"maxmem"`
+	Uptime   int64   `json:"uptime"`
+	Template int     `json:"template"`
+}
+
+// fetchProxmox lists the nodes and guests of a Proxmox VE cluster.
+func fetchProxmox(ctx context.Context, client *http.Client, cfg ProxmoxConfig) ([]VirtNode, []VirtGuest, error) {
+	secret, err := ResolveSecret(cfg.Secret, cfg.SecretFile, cfg.SecretEnv)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(cfg.URL, "/")+"/api2/json/cluster/resources", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", "PVEAPIToken="+cfg.TokenID+"="+secret)
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return nil, nil, fmt.Errorf("HTTP %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var body struct {
+		Data []proxmoxResource `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, nil, err
+	}
+
+	nodes := []VirtNode{}
+	guests := []VirtGuest{}
+	for _, r := range body.Data {
+		if cfg.Node != "" && r.Node != cfg.Node {
+			continue
+		}
+		switch r.Type {
+		case "node":
+			nodes = append(nodes, VirtNode{
+				Name:   r.Node,
+				Source: "proxmox",
+				Status: r.Status,
+				CPU:    r.CPU * 100,
+				CPUs:   r.MaxCPU,
+				Mem:    uint64(r.Mem),
+				MaxMem: uint64(r.MaxMem),
+				Uptime: r.Uptime,
+			})
+		case "qemu", "lxc":
+			if r.Template == 1 {
+				continue
+			}
+			kind := "vm"
+			if r.Type == "lxc" {
+				kind = "container"
+			}
+			name := r.Name
+			if name == "" {
+				name = strconv.Itoa(r.VMID)
+			}
+			guests = append(guests, VirtGuest{
+				ID:      "proxmox/" + r.ID,
+				Name:    name,
+				Kind:    kind,
+				Source:  "proxmox",
+				Node:    r.Node,
+				Status:  r.Status,
+				CPU:     r.CPU * 100,
+				CPUs:    r.MaxCPU,
+				Mem:     uint64(r.Mem),
+				MaxMem:  uint64(r.MaxMem),
+				Uptime:  r.Uptime,
+				Running: r.Status == "running",
+			})
+		}
+	}
+	return nodes, guests, nil
+}
+
+// libvirtStates names the libvirt domain states (virDomainState).
+var libvirtStates = []string{"unknown", "running", "blocked", "paused", "shutting down", "stopped", "crashed", "suspended"}
+
+// fetchLibvirt lists the domains of the local libvirt daemon with virsh domstats.
+// CPU usage is derived from the CPU time since the previous read.
+func (vm *VirtMonitor) fetchLibvirt(ctx context.Context, cfg LibvirtConfig) ([]VirtGuest, error) {
+	uri := cfg.URI
+	if uri == "" {
+		uri = "qemu:///system"
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "virsh", "-c", uri, "domstats", "--raw", "--state", "--cpu-total", "--balloon", "--vcpu")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("virsh: %s", msg)
+		}
+		return nil, fmt.Errorf("virsh: %w", err)
+	}
+	domains := parseDomstats(out)
+	now := time.Now()
+
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	guests := make([]VirtGuest, 0, len(domains))
+	seen := make(map[string]bool, len(domains))
+	for _, d := range domains {
+		name := d["name"]
+		seen[name] = true
+		state, _ := strconv.Atoi(d["state.state"])
+		status := "unknown"
+		if state >= 0 && state < len(libvirtStates) {
+			status = libvirtStates[state]
+		}
+		cpus, _ := strconv.Atoi(d["vcpu.current"])
+		g := VirtGuest{
+			ID:      "libvirt/" + name,
+			Name:    name,
+			Kind:    "vm",
+			Source:  "libvirt",
+			Status:  status,
+			CPUs:    cpus,
+			Running: state == 1,
+		}
+
+		// Balloon sizes are in KiB; the guest's own view needs the balloon driver
+		maxMem, _ := strconv.ParseUint(d["balloon.current"], 10, 64)
+		g.MaxMem = maxMem * 1024
+		available, okAvailable := parseDomstatUint(d, "balloon.available")
+		unused, okUnused := parseDomstatUint(d, "balloon.unused")
+		if okAvailable && okUnused && available >= unused {
+			g.Mem = (available - unused) * 1024
+		} else if rss, ok := parseDomstatUint(d, "balloon.rss"); ok && g.Running {
+			g.Mem = min(rss*1024, g.MaxMem)
+		}
+
+		if cpuTime, ok := parseDomstatUint(d, "cpu.time"); ok && g.Running {
+			if prev, ok := vm.cpuSamples[name]; ok && cpuTime >= prev.cpuTime && cpus > 0 {
+				if elapsed := now.Sub(prev.at); elapsed > 0 {
+					g.CPU = min(float64(cpuTime-prev.cpuTime)/float64(elapsed.Nanoseconds())/float64(cpus)*100, 100)
+				}
+			}
+			vm.cpuSamples[name] = libvirtCPUSample{cpuTime: cpuTime, at: now}
+		}
+		guests = append(guests, g)
+	}
+	for name := range vm.cpuSamples {
+		if !seen[name] {
+			delete(vm.cpuSamples, name)
+		}
+	}
+	return guests, nil
+}
+
+// parseDomstats splits virsh domstats output into one key/value map per domain, with
+// the domain name under "name".
+func parseDomstats(out []byte) []map[string]string {
+	var domains []map[string]string
+	var current map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if name, ok := strings.CutPrefix(line, "Domain: "); ok {
+			current = map[string]string{"name": strings.Trim(name, "'")}
+			domains = append(domains, current)
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && current != nil {
+			current[key] = value
+		}
+	}
+	return domains
+}
+
+func parseDomstatUint(d map[string]string, key string) (uint64, bool) {
+	v, ok := d[key]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	return n, err == nil
+}
@@ -5,31 +5,134 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"math"
 	"net/http"
 	"net/url"
+	"sync"
+	"time"
 )
 
-// OpenMeteoSummary fetches weather data from Open-Meteo API.
-func OpenMeteoSummary(ctx context.Context, lat, lon string) (WeatherData, error) {
-	u := "https://api.open-meteo.com/v1/forecast?latitude=" + lat + "&longitude=" + lon + "&current=temperature_2m,apparent_temperature,relative_humidity_2m,wind_speed_10m,wind_direction_10m,pressure_msl,uv_index,cloud_cover,visibility,dewpoint_2m,precipitation_probability,weather_code&daily=temperature_2m_max,temperature_2m_min,precipitation_probability_max,uv_index_max,sunrise,sunset,weather_code&timezone=auto&forecast_days=3"
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
-	req.Header.Set("User-Agent", "lan-index/1.0")
-	res, err := http.DefaultClient.Do(req)
+// weatherCacheTTL is how long fetched forecasts are reused for calendar annotations.
+const weatherCacheTTL = 30 * time.Minute
+
+// weatherCacheEntry holds a cached forecast for a location.
+type weatherCacheEntry struct {
+	data      WeatherData
+	timestamp time.Time
+}
+
+// Global forecast cache keyed by provider and location
+var weatherCache = struct {
+	mu      sync.Mutex
+	entries map[string]weatherCacheEntry
+}{entries: make(map[string]weatherCacheEntry)}
+
+// FetchWeather fetches weather for a location from the configured provider. Only
+// Open-Meteo has a minute-level forecast, so the nowcast of the others comes from it.
+func FetchWeather(ctx context.Context, cfg WeatherConfig, lat, lon string) (WeatherData, error) {
+	wd, err := GetWeatherProvider(cfg.Provider).Summary(ctx, lat, lon, cfg.APIKey)
 	if err != nil {
-		return WeatherData{}, err
+		return wd, err
 	}
-	defer func() {
-		if closeErr := res.Body.Close(); closeErr != nil {
-			log.Printf("Error closing weather response body: %v", closeErr)
+	if wd.Nowcast == nil {
+		if nc, err := OpenMeteoNowcast(ctx, lat, lon); err != nil {
+			GetDebugLogger().Logf("weather", "nowcast unavailable: %v", err)
+		} else {
+			wd.Nowcast = nc
 		}
-	}()
-	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return WeatherData{}, errors.New("weather http status " + res.Status)
 	}
+	if air, err := CachedAir(ctx, lat, lon); err != nil {
+		GetDebugLogger().Logf("weather", "air quality unavailable: %v", err)
+	} else {
+		wd.addAir(air)
+	}
+	return wd, nil
+}
+
+// addAir adds the air quality to the current weather and the days.
+func (wd *WeatherData) addAir(air *WeatherAir) {
+	if wd.Current != nil {
+		wd.Current.Air = air.Current
+	}
+	if wd.Today != nil {
+		wd.Today.Air = air.Today
+	}
+	if wd.Tomorrow != nil {
+		wd.Tomorrow.Air = air.Tomorrow
+	}
+}
+
+// weatherLocation is the location saved by the weather preferences.
+type weatherLocation struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// CachedWeather returns the forecast for a location, fetching it at most every weatherCacheTTL.
+func CachedWeather(ctx context.Context, cfg WeatherConfig, lat, lon string) (WeatherData, error) {
+	key := GetWeatherProvider(cfg.Provider).Name() + "|" + lat + "," + lon
 
+	weatherCache.mu.Lock()
+	entry, exists := weatherCache.entries[key]
+	weatherCache.mu.Unlock()
+	if exists && time.Since(entry.timestamp) < weatherCacheTTL {
+		RecordTiming(ctx, "weather", 0, TimingHit)
+		return entry.data, nil
+	}
+
+	done := StartTiming(ctx, "weather")
+	wd, err := FetchWeather(ctx, cfg, lat, lon)
+	done(TimingMiss)
+	if err != nil {
+		return WeatherData{}, err
+	}
+
+	weatherCache.mu.Lock()
+	weatherCache.entries[key] = weatherCacheEntry{data: wd, timestamp: time.Now()}
+	weatherCache.mu.Unlock()
+	return wd, nil
+}
+
+// SavedWeatherLocation returns the location saved by the weather preferences,
+// falling back to the configured coordinates.
+func SavedWeatherLocation(cfg WeatherConfig) (lat, lon, name string) {
+	var loc weatherLocation
+	if GetStorage().GetAs("weatherLocation", &loc) && (loc.Latitude != 0 || loc.Longitude != 0) {
+		return fmt.Sprintf("%g", loc.Latitude), fmt.Sprintf("%g", loc.Longitude), loc.Name
+	}
+	return cfg.Lat, cfg.Lon, ""
+}
+
+// ForecastFor returns the daily forecast for a date (YYYY-MM-DD) if it is in the forecast window.
+func (wd WeatherData) ForecastFor(date string) *WeatherDay {
+	now := time.Now()
+	switch date {
+	case now.Format("2006-01-02"):
+		return wd.Today
+	case now.AddDate(0, 0, 1).Format("2006-01-02"):
+		return wd.Tomorrow
+	}
+	return nil
+}
+
+// openMeteoWeather is the default provider, which needs no API key.
+type openMeteoWeather struct{}
+
+func (openMeteoWeather) Name() string { return "openmeteo" }
+
+// Summary fetches weather data from Open-Meteo API.
+func (openMeteoWeather) Summary(ctx context.Context, lat, lon, _ string) (WeatherData, error) {
+	u := "https://api.open-meteo.com/v1/forecast?latitude=" + lat + "&longitude=" + lon + "&current=temperature_2m,apparent_temperature,relative_humidity_2m,wind_speed_10m,wind_direction_10m,pressure_msl,uv_index,cloud_cover,visibility,dewpoint_2m,precipitation_probability,weather_code&daily=temperature_2m_max,temperature_2m_min,precipitation_probability_max,uv_index_max,sunrise,sunset,weather_code&timezone=auto&forecast_days=3&" + openMeteoNowcastParams + "&" + openMeteoHourlyParams
 	var raw struct {
-		Current struct {
+		UTCOffsetSeconds int               `json:"utc_offset_seconds"`
+		Minutely         openMeteoMinutely `json:"minutely_15"`
+		MinutelyUnits    struct {
+			Precipitation string `json:"precipitation"`
+		} `json:"minutely_15_units"`
+		Hourly      openMeteoHourly      `json:"hourly"`
+		HourlyUnits openMeteoHourlyUnits `json:"hourly_units"`
+		Current     struct {
 			Temperature         float64 `json:"temperature_2m"`
 			ApparentTemperature float64 `json:"apparent_temperature"`
 			Humidity            float64 `json:"relative_humidity_2m"`
@@ -64,7 +167,7 @@ func OpenMeteoSummary(ctx context.Context, lat, lon string) (WeatherData, error)
 			TemperatureMax string `json:"temperature_2m_max"`
 		} `json:"daily_units"`
 	}
-	if err := json.NewDecoder(res.Body).Decode(&raw); err != nil {
+	if err := getWeatherJSON(ctx, "Open-Meteo", u, &raw); err != nil {
 		return WeatherData{}, err
 	}
 
@@ -163,39 +266,37 @@ func OpenMeteoSummary(ctx context.Context, lat, lon string) (WeatherData, error)
 		}
 	}
 
+	nowcast, err := buildNowcast(raw.Minutely, raw.MinutelyUnits.Precipitation, raw.UTCOffsetSeconds, time.Now())
+	if err != nil {
+		GetDebugLogger().Logf("weather", "nowcast unavailable: %v", err)
+	}
+
 	return WeatherData{
 		Summary:  summary,
 		Forecast: forecast,
 		Current:  current,
 		Today:    today,
 		Tomorrow: tomorrow,
+		Nowcast:  nowcast,
+		Hourly:   buildOpenMeteoHourly(raw.Hourly, raw.HourlyUnits, raw.UTCOffsetSeconds),
 	}, nil
 }
 
-// OpenWeatherMapSummary fetches weather data from OpenWeatherMap API.
-func OpenWeatherMapSummary(ctx context.Context, lat, lon, apiKey string) (WeatherData, error) {
+// openWeatherMapWeather needs an API key; its free forecast is in steps of three hours.
+type openWeatherMapWeather struct{}
+
+func (openWeatherMapWeather) Name() string { return "openweathermap" }
+
+// Summary fetches weather data from OpenWeatherMap API.
+func (openWeatherMapWeather) Summary(ctx context.Context, lat, lon, apiKey string) (WeatherData, error) {
 	if apiKey == "" {
 		return WeatherData{}, errors.New("OpenWeatherMap API key required (set in Preferences)")
 	}
 
 	var today, tomorrow *WeatherDay
+	var hourly *WeatherHourly
 
 	u := "https://api.openweathermap.org/data/2.5/weather?lat=" + lat + "&lon=" + lon + "&appid=" + apiKey + "&units=metric"
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
-	req.Header.Set("User-Agent", "lan-index/1.0")
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return WeatherData{}, err
-	}
-	defer func() {
-		if closeErr := res.Body.Close(); closeErr != nil {
-			log.Printf("Error closing weather response body: %v", closeErr)
-		}
-	}()
-	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return WeatherData{}, fmt.Errorf("OpenWeatherMap API error: %s", res.Status)
-	}
-
 	var currentResp struct {
 		Main struct {
 			Temp      float64 `json:"temp"`
@@ -215,53 +316,77 @@ func OpenWeatherMapSummary(ctx context.Context, lat, lon, apiKey string) (Weathe
 			ID int `json:"id"`
 		} `json:"weather"`
 	}
-	if err := json.NewDecoder(res.Body).Decode(&currentResp); err != nil {
+	if err := getWeatherJSON(ctx, "OpenWeatherMap API", u, &currentResp); err != nil {
 		return WeatherData{}, err
 	}
 
-	forecastURL := "https://api.openweathermap.org/data/2.5/forecast?lat=" + lat + "&lon=" + lon + "&appid=" + apiKey + "&units=metric&cnt=2"
-	forecastReq, _ := http.NewRequestWithContext(ctx, http.MethodGet, forecastURL, nil)
-	forecastReq.Header.Set("User-Agent", "lan-index/1.0")
-	forecastRes, err := http.DefaultClient.Do(forecastReq)
-	if err == nil {
-		defer forecastRes.Body.Close()
-		if forecastRes.StatusCode >= 200 && forecastRes.StatusCode <= 299 {
-			var forecastResp struct {
-				List []struct {
-					Main struct {
-						Temp float64 `json:"temp"`
-					} `json:"main"`
-					Weather []struct {
-						ID int `json:"id"`
-					} `json:"weather"`
-					Dt int64 `json:"dt"`
-				} `json:"list"`
+	forecastURL := "https://api.openweathermap.org/data/2.5/forecast?lat=" + lat + "&lon=" + lon + "&appid=" + apiKey + "&units=metric&cnt=16"
+	var forecastResp struct {
+		List []struct {
+			Main struct {
+				Temp float64 `json:"temp"`
+			} `json:"main"`
+			Weather []struct {
+				ID int `json:"id"`
+			} `json:"weather"`
+			Wind struct {
+				Speed float64 `json:"speed"`
+				Deg   int     `json:"deg"`
+			} `json:"wind"`
+			Pop  float64 `json:"pop"`
+			Rain struct {
+				ThreeHours float64 `json:"3h"`
+			} `json:"rain"`
+			Snow struct {
+				ThreeHours float64 `json:"3h"`
+			} `json:"snow"`
+			Dt int64 `json:"dt"`
+		} `json:"list"`
+		City struct {
+			Timezone int `json:"timezone"`
+		} `json:"city"`
+	}
+	// Without a forecast there is still the current weather
+	if err := getWeatherJSON(ctx, "OpenWeatherMap API", forecastURL, &forecastResp); err == nil && len(forecastResp.List) > 0 {
+		if len(forecastResp.List) > 0 && len(forecastResp.List[0].Weather) > 0 {
+			todayIcon := GetWeatherIcon(forecastResp.List[0].Weather[0].ID)
+			today = &WeatherDay{
+				TempMax:         forecastResp.List[0].Main.Temp,
+				TempMin:         forecastResp.List[0].Main.Temp,
+				TempUnit:        "°C",
+				WeatherCode:     forecastResp.List[0].Weather[0].ID,
+				Icon:            todayIcon.Icon,
+				IconDescription: todayIcon.Desc,
 			}
-			if err := json.NewDecoder(forecastRes.Body).Decode(&forecastResp); err == nil && len(forecastResp.List) > 0 {
-				if len(forecastResp.List) > 0 && len(forecastResp.List[0].Weather) > 0 {
-					todayIcon := GetWeatherIcon(forecastResp.List[0].Weather[0].ID)
-					today = &WeatherDay{
-						TempMax:         forecastResp.List[0].Main.Temp,
-						TempMin:         forecastResp.List[0].Main.Temp,
-						TempUnit:        "°C",
-						WeatherCode:     forecastResp.List[0].Weather[0].ID,
-						Icon:            todayIcon.Icon,
-						IconDescription: todayIcon.Desc,
-					}
-				}
-				if len(forecastResp.List) > 1 && len(forecastResp.List[1].Weather) > 0 {
-					tomorrowIcon := GetWeatherIcon(forecastResp.List[1].Weather[0].ID)
-					tomorrow = &WeatherDay{
-						TempMax:         forecastResp.List[1].Main.Temp,
-						TempMin:         forecastResp.List[1].Main.Temp,
-						TempUnit:        "°C",
-						WeatherCode:     forecastResp.List[1].Weather[0].ID,
-						Icon:            tomorrowIcon.Icon,
-						IconDescription: tomorrowIcon.Desc,
-					}
-				}
+		}
+		if len(forecastResp.List) > 1 && len(forecastResp.List[1].Weather) > 0 {
+			tomorrowIcon := GetWeatherIcon(forecastResp.List[1].Weather[0].ID)
+			tomorrow = &WeatherDay{
+				TempMax:         forecastResp.List[1].Main.Temp,
+				TempMin:         forecastResp.List[1].Main.Temp,
+				TempUnit:        "°C",
+				WeatherCode:     forecastResp.List[1].Weather[0].ID,
+				Icon:            tomorrowIcon.Icon,
+				IconDescription: tomorrowIcon.Desc,
 			}
 		}
+		// The list is in steps of three hours, so it doubles as the hourly forecast
+		zone := time.FixedZone("", forecastResp.City.Timezone)
+		hourly = &WeatherHourly{Step: 3, TempUnit: "°C", WindUnit: "m/s", PrecipitationUnit: "mm", Hours: []WeatherHour{}}
+		for _, item := range forecastResp.List {
+			hour := WeatherHour{
+				Time:              time.Unix(item.Dt, 0).In(zone),
+				Temperature:       item.Main.Temp,
+				PrecipitationProb: math.Round(item.Pop * 100),
+				Precipitation:     item.Rain.ThreeHours + item.Snow.ThreeHours,
+				WindSpeed:         item.Wind.Speed,
+				WindDirection:     item.Wind.Deg,
+			}
+			if len(item.Weather) > 0 {
+				hour.WeatherCode = item.Weather[0].ID
+			}
+			hourly.Hours = append(hourly.Hours, newWeatherHour(hour))
+		}
 	}
 
 	weatherCode := 0
@@ -296,32 +421,26 @@ func OpenWeatherMapSummary(ctx context.Context, lat, lon, apiKey string) (Weathe
 		Current:  current,
 		Today:    today,
 		Tomorrow: tomorrow,
+		Hourly:   hourly,
 	}, nil
 }
 
-// WeatherAPISummary fetches weather data from WeatherAPI.com.
-func WeatherAPISummary(ctx context.Context, lat, lon, apiKey string) (WeatherData, error) {
+// weatherAPIWeather needs an API key.
+type weatherAPIWeather struct{}
+
+func (weatherAPIWeather) Name() string { return "weatherapi" }
+
+// Summary fetches weather data from WeatherAPI.com.
+func (weatherAPIWeather) Summary(ctx context.Context, lat, lon, apiKey string) (WeatherData, error) {
 	if apiKey == "" {
 		return WeatherData{}, errors.New("WeatherAPI.com API key required (set in Preferences)")
 	}
 
 	u := "https://api.weatherapi.com/v1/forecast.json?key=" + apiKey + "&q=" + lat + "," + lon + "&days=3&aqi=no&alerts=no"
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
-	req.Header.Set("User-Agent", "lan-index/1.0")
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return WeatherData{}, err
-	}
-	defer func() {
-		if closeErr := res.Body.Close(); closeErr != nil {
-			log.Printf("Error closing weather response body: %v", closeErr)
-		}
-	}()
-	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return WeatherData{}, fmt.Errorf("WeatherAPI.com error: %s", res.Status)
-	}
-
 	var raw struct {
+		Location struct {
+			TzID string `json:"tz_id"`
+		} `json:"location"`
 		Current struct {
 			TempC      float64 `json:"temp_c"`
 			FeelsLikeC float64 `json:"feelslike_c"`
@@ -353,10 +472,22 @@ func WeatherAPISummary(ctx context.Context, lat, lon, apiKey string) (WeatherDat
 					Sunrise string `json:"sunrise"`
 					Sunset  string `json:"sunset"`
 				} `json:"astro"`
+				Hour []struct {
+					TimeEpoch    int64   `json:"time_epoch"`
+					TempC        float64 `json:"temp_c"`
+					ChanceOfRain float64 `json:"chance_of_rain"`
+					ChanceOfSnow float64 `json:"chance_of_snow"`
+					PrecipMm     float64 `json:"precip_mm"`
+					WindKph      float64 `json:"wind_kph"`
+					WindDegree   int     `json:"wind_degree"`
+					Condition    struct {
+						Code int `json:"code"`
+					} `json:"condition"`
+				} `json:"hour"`
 			} `json:"forecastday"`
 		} `json:"forecast"`
 	}
-	if err := json.NewDecoder(res.Body).Decode(&raw); err != nil {
+	if err := getWeatherJSON(ctx, "WeatherAPI.com", u, &raw); err != nil {
 		return WeatherData{}, err
 	}
 
@@ -437,12 +568,33 @@ func WeatherAPISummary(ctx context.Context, lat, lon, apiKey string) (WeatherDat
 		}
 	}
 
+	// The forecast days start at midnight, so the hours before now are dropped
+	zone, err := time.LoadLocation(raw.Location.TzID)
+	if err != nil {
+		zone = time.UTC
+	}
+	hourly := &WeatherHourly{Step: 1, TempUnit: "°C", WindUnit: "km/h", PrecipitationUnit: "mm", Hours: []WeatherHour{}}
+	for _, day := range raw.Forecast.Forecastday {
+		for _, h := range day.Hour {
+			hourly.Hours = append(hourly.Hours, newWeatherHour(WeatherHour{
+				Time:              time.Unix(h.TimeEpoch, 0).In(zone),
+				Temperature:       h.TempC,
+				PrecipitationProb: max(h.ChanceOfRain, h.ChanceOfSnow),
+				Precipitation:     h.PrecipMm,
+				WindSpeed:         h.WindKph,
+				WindDirection:     h.WindDegree,
+				WeatherCode:       h.Condition.Code,
+			}))
+		}
+	}
+
 	return WeatherData{
 		Summary:  summary,
 		Forecast: forecast,
 		Current:  current,
 		Today:    today,
 		Tomorrow: tomorrow,
+		Hourly:   hourly.Next(time.Now(), hourlyHours),
 	}, nil
 }
 
@@ -460,7 +612,7 @@ func GeocodeCity(ctx context.Context, query string) ([]GeoLocation, error) {
 	}
 	defer func() {
 		if closeErr := res.Body.Close(); closeErr != nil {
-			log.Printf("Error closing geocode response body: %v", closeErr)
+			Logger("weather").Warn("closing geocode response body", "error", closeErr)
 		}
 	}()
 	if res.StatusCode < 200 || res.StatusCode > 299 {
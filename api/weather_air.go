@@ -0,0 +1,238 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// airCacheTTL is how long air quality is reused; Open-Meteo's values are hourly.
+const airCacheTTL = time.Hour
+
+// airQualityParams are the values requested from Open-Meteo's air quality API.
+const airQualityParams = "european_aqi,us_aqi,pm2_5,pm10,ozone,alder_pollen,birch_pollen,grass_pollen,mugwort_pollen,olive_pollen,ragweed_pollen"
+
+// pollenTypes are the pollen Open-Meteo reports (in Europe only), with the grains/m³ at
+// which their level becomes moderate, high and very high.
+var pollenTypes = []struct {
+	name   string
+	levels [3]float64
+}{
+	{"alder", [3]float64{10, 100, 1000}},
+	{"birch", [3]float64{10, 100, 1000}},
+	{"olive", [3]float64{10, 100, 1000}},
+	{"grass", [3]float64{5, 30, 200}},
+	{"mugwort", [3]float64{5, 20, 100}},
+	{"ragweed", [3]float64{5, 20, 100}},
+}
+
+// PollenCount is the concentration of one type of pollen.
+type PollenCount struct {
+	Type  string  `json:"type"`  // e.g. "birch", "grass"
+	Count float64 `json:"count"` // Grains/m³
+	Level string  `json:"level"` // "low", "moderate", "high" or "very high"
+}
+
+// AirQuality is the air quality and pollen at a time, or the maximum of a day.
+type AirQuality struct {
+	AQI      float64       `json:"aqi"`      // European AQI
+	AQILevel string        `json:"aqiLevel"` // "good", "fair", "moderate", "poor", "very poor" or "extremely poor"
+	USAQI    float64       `json:"usAqi"`
+	PM25     float64       `json:"pm25"`             // μg/m³
+	PM10     float64       `json:"pm10"`             // μg/m³
+	Ozone    float64       `json:"ozone"`            // μg/m³
+	Pollen   []PollenCount `json:"pollen,omitempty"` // Types in the air, only in Europe
+}
+
+// WeatherAir is the air quality now and the worst of today and tomorrow.
+type WeatherAir struct {
+	Current  *AirQuality `json:"current,omitempty"`
+	Today    *AirQuality `json:"today,omitempty"`
+	Tomorrow *AirQuality `json:"tomorrow,omitempty"`
+}
+
+// aqiLevel names a European AQI value.
+func aqiLevel(aqi float64) string {
+	switch {
+	case aqi <= 20:
+		return "good"
+	case aqi <= 40:
+		return "fair"
+	case aqi <= 60:
+		return "moderate"
+	case aqi <= 80:
+		return "poor"
+	case aqi <= 100:
+		return "very poor"
+	}
+	return "extremely poor"
+}
+
+// pollenLevel names a pollen count by the thresholds of its type.
+func pollenLevel(count float64, levels [3]float64) string {
+	switch {
+	case count >= levels[2]:
+		return "very high"
+	case count >= levels[1]:
+		return "high"
+	case count >= levels[0]:
+		return "moderate"
+	}
+	return "low"
+}
+
+// openMeteoAir is one set of values of Open-Meteo's air quality API. Pollen is null
+// outside Europe.
+type openMeteoAir struct {
+	EuropeanAQI float64  `json:"european_aqi"`
+	USAQI       float64  `json:"us_aqi"`
+	PM25        float64  `json:"pm2_5"`
+	PM10        float64  `json:"pm10"`
+	Ozone       float64  `json:"ozone"`
+	Alder       *float64 `json:"alder_pollen"`
+	Birch       *float64 `json:"birch_pollen"`
+	Olive       *float64 `json:"olive_pollen"`
+	Grass       *float64 `json:"grass_pollen"`
+	Mugwort     *float64 `json:"mugwort_pollen"`
+	Ragweed     *float64 `json:"ragweed_pollen"`
+}
+
+// airQuality converts the values, keeping pollen that is in the air.
+func (a openMeteoAir) airQuality() *AirQuality {
+	aq := &AirQuality{AQI: a.EuropeanAQI, AQILevel: aqiLevel(a.EuropeanAQI), USAQI: a.USAQI, PM25: a.PM25, PM10: a.PM10, Ozone: a.Ozone}
+	counts := []*float64{a.Alder, a.Birch, a.Olive, a.Grass, a.Mugwort, a.Ragweed}
+	for i, p := range pollenTypes {
+		if counts[i] != nil && *counts[i] > 0 {
+			aq.Pollen = append(aq.Pollen, PollenCount{Type: p.name, Count: *counts[i], Level: pollenLevel(*counts[i], p.levels)})
+		}
+	}
+	return aq
+}
+
+// openMeteoAirHourly is the hourly part of an air quality answer, one slice per value.
+type openMeteoAirHourly struct {
+	Time        []string   `json:"time"`
+	EuropeanAQI []*float64 `json:"european_aqi"`
+	USAQI       []*float64 `json:"us_aqi"`
+	PM25        []*float64 `json:"pm2_5"`
+	PM10        []*float64 `json:"pm10"`
+	Ozone       []*float64 `json:"ozone"`
+	Alder       []*float64 `json:"alder_pollen"`
+	Birch       []*float64 `json:"birch_pollen"`
+	Olive       []*float64 `json:"olive_pollen"`
+	Grass       []*float64 `json:"grass_pollen"`
+	Mugwort     []*float64 `json:"mugwort_pollen"`
+	Ragweed     []*float64 `json:"ragweed_pollen"`
+}
+
+// dayMax returns the highest value of each kind during a day ("2006-01-02", local to the
+// location), or nil when the forecast does not cover it.
+func (h openMeteoAirHourly) dayMax(day string) *AirQuality {
+	var peak openMeteoAir
+	found := false
+	raise := func(dst *float64, values []*float64, i int) {
+		if i < len(values) && values[i] != nil && *values[i] > *dst {
+			*dst = *values[i]
+		}
+	}
+	raisePollen := func(dst **float64, values []*float64, i int) {
+		if i < len(values) && values[i] != nil && (*dst == nil || *values[i] > **dst) {
+			v := *values[i]
+			*dst = &v
+		}
+	}
+	for i, t := range h.Time {
+		if len(t) < 10 || t[:10] != day {
+			continue
+		}
+		found = true
+		raise(&peak.EuropeanAQI, h.EuropeanAQI, i)
+		raise(&peak.USAQI, h.USAQI, i)
+		raise(&peak.PM25, h.PM25, i)
+		raise(&peak.PM10, h.PM10, i)
+		raise(&peak.Ozone, h.Ozone, i)
+		raisePollen(&peak.Alder, h.Alder, i)
+		raisePollen(&peak.Birch, h.Birch, i)
+		raisePollen(&peak.Olive, h.Olive, i)
+		raisePollen(&peak.Grass, h.Grass, i)
+		raisePollen(&peak.Mugwort, h.Mugwort, i)
+		raisePollen(&peak.Ragweed, h.Ragweed, i)
+	}
+	if !found {
+		return nil
+	}
+	return peak.airQuality()
+}
+
+// OpenMeteoAir fetches air quality and pollen from Open-Meteo. It needs no API key, so it
+// is used with every weather provider.
+func OpenMeteoAir(ctx context.Context, lat, lon string) (*WeatherAir, error) {
+	u := "https://air-quality-api.open-meteo.com/v1/air-quality?latitude=" + lat + "&longitude=" + lon + "&current=" + airQualityParams + "&hourly=" + airQualityParams + "&timezone=auto&forecast_days=2"
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, errors.New("air quality http status " + res.Status)
+	}
+	var raw struct {
+		Current openMeteoAir       `json:"current"`
+		Hourly  openMeteoAirHourly `json:"hourly"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	air := &WeatherAir{Current: raw.Current.airQuality()}
+	if len(raw.Hourly.Time) > 0 && len(raw.Hourly.Time[0]) >= 10 {
+		// The hours start at midnight of today at the location
+		today, err := time.Parse(time.DateOnly, raw.Hourly.Time[0][:10])
+		if err == nil {
+			air.Today = raw.Hourly.dayMax(today.Format(time.DateOnly))
+			air.Tomorrow = raw.Hourly.dayMax(today.AddDate(0, 0, 1).Format(time.DateOnly))
+		}
+	}
+	return air, nil
+}
+
+// airCacheEntry holds the air quality of a location.
+type airCacheEntry struct {
+	air       *WeatherAir
+	timestamp time.Time
+}
+
+// Air quality cache keyed by location
+var airCache = struct {
+	mu      sync.Mutex
+	entries map[string]airCacheEntry
+}{entries: make(map[string]airCacheEntry)}
+
+// CachedAir returns the air quality of a location, fetching it at most every airCacheTTL.
+func CachedAir(ctx context.Context, lat, lon string) (*WeatherAir, error) {
+	key := lat + "," + lon
+
+	airCache.mu.Lock()
+	entry, exists := airCache.entries[key]
+	airCache.mu.Unlock()
+	if exists && time.Since(entry.timestamp) < airCacheTTL {
+		RecordTiming(ctx, "air", 0, TimingHit)
+		return entry.air, nil
+	}
+
+	done := StartTiming(ctx, "air")
+	air, err := OpenMeteoAir(ctx, lat, lon)
+	done(TimingMiss)
+	if err != nil {
+		return nil, err
+	}
+
+	airCache.mu.Lock()
+	airCache.entries[key] = airCacheEntry{air: air, timestamp: time.Now()}
+	airCache.mu.Unlock()
+	return air, nil
+}
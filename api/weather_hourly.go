@@ -0,0 +1,111 @@
+package api
+
+import (
+	"time"
+)
+
+// hourlyHours is how far ahead the hourly forecast reaches.
+const hourlyHours = 48
+
+// WeatherHour is the forecast of one hour, or of one step of several hours.
+type WeatherHour struct {
+	Time              time.Time `json:"time"` // Start of the hour
+	Temperature       float64   `json:"temperature"`
+	PrecipitationProb float64   `json:"precipitationProb"` // Percent
+	Precipitation     float64   `json:"precipitation"`     // Amount during the step
+	WindSpeed         float64   `json:"windSpeed"`
+	WindDirection     int       `json:"windDirection"`
+	WeatherCode       int       `json:"weatherCode"`
+	Icon              string    `json:"icon,omitempty"`
+	IconDescription   string    `json:"iconDescription,omitempty"`
+}
+
+// WeatherHourly is the forecast for the next 48 hours.
+type WeatherHourly struct {
+	Step              int           `json:"step"` // Hours per point: 1, or 3 for OpenWeatherMap
+	TempUnit          string        `json:"tempUnit"`
+	WindUnit          string        `json:"windUnit"`
+	PrecipitationUnit string        `json:"precipitationUnit"`
+	Hours             []WeatherHour `json:"hours"`
+}
+
+// newWeatherHour sets the icon of an hour's weather code.
+func newWeatherHour(h WeatherHour) WeatherHour {
+	icon := GetWeatherIcon(h.WeatherCode)
+	h.Icon, h.IconDescription = icon.Icon, icon.Desc
+	return h
+}
+
+// openMeteoHourly is the hourly part of an Open-Meteo forecast.
+type openMeteoHourly struct {
+	Time              []string  `json:"time"`
+	Temperature       []float64 `json:"temperature_2m"`
+	PrecipitationProb []float64 `json:"precipitation_probability"`
+	Precipitation     []float64 `json:"precipitation"`
+	WindSpeed         []float64 `json:"wind_speed_10m"`
+	WindDirection     []int     `json:"wind_direction_10m"`
+	WeatherCode       []int     `json:"weather_code"`
+}
+
+// openMeteoHourlyUnits are the units of the hourly part.
+type openMeteoHourlyUnits struct {
+	Temperature   string `json:"temperature_2m"`
+	Precipitation string `json:"precipitation"`
+	WindSpeed     string `json:"wind_speed_10m"`
+}
+
+// openMeteoHourlyParams requests the hourly forecast from the current hour on.
+const openMeteoHourlyParams = "hourly=temperature_2m,precipitation_probability,precipitation,wind_speed_10m,wind_direction_10m,weather_code&forecast_hours=48"
+
+// buildOpenMeteoHourly turns Open-Meteo's hourly arrays into the hourly forecast. Times are
+// local to the location.
+func buildOpenMeteoHourly(h openMeteoHourly, units openMeteoHourlyUnits, utcOffset int) *WeatherHourly {
+	zone := time.FixedZone("", utcOffset)
+	hourly := &WeatherHourly{Step: 1, TempUnit: units.Temperature, WindUnit: units.WindSpeed, PrecipitationUnit: units.Precipitation, Hours: []WeatherHour{}}
+	at := func(values []float64, i int) float64 {
+		if i < len(values) {
+			return values[i]
+		}
+		return 0
+	}
+	for i, text := range h.Time {
+		t, err := time.ParseInLocation("2006-01-02T15:04", text, zone)
+		if err != nil || len(hourly.Hours) >= hourlyHours {
+			continue
+		}
+		hour := WeatherHour{
+			Time:              t,
+			Temperature:       at(h.Temperature, i),
+			PrecipitationProb: at(h.PrecipitationProb, i),
+			Precipitation:     at(h.Precipitation, i),
+			WindSpeed:         at(h.WindSpeed, i),
+		}
+		if i < len(h.WindDirection) {
+			hour.WindDirection = h.WindDirection[i]
+		}
+		if i < len(h.WeatherCode) {
+			hour.WeatherCode = h.WeatherCode[i]
+		}
+		hourly.Hours = append(hourly.Hours, newWeatherHour(hour))
+	}
+	return hourly
+}
+
+// Next returns a copy with the points from the current hour on that cover the given
+// number of hours, or nil when there is no forecast.
+func (wh *WeatherHourly) Next(now time.Time, hours int) *WeatherHourly {
+	if wh == nil {
+		return nil
+	}
+	next := *wh
+	step := max(wh.Step, 1)
+	// A step that started earlier still covers the current hour
+	start := now.Truncate(time.Hour).Add(-time.Duration(step-1) * time.Hour)
+	next.Hours = []WeatherHour{}
+	for _, h := range wh.Hours {
+		if !h.Time.Before(start) && len(next.Hours)*step < hours {
+			next.Hours = append(next.Hours, h)
+		}
+	}
+	return &next
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metNorwaySymbols maps MET Norway's symbol codes, without their _day/_night suffix, to
+// the WMO codes of the other providers. Thunder variants are handled apart.
+var metNorwaySymbols = map[string]int{
+	"clearsky":          0,
+	"fair":              1,
+	"partlycloudy":      2,
+	"cloudy":            3,
+	"fog":               45,
+	"lightrain":         61,
+	"rain":              63,
+	"heavyrain":         65,
+	"lightsleet":        61,
+	"sleet":             63,
+	"heavysleet":        65,
+	"lightrainshowers":  80,
+	"rainshowers":       81,
+	"heavyrainshowers":  82,
+	"lightsleetshowers": 80,
+	"sleetshowers":      81,
+	"heavysleetshowers": 82,
+	"lightsnow":         71,
+	"snow":              73,
+	"heavysnow":         75,
+	"lightsnowshowers":  71,
+	"snowshowers":       73,
+	"heavysnowshowers":  75,
+}
+
+// metNorwayCode converts a symbol code such as "lightrainshowers_day".
+func metNorwayCode(symbol string) int {
+	symbol, _, _ = strings.Cut(symbol, "_")
+	if strings.Contains(symbol, "thunder") {
+		return 95
+	}
+	if code, ok := metNorwaySymbols[symbol]; ok {
+		return code
+	}
+	return -1
+}
+
+// metNorwayPeriod is the forecast of the hour or six hours after a time.
+type metNorwayPeriod struct {
+	Summary struct {
+		SymbolCode string `json:"symbol_code"`
+	} `json:"summary"`
+	Details struct {
+		PrecipitationAmount        float64 `json:"precipitation_amount"`
+		ProbabilityOfPrecipitation float64 `json:"probability_of_precipitation"` // Only in the Nordic area
+	} `json:"details"`
+}
+
+// metNorwayWeather uses the Locationforecast API of the Norwegian Meteorological Institute
+// (yr.no), which needs no API key and covers the world.
+type metNorwayWeather struct{}
+
+func (metNorwayWeather) Name() string { return "metno" }
+
+// Summary fetches weather data from MET Norway. Its times are UTC, so the days are those of
+// the server's zone.
+func (metNorwayWeather) Summary(ctx context.Context, lat, lon, _ string) (WeatherData, error) {
+	// MET Norway asks for at most four decimals
+	latF, errLat := strconv.ParseFloat(lat, 64)
+	lonF, errLon := strconv.ParseFloat(lon, 64)
+	if errLat != nil || errLon != nil {
+		return WeatherData{}, fmt.Errorf("invalid location %s,%s", lat, lon)
+	}
+	u := "https://api.met.no/weatherapi/locationforecast/2.0/complete?lat=" + strconv.FormatFloat(latF, 'f', 4, 64) + "&lon=" + strconv.FormatFloat(lonF, 'f', 4, 64)
+	var raw struct {
+		Properties struct {
+			Timeseries []struct {
+				Time time.Time `json:"time"`
+				Data struct {
+					Instant struct {
+						Details struct {
+							AirTemperature        float64 `json:"air_temperature"`
+							RelativeHumidity      float64 `json:"relative_humidity"`
+							WindSpeed             float64 `json:"wind_speed"`
+							WindFromDirection     float64 `json:"wind_from_direction"`
+							AirPressureAtSeaLevel float64 `json:"air_pressure_at_sea_level"`
+							CloudAreaFraction     float64 `json:"cloud_area_fraction"`
+							DewPointTemperature   float64 `json:"dew_point_temperature"`
+							UltravioletIndex      float64 `json:"ultraviolet_index_clear_sky"`
+						} `json:"details"`
+					} `json:"instant"`
+					Next1Hours *metNorwayPeriod `json:"next_1_hours"`
+					Next6Hours *metNorwayPeriod `json:"next_6_hours"`
+				} `json:"data"`
+			} `json:"timeseries"`
+		} `json:"properties"`
+	}
+	if err := getWeatherJSON(ctx, "MET Norway", u, &raw); err != nil {
+		return WeatherData{}, err
+	}
+	series := raw.Properties.Timeseries
+	if len(series) == 0 {
+		return WeatherData{}, fmt.Errorf("MET Norway has no forecast for %s,%s", lat, lon)
+	}
+
+	// The first days are hourly and later ones in steps of six hours; the days use both,
+	// the hourly forecast only the hourly part
+	hourly := &WeatherHourly{Step: 1, TempUnit: "°C", WindUnit: "m/s", PrecipitationUnit: "mm", Hours: []WeatherHour{}}
+	var all []WeatherHour
+	for _, entry := range series {
+		period := entry.Data.Next1Hours
+		if period == nil {
+			period = entry.Data.Next6Hours
+		}
+		if period == nil {
+			continue
+		}
+		details := entry.Data.Instant.Details
+		hour := newWeatherHour(WeatherHour{
+			Time:              entry.Time.In(time.Local),
+			Temperature:       details.AirTemperature,
+			PrecipitationProb: period.Details.ProbabilityOfPrecipitation,
+			Precipitation:     period.Details.PrecipitationAmount,
+			WindSpeed:         details.WindSpeed,
+			WindDirection:     int(details.WindFromDirection),
+			WeatherCode:       metNorwayCode(period.Summary.SymbolCode),
+		})
+		all = append(all, hour)
+		if entry.Data.Next1Hours != nil {
+			hourly.Hours = append(hourly.Hours, hour)
+		}
+	}
+	now := time.Now()
+	today, tomorrow, forecast := daysFromHours(all, "°C", now)
+
+	first := series[0]
+	details := first.Data.Instant.Details
+	current := &WeatherCurrent{
+		Temperature:   details.AirTemperature,
+		TempUnit:      "°C",
+		Humidity:      details.RelativeHumidity,
+		WindSpeed:     details.WindSpeed,
+		WindUnit:      "m/s",
+		WindDirection: int(details.WindFromDirection),
+		Pressure:      details.AirPressureAtSeaLevel,
+		UVIndex:       details.UltravioletIndex,
+		CloudCover:    details.CloudAreaFraction,
+		DewPoint:      details.DewPointTemperature,
+		WeatherCode:   -1,
+	}
+	if period := first.Data.Next1Hours; period != nil {
+		current.WeatherCode = metNorwayCode(period.Summary.SymbolCode)
+		current.PrecipitationProb = period.Details.ProbabilityOfPrecipitation
+	}
+	icon := GetWeatherIcon(current.WeatherCode)
+	current.Icon, current.IconDescription = icon.Icon, icon.Desc
+
+	return WeatherData{
+		Summary:  fmt.Sprintf("Now: %.1f°C, %.0f%%, wind %.1f m/s", details.AirTemperature, details.RelativeHumidity, details.WindSpeed),
+		Forecast: forecast,
+		Current:  current,
+		Today:    today,
+		Tomorrow: tomorrow,
+		Hourly:   hourly.Next(now, hourlyHours),
+	}, nil
+}
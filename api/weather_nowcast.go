@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Nowcast resolution and horizon: Open-Meteo's 15-minute precipitation for two hours.
+const (
+	nowcastStep  = 15 * time.Minute
+	nowcastSteps = 8
+)
+
+// nowcastThreshold is the precipitation per step (mm) counted as rain.
+const nowcastThreshold = 0.1
+
+// NowcastPoint is the precipitation of one step of the nowcast.
+type NowcastPoint struct {
+	Time          time.Time `json:"time"`          // Start of the step
+	Precipitation float64   `json:"precipitation"` // Amount during the step
+}
+
+// WeatherNowcast is the precipitation for the next two hours.
+type WeatherNowcast struct {
+	Step     int            `json:"step"` // Minutes per point
+	Unit     string         `json:"unit"` // e.g. "mm"
+	Points   []NowcastPoint `json:"points"`
+	Raining  bool           `json:"raining"`
+	StartsIn *int           `json:"startsIn,omitempty"` // Minutes until rain starts when dry now
+	EndsIn   *int           `json:"endsIn,omitempty"`   // Minutes until rain stops when raining now
+	Summary  string         `json:"summary"`            // e.g. "Rain starting in 23 minutes"
+}
+
+// openMeteoMinutely is the minutely_15 part of an Open-Meteo forecast.
+type openMeteoMinutely struct {
+	Time          []string  `json:"time"`
+	Precipitation []float64 `json:"precipitation"`
+}
+
+// OpenMeteoNowcast fetches the precipitation nowcast from Open-Meteo. It needs no API key,
+// so it is also used with the other weather providers.
+func OpenMeteoNowcast(ctx context.Context, lat, lon string) (*WeatherNowcast, error) {
+	u := "https://api.open-meteo.com/v1/forecast?latitude=" + lat + "&longitude=" + lon + "&" + openMeteoNowcastParams + "&timezone=auto"
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	req.Header.Set("User-Agent", "lan-index/1.0")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, errors.New("weather http status " + res.Status)
+	}
+	var raw struct {
+		UTCOffsetSeconds int               `json:"utc_offset_seconds"`
+		Minutely         openMeteoMinutely `json:"minutely_15"`
+		MinutelyUnits    struct {
+			Precipitation string `json:"precipitation"`
+		} `json:"minutely_15_units"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	return buildNowcast(raw.Minutely, raw.MinutelyUnits.Precipitation, raw.UTCOffsetSeconds, time.Now())
+}
+
+// openMeteoNowcastParams requests the current and next 15-minute steps.
+var openMeteoNowcastParams = fmt.Sprintf("minutely_15=precipitation&past_minutely_15=1&forecast_minutely_15=%d", nowcastSteps+1)
+
+// buildNowcast turns Open-Meteo's 15-minute precipitation into a nowcast from now on.
+// Open-Meteo times are local to the location and label the end of each step.
+func buildNowcast(m openMeteoMinutely, unit string, utcOffset int, now time.Time) (*WeatherNowcast, error) {
+	zone := time.FixedZone("", utcOffset)
+	nc := &WeatherNowcast{Step: int(nowcastStep / time.Minute), Unit: unit, Points: []NowcastPoint{}}
+	if nc.Unit == "" {
+		nc.Unit = "mm"
+	}
+	for i, ts := range m.Time {
+		if i >= len(m.Precipitation) || len(nc.Points) >= nowcastSteps {
+			break
+		}
+		end, err := time.ParseInLocation("2006-01-02T15:04", ts, zone)
+		if err != nil || !end.After(now) {
+			continue
+		}
+		nc.Points = append(nc.Points, NowcastPoint{Time: end.Add(-nowcastStep), Precipitation: m.Precipitation[i]})
+	}
+	if len(nc.Points) == 0 {
+		return nil, errors.New("no nowcast data")
+	}
+
+	threshold := nowcastThreshold
+	if nc.Unit == "inch" {
+		threshold /= 25.4
+	}
+	nc.Raining = nc.Points[0].Precipitation >= threshold
+	horizon := nc.Points[len(nc.Points)-1].Time.Add(nowcastStep).Sub(now)
+	for _, p := range nc.Points[1:] {
+		if (p.Precipitation >= threshold) == nc.Raining {
+			continue
+		}
+		minutes := max(int(p.Time.Sub(now).Round(time.Minute)/time.Minute), 1)
+		if nc.Raining {
+			nc.EndsIn = &minutes
+		} else {
+			nc.StartsIn = &minutes
+		}
+		break
+	}
+
+	switch {
+	case nc.StartsIn != nil:
+		nc.Summary = fmt.Sprintf("Rain starting in %s", nowcastMinutes(*nc.StartsIn))
+	case nc.EndsIn != nil:
+		nc.Summary = fmt.Sprintf("Rain stopping in %s", nowcastMinutes(*nc.EndsIn))
+	case nc.Raining:
+		nc.Summary = fmt.Sprintf("Rain for the next %s", nowcastMinutes(int(horizon/time.Minute)))
+	default:
+		nc.Summary = fmt.Sprintf("No rain for the next %s", nowcastMinutes(int(horizon/time.Minute)))
+	}
+	return nc, nil
+}
+
+// nowcastMinutes describes a number of minutes, in hours from two hours on.
+func nowcastMinutes(m int) string {
+	switch {
+	case m == 1:
+		return "1 minute"
+	case m >= 110:
+		return fmt.Sprintf("%d hours", (m+30)/60)
+	}
+	return fmt.Sprintf("%d minutes", m)
+}
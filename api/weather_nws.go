@@ -0,0 +1,238 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// nwsIcons maps the conditions of NWS icon URLs to the WMO codes of the other providers.
+var nwsIcons = map[string]int{
+	"skc":             0,
+	"hot":             0,
+	"cold":            0,
+	"few":             1,
+	"sct":             2,
+	"bkn":             2,
+	"ovc":             3,
+	"fog":             45,
+	"haze":            45,
+	"smoke":           45,
+	"dust":            45,
+	"rain":            63,
+	"rain_showers":    80,
+	"rain_showers_hi": 80,
+	"fzra":            61,
+	"rain_fzra":       61,
+	"rain_sleet":      61,
+	"sleet":           61,
+	"snow_fzra":       71,
+	"snow_sleet":      71,
+	"rain_snow":       71,
+	"snow":            73,
+	"blizzard":        75,
+	"tsra":            95,
+	"tsra_sct":        95,
+	"tsra_hi":         95,
+	"tornado":         95,
+	"hurricane":       95,
+	"tropical_storm":  95,
+}
+
+// nwsCode converts the icon URL of a period, e.g.
+// "https://api.weather.gov/icons/land/day/rain_showers,40?size=small". A period that
+// changes has two conditions; the first is used.
+func nwsCode(icon string) int {
+	u, err := url.Parse(icon)
+	if err != nil {
+		return -1
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, part := range parts {
+		if part != "day" && part != "night" || i+1 >= len(parts) {
+			continue
+		}
+		condition, _, _ := strings.Cut(parts[i+1], ",")
+		condition = strings.TrimPrefix(condition, "wind_")
+		if code, ok := nwsIcons[condition]; ok {
+			return code
+		}
+	}
+	return -1
+}
+
+// nwsCompass is the wind direction of each 16th of the compass.
+var nwsCompass = []string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
+
+// nwsSpeedPattern finds the numbers of a wind speed such as "10 to 15 km/h".
+var nwsSpeedPattern = regexp.MustCompile(`\d+(?:\.\d+)?`)
+
+// nwsValue is a quantity of the NWS API; Value is null when unknown.
+type nwsValue struct {
+	Value *float64 `json:"value"`
+}
+
+func (v nwsValue) get() float64 {
+	if v.Value == nil {
+		return 0
+	}
+	return *v.Value
+}
+
+// nwsPoint is where the NWS keeps the forecast of a location.
+type nwsPoint struct {
+	forecastHourly string
+	zone           *time.Location
+}
+
+// Grid points by location; they do not change
+var nwsPoints = struct {
+	mu      sync.Mutex
+	entries map[string]nwsPoint
+}{entries: make(map[string]nwsPoint)}
+
+// nwsWeather uses the US National Weather Service, which needs no API key but only covers
+// the United States.
+type nwsWeather struct{}
+
+func (nwsWeather) Name() string { return "nws" }
+
+// point looks up the hourly forecast URL and zone of a location.
+func (nwsWeather) point(ctx context.Context, lat, lon float64) (nwsPoint, error) {
+	key := strconv.FormatFloat(lat, 'f', 4, 64) + "," + strconv.FormatFloat(lon, 'f', 4, 64)
+	nwsPoints.mu.Lock()
+	p, ok := nwsPoints.entries[key]
+	nwsPoints.mu.Unlock()
+	if ok {
+		return p, nil
+	}
+
+	var raw struct {
+		Properties struct {
+			ForecastHourly string `json:"forecastHourly"`
+			TimeZone       string `json:"timeZone"`
+		} `json:"properties"`
+	}
+	if err := getWeatherJSON(ctx, "NWS", "https://api.weather.gov/points/"+key, &raw); err != nil {
+		return nwsPoint{}, fmt.Errorf("%w (the NWS only covers the United States)", err)
+	}
+	if raw.Properties.ForecastHourly == "" {
+		return nwsPoint{}, fmt.Errorf("NWS has no forecast for %s", key)
+	}
+	p = nwsPoint{forecastHourly: raw.Properties.ForecastHourly, zone: time.Local}
+	if zone, err := time.LoadLocation(raw.Properties.TimeZone); err == nil {
+		p.zone = zone
+	}
+
+	nwsPoints.mu.Lock()
+	nwsPoints.entries[key] = p
+	nwsPoints.mu.Unlock()
+	return p, nil
+}
+
+// Summary fetches weather data from the NWS hourly forecast in metric units. The current
+// weather is that of the current hour.
+func (n nwsWeather) Summary(ctx context.Context, lat, lon, _ string) (WeatherData, error) {
+	latF, errLat := strconv.ParseFloat(lat, 64)
+	lonF, errLon := strconv.ParseFloat(lon, 64)
+	if errLat != nil || errLon != nil {
+		return WeatherData{}, fmt.Errorf("invalid location %s,%s", lat, lon)
+	}
+	p, err := n.point(ctx, latF, lonF)
+	if err != nil {
+		return WeatherData{}, err
+	}
+
+	var raw struct {
+		Properties struct {
+			Periods []struct {
+				StartTime                  time.Time `json:"startTime"`
+				Temperature                float64   `json:"temperature"`
+				TemperatureUnit            string    `json:"temperatureUnit"`
+				ProbabilityOfPrecipitation nwsValue  `json:"probabilityOfPrecipitation"`
+				Dewpoint                   nwsValue  `json:"dewpoint"`
+				RelativeHumidity           nwsValue  `json:"relativeHumidity"`
+				WindSpeed                  string    `json:"windSpeed"`
+				WindDirection              string    `json:"windDirection"`
+				Icon                       string    `json:"icon"`
+				ShortForecast              string    `json:"shortForecast"`
+			} `json:"periods"`
+		} `json:"properties"`
+	}
+	if err := getWeatherJSON(ctx, "NWS", p.forecastHourly+"?units=si", &raw); err != nil {
+		return WeatherData{}, err
+	}
+	periods := raw.Properties.Periods
+	if len(periods) == 0 {
+		return WeatherData{}, fmt.Errorf("NWS has no forecast for %s,%s", lat, lon)
+	}
+
+	// The hourly forecast has neither amounts of precipitation nor wind in numbers
+	hourly := &WeatherHourly{Step: 1, TempUnit: "°C", WindUnit: "km/h", PrecipitationUnit: "mm", Hours: []WeatherHour{}}
+	for _, period := range periods {
+		temp := period.Temperature
+		if period.TemperatureUnit == "F" {
+			temp = (temp - 32) * 5 / 9
+		}
+		hour := WeatherHour{
+			Time:              period.StartTime.In(p.zone),
+			Temperature:       temp,
+			PrecipitationProb: period.ProbabilityOfPrecipitation.get(),
+			WindDirection:     max(slices.Index(nwsCompass, period.WindDirection), 0) * 45 / 2,
+			WeatherCode:       nwsCode(period.Icon),
+		}
+		if speeds := nwsSpeedPattern.FindAllString(period.WindSpeed, -1); len(speeds) > 0 {
+			hour.WindSpeed, _ = strconv.ParseFloat(speeds[len(speeds)-1], 64)
+			if strings.HasSuffix(period.WindSpeed, "mph") {
+				hour.WindSpeed /= 0.621371
+			}
+		}
+		hour = newWeatherHour(hour)
+		if hour.WeatherCode < 0 {
+			hour.IconDescription = period.ShortForecast
+		}
+		hourly.Hours = append(hourly.Hours, hour)
+	}
+	now := time.Now()
+	today, tomorrow, forecast := daysFromHours(hourly.Hours, "°C", now)
+	hourly = hourly.Next(now, hourlyHours)
+	if len(hourly.Hours) == 0 {
+		return WeatherData{}, fmt.Errorf("NWS forecast for %s,%s is out of date", lat, lon)
+	}
+
+	first, period := hourly.Hours[0], periods[0]
+	for _, candidate := range periods {
+		if candidate.StartTime.Equal(first.Time) {
+			period = candidate
+			break
+		}
+	}
+	current := &WeatherCurrent{
+		Temperature:       first.Temperature,
+		TempUnit:          "°C",
+		Humidity:          period.RelativeHumidity.get(),
+		WindSpeed:         first.WindSpeed,
+		WindUnit:          "km/h",
+		WindDirection:     first.WindDirection,
+		DewPoint:          period.Dewpoint.get(),
+		PrecipitationProb: first.PrecipitationProb,
+		WeatherCode:       first.WeatherCode,
+		Icon:              first.Icon,
+		IconDescription:   first.IconDescription,
+	}
+
+	return WeatherData{
+		Summary:  fmt.Sprintf("Now: %.1f°C, %.0f%%, wind %.1f km/h", current.Temperature, current.Humidity, current.WindSpeed),
+		Forecast: forecast,
+		Current:  current,
+		Today:    today,
+		Tomorrow: tomorrow,
+		Hourly:   hourly,
+	}, nil
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// weatherUserAgent identifies the dashboard; MET Norway and the NWS refuse anonymous clients.
+const weatherUserAgent = "lan-index/1.0 (+https://github.com/earentir/homepage)"
+
+// WeatherProvider fetches the weather of a location from one weather service.
+type WeatherProvider interface {
+	Name() string
+	// Summary fetches the current weather, today, tomorrow and the hourly forecast. apiKey
+	// is empty unless configured; providers that need one return an error without it.
+	Summary(ctx context.Context, lat, lon, apiKey string) (WeatherData, error)
+}
+
+// Providers by config name
+var weatherProviders = map[string]WeatherProvider{
+	"openmeteo":      openMeteoWeather{},
+	"openweathermap": openWeatherMapWeather{},
+	"weatherapi":     weatherAPIWeather{},
+	"metno":          metNorwayWeather{},
+	"nws":            nwsWeather{},
+}
+
+// WeatherProviderNames returns the config names of the providers, sorted.
+func WeatherProviderNames() []string {
+	return slices.Sorted(maps.Keys(weatherProviders))
+}
+
+// GetWeatherProvider returns the provider of a config name, Open-Meteo when it is empty or
+// unknown.
+func GetWeatherProvider(name string) WeatherProvider {
+	if p, ok := weatherProviders[name]; ok {
+		return p
+	}
+	return weatherProviders["openmeteo"]
+}
+
+// getWeatherJSON decodes the JSON answer of a weather service, named in its errors.
+func getWeatherJSON(ctx context.Context, service, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", weatherUserAgent)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := res.Body.Close(); closeErr != nil {
+			Logger("weather").Warn("closing weather response body", "error", closeErr)
+		}
+	}()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s error: %s", service, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// daysFromHours builds today, tomorrow and the lines of the next three days for providers
+// that only forecast by the hour. Days are those of the hours' zone.
+func daysFromHours(hours []WeatherHour, tempUnit string, now time.Time) (today, tomorrow *WeatherDay, forecast []string) {
+	if len(hours) == 0 {
+		return nil, nil, nil
+	}
+	now = now.In(hours[0].Time.Location())
+	for i := range 4 {
+		date := now.AddDate(0, 0, i).Format(time.DateOnly)
+		var day *WeatherDay
+		noon := -1
+		for _, h := range hours {
+			if h.Time.Format(time.DateOnly) != date {
+				continue
+			}
+			if day == nil {
+				day = &WeatherDay{TempMax: h.Temperature, TempMin: h.Temperature, TempUnit: tempUnit, WeatherCode: h.WeatherCode}
+			}
+			day.TempMax = max(day.TempMax, h.Temperature)
+			day.TempMin = min(day.TempMin, h.Temperature)
+			day.PrecipitationProb = max(day.PrecipitationProb, h.PrecipitationProb)
+			// The weather around noon stands for the day
+			if distance := abs(h.Time.Hour() - 12); noon < 0 || distance < noon {
+				noon, day.WeatherCode = distance, h.WeatherCode
+			}
+		}
+		if day == nil {
+			continue
+		}
+		icon := GetWeatherIcon(day.WeatherCode)
+		day.Icon, day.IconDescription = icon.Icon, icon.Desc
+		switch i {
+		case 0:
+			today = day
+		case 1:
+			tomorrow = day
+		}
+		if i > 0 {
+			forecast = append(forecast, date[5:]+": "+Format1(day.TempMax)+"°/"+Format1(day.TempMin)+"°")
+		}
+	}
+	return today, tomorrow, forecast
+}
+
+// abs returns the absolute value of an int.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
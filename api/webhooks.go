@@ -0,0 +1,385 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// webhooksFile holds the incoming webhook tokens across restarts.
+const webhooksFile = "webhooks.json"
+
+// maxBanners caps the number of active dashboard banners.
+const maxBanners = 20
+
+// ErrUnknownWebhookToken is returned when a webhook is received for a token that does not exist.
+var ErrUnknownWebhookToken = errors.New("unknown webhook token")
+
+// WebhookToken represents an incoming webhook endpoint.
+type WebhookToken struct {
+	Token     string     `json:"token"`
+	Name      string     `json:"name"`
+	Banner    bool       `json:"banner"` // Also show received events as dashboard banners
+	CreatedAt time.Time  `json:"createdAt"`
+	LastUsed  *time.Time `json:"lastUsed,omitempty"`
+}
+
+// Banner is a dismissible message shown at the top of the dashboard.
+type Banner struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Title    string    `json:"title"`
+	Message  string    `json:"message,omitempty"`
+	Severity string    `json:"severity"`
+	Source   string    `json:"source,omitempty"`
+}
+
+// WebhookManager manages incoming webhook tokens and dashboard banners.
+type WebhookManager struct {
+	mu      sync.Mutex
+	tokens  map[string]*WebhookToken
+	banners []Banner
+	loaded  bool
+}
+
+// Global webhook manager instance
+var webhookManager = &WebhookManager{tokens: make(map[string]*WebhookToken)}
+
+// GetWebhookManager returns the global webhook manager instance.
+func GetWebhookManager() *WebhookManager {
+	return webhookManager
+}
+
+// load reads the tokens file. Caller must hold mu.
+func (wm *WebhookManager) load() {
+	if wm.loaded {
+		return
+	}
+	wm.loaded = true
+	data, err := os.ReadFile(webhooksFile)
+	if err != nil {
+		return
+	}
+	var tokens []*WebhookToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		GetDebugLogger().Logf("webhooks", "failed to parse %s: %v", webhooksFile, err)
+		return
+	}
+	for _, t := range tokens {
+		wm.tokens[t.Token] = t
+	}
+}
+
+// save writes the tokens file. Caller must hold mu.
+func (wm *WebhookManager) save() error {
+	data, err := json.MarshalIndent(wm.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(webhooksFile, data, 0600)
+}
+
+// list returns tokens sorted by creation time. Caller must hold mu.
+func (wm *WebhookManager) list() []WebhookToken {
+	result := make([]WebhookToken, 0, len(wm.tokens))
+	for _, t := range wm.tokens {
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// Tokens returns all webhook tokens.
+func (wm *WebhookManager) Tokens() []WebhookToken {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	wm.load()
+	return wm.list()
+}
+
+// CreateToken creates a new webhook token.
+func (wm *WebhookManager) CreateToken(name string, banner bool) (WebhookToken, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return WebhookToken{}, err
+	}
+	t := &WebhookToken{
+		Token:     hex.EncodeToString(buf),
+		Name:      name,
+		Banner:    banner,
+		CreatedAt: time.Now(),
+	}
+
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	wm.load()
+	wm.tokens[t.Token] = t
+	return *t, wm.save()
+}
+
+// DeleteToken removes a webhook token.
+func (wm *WebhookManager) DeleteToken(token string) (bool, error) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	wm.load()
+	if _, ok := wm.tokens[token]; !ok {
+		return false, nil
+	}
+	delete(wm.tokens, token)
+	return true, wm.save()
+}
+
+// use looks up a token and marks it as used.
+func (wm *WebhookManager) use(token string) (WebhookToken, bool) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	wm.load()
+	t, ok := wm.tokens[token]
+	if !ok {
+		return WebhookToken{}, false
+	}
+	now := time.Now()
+	t.LastUsed = &now
+	_ = wm.save()
+	return *t, true
+}
+
+// AddBanner shows a banner on all connected dashboards.
+func (wm *WebhookManager) AddBanner(b Banner) Banner {
+	if b.ID == "" {
+		b.ID = strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	if b.Time.IsZero() {
+		b.Time = time.Now()
+	}
+
+	wm.mu.Lock()
+	wm.banners = append(wm.banners, b)
+	if len(wm.banners) > maxBanners {
+		wm.banners = wm.banners[len(wm.banners)-maxBanners:]
+	}
+	wm.mu.Unlock()
+
+	GetWSManager().Broadcast(map[string]interface{}{
+		"type":   "banner",
+		"banner": b,
+	})
+	return b
+}
+
+// DismissBanner removes a banner from all dashboards.
+func (wm *WebhookManager) DismissBanner(id string) bool {
+	wm.mu.Lock()
+	found := false
+	for i, b := range wm.banners {
+		if b.ID == id {
+			wm.banners = append(wm.banners[:i], wm.banners[i+1:]...)
+			found = true
+			break
+		}
+	}
+	wm.mu.Unlock()
+
+	if found {
+		GetWSManager().Broadcast(map[string]interface{}{
+			"type": "banner-dismiss",
+			"id":   id,
+		})
+	}
+	return found
+}
+
+// Banners returns the active banners.
+func (wm *WebhookManager) Banners() []Banner {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	result := make([]Banner, len(wm.banners))
+	copy(result, wm.banners)
+	return result
+}
+
+// Receive converts an incoming webhook into a timeline entry (and banner if enabled).
+func (wm *WebhookManager) Receive(token string, header http.Header, body []byte) (TimelineEvent, error) {
+	t, ok := wm.use(token)
+	if !ok {
+		return TimelineEvent{}, ErrUnknownWebhookToken
+	}
+
+	ev, err := ParseWebhookPayload(header, body)
+	if err != nil {
+		return TimelineEvent{}, err
+	}
+	ev.Source = TimelineSourceWebhook
+	if t.Name != "" {
+		ev.Title = t.Name + ": " + ev.Title
+	}
+	ev = GetTimeline().Add(ev)
+
+	if t.Banner {
+		wm.AddBanner(Banner{
+			Title:    ev.Title,
+			Message:  ev.Detail,
+			Severity: ev.Severity,
+			Source:   t.Name,
+		})
+	}
+	return ev, nil
+}
+
+// ParseWebhookPayload detects GitHub, Grafana and Uptime Kuma payloads and falls back
+// to a generic {"title", "message", "severity"} format.
+func ParseWebhookPayload(header http.Header, body []byte) (TimelineEvent, error) {
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return TimelineEvent{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if event := header.Get("X-GitHub-Event"); event != "" {
+		return parseGitHubWebhook(event, payload), nil
+	}
+	if _, ok := payload["heartbeat"]; ok {
+		return parseUptimeKumaWebhook(payload), nil
+	}
+	if _, ok := payload["alerts"]; ok {
+		return parseGrafanaWebhook(payload), nil
+	}
+	return parseGenericWebhook(payload, body), nil
+}
+
+// jsonString walks a decoded JSON object and returns the string at the given path.
+func jsonString(v any, path ...string) string {
+	for _, key := range path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return ""
+		}
+		v = m[key]
+	}
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	return ""
+}
+
+func parseGitHubWebhook(event string, p map[string]any) TimelineEvent {
+	repo := jsonString(p, "repository", "full_name")
+	sender := jsonString(p, "sender", "login")
+	action := jsonString(p, "action")
+	ev := TimelineEvent{Severity: "info", Title: "GitHub " + event + " on " + repo}
+
+	switch event {
+	case "push":
+		commits, _ := p["commits"].([]any)
+		branch := strings.TrimPrefix(jsonString(p, "ref"), "refs/heads/")
+		ev.Title = fmt.Sprintf("%s pushed %d commit(s) to %s/%s", sender, len(commits), repo, branch)
+		ev.Detail = jsonString(p, "head_commit", "message")
+	case "pull_request":
+		ev.Title = fmt.Sprintf("PR #%s %s in %s", jsonString(p, "number"), action, repo)
+		ev.Detail = jsonString(p, "pull_request", "title")
+	case "issues":
+		ev.Title = fmt.Sprintf("Issue #%s %s in %s", jsonString(p, "issue", "number"), action, repo)
+		ev.Detail = jsonString(p, "issue", "title")
+	case "release":
+		ev.Title = fmt.Sprintf("Release %s %s in %s", jsonString(p, "release", "tag_name"), action, repo)
+		ev.Detail = jsonString(p, "release", "name")
+	case "workflow_run":
+		conclusion := jsonString(p, "workflow_run", "conclusion")
+		ev.Title = fmt.Sprintf("Workflow %s %s in %s", jsonString(p, "workflow_run", "name"), action, repo)
+		ev.Detail = conclusion
+		switch conclusion {
+		case "failure", "timed_out":
+			ev.Severity = "critical"
+		case "success":
+			ev.Severity = "ok"
+		}
+	case "ping":
+		ev.Title = "GitHub webhook connected for " + repo
+		ev.Detail = jsonString(p, "zen")
+	default:
+		if action != "" {
+			ev.Title += " (" + action + ")"
+		}
+	}
+	return ev
+}
+
+func parseGrafanaWebhook(p map[string]any) TimelineEvent {
+	status := jsonString(p, "status")
+	title := jsonString(p, "title")
+	if title == "" {
+		title = "Grafana alert " + status
+	}
+	ev := TimelineEvent{Title: title, Detail: jsonString(p, "message"), Severity: "warning"}
+	switch status {
+	case "firing":
+		ev.Severity = "critical"
+	case "resolved":
+		ev.Severity = "ok"
+	}
+	if ev.Detail == "" {
+		if alerts, ok := p["alerts"].([]any); ok && len(alerts) > 0 {
+			ev.Detail = jsonString(alerts[0], "annotations", "summary")
+		}
+	}
+	return ev
+}
+
+func parseUptimeKumaWebhook(p map[string]any) TimelineEvent {
+	name := jsonString(p, "monitor", "name")
+	msg := jsonString(p, "msg")
+	if msg == "" {
+		msg = jsonString(p, "heartbeat", "msg")
+	}
+	ev := TimelineEvent{Title: name + " status changed", Detail: msg, Severity: "warning"}
+	switch jsonString(p, "heartbeat", "status") {
+	case "0":
+		ev.Title = name + " went down"
+		ev.Severity = "critical"
+	case "1":
+		ev.Title = name + " is back up"
+		ev.Severity = "ok"
+	}
+	return ev
+}
+
+func parseGenericWebhook(p map[string]any, body []byte) TimelineEvent {
+	ev := TimelineEvent{
+		Title:    jsonString(p, "title"),
+		Detail:   jsonString(p, "message"),
+		Severity: jsonString(p, "severity"),
+	}
+	if ev.Detail == "" {
+		ev.Detail = jsonString(p, "detail")
+	}
+	if ev.Title == "" {
+		ev.Title = "Webhook received"
+		if ev.Detail == "" {
+			ev.Detail = string(body)
+			if len(ev.Detail) > 500 {
+				ev.Detail = ev.Detail[:500] + "…"
+			}
+		}
+	}
+	switch ev.Severity {
+	case "info", "warning", "critical", "ok":
+	default:
+		ev.Severity = "info"
+	}
+	return ev
+}
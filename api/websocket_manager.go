@@ -1,15 +1,68 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/gorilla/websocket"
 )
 
+// WebSocket topics clients can subscribe to.
+const (
+	WSTopicSystem   = "system"
+	WSTopicMonitors = "monitors"
+	WSTopicStorage  = "storage"
+	WSTopicTimers   = "timers"
+	WSTopicRSS      = "rss"
+	WSTopicGitHub   = "github"
+	WSTopicMQTT     = "mqtt"
+)
+
+// wsTopics is the set of valid subscription topics.
+var wsTopics = map[string]bool{
+	WSTopicSystem:   true,
+	WSTopicMonitors: true,
+	WSTopicStorage:  true,
+	WSTopicTimers:   true,
+	WSTopicRSS:      true,
+	WSTopicGitHub:   true,
+	WSTopicMQTT:     true,
+}
+
+// wsTimerTopics maps timer keys to the topic their refresh notifications are sent on.
+// Timer keys not listed here are sent on the timers topic.
+var wsTimerTopics = map[string]string{
+	"cpu":        WSTopicSystem,
+	"ram":        WSTopicSystem,
+	"disk":       WSTopicSystem,
+	"monitoring": WSTopicMonitors,
+	"rss":        WSTopicRSS,
+	"github":     WSTopicGitHub,
+}
+
+// TopicForTimer returns the topic used for refresh notifications of the given timer key.
+// Timers of module instances, keyed by the instance ID, use the topic of their type.
+func TopicForTimer(timerKey string) string {
+	timerKey = ModuleType(timerKey)
+	if topic, ok := wsTimerTopics[timerKey]; ok {
+		return topic
+	}
+	return WSTopicTimers
+}
+
+// WSClientMessage is a message sent by a client over the WebSocket.
+type WSClientMessage struct {
+	Type   string   `json:"type"`
+	Topics []string `json:"topics"`
+}
+
 // connWithMutex wraps a WebSocket connection with its own mutex for thread-safe writes.
 type connWithMutex struct {
-	conn *websocket.Conn
-	mu   sync.Mutex
+	conn   *websocket.Conn
+	mu     sync.Mutex
+	topics map[string]bool
 }
 
 // WSConnectionManager manages WebSocket connections for broadcasting.
@@ -30,7 +83,8 @@ func (m *WSConnectionManager) Add(conn *websocket.Conn) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.connections[conn] = &connWithMutex{
-		conn: conn,
+		conn:   conn,
+		topics: make(map[string]bool),
 	}
 }
 
@@ -64,9 +118,117 @@ func (m *WSConnectionManager) Broadcast(message map[string]interface{}) {
 	}
 }
 
+// BroadcastTopic sends a message to clients subscribed to the given topic.
+func (m *WSConnectionManager) BroadcastTopic(topic string, message map[string]interface{}) {
+	m.mu.RLock()
+	conns := make([]*connWithMutex, 0, len(m.connections))
+	for _, cwm := range m.connections {
+		if cwm.topics[topic] {
+			conns = append(conns, cwm)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, cwm := range conns {
+		cwm.mu.Lock()
+		err := cwm.conn.WriteJSON(message)
+		cwm.mu.Unlock()
+
+		if err != nil {
+			m.Remove(cwm.conn)
+		}
+	}
+}
+
+// HasSubscribers reports whether any client is subscribed to the given topic.
+func (m *WSConnectionManager) HasSubscribers(topic string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, cwm := range m.connections {
+		if cwm.topics[topic] {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSubscribed reports whether a connection is subscribed to the given topic.
+func (m *WSConnectionManager) IsSubscribed(conn *websocket.Conn, topic string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cwm, exists := m.connections[conn]
+	return exists && cwm.topics[topic]
+}
+
+// Subscriptions returns the sorted topics a connection is subscribed to.
+func (m *WSConnectionManager) Subscriptions(conn *websocket.Conn) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	topics := []string{}
+	if cwm, exists := m.connections[conn]; exists {
+		for topic := range cwm.topics {
+			topics = append(topics, topic)
+		}
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// setTopics subscribes or unsubscribes a connection from the given topics.
+func (m *WSConnectionManager) setTopics(conn *websocket.Conn, topics []string, subscribe bool) error {
+	for _, topic := range topics {
+		if !wsTopics[topic] {
+			return fmt.Errorf("unknown topic: %s", topic)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cwm, exists := m.connections[conn]
+	if !exists {
+		return fmt.Errorf("connection not registered")
+	}
+	for _, topic := range topics {
+		if subscribe {
+			cwm.topics[topic] = true
+		} else {
+			delete(cwm.topics, topic)
+		}
+	}
+	return nil
+}
+
+// HandleClientMessage processes a subscribe/unsubscribe message from a client and replies
+// with the resulting subscriptions.
+func (m *WSConnectionManager) HandleClientMessage(conn *websocket.Conn, data []byte) error {
+	var msg WSClientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return m.WriteJSON(conn, map[string]any{"type": "error", "error": "invalid message"})
+	}
+
+	var err error
+	switch msg.Type {
+	case "subscribe":
+		err = m.setTopics(conn, msg.Topics, true)
+	case "unsubscribe":
+		err = m.setTopics(conn, msg.Topics, false)
+	default:
+		err = fmt.Errorf("unknown message type: %s", msg.Type)
+	}
+	if err != nil {
+		return m.WriteJSON(conn, map[string]any{"type": "error", "error": err.Error()})
+	}
+
+	GetDebugLogger().Logf("websocket", "%s %v, now subscribed to %v", msg.Type, msg.Topics, m.Subscriptions(conn))
+	return m.WriteJSON(conn, map[string]any{
+		"type":   "subscribed",
+		"topics": m.Subscriptions(conn),
+	})
+}
+
 // BroadcastStorageUpdate broadcasts a storage update notification.
 func (m *WSConnectionManager) BroadcastStorageUpdate(key string, version int64) {
-	m.Broadcast(map[string]interface{}{
+	m.BroadcastTopic(WSTopicStorage, map[string]interface{}{
 		"type":    "storage-update",
 		"key":     key,
 		"version": version,
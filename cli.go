@@ -0,0 +1,598 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"homepage/api"
+)
+
+// Names in a backup archive. Data files, saved configs, themes and the database are kept
+// under their own directory and restored to where the config places them.
+const (
+	backupManifestName = "backup.json"
+	backupConfigName   = "homepage.config"
+	backupDataDir      = "data/"
+	backupConfigsDir   = "configs/"
+	backupThemesDir    = "themes/"
+	backupStoreName    = "store/homepage.db"
+)
+
+// savedConfigsDir holds the configurations saved through Preferences > Config.
+const savedConfigsDir = "configs"
+
+var savedConfigPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+\.json$`)
+
+// BackupManifest describes a backup archive.
+type BackupManifest struct {
+	App     string    `json:"app"`
+	Version string    `json:"version"`
+	Created time.Time `json:"created"`
+	Files   int       `json:"files"`
+}
+
+// addServerFlags adds the flags of the server, shared by the root and serve commands.
+func addServerFlags(cmd *cobra.Command) {
+	cmd.Flags().String("port", "", "Port to listen on (overrides config file)")
+	cmd.Flags().String("listen", "", "IP address to listen on (overrides config file)")
+	cmd.Flags().Bool("debug", false, "Enable debug output")
+	cmd.Flags().String("log", "", "Path to log file or directory")
+	cmd.Flags().Bool("healthcheck", false, "Check /healthz of the running server and exit non-zero when it fails")
+}
+
+func newServeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run the dashboard server (the default)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServer(cmd)
+		},
+	}
+	addServerFlags(cmd)
+	return cmd
+}
+
+func newVersionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print the version",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("homepage %s (%s %s/%s)\n", appversion, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+		},
+	}
+}
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Check the config file",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Validate the config file, including unknown keys, and exit non-zero when it is invalid",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return runConfigValidate(cmd)
+		},
+	})
+	return cmd
+}
+
+func newBackupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Export or import the config and data files",
+		Long: "Export or import the config file, the data files, saved configurations, user themes and the\n" +
+			"SQLite store. Run it from the dashboard's working directory, where the data files are kept.",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "export [file]",
+		Short: "Write a backup archive (default homepage-backup-<time>.tar.gz, - for stdout)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			out := "homepage-backup-" + time.Now().Format("20060102-150405") + ".tar.gz"
+			if len(args) == 1 {
+				out = args[0]
+			}
+			return runBackupExport(cmd, out)
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "import <file>",
+		Short: "Restore a backup archive; the dashboard must be stopped",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return runBackupImport(cmd, args[0])
+		},
+	})
+	return cmd
+}
+
+func newThemeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "theme",
+		Short: "Inspect the theme templates",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the built-in and user theme templates with their color schemes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return runThemeList(cmd)
+		},
+	})
+	return cmd
+}
+
+func newTokenCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage API tokens",
+		Long: "Manage API tokens in tokens.json of the working directory. Tokens can only be created or\n" +
+			"deleted while the dashboard is stopped; while it runs use Preferences or /api/tokens.",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the API tokens",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return runTokenList()
+		},
+	})
+	create := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create an API token and print it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return runTokenCreate(cmd, args[0])
+		},
+	}
+	create.Flags().String("role", string(api.RoleAdmin), "Role of the token: viewer, operator, editor or admin")
+	create.Flags().String("profile", "", "Dashboard profile used when a request selects none")
+	cmd.AddCommand(create)
+	cmd.AddCommand(&cobra.Command{
+		Use:   "delete <id>",
+		Short: "Delete an API token",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return runTokenDelete(cmd, args[0])
+		},
+	})
+	return cmd
+}
+
+// loadCLIConfig reads the config file of the --config flag. Unlike the server it does not
+// create a missing file.
+func loadCLIConfig(cmd *cobra.Command) (Config, string, error) {
+	configPath, _ := cmd.Flags().GetString("config")
+	configFile, err := configFilePath(configPath)
+	if err != nil {
+		return Config{}, "", fmt.Errorf("failed to resolve config path: %w", err)
+	}
+	if _, err := os.Stat(configFile); err != nil {
+		return Config{}, configFile, fmt.Errorf("config file %s not found", configFile)
+	}
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		return Config{}, configFile, err
+	}
+	if err := applyEnvOverrides(&config); err != nil {
+		return Config{}, configFile, fmt.Errorf("invalid environment: %w", err)
+	}
+	return config, configFile, nil
+}
+
+// ensureStopped fails while a server listens on the configured address, since it would
+// overwrite the files being changed.
+func ensureStopped(config Config, action string) error {
+	if serverRunning(config) {
+		return fmt.Errorf("the dashboard is running on %s; stop it before you %s", localAddr(config), action)
+	}
+	return nil
+}
+
+// runConfigValidate checks the config file with the HOMEPAGE_* environment variables as the
+// server would and also rejects keys the server does not know, which it would silently ignore.
+func runConfigValidate(cmd *cobra.Command) error {
+	configPath, _ := cmd.Flags().GetString("config")
+	configFile, err := configFilePath(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config path: %w", err)
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config Config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return fmt.Errorf("%s: %w", configFile, err)
+	}
+	if err := applyEnvOverrides(&config); err != nil {
+		return fmt.Errorf("invalid environment: %w", err)
+	}
+	if err := validateConfig(config); err != nil {
+		return fmt.Errorf("%s: %w", configFile, err)
+	}
+	fmt.Printf("%s: configuration is valid\n", configFile)
+	return nil
+}
+
+// configThemesDir returns the themes directory of a config.
+func configThemesDir(config Config) string {
+	if config.ThemesDir != "" {
+		return config.ThemesDir
+	}
+	return defaultThemesDir
+}
+
+// configStorePath returns the SQLite database of a config, "" for the JSON driver.
+func configStorePath(config Config) string {
+	if config.Store == nil || config.Store.Driver != api.StoreDriverSQLite {
+		return ""
+	}
+	if config.Store.Path != "" {
+		return config.Store.Path
+	}
+	return "homepage.db"
+}
+
+// runBackupExport writes the config, data files, saved configurations, user themes and a
+// copy of the SQLite store to a gzipped tar archive.
+func runBackupExport(cmd *cobra.Command, out string) error {
+	config, configFile, err := loadCLIConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	type entry struct{ name, path string }
+	entries := []entry{{backupConfigName, configFile}}
+	for _, name := range api.DataFiles() {
+		if _, err := os.Stat(name); err == nil {
+			entries = append(entries, entry{backupDataDir + name, name})
+		}
+	}
+	if files, err := os.ReadDir(savedConfigsDir); err == nil {
+		for _, f := range files {
+			if f.Type().IsRegular() && savedConfigPattern.MatchString(f.Name()) {
+				entries = append(entries, entry{backupConfigsDir + f.Name(), filepath.Join(savedConfigsDir, f.Name())})
+			}
+		}
+	}
+	themes := configThemesDir(config)
+	_ = filepath.WalkDir(themes, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(themes, p)
+		if err == nil {
+			entries = append(entries, entry{backupThemesDir + filepath.ToSlash(rel), p})
+		}
+		return nil
+	})
+
+	// The database is copied with VACUUM INTO, which is consistent while the server writes
+	if configStorePath(config) != "" {
+		if err := api.OpenStore(*config.Store); err != nil {
+			return fmt.Errorf("failed to open store: %w", err)
+		}
+		tmpDir, err := os.MkdirTemp("", "homepage-backup")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmpDir)
+		snapshot := filepath.Join(tmpDir, "homepage.db")
+		if err := api.GetStore().BackupTo(snapshot); err != nil {
+			return err
+		}
+		entries = append(entries, entry{backupStoreName, snapshot})
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "-" {
+		f, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest, _ := json.MarshalIndent(BackupManifest{App: "homepage", Version: appversion, Created: time.Now(), Files: len(entries)}, "", "  ")
+	if err := writeTarFile(tw, backupManifestName, manifest); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(e.path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", e.path, err)
+		}
+		if err := writeTarFile(tw, e.name, data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if out != "-" {
+		fmt.Fprintf(os.Stderr, "Wrote %d files to %s\n", len(entries), out)
+	}
+	return nil
+}
+
+// writeTarFile adds a file to a backup archive.
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// runBackupImport restores a backup archive written by backup export. The config file in the
+// archive decides where the themes and database go. Entries the dashboard does not know are
+// skipped.
+func runBackupImport(cmd *cobra.Command, in string) error {
+	configPath, _ := cmd.Flags().GetString("config")
+	configFile, err := configFilePath(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config path: %w", err)
+	}
+	if current, _, err := loadCLIConfig(cmd); err == nil {
+		if err := ensureStopped(current, "import a backup"); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%s is not a backup archive: %w", in, err)
+	}
+	tr := tar.NewReader(gz)
+
+	// The manifest and config come first
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != backupManifestName {
+		return fmt.Errorf("%s is not a backup archive: missing %s", in, backupManifestName)
+	}
+	var manifest BackupManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil || manifest.App != "homepage" {
+		return fmt.Errorf("%s is not a backup archive: invalid %s", in, backupManifestName)
+	}
+	hdr, err = tr.Next()
+	if err != nil || hdr.Name != backupConfigName {
+		return fmt.Errorf("%s is not a backup archive: missing %s", in, backupConfigName)
+	}
+	configData, err := io.ReadAll(tr)
+	if err != nil {
+		return err
+	}
+	var config Config
+	if err := json.Unmarshal(configData, &config); err != nil {
+		return fmt.Errorf("invalid config in backup: %w", err)
+	}
+	if err := validateConfig(config); err != nil {
+		return fmt.Errorf("invalid config in backup: %w", err)
+	}
+	if err := ensureStopped(config, "import a backup"); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(configFile, bytes.NewReader(configData)); err != nil {
+		return err
+	}
+	restored := 1
+
+	themes, storePath := configThemesDir(config), configStorePath(config)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", in, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		var dest string
+		switch name := hdr.Name; {
+		case strings.HasPrefix(name, backupDataDir):
+			if base := strings.TrimPrefix(name, backupDataDir); slices.Contains(api.DataFiles(), base) {
+				dest = base
+			}
+		case strings.HasPrefix(name, backupConfigsDir):
+			if base := strings.TrimPrefix(name, backupConfigsDir); savedConfigPattern.MatchString(base) {
+				dest = filepath.Join(savedConfigsDir, base)
+			}
+		case strings.HasPrefix(name, backupThemesDir):
+			if rel := strings.TrimPrefix(name, backupThemesDir); filepath.IsLocal(rel) {
+				dest = filepath.Join(themes, filepath.FromSlash(rel))
+			}
+		case name == backupStoreName:
+			if storePath == "" {
+				fmt.Fprintf(os.Stderr, "Skipping %s: the config does not use the sqlite store\n", name)
+				continue
+			}
+			// A write-ahead log of the replaced database would be applied to the restored one
+			os.Remove(storePath + "-wal")
+			os.Remove(storePath + "-shm")
+			dest = storePath
+		}
+		if dest == "" {
+			fmt.Fprintf(os.Stderr, "Skipping unknown entry %s\n", hdr.Name)
+			continue
+		}
+		if err := writeFileAtomic(dest, tr); err != nil {
+			return err
+		}
+		restored++
+	}
+	fmt.Printf("Restored %d files from %s (version %s, %s)\n", restored, in, manifest.Version, manifest.Created.Format(time.DateTime))
+	return nil
+}
+
+// writeFileAtomic writes a file through a temporary file in the same directory, so an
+// interrupted import leaves either the old or the new file.
+func writeFileAtomic(dest string, r io.Reader) error {
+	if dir := filepath.Dir(dest); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	return nil
+}
+
+// runThemeList prints the theme templates of the embedded files and the themes directory.
+func runThemeList(cmd *cobra.Command) error {
+	config, _, err := loadCLIConfig(cmd)
+	if err != nil {
+		return err
+	}
+	themesDir = configThemesDir(config)
+	result, err := reloadThemes(false)
+	if err != nil {
+		return fmt.Errorf("failed to read templates: %w", err)
+	}
+
+	templates, order := currentThemes()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TEMPLATE\tSOURCE\tSCHEMES")
+	for _, name := range order {
+		source := "built-in"
+		if slices.Contains(result.User, name) {
+			source = "user"
+		}
+		schemes := make([]string, 0, len(templates[name].Schemes))
+		for scheme := range templates[name].Schemes {
+			schemes = append(schemes, scheme)
+		}
+		slices.Sort(schemes)
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, source, strings.Join(schemes, ", "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for file, msg := range result.Errors {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", filepath.Join(themesDir, file), msg)
+	}
+	return nil
+}
+
+// runTokenList prints the API tokens.
+func runTokenList() error {
+	tokens := api.GetTokenManager().Tokens()
+	if len(tokens) == 0 {
+		fmt.Println("No API tokens; the API is open")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tROLE\tPROFILE\tCREATED\tLAST USED")
+	for _, t := range tokens {
+		lastUsed := "never"
+		if t.LastUsed != nil {
+			lastUsed = t.LastUsed.Format(time.DateTime)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Role, t.Profile, t.CreatedAt.Format(time.DateTime), lastUsed)
+	}
+	return tw.Flush()
+}
+
+// runTokenCreate creates an API token and prints its secret, which is not shown again.
+func runTokenCreate(cmd *cobra.Command, name string) error {
+	if config, _, err := loadCLIConfig(cmd); err == nil {
+		if err := ensureStopped(config, "create tokens"); err != nil {
+			return err
+		}
+	}
+	roleFlag, _ := cmd.Flags().GetString("role")
+	role, err := api.ParseRole(roleFlag)
+	if err != nil {
+		return err
+	}
+	profile, _ := cmd.Flags().GetString("profile")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("the token needs a name")
+	}
+	t, token, err := api.GetTokenManager().CreateToken(name, role, profile)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Created %s token %s (%s); it is not shown again:\n", t.Role, t.ID, t.Name)
+	fmt.Println(token)
+	return nil
+}
+
+// runTokenDelete deletes an API token by ID.
+func runTokenDelete(cmd *cobra.Command, id string) error {
+	if config, _, err := loadCLIConfig(cmd); err == nil {
+		if err := ensureStopped(config, "delete tokens"); err != nil {
+			return err
+		}
+	}
+	deleted, err := api.GetTokenManager().DeleteToken(id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("no token with id %q", id)
+	}
+	fmt.Printf("Deleted token %s\n", id)
+	return nil
+}
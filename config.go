@@ -6,8 +6,12 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+
+	"homepage/api"
 )
 
 // Config represents the application configuration
@@ -17,6 +21,187 @@ type Config struct {
 	ID    string `json:"id"`
 	Debug bool   `json:"debug"`
 	Log   string `json:"log"`
+
+	// Title of the page and the mDNS announcement (default "LAN Index")
+	Title string `json:"title,omitempty"`
+
+	// Weather the server fetches when a request names no location: the default location,
+	// provider and API key
+	Weather *WeatherSettings `json:"weather,omitempty"`
+
+	// GitHub token used by the GitHub modules when Preferences set none, for a higher rate
+	// limit and private repositories
+	GitHub *GitHubSettings `json:"github,omitempty"`
+
+	// More addresses to listen on besides ip and port, each optionally limited to a role,
+	// e.g. a read-only LAN port next to an admin port bound to localhost
+	Listeners []api.ListenerConfig `json:"listeners,omitempty"`
+
+	// Structured log: level, text or JSON format, rotation of the log file and modules
+	// whose debug messages are always logged
+	Logging *api.LoggingConfig `json:"logging,omitempty"`
+
+	// Per-request log lines and the counters behind /api/stats
+	RequestLog *api.RequestLogConfig `json:"requestLog,omitempty"`
+
+	// Local module usage and API latency statistics of /api/stats/usage, never sent anywhere
+	Usage *api.UsageConfig `json:"usage,omitempty"`
+
+	// Deadlines of module fetches and how long a module is paused after repeated failures
+	ModuleSandbox *api.ModuleSandboxConfig `json:"moduleSandbox,omitempty"`
+
+	// Reverse proxy: sub-path the dashboard is served under (e.g. "/dash") and the proxies
+	// (IPs or CIDRs) whose X-Forwarded-For headers are trusted. Default: loopback only
+	BasePath       string   `json:"basePath,omitempty"`
+	TrustedProxies []string `json:"trustedProxies,omitempty"`
+
+	// Metric history retention: full resolution (5s) and hourly averages, e.g. "24h", "30d"
+	HistoryRetention       string `json:"historyRetention,omitempty"`
+	HistoryHourlyRetention string `json:"historyHourlyRetention,omitempty"`
+
+	// Directory of user CSS themes loaded next to the built-in ones (default "themes")
+	ThemesDir string `json:"themesDir,omitempty"`
+
+	// Automatic switch between a light and a dark scheme at fixed times or sunrise and sunset
+	ThemeSchedule *api.ThemeScheduleConfig `json:"themeSchedule,omitempty"`
+
+	// Quiet hours for alerts, globally and per channel
+	QuietHours *api.QuietHoursConfig `json:"quietHours,omitempty"`
+
+	// Daily digest delivered to the alert channels
+	Digest *api.DigestConfig `json:"digest,omitempty"`
+
+	// Long-term data store: JSON files (default) or an embedded SQLite database
+	Store *api.StoreConfig `json:"store,omitempty"`
+
+	// Outgoing mail for alerts and the daily digest
+	SMTP *api.SMTPConfig `json:"smtp,omitempty"`
+
+	// API token authentication options
+	Auth *api.AuthConfig `json:"auth,omitempty"`
+
+	// Who is home, from Home Assistant or OwnTracks
+	Presence *api.PresenceConfig `json:"presence,omitempty"`
+
+	// HTTPS listener: certificate files, a generated self-signed certificate or Let's Encrypt
+	TLS *api.TLSConfig `json:"tls,omitempty"`
+
+	// Guest Wi-Fi password rotation through an OpenWrt or UniFi router
+	GuestWiFi *api.GuestWiFiConfig `json:"guestWifi,omitempty"`
+
+	// OpenWrt router read over ubus: WAN status, DHCP leases and wireless clients
+	Router *api.RouterConfig `json:"router,omitempty"`
+
+	// Addresses the favicon, RSS, monitor, ICS and SNMP fetchers may reach for clients
+	Outbound *api.OutboundConfig `json:"outbound,omitempty"`
+
+	// Per-client request rates of the endpoints that fetch from other hosts
+	RateLimit *api.RateLimitConfig `json:"rateLimit,omitempty"`
+
+	// Directory and lifetime of the favicon disk cache
+	Favicon *api.FaviconConfig `json:"favicon,omitempty"`
+	// Source and cache directory of the dashboard icons quick links and monitors can use
+	Icons *api.IconPackConfig `json:"icons,omitempty"`
+	// Release notes shown in the What's new panel
+	Changelog *api.ChangelogConfig `json:"changelog,omitempty"`
+
+	// Text-to-speech engine for the spoken morning briefing
+	TTS *api.TTSConfig `json:"tts,omitempty"`
+
+	// Chat bots (Telegram, Matrix, Discord) answering commands and forwarding alerts
+	Bots *api.BotConfig `json:"bots,omitempty"`
+
+	// MQTT broker for sensor values from Home Assistant, Tasmota and the like
+	MQTT *api.MQTTConfig `json:"mqtt,omitempty"`
+	// Stock tickers and cryptocurrencies priced for /api/quotes
+	Quotes *api.QuotesConfig `json:"quotes,omitempty"`
+	// Base currency and targets of the exchange rates for /api/currency
+	Currency *api.CurrencyConfig `json:"currency,omitempty"`
+	// Country and region whose public holidays are marked in the calendar
+	Holidays *api.HolidaysConfig `json:"holidays,omitempty"`
+	// CardDAV address books whose birthdays are added to the calendar
+	Birthdays *api.BirthdaysConfig `json:"birthdays,omitempty"`
+
+	// Proxmox VE and libvirt guests for /api/virt
+	Virt *api.VirtConfig `json:"virt,omitempty"`
+	// NUT and apcupsd UPSes for /api/ups, with alerts when one switches to battery
+	UPS *api.UPSConfig `json:"ups,omitempty"`
+	// Per-room temperature and humidity from MQTT, Home Assistant and SNMP sensors for /api/climate
+	Climate *api.ClimateConfig `json:"climate,omitempty"`
+	// ISS pass predictions of /api/astro: orbital elements source, lowest peak and days ahead
+	Astro *api.AstroConfig `json:"astro,omitempty"`
+	// Watering advice of /api/garden: days of past and forecast rain, the garden's need and the hot-day threshold
+	Garden *api.GardenConfig `json:"garden,omitempty"`
+	// Framed pages and sanitized HTML or Markdown snippets of the Embed card
+	Embed *api.EmbedConfig `json:"embed,omitempty"`
+	// NFS and SMB share capacity for /api/shares
+	Shares *api.SharesConfig `json:"shares,omitempty"`
+	// Redfish and IPMI BMCs for /api/oob, with optional power actions
+	OOB *api.OOBConfig `json:"oob,omitempty"`
+	// Whitelisted commands for /api/command, run on a timer or on demand
+	Command *api.CommandConfig `json:"command,omitempty"`
+	// Background public IP checks with dynamic DNS (DuckDNS, Cloudflare) updates on change
+	PublicIP *api.PublicIPConfig `json:"publicIP,omitempty"`
+	// Internet connectivity check; while offline, external modules show their last data
+	Connectivity *api.ConnectivityConfig `json:"connectivity,omitempty"`
+	// Announcement on the LAN as homepage.local and an HTTP service by multicast DNS
+	MDNS *api.MDNSConfig `json:"mdns,omitempty"`
+	// Country, city and ASN of the public IP from a GeoLite2 database or an online lookup
+	GeoIP *api.GeoIPConfig `json:"geoip,omitempty"`
+	// Periodic scan of the ports this host listens on for /api/exposure
+	Exposure *api.ExposureConfig `json:"exposure,omitempty"`
+	// Text and files shared between devices through /api/drop, on without a config
+	Drop *api.DropConfig `json:"drop,omitempty"`
+	// Opt-in search history kept on the server and shared by every device
+	SearchHistory *api.SearchHistoryConfig `json:"searchHistory,omitempty"`
+}
+
+// WeatherSettings sets the server's weather. Lat and Lon are the default location, used
+// until one is saved in Preferences.
+type WeatherSettings struct {
+	Disabled   bool   `json:"disabled,omitempty"`
+	Lat        string `json:"lat,omitempty"`
+	Lon        string `json:"lon,omitempty"`
+	Provider   string `json:"provider,omitempty"` // Default openmeteo
+	APIKey     string `json:"apiKey,omitempty"`   // For openweathermap and weatherapi
+	APIKeyFile string `json:"apiKeyFile,omitempty"`
+	APIKeyEnv  string `json:"apiKeyEnv,omitempty"`
+}
+
+// Validate checks the location, provider and API key.
+func (w WeatherSettings) Validate() error {
+	if (w.Lat == "") != (w.Lon == "") {
+		return fmt.Errorf("weather: lat and lon must be set together")
+	}
+	if w.Lat != "" {
+		lat, errLat := strconv.ParseFloat(w.Lat, 64)
+		lon, errLon := strconv.ParseFloat(w.Lon, 64)
+		if errLat != nil || errLon != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			return fmt.Errorf("weather: lat and lon must be coordinates in degrees")
+		}
+	}
+	if w.Provider != "" && !slices.Contains(api.WeatherProviderNames(), w.Provider) {
+		return fmt.Errorf("weather: provider must be one of %s", strings.Join(api.WeatherProviderNames(), ", "))
+	}
+	if _, err := api.ResolveSecret(w.APIKey, w.APIKeyFile, w.APIKeyEnv); err != nil {
+		return fmt.Errorf("weather: %w", err)
+	}
+	return nil
+}
+
+// GitHubSettings sets the server's GitHub token.
+type GitHubSettings struct {
+	Token     string `json:"token,omitempty"`
+	TokenFile string `json:"tokenFile,omitempty"`
+	TokenEnv  string `json:"tokenEnv,omitempty"`
+}
+
+// Validate checks that the token can be read.
+func (g GitHubSettings) Validate() error {
+	if _, err := api.ResolveSecret(g.Token, g.TokenFile, g.TokenEnv); err != nil {
+		return fmt.Errorf("github: %w", err)
+	}
+	return nil
 }
 
 // DefaultConfig returns the default configuration
@@ -27,21 +212,18 @@ func DefaultConfig() Config {
 		ID:    "homepage",
 		Debug: false,
 		Log:   "",
+
+		HistoryRetention:       "24h",
+		HistoryHourlyRetention: "30d",
 	}
 }
 
 // LoadConfig loads configuration from a file or directory path
 func LoadConfig(configPath string) (Config, error) {
 	config := DefaultConfig()
-	configFile := "homepage.config" // Default config file name
-
-	if configPath != "" {
-		// Determine the config file path from provided path
-		var err error
-		configFile, err = resolveConfigPath(configPath)
-		if err != nil {
-			return config, fmt.Errorf("failed to resolve config path: %w", err)
-		}
+	configFile, err := configFilePath(configPath)
+	if err != nil {
+		return config, fmt.Errorf("failed to resolve config path: %w", err)
 	}
 
 	// Check if config file exists
@@ -75,6 +257,14 @@ func LoadConfig(configPath string) (Config, error) {
 	return fileConfig, nil
 }
 
+// configFilePath returns the config file of the --config flag, homepage.config by default.
+func configFilePath(configPath string) (string, error) {
+	if configPath == "" {
+		return "homepage.config", nil
+	}
+	return resolveConfigPath(configPath)
+}
+
 // resolveConfigPath determines the full path to the config file
 func resolveConfigPath(configPath string) (string, error) {
 	// Check if it's already a file
@@ -137,6 +327,30 @@ func validateConfig(config Config) error {
 		}
 	}
 
+	// Validate the server's weather
+	if config.Weather != nil {
+		if err := config.Weather.Validate(); err != nil {
+			return err
+		}
+	}
+	if config.GitHub != nil {
+		if err := config.GitHub.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate the extra listeners
+	listenAddrs := map[string]bool{config.GetListenAddr(): true}
+	for i, l := range config.Listeners {
+		if err := l.Validate(); err != nil {
+			return fmt.Errorf("listeners[%d]: %w", i, err)
+		}
+		if listenAddrs[l.Addr] {
+			return fmt.Errorf("listeners[%d]: %s is already listened on", i, l.Addr)
+		}
+		listenAddrs[l.Addr] = true
+	}
+
 	// Validate ID
 	if config.ID == "" {
 		return fmt.Errorf("id cannot be empty")
@@ -148,9 +362,331 @@ func validateConfig(config Config) error {
 	// Debug is a boolean, no validation needed
 	// Log is a string path, no validation needed
 
+	// Validate logging options
+	if config.Logging != nil {
+		if err := config.Logging.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate reverse proxy settings
+	if _, err := api.NormalizeBasePath(config.BasePath); err != nil {
+		return err
+	}
+	if _, err := api.ParseTrustedProxies(config.TrustedProxies); err != nil {
+		return err
+	}
+
+	// Validate history retention (empty uses defaults)
+	if _, _, err := config.HistoryRetentionDurations(); err != nil {
+		return err
+	}
+
+	// Validate quiet hours
+	if config.QuietHours != nil {
+		if err := config.QuietHours.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate digest schedule
+	if config.Digest != nil {
+		if err := config.Digest.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate store
+	if config.Store != nil {
+		if err := config.Store.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate SMTP
+	if config.SMTP != nil {
+		if err := config.SMTP.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate authentication options
+	if config.Auth != nil {
+		if err := config.Auth.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate TLS
+	if config.TLS != nil {
+		if err := config.TLS.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate presence sources
+	if config.Presence != nil {
+		if err := config.Presence.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate guest Wi-Fi rotation
+	if config.GuestWiFi != nil {
+		if err := config.GuestWiFi.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate router integration
+	if config.Router != nil {
+		if err := config.Router.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate outbound request policy
+	if config.Outbound != nil {
+		if err := config.Outbound.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate rate limits
+	if config.RateLimit != nil {
+		if err := config.RateLimit.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate favicon cache
+	if config.Favicon != nil {
+		if err := config.Favicon.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate icon pack source
+	if config.Icons != nil {
+		if err := config.Icons.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate changelog source
+	if config.Changelog != nil {
+		if err := config.Changelog.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate text-to-speech engine
+	if config.TTS != nil {
+		if err := config.TTS.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate chat bots
+	if config.Bots != nil {
+		if err := config.Bots.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate MQTT broker
+	if config.MQTT != nil {
+		if err := config.MQTT.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate quote symbols and providers
+	if config.Quotes != nil {
+		if err := config.Quotes.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate exchange rate currencies
+	if config.Currency != nil {
+		if err := config.Currency.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate the holiday country
+	if config.Holidays != nil {
+		if err := config.Holidays.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate the birthday address books
+	if config.Birthdays != nil {
+		if err := config.Birthdays.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate hypervisors
+	if config.Virt != nil {
+		if err := config.Virt.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate UPSes
+	if config.UPS != nil {
+		if err := config.UPS.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate climate rooms and sensors
+	if config.Climate != nil {
+		if err := config.Climate.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate ISS pass predictions
+	if config.Astro != nil {
+		if err := config.Astro.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate watering advice thresholds
+	if config.Garden != nil {
+		if err := config.Garden.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate embed widgets
+	if config.Embed != nil {
+		if err := config.Embed.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate network shares
+	if config.Shares != nil {
+		if err := config.Shares.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate BMCs
+	if config.OOB != nil {
+		if err := config.OOB.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate commands
+	if config.Command != nil {
+		if err := config.Command.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate public IP watcher
+	if config.PublicIP != nil {
+		if err := config.PublicIP.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate theme schedule
+	if config.ThemeSchedule != nil {
+		if err := config.ThemeSchedule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate module sandbox
+	if config.ModuleSandbox != nil {
+		if err := config.ModuleSandbox.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate connectivity check
+	if config.Connectivity != nil {
+		if err := config.Connectivity.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate mDNS announcement
+	if config.MDNS != nil {
+		if err := config.MDNS.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate GeoIP
+	if config.GeoIP != nil {
+		if err := config.GeoIP.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate exposure scan
+	if config.Exposure != nil {
+		if err := config.Exposure.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate server-side search history
+	if config.SearchHistory != nil {
+		if err := config.SearchHistory.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate drop limits
+	if config.Drop != nil {
+		if err := config.Drop.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// APIConfig returns the settings of the API handler: the page title and the weather.
+func (c Config) APIConfig(listenAddr string) api.Config {
+	cfg := api.Config{
+		ListenAddr:      listenAddr,
+		Title:           c.Title,
+		PublicIPTimeout: 1500 * time.Millisecond,
+		Weather: api.WeatherConfig{
+			Enabled:  true,
+			Provider: "openmeteo",
+		},
+	}
+	if cfg.Title == "" {
+		cfg.Title = "LAN Index"
+	}
+	if w := c.Weather; w != nil {
+		cfg.Weather.Enabled = !w.Disabled
+		cfg.Weather.Lat, cfg.Weather.Lon = w.Lat, w.Lon
+		if w.Provider != "" {
+			cfg.Weather.Provider = w.Provider
+		}
+		// Checked by validateConfig
+		cfg.Weather.APIKey, _ = api.ResolveSecret(w.APIKey, w.APIKeyFile, w.APIKeyEnv)
+	}
+	if g := c.GitHub; g != nil {
+		cfg.GitHubToken, _ = api.ResolveSecret(g.Token, g.TokenFile, g.TokenEnv)
+	}
+	return cfg
+}
+
 // GetListenAddr returns the listen address string (ip:port)
 func (c Config) GetListenAddr() string {
 	ip := c.IP
@@ -158,4 +694,28 @@ func (c Config) GetListenAddr() string {
 		ip = "0.0.0.0"
 	}
 	return fmt.Sprintf("%s:%s", ip, c.Port)
-}
\ No newline at end of file
+}
+// HistoryRetentionDurations returns the parsed metric history retention values,
+// falling back to the defaults for empty values
+func (c Config) HistoryRetentionDurations() (time.Duration, time.Duration, error) {
+	parse := func(v string, def time.Duration) (time.Duration, error) {
+		if v == "" {
+			return def, nil
+		}
+		d, err := api.ParseHistoryRange(v)
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("invalid history retention %q (use e.g. 24h or 30d)", v)
+		}
+		return d, nil
+	}
+
+	retention, err := parse(c.HistoryRetention, api.DefaultHistoryRetention)
+	if err != nil {
+		return 0, 0, err
+	}
+	hourly, err := parse(c.HistoryHourlyRetention, api.DefaultHistoryHourlyRetention)
+	if err != nil {
+		return 0, 0, err
+	}
+	return retention, hourly, nil
+}
@@ -17,6 +17,8 @@ UNIT_SRC="${ROOT}/homepage.service"
 CFG_SRC="${ROOT}/homepage.config"
 DEST="/opt/homepage"
 UNIT_DST="/etc/systemd/system/homepage.service"
+# Optional socket activation and restart on config change
+EXTRA_UNITS="homepage.socket homepage-config.path homepage-config.service"
 
 BIN_OK=0
 UNIT_OK=0
@@ -50,6 +52,11 @@ fi
 
 if [ "$UNIT_OK" -eq 1 ]; then
 	install -m644 "$UNIT_SRC" "$UNIT_DST"
+	for unit in $EXTRA_UNITS; do
+		if [ -f "${ROOT}/${unit}" ]; then
+			install -m644 "${ROOT}/${unit}" "/etc/systemd/system/${unit}"
+		fi
+	done
 fi
 
 if [ -f "$CFG_SRC" ]; then
@@ -74,6 +81,7 @@ chown -R homepage:homepage "$DEST"
 if [ "$UNIT_OK" -eq 1 ]; then
 	systemctl daemon-reload || warn "systemctl daemon-reload failed"
 	systemctl enable --now homepage.service || warn "systemctl enable --now homepage.service failed"
+	echo "Optional: systemctl enable --now homepage.socket homepage-config.path (no refused connections on restart, restart on config change)."
 elif [ -f "$UNIT_DST" ]; then
 	systemctl daemon-reload || warn "systemctl daemon-reload failed"
 	systemctl try-restart homepage.service || warn "systemctl try-restart homepage.service failed (service may be inactive)"
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"homepage/api"
+)
+
+// envPrefix starts the environment variables that override settings of the config file.
+const envPrefix = "HOMEPAGE_"
+
+var (
+	envFieldsOnce sync.Once
+	envFieldIndex map[string][]int // Variable name to field index path in Config
+	envWarnOnce   sync.Once
+)
+
+// applyEnvOverrides sets the settings named by HOMEPAGE_* environment variables over those of
+// the config file. A variable is the path of config keys in upper snake case, e.g.
+// HOMEPAGE_PORT for port or HOMEPAGE_WEATHER_LAT for weather.lat; sections are created as
+// needed. Lists of strings are comma-separated. Lists of objects and maps can only be set in
+// the file.
+func applyEnvOverrides(c *Config) error {
+	envFieldsOnce.Do(func() {
+		envFieldIndex = make(map[string][]int)
+		collectEnvFields(reflect.TypeFor[Config](), strings.TrimSuffix(envPrefix, "_"), nil)
+	})
+
+	var unknown []string
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, envPrefix) {
+			continue
+		}
+		index, ok := envFieldIndex[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		if err := setEnvField(reflect.ValueOf(c).Elem(), index, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if len(unknown) > 0 {
+		envWarnOnce.Do(func() {
+			sort.Strings(unknown)
+			api.Logger("config").Warn("ignoring environment variables that name no setting", "variables", strings.Join(unknown, ", "))
+		})
+	}
+	return nil
+}
+
+// collectEnvFields records the variable names of the settable fields of a config struct.
+func collectEnvFields(t reflect.Type, prefix string, index []int) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name := jsonName(f)
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		path := append(append([]int(nil), index...), i)
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		switch {
+		case f.Anonymous && ft.Kind() == reflect.Struct && f.Tag.Get("json") == "":
+			collectEnvFields(ft, prefix, path)
+		case ft.Kind() == reflect.Struct && ft != reflect.TypeFor[time.Time]():
+			collectEnvFields(ft, prefix+"_"+envName(name), path)
+		case envSettable(ft):
+			envFieldIndex[prefix+"_"+envName(name)] = path
+		}
+	}
+}
+
+// envSettable reports whether a variable can hold a value of the type.
+func envSettable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+// envName converts a config key to upper snake case: apiKeyFile becomes API_KEY_FILE.
+func envName(key string) string {
+	var b strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		if r == '-' {
+			b.WriteRune('_')
+			continue
+		}
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+			b.WriteRune('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// setEnvField parses a variable into the field at an index path, allocating the sections on
+// the way.
+func setEnvField(v reflect.Value, index []int, value string) error {
+	for _, i := range index {
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	if v.Kind() == reflect.Pointer {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == reflect.TypeFor[time.Duration]() {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid duration %q", value)
+			}
+			v.SetInt(int64(d))
+			break
+		}
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		items := reflect.MakeSlice(v.Type(), 0, 0)
+		for item := range strings.SplitSeq(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = reflect.Append(items, reflect.ValueOf(item).Convert(v.Type().Elem()))
+			}
+		}
+		v.Set(items)
+	}
+	return nil
+}
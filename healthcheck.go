@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"homepage/api"
+)
+
+// runHealthcheck requests /healthz of the server the config describes, for container
+// HEALTHCHECK directives. It fails when the server is down or does not answer 200.
+func runHealthcheck(config Config) error {
+	scheme := "http"
+	tlsConfig := &tls.Config{
+		// Only liveness is checked, so the certificate is not verified
+		InsecureSkipVerify: true,
+	}
+	if config.TLS != nil {
+		scheme = "https"
+		if config.TLS.ACME != nil {
+			tlsConfig.ServerName = config.TLS.ACME.Domains[0]
+		}
+	}
+	basePath, _ := api.NormalizeBasePath(config.BasePath)
+	url := scheme + "://" + localAddr(config) + basePath + "/healthz"
+
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	status := strings.TrimSpace(string(body))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check failed: %s: %s", resp.Status, status)
+	}
+	fmt.Println(status)
+	return nil
+}
+
+// localAddr returns the address to reach the server the config describes on this host.
+// Wildcard addresses are reached over loopback.
+func localAddr(config Config) string {
+	host := config.IP
+	switch host {
+	case "", "0.0.0.0":
+		host = "127.0.0.1"
+	case "::":
+		host = "::1"
+	}
+	return net.JoinHostPort(host, config.Port)
+}
+
+// serverRunning reports whether a server listens on the address the config describes.
+func serverRunning(config Config) bool {
+	conn, err := net.DialTimeout("tcp", localAddr(config), time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
@@ -2,20 +2,22 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
-	"io"
 	"io/fs"
 	"log"
 	"net"
 	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"runtime"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -30,15 +32,6 @@ var templatesFS embed.FS
 //go:embed static
 var staticFS embed.FS
 
-// ThemeMetadata represents metadata parsed from CSS template files.
-type ThemeMetadata struct {
-	Template string
-	Scheme   string
-	Accent   string
-	Display  string
-	Border   bool
-}
-
 // TemplateInfo contains information about a CSS template and its color schemes.
 type TemplateInfo struct {
 	Name    string
@@ -62,202 +55,6 @@ var (
 	appversion    = "0.4.141"
 )
 
-// findBlockEnd finds the end of a CSS block (the matching closing brace)
-func findBlockEnd(content string, startPos int) int {
-	if startPos >= len(content) {
-		return len(content)
-	}
-
-	openBrace := strings.Index(content[startPos:], "{")
-	if openBrace == -1 {
-		return len(content)
-	}
-	openBrace += startPos
-
-	depth := 1
-	pos := openBrace + 1
-	for pos < len(content) && depth > 0 {
-		switch content[pos] {
-		case '{':
-			depth++
-		case '}':
-			depth--
-		}
-		pos++
-	}
-
-	return pos
-}
-
-func parseThemeMetadata(cssContent string) ThemeMetadata {
-	meta := ThemeMetadata{
-		Template: "",
-		Scheme:   "",
-		Accent:   "rgba(136,192,208,.85)",
-		Display:  "",
-		Border:   false,
-	}
-
-	startIdx := strings.Index(cssContent, "/*")
-	if startIdx == -1 {
-		return meta
-	}
-
-	endIdx := strings.Index(cssContent[startIdx:], "*/")
-	if endIdx == -1 {
-		return meta
-	}
-
-	metadataBlock := cssContent[startIdx+2 : startIdx+endIdx]
-	lines := strings.Split(metadataBlock, "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "Template:") {
-			meta.Template = strings.TrimSpace(strings.TrimPrefix(line, "Template:"))
-		} else if strings.HasPrefix(line, "Scheme:") {
-			meta.Scheme = strings.TrimSpace(strings.TrimPrefix(line, "Scheme:"))
-		} else if strings.HasPrefix(line, "Accent:") {
-			meta.Accent = strings.TrimSpace(strings.TrimPrefix(line, "Accent:"))
-		} else if strings.HasPrefix(line, "Display:") {
-			meta.Display = strings.TrimSpace(strings.TrimPrefix(line, "Display:"))
-		} else if strings.HasPrefix(line, "Border:") {
-			borderVal := strings.TrimSpace(strings.TrimPrefix(line, "Border:"))
-			meta.Border = borderVal == "true" || borderVal == "1" || borderVal == "yes"
-		}
-	}
-
-	return meta
-}
-
-func parseSchemesFromTemplate(cssContent string) ([]SchemeInfo, string) {
-	var schemes []SchemeInfo
-	content := cssContent
-	pos := 0
-	lastSchemeEnd := 0
-
-	for pos < len(content) {
-		metaStart := strings.Index(content[pos:], "/*")
-		if metaStart == -1 {
-			break
-		}
-		metaStart += pos
-
-		metaEnd := strings.Index(content[metaStart:], "*/")
-		if metaEnd == -1 {
-			break
-		}
-		metaEnd += metaStart
-
-		metadataBlock := content[metaStart : metaEnd+2]
-		meta := parseThemeMetadata(metadataBlock)
-
-		if meta.Template == "" || meta.Scheme == "" {
-			pos = metaEnd + 2
-			continue
-		}
-
-		schemeSelector := `[data-scheme="` + meta.Scheme + `"]`
-		schemeStart := strings.Index(content[metaEnd:], schemeSelector)
-		isWrappedFormat := true
-		if schemeStart == -1 {
-			rootStart := strings.Index(content[metaEnd:], ":root")
-			if rootStart == -1 {
-				pos = metaEnd + 2
-				continue
-			}
-			schemeStart = rootStart + metaEnd
-			isWrappedFormat = false
-		} else {
-			schemeStart += metaEnd
-		}
-
-		var schemeEnd int
-		if isWrappedFormat {
-			nextMetaStart := strings.Index(content[schemeStart:], "/*")
-			if nextMetaStart == -1 {
-				baseCSSMarker := strings.Index(content[schemeStart:], "/* Base CSS")
-				if baseCSSMarker != -1 {
-					schemeEnd = schemeStart + baseCSSMarker
-				} else {
-					schemeEnd = len(content)
-				}
-			} else {
-				nextMetaPos := schemeStart + nextMetaStart
-				nextMetaEnd := strings.Index(content[nextMetaPos:], "*/")
-				if nextMetaEnd != -1 {
-					nextMetaBlock := content[nextMetaPos : nextMetaPos+nextMetaEnd+2]
-					nextMeta := parseThemeMetadata(nextMetaBlock)
-					if nextMeta.Template != "" && nextMeta.Scheme != "" {
-						schemeEnd = nextMetaPos
-					} else {
-						schemeEnd = nextMetaPos
-					}
-				} else {
-					schemeEnd = schemeStart + nextMetaStart
-				}
-			}
-		} else {
-			rootBlockEnd := findBlockEnd(content, schemeStart)
-			schemeEnd = rootBlockEnd
-
-			bodyStart := strings.Index(content[schemeEnd:], "body{")
-			if bodyStart != -1 && bodyStart < 50 {
-				bodyBlockEnd := findBlockEnd(content, schemeEnd+bodyStart)
-				schemeEnd = bodyBlockEnd
-			}
-		}
-
-		schemeCSS := strings.TrimSpace(content[schemeStart:schemeEnd])
-		lastSchemeEnd = schemeEnd
-
-		if !strings.HasPrefix(schemeCSS, `[data-scheme="`) {
-			wrappedCSS := `[data-scheme="` + meta.Scheme + `"] ` + schemeCSS
-			schemeCSS = wrappedCSS
-		}
-
-		alreadyExists := false
-		for _, existingScheme := range schemes {
-			if existingScheme.Name == meta.Scheme {
-				alreadyExists = true
-				break
-			}
-		}
-
-		if !alreadyExists {
-			schemes = append(schemes, SchemeInfo{
-				Name:    meta.Scheme,
-				Accent:  meta.Accent,
-				Display: meta.Display,
-				Border:  meta.Border,
-				CSS:     schemeCSS,
-			})
-		}
-
-		pos = schemeEnd
-	}
-
-	baseCSSStart := strings.Index(content, "/* Base CSS")
-	if baseCSSStart != -1 {
-		baseCSSEnd := strings.Index(content[baseCSSStart:], "*/")
-		if baseCSSEnd != -1 {
-			baseCSSStart = baseCSSStart + baseCSSEnd + 2
-			for baseCSSStart < len(content) && (content[baseCSSStart] == ' ' || content[baseCSSStart] == '\n' || content[baseCSSStart] == '\r' || content[baseCSSStart] == '\t') {
-				baseCSSStart++
-			}
-		}
-	} else {
-		baseCSSStart = lastSchemeEnd
-		for baseCSSStart < len(content) && (content[baseCSSStart] == ' ' || content[baseCSSStart] == '\n' || content[baseCSSStart] == '\r' || content[baseCSSStart] == '\t') {
-			baseCSSStart++
-		}
-	}
-
-	baseCSS := strings.TrimSpace(content[baseCSSStart:])
-
-	return schemes, baseCSS
-}
-
 func init() {
 	templatesMap = make(map[string]*TemplateInfo)
 	templatesList = []string{}
@@ -270,111 +67,9 @@ func loadTemplates(debug bool) error {
 	}
 	indexTemplate = template.Must(template.New("index").Parse(string(indexHTML)))
 
-	entries, err := fs.ReadDir(templatesFS, "templates")
-	if err != nil {
+	if _, err := reloadThemes(debug); err != nil {
 		return fmt.Errorf("failed to read templates directory: %w", err)
 	}
-
-	if debug {
-		// List all files found for debugging
-		allFiles := make([]string, 0, len(entries))
-		for _, entry := range entries {
-			allFiles = append(allFiles, entry.Name())
-		}
-		log.Printf("Found %d entries in templates directory: %v", len(entries), allFiles)
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".css") {
-			if debug && entry.IsDir() {
-				log.Printf("Skipping directory: %s", entry.Name())
-			}
-			continue
-		}
-
-		if debug {
-			log.Printf("Processing template file: %s", entry.Name())
-		}
-		cssContent, err := templatesFS.ReadFile("templates/" + entry.Name())
-		if err != nil {
-			if debug {
-				log.Printf("Warning: failed to read template %s: %v", entry.Name(), err)
-			}
-			continue
-		}
-
-		schemes, baseCSS := parseSchemesFromTemplate(string(cssContent))
-		if len(schemes) == 0 {
-			if debug {
-				log.Printf("Warning: no schemes found in template %s", entry.Name())
-			}
-			continue
-		}
-
-		if debug {
-			schemeNames := make([]string, len(schemes))
-			for i, s := range schemes {
-				schemeNames[i] = s.Name
-			}
-			log.Printf("Found %d schemes in %s: %v", len(schemes), entry.Name(), schemeNames)
-		}
-
-		// Get template name from metadata - search for a metadata block with Template:
-		templateName := ""
-		content := string(cssContent)
-		pos := 0
-		for pos < len(content) {
-			metaStart := strings.Index(content[pos:], "/*")
-			if metaStart == -1 {
-				break
-			}
-			metaStart += pos
-			metaEnd := strings.Index(content[metaStart:], "*/")
-			if metaEnd == -1 {
-				break
-			}
-			metaEnd += metaStart
-			metadataBlock := content[metaStart+2 : metaEnd]
-			if strings.Contains(metadataBlock, "Template:") {
-				meta := parseThemeMetadata(content[metaStart : metaEnd+2])
-				if meta.Template != "" {
-					templateName = meta.Template
-					break
-				}
-			}
-			pos = metaEnd + 2
-		}
-		if templateName == "" {
-			templateName = strings.TrimSuffix(entry.Name(), ".css")
-		}
-
-		templateInfo := &TemplateInfo{
-			Name:    templateName,
-			BaseCSS: baseCSS,
-			Schemes: make(map[string]SchemeInfo),
-		}
-
-		for _, scheme := range schemes {
-			templateInfo.Schemes[scheme.Name] = scheme
-		}
-
-		templatesMap[templateName] = templateInfo
-		templatesList = append(templatesList, templateName)
-	}
-
-	templatesList = sortTemplates(templatesList)
-
-	if debug {
-		log.Printf("Loaded %d theme templates:", len(templatesMap))
-		for name, info := range templatesMap {
-			schemeNames := make([]string, 0, len(info.Schemes))
-			for schemeName := range info.Schemes {
-				schemeNames = append(schemeNames, schemeName)
-			}
-			log.Printf("  - %s: %d schemes (%s)", name, len(info.Schemes), strings.Join(schemeNames, ", "))
-		}
-	}
-
 	return nil
 }
 
@@ -450,88 +145,30 @@ func listEmbeddedFiles(efs embed.FS, root string, debug bool) []string {
 
 func main() {
 	var rootCmd = &cobra.Command{
-		Use:   "homepage",
-		Short: "Homepage dashboard server",
-		Long:  "A homepage dashboard with system metrics, weather, GitHub integration, and customizable themes.",
+		Use:     "homepage",
+		Short:   "Homepage dashboard server",
+		Long:    "A homepage dashboard with system metrics, weather, GitHub integration, and customizable themes.",
+		Version: appversion,
+		// Without a subcommand the server runs, as before subcommands existed
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runServer(cmd)
 		},
 	}
 
-	rootCmd.Flags().String("port", "", "Port to listen on (overrides config file)")
-	rootCmd.Flags().String("listen", "", "IP address to listen on (overrides config file)")
-	rootCmd.Flags().String("config", "", "Path to config file or directory (default: homepage.config)")
-	rootCmd.Flags().Bool("debug", false, "Enable debug output")
-	rootCmd.Flags().String("log", "", "Path to log file or directory")
-
-	if err := rootCmd.Execute(); err != nil {
-		log.Fatal(err)
-	}
-}
+	rootCmd.PersistentFlags().String("config", "", "Path to config file or directory (default: homepage.config)")
+	addServerFlags(rootCmd)
+	rootCmd.AddCommand(newServeCmd(), newVersionCmd(), newConfigCmd(), newBackupCmd(), newThemeCmd(), newTokenCmd())
 
-// setupLogging configures logging to file
-func setupLogging(logPath string) error {
-	// Determine the log file path
-	logFile, err := resolveLogPath(logPath)
-	if err != nil {
-		return fmt.Errorf("failed to resolve log path: %w", err)
-	}
-
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(logFile)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create log directory: %w", err)
-	}
-
-	// Open log file
-	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		return fmt.Errorf("failed to open log file: %w", err)
-	}
-
-	// Set log output to both file and stdout
-	multiWriter := &multiWriter{
-		writers: []io.Writer{file, os.Stdout},
-	}
-	log.SetOutput(multiWriter)
-
-	return nil
-}
-
-// resolveLogPath determines the full path to the log file
-func resolveLogPath(logPath string) (string, error) {
-	// Check if it's already a file
-	if info, err := os.Stat(logPath); err == nil {
-		if info.IsDir() {
-			// It's a directory, append default filename
-			return filepath.Join(logPath, "homepage.log"), nil
+	// Container HEALTHCHECK directives are often written with a single dash
+	for i, arg := range os.Args {
+		if arg == "-healthcheck" {
+			os.Args[i] = "--healthcheck"
 		}
-		// It's a file, use as-is
-		return logPath, nil
-	}
-
-	// Path doesn't exist, check if it ends with a separator (indicating directory)
-	if strings.HasSuffix(logPath, string(filepath.Separator)) ||
-		strings.HasSuffix(logPath, "/") {
-		return filepath.Join(logPath, "homepage.log"), nil
 	}
 
-	// Assume it's a file path
-	return logPath, nil
-}
-
-// multiWriter writes to multiple writers
-type multiWriter struct {
-	writers []io.Writer
-}
-
-func (mw *multiWriter) Write(p []byte) (n int, err error) {
-	for _, w := range mw.writers {
-		if n, err = w.Write(p); err != nil {
-			return
-		}
+	if err := rootCmd.Execute(); err != nil {
+		log.Fatal(err)
 	}
-	return len(p), nil
 }
 
 func runServer(cmd *cobra.Command) error {
@@ -548,25 +185,28 @@ func runServer(cmd *cobra.Command) error {
 	debugFlag, _ := cmd.Flags().GetBool("debug")
 	logFlag, _ := cmd.Flags().GetString("log")
 
-	// Apply overrides from command line flags
-	if portFlag != "" {
-		fileConfig.Port = portFlag
-	}
-	if listenFlag != "" {
-		fileConfig.IP = listenFlag
-	}
-	if cmd.Flags().Changed("debug") {
-		fileConfig.Debug = debugFlag
-	}
-	if cmd.Flags().Changed("log") {
-		fileConfig.Log = logFlag
-	}
-
-	// Set up logging to file if specified
-	if fileConfig.Log != "" {
-		if err := setupLogging(fileConfig.Log); err != nil {
-			return fmt.Errorf("failed to set up logging: %w", err)
+	// Apply overrides from HOMEPAGE_* environment variables and then command line flags
+	// (again whenever the config file is reloaded)
+	overrides := func(c *Config) error {
+		if err := applyEnvOverrides(c); err != nil {
+			return err
+		}
+		if portFlag != "" {
+			c.Port = portFlag
+		}
+		if listenFlag != "" {
+			c.IP = listenFlag
+		}
+		if cmd.Flags().Changed("debug") {
+			c.Debug = debugFlag
+		}
+		if cmd.Flags().Changed("log") {
+			c.Log = logFlag
 		}
+		return nil
+	}
+	if err := overrides(&fileConfig); err != nil {
+		return fmt.Errorf("invalid environment: %w", err)
 	}
 
 	// Validate final config
@@ -574,10 +214,28 @@ func runServer(cmd *cobra.Command) error {
 		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	// Only check the running server
+	if healthcheck, _ := cmd.Flags().GetBool("healthcheck"); healthcheck {
+		cmd.SilenceUsage = true
+		return runHealthcheck(fileConfig)
+	}
+
+	// Set up structured logging: level, format and the rotated log file if specified
+	loggingConfig := api.LoggingConfig{}
+	if fileConfig.Logging != nil {
+		loggingConfig = *fileConfig.Logging
+	}
+	if err := api.ConfigureLogging(loggingConfig, fileConfig.Log, fileConfig.Debug); err != nil {
+		return fmt.Errorf("failed to set up logging: %w", err)
+	}
+
 	// Use debug setting from final config
 	debug := fileConfig.Debug
 
-	// Load templates
+	// Load templates, the built-in ones and those of the themes directory
+	if fileConfig.ThemesDir != "" {
+		themesDir = fileConfig.ThemesDir
+	}
 	if err := loadTemplates(debug); err != nil {
 		return fmt.Errorf("failed to load templates: %w", err)
 	}
@@ -603,44 +261,104 @@ func runServer(cmd *cobra.Command) error {
 	}
 
 	listenAddr := fileConfig.GetListenAddr()
-	cfg := api.Config{
-		ListenAddr:      listenAddr,
-		Title:           "LAN Index",
-		PublicIPTimeout: 1500 * time.Millisecond,
-		Weather: api.WeatherConfig{
-			Enabled:  true,
-			Lat:      "",
-			Lon:      "",
-			Provider: "openmeteo",
-			APIKey:   "",
-		},
+
+	// Reverse proxy settings (both were checked by validateConfig)
+	basePath, _ := api.NormalizeBasePath(fileConfig.BasePath)
+	if fileConfig.TrustedProxies != nil {
+		_ = api.SetTrustedProxies(fileConfig.TrustedProxies)
+	}
+
+	// Restrict what URL-fetching endpoints may reach (checked by validateConfig)
+	if fileConfig.Outbound != nil {
+		_ = api.ConfigureOutbound(*fileConfig.Outbound)
+	}
+
+	// Limit how often a client may call the outbound-fetching endpoints (defaults apply without a config)
+	if fileConfig.RateLimit != nil {
+		api.GetRateLimiter().Configure(*fileConfig.RateLimit)
+	}
+
+	// Keep favicons on disk so quick links and monitors load without refetching (defaults apply without a config)
+	if fileConfig.Favicon != nil {
+		api.GetFaviconCache().Configure(*fileConfig.Favicon)
+	}
+
+	// Serve dashboard icons by slug from a mirror or the cache directory alone
+	if fileConfig.Icons != nil {
+		api.GetIconPack().Configure(*fileConfig.Icons)
+	}
+
+	// Compare release notes with the running version for the What's new panel
+	changelogConfig := api.ChangelogConfig{}
+	if fileConfig.Changelog != nil {
+		changelogConfig = *fileConfig.Changelog
+	}
+	api.GetChangelogService().Configure(changelogConfig, appversion)
+	cfg := fileConfig.APIConfig(listenAddr)
+
+	// Take over the socket systemd passes when socket-activated, so connections made while
+	// the service restarts wait instead of being refused
+	listener, activated, err := api.Listen(listenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", listenAddr, err)
 	}
+	if activated {
+		cfg.ListenAddr = listener.Addr().String()
+		log.Printf("Using the socket passed by systemd: %s", cfg.ListenAddr)
+	}
+
+	// Extra listeners, whose requests may be limited to a role
+	var extraListeners []net.Listener
+	for _, l := range fileConfig.Listeners {
+		ln, err := net.Listen("tcp", l.Addr)
+		if err != nil {
+			listener.Close()
+			for _, extra := range extraListeners {
+				extra.Close()
+			}
+			return fmt.Errorf("failed to listen on %s: %w", l.Addr, err)
+		}
+		extraListeners = append(extraListeners, api.LimitListener(ln, l.Role))
+	}
+
+	// The API handler's title and weather change when the config file is reloaded
+	apiHandler := api.NewHandler(cfg)
 
 	mux := http.NewServeMux()
 
 	// Index page handler
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/" {
+		// Named profiles are served from /p/{profile} and pages from /page/{page} or
+		// /p/{profile}/{page}; the frontend selects its storage from the path
+		profile, page, ok := api.DashboardPath(r.URL.Path)
+		if !ok {
 			http.NotFound(w, r)
 			return
 		}
-
-		defaultTemplate := "nordic"
-		defaultScheme := "default"
-		if len(templatesList) > 0 {
-			defaultTemplate = templatesList[0]
-			if templateInfo, exists := templatesMap[defaultTemplate]; exists {
-				for schemeName := range templateInfo.Schemes {
-					defaultScheme = schemeName
-					break
+		if profile == "" {
+			profile = api.ProfileFromRequest(r)
+		}
+		if page != "" && !api.PageExists(profile, page) {
+			http.NotFound(w, r)
+			return
+		}
+		// Without a page in the path, open the page the profile was switched to
+		if page == "" {
+			if active := api.ActivePage(profile); active != api.HomePage {
+				target := basePath + api.PagePath(profile, active)
+				if r.URL.RawQuery != "" {
+					target += "?" + r.URL.RawQuery
 				}
+				http.Redirect(w, r, target, http.StatusFound)
+				return
 			}
 		}
 
-		templateName := defaultTemplate
-		schemeName := defaultScheme
-
-		themeCSS := "/* Theme CSS loaded dynamically from /api/theme */"
+		// Render the profile's saved theme so the first paint already uses it
+		templatesMap, templatesList := currentThemes()
+		theme := currentTheme(profile)
+		templateName := theme.Template
+		schemeName := theme.Scheme
 
 		var templateMenuHTML strings.Builder
 		var schemeMenuHTML strings.Builder
@@ -704,8 +422,8 @@ func runServer(cmd *cobra.Command) error {
 
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		_ = indexTemplate.Execute(w, map[string]any{
-			"Title":            cfg.Title,
-			"ThemeCSS":         template.CSS(themeCSS),
+			"Title":            apiHandler.Title(),
+			"ThemeCSS":         template.CSS(themeCSS(templateName, schemeName)),
 			"TemplatesList":    templatesList,
 			"TemplateMenuHTML": template.HTML(templateMenuHTML.String()),
 			"SchemeMenuHTML":   template.HTML(schemeMenuHTML.String()),
@@ -713,11 +431,13 @@ func runServer(cmd *cobra.Command) error {
 			"CurrentScheme":    schemeName,
 			"Year":             time.Now().Year(),
 			"AppVersion":       appversion,
+			"BasePath":         basePath,
 		})
 	})
 
 	// Theme CSS API
 	mux.HandleFunc("/api/theme", func(w http.ResponseWriter, r *http.Request) {
+		templatesMap, _ := currentThemes()
 		templateName := "nordic"
 		schemeName := "default"
 
@@ -734,18 +454,9 @@ func runServer(cmd *cobra.Command) error {
 			}
 		}
 
-		var themeCSS string
-		if templateInfo, exists := templatesMap[templateName]; exists {
-			if scheme, schemeExists := templateInfo.Schemes[schemeName]; schemeExists {
-				themeCSS = scheme.CSS + "\n" + templateInfo.BaseCSS
-			} else if defaultScheme, hasDefault := templateInfo.Schemes["default"]; hasDefault {
-				themeCSS = defaultScheme.CSS + "\n" + templateInfo.BaseCSS
-			}
-		}
-
 		w.Header().Set("Content-Type", "text/css; charset=utf-8")
 		w.Header().Set("Cache-Control", "public, max-age=3600")
-		_, _ = w.Write([]byte(themeCSS))
+		_, _ = w.Write([]byte(themeCSS(templateName, schemeName)))
 	})
 
 	// Schemes API - returns available schemes for a template
@@ -756,6 +467,7 @@ func runServer(cmd *cobra.Command) error {
 			return
 		}
 
+		templatesMap, _ := currentThemes()
 		templateInfo, exists := templatesMap[templateName]
 		if !exists {
 			http.Error(w, "template not found", http.StatusNotFound)
@@ -814,8 +526,15 @@ func runServer(cmd *cobra.Command) error {
 		}
 	})
 
+	// User themes: read the themes directory again, install or validate a CSS template or generate a scheme
+	mux.HandleFunc("/api/theme/reload", api.RequireCapability("themes.manage", handleThemeReload(debug)))
+	mux.HandleFunc("/api/theme/upload", api.RequireCapability("themes.manage", handleThemeUpload(debug)))
+	mux.HandleFunc("/api/theme/generate", api.RequireCapability("themes.manage", handleThemeGenerate(debug)))
+	mux.HandleFunc("/api/theme/validate", handleThemeValidate)
+	mux.HandleFunc("/api/theme/schedule", handleThemeSchedule)
+	mux.HandleFunc("/api/theme/current", api.RequireWriteCapability("settings.write", handleThemeCurrent))
+
 	// Register API handlers
-	apiHandler := api.NewHandler(cfg)
 	apiHandler.RegisterHandlers(mux)
 
 	// Service worker
@@ -855,9 +574,10 @@ func runServer(cmd *cobra.Command) error {
 	}
 
 	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		wsLog := api.Logger("websocket")
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
-			log.Printf("WebSocket upgrade error: %v", err)
+			wsLog.Warn("upgrade failed", "remote", r.RemoteAddr, "error", err)
 			return
 		}
 		defer conn.Close()
@@ -867,9 +587,8 @@ func runServer(cmd *cobra.Command) error {
 		wsManager.Add(conn)
 		defer wsManager.Remove(conn)
 
-		log.Printf("WebSocket client connected from %s", r.RemoteAddr)
+		wsLog.Debug("client connected", "remote", r.RemoteAddr)
 
-		ctx := r.Context()
 		isLocal := api.IsLocalRequest(r)
 
 		serverInfo := api.ServerInfo{
@@ -886,13 +605,10 @@ func runServer(cmd *cobra.Command) error {
 			"status": "online",
 			"server": serverInfo,
 		}); err != nil {
-			log.Printf("WebSocket write error: %v", err)
+			wsLog.Debug("write failed", "remote", r.RemoteAddr, "error", err)
 			return
 		}
 
-		systemTicker := time.NewTicker(5 * time.Second)
-		defer systemTicker.Stop()
-
 		pingTicker := time.NewTicker(30 * time.Second)
 		defer pingTicker.Stop()
 
@@ -909,13 +625,18 @@ func runServer(cmd *cobra.Command) error {
 		go func() {
 			defer close(done)
 			for {
-				_, _, err := conn.ReadMessage()
+				_, data, err := conn.ReadMessage()
 				if err != nil {
 					if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-						log.Printf("WebSocket error: %v", err)
+						wsLog.Warn("connection closed unexpectedly", "remote", r.RemoteAddr, "error", err)
 					}
 					return
 				}
+				// Handle subscribe/unsubscribe messages
+				if err := wsManager.HandleClientMessage(conn, data); err != nil {
+					wsLog.Debug("subscription reply failed", "remote", r.RemoteAddr, "error", err)
+					return
+				}
 			}
 		}()
 
@@ -923,27 +644,15 @@ func runServer(cmd *cobra.Command) error {
 			select {
 			case <-done:
 				return
-			case <-systemTicker.C:
-				metrics := api.GetSystemMetrics(ctx)
-				uptimeSec := api.GetSystemUptime()
-				if err := wsManager.WriteJSON(conn, map[string]any{
-					"type":   "system",
-					"system": metrics,
-					"server": api.ServerInfo{
-						Time:            time.Now().Format(time.RFC3339),
-						UptimeSec:       uptimeSec,
-						UptimeFormatted: api.FmtUptime(uptimeSec),
-					},
-				}); err != nil {
-					log.Printf("WebSocket system update error: %v", err)
-					return
-				}
 			case <-pingTicker.C:
 				if err := wsManager.WriteJSON(conn, map[string]string{"type": "ping"}); err != nil {
-					log.Printf("WebSocket ping error: %v", err)
+					wsLog.Debug("ping failed", "remote", r.RemoteAddr, "error", err)
 					return
 				}
 			case <-timerStatusTicker.C:
+				if !wsManager.IsSubscribed(conn, api.WSTopicTimers) {
+					continue
+				}
 				// Send timer status updates for UI
 				timerManager := api.GetTimerManager()
 				timerStatus := timerManager.GetTimerStatus()
@@ -952,7 +661,7 @@ func runServer(cmd *cobra.Command) error {
 					"timerStatus":  timerStatus,
 					"timestamp":    time.Now().Unix(),
 				}); err != nil {
-					log.Printf("WebSocket timer status error: %v", err)
+					wsLog.Debug("timer status failed", "remote", r.RemoteAddr, "error", err)
 					return
 				}
 			}
@@ -961,8 +670,32 @@ func runServer(cmd *cobra.Command) error {
 
 	srv := &http.Server{
 		Addr:              cfg.ListenAddr,
-		Handler:           api.WithSecurityHeaders(mux),
+		Handler:           api.WithBasePath(basePath, api.WithRequestLog(api.WithSecurityHeaders(api.WithAuth(api.WithTimings(mux))))),
 		ReadHeaderTimeout: 5 * time.Second,
+		ConnContext:       api.ConnContext,
+	}
+
+	// Serve HTTPS when TLS is configured
+	scheme := "http"
+	if fileConfig.TLS != nil {
+		tlsConfig, challengeHandler, err := fileConfig.TLS.ServerTLS()
+		if err != nil {
+			return fmt.Errorf("failed to set up TLS: %w", err)
+		}
+		srv.TLSConfig = tlsConfig
+		scheme = "https"
+		if challengeHandler != nil && fileConfig.TLS.ACME.HTTPAddr != "" {
+			go func() {
+				challengeSrv := &http.Server{
+					Addr:              fileConfig.TLS.ACME.HTTPAddr,
+					Handler:           challengeHandler,
+					ReadHeaderTimeout: 5 * time.Second,
+				}
+				if err := challengeSrv.ListenAndServe(); err != nil {
+					log.Printf("ACME HTTP challenge listener error: %v", err)
+				}
+			}()
+		}
 	}
 
 	_, listenPort, _ := net.SplitHostPort(cfg.ListenAddr)
@@ -970,9 +703,6 @@ func runServer(cmd *cobra.Command) error {
 		listenPort = "8080"
 	}
 
-	// Initialize debug logger and load preferences
-	api.GetDebugLogger().UpdatePrefs()
-
 	// Log app startup
 	log.Printf("Homepage Dashboard v%s starting...", appversion)
 
@@ -981,8 +711,232 @@ func runServer(cmd *cobra.Command) error {
 	timerManager := api.GetTimerManager()
 	go timerManager.Start()
 
+	// Open the SQLite store if configured and restore persisted storage items
+	if fileConfig.Store != nil {
+		if err := api.OpenStore(*fileConfig.Store); err != nil {
+			return fmt.Errorf("failed to open store: %w", err)
+		}
+	}
+	if store := api.GetStore(); store != nil {
+		api.GetStorage().Restore(store.LoadStorageItems())
+	}
+
+	// Prune the store, timeline, audit log, search history and sessions past their retention
+	if fileConfig.Store != nil {
+		api.GetRetentionManager().Configure(fileConfig.Store.Retention)
+	}
+	go api.GetRetentionManager().Start()
+
+	// Load per-module debug logging preferences (after restoring storage)
+	api.GetDebugLogger().UpdatePrefs()
+
+	// Start metrics collector (system updates are fanned out to WebSocket subscribers
+	// and recorded in the metric history)
+	retention, hourlyRetention, _ := fileConfig.HistoryRetentionDurations()
+	api.ConfigureMetricsHistory(retention, hourlyRetention)
+	go api.GetMetricsCollector().Start()
+
+	// Configure API token authentication (tokens are managed through /api/tokens) and single sign-on
+	if fileConfig.Auth != nil {
+		api.GetTokenManager().Configure(*fileConfig.Auth)
+		api.GetOIDCManager().Configure(fileConfig.Auth.OIDC)
+	}
+
+	// Start presence tracking (Home Assistant polling; OwnTracks posts to /api/presence/owntracks)
+	if fileConfig.Presence != nil {
+		api.GetPresenceManager().Configure(*fileConfig.Presence)
+		go api.GetPresenceManager().Start()
+	}
+
+	// Schedule guest Wi-Fi password rotation (credentials are stored through /api/guest-wifi)
+	if fileConfig.GuestWiFi != nil {
+		api.GetGuestWiFiManager().Configure(*fileConfig.GuestWiFi)
+		go api.GetGuestWiFiManager().Start()
+	}
+
+	// Log and count requests for /api/stats
+	if fileConfig.RequestLog != nil {
+		api.GetRequestStats().Configure(*fileConfig.RequestLog)
+	}
+
+	// Count module use and API latencies locally for /api/stats/usage (on unless disabled)
+	if fileConfig.Usage != nil {
+		api.GetUsageStats().Configure(*fileConfig.Usage)
+	}
+	go api.GetUsageStats().Start()
+
+	// Limit how long module fetches may take and when failing modules are paused
+	if fileConfig.ModuleSandbox != nil {
+		api.GetModuleSandbox().Configure(*fileConfig.ModuleSandbox)
+	}
+
+	// Read router status over ubus for /api/router
+	if fileConfig.Router != nil {
+		api.GetRouterMonitor().Configure(*fileConfig.Router)
+	}
+
+	// Read virtual machines and containers for /api/virt
+	if fileConfig.Virt != nil {
+		api.GetVirtMonitor().Configure(*fileConfig.Virt)
+	}
+
+	// Poll UPSes for /api/ups and alert when one switches to battery
+	if fileConfig.UPS != nil {
+		api.GetUPSMonitor().Configure(*fileConfig.UPS)
+		go api.GetUPSMonitor().Start()
+	}
+
+	// Poll room temperature and humidity sensors for /api/climate
+	if fileConfig.Climate != nil {
+		api.GetClimateMonitor().Configure(*fileConfig.Climate)
+		go api.GetClimateMonitor().Start()
+	}
+
+	// Predict ISS passes for /api/astro (defaults apply without a config)
+	if fileConfig.Astro != nil {
+		api.GetAstroService().Configure(*fileConfig.Astro)
+	}
+
+	// Advise on watering the garden for /api/garden (defaults apply without a config)
+	if fileConfig.Garden != nil {
+		api.GetGardenService().Configure(*fileConfig.Garden)
+	}
+
+	// Sanitize the Embed card's snippets for /api/embed
+	if fileConfig.Embed != nil {
+		api.GetEmbedService().Configure(*fileConfig.Embed)
+	}
+
+	// Report NFS and SMB share capacity for /api/shares
+	if fileConfig.Shares != nil {
+		api.GetShareMonitor().Configure(*fileConfig.Shares)
+	}
+
+	// Read server BMCs over Redfish or IPMI for /api/oob
+	if fileConfig.OOB != nil {
+		api.GetOOBMonitor().Configure(*fileConfig.OOB)
+	}
+
+	// Run the whitelisted commands of /api/command on their timers
+	if fileConfig.Command != nil {
+		api.GetCommandRunner().Configure(*fileConfig.Command)
+		go api.GetCommandRunner().Start()
+	}
+
+	// Watch the public IP and update dynamic DNS records when it changes
+	if fileConfig.PublicIP != nil {
+		api.GetPublicIPWatcher().Configure(*fileConfig.PublicIP)
+		go api.GetPublicIPWatcher().Start()
+	}
+
+	// Check the internet connection so external modules can show cached data while offline
+	if fileConfig.Connectivity != nil {
+		api.GetConnectivity().Configure(*fileConfig.Connectivity)
+	}
+	go api.GetConnectivity().Start()
+
+	// Look up the location and network of the public IP for /api/ip and /api/summary
+	if fileConfig.GeoIP != nil {
+		if err := api.GetGeoIPResolver().Configure(*fileConfig.GeoIP); err != nil {
+			return err
+		}
+	}
+
+	// Scan the listening ports of this host and flag newly opened ones for /api/exposure
+	if fileConfig.Exposure != nil {
+		api.GetExposureScanner().Configure(*fileConfig.Exposure)
+		go api.GetExposureScanner().Start()
+	}
+
+	// Keep search history on the server so every device shares it
+	if fileConfig.SearchHistory != nil {
+		api.GetSearchHistoryStore().Configure(*fileConfig.SearchHistory)
+	}
+
+	// Share text and files between devices through /api/drop (on without a config)
+	if fileConfig.Drop != nil {
+		api.GetDropStore().Configure(*fileConfig.Drop)
+	}
+	go api.GetDropStore().Start()
+
+	// Subscribe to MQTT topics for /api/mqtt
+	if fileConfig.MQTT != nil {
+		api.GetMQTTManager().Configure(*fileConfig.MQTT)
+		go api.GetMQTTManager().Start()
+	}
+
+	// Price stocks and cryptocurrencies for /api/quotes
+	if fileConfig.Quotes != nil {
+		api.GetQuoteService().Configure(*fileConfig.Quotes)
+	}
+
+	// Exchange rates for /api/currency (EUR to USD and GBP without a config)
+	if fileConfig.Currency != nil {
+		api.GetCurrencyService().Configure(*fileConfig.Currency)
+	}
+
+	// Public holidays in the calendar (none without a country)
+	if fileConfig.Holidays != nil {
+		api.GetHolidayService().Configure(*fileConfig.Holidays)
+	}
+
+	// Birthdays of CardDAV address books (uploaded vCard files need no config)
+	if fileConfig.Birthdays != nil {
+		api.GetBirthdayService().Configure(*fileConfig.Birthdays)
+	}
+
+	// Text-to-speech for /api/brief/audio
+	if fileConfig.TTS != nil {
+		api.GetSpeaker().Configure(*fileConfig.TTS)
+	}
+
+	// Register the email alert channel
+	if fileConfig.SMTP != nil {
+		api.GetMailer().Configure(*fileConfig.SMTP)
+	}
+
+	// Start chat bots and register their alert channels
+	if fileConfig.Bots != nil {
+		api.GetBotManager().Configure(*fileConfig.Bots, cfg.Weather)
+		go api.GetBotManager().Start()
+	}
+
+	// Start alert manager to deliver alerts queued during quiet hours
+	if fileConfig.QuietHours != nil {
+		api.GetAlertManager().Configure(*fileConfig.QuietHours)
+	}
+	go api.GetAlertManager().Start()
+
+	// Start daily digest scheduler
+	digestConfig := api.DigestConfig{}
+	if fileConfig.Digest != nil {
+		digestConfig = *fileConfig.Digest
+	}
+	api.GetDigestScheduler().Configure(digestConfig, cfg.Weather)
+	go api.GetDigestScheduler().Start()
+
+	// Switch between the light and dark scheme at fixed times or at sunrise and sunset
+	if fileConfig.ThemeSchedule != nil {
+		checkThemeSchedule(*fileConfig.ThemeSchedule)
+		api.GetThemeScheduler().Configure(*fileConfig.ThemeSchedule, cfg.Weather)
+		go api.GetThemeScheduler().Start()
+	}
+
+	// Start boot tracker to record reboots
+	go api.GetBootTracker().Start()
+
+	// Record hardware changes since the last run on the timeline
+	go api.GetTimeline().CheckHardware(context.Background())
+
 	log.Printf("Dashboard starting...")
 	log.Printf("  Listening on: %s", cfg.ListenAddr)
+	for _, l := range fileConfig.Listeners {
+		if l.Role != "" {
+			log.Printf("  Also listening on: %s (up to %s)", l.Addr, l.Role)
+		} else {
+			log.Printf("  Also listening on: %s", l.Addr)
+		}
+	}
 
 	ifaces, err := net.Interfaces()
 	if err == nil {
@@ -1006,12 +960,66 @@ func runServer(cmd *cobra.Command) error {
 					continue
 				}
 				if ip.To4() != nil {
-					log.Printf("  http://%s:%s", ip.String(), listenPort)
+					log.Printf("  %s://%s:%s%s", scheme, ip.String(), listenPort, basePath)
 				}
 			}
 		}
 	}
-	log.Printf("  http://localhost:%s", listenPort)
+	log.Printf("  %s://localhost:%s%s", scheme, listenPort, basePath)
+
+	// Announce the dashboard on the LAN by mDNS (on without a config)
+	mdnsConfig := api.MDNSConfig{}
+	if fileConfig.MDNS != nil {
+		mdnsConfig = *fileConfig.MDNS
+	}
+	api.GetMDNS().Configure(mdnsConfig, cfg.Title, cfg.ListenAddr, srv.TLSConfig != nil, basePath)
+	go api.GetMDNS().Start()
+
+	// Apply changes of the config file on SIGHUP or when it is saved
+	go newConfigReloader(configPath, fileConfig, overrides, apiHandler).Start()
+
+	// On SIGTERM or Ctrl-C, finish the requests in flight before exiting
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+		sig := <-stop
+		log.Printf("Received %s, shutting down...", sig)
+		api.SdNotify("STOPPING=1")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("Shutdown error: %v", err)
+		}
+	}()
 
-	return srv.ListenAndServe()
+	// Tell systemd (Type=notify) the dashboard is ready and keep its watchdog fed
+	if err := api.SdNotify("READY=1\nSTATUS=Serving on " + cfg.ListenAddr); err != nil {
+		log.Printf("sd_notify failed: %v", err)
+	}
+	go api.SdWatchdog()
+
+	// One server serves every listener, so shutting it down closes them all. Serving sets
+	// up HTTP/2 in srv.TLSConfig, so whether to use TLS is decided first
+	useTLS := srv.TLSConfig != nil
+	serve := func(ln net.Listener) error {
+		if useTLS {
+			return srv.ServeTLS(ln, "", "")
+		}
+		return srv.Serve(ln)
+	}
+	for _, ln := range extraListeners {
+		go func() {
+			if err := serve(ln); !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Listener %s error: %v", ln.Addr(), err)
+			}
+		}()
+	}
+	err = serve(listener)
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	<-shutdownDone
+	return nil
 }
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"homepage/api"
+)
+
+// How often the config file is checked for changes
+const configPollInterval = 2 * time.Second
+
+// configReloader applies changes of the config file while the dashboard runs. It reloads on
+// SIGHUP and when the file's modification time or size changes. Only settings that are safe
+// to change at runtime are applied; the others are logged as needing a restart.
+type configReloader struct {
+	mu        sync.Mutex
+	path      string
+	flagsArg  string              // --config as given, resolved again on each load
+	overrides func(*Config) error // Environment and command line flags, which win over the file
+	current   Config
+	modTime   time.Time
+	size      int64
+	handler   *api.Handler
+}
+
+// newConfigReloader watches the config file the dashboard was started with.
+func newConfigReloader(configPath string, current Config, overrides func(*Config) error, handler *api.Handler) *configReloader {
+	cr := &configReloader{flagsArg: configPath, current: current, overrides: overrides, handler: handler}
+	cr.path, _ = configFilePath(configPath)
+	if info, err := os.Stat(cr.path); err == nil {
+		cr.modTime, cr.size = info.ModTime(), info.Size()
+	}
+	return cr
+}
+
+// Start reloads on SIGHUP and when the file changes, until the process exits.
+func (cr *configReloader) Start() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-hup:
+			api.Logger("config").Info("received SIGHUP, reloading", "path", cr.path)
+			cr.Reload()
+		case <-ticker.C:
+			info, err := os.Stat(cr.path)
+			if err != nil {
+				continue
+			}
+			cr.mu.Lock()
+			changed := !info.ModTime().Equal(cr.modTime) || info.Size() != cr.size
+			cr.modTime, cr.size = info.ModTime(), info.Size()
+			cr.mu.Unlock()
+			if changed {
+				api.Logger("config").Info("config file changed, reloading", "path", cr.path)
+				cr.Reload()
+			}
+		}
+	}
+}
+
+// Reload reads the config file and applies it. A file that does not parse or validate is
+// ignored, keeping the running settings.
+func (cr *configReloader) Reload() {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	// LoadConfig would write a default file in place of a removed one
+	if _, err := os.Stat(cr.path); err != nil {
+		api.Logger("config").Error("reload failed, keeping the running settings", "path", cr.path, "error", err)
+		return
+	}
+	next, err := LoadConfig(cr.flagsArg)
+	if err != nil {
+		api.Logger("config").Error("reload failed, keeping the running settings", "path", cr.path, "error", err)
+		return
+	}
+	if err := cr.overrides(&next); err != nil {
+		api.Logger("config").Error("reload failed, keeping the running settings: invalid environment", "error", err)
+		return
+	}
+	if err := validateConfig(next); err != nil {
+		api.Logger("config").Error("reload failed, keeping the running settings: invalid configuration", "path", cr.path, "error", err)
+		return
+	}
+
+	if restart := restartRequired(cr.current, next); len(restart) > 0 {
+		api.Logger("config").Warn("restart to apply changed settings", "settings", restart)
+	}
+	changed := reloadableChanges(cr.current, next)
+	applyRuntimeConfig(next, cr.handler)
+	cr.current = next
+	api.Logger("config").Info("config reloaded", "changed", changed)
+
+	api.GetWSManager().Broadcast(map[string]interface{}{
+		"type":    "config-reload",
+		"title":   cr.handler.Title(),
+		"changed": changed,
+	})
+}
+
+// applyRuntimeConfig applies the settings that may change while running. A section removed
+// from the file falls back to its defaults.
+func applyRuntimeConfig(c Config, h *api.Handler) {
+	apiConfig := c.APIConfig(h.Config().ListenAddr)
+	h.SetConfig(apiConfig)
+
+	if err := api.SetLogLevel(orZero(c.Logging), c.Debug); err != nil {
+		api.Logger("config").Warn("invalid logging settings", "error", err)
+	}
+	api.GetDebugLogger().UpdatePrefs()
+	api.GetTimerManager().Reload()
+
+	api.GetRateLimiter().Configure(orZero(c.RateLimit))
+	api.GetFaviconCache().Configure(orZero(c.Favicon))
+	api.GetIconPack().Configure(orZero(c.Icons))
+	api.GetChangelogService().Configure(orZero(c.Changelog), appversion)
+	api.GetRequestStats().Configure(orZero(c.RequestLog))
+	api.GetModuleSandbox().Configure(orZero(c.ModuleSandbox))
+	api.GetAstroService().Configure(orZero(c.Astro))
+	api.GetGardenService().Configure(orZero(c.Garden))
+	api.GetEmbedService().Configure(orZero(c.Embed))
+	api.GetQuoteService().Configure(orZero(c.Quotes))
+	api.GetCurrencyService().Configure(orZero(c.Currency))
+	api.GetHolidayService().Configure(orZero(c.Holidays))
+	api.GetBirthdayService().Configure(orZero(c.Birthdays))
+	api.GetAlertManager().Configure(orZero(c.QuietHours))
+	api.GetDigestScheduler().Configure(orZero(c.Digest), apiConfig.Weather)
+}
+
+// reloadableChanges names the runtime settings that differ, as the config file keys.
+func reloadableChanges(old, next Config) []string {
+	changed := []string{}
+	check := func(name string, a, b any) {
+		if !reflect.DeepEqual(a, b) {
+			changed = append(changed, name)
+		}
+	}
+	check("title", old.Title, next.Title)
+	check("weather", old.Weather, next.Weather)
+	check("github", old.GitHub, next.GitHub)
+	check("debug", old.Debug, next.Debug)
+	check("logging", old.Logging, next.Logging)
+	check("rateLimit", old.RateLimit, next.RateLimit)
+	check("favicon", old.Favicon, next.Favicon)
+	check("icons", old.Icons, next.Icons)
+	check("changelog", old.Changelog, next.Changelog)
+	check("requestLog", old.RequestLog, next.RequestLog)
+	check("moduleSandbox", old.ModuleSandbox, next.ModuleSandbox)
+	check("astro", old.Astro, next.Astro)
+	check("garden", old.Garden, next.Garden)
+	check("embed", old.Embed, next.Embed)
+	check("quotes", old.Quotes, next.Quotes)
+	check("currency", old.Currency, next.Currency)
+	check("holidays", old.Holidays, next.Holidays)
+	check("birthdays", old.Birthdays, next.Birthdays)
+	check("quietHours", old.QuietHours, next.QuietHours)
+	check("digest", old.Digest, next.Digest)
+	return changed
+}
+
+// restartRequired names the changed settings that only take effect after a restart: what
+// the server listens on, its store and security, and the background monitors.
+func restartRequired(old, next Config) []string {
+	// Everything but the runtime settings
+	strip := func(c Config) Config {
+		c.Title, c.Weather, c.GitHub, c.Debug = "", nil, nil, false
+		c.RateLimit, c.Favicon, c.Icons, c.Changelog = nil, nil, nil, nil
+		c.RequestLog, c.ModuleSandbox, c.Astro, c.Garden, c.Embed = nil, nil, nil, nil, nil
+		c.Quotes, c.Currency, c.Holidays, c.Birthdays = nil, nil, nil, nil
+		c.QuietHours, c.Digest = nil, nil
+		if c.Logging != nil {
+			// The level and modules are applied, the output is not
+			logging := *c.Logging
+			logging.Level, logging.Modules = "", nil
+			c.Logging = &logging
+			if reflect.DeepEqual(logging, api.LoggingConfig{}) {
+				c.Logging = nil
+			}
+		}
+		return c
+	}
+	a, b := reflect.ValueOf(strip(old)), reflect.ValueOf(strip(next))
+	var fields []string
+	for i := range a.NumField() {
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			fields = append(fields, jsonName(a.Type().Field(i)))
+		}
+	}
+	return fields
+}
+
+// jsonName returns the config file key of a field.
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// orZero returns the value of an optional config section, the zero value when absent.
+func orZero[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
@@ -12,9 +12,36 @@ const refreshHandlers = {
   snmp: () => window.refreshSnmp && window.refreshSnmp(),
   speedplane: () => window.refreshSpeedplane && window.refreshSpeedplane(),
   dnsplane: () => window.refreshDnsplane && window.refreshDnsplane(),
+  presence: () => window.refreshPresence && window.refreshPresence(),
+  guestwifi: () => window.refreshGuestWifi && window.refreshGuestWifi(),
+  router: () => window.refreshRouter && window.refreshRouter(),
+  virt: () => window.refreshVirt && window.refreshVirt(),
+  ups: () => window.refreshUps && window.refreshUps(),
+  climate: () => window.refreshClimate && window.refreshClimate(),
+  astro: () => window.refreshAstro && window.refreshAstro(),
+  garden: () => window.refreshGarden && window.refreshGarden(),
+  shares: () => window.refreshShares && window.refreshShares(),
+  oob: () => window.refreshOob && window.refreshOob(),
+  command: () => window.refreshCommands && window.refreshCommands(),
+  mqtt: () => window.refreshMqtt && window.refreshMqtt(),
+  quotes: () => window.refreshQuotes && window.refreshQuotes(true), // Skip the server cache on double-click
+  currency: () => window.refreshCurrency && window.refreshCurrency(),
   rss: () => window.refreshRss && window.refreshRss()
 };
 
+// Refreshes one card of a module type that can be placed more than once
+function refreshModuleInstance(id) {
+  const handler = {
+    disk: window.refreshDiskInstance,
+    github: window.refreshGitHubInstance,
+    rss: window.refreshRssInstance
+  }[window.moduleTypeOf(id)];
+  if (!handler) return;
+  handler(id);
+  if (window.startTimer) window.startTimer(id);
+}
+window.refreshModuleInstance = refreshModuleInstance;
+
 function setupTimerHandlers() {
   Object.keys(refreshHandlers).forEach(key => {
     const timerEl = document.getElementById(key + 'Timer');
@@ -54,6 +81,11 @@ function loadModulePrefs() {
 async function saveModulePrefs() {
   try {
     const prefs = {};
+    // Module instances are not in moduleConfig, keep their entries
+    const saved = window.loadFromStorage('modulePrefs') || {};
+    Object.keys(saved).forEach(key => {
+      if (window.isModuleInstanceId(key)) prefs[key] = saved[key];
+    });
     if (window.moduleConfig) {
       Object.keys(window.moduleConfig).forEach(key => {
         prefs[key] = {
@@ -187,12 +219,6 @@ async function initApp() {
   loadModulePrefs();
   applyModuleVisibility();
 
-  // Load saved page title
-  const savedTitle = window.loadFromStorage('pageTitle');
-  if (savedTitle && window.applyPageTitle) {
-    window.applyPageTitle(savedTitle);
-  }
-
   // Init search
   if (window.initSearch) window.initSearch();
 
@@ -213,7 +239,30 @@ async function initApp() {
   if (window.initDisk) window.initDisk();
   if (window.initCalendar) window.initCalendar();
   if (window.initTodo) window.initTodo();
+  if (window.initNotes) window.initNotes();
+  if (window.initDrop) window.initDrop();
   if (window.initWorldClock) window.initWorldClock();
+  if (window.initCountdown) window.initCountdown();
+  if (window.initTools) window.initTools();
+  if (window.initEmbed) window.initEmbed();
+  if (window.initPresence) window.initPresence();
+  if (window.initGuestWifi) window.initGuestWifi();
+  if (window.initRouter) window.initRouter();
+  if (window.initVirt) window.initVirt();
+  if (window.initUps) window.initUps();
+  if (window.initClimate) window.initClimate();
+  if (window.initAstro) window.initAstro();
+  if (window.initGarden) window.initGarden();
+  if (window.initChangelog) window.initChangelog();
+  if (window.initUsage) window.initUsage();
+  if (window.initShares) window.initShares();
+  if (window.initOob) window.initOob();
+  if (window.initCommands) window.initCommands();
+  if (window.initMqtt) window.initMqtt();
+  if (window.initQuotes) window.initQuotes();
+  if (window.initCurrency) window.initCurrency();
+  if (window.initModuleHealth) window.initModuleHealth();
+  if (window.initBanners) window.initBanners();
 
   // Init layout
   if (window.initLayout) window.initLayout();
@@ -232,19 +281,39 @@ async function initApp() {
   window.onModuleRefresh = function(moduleName) {
     if (window.debugLog) window.debugLog('app', 'WebSocket refresh notification for:', moduleName);
 
+    // Instances with an interval of their own are refreshed alone
+    if (window.isModuleInstanceId(moduleName)) {
+      refreshModuleInstance(moduleName);
+      return;
+    }
+
     // Map timer keys to refresh handlers - modules fetch their own data via HTTP
     const refreshMap = {
       'cpu': () => window.refreshCPU && window.refreshCPU(),
       'ram': () => window.refreshRAM && window.refreshRAM(),
-      'disk': () => window.refreshAllDisks && window.refreshAllDisks(),
-      'github': () => window.refreshGitHub && window.refreshGitHub(),
+      'disk': () => window.refreshAllDisks && window.refreshAllDisks(true),
+      'github': () => window.refreshGitHub && window.refreshGitHub(false, true),
       'weather': () => window.refreshWeather && window.refreshWeather(),
       'ip': () => window.refreshIP && window.refreshIP(),
       'monitoring': () => window.refreshMonitoring && window.refreshMonitoring(),
       'snmp': () => window.refreshSnmp && window.refreshSnmp(),
       'speedplane': () => window.refreshSpeedplane && window.refreshSpeedplane(),
       'dnsplane': () => window.refreshDnsplane && window.refreshDnsplane(),
-      'rss': () => window.refreshRss && window.refreshRss()
+      'presence': () => window.refreshPresence && window.refreshPresence(),
+      'guestwifi': () => window.refreshGuestWifi && window.refreshGuestWifi(),
+      'router': () => window.refreshRouter && window.refreshRouter(),
+      'virt': () => window.refreshVirt && window.refreshVirt(),
+      'ups': () => window.refreshUps && window.refreshUps(),
+      'climate': () => window.refreshClimate && window.refreshClimate(),
+      'astro': () => window.refreshAstro && window.refreshAstro(),
+      'garden': () => window.refreshGarden && window.refreshGarden(),
+      'shares': () => window.refreshShares && window.refreshShares(),
+      'oob': () => window.refreshOob && window.refreshOob(),
+      'command': () => window.refreshCommands && window.refreshCommands(),
+      'mqtt': () => window.refreshMqtt && window.refreshMqtt(),
+      'quotes': () => window.refreshQuotes && window.refreshQuotes(),
+      'currency': () => window.refreshCurrency && window.refreshCurrency(),
+      'rss': () => window.refreshRss && window.refreshRss(true)
     };
 
     const handler = refreshMap[moduleName];
@@ -257,6 +326,38 @@ async function initApp() {
     }
   };
 
+  // The server reloaded its config file: show the new title and refresh what changed
+  window.onConfigReload = function(data) {
+    if (window.debugLog) window.debugLog('app', 'Config reloaded, changed:', data.changed);
+    if (data.title) {
+      document.title = data.title;
+      const titleEl = document.querySelector('.h-title');
+      if (titleEl) titleEl.textContent = data.title;
+    }
+    const changed = data.changed || [];
+    ['weather', 'quotes', 'currency', 'astro', 'garden'].forEach(name => {
+      if (changed.includes(name)) window.onModuleRefresh(name);
+    });
+    if (changed.includes('holidays') || changed.includes('birthdays')) {
+      if (window.renderCalendar) window.renderCalendar();
+      if (window.renderWeekCalendar) window.renderWeekCalendar();
+      if (window.renderUpcomingEvents) window.renderUpcomingEvents();
+    }
+  };
+
+  // The general settings changed: show the title and redraw what the server formats
+  window.onSettingsChange = function(data) {
+    if (!data || !data.effective) return;
+    window.dashboardSettings = data;
+    if (window.applyPageTitle) window.applyPageTitle(data.effective.title);
+    if (window.showGeneralSettings) window.showGeneralSettings(data);
+    ['weather', 'ram', 'disk'].forEach(name => window.onModuleRefresh(name));
+    if (window.renderCalendar) window.renderCalendar();
+    if (window.renderWeekCalendar) window.renderWeekCalendar();
+    if (window.renderUpcomingEvents) window.renderUpcomingEvents();
+    if (window.renderNextTodos) window.renderNextTodos();
+  };
+
   // Initialize sync status indicator
   if (window.updateSyncStatusIndicator) {
     window.updateSyncStatusIndicator();
@@ -278,7 +379,7 @@ async function initApp() {
 
       // Reload layout only if the backend actually overwrote layout storage (avoids clobbering in-memory edits / stale rows)
       const keys = (syncResult && syncResult.updatedKeys) || [];
-      const layoutStorageTouched = keys.indexOf('layoutConfig') !== -1 || keys.indexOf('moduleOrder') !== -1;
+      const layoutStorageTouched = keys.indexOf(window.layoutStorageKey) !== -1 || keys.indexOf('moduleOrder') !== -1;
       if (layoutStorageTouched && window.loadLayoutConfig) {
         window.loadLayoutConfig();
         if (window.renderLayout) {
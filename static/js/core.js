@@ -12,6 +12,20 @@ const timers = {
   snmp: {interval: 60000, lastUpdate: 0, timer: null},
   speedplane: {interval: 300000, lastUpdate: 0, timer: null},
   dnsplane: {interval: 60000, lastUpdate: 0, timer: null},
+  presence: {interval: 60000, lastUpdate: 0, timer: null},
+  guestwifi: {interval: 300000, lastUpdate: 0, timer: null},
+  router: {interval: 60000, lastUpdate: 0, timer: null},
+  virt: {interval: 60000, lastUpdate: 0, timer: null},
+  ups: {interval: 30000, lastUpdate: 0, timer: null},
+  climate: {interval: 60000, lastUpdate: 0, timer: null},
+  astro: {interval: 900000, lastUpdate: 0, timer: null},
+  garden: {interval: 1800000, lastUpdate: 0, timer: null},
+  shares: {interval: 60000, lastUpdate: 0, timer: null},
+  oob: {interval: 60000, lastUpdate: 0, timer: null},
+  command: {interval: 60000, lastUpdate: 0, timer: null},
+  mqtt: {interval: 60000, lastUpdate: 0, timer: null},
+  quotes: {interval: 300000, lastUpdate: 0, timer: null},
+  currency: {interval: 3600000, lastUpdate: 0, timer: null},
   rss: {interval: 300000, lastUpdate: 0, timer: null},
   general: {interval: 30000, lastUpdate: 0, timer: null}
 };
@@ -44,6 +58,7 @@ function updateTimer(moduleName) {
 function startTimer(moduleName) {
   const timer = timers[moduleName];
   if (!timer) return;
+  if (window.countModuleRender) window.countModuleRender(moduleName);
   timer.lastUpdate = Date.now();
   updateTimer(moduleName);
   if (timer.timer) clearInterval(timer.timer);
@@ -377,10 +392,223 @@ function updateSyncStatusIndicator() {
   }
 }
 
+// Dashboard profile selected by /p/{profile} (or ?profile=). Layout, module, quick link,
+// theme and locale keys are stored per profile; everything else is shared. The default profile uses plain keys.
+const PROFILE_SCOPED_KEYS = new Set([
+  'layoutConfig', 'layoutPages', 'moduleOrder', 'modulePrefs',
+  'quicklinks', 'quicklinksLayout', 'quicklinksIconsOnly', 'quicklinksEqualSize',
+  'template', 'scheme', 'localePrefs'
+]);
+const currentProfile = (function() {
+  const match = window.appPath().match(/^\/p\/([a-z0-9][a-z0-9_-]{0,31})(?:\/[a-z0-9][a-z0-9_-]{0,31})?\/?$/);
+  if (match) return match[1];
+  const param = (new URLSearchParams(window.location.search).get('profile') || '').toLowerCase();
+  return param && param !== 'default' ? param : '';
+})();
+
+// Layout page selected by /page/{page} or /p/{profile}/{page}; its layout is stored under
+// layoutConfig.{page}, the home page's under layoutConfig
+const currentPage = (function() {
+  const match = window.appPath().match(/^\/(?:page|p\/[a-z0-9][a-z0-9_-]{0,31})\/([a-z0-9][a-z0-9_-]{0,31})\/?$/);
+  return match ? match[1] : 'home';
+})();
+const layoutStorageKey = currentPage === 'home' ? 'layoutConfig' : 'layoutConfig.' + currentPage;
+
+function isProfileScopedKey(key) {
+  return PROFILE_SCOPED_KEYS.has(key) || /^layoutConfig\.[a-z0-9][a-z0-9_-]{0,31}$/.test(key);
+}
+
+// localStorage (and backend) key for a storage key in the current profile
+function profileStorageKey(key) {
+  return currentProfile && isProfileScopedKey(key) ? `profile:${currentProfile}:${key}` : key;
+}
+
+// Maps a backend storage key to a key of the current profile, or null if it belongs to another profile
+function storageKeyFromBackend(storedKey) {
+  const match = /^profile:([^:]+):(.+)$/.exec(storedKey || '');
+  if (match) return match[1] === currentProfile ? match[2] : null;
+  if (currentProfile && isProfileScopedKey(storedKey)) return null;
+  return storedKey;
+}
+
+// Appends the current profile to a storage API URL
+function withProfile(url) {
+  if (!currentProfile) return url;
+  return url + (url.includes('?') ? '&' : '?') + 'profile=' + encodeURIComponent(currentProfile);
+}
+
+// Responses of the first paint, fetched in one request from /api/bootstrap. Cards read them
+// through bootstrapFetch while they are fresh and fetch for themselves after that.
+const BOOTSTRAP_TTL = 5000;
+let bootstrapResponses = {};
+let bootstrapLoaded = 0;
+
+async function loadBootstrap() {
+  try {
+    const res = await fetch(withProfile('/api/bootstrap?page=' + encodeURIComponent(currentPage)), { cache: 'no-store' });
+    if (!res.ok) return;
+    const data = await res.json();
+    if (data.error || !data.responses) return;
+    bootstrapResponses = data.responses;
+    bootstrapLoaded = Date.now();
+    if (window.debugLog) window.debugLog('core', 'Bootstrap:', Object.keys(data.responses).length, 'responses in', data.ms, 'ms, pending:', data.pending);
+  } catch (err) {
+    if (window.debugError) window.debugError('core', 'Bootstrap failed:', err);
+  }
+}
+
+// URL of a request as /api/bootstrap keys it: the query sorted, without the profile or
+// GitHub token, and escaped like Go's url.Values (~ kept, * escaped)
+function bootstrapKey(url) {
+  const u = new URL(url, window.location.origin);
+  u.searchParams.delete('profile');
+  u.searchParams.delete('token');
+  u.searchParams.sort();
+  const query = u.searchParams.toString().replace(/%7E/g, '~').replace(/\*/g, '%2A');
+  return u.pathname + (query ? '?' + query : '');
+}
+
+// fetch for the first requests of the cards: answered from /api/bootstrap when it has the
+// response, else by fetcher (default fetch)
+async function bootstrapFetch(url, options, fetcher) {
+  await bootstrapReady;
+  if ((!options || !options.method || options.method === 'GET') && Date.now() - bootstrapLoaded < BOOTSTRAP_TTL) {
+    const body = bootstrapResponses[bootstrapKey(url)];
+    if (body !== undefined) {
+      return new Response(JSON.stringify(body), { status: 200, headers: { 'Content-Type': 'application/json' } });
+    }
+  }
+  return (fetcher || fetch)(url, options);
+}
+
+const bootstrapReady = loadBootstrap();
+
+// Module types that can be placed more than once give each card an instance ID of the form
+// {type}:{name}, e.g. rss:news or disk:/mnt/media. Cards added before instance IDs keep
+// theirs (rss-1712345678). api/module_instances.go parses them the same way.
+const MODULE_INSTANCE_TYPES = ['disk', 'github', 'rss'];
+
+function moduleTypeOf(id) {
+  if (typeof id !== 'string') return id;
+  const sep = id.indexOf(':');
+  if (sep > 0) return id.slice(0, sep);
+  return MODULE_INSTANCE_TYPES.find(t => id.startsWith(t + '-') && id.length > t.length + 1) || id;
+}
+
+function isModuleInstanceId(id) {
+  return moduleTypeOf(id) !== id;
+}
+
+// Free instance ID for a name among the existing instances: "news" becomes rss:news, or rss:news-2 when taken
+function newModuleInstanceId(type, name, existing) {
+  const base = String(name || '').replace(/[^A-Za-z0-9_.~\/-]+/g, '-').replace(/^-+|-+$/g, '').slice(0, 56) || type;
+  const taken = id => (existing || []).some(m => m && m.id === id);
+  let id = type + ':' + base;
+  for (let n = 2; taken(id); n++) id = type + ':' + base + '-' + n;
+  return id;
+}
+
+// Own refresh interval of a module instance in seconds, 0 when it refreshes with its type
+function moduleInstanceInterval(id) {
+  const prefs = loadFromStorage('modulePrefs') || {};
+  const pref = prefs[id];
+  return pref && pref.interval > 0 ? pref.interval : 0;
+}
+
+// Gives a module instance a refresh interval of its own, or 0 to refresh with its type.
+// The server runs the timer from the module preferences.
+function setModuleInstanceInterval(id, seconds) {
+  if (moduleInstanceInterval(id) === seconds) return;
+  const prefs = loadFromStorage('modulePrefs') || {};
+  if (seconds > 0) {
+    prefs[id] = Object.assign({}, prefs[id], { interval: seconds });
+  } else {
+    delete prefs[id];
+  }
+  saveToStorage('modulePrefs', prefs);
+}
+
+// Timer circle of a module instance with an interval of its own, '' for one refreshed with its type
+function moduleInstanceTimerHtml(id) {
+  if (!moduleInstanceInterval(id)) return '';
+  return `<div class="timer-circle" id="${escapeHtml(id)}Timer" data-instance="${escapeHtml(id)}" title="Double-click to refresh"></div>`;
+}
+
+function bindModuleInstanceTimer(card) {
+  const timerEl = card.querySelector('.timer-circle[data-instance]');
+  if (!timerEl) return;
+  timerEl.addEventListener('dblclick', (e) => {
+    e.preventDefault();
+    e.stopPropagation();
+    if (window.refreshModuleInstance) window.refreshModuleInstance(timerEl.dataset.instance);
+  });
+  if (timers[timerEl.dataset.instance]) updateTimer(timerEl.dataset.instance);
+}
+
+// Config schemas of the module types by type, fetched from /api/modules/schema once
+let moduleSchemasPromise = null;
+
+// Config schema of a module type with its defaults, null when the type has none
+function loadModuleSchema(type) {
+  if (!moduleSchemasPromise) {
+    moduleSchemasPromise = fetch('/api/modules/schema')
+      .then(res => res.json())
+      .then(data => {
+        const byType = {};
+        (data.schemas || []).forEach(schema => { byType[schema.type] = schema; });
+        return byType;
+      })
+      .catch(err => {
+        moduleSchemasPromise = null;
+        if (window.debugError) window.debugError('core', 'Error loading module schemas:', err);
+        return {};
+      });
+  }
+  return moduleSchemasPromise.then(byType => {
+    const schema = byType[type];
+    if (!schema) return null;
+    const defaults = {};
+    schema.fields.forEach(f => { if (f.default !== undefined) defaults[f.id] = f.default; });
+    return Object.assign({}, schema, { defaults });
+  });
+}
+
+// Edit dialog fields of a module schema. Fields that only apply to some configs are not
+// marked required here, the server checks them on validate.
+function moduleSchemaFields(schema) {
+  return schema.fields.map(f => ({
+    id: f.id,
+    label: f.label,
+    type: f.type,
+    placeholder: f.placeholder || '',
+    required: !!f.required && !f.when,
+    options: f.options || [],
+    min: f.max > f.min ? f.min : undefined,
+    max: f.max > f.min ? f.max : undefined
+  }));
+}
+
+// Checks a module config against its schema on the server, resolves to the error or ''
+async function validateModuleConfig(type, data) {
+  try {
+    const res = await fetch('/api/modules/config', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ type, action: 'validate', data })
+    });
+    const result = await res.json();
+    return result.valid ? '' : (result.error || 'Invalid configuration');
+  } catch (err) {
+    // Saving is not blocked when the server cannot be asked
+    if (window.debugError) window.debugError('core', 'Error validating module config:', type, err);
+    return '';
+  }
+}
+
 // Storage version metadata (tracks lastModified timestamp for each key)
 function getStorageVersion(key) {
   try {
-    const metaKey = key + '_meta';
+    const metaKey = profileStorageKey(key) + '_meta';
     const meta = localStorage.getItem(metaKey);
     if (meta) {
       const parsed = JSON.parse(meta);
@@ -394,7 +622,7 @@ function getStorageVersion(key) {
 
 function setStorageVersion(key, version) {
   try {
-    const metaKey = key + '_meta';
+    const metaKey = profileStorageKey(key) + '_meta';
     const meta = {
       version: version,
       lastModified: Date.now()
@@ -408,7 +636,7 @@ function setStorageVersion(key, version) {
 /** If local *_meta version is behind the server, bump local meta to match so the next save sync wins (avoids rejected writes then refresh restoring old layout/maxWidth). */
 async function alignLocalStorageVersionWithBackendKey(key) {
   try {
-    const res = await fetch('/api/storage/get?key=' + encodeURIComponent(key), { cache: 'no-store' });
+    const res = await fetch(withProfile('/api/storage/get?key=' + encodeURIComponent(key)), { cache: 'no-store' });
     if (!res.ok) return;
     const data = await res.json();
     const backendVer = parseInt(data && data.version, 10);
@@ -445,7 +673,7 @@ function syncToBackend(key, value, version) {
   };
 
   // Try to sync immediately
-  fetch('/api/storage/sync', {
+  fetch(withProfile('/api/storage/sync'), {
     method: 'POST',
     headers: {
       'Content-Type': 'application/json'
@@ -521,7 +749,7 @@ function processStorageSyncQueue() {
     timestamp: Date.now()
   };
 
-  fetch('/api/storage/sync', {
+  fetch(withProfile('/api/storage/sync'), {
     method: 'POST',
     headers: {
       'Content-Type': 'application/json'
@@ -560,10 +788,10 @@ function saveToStorage(key, value) {
     // Always JSON stringify for consistency (handles objects, arrays, booleans, numbers, null)
     // Only store plain strings as-is to avoid double-stringifying
     if (typeof value === 'string') {
-      localStorage.setItem(key, value);
+      localStorage.setItem(profileStorageKey(key), value);
     } else {
       // Object, array, boolean, number, null - JSON stringify
-      localStorage.setItem(key, JSON.stringify(value));
+      localStorage.setItem(profileStorageKey(key), JSON.stringify(value));
     }
 
     // Update version (increment)
@@ -580,7 +808,7 @@ function saveToStorage(key, value) {
 
 function loadFromStorage(key, defaultValue = null) {
   try {
-    const value = localStorage.getItem(key);
+    const value = localStorage.getItem(profileStorageKey(key));
     if (value === null) return defaultValue;
     // Try to parse as JSON, if fails return as string
     try {
@@ -597,7 +825,7 @@ function loadFromStorage(key, defaultValue = null) {
 // Check backend for newer version and update if needed
 async function syncFromBackend(key) {
   try {
-    const response = await fetch(`/api/storage/get?key=${encodeURIComponent(key)}`);
+    const response = await fetch(withProfile(`/api/storage/get?key=${encodeURIComponent(key)}`));
     if (!response.ok) {
       return false; // Backend doesn't have this key or error
     }
@@ -623,9 +851,9 @@ async function syncFromBackend(key) {
       
       // Save to localStorage using our wrapper (but sync is disabled)
       if (typeof data.value === 'string') {
-        localStorage.setItem(key, data.value);
+        localStorage.setItem(profileStorageKey(key), data.value);
       } else {
-        localStorage.setItem(key, JSON.stringify(data.value));
+        localStorage.setItem(profileStorageKey(key), JSON.stringify(data.value));
       }
       
       // Update version
@@ -652,7 +880,7 @@ async function syncAllFromBackend() {
     syncStatus.state = 'syncing';
     updateSyncStatusIndicator();
 
-    const response = await fetch('/api/storage/get-all');
+    const response = await bootstrapFetch(withProfile('/api/storage/get-all'));
     if (!response.ok) {
       syncStatus.state = 'offline';
       updateSyncStatusIndicator();
@@ -684,9 +912,9 @@ async function syncAllFromBackend() {
         
         // Save to localStorage
         if (typeof item.value === 'string') {
-          localStorage.setItem(item.key, item.value);
+          localStorage.setItem(profileStorageKey(item.key), item.value);
         } else {
-          localStorage.setItem(item.key, JSON.stringify(item.value));
+          localStorage.setItem(profileStorageKey(item.key), JSON.stringify(item.value));
         }
         
         // Update version
@@ -981,6 +1209,22 @@ window.fetchWithTimeout = fetchWithTimeout;
 window.saveToStorage = saveToStorage;
 window.loadFromStorage = loadFromStorage;
 window.syncFromBackend = syncFromBackend;
+window.currentProfile = currentProfile;
+window.currentPage = currentPage;
+window.layoutStorageKey = layoutStorageKey;
+window.withProfile = withProfile;
+window.bootstrapFetch = bootstrapFetch;
+window.moduleTypeOf = moduleTypeOf;
+window.isModuleInstanceId = isModuleInstanceId;
+window.newModuleInstanceId = newModuleInstanceId;
+window.moduleInstanceInterval = moduleInstanceInterval;
+window.setModuleInstanceInterval = setModuleInstanceInterval;
+window.moduleInstanceTimerHtml = moduleInstanceTimerHtml;
+window.bindModuleInstanceTimer = bindModuleInstanceTimer;
+window.loadModuleSchema = loadModuleSchema;
+window.moduleSchemaFields = moduleSchemaFields;
+window.validateModuleConfig = validateModuleConfig;
+window.storageKeyFromBackend = storageKeyFromBackend;
 window.syncAllFromBackend = syncAllFromBackend;
 window.getStorageVersion = getStorageVersion;
 window.setStorageVersion = setStorageVersion;
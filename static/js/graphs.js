@@ -296,7 +296,46 @@ function updateDiskGraph(usage, mountKey) {
   updateGraph(graphId, diskHistory[key], () => saveDiskHistory(key), usage);
 }
 
+// Load history from the server so graphs are identical on every device
+async function loadServerHistory() {
+  const graph = document.getElementById("cpuGraph");
+  const containerWidth = graph ? graph.clientWidth - 6 : 0;
+  const maxBars = containerWidth > 0 ? Math.floor((containerWidth + barGap) / (minBarWidth + barGap)) : 100;
+  if (maxBars <= 0) return;
+  const range = Math.max(maxBars * 5, 60) + 's';
+
+  const fetchSeries = async (metric) => {
+    try {
+      const res = await fetch(`/api/graphs/history?metric=${encodeURIComponent(metric)}&range=${range}`, { cache: 'no-store' });
+      if (!res.ok) return null;
+      const data = await res.json();
+      if (data.error || !Array.isArray(data.points) || data.points.length === 0) return null;
+      return data.points.map(p => p.v).slice(-maxBars);
+    } catch (e) {
+      if (window.debugError) window.debugError('graphs', 'Error loading server history:', metric, e);
+      return null;
+    }
+  };
+
+  const cpu = await fetchSeries('cpu');
+  if (cpu) { cpuHistory = cpu; saveCpuHistory(); }
+  const ram = await fetchSeries('ram');
+  if (ram) { ramHistory = ram; saveRamHistory(); }
+
+  if (window.diskModules) {
+    for (const mod of window.diskModules) {
+      if (!mod.enabled || !mod.mountPoint) continue;
+      const series = await fetchSeries('disk:' + mod.mountPoint);
+      if (series) {
+        diskHistory[mod.mountPoint.replace(/[^a-zA-Z0-9]/g, '_')] = series;
+      }
+    }
+    saveDiskHistory();
+  }
+}
+
 async function initGraphs() {
+  await loadServerHistory();
   const graphsReady = await trimHistoryArrays();
   renderCpuGraph();
   renderRamGraph();
@@ -332,6 +371,7 @@ window.saveFullBarsPreference = saveFullBarsPreference;
 window.saveColorizeBackgroundPreference = saveColorizeBackgroundPreference;
 window.applyFullBarsClass = applyFullBarsClass;
 window.trimHistoryArrays = trimHistoryArrays;
+window.loadServerHistory = loadServerHistory;
 window.renderCpuGraph = renderCpuGraph;
 window.updateCpuGraph = updateCpuGraph;
 window.renderRamGraph = renderRamGraph;
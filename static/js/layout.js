@@ -4,9 +4,19 @@ const LAYOUT_MIN_COLS = 1;
 const LAYOUT_MAX_COLS = 4;
 const LAYOUT_DEFAULT_COLS = 3;
 
+// Narrower screens with a grid of their own, narrowest first; wider viewports use the
+// desktop layout. Same values as layoutBreakpoints in api/layout_breakpoints.go.
+const LAYOUT_BREAKPOINTS = [
+  { name: 'mobile', maxWidth: 640, columns: 1 },
+  { name: 'tablet', maxWidth: 1024, columns: 2 }
+];
+
 let moduleConfig = {};
 let grid = null;
 let draggedElement = null;
+let renderedBreakpoint = null;
+// Set while the layout of a page other than home is fetched, so an empty one is not saved over it
+let pageLayoutPending = false;
 
 let layoutConfig = {
   maxWidth: 80,
@@ -27,7 +37,7 @@ const UNIVERSAL_CARD_DEFAULT_HEIGHT = 230;
 
 async function loadModuleMetadata() {
   try {
-    const res = await fetch("/api/modules", { cache: "no-store" });
+    const res = await window.bootstrapFetch("/api/modules", { cache: "no-store" });
     if (res.ok) {
       const data = await res.json();
       if (data.modules && typeof data.modules === 'object') {
@@ -41,7 +51,8 @@ async function loadModuleMetadata() {
             hasTimer: mod.hasTimer !== undefined ? mod.hasTimer : (mod.HasTimer !== undefined ? mod.HasTimer : false),
             timerKey: mod.timerKey || mod.TimerKey,
             defaultInterval: mod.defaultInterval || mod.DefaultInterval,
-            enabled: mod.enabled !== undefined ? mod.enabled : (mod.Enabled !== undefined ? mod.Enabled : true)
+            enabled: mod.enabled !== undefined ? mod.enabled : (mod.Enabled !== undefined ? mod.Enabled : true),
+            instances: !!mod.instances
           };
         });
         moduleConfig = converted;
@@ -101,9 +112,68 @@ function normalizeLayoutConfig() {
   });
   if (normalizedModules.length !== layoutConfig.modules.length) changed = true;
   layoutConfig.modules = normalizedModules;
+
+  if (layoutConfig.breakpoints !== undefined) {
+    const breakpoints = {};
+    const saved = layoutConfig.breakpoints && typeof layoutConfig.breakpoints === 'object' ? layoutConfig.breakpoints : {};
+    LAYOUT_BREAKPOINTS.forEach(bp => {
+      const grid = saved[bp.name];
+      if (!grid || typeof grid !== 'object' || !Array.isArray(grid.modules)) return;
+      const columns = clampInt(grid.columns, LAYOUT_MIN_COLS, LAYOUT_MAX_COLS, bp.columns);
+      const modules = grid.modules
+        .filter(entry => entry && typeof entry.id === 'string' && entry.id)
+        .map(entry => ({ id: entry.id, span: Math.min(columns, clampInt(entry.span, 1, LAYOUT_MAX_COLS, 1)) }));
+      breakpoints[bp.name] = { columns, modules };
+    });
+    if (JSON.stringify(breakpoints) !== JSON.stringify(layoutConfig.breakpoints)) changed = true;
+    if (Object.keys(breakpoints).length > 0) {
+      layoutConfig.breakpoints = breakpoints;
+    } else {
+      delete layoutConfig.breakpoints;
+    }
+  }
   return changed;
 }
 
+// Breakpoint of the current viewport: mobile, tablet or desktop
+function currentBreakpoint() {
+  const bp = LAYOUT_BREAKPOINTS.find(b => window.innerWidth <= b.maxWidth);
+  return bp ? bp.name : 'desktop';
+}
+
+// Grid of a breakpoint: the desktop layout, the grid saved for the breakpoint (placing the
+// desktop's modules), or one derived from the desktop layout.
+function layoutGridFor(name) {
+  const bp = LAYOUT_BREAKPOINTS.find(b => b.name === name);
+  if (!bp) return { columns: layoutConfig.columns, modules: layoutConfig.modules, derived: false };
+  const saved = layoutConfig.breakpoints && layoutConfig.breakpoints[name];
+  if (saved) {
+    const placed = new Set(layoutConfig.modules.map(m => m.id));
+    const modules = saved.modules.filter(m => placed.has(m.id));
+    const seen = new Set(modules.map(m => m.id));
+    layoutConfig.modules.forEach(m => {
+      if (!seen.has(m.id)) modules.push({ id: m.id, span: 1 });
+    });
+    saved.modules = modules;
+    return { columns: saved.columns, modules, derived: false };
+  }
+  const columns = Math.min(bp.columns, layoutConfig.columns);
+  return {
+    columns,
+    modules: layoutConfig.modules.map(m => ({ id: m.id, span: Math.min(columns, m.span || 1) })),
+    derived: true
+  };
+}
+
+// Modules of a breakpoint's grid for editing; a derived grid is saved for the breakpoint first
+function editableLayoutModules(name) {
+  const grid = layoutGridFor(name);
+  if (name === 'desktop' || !grid.derived) return grid.modules;
+  if (!layoutConfig.breakpoints) layoutConfig.breakpoints = {};
+  layoutConfig.breakpoints[name] = { columns: grid.columns, modules: grid.modules };
+  return grid.modules;
+}
+
 function migrateLegacyRowsToModules(saved) {
   if (!saved || typeof saved !== 'object') return null;
   if (Array.isArray(saved.modules)) return saved;
@@ -154,9 +224,23 @@ function buildDefaultLayoutFromDom() {
 
 function loadLayoutConfig() {
   try {
-    const saved = window.loadFromStorage('layoutConfig');
+    const saved = window.loadFromStorage(window.layoutStorageKey);
     if (saved) {
       layoutConfig = migrateLegacyRowsToModules(saved) || saved;
+    } else if (window.currentPage !== 'home') {
+      // Other pages start from the layout the server created for them
+      layoutConfig = { maxWidth: 80, columns: LAYOUT_DEFAULT_COLS, modules: [] };
+      if (!pageLayoutPending && window.syncFromBackend) {
+        pageLayoutPending = true;
+        window.syncFromBackend(window.layoutStorageKey).finally(() => {
+          pageLayoutPending = false;
+          if (!window.loadFromStorage(window.layoutStorageKey)) return;
+          loadLayoutConfig();
+          renderLayout();
+          initLayoutEditor();
+        });
+      }
+      return;
     } else {
       layoutConfig = buildDefaultLayoutFromDom();
     }
@@ -170,16 +254,17 @@ function loadLayoutConfig() {
 }
 
 function saveLayoutConfig() {
+  if (pageLayoutPending) return;
   (async () => {
     try {
       if (window.alignLocalStorageVersionWithBackendKey) {
-        await window.alignLocalStorageVersionWithBackendKey('layoutConfig');
+        await window.alignLocalStorageVersionWithBackendKey(window.layoutStorageKey);
       }
     } catch (e) {
       if (window.debugError) window.debugError('layout', 'Version align before layout save:', e);
     }
     try {
-      window.saveToStorage('layoutConfig', layoutConfig);
+      window.saveToStorage(window.layoutStorageKey, layoutConfig);
     } catch (e) {
       if (window.debugError) window.debugError('layout', 'Failed to save layout config:', e);
     }
@@ -353,7 +438,7 @@ function applyPackedLayout() {
   }
   if (!grid) return;
   const slots = Array.from(grid.querySelectorAll(':scope > .layout-slot'));
-  const cols = clampInt(layoutConfig.columns, LAYOUT_MIN_COLS, LAYOUT_MAX_COLS, LAYOUT_DEFAULT_COLS);
+  const cols = clampInt(layoutGridFor(currentBreakpoint()).columns, LAYOUT_MIN_COLS, LAYOUT_MAX_COLS, LAYOUT_DEFAULT_COLS);
   const gap = 16;
   const gridWidth = grid.clientWidth;
   if (!gridWidth || slots.length === 0) return;
@@ -363,7 +448,7 @@ function applyPackedLayout() {
 
   slots.forEach(slot => {
     const card = slot.querySelector(':scope > .card[data-module]');
-    if (!card) return;
+    if (!card || slot.hidden) return;
     const span = Math.min(cols, Math.max(1, clampInt(slot.dataset.span, 1, LAYOUT_MAX_COLS, 1)));
 
     let bestCol = 0;
@@ -565,22 +650,26 @@ function renderLayout() {
     if (enabled) cardsMap.set(id, card);
   });
 
-  // Ensure layout includes all currently enabled cards.
+  // Ensure the home page includes all currently enabled cards; other pages show only their own.
+  const isHomePage = window.currentPage === 'home';
   const present = new Set(layoutConfig.modules.map(m => m.id));
   cardsMap.forEach((card, id) => {
-    if (!present.has(id)) {
+    if (isHomePage && !present.has(id)) {
       layoutConfig.modules.push({ id, span: getDefaultSpanForCard(card) });
     }
   });
 
   grid.innerHTML = '';
   grid.className = 'layout-grid';
+  renderedBreakpoint = currentBreakpoint();
+  grid.dataset.breakpoint = renderedBreakpoint;
+  const activeGrid = layoutGridFor(renderedBreakpoint);
 
-  layoutConfig.modules.forEach(entry => {
+  activeGrid.modules.forEach(entry => {
     if (!entry || !entry.id) return;
     const card = cardsMap.get(entry.id);
     if (!card) return;
-    const span = Math.min(layoutConfig.columns, Math.max(1, clampInt(entry.span, 1, LAYOUT_MAX_COLS, 1)));
+    const span = Math.min(activeGrid.columns, Math.max(1, clampInt(entry.span, 1, LAYOUT_MAX_COLS, 1)));
     const slot = document.createElement('div');
     slot.className = 'layout-slot';
     slot.dataset.module = entry.id;
@@ -595,7 +684,7 @@ function renderLayout() {
     cardsMap.delete(entry.id);
   });
 
-  // Fallback append for anything that still exists.
+  // Fallback append for anything that still exists; modules of other pages stay hidden.
   cardsMap.forEach((card, id) => {
     const slot = document.createElement('div');
     slot.className = 'layout-slot';
@@ -607,7 +696,9 @@ function renderLayout() {
     `;
     grid.appendChild(slot);
     slot.appendChild(card);
-    if (!layoutConfig.modules.some(m => m.id === id)) {
+    if (!isHomePage) {
+      slot.hidden = true;
+    } else if (!layoutConfig.modules.some(m => m.id === id)) {
       layoutConfig.modules.push({ id, span: 1 });
     }
   });
@@ -635,15 +726,16 @@ function setupCardResizeObserver() {
 
 function getModuleName(moduleId) {
   if (moduleConfig[moduleId]) return moduleConfig[moduleId].name;
-  if (moduleId && moduleId.startsWith('github-')) {
+  const moduleType = window.moduleTypeOf(moduleId);
+  if (moduleType === 'github') {
     const m = window.githubModules ? window.githubModules.find(x => x.id === moduleId) : null;
     if (m) return m.name;
   }
-  if (moduleId && moduleId.startsWith('rss-')) {
+  if (moduleType === 'rss') {
     const m = window.rssModules ? window.rssModules.find(x => x.id === moduleId) : null;
     if (m) return m.name || 'RSS Feed';
   }
-  if (moduleId && moduleId.startsWith('disk-')) {
+  if (moduleType === 'disk') {
     const m = window.diskModules ? window.diskModules.find(x => x.id === moduleId) : null;
     if (m) return m.mountPoint === '/' ? 'Disk' : `Disk ${m.mountPoint}`;
   }
@@ -721,20 +813,51 @@ function bindLayoutColumnsSelectDelegated() {
   window._layoutColumnsDelegatedBound = true;
   function applyFromSelect(sel) {
     if (!sel || sel.id !== 'layoutColumns') return;
-    layoutConfig.columns = clampInt(sel.value, LAYOUT_MIN_COLS, LAYOUT_MAX_COLS, LAYOUT_DEFAULT_COLS);
+    const columns = clampInt(sel.value, LAYOUT_MIN_COLS, LAYOUT_MAX_COLS, LAYOUT_DEFAULT_COLS);
+    const breakpoint = editedBreakpoint();
+    if (breakpoint === 'desktop') {
+      layoutConfig.columns = columns;
+    } else {
+      editableLayoutModules(breakpoint);
+      layoutConfig.breakpoints[breakpoint].columns = columns;
+    }
     // Clamp spans to selected columns.
-    layoutConfig.modules.forEach(entry => {
-      entry.span = Math.min(layoutConfig.columns, clampInt(entry.span, 1, LAYOUT_MAX_COLS, 1));
+    layoutGridFor(breakpoint).modules.forEach(entry => {
+      entry.span = Math.min(columns, clampInt(entry.span, 1, LAYOUT_MAX_COLS, 1));
     });
     saveLayoutConfig();
     renderLayout();
     renderLayoutEditor();
+    initLayoutEditor();
   }
   document.addEventListener('change', function(e) {
     if (e.target && e.target.id === 'layoutColumns') applyFromSelect(e.target);
   });
 }
 
+// Breakpoint chosen in the Layout tab
+function editedBreakpoint() {
+  const sel = document.getElementById('layoutBreakpoint');
+  return sel && sel.value ? sel.value : 'desktop';
+}
+
+function bindLayoutBreakpointControls() {
+  const sel = document.getElementById('layoutBreakpoint');
+  const reset = document.getElementById('layoutBreakpointReset');
+  if (!sel || sel.dataset.bound === '1') return;
+  sel.dataset.bound = '1';
+  sel.value = currentBreakpoint();
+  sel.addEventListener('change', () => initLayoutEditor());
+  if (reset) {
+    reset.addEventListener('click', () => {
+      if (layoutConfig.breakpoints) delete layoutConfig.breakpoints[editedBreakpoint()];
+      saveLayoutConfig();
+      renderLayout();
+      initLayoutEditor();
+    });
+  }
+}
+
 function initLayoutEditor() {
   bindLayoutMaxWidthSelectDelegated();
   bindLayoutColumnsSelectDelegated();
@@ -742,8 +865,209 @@ function initLayoutEditor() {
   const maxWidthSelect = document.getElementById('layoutMaxWidth');
   if (maxWidthSelect) maxWidthSelect.value = String(layoutConfig.maxWidth);
 
+  bindLayoutBreakpointControls();
+  const breakpoint = editedBreakpoint();
+  const activeGrid = layoutGridFor(breakpoint);
   const colsSelect = document.getElementById('layoutColumns');
-  if (colsSelect) colsSelect.value = String(layoutConfig.columns);
+  if (colsSelect) colsSelect.value = String(activeGrid.columns);
+
+  const row = document.getElementById('layoutBreakpointRow');
+  const status = document.getElementById('layoutBreakpointStatus');
+  if (row) row.style.display = breakpoint === 'desktop' ? 'none' : '';
+  if (status) status.textContent = activeGrid.derived ? 'Follows the desktop layout' : 'Has its own layout';
+  const reset = document.getElementById('layoutBreakpointReset');
+  if (reset) reset.disabled = activeGrid.derived;
+}
+
+// Posts to a page endpoint of the current profile, showing the error of a failed request
+async function postPageRequest(action, body, title) {
+  try {
+    const res = await (await fetch(window.withProfile('/api/pages/' + action), {
+      method: 'POST',
+      headers: {'Content-Type': 'application/json'},
+      body: JSON.stringify(body)
+    })).json();
+    if (res.error) {
+      await window.popup.alert(res.error, title);
+      return null;
+    }
+    return res;
+  } catch (e) {
+    await window.popup.alert('Unable to reach the server', title);
+    return null;
+  }
+}
+
+// Tabs above the grid linking the pages of the profile, and the Pages section of the Layout tab
+async function renderPageTabs() {
+  let data;
+  try {
+    data = await (await fetch(window.withProfile('/api/pages'))).json();
+  } catch (e) {
+    if (window.debugError) window.debugError('layout', 'Failed to load pages:', e);
+    return;
+  }
+  const pages = data.pages || [];
+  if (!pages.some(p => p.id === window.currentPage)) {
+    // The page was deleted on another device
+    window.location.href = window.appUrl(pages.length ? pages[0].path : '/');
+    return;
+  }
+
+  const tabs = document.getElementById('pageTabs');
+  if (tabs) {
+    tabs.innerHTML = '';
+    pages.forEach(p => {
+      const a = document.createElement('a');
+      a.href = window.appUrl(p.path);
+      a.textContent = p.name;
+      if (p.id === window.currentPage) a.className = 'active';
+      tabs.appendChild(a);
+    });
+    tabs.hidden = pages.length < 2;
+  }
+  renderLayoutPages(pages, data.active);
+}
+
+function renderLayoutPages(pages, active) {
+  const list = document.getElementById('layoutPagesList');
+  if (!list) return;
+  list.innerHTML = '';
+  pages.forEach(p => {
+    const row = document.createElement('div');
+    row.className = 'pref-row';
+    const current = p.id === window.currentPage;
+    row.innerHTML = `
+      <label>${window.escapeHtml(p.name)}${current ? ' <span class="small" style="color:var(--muted);">(shown)</span>' : ''}</label>
+      <span class="small" style="flex:1; color:var(--muted);">${p.modules} module${p.modules === 1 ? '' : 's'}${p.id === active ? ' · opens at start' : ''}</span>
+      <a class="btn-small" href="${window.escapeHtml(window.appUrl(p.path))}" title="Open this page"><i class="fas fa-external-link-alt"></i></a>
+      <button class="btn-small" data-action="switch" title="Open this page when no page is in the address"${p.id === active ? ' disabled' : ''}><i class="fas fa-home"></i></button>
+      <button class="btn-small" data-action="delete" title="Delete this page"${p.id === 'home' ? ' disabled' : ''}><i class="fas fa-trash"></i></button>`;
+    row.querySelector('[data-action="switch"]').addEventListener('click', async () => {
+      await postPageRequest('switch', {id: p.id}, 'Switch Failed');
+    });
+    row.querySelector('[data-action="delete"]').addEventListener('click', async () => {
+      if (!await window.popup.confirm('Delete the page "' + p.name + '" and its layout?', 'Delete Page')) return;
+      await postPageRequest('delete', {id: p.id}, 'Delete Failed');
+    });
+    list.appendChild(row);
+  });
+  renderPageModules();
+}
+
+// Checklist of the modules shown on a page other than home; home shows every enabled module
+function renderPageModules() {
+  const section = document.getElementById('layoutPageModulesRow');
+  const list = document.getElementById('layoutPageModules');
+  if (!section || !list) return;
+  section.style.display = window.currentPage === 'home' ? 'none' : '';
+  if (window.currentPage === 'home') return;
+  list.innerHTML = '';
+  // Types placed more than once are listed by instance
+  const ids = Object.keys(moduleConfig).filter(id => isModuleEnabled(id) && !moduleConfig[id].instances);
+  [window.diskModules, window.githubModules, window.rssModules].forEach(instances => {
+    (instances || []).forEach(m => {
+      if (m.enabled && isModuleEnabled(window.moduleTypeOf(m.id))) ids.push(m.id);
+    });
+  });
+  ids.forEach(id => {
+    const label = document.createElement('label');
+    label.className = 'small';
+    label.style.cssText = 'display:inline-flex; align-items:center; gap:4px; margin:0 12px 6px 0;';
+    const box = document.createElement('input');
+    box.type = 'checkbox';
+    box.checked = layoutConfig.modules.some(m => m.id === id);
+    box.addEventListener('change', () => {
+      if (box.checked) {
+        if (!layoutConfig.modules.some(m => m.id === id)) layoutConfig.modules.push({ id, span: 1 });
+      } else {
+        removeModuleFromLayout(id);
+      }
+      saveLayoutConfig();
+      renderLayout();
+      renderLayoutEditor();
+    });
+    label.appendChild(box);
+    label.appendChild(document.createTextNode(getModuleName(id)));
+    list.appendChild(label);
+  });
+}
+
+function bindLayoutPageControls() {
+  const create = document.getElementById('layoutPageCreate');
+  if (!create || create.dataset.bound) return;
+  create.dataset.bound = '1';
+  create.addEventListener('click', async () => {
+    const input = document.getElementById('layoutPageName');
+    const name = input ? input.value.trim() : '';
+    if (!name) {
+      await window.popup.alert('Enter a name for the page', 'New Page');
+      return;
+    }
+    const copy = document.getElementById('layoutPageCopy');
+    const res = copy && copy.checked
+      ? await postPageRequest('clone', {id: window.currentPage, name}, 'New Page')
+      : await postPageRequest('create', {name}, 'New Page');
+    if (!res) return;
+    window.location.href = window.appUrl(res.path);
+  });
+}
+
+// Adds the page being shown to an API URL
+function withPage(url) {
+  if (window.currentPage === 'home') return url;
+  return url + (url.includes('?') ? '&' : '?') + 'page=' + encodeURIComponent(window.currentPage);
+}
+
+// Earlier layouts kept by the server, with a button to restore each
+async function renderLayoutHistory() {
+  const list = document.getElementById('layoutHistoryList');
+  if (!list) return;
+  let data;
+  try {
+    data = await (await fetch(withPage(window.withProfile('/api/layout/history')))).json();
+  } catch (e) {
+    if (window.debugError) window.debugError('layout', 'Failed to load layout history:', e);
+    list.innerHTML = '<div class="small" style="color:var(--muted);">Unable to load the layout history</div>';
+    return;
+  }
+  const versions = data.versions || [];
+  if (versions.length === 0) {
+    list.innerHTML = '<div class="small" style="color:var(--muted);">No earlier layouts yet</div>';
+    return;
+  }
+  list.innerHTML = '';
+  versions.forEach(v => {
+    const row = document.createElement('div');
+    row.className = 'pref-row';
+    row.innerHTML = `
+      <label>${window.escapeHtml(new Date(v.replaced).toLocaleString())}</label>
+      <span class="small" style="flex:1; color:var(--muted);">${v.modules} module${v.modules === 1 ? '' : 's'}</span>
+      <button class="btn-small" title="Restore this layout"><i class="fas fa-undo"></i> Restore</button>`;
+    row.querySelector('button').addEventListener('click', async () => {
+      if (!await window.popup.confirm('Replace the current layout with the one from ' + new Date(v.saved).toLocaleString() + '?', 'Restore Layout')) return;
+      try {
+        const res = await (await fetch(withPage(window.withProfile('/api/layout/rollback')), {
+          method: 'POST',
+          headers: {'Content-Type': 'application/json'},
+          body: JSON.stringify({id: v.id})
+        })).json();
+        if (res.error) {
+          await window.popup.alert(res.error, 'Restore Failed');
+          return;
+        }
+        if (window.syncFromBackend) await window.syncFromBackend(window.layoutStorageKey);
+        loadLayoutConfig();
+        initLayoutEditor();
+        renderLayout();
+        renderLayoutEditor();
+        renderLayoutHistory();
+      } catch (e) {
+        await window.popup.alert('Unable to restore the layout', 'Error');
+      }
+    });
+    list.appendChild(row);
+  });
 }
 
 function bindModuleSpanSelectDelegated() {
@@ -765,36 +1089,39 @@ function bindModuleSpanSelectDelegated() {
 
 function reorderByModuleId(sourceId, targetId) {
   if (!sourceId || !targetId || sourceId === targetId) return false;
-  const srcIndex = layoutConfig.modules.findIndex(m => m.id === sourceId);
-  const dstIndex = layoutConfig.modules.findIndex(m => m.id === targetId);
+  const modules = editableLayoutModules(currentBreakpoint());
+  const srcIndex = modules.findIndex(m => m.id === sourceId);
+  const dstIndex = modules.findIndex(m => m.id === targetId);
   if (srcIndex === -1 || dstIndex === -1) return false;
-  const [item] = layoutConfig.modules.splice(srcIndex, 1);
+  const [item] = modules.splice(srcIndex, 1);
   const nextIndex = srcIndex < dstIndex ? dstIndex : dstIndex;
-  layoutConfig.modules.splice(nextIndex, 0, item);
+  modules.splice(nextIndex, 0, item);
   return true;
 }
 
 function swapModulePositions(sourceId, targetId) {
   if (!sourceId || !targetId || sourceId === targetId) return false;
-  const srcIndex = layoutConfig.modules.findIndex(m => m.id === sourceId);
-  const dstIndex = layoutConfig.modules.findIndex(m => m.id === targetId);
+  const modules = editableLayoutModules(currentBreakpoint());
+  const srcIndex = modules.findIndex(m => m.id === sourceId);
+  const dstIndex = modules.findIndex(m => m.id === targetId);
   if (srcIndex === -1 || dstIndex === -1) return false;
-  const tmp = layoutConfig.modules[srcIndex];
-  layoutConfig.modules[srcIndex] = layoutConfig.modules[dstIndex];
-  layoutConfig.modules[dstIndex] = tmp;
+  const tmp = modules[srcIndex];
+  modules[srcIndex] = modules[dstIndex];
+  modules[dstIndex] = tmp;
   return true;
 }
 
 function insertModuleRelative(sourceId, targetId, placeBefore) {
   if (!sourceId || !targetId || sourceId === targetId) return false;
-  const srcIndex = layoutConfig.modules.findIndex(m => m.id === sourceId);
-  const dstIndex = layoutConfig.modules.findIndex(m => m.id === targetId);
+  const modules = editableLayoutModules(currentBreakpoint());
+  const srcIndex = modules.findIndex(m => m.id === sourceId);
+  const dstIndex = modules.findIndex(m => m.id === targetId);
   if (srcIndex === -1 || dstIndex === -1) return false;
-  const [item] = layoutConfig.modules.splice(srcIndex, 1);
-  const targetIndexAfterRemoval = layoutConfig.modules.findIndex(m => m.id === targetId);
+  const [item] = modules.splice(srcIndex, 1);
+  const targetIndexAfterRemoval = modules.findIndex(m => m.id === targetId);
   if (targetIndexAfterRemoval === -1) return false;
   const insertIndex = placeBefore ? targetIndexAfterRemoval : targetIndexAfterRemoval + 1;
-  layoutConfig.modules.splice(insertIndex, 0, item);
+  modules.splice(insertIndex, 0, item);
   return true;
 }
 
@@ -984,6 +1311,8 @@ function initLayout() {
   bindModuleSpanSelectDelegated();
 
   loadLayoutConfig();
+  bindLayoutPageControls();
+  renderPageTabs();
   setTimeout(() => {
     renderLayout();
     renderLayoutEditor();
@@ -1000,7 +1329,11 @@ function initLayout() {
     window.addEventListener('resize', () => {
       if (resizeDebounce) clearTimeout(resizeDebounce);
       resizeDebounce = setTimeout(() => {
-        applyPackedLayout();
+        if (currentBreakpoint() !== renderedBreakpoint) {
+          renderLayout();
+        } else {
+          applyPackedLayout();
+        }
       }, 80);
     });
   }
@@ -1012,6 +1345,8 @@ window.loadModuleMetadata = loadModuleMetadata;
 window.layoutSystem = {
   renderLayout,
   renderLayoutEditor,
+  renderLayoutHistory,
+  renderPageTabs,
   renderModuleHeightModesEditor,
   getModuleHeightMode,
   setModuleHeightMode,
@@ -1026,5 +1361,8 @@ window.layoutSystem = {
 };
 window.initDragAndDrop = initDragAndDrop;
 window.initLayout = initLayout;
+window.loadLayoutConfig = loadLayoutConfig;
+window.renderLayout = renderLayout;
+window.renderPageTabs = renderPageTabs;
 window.cleanupLayoutConfig = cleanupLayoutConfig;
 window.adjustRowHeights = adjustRowHeights;
@@ -0,0 +1,96 @@
+// Night sky: ISS passes and naked-eye planets at the weather location (via /api/astro).
+
+function astroTime(value) {
+  return new Date(value).toLocaleTimeString([], {hour: '2-digit', minute: '2-digit'});
+}
+
+function astroDay(value) {
+  const d = new Date(value);
+  const today = new Date();
+  if (d.toDateString() === today.toDateString()) return 'Today';
+  const tomorrow = new Date(today.getTime() + 86400000);
+  if (d.toDateString() === tomorrow.toDateString()) return 'Tomorrow';
+  return d.toLocaleDateString([], {weekday: 'short'});
+}
+
+function astroPlanetRow(p) {
+  let when;
+  if (p.visible) {
+    when = astroTime(p.visibleFrom) + '–' + astroTime(p.visibleUntil) + ', best ' + astroTime(p.bestTime) + ' ' + p.bestDirection + ' ' + p.bestAltitude.toFixed(0) + '°';
+  } else {
+    when = '<span style="color:var(--muted);">Not visible tonight</span>';
+  }
+  const title = 'Magnitude ' + p.magnitude.toFixed(1) + ', now ' + p.altitude.toFixed(0) + '° ' + p.direction;
+  return `<div class="kv" title="${window.escapeHtml(title)}"><div class="k"><i class="fas fa-circle" style="color:${p.visible ? 'var(--good)' : 'var(--muted)'};font-size:0.6em;width:2em;"></i>${window.escapeHtml(p.name)}</div><div class="v small">${when}</div></div>`;
+}
+
+function astroPassRow(pass) {
+  const icon = pass.visible ? 'fa-eye' : 'fa-eye-slash';
+  const color = pass.visible ? 'var(--good)' : 'var(--muted)';
+  const title = (pass.visible ? 'Visible' : 'Not visible (daylight or in the earth\'s shadow)') +
+    '\nRises ' + astroTime(pass.start) + ' ' + pass.startDirection +
+    ', peaks ' + astroTime(pass.peak) + ' ' + pass.peakDirection + ' ' + pass.maxElevation.toFixed(0) + '°' +
+    ', sets ' + astroTime(pass.end) + ' ' + pass.endDirection;
+  const minutes = Math.max(1, Math.round(pass.duration / 60));
+  return `<div class="kv" title="${window.escapeHtml(title)}"><div class="k"><i class="fas ${icon}" style="color:${color};width:1.2em;"></i> ${astroDay(pass.start)} ${astroTime(pass.start)}</div><div class="v small">${pass.startDirection} → ${pass.endDirection}, ${pass.maxElevation.toFixed(0)}°, ${minutes} min</div></div>`;
+}
+
+async function refreshAstro() {
+  const container = document.getElementById('astroContainer');
+  if (!container) return;
+  window.startTimer('astro');
+
+  let url = '/api/astro';
+  try {
+    const savedLoc = window.loadFromStorage('weatherLocation');
+    if (savedLoc) {
+      const loc = typeof savedLoc === 'string' ? JSON.parse(savedLoc) : savedLoc;
+      url += '?lat=' + loc.latitude + '&lon=' + loc.longitude;
+    }
+  } catch (e) {}
+
+  try {
+    const res = await fetch(url, {cache: 'no-store'});
+    const data = await res.json();
+    if (data.location === false) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">Set a weather location to see the night sky.</div>';
+      return;
+    }
+    if (data.error) {
+      container.innerHTML = `<div class="small" style="color:var(--muted);">${window.escapeHtml(data.error)}</div>`;
+      return;
+    }
+
+    let html = '';
+    if (data.night) {
+      html += `<div class="kv"><div class="k"><i class="fas fa-moon" style="width:1.2em;"></i> Dark sky</div><div class="v small">${astroTime(data.night.start)}–${astroTime(data.night.end)}</div></div>`;
+    } else {
+      html += '<div class="kv"><div class="k"><i class="fas fa-sun" style="width:1.2em;"></i> Dark sky</div><div class="v small" style="color:var(--muted);">No dark hours tonight</div></div>';
+    }
+    for (const p of data.planets) html += astroPlanetRow(p);
+
+    html += '<div class="small" style="color:var(--muted);margin-top:6px;">ISS passes</div>';
+    if (data.issError) {
+      html += `<div class="small" style="color:var(--muted);">${window.escapeHtml(data.issError)}</div>`;
+    } else if (!data.iss.length) {
+      html += '<div class="small" style="color:var(--muted);">No passes in the coming days.</div>';
+    } else {
+      for (const pass of data.iss.slice(0, 5)) html += astroPassRow(pass);
+    }
+    container.innerHTML = html;
+  } catch (err) {
+    if (window.debugError) window.debugError('astro', 'Error loading night sky:', err);
+  }
+}
+
+function initAstro() {
+  setTimeout(refreshAstro, 1000);
+  setInterval(function() {
+    if (!window.wsIsConnected || !window.wsIsConnected()) {
+      refreshAstro();
+    }
+  }, window.timers && window.timers.astro ? window.timers.astro.interval : 900000);
+}
+
+window.refreshAstro = refreshAstro;
+window.initAstro = initAstro;
@@ -0,0 +1,67 @@
+// Banners: dismissible dashboard messages raised by incoming webhooks.
+
+(function() {
+  'use strict';
+
+  function getContainer() {
+    let container = document.getElementById('bannerContainer');
+    if (!container) {
+      container = document.createElement('div');
+      container.id = 'bannerContainer';
+      container.className = 'banner-container';
+      const main = document.getElementById('mainContainer');
+      if (main && main.parentNode) {
+        main.parentNode.insertBefore(container, main);
+      } else {
+        document.body.prepend(container);
+      }
+    }
+    return container;
+  }
+
+  function addBanner(banner) {
+    if (!banner || !banner.id || document.querySelector(`[data-banner-id="${banner.id}"]`)) return;
+
+    const el = document.createElement('div');
+    el.className = `banner banner-${banner.severity || 'info'}`;
+    el.dataset.bannerId = banner.id;
+    el.innerHTML = `
+      <div class="banner-text">
+        <strong>${window.escapeHtml(banner.title)}</strong>
+        ${banner.message ? `<span class="banner-message">${window.escapeHtml(banner.message)}</span>` : ''}
+      </div>
+      <button class="banner-close" title="Dismiss" data-capability="banners.dismiss"><i class="fas fa-times"></i></button>
+    `;
+    el.querySelector('.banner-close').addEventListener('click', () => dismissBanner(banner.id));
+    if (window.applyCapabilities) window.applyCapabilities(el);
+    getContainer().appendChild(el);
+  }
+
+  function removeBanner(id) {
+    const el = document.querySelector(`[data-banner-id="${id}"]`);
+    if (el) el.remove();
+  }
+
+  async function dismissBanner(id) {
+    removeBanner(id);
+    try {
+      await fetch(`/api/banners?id=${encodeURIComponent(id)}`, { method: 'DELETE' });
+    } catch (err) {
+      if (window.debugError) window.debugError('banners', 'Failed to dismiss banner:', err);
+    }
+  }
+
+  async function initBanners() {
+    try {
+      const res = await fetch('/api/banners');
+      const data = await res.json();
+      (data.banners || []).forEach(addBanner);
+    } catch (err) {
+      if (window.debugError) window.debugError('banners', 'Failed to load banners:', err);
+    }
+  }
+
+  window.initBanners = initBanners;
+  window.addBanner = addBanner;
+  window.removeBanner = removeBanner;
+})();
@@ -10,7 +10,7 @@ let currentWeekDate = new Date();
 // Calendar settings
 let calendarSettings = {
   workWeekOnly: false,
-  startDay: 1,
+  startDay: null, // null follows the dashboard's first day of the week (Preferences > General)
   dimWeekends: false,
   weekendShade: false,
   weekendShadeColor: 'rgba(0,0,0,0.12)',
@@ -301,6 +301,12 @@ function openCalendarColorPicker(title, key) {
   });
 }
 
+/** First day of the week, 0 = Sunday: this profile's choice, else the dashboard's from the server. */
+function calendarStartDay(serverDefault) {
+  if (typeof calendarSettings.startDay === 'number') return calendarSettings.startDay;
+  return typeof serverDefault === 'number' ? serverDefault : 1;
+}
+
 function monthCellBackgroundStyle(dateStr, dayOfWeek, isToday, timeOffMap) {
   if (isToday) return '';
   const map = timeOffMap || timeOffMapFromSettings();
@@ -339,8 +345,6 @@ function generateEventId() {
 
 // Get events for a specific date - uses backend processing
 async function getEventsForDate(dateStr) {
-  if (calendarEvents.length === 0) return [];
-
   try {
     const res = await fetch(`/api/calendar/events-for-date?date=${encodeURIComponent(dateStr)}`, {
       method: 'POST',
@@ -363,8 +367,6 @@ async function getEventsForDate(dateStr) {
 
 // Get next N upcoming events - uses backend processing
 async function getUpcomingEvents(count = 5) {
-  if (calendarEvents.length === 0) return [];
-
   try {
     const res = await fetch(`/api/calendar/process?count=${count}`, {
       method: 'POST',
@@ -427,10 +429,11 @@ async function renderCalendar() {
   const daysInMonth = monthData.daysInMonth;
   const todayStr = monthData.today;
   const datesWithEvents = monthData.datesWithEvents;
+  const holidays = monthData.holidays || {};
   const timeOffMap = timeOffMapFromSettings();
 
   // Adjust day names based on startDay setting
-  const startDay = calendarSettings.startDay || 0; // 0 = Sunday, 1 = Monday
+  const startDay = calendarStartDay(monthData.weekStart); // 0 = Sunday, 1 = Monday
   const dayNames = ['Su', 'Mo', 'Tu', 'We', 'Th', 'Fr', 'Sa'];
   const reorderedDayNames = [];
   for (let i = 0; i < 7; i++) {
@@ -465,9 +468,11 @@ async function renderCalendar() {
     const isWeekend = (dayOfWeek === 0 || dayOfWeek === 6);
     const hasEvents = datesWithEvents.includes(dateStr);
     const isToday = dateStr === todayStr;
+    const holiday = holidays[dateStr] || '';
 
     let classes = 'cal-day';
     if (hasEvents) classes += ' has-event';
+    if (holiday) classes += ' holiday';
     if (isToday) classes += ' today';
     if (isWeekend && calendarSettings.dimWeekends) classes += ' dim';
 
@@ -476,7 +481,8 @@ async function renderCalendar() {
     const offTitle = timeOffMap.get(dateStr) || '';
     const tipParts = [];
     if (offTitle) tipParts.push(offTitle);
-    if (hasEvents) tipParts.push('Has events');
+    if (holiday) tipParts.push(holiday);
+    else if (hasEvents) tipParts.push('Has events');
     const tipStr = tipParts.length
       ? (window.escapeHtml ? window.escapeHtml(tipParts.join(' — ')) : tipParts.join(' — '))
       : '';
@@ -537,7 +543,9 @@ async function renderWeekCalendar() {
   let weekData = null;
   try {
     const weekStartStr = currentWeekDate.toISOString().split('T')[0];
-    const res = await fetch(`/api/calendar/week?weekStart=${weekStartStr}&workWeekOnly=${calendarSettings.workWeekOnly}&startDay=${calendarSettings.startDay || 1}`, {
+    // Without a start day of its own the server uses the dashboard's
+    const startDayParam = typeof calendarSettings.startDay === 'number' ? '&startDay=' + calendarSettings.startDay : '';
+    const res = await fetch(`/api/calendar/week?weekStart=${weekStartStr}&workWeekOnly=${calendarSettings.workWeekOnly}${startDayParam}`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify(calendarEvents),
@@ -600,7 +608,8 @@ async function renderWeekCalendar() {
       let eventsHtml = '';
       if (day.events && day.events.length > 0) {
         day.events.slice(0, 3).forEach(evt => {
-          eventsHtml += `<div class="week-event" title="${window.escapeHtml(evt.title)}">${evt.time ? evt.time + ' ' : ''}${window.escapeHtml(evt.title)}</div>`;
+          const eventClass = evt.type ? 'week-event ' + evt.type : 'week-event';
+          eventsHtml += `<div class="${eventClass}" title="${window.escapeHtml(evt.title)}">${evt.time ? evt.time + ' ' : ''}${window.escapeHtml(evt.title)}</div>`;
         });
         if (day.events.length > 3) {
           eventsHtml += `<div class="week-event more">+${day.events.length - 3} more</div>`;
@@ -664,10 +673,17 @@ async function renderUpcomingEvents() {
   for (const evt of events) {
     // Backend provides formattedDate, but if missing use basic format
     const formattedDate = evt.formattedDate || (evt.date + (evt.time ? ' ' + evt.time : ''));
+    // Backend adds the forecast for events within the forecast window
+    let weatherHtml = '';
+    if (evt.weather) {
+      const w = evt.weather;
+      const rain = w.precipitationProb ? `, ${Math.round(w.precipitationProb)}%` : '';
+      weatherHtml = ` <span title="${window.escapeHtml(w.iconDescription || '')}"><i class="fas ${window.escapeHtml(w.icon || 'fa-question')}"></i> ${Math.round(w.tempMin)}–${Math.round(w.tempMax)}${window.escapeHtml(w.tempUnit || '')}${rain}</span>`;
+    }
     html += `
       <div class="kv" style="flex-direction:column; align-items:flex-start; gap:4px;">
-        <div class="v" style="font-weight:500;">${window.escapeHtml(evt.title)}</div>
-        <div class="muted" style="font-size:0.85em;">${formattedDate}</div>
+        <div class="v" style="font-weight:500;">${eventTypeIcon(evt)}${window.escapeHtml(evt.title)}</div>
+        <div class="muted" style="font-size:0.85em;">${formattedDate}${weatherHtml}</div>
       </div>
     `;
   }
@@ -676,6 +692,13 @@ async function renderUpcomingEvents() {
 
 // Using escapeHtml from core.js
 
+// Icon of the events the server adds: public holidays and birthdays
+function eventTypeIcon(evt) {
+  if (evt.type === 'holiday') return '<i class="fas fa-flag" title="Public holiday"></i> ';
+  if (evt.type === 'birthday') return '<i class="fas fa-birthday-cake" title="Birthday"></i> ';
+  return '';
+}
+
 function moveEventUp(index) {
   if (window.moveArrayItemUp && window.moveArrayItemUp(calendarEvents, index)) {
     saveEvents();
@@ -951,10 +974,11 @@ function initCalendar() {
   }
 
   if (startDaySelect) {
-    startDaySelect.value = calendarSettings.startDay;
+    startDaySelect.value = typeof calendarSettings.startDay === 'number' ? String(calendarSettings.startDay) : '';
     startDaySelect.addEventListener('change', () => {
-      calendarSettings.startDay = parseInt(startDaySelect.value, 10);
+      calendarSettings.startDay = startDaySelect.value === '' ? null : parseInt(startDaySelect.value, 10);
       saveCalendarSettings();
+      renderCalendar();
       renderWeekCalendar(); // renderWeekCalendar is now async but we don't await it (fire and forget)
     });
   }
@@ -1066,6 +1090,7 @@ function initCalendar() {
   });
 
   syncCalendarPreferenceWidgets();
+  initBirthdays();
 }
 
 // ICS Calendar Management
@@ -1353,6 +1378,73 @@ async function refreshICSCalendars() {
   }
 }
 
+// Show how many birthdays the address books and uploaded contacts have
+async function renderBirthdaysStatus() {
+  const status = document.getElementById('birthdaysStatus');
+  if (!status) return;
+  try {
+    const res = await fetch('/api/calendar/birthdays', { cache: 'no-store' });
+    const data = await res.json();
+    const birthdays = data.birthdays || [];
+    if (birthdays.length === 0) {
+      status.textContent = 'No birthdays yet.';
+      return;
+    }
+    const next = birthdays[0];
+    status.textContent = `${birthdays.length} birthday(s); next: ${next.name} on ${next.next}${next.age ? ' (' + next.age + ')' : ''}`;
+  } catch (e) {
+    if (window.debugError) window.debugError('calendar', 'Failed to load birthdays:', e);
+  }
+}
+
+// Upload a vCard file, replacing the birthdays of the previous one
+async function uploadBirthdays(file) {
+  try {
+    const res = await fetch(`/api/calendar/birthdays?name=${encodeURIComponent(file.name.replace(/\.vcf$/i, ''))}`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'text/vcard' },
+      body: await file.text()
+    });
+    const data = await res.json();
+    if (!data.success) {
+      await window.popup.alert('Error: ' + (data.error || 'Upload failed'), 'Birthdays');
+      return;
+    }
+    await window.popup.alert(`Imported ${data.count} birthday(s).`, 'Birthdays');
+  } catch (e) {
+    await window.popup.alert('Error uploading contacts: ' + e.message, 'Birthdays');
+  }
+  renderBirthdaysStatus();
+  renderCalendar();
+  renderWeekCalendar();
+  renderUpcomingEvents();
+}
+
+function initBirthdays() {
+  const fileInput = document.getElementById('birthdaysFile');
+  const uploadBtn = document.getElementById('uploadBirthdaysBtn');
+  const clearBtn = document.getElementById('clearBirthdaysBtn');
+  if (uploadBtn && fileInput) {
+    uploadBtn.addEventListener('click', () => fileInput.click());
+    fileInput.addEventListener('change', () => {
+      if (fileInput.files.length > 0) uploadBirthdays(fileInput.files[0]);
+      fileInput.value = '';
+    });
+  }
+  if (clearBtn) {
+    clearBtn.addEventListener('click', async () => {
+      const ok = await window.popup.confirm('Remove the birthdays of the uploaded contacts?', 'Clear');
+      if (!ok) return;
+      await fetch('/api/calendar/birthdays', { method: 'DELETE' }).catch(() => {});
+      renderBirthdaysStatus();
+      renderCalendar();
+      renderWeekCalendar();
+      renderUpcomingEvents();
+    });
+  }
+  renderBirthdaysStatus();
+}
+
 // Initialize ICS calendar management
 function initICSCalendars() {
   loadICSCalendars();
@@ -1391,6 +1483,7 @@ window.renderWeekCalendar = renderWeekCalendar;
 window.renderUpcomingEvents = renderUpcomingEvents;
 window.renderEventsPreferenceList = renderEventsPreferenceList;
 window.initICSCalendars = initICSCalendars;
+window.renderBirthdaysStatus = renderBirthdaysStatus;
 window.showICSCalendarEditDialog = showICSCalendarEditDialog;
 window.showEventForm = showEventForm;
 window.hideEventForm = hideEventForm;
@@ -0,0 +1,107 @@
+// What's new: the dashboard's release notes (via /api/changelog), shown in Preferences → About.
+// After an update the footer offers the notes added since the version last seen.
+
+let changelogData = null;
+
+// changelogNotes renders the Markdown of release notes as escaped text with lists and headings.
+function changelogNotes(markdown) {
+  const lines = (markdown || '').split(/\r?\n/);
+  let html = '';
+  let inList = false;
+  for (const raw of lines) {
+    const line = raw.trim();
+    const item = line.match(/^[-*+]\s+(.*)$/);
+    if (item) {
+      if (!inList) { html += '<ul class="changelog-list">'; inList = true; }
+      html += '<li>' + window.escapeHtml(item[1]) + '</li>';
+      continue;
+    }
+    if (inList) { html += '</ul>'; inList = false; }
+    if (!line) continue;
+    const heading = line.match(/^#+\s+(.*)$/);
+    if (heading) {
+      html += '<div class="changelog-heading">' + window.escapeHtml(heading[1]) + '</div>';
+    } else {
+      html += '<div>' + window.escapeHtml(line) + '</div>';
+    }
+  }
+  if (inList) html += '</ul>';
+  return html;
+}
+
+function renderChangelog() {
+  const panel = document.getElementById('changelogPanel');
+  if (!panel || !changelogData) return;
+  const data = changelogData;
+
+  let html = '<div class="changelog-title"><i class="fas fa-gift"></i> What\'s new</div>';
+  if (data.updateAvailable) {
+    html += `<div class="changelog-update small">Version ${window.escapeHtml(data.latest)} is available (running ${window.escapeHtml(data.current)}).</div>`;
+  }
+  if (data.error && !data.entries.length) {
+    html += `<div class="small" style="color:var(--muted);">${window.escapeHtml(data.error)}</div>`;
+  } else if (!data.entries.length) {
+    html += '<div class="small" style="color:var(--muted);">No release notes.</div>';
+  }
+  for (const entry of data.entries) {
+    let badge = '';
+    if (entry.new) badge = '<span class="changelog-badge">Available</span>';
+    else if (entry.unseen) badge = '<span class="changelog-badge">New</span>';
+    else if (entry.version === data.current) badge = '<span class="changelog-badge current">Running</span>';
+    const date = entry.published ? new Date(entry.published).toLocaleDateString() : '';
+    html += `<div class="changelog-entry${entry.unseen || entry.new ? ' unseen' : ''}">
+      <div class="changelog-version"><a href="${window.escapeHtml(entry.url).replace(/"/g, '&quot;')}" target="_blank" rel="noreferrer">${window.escapeHtml(entry.name || entry.version)}</a> ${badge}<span class="changelog-date">${date}</span></div>
+      <div class="changelog-notes small">${changelogNotes(entry.notes)}</div>
+    </div>`;
+  }
+  panel.innerHTML = html;
+}
+
+function markChangelogSeen() {
+  if (!changelogData) return;
+  window.saveToStorage('changelogSeenVersion', changelogData.current);
+  if (changelogData.latest) window.saveToStorage('changelogSeenLatest', changelogData.latest);
+  const btn = document.getElementById('whatsNewBtn');
+  if (btn) btn.style.display = 'none';
+}
+
+async function refreshChangelog() {
+  let since = '';
+  try {
+    since = window.loadFromStorage('changelogSeenVersion') || '';
+  } catch (e) {}
+
+  try {
+    const res = await fetch('/api/changelog?since=' + encodeURIComponent(since));
+    changelogData = await res.json();
+    // A fresh install has nothing to catch up on
+    if (!since) window.saveToStorage('changelogSeenVersion', changelogData.current);
+    renderChangelog();
+
+    const unseen = changelogData.entries.filter(e => e.unseen).length;
+    const newUpdate = changelogData.updateAvailable && window.loadFromStorage('changelogSeenLatest') !== changelogData.latest;
+    const btn = document.getElementById('whatsNewBtn');
+    if (btn && (unseen > 0 || newUpdate)) {
+      btn.title = newUpdate ? 'Version ' + changelogData.latest + ' is available' : unseen + ' release(s) since your last visit';
+      btn.style.display = '';
+    }
+  } catch (err) {
+    if (window.debugError) window.debugError('changelog', 'Error loading changelog:', err);
+  }
+}
+
+function initChangelog() {
+  const btn = document.getElementById('whatsNewBtn');
+  if (btn) {
+    btn.addEventListener('click', () => {
+      if (window.openPreferencesTab) window.openPreferencesTab('about');
+      markChangelogSeen();
+    });
+  }
+  const aboutTab = document.querySelector('.modal-tab[data-tab="about"]');
+  if (aboutTab) aboutTab.addEventListener('click', markChangelogSeen);
+  setTimeout(refreshChangelog, 3000);
+}
+
+window.refreshChangelog = refreshChangelog;
+window.initChangelog = initChangelog;
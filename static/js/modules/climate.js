@@ -0,0 +1,75 @@
+// Climate: temperature and humidity per room from MQTT, Home Assistant and SNMP sensors (via /api/climate).
+
+const climateLevelColors = {
+  comfortable: 'var(--good)',
+  cold: 'var(--accent, #3b82f6)',
+  dry: 'var(--warn, #f59e0b)',
+  warm: 'var(--warn, #f59e0b)',
+  humid: 'var(--accent, #3b82f6)'
+};
+
+function climateValue(value, unit, level) {
+  if (value === undefined || value === null) return '';
+  const color = climateLevelColors[level] || 'inherit';
+  return `<span style="color:${color};white-space:nowrap;" title="${window.escapeHtml(level || '')}">${value.toFixed(1)}${unit}</span>`;
+}
+
+function climateRow(room) {
+  const parts = [];
+  if (room.temperature !== undefined) parts.push(climateValue(room.temperature, '°C', room.temperatureLevel));
+  if (room.humidity !== undefined) parts.push(climateValue(room.humidity, '%', room.humidityLevel));
+  const lines = ['Comfort ' + room.comfort.temperatureMin + '–' + room.comfort.temperatureMax + '°C, ' + room.comfort.humidityMin + '–' + room.comfort.humidityMax + '%'];
+  for (const s of room.sensors) {
+    let state = s.error ? s.error : (s.value !== undefined ? s.value.toFixed(1) + (s.metric === 'humidity' ? '%' : '°C') : '');
+    if (s.stale) state += ' (stale)';
+    lines.push(s.name + ' [' + s.source + ']: ' + state);
+  }
+  const icon = room.comfortable ? 'fa-check-circle' : 'fa-thermometer-half';
+  const color = room.comfortable ? 'var(--good)' : 'var(--muted)';
+  const value = parts.length ? parts.join(' · ') : '<span style="color:var(--muted);">No reading</span>';
+  return `<div class="kv" title="${window.escapeHtml(lines.join('\n'))}"><div class="k"><i class="fas ${icon}" style="color:${color};width:1.2em;"></i> ${window.escapeHtml(room.name)}</div><div class="v small">${value}</div></div>`;
+}
+
+async function refreshClimate() {
+  const container = document.getElementById('climateContainer');
+  if (!container) return;
+  window.startTimer('climate');
+
+  try {
+    const res = await fetch('/api/climate');
+    const data = await res.json();
+    if (!data.enabled) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">Configure rooms under "climate" in the config file.</div>';
+      return;
+    }
+    if (!data.rooms.length) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">No temperature or humidity sensors found.</div>';
+      return;
+    }
+    let html = '';
+    for (const room of data.rooms) {
+      html += climateRow(room);
+      if (room.temperatureToday || room.humidityToday) {
+        const range = [];
+        if (room.temperatureToday) range.push(room.temperatureToday.min.toFixed(1) + '–' + room.temperatureToday.max.toFixed(1) + '°C');
+        if (room.humidityToday) range.push(room.humidityToday.min.toFixed(0) + '–' + room.humidityToday.max.toFixed(0) + '%');
+        html += `<div class="kv"><div class="k small" style="color:var(--muted);">Today</div><div class="v small" style="color:var(--muted);">${range.join(' · ')}</div></div>`;
+      }
+    }
+    container.innerHTML = html;
+  } catch (err) {
+    if (window.debugError) window.debugError('climate', 'Error loading climate:', err);
+  }
+}
+
+function initClimate() {
+  setTimeout(refreshClimate, 1000);
+  setInterval(function() {
+    if (!window.wsIsConnected || !window.wsIsConnected()) {
+      refreshClimate();
+    }
+  }, window.timers && window.timers.climate ? window.timers.climate.interval : 60000);
+}
+
+window.refreshClimate = refreshClimate;
+window.initClimate = initClimate;
@@ -0,0 +1,90 @@
+// Commands: output of the whitelisted commands configured on the server (via /api/command).
+
+function commandAgo(value) {
+  if (!value) return 'never run';
+  const secs = Math.max(0, Math.floor((Date.now() - new Date(value).getTime()) / 1000));
+  if (secs < 60) return secs + 's ago';
+  if (secs < 3600) return Math.floor(secs / 60) + 'm ago';
+  return Math.floor(secs / 3600) + 'h ago';
+}
+
+function commandBlock(c) {
+  let icon = 'fa-circle';
+  let color = 'var(--muted)';
+  if (c.running) {
+    icon = 'fa-spinner fa-spin';
+  } else if (c.ran) {
+    icon = c.error ? 'fa-times-circle' : 'fa-check-circle';
+    color = c.error ? 'var(--bad, #ef4444)' : 'var(--good)';
+  }
+  const meta = [commandAgo(c.ran)];
+  if (c.ran) meta.push(c.durationMs.toFixed(0) + ' ms');
+  if (c.error) meta.push(c.error);
+  const title = c.interval ? 'Runs every ' + c.interval + 's' : 'Runs on demand';
+  let html = `<div class="kv" title="${window.escapeHtml(title)}"><div class="k"><i class="fas ${icon}" style="color:${color};width:1.2em;"></i> ${window.escapeHtml(c.title)}</div>`;
+  html += `<div class="v small">${window.escapeHtml(meta.join(' · '))} <button class="btn-small command-run" data-command="${window.escapeHtml(c.name)}" data-capability="command.run" title="Run now"${c.running ? ' disabled' : ''}><i class="fas fa-play"></i></button></div></div>`;
+  if (c.output) {
+    html += `<pre class="mono small" style="white-space:pre-wrap;max-height:200px;overflow:auto;margin:2px 0 8px;">${window.escapeHtml(c.output)}${c.truncated ? '\n…' : ''}</pre>`;
+  }
+  return html;
+}
+
+async function refreshCommands() {
+  const container = document.getElementById('commandContainer');
+  if (!container) return;
+  window.startTimer('command');
+
+  try {
+    const res = await fetch('/api/command');
+    const data = await res.json();
+    if (!data.enabled) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">Configure commands under "command" in the config file.</div>';
+      return;
+    }
+    container.innerHTML = data.commands.map(commandBlock).join('');
+    if (window.applyCapabilities) window.applyCapabilities(container);
+  } catch (err) {
+    if (window.debugError) window.debugError('command', 'Error loading commands:', err);
+  }
+}
+
+async function runCommand(name) {
+  try {
+    const res = await fetch('/api/command/' + encodeURIComponent(name), { method: 'POST' });
+    if (!res.ok) {
+      await window.popup.alert(res.status === 403 ? 'You are not allowed to run commands.' : 'Could not run the command.', 'Commands');
+      return;
+    }
+    const data = await res.json();
+    if (data.error) {
+      await window.popup.alert(data.error, 'Commands');
+      return;
+    }
+  } catch (err) {
+    if (window.debugError) window.debugError('command', 'Error running command:', name, err);
+  }
+  refreshCommands();
+}
+
+function initCommands() {
+  const container = document.getElementById('commandContainer');
+  if (container) {
+    container.addEventListener('click', (e) => {
+      const btn = e.target.closest('.command-run');
+      if (!btn) return;
+      btn.disabled = true;
+      btn.innerHTML = '<i class="fas fa-spinner fa-spin"></i>';
+      runCommand(btn.dataset.command);
+    });
+  }
+
+  setTimeout(refreshCommands, 1000);
+  setInterval(function() {
+    if (!window.wsIsConnected || !window.wsIsConnected()) {
+      refreshCommands();
+    }
+  }, window.timers && window.timers.command ? window.timers.command.interval : 60000);
+}
+
+window.refreshCommands = refreshCommands;
+window.initCommands = initCommands;
@@ -122,6 +122,129 @@ async function loadServerConfigs() {
   }
 }
 
+// Show the access level of this browser
+async function loadAuthStatus() {
+  const statusEl = document.getElementById('authStatus');
+  if (!statusEl) return;
+
+  try {
+    const res = await fetch(withProfile('/api/auth'));
+    const data = await res.json();
+    const id = data.identity || {};
+    if (!data.enabled) {
+      statusEl.textContent = 'No tokens configured, everyone can edit';
+    } else if (id.anonymous) {
+      statusEl.textContent = id.role ? 'Signed out (read-only)' : 'Signed out';
+    } else if (id.provider) {
+      statusEl.textContent = `Signed in as ${id.name} via ${id.provider} (${id.role})`;
+    } else {
+      statusEl.textContent = `Signed in as ${id.name || id.tokenId} (${id.role})`;
+    }
+    renderAuthProviders(data.providers || [], id);
+    loadSessions(data.enabled && !id.anonymous);
+    if (id.limited) {
+      statusEl.textContent += `, limited to ${id.role} here`;
+    }
+  } catch (err) {
+    statusEl.textContent = 'Error: ' + err.message;
+  }
+}
+
+// Signed-in devices of this user (of every user with sessions.manage), each with a
+// sign-out button
+async function loadSessions(signedIn) {
+  const row = document.getElementById('authSessionsRow');
+  const list = document.getElementById('authSessionsList');
+  if (!row || !list) return;
+  row.style.display = signedIn ? '' : 'none';
+  if (!signedIn) return;
+
+  try {
+    const all = can('sessions.manage') ? '?all=1' : '';
+    const res = await fetch('/api/sessions' + all);
+    const data = await res.json();
+    if (data.error) {
+      list.innerHTML = '<div class="small" style="color:var(--muted);">' + window.escapeHtml(data.error) + '</div>';
+      return;
+    }
+    if (!data.sessions.length) {
+      list.innerHTML = '<div class="small" style="color:var(--muted);">No browser sessions</div>';
+      return;
+    }
+    list.innerHTML = data.sessions.map(s => {
+      const seen = s.lastSeen ? new Date(s.lastSeen).toLocaleString() : 'never';
+      const who = data.all ? window.escapeHtml(s.name) + (s.provider ? ' via ' + window.escapeHtml(s.provider) : '') + ' · ' : '';
+      return `<div class="kv small" title="${window.escapeHtml(s.userAgent || '')}"><div class="k">${who}${window.escapeHtml(s.device)}${s.current ? ' <span class="muted">(this device)</span>' : ''}<br><span class="muted">${window.escapeHtml(s.ip || '')} · last seen ${window.escapeHtml(seen)}</span></div>` +
+        `<div class="v">${s.current ? '' : `<button class="btn-small" data-session-id="${window.escapeHtml(s.id)}" title="Sign out this device"><i class="fas fa-sign-out-alt"></i></button>`}</div></div>`;
+    }).join('');
+    list.querySelectorAll('[data-session-id]').forEach(btn => {
+      btn.addEventListener('click', () => revokeSessions('id=' + encodeURIComponent(btn.dataset.sessionId) + (all ? '&all=1' : '')));
+    });
+  } catch (err) {
+    list.innerHTML = '<div class="small" style="color:var(--muted);">Error: ' + window.escapeHtml(err.message) + '</div>';
+  }
+}
+
+async function revokeSessions(query) {
+  try {
+    const res = await fetch('/api/sessions?' + query, { method: 'DELETE' });
+    const result = await res.json();
+    if (result.error) {
+      await window.popup.alert('Error: ' + result.error, 'Error');
+    }
+  } catch (err) {
+    await window.popup.alert('Error signing out device: ' + err.message, 'Error');
+  }
+  loadSessions(true);
+}
+
+// Single sign-on buttons; the login runs in this window and comes back to the current page
+function renderAuthProviders(providers, id) {
+  const row = document.getElementById('authProvidersRow');
+  const container = document.getElementById('authProviders');
+  if (!row || !container) return;
+  row.style.display = providers.length && id.anonymous ? '' : 'none';
+  container.innerHTML = '';
+  providers.forEach(p => {
+    const btn = document.createElement('button');
+    btn.className = 'btn-small';
+    btn.innerHTML = '<i class="fas fa-user-shield"></i> ' + window.escapeHtml(p.name);
+    btn.addEventListener('click', () => {
+      const back = window.location.pathname + window.location.search;
+      window.location.href = window.appUrl('/api/auth/oidc/login') + '?provider=' + encodeURIComponent(p.id) + '&return=' + encodeURIComponent(back);
+    });
+    container.appendChild(btn);
+  });
+}
+
+// Actions the server allows this browser to run, from /api/capabilities. Until they are
+// loaded everything is allowed so that buttons do not flicker for open installations.
+let capabilities = null;
+
+function can(name) {
+  return !capabilities || capabilities[name] === true;
+}
+
+// Shows or hides elements marked with data-capability="name" below root
+function applyCapabilities(root) {
+  (root || document).querySelectorAll('[data-capability]').forEach(el => {
+    el.style.display = can(el.dataset.capability) ? '' : 'none';
+  });
+}
+
+async function loadCapabilities() {
+  try {
+    const res = await window.bootstrapFetch(withProfile('/api/capabilities'));
+    const data = await res.json();
+    capabilities = data.capabilities || {};
+  } catch (err) {
+    if (window.debugError) window.debugError('config', 'Error loading capabilities:', err);
+    return;
+  }
+  applyCapabilities();
+  document.dispatchEvent(new CustomEvent('capabilitieschange'));
+}
+
 // Initialize config management
 (function() {
   // Wait for DOM to be ready
@@ -218,9 +341,67 @@ async function loadServerConfigs() {
       });
     }
 
+    // Access token sign in / sign out (the server keeps the token in an HttpOnly cookie)
+    const authTokenInput = document.getElementById('authTokenInput');
+    const authLoginBtn = document.getElementById('authLoginBtn');
+    const authLogoutBtn = document.getElementById('authLogoutBtn');
+    if (authTokenInput && authLoginBtn) {
+      authLoginBtn.addEventListener('click', async () => {
+        const token = authTokenInput.value.trim();
+        if (!token) {
+          await window.popup.alert('Please enter an access token', 'Input Required');
+          return;
+        }
+        try {
+          const res = await fetch('/api/auth/login', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ token })
+          });
+          const result = await res.json();
+          if (result.error) {
+            await window.popup.alert('Error: ' + result.error, 'Error');
+            return;
+          }
+          authTokenInput.value = '';
+          loadAuthStatus();
+          await loadCapabilities();
+          loadServerConfigs();
+        } catch (err) {
+          await window.popup.alert('Error signing in: ' + err.message, 'Error');
+        }
+      });
+    }
+    const signOutOthersBtn = document.getElementById('authSignOutOthersBtn');
+    if (signOutOthersBtn) {
+      signOutOthersBtn.addEventListener('click', async () => {
+        if (await window.popup.confirm('Sign out all other devices?', 'Sign Out')) {
+          revokeSessions('others=1');
+        }
+      });
+    }
+    if (authLogoutBtn) {
+      authLogoutBtn.addEventListener('click', async () => {
+        try {
+          await fetch('/api/auth/login', { method: 'DELETE' });
+        } catch (err) {
+          if (window.debugError) window.debugError('config', 'Error signing out:', err);
+        }
+        loadAuthStatus();
+        await loadCapabilities();
+        loadServerConfigs();
+      });
+    }
+    loadAuthStatus();
+    loadCapabilities();
+
     // Server configs are now loaded when preferences modal opens (handled in preferences.js)
   }
 
   // Export to window
   window.loadServerConfigs = loadServerConfigs;
+  window.loadAuthStatus = loadAuthStatus;
+  window.loadCapabilities = loadCapabilities;
+  window.applyCapabilities = applyCapabilities;
+  window.can = can;
 })();
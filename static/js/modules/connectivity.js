@@ -0,0 +1,61 @@
+// Offline state: while the server has no internet connection, external modules (weather,
+// GitHub, RSS, public IP) get their last data marked "offline" and show a badge with its age.
+
+let connectivityOnline = true;
+
+function offlineAge(cachedAt) {
+  const minutes = Math.floor((Date.now() - new Date(cachedAt).getTime()) / 60000);
+  if (minutes < 1) return 'just now';
+  if (minutes < 60) return minutes + ' min ago';
+  const hours = Math.floor(minutes / 60);
+  if (hours < 48) return hours + ' h ago';
+  return Math.floor(hours / 24) + ' days ago';
+}
+
+// offlineMessage describes the data of an offline response.
+function offlineMessage(data) {
+  if (!data || !data.offline) return '';
+  if (data.cachedAt) {
+    return 'Offline, showing cached data from ' + new Date(data.cachedAt).toLocaleString() + ' (' + offlineAge(data.cachedAt) + ')';
+  }
+  return 'Offline, no data fetched yet';
+}
+
+// applyOffline shows or removes the offline badge of the card with the data-module name.
+function applyOffline(cardModule, data) {
+  const card = document.querySelector(`.card[data-module="${cardModule}"]`);
+  const icons = card && card.querySelector('h3 .header-icons');
+  if (!icons) return;
+  let badge = icons.querySelector('.module-offline');
+  if (!data || !data.offline) {
+    if (badge) badge.remove();
+    return;
+  }
+  if (!badge) {
+    badge = document.createElement('span');
+    badge.className = 'module-offline small';
+    icons.insertBefore(badge, icons.firstChild);
+  }
+  badge.innerHTML = '<i class="fas fa-plug"></i> ' + (data.cachedAt ? offlineAge(data.cachedAt) : 'offline');
+  badge.title = offlineMessage(data);
+}
+
+function refreshExternalModules() {
+  if (window.refreshWeather) window.refreshWeather();
+  if (window.refreshGitHub) window.refreshGitHub(true);
+  if (window.refreshRss) window.refreshRss();
+  if (window.refreshIP) window.refreshIP();
+}
+
+window.onConnectivity = function(data) {
+  const status = data.connectivity;
+  if (!status) return;
+  const wasOnline = connectivityOnline;
+  connectivityOnline = status.online;
+  if (window.debugLog) window.debugLog('connectivity', 'Internet connection', status.online ? 'restored' : 'lost');
+  // Replace the cached data as soon as the connection is back
+  if (status.online && !wasOnline) refreshExternalModules();
+};
+
+window.offlineMessage = offlineMessage;
+window.applyOffline = applyOffline;
@@ -0,0 +1,154 @@
+// Countdown: days and time left until dates or calendar events. The moments are worked out by
+// /api/clocks in the zone of each countdown; the card only counts down to them.
+
+let countdownItems = [];   // Stored countdowns ("clockCountdowns")
+let countdownStatus = [];  // Their targets from the server
+let countdownTick = null;
+
+function loadCountdowns() {
+  const saved = window.loadFromStorage('clockCountdowns');
+  countdownItems = Array.isArray(saved) ? saved : [];
+}
+
+function saveCountdowns() {
+  window.saveToStorage('clockCountdowns', countdownItems);
+  refreshCountdown();
+}
+
+function countdownLeft(status) {
+  if (status.error) return status.error;
+  let secs = Math.round((Date.parse(status.target) - Date.now()) / 1000);
+  if (secs <= 0) return status.yearly ? 'Today' : 'Passed';
+  if (status.days > 1) return status.days + ' days';
+  const h = Math.floor(secs / 3600);
+  secs -= h * 3600;
+  const m = Math.floor(secs / 60);
+  return `${h}:${String(m).padStart(2, '0')}:${String(secs - m * 60).padStart(2, '0')}`;
+}
+
+function renderCountdown() {
+  const container = document.getElementById('countdownContainer');
+  if (!container) return;
+  if (!countdownStatus.length) {
+    container.innerHTML = '<div class="small" style="color:var(--muted);">No countdowns yet. Add one with +.</div>';
+    return;
+  }
+  container.innerHTML = countdownStatus.map((s, i) => {
+    const when = s.error ? '' : new Date(s.target).toLocaleString([], {dateStyle: 'medium', timeStyle: s.time || s.event ? 'short' : undefined});
+    const sub = [when, s.timeZone, s.eventTitle && s.eventTitle !== s.title ? s.eventTitle : ''].filter(Boolean).map(window.escapeHtml).join(' · ');
+    return `<div class="countdown-row${s.passed && !s.error ? ' countdown-passed' : ''}" data-countdown-index="${i}" title="Double-click to edit">
+      <div class="countdown-info"><div>${window.escapeHtml(s.title || 'Countdown')}</div><div class="small" style="color:var(--muted);">${sub}</div></div>
+      <div class="countdown-left mono" data-countdown-left="${i}">${window.escapeHtml(countdownLeft(s))}</div>
+    </div>`;
+  }).join('');
+}
+
+// Only the time left changes every second
+function tickCountdown() {
+  countdownStatus.forEach((s, i) => {
+    const el = document.querySelector(`[data-countdown-left="${i}"]`);
+    if (el) el.textContent = countdownLeft(s);
+  });
+}
+
+async function refreshCountdown() {
+  if (!document.getElementById('countdownContainer')) return;
+  try {
+    const tz = encodeURIComponent(Intl.DateTimeFormat().resolvedOptions().timeZone || '');
+    const body = {countdowns: countdownItems};
+    // Events of this browser count even before they are synced
+    const events = window.loadFromStorage('calendarEvents');
+    if (Array.isArray(events)) body.events = events;
+    const res = await fetch('/api/clocks?tz=' + tz, {method: 'POST', body: JSON.stringify(body)});
+    const data = await res.json();
+    if (data.error) {
+      if (window.debugError) window.debugError('countdown', 'Error loading countdowns:', data.error);
+      return;
+    }
+    countdownStatus = data.countdowns || [];
+    renderCountdown();
+  } catch (err) {
+    if (window.debugError) window.debugError('countdown', 'Error loading countdowns:', err);
+  }
+}
+
+function showCountdownDialog(index) {
+  const isNew = index < 0;
+  const c = isNew ? {} : countdownItems[index];
+  window.showModuleEditDialog({
+    title: isNew ? 'Add countdown' : 'Edit countdown',
+    icon: 'fas fa-hourglass-half',
+    fields: [
+      {id: 'title', label: 'Title', type: 'text', placeholder: 'e.g. Holidays'},
+      {id: 'when', label: 'Date and time', type: 'datetime-local'},
+      {id: 'timeZone', label: 'Time zone (optional)', type: 'text', placeholder: 'Blank = this device\'s, e.g. America/New_York'},
+      {id: 'event', label: 'Calendar event (instead of a date)', type: 'text', placeholder: 'Text in the title of the next event'},
+      {id: 'yearly', label: 'Every year (birthdays, anniversaries)', type: 'checkbox'}
+    ],
+    values: {
+      title: c.title || '',
+      when: c.date ? c.date + 'T' + (c.time || '00:00') : '',
+      timeZone: c.timeZone || '',
+      event: c.event || '',
+      yearly: !!c.yearly
+    },
+    onDialogCreated: dialog => {
+      const tz = dialog.querySelector('#module-edit-timeZone');
+      if (tz) tz.setAttribute('list', 'worldclock-tz-suggestions');
+      if (!isNew) {
+        const actions = dialog.querySelector('#module-save').parentElement;
+        const del = document.createElement('button');
+        del.className = 'btn-small';
+        del.style.marginRight = 'auto';
+        del.innerHTML = '<i class="fas fa-trash"></i> Delete';
+        del.addEventListener('click', async () => {
+          if (!await window.popup.confirm(`Delete countdown "${c.title || c.event}"?`, 'Confirm Delete')) return;
+          countdownItems.splice(index, 1);
+          dialog.remove();
+          saveCountdowns();
+        });
+        actions.insertBefore(del, actions.firstChild);
+      }
+    },
+    onSave: async formData => {
+      if (!formData.when && !formData.event) {
+        await window.popup.alert('Enter a date or a calendar event.', 'Countdown');
+        return;
+      }
+      const [date, time] = formData.when.split('T');
+      const entry = {
+        id: c.id || 'cd_' + Date.now().toString(36),
+        title: formData.title,
+        date: formData.event ? '' : date,
+        time: formData.event || time === '00:00' ? '' : time,
+        timeZone: formData.timeZone,
+        event: formData.event,
+        yearly: formData.yearly && !formData.event
+      };
+      if (isNew) countdownItems.push(entry);
+      else countdownItems[index] = entry;
+      saveCountdowns();
+    }
+  });
+}
+
+function initCountdown() {
+  loadCountdowns();
+  const addBtn = document.getElementById('countdownCardAddBtn');
+  if (addBtn) addBtn.addEventListener('click', () => showCountdownDialog(-1));
+  const container = document.getElementById('countdownContainer');
+  if (container) {
+    container.addEventListener('dblclick', e => {
+      const row = e.target.closest('[data-countdown-index]');
+      if (row) showCountdownDialog(Number(row.dataset.countdownIndex));
+    });
+  }
+  setTimeout(refreshCountdown, 1000);
+  if (countdownTick) clearInterval(countdownTick);
+  countdownTick = setInterval(tickCountdown, 1000);
+  // Days left change at midnight, calendar events move
+  setInterval(refreshCountdown, 600000);
+}
+
+window.refreshCountdown = refreshCountdown;
+window.initCountdown = initCountdown;
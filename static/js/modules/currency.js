@@ -0,0 +1,97 @@
+// Currency: exchange rates of the base currency (via /api/currency) and a converter.
+
+let currencyRates = null; // Last answer of /api/currency
+
+function currencyFormat(value, code) {
+  try {
+    return value.toLocaleString([], {style: 'currency', currency: code, maximumFractionDigits: value < 1 ? 4 : 2});
+  } catch (e) {
+    return value.toFixed(4) + ' ' + code;
+  }
+}
+
+function currencyFillSelects() {
+  const from = document.getElementById('currencyFrom');
+  const to = document.getElementById('currencyTo');
+  if (!from || !to || !currencyRates) return;
+  const codes = [currencyRates.base, ...Object.keys(currencyRates.rates).sort()];
+  const options = codes.map(c => `<option value="${window.escapeHtml(c)}">${window.escapeHtml(c)}</option>`).join('');
+  const prevFrom = from.value;
+  const prevTo = to.value;
+  from.innerHTML = options;
+  to.innerHTML = options;
+  from.value = codes.includes(prevFrom) ? prevFrom : codes[0];
+  to.value = codes.includes(prevTo) ? prevTo : (codes[1] || codes[0]);
+}
+
+async function convertCurrency() {
+  const result = document.getElementById('currencyResult');
+  const amount = document.getElementById('currencyAmount');
+  const from = document.getElementById('currencyFrom');
+  const to = document.getElementById('currencyTo');
+  if (!result || !amount || !from.value || !to.value) return;
+  if (amount.value === '') {
+    result.textContent = '';
+    return;
+  }
+  try {
+    const params = new URLSearchParams({amount: amount.value, from: from.value, to: to.value});
+    const res = await fetch('/api/currency/convert?' + params);
+    const data = await res.json();
+    if (data.error) {
+      result.innerHTML = '<span class="small" style="color:var(--bad, #ef4444);">' + window.escapeHtml(data.error) + '</span>';
+      return;
+    }
+    result.textContent = currencyFormat(data.result, data.to);
+    result.title = `1 ${data.from} = ${data.rate} ${data.to}` + (data.date ? ` (${data.date})` : '');
+  } catch (err) {
+    if (window.debugError) window.debugError('currency', 'Error converting:', err);
+  }
+}
+
+async function refreshCurrency() {
+  const container = document.getElementById('currencyContainer');
+  if (!container) return;
+  window.startTimer('currency');
+
+  try {
+    const res = await fetch('/api/currency');
+    const data = await res.json();
+    if (!data.rates) {
+      container.innerHTML = '<div class="small" style="color:var(--bad, #ef4444);">' + window.escapeHtml(data.error || 'No exchange rates') + '</div>';
+      return;
+    }
+    currencyRates = data;
+    let html = '';
+    if (data.error) {
+      html += '<div class="small" style="color:var(--bad, #ef4444);">' + window.escapeHtml(data.error) + '</div>';
+    }
+    for (const code of Object.keys(data.rates).sort()) {
+      html += `<div class="kv" title="1 ${window.escapeHtml(code)} = ${(1 / data.rates[code]).toFixed(4)} ${window.escapeHtml(data.base)}"><div class="k">${window.escapeHtml(data.base)} → ${window.escapeHtml(code)}</div><div class="v">${data.rates[code].toFixed(4)}</div></div>`;
+    }
+    if (data.date) {
+      html += '<div class="small" style="color:var(--muted);">Reference rates of ' + window.escapeHtml(data.date) + '</div>';
+    }
+    container.innerHTML = html;
+    currencyFillSelects();
+    convertCurrency();
+  } catch (err) {
+    if (window.debugError) window.debugError('currency', 'Error loading exchange rates:', err);
+  }
+}
+
+function initCurrency() {
+  ['currencyAmount', 'currencyFrom', 'currencyTo'].forEach(id => {
+    const el = document.getElementById(id);
+    if (el) el.addEventListener(id === 'currencyAmount' ? 'input' : 'change', convertCurrency);
+  });
+  setTimeout(refreshCurrency, 1000);
+  setInterval(function() {
+    if (!window.wsIsConnected || !window.wsIsConnected()) {
+      refreshCurrency();
+    }
+  }, window.timers && window.timers.currency ? window.timers.currency.interval : 3600000);
+}
+
+window.refreshCurrency = refreshCurrency;
+window.initCurrency = initCurrency;
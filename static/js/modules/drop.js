@@ -0,0 +1,160 @@
+// Drop: a clipboard shared by the devices on the LAN (via /api/drop). Text and files sent
+// from one device show up on every open dashboard until they expire.
+
+function dropExpires(expires) {
+  const mins = Math.max(0, Math.round((new Date(expires) - Date.now()) / 60000));
+  if (mins < 60) return mins + 'm left';
+  if (mins < 48 * 60) return Math.round(mins / 60) + 'h left';
+  return Math.round(mins / 1440) + 'd left';
+}
+
+function dropRow(d) {
+  const meta = [d.device, d.sizeFormatted, dropExpires(d.expires)].filter(Boolean).map(window.escapeHtml).join(' · ');
+  const url = window.appUrl('/api/drop/' + encodeURIComponent(d.id));
+  let html = '<div class="drop-item">';
+  if (d.kind === 'text') {
+    html += `<div class="drop-text small" title="${window.escapeHtml(d.text)}">${window.escapeHtml(d.text)}</div>`;
+  } else {
+    html += `<div class="small"><i class="fas fa-file" style="width:1.2em;"></i> <a href="${url}" download>${window.escapeHtml(d.name)}</a></div>`;
+  }
+  html += `<div class="drop-actions"><span class="small" style="color:var(--muted);">${meta}</span>`;
+  if (d.kind === 'text') html += `<button type="button" class="btn-icon" data-drop-copy="${window.escapeHtml(d.id)}" title="Copy"><i class="fas fa-copy"></i></button>`;
+  html += `<button type="button" class="btn-icon" data-drop-delete="${window.escapeHtml(d.id)}" title="Delete"><i class="fas fa-trash"></i></button></div>`;
+  return html + '</div>';
+}
+
+let dropItems = [];
+
+async function refreshDrop() {
+  const container = document.getElementById('dropContainer');
+  if (!container) return;
+
+  try {
+    const res = await fetch('/api/drop', {cache: 'no-store'});
+    const data = await res.json();
+    if (data.enabled === false) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">The drop is disabled in the config file.</div>';
+      return;
+    }
+    if (data.error) {
+      container.innerHTML = `<div class="small" style="color:var(--muted);">${window.escapeHtml(data.error)}</div>`;
+      return;
+    }
+    dropItems = data.drops || [];
+    const list = document.getElementById('dropList');
+    if (list) {
+      list.innerHTML = dropItems.length ? dropItems.map(dropRow).join('') : '<div class="small" style="color:var(--muted);">Nothing dropped yet. Paste text or drop a file here.</div>';
+    }
+    const limits = document.getElementById('dropLimits');
+    if (limits && data.status) {
+      limits.textContent = `Files up to ${Math.round(data.status.maxSize / 1048576)} MB, kept ${Math.round(data.status.ttl / 3600)}h`;
+    }
+  } catch (err) {
+    if (window.debugError) window.debugError('drop', 'Error loading drops:', err);
+  }
+}
+
+async function sendDrop(body) {
+  try {
+    const res = await fetch('/api/drop', {method: 'POST', body: body});
+    const data = await res.json();
+    if (data.error) {
+      await window.popup.alert(data.error, 'Drop');
+      return false;
+    }
+    refreshDrop();
+    return true;
+  } catch (err) {
+    if (window.debugError) window.debugError('drop', 'Error sending drop:', err);
+    return false;
+  }
+}
+
+function sendDropFiles(files) {
+  Array.from(files || []).forEach(file => {
+    const form = new FormData();
+    form.append('file', file, file.name);
+    sendDrop(form);
+  });
+}
+
+function initDrop() {
+  const container = document.getElementById('dropContainer');
+  if (!container) return;
+  container.innerHTML = `
+    <div style="display:flex;gap:6px;">
+      <textarea id="dropTextInput" rows="2" placeholder="Text to share with your other devices" spellcheck="false" style="flex:1;box-sizing:border-box;resize:vertical;"></textarea>
+      <div style="display:flex;flex-direction:column;gap:4px;">
+        <button type="button" class="btn-small" id="dropSendBtn" title="Share the text"><i class="fas fa-paper-plane"></i></button>
+        <button type="button" class="btn-small" id="dropFileBtn" title="Share a file"><i class="fas fa-paperclip"></i></button>
+      </div>
+      <input type="file" id="dropFileInput" multiple style="display:none;">
+    </div>
+    <div class="small" id="dropLimits" style="color:var(--muted);margin:2px 0 6px;"></div>
+    <div id="dropList"></div>`;
+
+  const input = document.getElementById('dropTextInput');
+  const fileInput = document.getElementById('dropFileInput');
+  document.getElementById('dropSendBtn').addEventListener('click', async () => {
+    if (!input.value.trim()) return;
+    if (await sendDrop(JSON.stringify({text: input.value}))) input.value = '';
+  });
+  input.addEventListener('keydown', async e => {
+    if (e.key === 'Enter' && (e.ctrlKey || e.metaKey) && input.value.trim()) {
+      e.preventDefault();
+      if (await sendDrop(JSON.stringify({text: input.value}))) input.value = '';
+    }
+  });
+  document.getElementById('dropFileBtn').addEventListener('click', () => fileInput.click());
+  fileInput.addEventListener('change', () => {
+    sendDropFiles(fileInput.files);
+    fileInput.value = '';
+  });
+
+  // Files dragged onto the card are shared; the card's own layout drag is left alone
+  container.addEventListener('dragover', e => {
+    if (e.dataTransfer && Array.from(e.dataTransfer.types || []).includes('Files')) {
+      e.preventDefault();
+      e.stopPropagation();
+      container.classList.add('drop-target');
+    }
+  });
+  container.addEventListener('dragleave', () => container.classList.remove('drop-target'));
+  container.addEventListener('drop', e => {
+    if (!e.dataTransfer || !e.dataTransfer.files.length) return;
+    e.preventDefault();
+    e.stopPropagation();
+    container.classList.remove('drop-target');
+    sendDropFiles(e.dataTransfer.files);
+  });
+
+  container.addEventListener('click', async e => {
+    const copy = e.target.closest('[data-drop-copy]');
+    if (copy) {
+      const drop = dropItems.find(d => d.id === copy.dataset.dropCopy);
+      if (drop && navigator.clipboard) {
+        try {
+          await navigator.clipboard.writeText(drop.text);
+          copy.innerHTML = '<i class="fas fa-check"></i>';
+          setTimeout(() => { copy.innerHTML = '<i class="fas fa-copy"></i>'; }, 1500);
+        } catch (err) {
+          if (window.debugError) window.debugError('drop', 'Clipboard write failed:', err);
+        }
+      }
+      return;
+    }
+    const del = e.target.closest('[data-drop-delete]');
+    if (del) {
+      const data = await (await fetch('/api/drop/delete', {method: 'POST', body: JSON.stringify({id: del.dataset.dropDelete})})).json();
+      if (data.error) await window.popup.alert(data.error, 'Drop');
+      refreshDrop();
+    }
+  });
+
+  setTimeout(refreshDrop, 1000);
+  // Expiry countdowns and drops that expired
+  setInterval(refreshDrop, 60000);
+}
+
+window.refreshDrop = refreshDrop;
+window.initDrop = initDrop;
@@ -0,0 +1,40 @@
+// Embed: framed pages and HTML or Markdown snippets from the config, sanitized by the server
+// (via /api/embed), for services without an API.
+
+function embedWidget(w) {
+  let html = '<div class="embed-widget">';
+  if (w.title) html += `<div class="small embed-title">${window.escapeHtml(w.title)}</div>`;
+  if (w.type === 'frame') {
+    html += `<iframe class="embed-frame" src="${window.escapeHtml(w.url)}" style="height:${parseInt(w.height, 10) || 300}px;" sandbox="${window.escapeHtml(w.sandbox || '')}" referrerpolicy="no-referrer" loading="lazy" title="${window.escapeHtml(w.title || w.url)}"></iframe>`;
+  } else {
+    // Already sanitized on the server
+    html += `<div class="embed-html small">${w.html || ''}</div>`;
+  }
+  return html + '</div>';
+}
+
+async function refreshEmbed() {
+  const container = document.getElementById('embedContainer');
+  if (!container) return;
+
+  try {
+    const res = await fetch('/api/embed');
+    const data = await res.json();
+    if (!data.enabled) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">Configure pages and snippets under "embed" in the config file.</div>';
+      return;
+    }
+    container.innerHTML = data.widgets.map(embedWidget).join('');
+  } catch (err) {
+    if (window.debugError) window.debugError('embed', 'Error loading embeds:', err);
+  }
+}
+
+function initEmbed() {
+  const reloadBtn = document.getElementById('embedReloadBtn');
+  if (reloadBtn) reloadBtn.addEventListener('click', refreshEmbed);
+  setTimeout(refreshEmbed, 1000);
+}
+
+window.refreshEmbed = refreshEmbed;
+window.initEmbed = initEmbed;
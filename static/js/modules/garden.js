@@ -0,0 +1,78 @@
+// Garden: whether to water, from recent rain and the forecast at the weather location (via /api/garden).
+
+const GARDEN_ADVICE = {
+  water: {icon: 'fa-tint', color: 'var(--warn)', label: 'Water the garden'},
+  wait: {icon: 'fa-cloud-rain', color: 'var(--accent)', label: 'Wait for the rain'},
+  skip: {icon: 'fa-check-circle', color: 'var(--good)', label: 'No need to water'}
+};
+
+function gardenDayName(date) {
+  const d = new Date(date + 'T12:00:00');
+  const today = new Date();
+  if (d.toDateString() === today.toDateString()) return 'Today';
+  return d.toLocaleDateString([], {weekday: 'short'});
+}
+
+function gardenAmount(value, unit) {
+  return value.toFixed(unit === 'in' ? 2 : 1) + ' ' + unit;
+}
+
+async function refreshGarden() {
+  const container = document.getElementById('gardenContainer');
+  if (!container) return;
+  window.startTimer('garden');
+
+  let url = '/api/garden';
+  try {
+    const savedLoc = window.loadFromStorage('weatherLocation');
+    if (savedLoc) {
+      const loc = typeof savedLoc === 'string' ? JSON.parse(savedLoc) : savedLoc;
+      url += '?lat=' + loc.latitude + '&lon=' + loc.longitude;
+    }
+  } catch (e) {}
+
+  try {
+    const res = await fetch(url, {cache: 'no-store'});
+    const data = await res.json();
+    if (window.applyOffline) window.applyOffline('garden', data);
+    if (data.location === false) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">Set a weather location to get watering advice.</div>';
+      return;
+    }
+    if (data.error) {
+      container.innerHTML = `<div class="small" style="color:var(--muted);">${window.escapeHtml(data.error)}</div>`;
+      return;
+    }
+
+    const advice = GARDEN_ADVICE[data.advice] || GARDEN_ADVICE.water;
+    let html = `<div class="kv"><div class="k"><i class="fas ${advice.icon}" style="color:${advice.color};width:1.2em;"></i> <b>${advice.label}</b></div></div>`;
+    html += `<div class="small" style="color:var(--muted);margin-bottom:6px;">${window.escapeHtml(data.reason)}</div>`;
+    html += `<div class="kv"><div class="k">Rain, last ${data.pastDays} days</div><div class="v small">${gardenAmount(data.pastRain, data.unit)}</div></div>`;
+    html += `<div class="kv"><div class="k">Expected, next ${data.aheadDays} days</div><div class="v small">${gardenAmount(data.expectedRain, data.unit)}</div></div>`;
+    html += `<div class="kv"><div class="k">Needed${data.hot ? ' <i class="fas fa-temperature-high" title="Hot days ahead" style="color:var(--warn);"></i>' : ''}</div><div class="v small">${gardenAmount(data.needed, data.unit)}</div></div>`;
+
+    const bars = data.days.map(day => {
+      const title = gardenDayName(day.date) + ' ' + day.date + ': ' + gardenAmount(day.rain, data.unit) +
+        (day.past ? '' : ', ' + day.probability.toFixed(0) + '% chance') + ', high ' + day.tempMax.toFixed(0) + data.tempUnit;
+      const height = Math.max(Math.round(Math.min(day.rain / (data.needed || 1), 1) * 24), day.rain > 0 ? 2 : 1);
+      const color = day.past ? 'var(--accent)' : (day.counted ? 'var(--good)' : 'var(--muted)');
+      return `<span title="${window.escapeHtml(title)}" style="display:inline-block;width:10px;height:${height}px;margin-right:2px;background:${color};opacity:${day.rain > 0 ? 1 : 0.4};"></span>`;
+    }).join('');
+    html += `<div style="display:flex;align-items:flex-end;height:26px;margin-top:6px;" title="Daily rain, past and forecast">${bars}</div>`;
+    container.innerHTML = html;
+  } catch (err) {
+    if (window.debugError) window.debugError('garden', 'Error loading garden advice:', err);
+  }
+}
+
+function initGarden() {
+  setTimeout(refreshGarden, 1000);
+  setInterval(function() {
+    if (!window.wsIsConnected || !window.wsIsConnected()) {
+      refreshGarden();
+    }
+  }, window.timers && window.timers.garden ? window.timers.garden.interval : 1800000);
+}
+
+window.refreshGarden = refreshGarden;
+window.initGarden = initGarden;
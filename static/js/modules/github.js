@@ -114,7 +114,14 @@ function renderGitHubContent(moduleId, displayType, data, maxItems, accountType)
   const limit = maxItems || 5;
 
   if (!container) return;
+  if (window.applyOffline) window.applyOffline(moduleId, data);
 
+  if (data.error && data.offline) {
+    container.innerHTML = '<div class="small" style="color: var(--muted);">No internet connection, nothing fetched yet</div>';
+    if (errEl) errEl.textContent = "";
+    if (countEl) countEl.textContent = "Offline";
+    return;
+  }
   if (data.error) {
     container.innerHTML = `<div class="small" style="color: red;">Error: ${data.error}</div>`;
     if (errEl) errEl.textContent = data.error;
@@ -396,11 +403,11 @@ async function refreshGitHubModule(mod, forceRefresh = false) {
     const order = mod.order || 'desc';
     let url = "/api/github/" + displayType + "?name=" + encodeURIComponent(mod.name) + "&type=" + accountType + "&count=" + maxItems + "&sort=" + sort + "&order=" + order;
     if (githubToken) url += "&token=" + encodeURIComponent(githubToken);
-    const res = await fetch(url, {cache:"no-store"});
+    const res = await window.bootstrapFetch(url, {cache:"no-store"});
     const data = await res.json();
 
-    // Store in cache
-    setCachedGitHubData(mod.id, displayType, data);
+    // Store in cache, but not the stale data served while offline
+    if (!data.offline) setCachedGitHubData(mod.id, displayType, data);
     renderGitHubContent(mod.id, displayType, data, maxItems, accountType);
   } catch(err) {
     if (window.debugError) window.debugError('github', "Error refreshing GitHub module " + mod.id + ":", err);
@@ -415,9 +422,12 @@ async function refreshGitHubModule(mod, forceRefresh = false) {
   }
 }
 
-async function refreshGitHub(forceRefresh = false) {
+// Refreshes the GitHub modules; scheduled refreshes skip modules with an interval of their own
+async function refreshGitHub(forceRefresh = false, scheduled = false) {
   try {
-    const promises = githubModules.filter(m => m.enabled).map(mod => refreshGitHubModule(mod, forceRefresh));
+    const promises = githubModules
+      .filter(m => m.enabled && !(scheduled && window.moduleInstanceInterval(m.id)))
+      .map(mod => refreshGitHubModule(mod, forceRefresh));
     await Promise.all(promises);
     window.startTimer("github");
   } catch(err) {
@@ -425,12 +435,20 @@ async function refreshGitHub(forceRefresh = false) {
   }
 }
 
+function refreshGitHubInstance(id) {
+  const mod = githubModules.find(m => m.id === id);
+  if (mod && mod.enabled) refreshGitHubModule(mod, true);
+}
+
 function renderGitHubModules() {
   const container = document.getElementById('githubModulesContainer');
   if (!container) return;
   container.innerHTML = '';
 
-  githubModules.forEach((mod, index) => {
+  // The shared timer goes on the first module refreshed with the others
+  const sharedTimerModule = githubModules.find(m => !window.moduleInstanceInterval(m.id));
+
+  githubModules.forEach((mod) => {
     const displayType = mod.displayType || 'repos';
     const typeInfo = githubDisplayTypes[displayType] || githubDisplayTypes.repos;
 
@@ -439,8 +457,8 @@ function renderGitHubModules() {
     card.setAttribute('data-module', mod.id);
     card.setAttribute('draggable', 'true');
 
-    const hasTimer = index === 0;
-    const timerHtml = hasTimer ? '<div class="timer-circle" id="githubTimer" title="Double-click to refresh"></div>' : '';
+    const hasTimer = mod === sharedTimerModule;
+    const timerHtml = hasTimer ? '<div class="timer-circle" id="githubTimer" title="Double-click to refresh"></div>' : window.moduleInstanceTimerHtml(mod.id);
     const titleSuffix = displayType !== 'repos' ? ' - ' + typeInfo.name : '';
 
     card.innerHTML = `
@@ -452,6 +470,7 @@ function renderGitHubModules() {
     `;
 
     container.appendChild(card);
+    window.bindModuleInstanceTimer(card);
 
     // Load cached data on initial render
     const cachedData = getCachedGitHubData(mod.id, displayType);
@@ -525,6 +544,7 @@ function renderGitHubModuleList() {
       if (confirmed) {
         const removedId = githubModules[index].id;
         githubModules.splice(index, 1);
+        window.setModuleInstanceInterval(removedId, 0);
         saveGitHubModules();
         if (window.layoutSystem && window.layoutSystem.removeModuleFromLayout) {
           if (window.layoutSystem.removeModuleFromLayout(removedId)) {
@@ -546,7 +566,7 @@ function renderGitHubModuleList() {
 }
 
 function showGitHubEditDialog(index) {
-  const mod = index >= 0 ? githubModules[index] : { id: 'github-' + Date.now(), type: 'user', name: '', url: '', enabled: true, maxItems: 5 };
+  const mod = index >= 0 ? githubModules[index] : { id: '', type: 'user', name: '', url: '', enabled: true, maxItems: 5 };
   const isNew = index < 0;
 
   // Ensure default for existing modules
@@ -633,6 +653,15 @@ function showGitHubEditDialog(index) {
       type: 'select',
       options: githubOrderOptions,
       required: false
+    },
+    {
+      id: 'interval',
+      label: 'Refresh (s)',
+      type: 'number',
+      min: 60,
+      max: 86400,
+      placeholder: 'Shared',
+      required: false
     }
   ];
 
@@ -646,7 +675,8 @@ function showGitHubEditDialog(index) {
       displayType: mod.displayType || 'repos',
       maxItems: mod.maxItems || 5,
       sort: mod.sort || 'created',
-      order: mod.order || 'desc'
+      order: mod.order || 'desc',
+      interval: (mod.id && window.moduleInstanceInterval(mod.id)) || ''
     },
     onSave: async (formData) => {
       const url = formData.url.trim();
@@ -666,9 +696,12 @@ function showGitHubEditDialog(index) {
       const name = accountType === 'repo' ? parts.join('/') : parts[0];
       const maxItems = Math.max(1, Math.min(20, parseInt(formData.maxItems) || 5));
 
+      const interval = parseInt(formData.interval) > 0 ? Math.max(60, Math.min(86400, parseInt(formData.interval))) : 0;
+      let id = mod.id;
       if (isNew) {
+        id = window.newModuleInstanceId('github', (name + '-' + formData.displayType).toLowerCase(), githubModules);
         githubModules.push({
-          id: 'github-' + Date.now(),
+          id: id,
           accountType: accountType,
           displayType: formData.displayType,
           name: name,
@@ -688,6 +721,7 @@ function showGitHubEditDialog(index) {
         githubModules[index].order = formData.order || 'desc';
       }
 
+      window.setModuleInstanceInterval(id, interval);
       saveGitHubModules();
       renderGitHubModuleList();
       renderGitHubModules();
@@ -707,6 +741,7 @@ function initGitHub() {
 
 // Export to window
 window.githubModules = githubModules;
+window.refreshGitHubInstance = refreshGitHubInstance;
 window.githubDisplayTypes = githubDisplayTypes;
 window.saveGitHubModules = saveGitHubModules;
 window.renderGitHubModules = renderGitHubModules;
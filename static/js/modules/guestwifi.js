@@ -0,0 +1,61 @@
+// Guest Wi-Fi: guest network credentials and QR code for visitors (via /api/guest-wifi).
+
+async function refreshGuestWifi() {
+  const container = document.getElementById('guestwifiContainer');
+  if (!container) return;
+  window.startTimer('guestwifi');
+
+  try {
+    const res = await fetch('/api/guest-wifi');
+    const data = await res.json();
+    if (data.error) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">' + window.escapeHtml(data.error) + '</div>';
+      return;
+    }
+    if (!data.configured) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">Set the guest network with POST /api/guest-wifi.</div>';
+      return;
+    }
+
+    const wifi = data.wifi;
+    let html = `
+      <div style="display:flex;gap:12px;align-items:center;">
+        <img src="${window.appUrl('/api/guest-wifi/qr.png')}?size=160&t=${encodeURIComponent(wifi.rotatedAt || '')}" alt="Guest Wi-Fi QR code" width="120" height="120" style="background:#fff;border-radius:6px;padding:4px;">
+        <div style="flex:1;min-width:0;">
+          <div class="kv"><div class="k">Network</div><div class="v">${window.escapeHtml(wifi.ssid)}</div></div>
+    `;
+    if (wifi.security !== 'nopass') {
+      html += `<div class="kv"><div class="k">Password</div><div class="v"><span id="guestwifiPassword" style="cursor:pointer;font-family:monospace;" title="Click to copy">${window.escapeHtml(wifi.password)}</span></div></div>`;
+    } else {
+      html += '<div class="kv"><div class="k">Password</div><div class="v">Open network</div></div>';
+    }
+    if (data.nextRotation) {
+      html += `<div class="small" style="color:var(--muted);">Password changes ${window.escapeHtml(new Date(data.nextRotation).toLocaleDateString())}</div>`;
+    }
+    html += '</div></div>';
+    container.innerHTML = html;
+
+    const pw = document.getElementById('guestwifiPassword');
+    if (pw) {
+      pw.addEventListener('click', async () => {
+        if (await copyToClipboard(wifi.password)) {
+          pw.title = 'Copied';
+        }
+      });
+    }
+  } catch (err) {
+    if (window.debugError) window.debugError('guestwifi', 'Error loading guest Wi-Fi:', err);
+  }
+}
+
+function initGuestWifi() {
+  setTimeout(refreshGuestWifi, 1000);
+  setInterval(function() {
+    if (!window.wsIsConnected || !window.wsIsConnected()) {
+      refreshGuestWifi();
+    }
+  }, window.timers && window.timers.guestwifi ? window.timers.guestwifi.interval : 300000);
+}
+
+window.refreshGuestWifi = refreshGuestWifi;
+window.initGuestWifi = initGuestWifi;
@@ -0,0 +1,52 @@
+// Module health: a warning icon on cards whose fetches keep failing (via /api/modules/health).
+
+function moduleHealthCards(module) {
+  return document.querySelectorAll(`.card[data-module="${module}"], .card[data-module^="${module}-"], .card[data-module^="${module}:"]`);
+}
+
+function applyModuleHealth(health) {
+  moduleHealthCards(health.module).forEach(card => {
+    const icons = card.querySelector('h3 .header-icons');
+    if (!icons) return;
+    let icon = icons.querySelector('.module-degraded');
+    if (!health.degraded) {
+      if (icon) icon.remove();
+      return;
+    }
+    if (!icon) {
+      icon = document.createElement('i');
+      icon.className = 'fas fa-exclamation-triangle module-degraded';
+      icon.style.color = 'var(--warn, #f59e0b)';
+      icons.insertBefore(icon, icons.firstChild);
+    }
+    const lines = [health.successRate.toFixed(0) + '% of recent updates succeeded'];
+    if (health.lastError) lines.push('Last error: ' + health.lastError);
+    if (health.circuitOpen && health.retryAt) lines.push('Paused, retrying at ' + new Date(health.retryAt).toLocaleTimeString());
+    icon.title = lines.join('\n');
+  });
+}
+
+async function refreshModuleHealth() {
+  try {
+    const res = await fetch('/api/modules/health');
+    const data = await res.json();
+    (data.modules || []).forEach(applyModuleHealth);
+  } catch (err) {
+    if (window.debugError) window.debugError('health', 'Error loading module health:', err);
+  }
+}
+
+function initModuleHealth() {
+  setTimeout(refreshModuleHealth, 5000);
+  setInterval(function() {
+    if (!window.wsIsConnected || !window.wsIsConnected()) {
+      refreshModuleHealth();
+    }
+  }, 60000);
+}
+
+window.onModuleHealth = function(data) {
+  if (data.health) applyModuleHealth(data.health);
+};
+window.refreshModuleHealth = refreshModuleHealth;
+window.initModuleHealth = initModuleHealth;
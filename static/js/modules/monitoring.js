@@ -119,56 +119,6 @@ function updateMonitorLayoutButtons() {
   });
 }
 
-function getFaviconCache() {
-  try {
-    const cache = window.loadFromStorage('faviconCache');
-    return cache || {};
-  } catch (e) {
-    return {};
-  }
-}
-
-function saveFaviconCache(cache) {
-  try {
-    window.saveToStorage('faviconCache', cache);
-  } catch (e) {}
-}
-
-function getCachedFavicon(url) {
-  try {
-    const cache = getFaviconCache();
-    const cacheKey = new URL(url).origin;
-    if (cache[cacheKey] && cache[cacheKey].expires > Date.now()) {
-      return cache[cacheKey].data;
-    }
-  } catch (e) {}
-  return null;
-}
-
-async function fetchAndCacheFavicon(url) {
-  try {
-    const cacheKey = new URL(url).origin;
-    const cache = getFaviconCache();
-    if (cache[cacheKey] && cache[cacheKey].expires > Date.now()) {
-      return cache[cacheKey].data;
-    }
-
-    const res = await fetch('/api/favicon?url=' + encodeURIComponent(url));
-    const data = await res.json();
-    if (data && data.favicon) {
-      cache[cacheKey] = {
-        data: data.favicon,
-        expires: Date.now() + (7 * 24 * 60 * 60 * 1000)
-      };
-      saveFaviconCache(cache);
-      return data.favicon;
-    }
-  } catch (e) {
-    if (window.debugError) window.debugError('monitoring', 'Error fetching monitor favicon:', e);
-  }
-  return null;
-}
-
 function shouldMonitoringOccupyLayout() {
   const globallyEnabled = !!(
     window.moduleConfig &&
@@ -230,14 +180,10 @@ function renderMonitors() {
     k.className = 'k';
     const isHttps = mon.type === 'http' && mon.url && mon.url.startsWith('https://');
     const sslIconHtml = isHttps ? '<span class="ssl-status" id="mon-ssl-' + index + '" style="margin-left:4px;"></span>' : '';
+    const showIcon = mon.iconSlug || (monitorShowFavicons && isHttps);
     let faviconHtml = '';
-    if (monitorShowFavicons && isHttps) {
-      const cachedFavicon = getCachedFavicon(mon.url);
-      if (cachedFavicon) {
-        faviconHtml = '<span class="monitor-favicon"><img src="' + cachedFavicon + '" width="14" height="14" alt=""></span>';
-      } else {
-        faviconHtml = '<span class="monitor-favicon" id="mon-favicon-' + index + '"><i class="fas fa-globe"></i></span>';
-      }
+    if (showIcon) {
+      faviconHtml = '<span class="monitor-favicon" id="mon-favicon-' + index + '"><i class="fas fa-globe"></i></span>';
     }
     k.innerHTML = '<span class="monitor-status" id="mon-status-' + index + '"><i class="fas fa-circle" style="color:var(--muted);"></i></span>' + sslIconHtml + faviconHtml + ' <span class="monitor-name">' + mon.name + '</span>';
 
@@ -250,16 +196,22 @@ function renderMonitors() {
     row.appendChild(v);
     container.appendChild(row);
 
-    if (monitorShowFavicons && isHttps && !getCachedFavicon(mon.url)) {
+    if (showIcon) {
       const faviconEl = document.getElementById('mon-favicon-' + index);
       if (faviconEl) {
-        fetchAndCacheFavicon(mon.url).then(favicon => {
-          if (favicon) {
-            faviconEl.innerHTML = '<img src="' + favicon + '" width="14" height="14" alt="">';
-          } else {
-            faviconEl.innerHTML = '<i class="fas fa-globe"></i>';
+        // Swap the placeholder for the dashboard icon or server-cached favicon once it has loaded
+        const faviconUrl = window.appUrl('/api/favicon/img') + '?url=' + encodeURIComponent(mon.url);
+        const img = new Image(14, 14);
+        img.alt = '';
+        img.addEventListener('load', () => {
+          faviconEl.replaceChildren(img);
+        });
+        img.addEventListener('error', () => {
+          if (mon.iconSlug && monitorShowFavicons && isHttps && img.src.indexOf('/api/favicon/img') < 0) {
+            img.src = faviconUrl;
           }
         });
+        img.src = mon.iconSlug ? window.appUrl('/api/icons/' + encodeURIComponent(mon.iconSlug)) : faviconUrl;
       }
     }
   });
@@ -276,7 +228,7 @@ async function checkMonitor(mon, index) {
   statusEl.innerHTML = '<i class="fas fa-spinner fa-spin" style="color:var(--accent);"></i>';
 
   try {
-    let url = '/api/monitor?type=' + mon.type;
+    let url = '/api/monitor?type=' + mon.type + '&name=' + encodeURIComponent(mon.name || '');
     if (mon.type === 'http') {
       url += '&url=' + encodeURIComponent(mon.url);
     } else if (mon.type === 'port') {
@@ -692,6 +644,7 @@ function initMonitoring() {
       const editIndex = form.dataset.editIndex;
       if (editIndex !== undefined) {
         mon.id = monitors[parseInt(editIndex)].id;
+        if (monitors[parseInt(editIndex)].iconSlug) mon.iconSlug = monitors[parseInt(editIndex)].iconSlug;
         monitors[parseInt(editIndex)] = mon;
       } else {
         monitors.push(mon);
@@ -742,7 +695,7 @@ function initMonitoring() {
 }
 
 function showMonitorEditDialog(index) {
-  const monitor = index >= 0 ? monitors[index] : { name: '', type: 'http', url: '', host: '', port: '' };
+  const monitor = index >= 0 ? monitors[index] : { name: '', type: 'http', url: '', host: '', port: '', iconSlug: '' };
   const isNew = index < 0;
 
   const fields = [
@@ -789,6 +742,13 @@ function showMonitorEditDialog(index) {
       min: 1,
       max: 65535,
       required: false
+    },
+    {
+      id: 'iconSlug',
+      label: 'Dashboard icon',
+      type: 'text',
+      placeholder: 'e.g., proxmox (instead of the favicon)',
+      required: false
     }
   ];
 
@@ -800,12 +760,16 @@ function showMonitorEditDialog(index) {
     onDialogCreated: (dialog) => {
       // Set initial field visibility
       updateMonitorFieldsVisibility(dialog, monitor.type || 'http');
+      const urlInput = dialog.querySelector('#module-edit-url');
+      if (urlInput) urlInput.addEventListener('change', () => identifyMonitorURL(dialog));
     },
     onSave: async (formData) => {
       const name = formData.name.trim();
       const type = formData.type;
 
       let mon = { id: 'mon-' + Date.now(), name, type };
+      const iconSlug = (formData.iconSlug || '').trim().toLowerCase();
+      if (iconSlug) mon.iconSlug = iconSlug;
 
       if (type === 'http') {
         const url = formData.url.trim();
@@ -861,6 +825,35 @@ function showMonitorEditDialog(index) {
   });
 }
 
+// Recognizes the service behind a URL to fill in an empty name and suggest its module
+async function identifyMonitorURL(dialog) {
+  const urlInput = dialog.querySelector('#module-edit-url');
+  const nameInput = dialog.querySelector('#module-edit-name');
+  const url = urlInput.value.trim();
+  let hint = dialog.querySelector('#module-edit-identify');
+  if (!hint) {
+    hint = document.createElement('div');
+    hint.id = 'module-edit-identify';
+    hint.className = 'small';
+    urlInput.parentElement.appendChild(hint);
+  }
+  hint.textContent = '';
+  if (!/^https?:\/\//.test(url)) return;
+
+  try {
+    const res = await fetch('/api/identify?url=' + encodeURIComponent(url));
+    const id = await res.json();
+    if (id.error || urlInput.value.trim() !== url) return;
+    if (id.label && nameInput && !nameInput.value.trim()) nameInput.value = id.label;
+    if (id.service) {
+      const module = id.module && window.moduleConfig && window.moduleConfig[id.module];
+      hint.textContent = `Looks like ${id.service}.` + (module ? ` The ${module.name} module integrates with it: ${id.hint}.` : '');
+    }
+  } catch (e) {
+    if (window.debugError) window.debugError('monitoring', 'Error identifying service:', e);
+  }
+}
+
 function updateMonitorFieldsVisibility(dialog, type) {
   const urlField = dialog.querySelector('#module-edit-url').parentElement;
   const hostField = dialog.querySelector('#module-edit-host').parentElement;
@@ -924,6 +917,38 @@ function renderMonitorModuleList() {
   });
 }
 
+// Creates monitors from an nmap XML scan or a CSV of hosts, after showing what will be added
+async function importMonitorsFile(file) {
+  try {
+    const body = await file.text();
+    const preview = await (await fetch(withProfile('/api/monitor/import?dryRun=1'), {method: 'POST', body})).json();
+    if (preview.error) {
+      await window.popup.alert(preview.error, 'Import Failed');
+      return;
+    }
+    if (!preview.added.length) {
+      await window.popup.alert('No new monitors found.' + (preview.skipped.length ? '\n\n' + preview.skipped.join('\n') : ''), 'Import Monitors');
+      return;
+    }
+    const names = preview.added.slice(0, 20).map(m => m.name + (m.service && !m.name.startsWith(m.service) ? ` [${m.service}]` : '')).join('\n') + (preview.added.length > 20 ? '\n…' : '');
+    if (!await window.popup.confirm(`Add ${preview.added.length} monitor(s)?\n\n${names}`, 'Import Monitors')) return;
+
+    const result = await (await fetch(withProfile('/api/monitor/import'), {method: 'POST', body})).json();
+    if (result.error) {
+      await window.popup.alert(result.error, 'Import Failed');
+      return;
+    }
+    if (window.syncFromBackend) await window.syncFromBackend('monitors');
+    monitors = window.loadFromStorage('monitors') || monitors;
+    renderMonitorModuleList();
+    renderMonitors();
+    refreshMonitoring();
+  } catch (e) {
+    if (window.debugError) window.debugError('monitoring', 'Error importing monitors:', e);
+    await window.popup.alert('Unable to import monitors', 'Error');
+  }
+}
+
 function initMonitoring() {
   monitorColumns = getSavedMonitorColumns();
   monitorShowFavicons = getSavedMonitorShowFavicons();
@@ -937,6 +962,17 @@ function initMonitoring() {
     });
   }
 
+  const importBtn = document.getElementById('importMonitorsBtn');
+  const importFile = document.getElementById('importMonitorsFile');
+  if (importBtn && importFile) {
+    importBtn.addEventListener('click', () => importFile.click());
+    importFile.addEventListener('change', async () => {
+      const file = importFile.files[0];
+      importFile.value = '';
+      if (file) await importMonitorsFile(file);
+    });
+  }
+
   const monCardAdd = document.getElementById('monCardAddBtn');
   if (monCardAdd) {
     monCardAdd.addEventListener('click', () => {
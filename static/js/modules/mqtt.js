@@ -0,0 +1,77 @@
+// MQTT: sensor values from an MQTT broker (via /api/mqtt), updated live over the WebSocket.
+
+function mqttFormatValue(sensor) {
+  const v = sensor.value;
+  if (v === null || v === undefined) return '<span class="muted">–</span>';
+  let text;
+  if (typeof v === 'number') {
+    text = Number.isInteger(v) ? String(v) : v.toFixed(Math.abs(v) < 10 ? 2 : 1);
+  } else if (typeof v === 'object') {
+    text = JSON.stringify(v);
+  } else {
+    text = String(v);
+  }
+  return window.escapeHtml(text) + (sensor.unit ? ' <span class="muted small">' + window.escapeHtml(sensor.unit) + '</span>' : '');
+}
+
+function mqttSensorRow(sensor) {
+  const updated = sensor.updated ? new Date(sensor.updated).toLocaleString() : 'No value yet';
+  return `<div class="kv" data-sensor-id="${window.escapeHtml(sensor.id)}" title="${window.escapeHtml(sensor.topic + (sensor.field ? ' → ' + sensor.field : '') + '\n' + updated)}"><div class="k">${window.escapeHtml(sensor.name)}</div><div class="v">${mqttFormatValue(sensor)}</div></div>`;
+}
+
+async function refreshMqtt() {
+  const container = document.getElementById('mqttContainer');
+  if (!container) return;
+  window.startTimer('mqtt');
+
+  try {
+    const res = await fetch('/api/mqtt');
+    const data = await res.json();
+    if (!data.enabled) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">Configure a broker under "mqtt" in the config file.</div>';
+      return;
+    }
+    let html = '';
+    if (!data.connected) {
+      html += '<div class="small" style="color:var(--bad, #ef4444);">Not connected to ' + window.escapeHtml(data.broker) + (data.error ? ': ' + window.escapeHtml(data.error) : '') + '</div>';
+    }
+    if (!data.sensors.length) {
+      html += '<div class="small" style="color:var(--muted);">No sensors configured or discovered.</div>';
+    }
+    for (const s of data.sensors) {
+      html += mqttSensorRow(s);
+    }
+    container.innerHTML = html;
+  } catch (err) {
+    if (window.debugError) window.debugError('mqtt', 'Error loading MQTT sensors:', err);
+  }
+}
+
+// onMqttUpdate replaces the rows of sensors whose topic received a message.
+function onMqttUpdate(data) {
+  const container = document.getElementById('mqttContainer');
+  if (!container || !data.sensors) return;
+  for (const s of data.sensors) {
+    const row = container.querySelector('[data-sensor-id="' + CSS.escape(s.id) + '"]');
+    if (row) {
+      row.outerHTML = mqttSensorRow(s);
+    } else {
+      // New sensor (e.g. just discovered): reload the list
+      refreshMqtt();
+      return;
+    }
+  }
+}
+
+function initMqtt() {
+  setTimeout(refreshMqtt, 1000);
+  setInterval(function() {
+    if (!window.wsIsConnected || !window.wsIsConnected()) {
+      refreshMqtt();
+    }
+  }, window.timers && window.timers.mqtt ? window.timers.mqtt.interval : 60000);
+}
+
+window.refreshMqtt = refreshMqtt;
+window.initMqtt = initMqtt;
+window.onMqttUpdate = onMqttUpdate;
@@ -95,14 +95,23 @@ function detectClientInfo() {
   return { os, browser, timezone };
 }
 
+// Formats where the public IP exits, e.g. "Berlin, Germany · AS3320 Deutsche Telekom AG"
+function formatGeoIP(geo) {
+  if (!geo) return "";
+  const place = [geo.city, geo.country || geo.countryCode].filter(Boolean).join(", ");
+  const network = [geo.asn ? "AS" + geo.asn : "", geo.isp].filter(Boolean).join(" ");
+  return [place, network].filter(Boolean).join(" · ");
+}
+
 async function refreshIP() {
   try {
-    const summaryRes = await fetch("/api/summary", {cache:"no-store"});
+    const summaryRes = await window.bootstrapFetch("/api/summary", {cache:"no-store"});
     const summary = await summaryRes.json();
     const isLocal = summary.client && summary.client.isLocal;
 
-    const res = await fetch("/api/ip", {cache:"no-store"});
+    const res = await window.bootstrapFetch("/api/ip", {cache:"no-store"});
     const j = await res.json();
+    if (window.applyOffline) window.applyOffline('network', j);
 
     // Update label based on whether it's local or remote
     const lanIpLabel = document.getElementById("lanIpLabel");
@@ -153,11 +162,15 @@ async function refreshIP() {
           pubPtrEl.textContent = "";
         }
       }
+      const pubGeoEl = document.getElementById("pubGeo");
+      if (pubGeoEl) pubGeoEl.textContent = formatGeoIP(j.public.geo);
       document.getElementById("pubIpErr").textContent = "";
     } else {
       if (pubIpEl) pubIpEl.textContent = "—";
       if (pubPtrEl) pubPtrEl.textContent = "";
-      document.getElementById("pubIpErr").textContent = (j.public && j.public.error) || "";
+      const pubGeoEl = document.getElementById("pubGeo");
+      if (pubGeoEl) pubGeoEl.textContent = "";
+      document.getElementById("pubIpErr").textContent = j.offline ? "No internet connection" : (j.public && j.public.error) || "";
     }
 
     window.startTimer("ip");
@@ -166,6 +179,12 @@ async function refreshIP() {
   }
 }
 
+// Called by the WebSocket when the server sees a new public IP address
+function onPublicIPChange(data) {
+  if (window.debugLog) window.debugLog('network', 'Public IP changed:', data.previous, '->', data.ip);
+  refreshIP();
+}
+
 // Track offline state (managed by WebSocket)
 let isOffline = false;
 
@@ -262,7 +281,7 @@ async function refresh() {
     let res;
     if (window.fetchWithTimeout) {
       if (window.debugLog) window.debugLog('network', 'Using fetchWithTimeout');
-      res = await window.fetchWithTimeout("/api/summary", {cache:"no-store"}, 10000); // Increased timeout to 10s
+      res = await window.bootstrapFetch("/api/summary", {cache:"no-store"}, (url, opts) => window.fetchWithTimeout(url, opts, 10000)); // Increased timeout to 10s
     } else {
       if (window.debugLog) window.debugLog('network', 'Using AbortController fallback');
       // Fallback: use AbortController for timeout
@@ -499,6 +518,7 @@ function updateSystemMetrics(system) {
 // Export to window
 window.detectClientInfo = detectClientInfo;
 window.refreshIP = refreshIP;
+window.onPublicIPChange = onPublicIPChange;
 window.refresh = refresh;
 window.initWebSocket = initWebSocket;
 
@@ -0,0 +1,155 @@
+// Notes: named Markdown notes kept on the server (via /api/notes), rendered by the server and
+// updated on every device over the WebSocket.
+
+let notesList = [];
+let notesEditing = null; // Note being edited, {} for a new one
+
+function notesSelectedId() {
+  const id = window.loadFromStorage('notesSelected');
+  if (notesList.some(n => n.id === id)) return id;
+  return notesList.length ? notesList[0].id : '';
+}
+
+function notesModified(note) {
+  const d = new Date(note.modified);
+  return 'Saved ' + (d.toDateString() === new Date().toDateString() ? d.toLocaleTimeString([], {hour: '2-digit', minute: '2-digit'}) : d.toLocaleDateString());
+}
+
+function renderNotes() {
+  const container = document.getElementById('notesContainer');
+  if (!container) return;
+
+  if (notesEditing) {
+    const note = notesEditing;
+    container.innerHTML = `
+      <input type="text" id="notesNameInput" placeholder="Name" maxlength="80" value="${window.escapeHtml(note.name || '')}" style="width:100%;box-sizing:border-box;margin-bottom:6px;">
+      <textarea id="notesContentInput" rows="8" placeholder="Markdown" spellcheck="false" style="width:100%;box-sizing:border-box;resize:vertical;">${window.escapeHtml(note.content || '')}</textarea>
+      <div style="display:flex;gap:6px;margin-top:6px;">
+        <button type="button" class="btn-small" id="notesSaveBtn">Save</button>
+        <button type="button" class="btn-small" id="notesCancelBtn">Cancel</button>
+        ${note.id ? '<button type="button" class="btn-small" id="notesDeleteBtn" style="margin-left:auto;">Delete</button>' : ''}
+      </div>
+      <div class="small" id="notesEditHint" style="color:var(--warn);margin-top:4px;"></div>`;
+    document.getElementById('notesSaveBtn').addEventListener('click', saveNote);
+    document.getElementById('notesCancelBtn').addEventListener('click', () => { notesEditing = null; renderNotes(); });
+    const del = document.getElementById('notesDeleteBtn');
+    if (del) del.addEventListener('click', deleteNote);
+    return;
+  }
+
+  if (!notesList.length) {
+    container.innerHTML = '<div class="small" style="color:var(--muted);">No notes yet. Add one with +.</div>';
+    return;
+  }
+  const selected = notesSelectedId();
+  const note = notesList.find(n => n.id === selected);
+  let html = '';
+  if (notesList.length > 1) {
+    html += '<select id="notesSelect" style="width:100%;margin-bottom:6px;">' +
+      notesList.map(n => `<option value="${window.escapeHtml(n.id)}"${n.id === selected ? ' selected' : ''}>${window.escapeHtml(n.name)}</option>`).join('') +
+      '</select>';
+  } else {
+    html += `<div class="small" style="color:var(--muted);margin-bottom:4px;">${window.escapeHtml(note.name)}</div>`;
+  }
+  // Rendered and sanitized on the server
+  html += `<div class="notes-body small" title="Double-click to edit">${note.html || '<span style="color:var(--muted);">Empty note</span>'}</div>`;
+  html += `<div class="small" style="color:var(--muted);margin-top:4px;">${notesModified(note)}</div>`;
+  container.innerHTML = html;
+
+  const select = document.getElementById('notesSelect');
+  if (select) select.addEventListener('change', () => { window.saveToStorage('notesSelected', select.value); renderNotes(); });
+  container.querySelector('.notes-body').addEventListener('dblclick', () => editNote(note));
+}
+
+function editNote(note) {
+  notesEditing = note ? {...note} : {};
+  renderNotes();
+  const input = document.getElementById(note ? 'notesContentInput' : 'notesNameInput');
+  if (input) input.focus();
+}
+
+async function saveNote() {
+  const note = notesEditing;
+  const body = {
+    id: note.id,
+    name: document.getElementById('notesNameInput').value,
+    content: document.getElementById('notesContentInput').value,
+    modified: note.modified
+  };
+  try {
+    const res = await fetch(note.id ? '/api/notes/update' : '/api/notes/add', {method: 'POST', body: JSON.stringify(body)});
+    const data = await res.json();
+    if (data.error) {
+      await window.popup.alert(data.error, 'Notes');
+      return;
+    }
+    upsertNote(data.note);
+    window.saveToStorage('notesSelected', data.note.id);
+    notesEditing = null;
+    renderNotes();
+  } catch (err) {
+    if (window.debugError) window.debugError('notes', 'Error saving note:', err);
+  }
+}
+
+async function deleteNote() {
+  const note = notesEditing;
+  if (!await window.popup.confirm(`Delete note "${note.name}"?`, 'Confirm Delete')) return;
+  try {
+    const data = await (await fetch('/api/notes/delete', {method: 'POST', body: JSON.stringify({id: note.id})})).json();
+    if (data.error) {
+      await window.popup.alert(data.error, 'Notes');
+      return;
+    }
+    notesList = notesList.filter(n => n.id !== note.id);
+    notesEditing = null;
+    renderNotes();
+  } catch (err) {
+    if (window.debugError) window.debugError('notes', 'Error deleting note:', err);
+  }
+}
+
+function upsertNote(note) {
+  const i = notesList.findIndex(n => n.id === note.id);
+  if (i >= 0) notesList[i] = note;
+  else notesList.push(note);
+}
+
+// onNoteChange applies a note added, changed or deleted on another device.
+function onNoteChange(data) {
+  if (!data.note) return;
+  if (data.action === 'delete') {
+    notesList = notesList.filter(n => n.id !== data.note.id);
+  } else {
+    upsertNote(data.note);
+  }
+  // Keep the editor open; saving over a newer version is refused by the server
+  if (notesEditing && notesEditing.id === data.note.id) {
+    const hint = document.getElementById('notesEditHint');
+    if (hint) hint.textContent = data.action === 'delete' ? 'This note was deleted on another device.' : 'This note was changed on another device.';
+    return;
+  }
+  if (!notesEditing) renderNotes();
+}
+
+async function refreshNotes() {
+  if (notesEditing) return;
+  try {
+    const res = await fetch('/api/notes');
+    const data = await res.json();
+    notesList = data.notes || [];
+    renderNotes();
+  } catch (err) {
+    if (window.debugError) window.debugError('notes', 'Error loading notes:', err);
+  }
+}
+
+function initNotes() {
+  const addBtn = document.getElementById('notesCardAddBtn');
+  if (addBtn) addBtn.addEventListener('click', () => editNote(null));
+  setTimeout(refreshNotes, 1000);
+}
+
+window.refreshNotes = refreshNotes;
+window.initNotes = initNotes;
+window.onNoteChange = onNoteChange;
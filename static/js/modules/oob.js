@@ -0,0 +1,57 @@
+// Servers: power state, sensors and event log read from BMCs over Redfish or IPMI (via /api/oob).
+
+const OOB_STATUS_COLORS = {ok: 'var(--good)', warning: 'var(--warn, #f59e0b)', critical: 'var(--bad, #ef4444)'};
+
+function oobRow(s) {
+  if (s.error) {
+    return `<div class="kv" title="${window.escapeHtml(s.error)}"><div class="k"><i class="fas fa-question-circle" style="color:var(--muted);width:1.2em;"></i> ${window.escapeHtml(s.name)}</div><div class="v small" style="color:var(--muted);">Unavailable</div></div>`;
+  }
+  const on = s.powerState === 'on';
+  const color = !on ? 'var(--muted)' : (OOB_STATUS_COLORS[s.health] || 'var(--good)');
+  const parts = [on ? 'On' : (s.powerState === 'off' ? 'Off' : 'Unknown')];
+
+  // Hottest temperature and total power draw
+  const temps = s.sensors.filter(x => x.type === 'temperature');
+  if (temps.length) parts.push(Math.max(...temps.map(x => x.reading)).toFixed(0) + ' °C');
+  const watts = s.sensors.filter(x => x.type === 'power' && x.unit === 'W');
+  if (watts.length) parts.push(watts[0].reading.toFixed(0) + ' W');
+  if (s.eventLog && s.eventLog.entries) {
+    const severe = (s.eventLog.critical || 0) + (s.eventLog.warning || 0);
+    parts.push(s.eventLog.entries + ' SEL' + (severe ? ` (${severe} !)` : ''));
+  }
+
+  const bad = s.sensors.filter(x => x.status === 'warning' || x.status === 'critical');
+  const title = [[s.manufacturer, s.model].filter(Boolean).join(' '),
+    ...bad.map(x => `${x.name}: ${x.reading} ${x.unit || ''} (${x.status})`)].filter(Boolean).join('\n');
+  return `<div class="kv" title="${window.escapeHtml(title)}"><div class="k"><i class="fas fa-power-off" style="color:${color};width:1.2em;"></i> ${window.escapeHtml(s.name)}</div><div class="v small">${window.escapeHtml(parts.join(' · '))}</div></div>`;
+}
+
+async function refreshOob() {
+  const container = document.getElementById('oobContainer');
+  if (!container) return;
+  window.startTimer('oob');
+
+  try {
+    const res = await fetch('/api/oob');
+    const data = await res.json();
+    if (!data.enabled) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">Configure Redfish or IPMI servers under "oob" in the config file.</div>';
+      return;
+    }
+    container.innerHTML = data.servers.map(oobRow).join('');
+  } catch (err) {
+    if (window.debugError) window.debugError('oob', 'Error loading server status:', err);
+  }
+}
+
+function initOob() {
+  setTimeout(refreshOob, 1000);
+  setInterval(function() {
+    if (!window.wsIsConnected || !window.wsIsConnected()) {
+      refreshOob();
+    }
+  }, window.timers && window.timers.oob ? window.timers.oob.interval : 60000);
+}
+
+window.refreshOob = refreshOob;
+window.initOob = initOob;
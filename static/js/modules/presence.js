@@ -0,0 +1,66 @@
+// Presence: who is home, from Home Assistant or OwnTracks (via /api/presence).
+
+function presenceSince(iso) {
+  const since = new Date(iso);
+  if (isNaN(since.getTime())) return '';
+  const mins = Math.floor((Date.now() - since.getTime()) / 60000);
+  if (mins < 1) return 'just now';
+  if (mins < 60) return mins + 'm';
+  const hours = Math.floor(mins / 60);
+  if (hours < 24) return hours + 'h';
+  return Math.floor(hours / 24) + 'd';
+}
+
+async function refreshPresence() {
+  const container = document.getElementById('presenceContainer');
+  if (!container) return;
+  window.startTimer('presence');
+
+  try {
+    const res = await fetch('/api/presence');
+    const data = await res.json();
+    if (!data.enabled) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">Configure Home Assistant or OwnTracks under "presence" in the config file.</div>';
+      return;
+    }
+    if (data.error) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">' + window.escapeHtml(data.error) + '</div>';
+      return;
+    }
+    const people = data.people || [];
+    if (people.length === 0) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">No one tracked yet</div>';
+      return;
+    }
+
+    let html = '';
+    for (const p of people) {
+      const icon = p.state === 'home' ? 'fa-house-user' : (p.state === 'away' ? 'fa-walking' : 'fa-question');
+      const label = p.state === 'away' && p.zone ? p.zone : p.state;
+      html += `
+        <div class="kv presence-row presence-${window.escapeHtml(p.state)}">
+          <div class="k"><i class="fas ${icon}"></i> ${window.escapeHtml(p.name)}</div>
+          <div class="v" title="Since ${window.escapeHtml(new Date(p.since).toLocaleString())}">${window.escapeHtml(label)} <span class="muted small">${presenceSince(p.since)}</span></div>
+        </div>
+      `;
+    }
+    if (data.lastError) {
+      html += '<div class="small" style="color:var(--muted);">Last update failed: ' + window.escapeHtml(data.lastError) + '</div>';
+    }
+    container.innerHTML = html;
+  } catch (err) {
+    if (window.debugError) window.debugError('presence', 'Error loading presence:', err);
+  }
+}
+
+function initPresence() {
+  setTimeout(refreshPresence, 1000);
+  setInterval(function() {
+    if (!window.wsIsConnected || !window.wsIsConnected()) {
+      refreshPresence();
+    }
+  }, window.timers && window.timers.presence ? window.timers.presence.interval : 60000);
+}
+
+window.refreshPresence = refreshPresence;
+window.initPresence = initPresence;
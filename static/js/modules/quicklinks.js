@@ -117,47 +117,14 @@ function updateLayoutButtons() {
   });
 }
 
-// Favicon cache
-function getFaviconCache() {
-  try {
-    const cache = window.loadFromStorage('faviconCache');
-    return cache || {};
-  } catch (e) {
-    return {};
-  }
+// Favicons are cached on the server and served as images, so they load with the page
+function faviconImageUrl(url) {
+  return window.appUrl('/api/favicon/img') + '?url=' + encodeURIComponent(url);
 }
 
-function saveFaviconCache(cache) {
-  try {
-    window.saveToStorage('faviconCache', cache);
-  } catch (e) {}
-}
-
-async function fetchAndCacheFavicon(url) {
-  const cache = getFaviconCache();
-  const cacheKey = new URL(url).origin;
-
-  if (cache[cacheKey] && cache[cacheKey].expires > Date.now()) {
-    return cache[cacheKey].data;
-  }
-
-  try {
-    const res = await fetch('/api/favicon?url=' + encodeURIComponent(url));
-    const data = await res.json();
-
-    if (data.favicon) {
-      cache[cacheKey] = {
-        data: data.favicon,
-        expires: Date.now() + (7 * 24 * 60 * 60 * 1000)
-      };
-      saveFaviconCache(cache);
-      return data.favicon;
-    }
-  } catch (e) {
-    if (window.debugError) window.debugError('quicklinks', 'Error fetching favicon:', e);
-  }
-
-  return null;
+// Icons of the dashboard-icons set by slug, e.g. "proxmox"
+function iconSlugImageUrl(slug) {
+  return window.appUrl('/api/icons/' + encodeURIComponent(slug));
 }
 
 function renderQuicklinks() {
@@ -191,33 +158,35 @@ function renderQuicklinks() {
       a.title = link.title; // Use title as tooltip when icons only
     }
 
-    if (link.icon) {
+    if (link.icon && !link.iconSlug) {
       a.innerHTML = '<span class="ql-icon"><i class="fas ' + link.icon + '"></i></span>' + (quicklinksIconsOnly ? '' : '<span class="ql-title">' + link.title + '</span>');
     } else {
-      const cache = getFaviconCache();
       try {
-        const cacheKey = new URL(link.url).origin;
-        if (cache[cacheKey] && cache[cacheKey].expires > Date.now()) {
-          a.innerHTML = '<span class="ql-icon"><img src="' + cache[cacheKey].data + '" width="14" height="14"></span>' + (quicklinksIconsOnly ? '' : '<span class="ql-title">' + link.title + '</span>');
-        } else {
-          const iconSpan = document.createElement('span');
-          iconSpan.className = 'ql-icon';
-          iconSpan.innerHTML = '<i class="fas fa-spinner fa-spin"></i>';
-          if (!quicklinksIconsOnly) {
-            const titleSpan = document.createElement('span');
-            titleSpan.className = 'ql-title';
-            titleSpan.textContent = link.title;
-            a.appendChild(titleSpan);
+        const iconSpan = document.createElement('span');
+        iconSpan.className = 'ql-icon';
+        const img = document.createElement('img');
+        const faviconUrl = faviconImageUrl(new URL(link.url).href);
+        img.src = link.iconSlug ? iconSlugImageUrl(link.iconSlug) : faviconUrl;
+        img.width = 14;
+        img.height = 14;
+        img.alt = '';
+        img.addEventListener('error', () => {
+          // A slug the pack does not have falls back to the chosen icon, then the favicon
+          if (link.iconSlug && link.icon) {
+            iconSpan.innerHTML = '<i class="fas ' + link.icon + '"></i>';
+          } else if (link.iconSlug && img.src.indexOf('/api/favicon/img') < 0) {
+            img.src = faviconUrl;
+          } else {
+            iconSpan.innerHTML = '<i class="fas fa-link"></i>';
           }
-          a.appendChild(iconSpan);
-
-          fetchAndCacheFavicon(link.url).then(favicon => {
-            if (favicon) {
-              iconSpan.innerHTML = '<img src="' + favicon + '" width="14" height="14">';
-            } else {
-              iconSpan.innerHTML = '<i class="fas fa-link"></i>';
-            }
-          });
+        });
+        iconSpan.appendChild(img);
+        a.appendChild(iconSpan);
+        if (!quicklinksIconsOnly) {
+          const titleSpan = document.createElement('span');
+          titleSpan.className = 'ql-title';
+          titleSpan.textContent = link.title;
+          a.appendChild(titleSpan);
         }
       } catch (e) {
         a.innerHTML = '<span class="ql-icon"><i class="fas fa-link"></i></span>' + (quicklinksIconsOnly ? '' : '<span class="ql-title">' + link.title + '</span>');
@@ -342,7 +311,7 @@ function renderQuicklinksList() {
 }
 
 function showQuicklinkEditDialog(index) {
-  const link = index >= 0 ? quicklinks[index] : { title: '', url: '', icon: '' };
+  const link = index >= 0 ? quicklinks[index] : { title: '', url: '', icon: '', iconSlug: '' };
   const isNew = index < 0;
 
   const fields = [
@@ -388,6 +357,13 @@ function showQuicklinkEditDialog(index) {
         { value: 'fa-terminal', label: 'Terminal' }
       ],
       required: false
+    },
+    {
+      id: 'iconSlug',
+      label: 'Dashboard icon',
+      type: 'text',
+      placeholder: 'e.g., proxmox (overrides the icon)',
+      required: false
     }
   ];
 
@@ -400,17 +376,23 @@ function showQuicklinkEditDialog(index) {
       const title = formData.title.trim();
       const url = formData.url.trim();
       const icon = formData.icon;
+      const iconSlug = (formData.iconSlug || '').trim().toLowerCase();
 
       if (!title || !url) {
         await window.popup.alert('Please enter a title and URL', 'Input Required');
         return;
       }
+      if (iconSlug && !/^[a-z0-9][a-z0-9._-]{0,63}$/.test(iconSlug)) {
+        await window.popup.alert('Dashboard icons are named like home-assistant', 'Invalid Icon');
+        return;
+      }
 
       const linkData = {
         id: isNew ? 'ql-' + Date.now() : link.id,
         title: title,
         url: url,
-        icon: icon
+        icon: icon,
+        iconSlug: iconSlug
       };
 
       if (isNew) {
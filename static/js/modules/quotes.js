@@ -0,0 +1,60 @@
+// Quotes: stock and cryptocurrency prices from /api/quotes, cached on the server.
+
+function quoteFormatPrice(q) {
+  const digits = q.price >= 1000 ? 0 : q.price >= 1 ? 2 : 4;
+  const text = q.price.toLocaleString([], {minimumFractionDigits: digits, maximumFractionDigits: digits});
+  return q.currency ? text + ' <span class="muted small">' + window.escapeHtml(q.currency) + '</span>' : text;
+}
+
+function quoteRow(q) {
+  const label = window.escapeHtml(q.symbol.toUpperCase());
+  if (q.error) {
+    return `<div class="kv" title="${window.escapeHtml(q.provider + ': ' + q.error)}"><div class="k">${label}</div><div class="v small" style="color:var(--muted);">${window.escapeHtml(q.error)}</div></div>`;
+  }
+  const cls = q.changePercent > 0 ? 'quote-up' : q.changePercent < 0 ? 'quote-down' : '';
+  const arrow = q.changePercent > 0 ? '▲' : q.changePercent < 0 ? '▼' : '';
+  const since = q.kind === 'crypto' ? '24h' : 'since previous close';
+  const title = [q.name, q.updated ? 'Priced ' + new Date(q.updated).toLocaleString() : '', q.provider].filter(Boolean).join('\n');
+  return `<div class="kv" title="${window.escapeHtml(title)}"><div class="k">${label}</div><div class="v">${quoteFormatPrice(q)} <span class="small ${cls}" title="${since}">${arrow} ${Math.abs(q.changePercent).toFixed(2)}%</span></div></div>`;
+}
+
+async function refreshQuotes(force) {
+  const container = document.getElementById('quotesContainer');
+  if (!container) return;
+  window.startTimer('quotes');
+
+  try {
+    const res = await fetch('/api/quotes' + (force ? '?refresh=1' : ''));
+    const data = await res.json();
+    if (!data.enabled) {
+      container.innerHTML = data.error
+        ? '<div class="small" style="color:var(--bad, #ef4444);">' + window.escapeHtml(data.error) + '</div>'
+        : '<div class="small" style="color:var(--muted);">List "stocks" and "crypto" under "quotes" in the config file.</div>';
+      return;
+    }
+    let html = '';
+    if (data.error) {
+      html += '<div class="small" style="color:var(--bad, #ef4444);">' + window.escapeHtml(data.error) + '</div>';
+    }
+    const stocks = data.quotes.filter(q => q.kind !== 'crypto');
+    const crypto = data.quotes.filter(q => q.kind === 'crypto');
+    html += stocks.map(quoteRow).join('');
+    if (stocks.length && crypto.length) html += '<div style="border-top:1px solid var(--panel2);margin:4px 0;"></div>';
+    html += crypto.map(quoteRow).join('');
+    container.innerHTML = html;
+  } catch (err) {
+    if (window.debugError) window.debugError('quotes', 'Error loading quotes:', err);
+  }
+}
+
+function initQuotes() {
+  setTimeout(refreshQuotes, 1000);
+  setInterval(function() {
+    if (!window.wsIsConnected || !window.wsIsConnected()) {
+      refreshQuotes();
+    }
+  }, window.timers && window.timers.quotes ? window.timers.quotes.interval : 300000);
+}
+
+window.refreshQuotes = refreshQuotes;
+window.initQuotes = initQuotes;
@@ -0,0 +1,76 @@
+// Router: WAN status, DHCP leases and wireless clients from OpenWrt (via /api/router).
+
+function routerDuration(sec) {
+  if (!sec || sec < 0) return '';
+  const days = Math.floor(sec / 86400);
+  const hours = Math.floor((sec % 86400) / 3600);
+  const mins = Math.floor((sec % 3600) / 60);
+  if (days > 0) return days + 'd ' + hours + 'h';
+  if (hours > 0) return hours + 'h ' + mins + 'm';
+  return mins + 'm';
+}
+
+async function refreshRouter() {
+  const container = document.getElementById('routerContainer');
+  if (!container) return;
+  window.startTimer('router');
+
+  try {
+    const res = await fetch('/api/router');
+    const data = await res.json();
+    if (!data.enabled) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">Configure an OpenWrt router under "router" in the config file.</div>';
+      return;
+    }
+    if (data.error) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">' + window.escapeHtml(data.error) + '</div>';
+      return;
+    }
+
+    const router = data.router;
+    let html = '';
+    if (router.board) {
+      html += `<div class="kv"><div class="k">Router</div><div class="v">${window.escapeHtml(router.board.hostname)} <span class="muted small">${window.escapeHtml(router.board.model)}</span></div></div>`;
+    }
+    if (router.wan) {
+      const wan = router.wan;
+      const state = wan.up ? '<span style="color:var(--good);">Up</span>' : '<span style="color:var(--bad, #ef4444);">Down</span>';
+      html += `<div class="kv"><div class="k">WAN</div><div class="v">${state} <span class="muted small">${window.escapeHtml(wan.protocol || '')} ${routerDuration(wan.uptime)}</span></div></div>`;
+      if (wan.ipv4 && wan.ipv4.length) {
+        html += `<div class="kv"><div class="k">WAN IP</div><div class="v">${window.escapeHtml(wan.ipv4.join(', '))}</div></div>`;
+      }
+    }
+    html += `<div class="kv"><div class="k">DHCP leases</div><div class="v">${router.leases.length}</div></div>`;
+    html += `<div class="kv"><div class="k">Wireless clients</div><div class="v">${router.totalClients}</div></div>`;
+    for (const w of router.wireless) {
+      const label = w.ssid ? w.ssid + (w.band ? ' (' + w.band + ')' : '') : w.device;
+      html += `<div class="kv"><div class="k small">&nbsp;&nbsp;${window.escapeHtml(label)}</div><div class="v small">${w.clients}</div></div>`;
+    }
+
+    if (router.leases.length > 0) {
+      html += '<details style="margin-top:6px;"><summary class="small" style="cursor:pointer;">Leases</summary>';
+      for (const l of router.leases) {
+        html += `<div class="kv small"><div class="k">${window.escapeHtml(l.hostname || l.mac || '')}</div><div class="v">${window.escapeHtml(l.ip)}</div></div>`;
+      }
+      html += '</details>';
+    }
+    if (router.errors && router.errors.length) {
+      html += '<div class="small" style="color:var(--muted);" title="' + window.escapeHtml(router.errors.join('\n')) + '">Some router data is unavailable (check rpcd ACLs)</div>';
+    }
+    container.innerHTML = html;
+  } catch (err) {
+    if (window.debugError) window.debugError('router', 'Error loading router status:', err);
+  }
+}
+
+function initRouter() {
+  setTimeout(refreshRouter, 1000);
+  setInterval(function() {
+    if (!window.wsIsConnected || !window.wsIsConnected()) {
+      refreshRouter();
+    }
+  }, window.timers && window.timers.router ? window.timers.router.interval : 60000);
+}
+
+window.refreshRouter = refreshRouter;
+window.initRouter = initRouter;
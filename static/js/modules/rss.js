@@ -129,7 +129,7 @@ function renderRssModules() {
     if (Array.isArray(layoutConfig.modules)) {
       layoutConfig.modules.forEach(entry => {
         const moduleId = entry && typeof entry === 'object' ? entry.id : entry;
-        if (moduleId && typeof moduleId === 'string' && moduleId.startsWith('rss-')) {
+        if (moduleId && typeof moduleId === 'string' && window.moduleTypeOf(moduleId) === 'rss') {
           modulesInLayout.add(moduleId);
         }
       });
@@ -140,11 +140,11 @@ function renderRssModules() {
         row.modules.forEach(moduleId => {
           if (Array.isArray(moduleId)) {
             moduleId.forEach(id => {
-              if (id && typeof id === 'string' && id.startsWith('rss-')) {
+              if (id && typeof id === 'string' && window.moduleTypeOf(id) === 'rss') {
                 modulesInLayout.add(id);
               }
             });
-          } else if (moduleId && typeof moduleId === 'string' && moduleId.startsWith('rss-')) {
+          } else if (moduleId && typeof moduleId === 'string' && window.moduleTypeOf(moduleId) === 'rss') {
             modulesInLayout.add(moduleId);
           }
         });
@@ -152,7 +152,10 @@ function renderRssModules() {
     }
   }
 
-  rssModules.forEach((mod, index) => {
+  // The shared timer goes on the first feed refreshed with the others
+  const sharedTimerModule = rssModules.find(m => m.enabled && !window.moduleInstanceInterval(m.id));
+
+  rssModules.forEach((mod) => {
     if (!mod.enabled) return;
     if (modulesInLayout.has(mod.id)) return;
 
@@ -161,14 +164,15 @@ function renderRssModules() {
     card.setAttribute('data-module', mod.id);
     card.setAttribute('draggable', 'true');
 
-    const hasTimer = index === 0;
-    const timerHtml = hasTimer ? '<div class="timer-circle" id="rssTimer" title="Double-click to refresh"></div>' : '';
+    const hasTimer = mod === sharedTimerModule;
+    const timerHtml = hasTimer ? '<div class="timer-circle" id="rssTimer" title="Double-click to refresh"></div>' : window.moduleInstanceTimerHtml(mod.id);
 
     card.innerHTML = `
       <h3><i class="fas fa-rss"></i> ${mod.name || 'RSS Feed'}<div class="header-icons"><a href="${mod.url}" target="_blank" rel="noreferrer"><i class="fas fa-external-link-alt"></i></a>${timerHtml}<i class="fas fa-grip-vertical drag-handle" title="Drag to reorder"></i></div></h3>
       <div id="rss-content-${mod.id}">Loading...</div>
     `;
     container.appendChild(card);
+    window.bindModuleInstanceTimer(card);
 
     const cachedData = getCachedFeed(mod.id);
     if (cachedData) {
@@ -186,12 +190,22 @@ function renderRssModules() {
 async function refreshRssModule(mod) {
   try {
     const maxItems = mod.maxItems || 5;
-    const res = await fetch(`/api/rss?url=${encodeURIComponent(mod.url)}&count=${maxItems}`, {cache: "no-store"});
+    const res = await window.bootstrapFetch(`/api/rss?url=${encodeURIComponent(mod.url)}&count=${maxItems}`, {cache: "no-store"});
     const j = await res.json();
 
     const contentEl = document.getElementById(`rss-content-${mod.id}`);
     if (!contentEl) return;
+    if (window.applyOffline) window.applyOffline(mod.id, j);
 
+    if (j.error && j.offline) {
+      const cachedData = getCachedFeed(mod.id);
+      if (cachedData) {
+        renderRssContent(mod.id, cachedData);
+      } else {
+        contentEl.innerHTML = '<div class="muted">No internet connection, nothing fetched yet</div>';
+      }
+      return;
+    }
     if (j.error) {
       contentEl.innerHTML = `<div class="error">Error: ${j.error}</div>`;
       return;
@@ -220,9 +234,12 @@ async function refreshRssModule(mod) {
   }
 }
 
-async function refreshRss() {
+// Refreshes the feeds; scheduled refreshes skip feeds with an interval of their own
+async function refreshRss(scheduled = false) {
   try {
-    const promises = rssModules.filter(m => m.enabled).map(mod => refreshRssModule(mod));
+    const promises = rssModules
+      .filter(m => m.enabled && !(scheduled && window.moduleInstanceInterval(m.id)))
+      .map(mod => refreshRssModule(mod));
     await Promise.all(promises);
     window.startTimer("rss");
   } catch(err) {
@@ -230,6 +247,11 @@ async function refreshRss() {
   }
 }
 
+function refreshRssInstance(id) {
+  const mod = rssModules.find(m => m.id === id);
+  if (mod && mod.enabled) refreshRssModule(mod);
+}
+
 function renderRssModuleList() {
   const list = document.getElementById('rssModuleList');
   if (!list) return;
@@ -281,6 +303,7 @@ function renderRssModuleList() {
       const confirmed = await window.popup.confirm(`Delete RSS module "${mod.name || 'RSS Feed'}"?`, 'Confirm Delete');
       if (confirmed) {
         rssModules.splice(index, 1);
+        window.setModuleInstanceInterval(mod.id, 0);
         saveRssModules();
         renderRssModuleList();
         renderRssModules();
@@ -289,78 +312,53 @@ function renderRssModuleList() {
   });
 }
 
-function showRssEditDialog(index) {
-  const mod = index >= 0 ? rssModules[index] : { id: 'rss-' + Date.now(), name: '', url: '', enabled: true, showTitle: true, showText: true, showDate: true, maxItems: 5 };
+async function showRssEditDialog(index) {
+  const schema = await window.loadModuleSchema('rss');
+  if (!schema) {
+    await window.popup.alert('Could not load the RSS module settings', 'Error');
+    return;
+  }
+  // New modules and fields missing from older ones take the schema defaults
+  const mod = Object.assign({ id: '', name: '', url: '', enabled: true }, schema.defaults, index >= 0 ? rssModules[index] : {});
   const isNew = index < 0;
 
-  // Ensure defaults for existing modules
-  if (mod.showTitle === undefined) mod.showTitle = true;
-  if (mod.showText === undefined) mod.showText = true;
-  if (mod.showDate === undefined) mod.showDate = true;
-  if (mod.maxItems === undefined) mod.maxItems = 5;
-
-  const fields = [
-    {
-      id: 'name',
-      label: 'Name',
-      type: 'text',
-      placeholder: 'RSS Feed Name',
-      required: false
-    },
-    {
-      id: 'url',
-      label: 'RSS Feed URL',
-      type: 'text',
-      placeholder: 'https://example.com/feed.xml',
-      required: true
-    },
-    {
-      id: 'maxItems',
-      label: 'Articles',
-      type: 'number',
-      min: 1,
-      max: 20,
-      style: 'width:60px;',
-      required: false
-    },
-    {
-      id: 'showTitle',
-      label: 'Show Title',
-      type: 'checkbox'
-    },
-    {
-      id: 'showText',
-      label: 'Show Text',
-      type: 'checkbox'
-    },
-    {
-      id: 'showDate',
-      label: 'Show Date',
-      type: 'checkbox'
-    }
-  ];
+  const fields = window.moduleSchemaFields(schema);
+  fields.splice(fields.findIndex(f => f.id === 'maxItems') + 1, 0, {
+    id: 'interval',
+    label: 'Refresh (s)',
+    type: 'number',
+    min: 60,
+    max: 86400,
+    placeholder: 'Shared',
+    style: 'width:80px;',
+    required: false
+  });
 
   showModuleEditDialog({
     title: `${isNew ? 'Add' : 'Edit'} RSS Module`,
     icon: 'fas fa-rss',
     fields: fields,
-    values: mod,
-    onSave: (formData) => {
+    values: Object.assign({}, mod, { interval: (mod.id && window.moduleInstanceInterval(mod.id)) || '' }),
+    onSave: async (formData) => {
       const name = formData.name.trim();
       const url = formData.url.trim();
       const maxItems = Math.max(1, Math.min(20, parseInt(formData.maxItems) || 5));
       const showTitle = formData.showTitle;
       const showText = formData.showText;
       const showDate = formData.showDate;
+      const interval = parseInt(formData.interval) > 0 ? Math.max(60, Math.min(86400, parseInt(formData.interval))) : 0;
 
-      if (!url) {
-        window.popup.alert('RSS Feed URL is required', 'Input Required');
+      const error = await window.validateModuleConfig('rss', formData);
+      if (error) {
+        await window.popup.alert(error, 'Invalid Input');
         return;
       }
 
+      let id = mod.id;
       if (isNew) {
+        id = window.newModuleInstanceId('rss', (name || url.replace(/^https?:\/\/(www\.)?/, '').split(/[/?#]/)[0]).toLowerCase(), rssModules);
         rssModules.push({
-          id: 'rss-' + Date.now(),
+          id: id,
           name: name || 'RSS Feed',
           url: url,
           enabled: true,
@@ -378,6 +376,7 @@ function showRssEditDialog(index) {
         rssModules[index].showDate = showDate;
       }
 
+      window.setModuleInstanceInterval(id, interval);
       saveRssModules();
       window.rssModules = rssModules;
       renderRssModuleList();
@@ -410,5 +409,6 @@ window.rssModules = rssModules;
 window.saveRssModules = saveRssModules;
 window.renderRssModules = renderRssModules;
 window.refreshRss = refreshRss;
+window.refreshRssInstance = refreshRssInstance;
 window.renderRssModuleList = renderRssModuleList;
 window.initRss = initRss;
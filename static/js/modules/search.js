@@ -4,6 +4,8 @@
 let engines = [];
 let currentEngineIndex = 0;
 let searchHistory = [];
+// Set when the server keeps the search history of every device
+let serverSearchHistory = false;
 let autocompleteItems = [];
 let selectedAutocompleteIndex = -1;
 let searchKeydownInitialized = false;
@@ -17,10 +19,13 @@ async function loadSearchEngines() {
       if (data.engines && Array.isArray(data.engines)) {
         // Convert backend format (Name, URL, Icon, Category) to frontend format (name, url, icon, category)
         engines = data.engines.map(e => ({
+          id: e.id,
           name: e.name || e.Name,
           url: e.url || e.URL,
           icon: e.icon || e.Icon,
-          category: e.category || e.Category
+          category: e.category || e.Category,
+          bang: e.bang,
+          custom: !!e.custom
         }));
         window.engines = engines;
         return true;
@@ -105,6 +110,47 @@ function loadSearchHistory() {
   }
 }
 
+// Incognito is per device: searches on it are not recorded here or on the server
+function isSearchIncognito() {
+  return localStorage.getItem('searchIncognito') === '1';
+}
+
+function setSearchIncognito(on) {
+  localStorage.setItem('searchIncognito', on ? '1' : '0');
+  const q = document.getElementById('q');
+  if (q) {
+    q.placeholder = on ? 'Search… (incognito)' : 'Search…';
+  }
+}
+
+// Loads the server-side search history, when the server keeps one, into this browser
+async function loadServerSearchHistory() {
+  try {
+    const res = await fetch('/api/search/history?limit=1000');
+    const data = await res.json();
+    serverSearchHistory = data.enabled === true;
+    if (!serverSearchHistory) return;
+    searchHistory = (data.history || []).reverse();
+    saveSearchHistory();
+  } catch (e) {
+    if (window.debugError) window.debugError('search', 'Error loading server search history:', e);
+  }
+}
+
+async function purgeServerSearchHistory(selector) {
+  try {
+    const res = await fetch('/api/search/history/purge', {
+      method: 'POST',
+      headers: {'Content-Type': 'application/json'},
+      body: JSON.stringify(selector)
+    });
+    const data = await res.json();
+    if (data.error) window.popup.alert(data.error, 'Search History');
+  } catch (e) {
+    if (window.debugError) window.debugError('search', 'Error purging server search history:', e);
+  }
+}
+
 function saveSearchHistory() {
   if (searchHistory.length > 100) {
     searchHistory = searchHistory.slice(-100);
@@ -113,6 +159,16 @@ function saveSearchHistory() {
 }
 
 function addToSearchHistory(term, engineName) {
+  if (isSearchIncognito()) return;
+  if (serverSearchHistory) {
+    fetch('/api/search/history', {
+      method: 'POST',
+      headers: {'Content-Type': 'application/json'},
+      body: JSON.stringify({term: term, engine: engineName})
+    }).catch(e => {
+      if (window.debugError) window.debugError('search', 'Error saving search to server history:', e);
+    });
+  }
   searchHistory = searchHistory.filter(item => !(item.term === term && item.engine === engineName));
   searchHistory.push({
     term: term,
@@ -125,9 +181,12 @@ function addToSearchHistory(term, engineName) {
   }
 }
 
-function removeFromSearchHistory(index) {
+async function removeFromSearchHistory(index) {
   if (index >= 0 && index < searchHistory.length) {
-    searchHistory.splice(index, 1);
+    const [item] = searchHistory.splice(index, 1);
+    if (serverSearchHistory) {
+      await purgeServerSearchHistory({term: item.term, engine: item.engine});
+    }
     saveSearchHistory();
     if (window.renderSearchHistory) {
       window.renderSearchHistory();
@@ -139,6 +198,9 @@ async function clearSearchHistory() {
   const confirmed = await window.popup.confirm("Are you sure you want to clear all search history?", "Confirm Clear");
   if (confirmed) {
     searchHistory = [];
+    if (serverSearchHistory) {
+      await purgeServerSearchHistory({});
+    }
     saveSearchHistory();
     if (window.renderSearchHistory) {
       window.renderSearchHistory();
@@ -162,21 +224,28 @@ async function renderSearchHistory(filter = '') {
   // Use backend API for filtering
   let filtered = [];
   try {
-    const response = await fetch(`/api/search/history/filter?filter=${encodeURIComponent(filter)}`, {
-      method: 'POST',
-      headers: {
-        'Content-Type': 'application/json'
-      },
-      body: JSON.stringify(searchHistory)
-    });
+    if (serverSearchHistory) {
+      // The server has more searches than this browser keeps, with their devices
+      const res = await fetch(`/api/search/history?limit=1000&filter=${encodeURIComponent(filter)}`);
+      const data = await res.json();
+      filtered = (data.history || []).reverse();
+    } else {
+      const response = await fetch(`/api/search/history/filter?filter=${encodeURIComponent(filter)}`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json'
+        },
+        body: JSON.stringify(searchHistory)
+      });
     
-    if (response.ok) {
-      const data = await response.json();
-      if (data.history && Array.isArray(data.history)) {
-        filtered = data.history;
+      if (response.ok) {
+        const data = await response.json();
+        if (data.history && Array.isArray(data.history)) {
+          filtered = data.history;
+        }
+      } else {
+        if (window.debugError) window.debugError('search', 'Error filtering search history: HTTP ' + response.status);
       }
-    } else {
-      if (window.debugError) window.debugError('search', 'Error filtering search history: HTTP ' + response.status);
     }
   } catch (e) {
     if (window.debugError) window.debugError('search', 'Error filtering search history:', e);
@@ -190,14 +259,14 @@ async function renderSearchHistory(filter = '') {
   list.innerHTML = '';
   // Show in reverse order (newest first)
   [...filtered].reverse().forEach((item, displayIndex) => {
-    const actualIndex = searchHistory.indexOf(item);
+    const actualIndex = searchHistory.findIndex(h => h.term === item.term && h.engine === item.engine && h.timestamp === item.timestamp);
     const div = document.createElement('div');
     div.className = 'module-item';
     div.innerHTML = `
       <div class="module-icon"><i class="fas fa-search"></i></div>
       <div class="module-info">
         <div class="module-name">${window.escapeHtml(item.term)}</div>
-        <div class="module-desc">${item.engine} • ${new Date(item.timestamp).toLocaleString()}</div>
+        <div class="module-desc">${window.escapeHtml(item.engine)} • ${new Date(item.timestamp).toLocaleString()}${item.device ? ' • ' + window.escapeHtml(item.device) : ''}</div>
       </div>
       <div class="module-controls">
         <button class="btn-small delete-search-btn" data-index="${actualIndex}"><i class="fas fa-trash"></i></button>
@@ -206,8 +275,15 @@ async function renderSearchHistory(filter = '') {
     list.appendChild(div);
 
     const deleteBtn = div.querySelector('.delete-search-btn');
-    deleteBtn.addEventListener('click', () => {
-      removeFromSearchHistory(actualIndex);
+    deleteBtn.addEventListener('click', async () => {
+      if (serverSearchHistory) {
+        await purgeServerSearchHistory({term: item.term, engine: item.engine});
+        searchHistory = searchHistory.filter(h => !(h.term === item.term && h.engine === item.engine));
+        saveSearchHistory();
+        renderSearchHistory(filter);
+      } else {
+        removeFromSearchHistory(actualIndex);
+      }
     });
   });
 }
@@ -254,7 +330,8 @@ function renderEngines() {
     "media": "Media",
     "shopping": "Shopping",
     "maps": "Maps",
-    "development": "Development"
+    "development": "Development",
+    "custom": "Custom"
   };
 
   const enginesByCategory = {};
@@ -576,7 +653,7 @@ async function renderAutocomplete(filter = '') {
   // Reload search history to ensure it's up to date
   loadSearchHistory();
 
-  if (!searchHistory || searchHistory.length === 0) {
+  if (!serverSearchHistory && (!searchHistory || searchHistory.length === 0)) {
     hideAutocomplete();
     return;
   }
@@ -839,6 +916,13 @@ function goSearch() {
     return;
   }
 
+  // Bangs such as "!yt cats" search the engine with that shortcut
+  if (/(^|\s)![a-z0-9]/i.test(term)) {
+    goBangSearch(term, sameTab);
+    q.value = "";
+    return;
+  }
+
   // Otherwise, perform normal search
   const engine = engines[currentEngineIndex];
   addToSearchHistory(term, engine.name);
@@ -851,6 +935,25 @@ function goSearch() {
   q.value = "";
 }
 
+// Resolve a query with a bang on the backend, falling back to the current engine
+async function goBangSearch(term, sameTab) {
+  const engine = engines[currentEngineIndex];
+  let target = {engine: engine.name, query: term, url: engine.url.replace("%s", encodeURIComponent(term))};
+  try {
+    const res = await fetch(`/api/search/resolve?q=${encodeURIComponent(term)}&engine=${encodeURIComponent(engine.name)}`);
+    const data = await res.json();
+    if (data.url) target = data;
+  } catch (e) {
+    if (window.debugError) window.debugError('search', 'Error resolving bang:', e);
+  }
+  if (target.query) addToSearchHistory(target.query, target.engine);
+  if (sameTab) {
+    window.location.href = target.url;
+  } else {
+    window.open(target.url, "_blank", "noreferrer");
+  }
+}
+
 // Global keyboard shortcuts handler (only add once)
 let keyboardShortcutsInitialized = false;
 
@@ -947,6 +1050,14 @@ async function initSearch() {
   await initSearchEngines();
   
   loadSearchHistory();
+  loadServerSearchHistory();
+
+  const incognito = document.getElementById('searchIncognito');
+  if (incognito) {
+    incognito.checked = isSearchIncognito();
+    incognito.addEventListener('change', () => setSearchIncognito(incognito.checked));
+  }
+  setSearchIncognito(isSearchIncognito());
 
   const q = document.getElementById("q");
   const engineBtn = document.getElementById("engineBtn");
@@ -1290,8 +1401,10 @@ window.saveSearchHistory = saveSearchHistory;
 window.addToSearchHistory = addToSearchHistory;
 window.removeFromSearchHistory = removeFromSearchHistory;
 window.clearSearchHistory = clearSearchHistory;
+window.setSearchIncognito = setSearchIncognito;
 window.renderSearchHistory = renderSearchHistory;
 window.renderEngines = renderEngines;
+window.loadSearchEngines = loadSearchEngines;
 window.goSearch = goSearch;
 window.initSearch = initSearch;
 window.renderAutocomplete = renderAutocomplete;
@@ -0,0 +1,62 @@
+// Shares: capacity and reachability of NFS and SMB shares (via /api/shares).
+
+function shareRow(s) {
+  const proto = s.protocol ? s.protocol.toUpperCase() : '';
+  const title = [s.source, s.host ? s.host + (s.reachable ? ' reachable (' + s.latency + ' ms)' : ' unreachable') : '', s.mountPoint].filter(Boolean).join('\n');
+  let state = '';
+  if (!s.mounted) {
+    state = '<span style="color:var(--bad, #ef4444);">Not mounted</span>';
+  } else if (!s.responding) {
+    state = '<span style="color:var(--bad, #ef4444);">' + window.escapeHtml(s.error || 'Not responding') + '</span>';
+  } else if (s.host && !s.reachable) {
+    state = '<span style="color:var(--warn, #f59e0b);">Server unreachable</span>';
+  }
+  let html = `<div class="kv" title="${window.escapeHtml(title)}"><div class="k"><i class="fas fa-folder" style="width:1.2em;"></i> ${window.escapeHtml(s.name)}${proto ? ' <span class="small" style="color:var(--muted);">' + proto + '</span>' : ''}</div>`;
+  if (s.responding) {
+    html += `<div class="v small">${window.escapeHtml(s.usedFormatted)} / ${window.escapeHtml(s.totalFormatted)} (${s.percent.toFixed(0)}%)</div></div>`;
+    const color = s.percent >= 90 ? 'var(--bad, #ef4444)' : (s.percent >= 80 ? 'var(--warn, #f59e0b)' : 'var(--accent)');
+    html += `<div style="height:6px;background:var(--panel2);border-radius:3px;overflow:hidden;margin:2px 0 6px;"><div style="height:100%;width:${Math.min(100, s.percent).toFixed(1)}%;background:${color};"></div></div>`;
+    if (state) html += '<div class="small">' + state + '</div>';
+  } else {
+    html += `<div class="v small">${state}</div></div>`;
+  }
+  return html;
+}
+
+async function refreshShares() {
+  const container = document.getElementById('sharesContainer');
+  if (!container) return;
+  window.startTimer('shares');
+
+  try {
+    const res = await fetch('/api/shares');
+    const data = await res.json();
+    if (!data.enabled) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">Configure NFS and SMB mounts under "shares" in the config file.</div>';
+      return;
+    }
+    if (data.error) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">' + window.escapeHtml(data.error) + '</div>';
+      return;
+    }
+    if (!data.shares.length) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">No network shares mounted.</div>';
+      return;
+    }
+    container.innerHTML = data.shares.map(shareRow).join('');
+  } catch (err) {
+    if (window.debugError) window.debugError('shares', 'Error loading shares:', err);
+  }
+}
+
+function initShares() {
+  setTimeout(refreshShares, 1000);
+  setInterval(function() {
+    if (!window.wsIsConnected || !window.wsIsConnected()) {
+      refreshShares();
+    }
+  }, window.timers && window.timers.shares ? window.timers.shares.interval : 60000);
+}
+
+window.refreshShares = refreshShares;
+window.initShares = initShares;
@@ -171,7 +171,7 @@ function renderSnmpModuleList() {
 }
 
 function showSnmpEditDialog(index) {
-  const query = index >= 0 ? snmpQueries[index] : { title: '', host: '', port: 161, community: 'public', oid: '', displayType: 'show', prefix: '', suffix: '', divisor: 1, siUnits: false };
+  const query = index >= 0 ? snmpQueries[index] : { title: '', host: '', port: 161, community: 'public', profile: '', oid: '', displayType: 'show', prefix: '', suffix: '', divisor: 1, siUnits: false };
   const isNew = index < 0;
 
   const fields = [
@@ -202,7 +202,14 @@ function showSnmpEditDialog(index) {
       label: 'Community',
       type: 'text',
       placeholder: 'public',
-      required: true
+      required: false
+    },
+    {
+      id: 'profile',
+      label: 'Credential Profile',
+      type: 'text',
+      placeholder: 'Saved profile (SNMPv3), instead of community',
+      required: false
     },
     {
       id: 'oid',
@@ -218,7 +225,9 @@ function showSnmpEditDialog(index) {
       options: [
         { value: 'show', label: 'Show' },
         { value: 'diff', label: 'Diff' },
-        { value: 'period-diff', label: 'Period Diff' }
+        { value: 'period-diff', label: 'Period Diff' },
+        { value: 'mbps', label: 'Rate (Mbps)' },
+        { value: 'interface', label: 'Interface Traffic (OID = interface name or index)' }
       ],
       required: false
     },
@@ -281,6 +290,7 @@ function showSnmpEditDialog(index) {
       const host = formData.host.trim();
       const port = parseInt(formData.port) || 161;
       const community = formData.community.trim();
+      const profile = (formData.profile || '').trim();
       const oid = formData.oid.trim();
       const displayType = formData.displayType;
       const prefix = formData.prefix.trim();
@@ -288,12 +298,12 @@ function showSnmpEditDialog(index) {
       const divisor = parseInt(formData.divisor) || 1;
       const siUnits = formData.siUnits;
 
-      if (!title || !host || !community || !oid) {
+      if (!title || !host || (!community && !profile) || !oid) {
         window.popup.alert('Please fill in all required fields', 'Input Required');
         return;
       }
 
-      const newQuery = { title, host, port, community, oid, displayType, prefix, suffix, divisor, siUnits, enabled: true };
+      const newQuery = { title, host, port, community, profile, oid, displayType, prefix, suffix, divisor, siUnits, enabled: true };
 
       if (isNew) {
         snmpQueries.push(newQuery);
@@ -373,8 +383,13 @@ async function checkSnmpQuery(query, index) {
 
   statusEl.innerHTML = '<i class="fas fa-spinner fa-spin" style="color:var(--accent);"></i>';
 
+  if (query.displayType === 'interface') {
+    return checkSnmpInterface(query, statusEl, valueEl);
+  }
+
   try {
-    const url = `/api/snmp?host=${encodeURIComponent(query.host)}&port=${encodeURIComponent(query.port)}&community=${encodeURIComponent(query.community)}&oid=${encodeURIComponent(query.oid)}`;
+    const credentials = query.profile ? `profile=${encodeURIComponent(query.profile)}` : `community=${encodeURIComponent(query.community)}`;
+    const url = `/api/snmp?host=${encodeURIComponent(query.host)}&port=${encodeURIComponent(query.port)}&${credentials}&oid=${encodeURIComponent(query.oid)}`;
     const res = await fetch(url, {cache: "no-store"});
     const data = await res.json();
 
@@ -392,6 +407,9 @@ async function checkSnmpQuery(query, index) {
 
       if (displayType === 'show') {
         displayValue = String(currentValue);
+      } else if (displayType === 'mbps') {
+        // Rates of interface counters are computed by the server between queries
+        displayValue = data.mbps !== undefined ? data.mbps.toFixed(2) : '0';
       } else if (displayType === 'diff' || displayType === 'period-diff') {
         const queryKey = `${query.host}:${query.port}:${query.oid}`;
         const lastData = snmpLastValues[queryKey];
@@ -439,6 +457,49 @@ async function checkSnmpQuery(query, index) {
   }
 }
 
+// Renders a series of {t, v} points as a small inline SVG line
+function snmpSparkline(points, max, color) {
+  if (!points || points.length < 2 || max <= 0) return '';
+  const w = 120, h = 22;
+  const t0 = points[0].t, span = Math.max(points[points.length - 1].t - t0, 1);
+  const coords = points.map(p => `${((p.t - t0) / span * w).toFixed(1)},${(h - Math.min(p.v / max, 1) * h).toFixed(1)}`).join(' ');
+  return `<polyline points="${coords}" fill="none" stroke="${color}" stroke-width="1.5"/>`;
+}
+
+// Interface traffic: the server walks IF-MIB and computes the rates between polls
+async function checkSnmpInterface(query, statusEl, valueEl) {
+  try {
+    const credentials = query.profile ? `profile=${encodeURIComponent(query.profile)}` : `community=${encodeURIComponent(query.community)}`;
+    const url = `/api/snmp/interfaces?host=${encodeURIComponent(query.host)}&port=${encodeURIComponent(query.port)}&${credentials}&interface=${encodeURIComponent(query.oid)}&history=1h`;
+    const res = await fetch(url, {cache: "no-store"});
+    const data = await res.json();
+    const iface = data.success && data.interfaces && data.interfaces[0];
+    if (!iface) {
+      statusEl.innerHTML = '<i class="fas fa-times-circle" style="color:#bf616a;"></i>';
+      valueEl.textContent = data.error || 'Interface not found';
+      valueEl.style.color = '#bf616a';
+      return;
+    }
+
+    statusEl.innerHTML = iface.up
+      ? '<i class="fas fa-check-circle" style="color:#a3be8c;"></i>'
+      : '<i class="fas fa-times-circle" style="color:#bf616a;"></i>';
+    const fmt = v => v === undefined ? '—' : (v >= 100 ? v.toFixed(0) : v.toFixed(2));
+    const history = iface.history || { in: [], out: [] };
+    const max = Math.max(...history.in.map(p => p.v), ...history.out.map(p => p.v), 0);
+    const graph = history.in.length > 1
+      ? `<svg width="120" height="22" style="vertical-align:middle;margin-right:6px;">${snmpSparkline(history.in, max, 'var(--accent)')}${snmpSparkline(history.out, max, 'var(--muted)')}</svg>`
+      : '';
+    valueEl.innerHTML = `${graph}↓ ${fmt(iface.inMbps)} ↑ ${fmt(iface.outMbps)} Mbps`;
+    valueEl.title = [iface.name, iface.alias, iface.speedMbps ? `${iface.speedMbps} Mbps link` : ''].filter(Boolean).join(' · ');
+    valueEl.style.color = '';
+  } catch (err) {
+    statusEl.innerHTML = '<i class="fas fa-times-circle" style="color:#bf616a;"></i>';
+    valueEl.textContent = 'Error: ' + err.message;
+    valueEl.style.color = '#bf616a';
+  }
+}
+
 function refreshSnmp() {
   snmpQueries.filter(q => q.enabled !== false).forEach((query, index) => {
     checkSnmpQuery(query, index);
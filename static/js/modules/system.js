@@ -23,7 +23,7 @@ async function refreshCPU() {
   // Start timer immediately when refresh begins
   if (window.startTimer) window.startTimer("cpu");
   try {
-    const res = await fetch("/api/system", {cache:"no-store"});
+    const res = await window.bootstrapFetch("/api/system", {cache:"no-store"});
     const j = await res.json();
     if (j.cpu && j.cpu.usage !== undefined) {
       const usage = j.cpu.usage.toFixed(1);
@@ -42,7 +42,7 @@ async function refreshRAM() {
   // Start timer immediately when refresh begins
   if (window.startTimer) window.startTimer("ram");
   try {
-    const res = await fetch("/api/system", {cache:"no-store"});
+    const res = await window.bootstrapFetch("/api/system", {cache:"no-store"});
     const j = await res.json();
     if (j.ram && j.ram.percent !== undefined) {
       // Use formatted values from backend
@@ -67,7 +67,7 @@ async function refreshRAM() {
 async function refreshDiskSingle(mountPoint) {
   try {
     const mount = mountPoint || "/";
-    const res = await fetch("/api/disk?mount=" + encodeURIComponent(mount), {cache:"no-store"});
+    const res = await window.bootstrapFetch("/api/disk?mount=" + encodeURIComponent(mount), {cache:"no-store"});
     const j = await res.json();
 
     const safeMount = mount.replace(/[^a-zA-Z0-9]/g, '_');
@@ -107,9 +107,11 @@ async function refreshDiskSingle(mountPoint) {
   }
 }
 
-async function refreshAllDisks() {
+// Refreshes the disks; scheduled refreshes skip disks with an interval of their own
+async function refreshAllDisks(scheduled = false) {
   if (!diskModules || diskModules.length === 0) return;
   for (const mod of diskModules) {
+    if (scheduled && window.moduleInstanceInterval(mod.id)) continue;
     if (mod.enabled && mod.mountPoint) {
       await refreshDiskSingle(mod.mountPoint);
     }
@@ -117,9 +119,14 @@ async function refreshAllDisks() {
   window.startTimer("disk");
 }
 
+function refreshDiskInstance(id) {
+  const mod = diskModules.find(m => m.id === id);
+  if (mod && mod.enabled && mod.mountPoint) refreshDiskSingle(mod.mountPoint);
+}
+
 async function refreshCPUInfo() {
   try {
-    const res = await fetch("/api/cpuid", {cache:"no-store"});
+    const res = await window.bootstrapFetch("/api/cpuid", {cache:"no-store"});
     const j = await res.json();
 
     const el = document.getElementById("cpuidContent");
@@ -272,7 +279,7 @@ async function refreshCPUInfo() {
 
 async function refreshRAMInfo() {
   try {
-    const res = await fetch("/api/raminfo", {cache:"no-store"});
+    const res = await window.bootstrapFetch("/api/raminfo", {cache:"no-store"});
     const j = await res.json();
 
     const el = document.getElementById("raminfoContent");
@@ -335,7 +342,7 @@ async function refreshRAMInfo() {
 
 async function refreshFirmwareInfo() {
   try {
-    const res = await fetch("/api/firmware", {cache:"no-store"});
+    const res = await window.bootstrapFetch("/api/firmware", {cache:"no-store"});
     const j = await res.json();
 
     const el = document.getElementById("firmwareContent");
@@ -375,7 +382,7 @@ async function refreshFirmwareInfo() {
 
 async function refreshSystemInfo() {
   try {
-    const res = await fetch("/api/systeminfo", {cache:"no-store"});
+    const res = await window.bootstrapFetch("/api/systeminfo", {cache:"no-store"});
     const j = await res.json();
 
     const el = document.getElementById("systeminfoContent");
@@ -440,7 +447,7 @@ async function refreshSystemInfo() {
 
 async function refreshBaseboardInfo() {
   try {
-    const res = await fetch("/api/baseboard", {cache:"no-store"});
+    const res = await window.bootstrapFetch("/api/baseboard", {cache:"no-store"});
     const j = await res.json();
 
     const el = document.getElementById("baseboardContent");
@@ -509,7 +516,10 @@ function renderDiskModules() {
   if (!container) return;
   container.innerHTML = '';
 
-  diskModules.forEach((mod, index) => {
+  // The shared timer goes on the first disk refreshed with the others
+  const sharedTimerModule = diskModules.find(m => m.enabled && !window.moduleInstanceInterval(m.id));
+
+  diskModules.forEach((mod) => {
     if (!mod.enabled) return;
 
     // Check if the card already exists in the DOM (in the grid or elsewhere)
@@ -524,10 +534,9 @@ function renderDiskModules() {
     card.setAttribute('data-module', mod.id);
     card.setAttribute('draggable', 'true');
 
-    // First disk module gets the main disk timer
-    const hasTimer = index === 0;
-    const timerId = hasTimer ? 'diskTimer' : '';
-    const timerHtml = hasTimer ? `<div class="timer-circle" id="diskTimer" title="Double-click to refresh"></div>` : '';
+    // One disk module gets the main disk timer, disks with an interval of their own get theirs
+    const hasTimer = mod === sharedTimerModule;
+    const timerHtml = hasTimer ? `<div class="timer-circle" id="diskTimer" title="Double-click to refresh"></div>` : window.moduleInstanceTimerHtml(mod.id);
 
     card.innerHTML = `
       <h3><i class="fas fa-hdd"></i> ${displayName}<div class="header-icons">${timerHtml}<i class="fas fa-grip-vertical drag-handle" title="Drag to reorder"></i></div></h3>
@@ -540,6 +549,7 @@ function renderDiskModules() {
     `;
 
     container.appendChild(card);
+    window.bindModuleInstanceTimer(card);
 
     // Initialize graph for this disk
     if (window.initGraphs) {
@@ -627,6 +637,7 @@ function renderDiskModuleList() {
       const confirmed = await window.popup.confirm(`Delete disk module "${mod.mountPoint === '/' ? 'Disk (Root)' : mod.mountPoint}"?`, 'Confirm Delete');
       if (confirmed) {
         diskModules.splice(index, 1);
+        window.setModuleInstanceInterval(mod.id, 0);
         saveDiskModules();
         renderDiskModuleList();
         renderDiskModules();
@@ -667,6 +678,15 @@ function showDiskEditDialog(index) {
             ...mountPointOptions
           ],
           required: true
+        },
+        {
+          id: 'interval',
+          label: 'Refresh (s)',
+          type: 'number',
+          min: 5,
+          max: 86400,
+          placeholder: 'Shared',
+          required: false
         }
       ];
 
@@ -675,38 +695,46 @@ function showDiskEditDialog(index) {
         icon: 'fas fa-hdd',
         fields: fields,
         values: {
-          mountPoint: mod.mountPoint || ''
+          mountPoint: mod.mountPoint || '',
+          interval: (mod.id && window.moduleInstanceInterval(mod.id)) || ''
         },
         onSave: async (formData) => {
           const mountPoint = formData.mountPoint.trim();
+          const interval = parseInt(formData.interval) > 0 ? Math.max(5, Math.min(86400, parseInt(formData.interval))) : 0;
 
           if (!mountPoint) {
             await window.popup.alert('Mount point is required', 'Input Required');
             return;
           }
 
+          let id = mod.id;
           if (isNew) {
-            const safeMount = mountPoint.replace(/[^a-zA-Z0-9]/g, '_');
             const exists = diskModules.find(m => m.mountPoint === mountPoint);
             if (exists) {
               await window.popup.alert('This disk is already added', 'Duplicate');
               return;
             }
+            id = window.newModuleInstanceId('disk', mountPoint, diskModules);
             diskModules.push({
-              id: 'disk-' + safeMount,
+              id: id,
               mountPoint: mountPoint,
               enabled: true
             });
           } else {
-            const safeMount = mountPoint.replace(/[^a-zA-Z0-9]/g, '_');
             const exists = diskModules.find((m, i) => m.mountPoint === mountPoint && i !== index);
             if (exists) {
               await window.popup.alert('This disk is already added', 'Duplicate');
               return;
             }
+            if (diskModules[index].mountPoint !== mountPoint) {
+              // The instance ID follows the mount point
+              window.setModuleInstanceInterval(id, 0);
+              id = window.newModuleInstanceId('disk', mountPoint, diskModules.filter((m, i) => i !== index));
+            }
             diskModules[index].mountPoint = mountPoint;
-            diskModules[index].id = 'disk-' + safeMount;
+            diskModules[index].id = id;
           }
+          window.setModuleInstanceInterval(id, interval);
 
           saveDiskModules();
           window.diskModules = diskModules;
@@ -752,6 +780,7 @@ window.refreshRAM = refreshRAM;
 window.refreshDisk = refreshAllDisks;
 window.refreshDiskSingle = refreshDiskSingle;
 window.refreshAllDisks = refreshAllDisks;
+window.refreshDiskInstance = refreshDiskInstance;
 window.renderDiskModules = renderDiskModules;
 window.renderDiskModuleList = renderDiskModuleList;
 window.refreshCPUInfo = refreshCPUInfo;
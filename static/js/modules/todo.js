@@ -3,6 +3,8 @@
 // Todo structure: { id, title, completed, priority, dueDate }
 // priority: 'low', 'medium', 'high' (optional)
 // dueDate: YYYY-MM-DD (optional)
+// Tasks of ICS calendars are added by the backend with the calendar name as source; they
+// are read-only here.
 
 let todos = [];
 
@@ -50,10 +52,9 @@ function parseDateFromInput(datetimeString) {
   return datetimeString; // Fallback, though datetime-local should always have 'T'
 }
 
-// Get next N incomplete todos in list order (matches drag order in storage), with formatted dates from backend
+// Get next N incomplete todos in list order (matches drag order in storage), then the tasks of
+// ICS calendars, with formatted dates from backend
 async function getNextTodos(count = 5) {
-  if (todos.length === 0) return [];
-
   try {
     const res = await fetch(`/api/todos/process?count=${count}&includeCompleted=false&preserveOrder=true`, {
       method: 'POST',
@@ -97,6 +98,10 @@ async function renderNextTodos() {
 
   let html = '';
   for (const todo of nextTodos) {
+    if (todo.source) {
+      html += renderImportedTodo(todo);
+      continue;
+    }
     const index = todos.findIndex(t => t.id === todo.id);
     const canMoveUp = index > 0;
     const canMoveDown = index >= 0 && index < todos.length - 1;
@@ -190,6 +195,29 @@ async function renderNextTodos() {
   });
 }
 
+// renderImportedTodo renders a task of an ICS calendar, which is edited in its own app.
+function renderImportedTodo(todo) {
+  const priorityBadge = todo.priority
+    ? `<span class="todo-priority ${getPriorityClass(todo.priority)}">${window.escapeHtml(todo.priority)}</span>`
+    : '';
+  const dueDateText = todo.formattedDueDate ? ` - ${window.escapeHtml(todo.formattedDueDate)}` : '';
+  return `
+      <div class="module-item todo-next-card-item${todo.completed ? ' completed' : ''}" draggable="false">
+        <div class="module-icon" style="color: var(--muted);" title="From calendar ${window.escapeHtml(todo.source)}">
+          <i class="fas fa-calendar-check"></i>
+        </div>
+        <div class="module-info">
+          <div class="module-name" style="display:flex; align-items:center; gap:8px;">
+            <input type="checkbox" class="todo-list-checkbox" ${todo.completed ? 'checked' : ''} disabled title="Complete it in ${window.escapeHtml(todo.source)}">
+            <span>${window.escapeHtml(todo.title)}</span>
+            ${priorityBadge}
+          </div>
+          <div class="module-desc">${todo.completed ? 'Completed' : 'Active'}${dueDateText} • ${window.escapeHtml(todo.source)}</div>
+        </div>
+      </div>
+    `;
+}
+
 // Toggle todo completion
 function toggleTodo(id) {
   const todo = todos.find(t => t.id === id);
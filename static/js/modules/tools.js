@@ -0,0 +1,248 @@
+// Tools: small utilities, a password and passphrase generator (via /api/tools/password) and
+// hashing and encoding of text (via /api/tools/hash).
+
+const TOOLS_STRENGTH_COLORS = {weak: 'var(--bad, #ef4444)', fair: 'var(--warn, #f59e0b)', strong: 'var(--good)', 'very strong': 'var(--good)'};
+
+async function generateToolsPassword() {
+  const output = document.getElementById('toolsPassword');
+  const meta = document.getElementById('toolsPasswordMeta');
+  const mode = document.getElementById('toolsPasswordMode');
+  const size = document.getElementById('toolsPasswordSize');
+  if (!output || !mode || !size) return;
+
+  const passphrase = mode.value === 'passphrase';
+  const params = new URLSearchParams({mode: mode.value});
+  params.set(passphrase ? 'words' : 'length', size.value);
+  if (passphrase) params.set('number', '1');
+
+  try {
+    const res = await fetch('/api/tools/password?' + params.toString());
+    const data = await res.json();
+    if (data.error) {
+      output.textContent = '';
+      meta.innerHTML = `<span style="color:var(--bad, #ef4444);">${window.escapeHtml(data.error)}</span>`;
+      return;
+    }
+    output.textContent = data.passwords[0];
+    meta.innerHTML = `<span style="color:${TOOLS_STRENGTH_COLORS[data.strength] || 'var(--muted)'};">${window.escapeHtml(data.strength)}</span> · ${data.entropy} bits`;
+  } catch (err) {
+    if (window.debugError) window.debugError('tools', 'Error generating password:', err);
+  }
+}
+
+async function runToolsHash() {
+  const op = document.getElementById('toolsHashOp');
+  const input = document.getElementById('toolsHashInput');
+  const output = document.getElementById('toolsHashOutput');
+  if (!op || !input || !output) return;
+  if (!input.value) {
+    output.textContent = '';
+    return;
+  }
+
+  try {
+    const res = await fetch('/api/tools/hash', {
+      method: 'POST',
+      headers: {'Content-Type': 'application/json'},
+      body: JSON.stringify({op: op.value, text: input.value})
+    });
+    const data = await res.json();
+    output.style.color = data.error ? 'var(--bad, #ef4444)' : '';
+    output.textContent = data.error || data.result;
+  } catch (err) {
+    if (window.debugError) window.debugError('tools', 'Error hashing text:', err);
+  }
+}
+
+async function initToolsHash() {
+  const op = document.getElementById('toolsHashOp');
+  const input = document.getElementById('toolsHashInput');
+  const output = document.getElementById('toolsHashOutput');
+  if (!op || !input || !output) return;
+
+  try {
+    const res = await fetch('/api/tools/hash');
+    const data = await res.json();
+    op.innerHTML = (data.operations || []).map(o => `<option value="${window.escapeHtml(o)}">${window.escapeHtml(o)}</option>`).join('');
+    op.value = 'sha256';
+  } catch (err) {
+    if (window.debugError) window.debugError('tools', 'Error loading hash operations:', err);
+  }
+
+  let debounce = null;
+  input.addEventListener('input', () => {
+    clearTimeout(debounce);
+    debounce = setTimeout(runToolsHash, 300);
+  });
+  op.addEventListener('change', runToolsHash);
+  output.addEventListener('click', async () => {
+    if (output.textContent && !output.style.color && await copyToClipboard(output.textContent)) {
+      output.title = 'Copied';
+    }
+  });
+}
+
+async function runToolsTime() {
+  const input = document.getElementById('toolsTimeInput');
+  const output = document.getElementById('toolsTimeOutput');
+  if (!input || !output) return;
+  const value = input.value.trim();
+  if (!value) {
+    output.innerHTML = '';
+    return;
+  }
+
+  const tz = Intl.DateTimeFormat().resolvedOptions().timeZone || '';
+  // Five fields or a macro such as @daily is a cron expression
+  const isCron = /^@\w+$|^(\S+\s+){4}\S+$/.test(value);
+  const params = new URLSearchParams({tz});
+  params.set(isCron ? 'cron' : 'value', value);
+  try {
+    const res = await fetch('/api/tools/time?' + params);
+    const data = await res.json();
+    if (data.error) {
+      output.innerHTML = `<span style="color:var(--bad, #ef4444);">${window.escapeHtml(data.error)}</span>`;
+    } else if (data.cron) {
+      const next = (data.cron.next || []).map(t => `<div>${window.escapeHtml(new Date(t).toLocaleString())}</div>`).join('');
+      output.innerHTML = `<div>${window.escapeHtml(data.cron.description)}</div><div style="color:var(--muted);">${next}</div>`;
+    } else if (data.time) {
+      const t = data.time;
+      output.innerHTML = [
+        `${t.unix} · ${t.unixMilli}`,
+        t.utc,
+        ...(t.zones || []).map(z => `${z.time} ${z.abbr}`),
+        `<span style="color:var(--muted);">${window.escapeHtml(t.format)}, ${window.escapeHtml(t.relative)}</span>`
+      ].map(line => `<div>${line.startsWith('<') ? line : window.escapeHtml(line)}</div>`).join('');
+    }
+  } catch (err) {
+    if (window.debugError) window.debugError('tools', 'Error converting time:', err);
+  }
+}
+
+async function runToolsFormat() {
+  const input = document.getElementById('toolsFormatInput');
+  const to = document.getElementById('toolsFormatTo');
+  const output = document.getElementById('toolsFormatOutput');
+  if (!input || !to || !output) return;
+  if (!input.value.trim()) {
+    output.textContent = '';
+    return;
+  }
+
+  try {
+    const res = await fetch('/api/tools/format', {
+      method: 'POST',
+      headers: {'Content-Type': 'application/json'},
+      body: JSON.stringify({text: input.value, to: to.value})
+    });
+    const data = await res.json();
+    output.style.color = data.error ? 'var(--bad, #ef4444)' : '';
+    output.textContent = data.error || data.result.output;
+  } catch (err) {
+    if (window.debugError) window.debugError('tools', 'Error formatting document:', err);
+  }
+}
+
+async function sendToolsHttpRequest() {
+  const url = document.getElementById('toolsHttpUrl');
+  const output = document.getElementById('toolsHttpOutput');
+  if (!url || !output || !url.value.trim()) return;
+  const headers = document.getElementById('toolsHttpHeaders').value.split('\n')
+    .map(line => line.split(':'))
+    .filter(parts => parts.length > 1 && parts[0].trim())
+    .map(([name, ...value]) => ({name: name.trim(), value: value.join(':').trim()}));
+
+  output.textContent = 'Sending...';
+  try {
+    const res = await fetch('/api/tools/httpreq', {
+      method: 'POST',
+      headers: {'Content-Type': 'application/json'},
+      body: JSON.stringify({
+        method: document.getElementById('toolsHttpMethod').value,
+        url: url.value.trim(),
+        headers,
+        body: document.getElementById('toolsHttpBody').value
+      })
+    });
+    const data = await res.json();
+    if (data.error) {
+      output.innerHTML = `<span style="color:var(--bad, #ef4444);">${window.escapeHtml(data.error)}</span>`;
+      return;
+    }
+    const r = data.response;
+    const t = r.timing;
+    const color = r.statusCode >= 400 ? 'var(--bad, #ef4444)' : 'var(--good, #22c55e)';
+    const headerLines = Object.entries(r.headers || {})
+      .flatMap(([name, values]) => values.map(v => `${window.escapeHtml(name)}: ${window.escapeHtml(v)}`))
+      .join('<br>');
+    const body = r.body !== undefined ? r.body : (r.bodyBase64 ? `(${r.bodySize} bytes of binary data)` : '');
+    output.innerHTML = `
+      <div><span style="color:${color};">${window.escapeHtml(r.status)}</span> · ${t.total} ms · ${r.bodySize} bytes${r.bodyTruncated ? ' (truncated)' : ''}</div>
+      <div style="color:var(--muted);">DNS ${t.dns} · connect ${t.connect} · TLS ${t.tls} · first byte ${t.firstByte} · download ${t.download} ms</div>
+      <details><summary>Headers</summary>${headerLines}</details>
+      <pre style="white-space:pre-wrap;max-height:200px;overflow:auto;margin:4px 0 0;">${window.escapeHtml(body)}</pre>`;
+  } catch (err) {
+    if (window.debugError) window.debugError('tools', 'Error sending request:', err);
+  }
+}
+
+function initTools() {
+  const output = document.getElementById('toolsPassword');
+  if (!output) return;
+  const mode = document.getElementById('toolsPasswordMode');
+  const size = document.getElementById('toolsPasswordSize');
+
+  mode.addEventListener('change', () => {
+    const passphrase = mode.value === 'passphrase';
+    size.min = passphrase ? 3 : 8;
+    size.max = passphrase ? 20 : 128;
+    size.value = passphrase ? 6 : 20;
+    size.title = passphrase ? 'Words' : 'Length';
+    generateToolsPassword();
+  });
+  size.addEventListener('change', generateToolsPassword);
+  document.getElementById('toolsPasswordBtn').addEventListener('click', generateToolsPassword);
+  output.addEventListener('click', async () => {
+    if (output.textContent && await copyToClipboard(output.textContent)) {
+      output.title = 'Copied';
+    }
+  });
+
+  generateToolsPassword();
+  initToolsHash();
+
+  const formatOutput = document.getElementById('toolsFormatOutput');
+  if (formatOutput) {
+    document.getElementById('toolsFormatBtn').addEventListener('click', runToolsFormat);
+    document.getElementById('toolsFormatTo').addEventListener('change', runToolsFormat);
+    formatOutput.addEventListener('click', async () => {
+      if (formatOutput.textContent && !formatOutput.style.color && await copyToClipboard(formatOutput.textContent)) {
+        formatOutput.title = 'Copied';
+      }
+    });
+  }
+
+  const httpSend = document.getElementById('toolsHttpSend');
+  if (httpSend) {
+    httpSend.addEventListener('click', sendToolsHttpRequest);
+    document.getElementById('toolsHttpUrl').addEventListener('keydown', (e) => {
+      if (e.key === 'Enter') sendToolsHttpRequest();
+    });
+  }
+
+  const timeInput = document.getElementById('toolsTimeInput');
+  if (timeInput) {
+    let debounce = null;
+    timeInput.addEventListener('input', () => {
+      clearTimeout(debounce);
+      debounce = setTimeout(runToolsTime, 400);
+    });
+  }
+}
+
+window.generateToolsPassword = generateToolsPassword;
+window.runToolsHash = runToolsHash;
+window.runToolsTime = runToolsTime;
+window.runToolsFormat = runToolsFormat;
+window.sendToolsHttpRequest = sendToolsHttpRequest;
+window.initTools = initTools;
@@ -0,0 +1,68 @@
+// UPS: battery charge, runtime and load of NUT and apcupsd UPSes (via /api/ups).
+
+function upsRuntime(sec) {
+  if (sec === undefined || sec === null) return '';
+  const hours = Math.floor(sec / 3600);
+  const mins = Math.floor((sec % 3600) / 60);
+  return hours > 0 ? hours + 'h ' + mins + 'm' : mins + 'm';
+}
+
+function upsRow(u) {
+  if (u.error) {
+    return `<div class="kv" title="${window.escapeHtml(u.error)}"><div class="k"><i class="fas fa-question-circle" style="color:var(--muted);width:1.2em;"></i> ${window.escapeHtml(u.name)}</div><div class="v small" style="color:var(--muted);">Unavailable</div></div>`;
+  }
+  let icon = 'fa-plug';
+  let color = 'var(--good)';
+  let state = u.charging ? 'Charging' : 'Online';
+  if (u.onBattery) {
+    icon = 'fa-battery-half';
+    color = u.lowBattery ? 'var(--bad, #ef4444)' : 'var(--warn, #f59e0b)';
+    state = u.lowBattery ? 'Low battery' : 'On battery';
+  }
+  const parts = [state];
+  if (u.charge !== undefined) parts.push(u.charge.toFixed(0) + '%');
+  if (u.runtime !== undefined) parts.push(upsRuntime(u.runtime) + ' left');
+  const title = [u.model, u.flags ? u.flags.join(' ') : '', u.load !== undefined ? 'Load ' + u.load.toFixed(0) + '%' : '', u.inputVoltage !== undefined ? 'Input ' + u.inputVoltage.toFixed(0) + ' V' : ''].filter(Boolean).join('\n');
+  return `<div class="kv" title="${window.escapeHtml(title)}"><div class="k"><i class="fas ${icon}" style="color:${color};width:1.2em;"></i> ${window.escapeHtml(u.name)}</div><div class="v small">${window.escapeHtml(parts.join(' · '))}</div></div>`;
+}
+
+async function refreshUps() {
+  const container = document.getElementById('upsContainer');
+  if (!container) return;
+  window.startTimer('ups');
+
+  try {
+    const res = await fetch('/api/ups');
+    const data = await res.json();
+    if (!data.enabled) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">Configure NUT or apcupsd under "ups" in the config file.</div>';
+      return;
+    }
+    if (!data.ups.length) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">Waiting for the first reading...</div>';
+      return;
+    }
+    let html = '';
+    for (const u of data.ups) {
+      html += upsRow(u);
+      if (!u.error && u.load !== undefined) {
+        html += `<div class="kv"><div class="k small">Load</div><div class="v small">${u.load.toFixed(0)}%</div></div>`;
+      }
+    }
+    container.innerHTML = html;
+  } catch (err) {
+    if (window.debugError) window.debugError('ups', 'Error loading UPS status:', err);
+  }
+}
+
+function initUps() {
+  setTimeout(refreshUps, 1000);
+  setInterval(function() {
+    if (!window.wsIsConnected || !window.wsIsConnected()) {
+      refreshUps();
+    }
+  }, window.timers && window.timers.ups ? window.timers.ups.interval : 30000);
+}
+
+window.refreshUps = refreshUps;
+window.initUps = initUps;
@@ -0,0 +1,111 @@
+// Usage statistics: counts card refreshes and clicks per module and reports them to this
+// server only (/api/stats/usage), so Preferences → Modules can show which cards are used.
+
+const usagePending = { renders: {}, interactions: {} };
+let usagePendingCount = 0;
+
+function countModuleRender(moduleName) {
+  usagePending.renders[moduleName] = (usagePending.renders[moduleName] || 0) + 1;
+  usagePendingCount++;
+}
+
+function countModuleInteraction(moduleName) {
+  usagePending.interactions[moduleName] = (usagePending.interactions[moduleName] || 0) + 1;
+  usagePendingCount++;
+}
+
+function takeUsagePending() {
+  if (!usagePendingCount) return null;
+  const body = JSON.stringify(usagePending);
+  usagePending.renders = {};
+  usagePending.interactions = {};
+  usagePendingCount = 0;
+  return body;
+}
+
+async function flushUsage() {
+  const body = takeUsagePending();
+  if (!body) return;
+  try {
+    await fetch('/api/stats/usage', { method: 'POST', headers: { 'Content-Type': 'application/json' }, body });
+  } catch (e) {}
+}
+
+function usageAgo(value) {
+  if (!value) return 'never';
+  const days = Math.floor((Date.now() - new Date(value).getTime()) / 86400000);
+  if (days <= 0) return 'today';
+  return days === 1 ? 'yesterday' : days + ' days ago';
+}
+
+async function renderUsageStats() {
+  const list = document.getElementById('usageList');
+  if (!list) return;
+  await flushUsage();
+  try {
+    const data = await (await fetch('/api/stats/usage')).json();
+    if (!data.enabled) {
+      list.innerHTML = '<div class="small" style="color:var(--muted);">Usage statistics are disabled in the config.</div>';
+      return;
+    }
+    if (!data.modules.length) {
+      list.innerHTML = '<div class="small" style="color:var(--muted);">Nothing counted yet.</div>';
+      return;
+    }
+    let html = `<div class="small" style="color:var(--muted); margin-bottom:6px;">Since ${new Date(data.since).toLocaleDateString()}, kept on this server only.</div>`;
+    for (const m of data.modules) {
+      const title = `Refreshed ${m.renders}×, last ${usageAgo(m.lastRender)}\nClicked ${m.interactions}×, last ${usageAgo(m.lastInteraction)}` +
+        (m.fetches ? `\nServer fetches: ${m.fetches} (${m.fetchErrors} failed), avg ${m.avgFetchMs.toFixed(0)} ms, max ${m.maxFetchMs.toFixed(0)} ms` : '');
+      const idle = m.idle ? ' <span class="small" style="color:var(--warn);" title="Shown but not clicked for two weeks">idle</span>' : '';
+      html += `<div class="kv" title="${window.escapeHtml(title)}"><div class="k small">${window.escapeHtml(m.name || m.module)}${idle}</div><div class="v small mono">${m.interactions} clicks · ${m.renders} views</div></div>`;
+    }
+    const slow = data.routes.filter(r => r.count >= 5).sort((a, b) => b.avgMs - a.avgMs).slice(0, 5);
+    if (slow.length) {
+      html += '<div class="small" style="color:var(--muted); margin-top:8px;">Slowest API routes</div>';
+      for (const r of slow) {
+        html += `<div class="kv"><div class="k small mono">${window.escapeHtml(r.method + ' ' + r.path)}</div><div class="v small mono">${r.avgMs.toFixed(0)} ms avg</div></div>`;
+      }
+    }
+    list.innerHTML = html;
+  } catch (err) {
+    if (window.debugError) window.debugError('usage', 'Error loading usage statistics:', err);
+  }
+}
+
+function initUsage() {
+  // Clicks anywhere in a card count as using its module
+  document.addEventListener('click', (e) => {
+    const card = e.target.closest && e.target.closest('.card[data-module]');
+    if (card && card.dataset.module) countModuleInteraction(card.dataset.module);
+  }, true);
+
+  setInterval(flushUsage, 60000);
+  window.addEventListener('pagehide', () => {
+    const body = takeUsagePending();
+    if (body && navigator.sendBeacon) {
+      navigator.sendBeacon(window.appUrl('/api/stats/usage'), new Blob([body], { type: 'application/json' }));
+    }
+  });
+
+  const modulesTab = document.querySelector('.modal-tab[data-tab="modules"]');
+  if (modulesTab) modulesTab.addEventListener('click', renderUsageStats);
+
+  const purgeBtn = document.getElementById('usagePurgeBtn');
+  if (purgeBtn) {
+    purgeBtn.addEventListener('click', async () => {
+      if (!await window.popup.confirm('Delete all usage statistics?', 'Purge Usage')) return;
+      takeUsagePending();
+      try {
+        const data = await (await fetch('/api/stats/usage', { method: 'DELETE' })).json();
+        if (data.error) await window.popup.alert(data.error, 'Purge Failed');
+      } catch (e) {
+        await window.popup.alert('Unable to purge usage statistics', 'Error');
+      }
+      renderUsageStats();
+    });
+  }
+}
+
+window.countModuleRender = countModuleRender;
+window.renderUsageStats = renderUsageStats;
+window.initUsage = initUsage;
@@ -0,0 +1,82 @@
+// Virtualization: Proxmox VE and libvirt virtual machines and containers (via /api/virt).
+
+function virtBytes(bytes) {
+  if (!bytes) return '0';
+  const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+  let i = 0;
+  let v = bytes;
+  while (v >= 1024 && i < units.length - 1) {
+    v /= 1024;
+    i++;
+  }
+  return (v >= 10 || i === 0 ? Math.round(v) : v.toFixed(1)) + ' ' + units[i];
+}
+
+function virtUptime(sec) {
+  if (!sec || sec < 0) return '';
+  const days = Math.floor(sec / 86400);
+  const hours = Math.floor((sec % 86400) / 3600);
+  const mins = Math.floor((sec % 3600) / 60);
+  if (days > 0) return days + 'd ' + hours + 'h';
+  if (hours > 0) return hours + 'h ' + mins + 'm';
+  return mins + 'm';
+}
+
+function virtGuestRow(g) {
+  const icon = g.kind === 'container' ? 'fa-box' : 'fa-desktop';
+  const color = g.running ? 'var(--good)' : (g.status === 'paused' || g.status === 'suspended' ? 'var(--warn, #f59e0b)' : 'var(--muted)');
+  const title = [g.source + (g.node ? ' · ' + g.node : ''), g.status, g.uptime ? 'up ' + virtUptime(g.uptime) : ''].filter(Boolean).join('\n');
+  let detail = window.escapeHtml(g.status);
+  if (g.running) {
+    detail = g.cpu.toFixed(0) + '% · ' + virtBytes(g.mem) + (g.maxMem ? ' / ' + virtBytes(g.maxMem) : '');
+  }
+  return `<div class="kv" title="${window.escapeHtml(title)}"><div class="k"><i class="fas ${icon}" style="color:${color};width:1.2em;"></i> ${window.escapeHtml(g.name)}</div><div class="v small">${detail}</div></div>`;
+}
+
+async function refreshVirt() {
+  const container = document.getElementById('virtContainer');
+  if (!container) return;
+  window.startTimer('virt');
+
+  try {
+    const res = await fetch('/api/virt');
+    const data = await res.json();
+    if (!data.enabled) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">Configure Proxmox VE or libvirt under "virt" in the config file.</div>';
+      return;
+    }
+    if (data.error) {
+      container.innerHTML = '<div class="small" style="color:var(--muted);">' + window.escapeHtml(data.error) + '</div>';
+      return;
+    }
+
+    const virt = data.virt;
+    let html = '';
+    for (const n of virt.nodes) {
+      const state = n.status === 'online' ? '' : ' <span style="color:var(--bad, #ef4444);">' + window.escapeHtml(n.status) + '</span>';
+      html += `<div class="kv"><div class="k"><i class="fas fa-server"></i> ${window.escapeHtml(n.name)}${state}</div><div class="v small">${n.cpu.toFixed(0)}% · ${virtBytes(n.mem)} / ${virtBytes(n.maxMem)}</div></div>`;
+    }
+    html += `<div class="kv"><div class="k">Running</div><div class="v">${virt.running} of ${virt.guests.length}</div></div>`;
+    for (const g of virt.guests) {
+      html += virtGuestRow(g);
+    }
+    if (virt.errors && virt.errors.length) {
+      html += '<div class="small" style="color:var(--muted);" title="' + window.escapeHtml(virt.errors.join('\n')) + '">Some hypervisors are unavailable</div>';
+    }
+    container.innerHTML = html;
+  } catch (err) {
+    if (window.debugError) window.debugError('virt', 'Error loading virtual machines:', err);
+  }
+}
+
+function initVirt() {
+  setTimeout(refreshVirt, 1000);
+  setInterval(function() {
+    if (!window.wsIsConnected || !window.wsIsConnected()) {
+      refreshVirt();
+    }
+  }, window.timers && window.timers.virt ? window.timers.virt.interval : 60000);
+}
+
+window.refreshVirt = refreshVirt;
+window.initVirt = initVirt;
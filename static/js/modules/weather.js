@@ -1,15 +1,73 @@
 // Weather module
 
+// weatherAirItems lists the air quality and pollen that is in the air.
+function weatherAirItems(air, details) {
+  const items = [];
+  items.push('<span style="white-space: nowrap;" title="European AQI (US AQI ' + air.usAqi.toFixed(0) + ')"><i class="fas fa-smog"></i> AQI ' + air.aqi.toFixed(0) + ' ' + window.escapeHtml(air.aqiLevel) + '</span>');
+  if (details) {
+    items.push('<span style="white-space: nowrap;" title="Fine particles (μg/m³)">PM2.5 ' + air.pm25.toFixed(0) + '</span>');
+    items.push('<span style="white-space: nowrap;" title="Coarse particles (μg/m³)">PM10 ' + air.pm10.toFixed(0) + '</span>');
+    items.push('<span style="white-space: nowrap;" title="Ozone (μg/m³)">O₃ ' + air.ozone.toFixed(0) + '</span>');
+  }
+  for (const p of (air.pollen || []).filter(p => details || p.level !== 'low')) {
+    items.push('<span style="white-space: nowrap;" title="' + p.count.toFixed(0) + ' grains/m³"><i class="fas fa-seedling"></i> ' + window.escapeHtml(p.type) + ' ' + window.escapeHtml(p.level) + '</span>');
+  }
+  return items;
+}
+
+// weatherHourlyGraph draws the temperature as a line over the precipitation chance as bars.
+function weatherHourlyGraph(hourly) {
+  const width = 240, height = 40, pad = 4;
+  const hours = hourly.hours;
+  const temps = hours.map(h => h.temperature);
+  const low = Math.min(...temps), high = Math.max(...temps);
+  const slot = width / hours.length;
+  const y = t => pad + (high === low ? 0.5 : (high - t) / (high - low)) * (height - 2 * pad);
+  const bars = hours.map((h, i) => {
+    const barHeight = h.precipitationProb / 100 * height;
+    const at = new Date(h.time).toLocaleString([], {weekday: 'short', hour: '2-digit', minute: '2-digit'});
+    const title = at + ': ' + h.temperature.toFixed(0) + hourly.tempUnit + ', ' + h.precipitationProb.toFixed(0) + '% ' +
+      h.precipitation.toFixed(hourly.precipitationUnit === 'in' ? 2 : 1) + ' ' + hourly.precipitationUnit + ', wind ' + h.windSpeed.toFixed(0) + ' ' + hourly.windUnit +
+      (h.iconDescription ? ', ' + h.iconDescription : '');
+    return '<rect x="' + (i * slot).toFixed(1) + '" y="' + (height - barHeight).toFixed(1) + '" width="' + Math.max(slot - 1, 1).toFixed(1) + '" height="' + height +
+      '" fill="var(--accent)" opacity="' + (h.precipitationProb > 0 ? 0.35 : 0) + '" pointer-events="all"><title>' + window.escapeHtml(title) + '</title></rect>';
+  }).join('');
+  const line = hours.map((h, i) => ((i + 0.5) * slot).toFixed(1) + ',' + y(h.temperature).toFixed(1)).join(' ');
+  return '<span style="white-space: nowrap;">' + low.toFixed(0) + '–' + high.toFixed(0) + window.escapeHtml(hourly.tempUnit) + '</span> ' +
+    '<svg width="' + width + '" height="' + height + '" viewBox="0 0 ' + width + ' ' + height + '" style="vertical-align: middle; max-width: 100%;">' +
+    bars + '<polyline points="' + line + '" fill="none" stroke="var(--txt)" stroke-width="1.5" pointer-events="none"/></svg>';
+}
+
+// refreshWeatherHourly fills the forecast graph of the next 24 hours.
+async function refreshWeatherHourly(query) {
+  const hourlyEl = document.getElementById("weatherHourly");
+  if (!hourlyEl) return;
+  try {
+    const res = await window.bootstrapFetch("/api/weather/hourly" + (query ? query + "&" : "?") + "hours=24", {cache:"no-store"});
+    const j = await res.json();
+    if (j.hours && j.hours.length > 1) {
+      document.getElementById("weatherHourlyData").innerHTML = weatherHourlyGraph(j);
+      hourlyEl.style.display = '';
+    } else {
+      hourlyEl.style.display = 'none';
+    }
+  } catch (err) {
+    hourlyEl.style.display = 'none';
+  }
+}
+
 async function refreshWeather() {
   try {
     // Get saved location from localStorage
     let weatherUrl = "/api/weather";
+    let locationQuery = "";
     let locationName = "";
     try {
       const savedLoc = window.loadFromStorage('weatherLocation');
       if (savedLoc) {
         const loc = typeof savedLoc === 'string' ? JSON.parse(savedLoc) : savedLoc;
-        weatherUrl += "?lat=" + loc.latitude + "&lon=" + loc.longitude;
+        locationQuery = "?lat=" + loc.latitude + "&lon=" + loc.longitude;
+        weatherUrl += locationQuery;
         locationName = loc.name || "";
       }
     } catch (e) {}
@@ -20,8 +78,9 @@ async function refreshWeather() {
       locationEl.textContent = locationName ? "• " + locationName : "";
     }
 
-    const res = await fetch(weatherUrl, {cache:"no-store"});
+    const res = await window.bootstrapFetch(weatherUrl, {cache:"no-store"});
     const j = await res.json();
+    if (window.applyOffline) window.applyOffline('weather', j);
 
     // Now - current weather
     if (j.current) {
@@ -46,7 +105,7 @@ async function refreshWeather() {
           items.push('<span style="white-space: nowrap;"><i class="fas fa-hand-holding" title="Feels like"></i> ' + j.current.feelsLike.toFixed(0) + '°</span>');
         }
         if (j.current.pressure !== undefined) {
-          items.push('<span style="white-space: nowrap;"><i class="fas fa-compress-arrows-alt" title="Pressure"></i> ' + j.current.pressure.toFixed(0) + ' hPa</span>');
+          items.push('<span style="white-space: nowrap;"><i class="fas fa-compress-arrows-alt" title="Pressure"></i> ' + (j.current.pressureUnit ? j.current.pressure.toFixed(2) + ' ' + j.current.pressureUnit : j.current.pressure.toFixed(0) + ' hPa') + '</span>');
         }
         if (j.current.windDirection !== undefined) {
           const dirs = ['N', 'NNE', 'NE', 'ENE', 'E', 'ESE', 'SE', 'SSE', 'S', 'SSW', 'SW', 'WSW', 'W', 'WNW', 'NW', 'NNW'];
@@ -63,7 +122,7 @@ async function refreshWeather() {
           items.push('<span style="white-space: nowrap;"><i class="fas fa-cloud" title="Cloud cover"></i> ' + j.current.cloudCover.toFixed(0) + '%</span>');
         }
         if (j.current.visibility !== undefined) {
-          items.push('<span style="white-space: nowrap;"><i class="fas fa-eye" title="Visibility"></i> ' + j.current.visibility.toFixed(1) + ' km</span>');
+          items.push('<span style="white-space: nowrap;"><i class="fas fa-eye" title="Visibility"></i> ' + j.current.visibility.toFixed(1) + ' ' + (j.current.visibilityUnit || 'km') + '</span>');
         }
         if (j.current.dewPoint !== undefined) {
           items.push('<span style="white-space: nowrap;"><i class="fas fa-droplet" title="Dew point"></i> ' + j.current.dewPoint.toFixed(0) + '°</span>');
@@ -83,6 +142,38 @@ async function refreshWeather() {
       }
     }
 
+    // Nowcast - precipitation for the next two hours
+    const nowcastEl = document.getElementById("weatherNowcast");
+    if (nowcastEl) {
+      const nc = j.nowcast;
+      if (nc && nc.points && nc.points.length) {
+        const peak = Math.max(...nc.points.map(p => p.precipitation), nc.unit === 'in' ? 0.02 : 0.5);
+        const bars = nc.points.map(p => {
+          const h = Math.max(Math.round(p.precipitation / peak * 12), p.precipitation > 0 ? 2 : 1);
+          const at = new Date(p.time).toLocaleTimeString([], {hour: '2-digit', minute: '2-digit'});
+          return '<span title="' + at + ': ' + p.precipitation.toFixed(nc.unit === 'in' ? 2 : 1) + ' ' + window.escapeHtml(nc.unit) + '" style="display:inline-block;width:5px;height:' + h + 'px;margin-right:1px;background:' + (p.precipitation > 0 ? 'var(--accent)' : 'var(--muted)') + ';opacity:' + (p.precipitation > 0 ? 1 : 0.4) + ';"></span>';
+        }).join('');
+        document.getElementById("weatherNowcastData").innerHTML = window.escapeHtml(nc.summary) + ' <span style="white-space: nowrap; vertical-align: baseline;">' + bars + '</span>';
+        nowcastEl.style.display = '';
+      } else {
+        nowcastEl.style.display = 'none';
+      }
+    }
+
+    // Air quality and pollen now
+    const airEl = document.getElementById("weatherAir");
+    if (airEl) {
+      if (j.current && j.current.air) {
+        document.getElementById("weatherAirData").innerHTML = weatherAirItems(j.current.air, true).join(' • ');
+        airEl.style.display = '';
+      } else {
+        airEl.style.display = 'none';
+      }
+    }
+
+    // Hourly forecast graph
+    refreshWeatherHourly(locationQuery);
+
     // Today
     if (j.today) {
       // Use icon from backend if available, fallback to client-side mapping
@@ -117,6 +208,11 @@ async function refreshWeather() {
         if (j.today.uvIndexMax !== undefined && j.current && j.current.uvIndex !== undefined) {
           items.push('<span style="white-space: nowrap;"><i class="fas fa-sun" title="UV Index Max"></i> ' + j.today.uvIndexMax.toFixed(0) + '</span>');
         }
+
+        // Worst air quality and pollen above low
+        if (j.today.air) {
+          items.push(...weatherAirItems(j.today.air, false));
+        }
         
         todayDataEl.innerHTML = items.join(' • ');
       }
@@ -172,6 +268,9 @@ async function refreshWeather() {
         if (j.tomorrow.uvIndexMax !== undefined && j.current && j.current.uvIndex !== undefined) {
           items.push('<span style="white-space: nowrap;"><i class="fas fa-sun" title="UV Index Max"></i> ' + j.tomorrow.uvIndexMax.toFixed(0) + '</span>');
         }
+        if (j.tomorrow.air) {
+          items.push(...weatherAirItems(j.tomorrow.air, false));
+        }
         
         tomorrowDataEl.innerHTML = items.join(' • ');
       }
@@ -5,6 +5,9 @@ let reconnectInterval = null;
 let reconnectAttempts = 0;
 const RECONNECT_DELAY = 2000; // 2 seconds - fixed delay, keep trying forever
 
+// Topics this client is subscribed to (server only sends data for subscribed topics)
+let subscribedTopics = new Set();
+
 // Callbacks
 let onStatusChange = null;
 let onConnect = null;
@@ -18,7 +21,7 @@ function connect() {
   }
   
   const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-  const wsUrl = `${protocol}//${window.location.host}/ws`;
+  const wsUrl = `${protocol}//${window.location.host}${window.BASE_PATH}/ws`;
   
   if (window.debugLog) window.debugLog('websocket', 'Connecting to:', wsUrl);
   
@@ -33,6 +36,9 @@ function connect() {
         clearTimeout(reconnectInterval);
         reconnectInterval = null;
       }
+      // Subscribe to topics for the enabled modules
+      subscribedTopics = new Set();
+      subscribe(getDefaultTopics());
       if (onConnect) onConnect();
       if (onStatusChange) onStatusChange('online');
     };
@@ -44,6 +50,10 @@ function connect() {
         
         if (data.type === 'status' && data.status === 'online') {
           if (onStatusChange) onStatusChange('online', data);
+        } else if (data.type === 'subscribed') {
+          if (window.debugLog) window.debugLog('websocket', 'Subscribed topics:', data.topics);
+        } else if (data.type === 'error') {
+          if (window.debugError) window.debugError('websocket', 'Server error:', data.error);
         } else if (data.type === 'ping') {
           // Ping received, connection is alive
           if (onStatusChange) onStatusChange('online', data);
@@ -52,6 +62,38 @@ function connect() {
           if (window.onWebSocketUpdate) {
             window.onWebSocketUpdate('system', data);
           }
+        } else if (data.type === 'mqtt') {
+          // New value on a subscribed MQTT topic
+          if (window.onMqttUpdate) window.onMqttUpdate(data);
+        } else if (data.type === 'module-health') {
+          // A module became degraded or recovered
+          if (window.onModuleHealth) window.onModuleHealth(data);
+        } else if (data.type === 'connectivity') {
+          // The server's internet connection went down or came back
+          if (window.onConnectivity) window.onConnectivity(data);
+        } else if (data.type === 'theme-change') {
+          // Scheduled switch between the light and dark scheme
+          if (window.onThemeChange) window.onThemeChange(data);
+        } else if (data.type === 'config-reload') {
+          // The server applied a changed config file
+          if (window.onConfigReload) window.onConfigReload(data);
+        } else if (data.type === 'settings') {
+          // The general settings were changed
+          if (window.onSettingsChange) window.onSettingsChange(data.settings);
+        } else if (data.type === 'public-ip') {
+          // The public IP address changed
+          if (window.onPublicIPChange) window.onPublicIPChange(data);
+        } else if (data.type === 'note') {
+          // A note was added, changed or deleted on another device
+          if (window.onNoteChange) window.onNoteChange(data);
+        } else if (data.type === 'drop') {
+          // Text or a file was shared or deleted through the drop
+          if (window.refreshDrop) window.refreshDrop();
+        } else if (data.type === 'banner') {
+          // Banner raised by an incoming webhook
+          if (window.addBanner) window.addBanner(data.banner);
+        } else if (data.type === 'banner-dismiss') {
+          if (window.removeBanner) window.removeBanner(data.id);
         } else if (data.type === 'refresh') {
           // Refresh notification for a module - module will fetch its own data
           if (window.debugLog) window.debugLog('websocket', 'Refresh notification received for module:', data.module);
@@ -66,6 +108,8 @@ function connect() {
         } else if (data.type === 'storage-update') {
           // Storage update notification - fetch updated data from backend
           if (window.debugLog) window.debugLog('websocket', 'Storage update received for:', data.key);
+          // Keys of other dashboard profiles are ignored, our own are unprefixed
+          if (window.storageKeyFromBackend) data.key = window.storageKeyFromBackend(data.key);
           if (data.key && window.syncFromBackend) {
             window.syncFromBackend(data.key).then(updated => {
               if (updated && window.debugLog) {
@@ -92,9 +136,10 @@ function connect() {
                       window.applyModuleVisibility();
                     }
                   }
+                  refreshSubscriptions();
                 }
                 // Layout config
-                if (data.key === 'layoutConfig' || data.key === 'moduleOrder') {
+                if (data.key === window.layoutStorageKey || data.key === 'moduleOrder') {
                   if (window.loadLayoutConfig) {
                     window.loadLayoutConfig();
                     if (window.renderLayout) {
@@ -102,6 +147,10 @@ function connect() {
                     }
                   }
                 }
+                // Pages of the profile
+                if (data.key === 'layoutPages' && window.renderPageTabs) {
+                  window.renderPageTabs();
+                }
                 // Graph settings
                 if (data.key === 'showFullBars' || data.key === 'colorizeBackground' || data.key === 'minBarWidth') {
                   if (window.initGraphs) {
@@ -214,6 +263,42 @@ function connect() {
   }
 }
 
+// Returns the topics needed by the currently enabled modules
+function getDefaultTopics() {
+  const enabled = (id) => !window.moduleConfig || !window.moduleConfig[id] || window.moduleConfig[id].enabled !== false;
+  const topics = ['storage', 'timers'];
+  if (['status', 'cpu', 'ram', 'disk'].some(enabled)) topics.push('system');
+  if (enabled('monitoring')) topics.push('monitors');
+  if (enabled('rss')) topics.push('rss');
+  if (enabled('github')) topics.push('github');
+  if (enabled('mqtt')) topics.push('mqtt');
+  return topics;
+}
+
+function sendTopics(type, topics) {
+  if (!isConnected() || !topics.length) return;
+  ws.send(JSON.stringify({ type: type, topics: topics }));
+}
+
+function subscribe(topics) {
+  const added = topics.filter(t => !subscribedTopics.has(t));
+  added.forEach(t => subscribedTopics.add(t));
+  sendTopics('subscribe', added);
+}
+
+function unsubscribe(topics) {
+  const removed = topics.filter(t => subscribedTopics.has(t));
+  removed.forEach(t => subscribedTopics.delete(t));
+  sendTopics('unsubscribe', removed);
+}
+
+// Re-sync subscriptions after module visibility changes
+function refreshSubscriptions() {
+  const wanted = new Set(getDefaultTopics());
+  unsubscribe([...subscribedTopics].filter(t => !wanted.has(t)));
+  subscribe([...wanted]);
+}
+
 function disconnect() {
   if (reconnectInterval) {
     clearTimeout(reconnectInterval);
@@ -237,6 +322,9 @@ window.onWebSocketUpdate = null;
 window.wsConnect = connect;
 window.wsDisconnect = disconnect;
 window.wsIsConnected = isConnected;
+window.wsSubscribe = subscribe;
+window.wsUnsubscribe = unsubscribe;
+window.wsRefreshSubscriptions = refreshSubscriptions;
 window.wsOnStatusChange = function(callback) { onStatusChange = callback; };
 window.wsOnConnect = function(callback) { onConnect = callback; };
 window.wsOnDisconnect = function(callback) { onDisconnect = callback; };
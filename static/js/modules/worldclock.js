@@ -1,4 +1,5 @@
-// World clock: local time + user-defined IANA zones (stored in localStorage).
+// World clock: local time + user-defined IANA zones (stored in localStorage). Offsets come from
+// /api/clocks, so they follow the server's zone database; the browser's is the fallback.
 
 (function() {
   'use strict';
@@ -84,6 +85,67 @@
 
   let worldClockZones = [];
   let tickTimer = null;
+  let refreshTimer = null;
+
+  // From /api/clocks: the clock of each zone by name, and of this browser's zone
+  let serverClocks = {};
+  let serverLocal = null;
+  let serverRefreshPending = false;
+  // From /api/clocks/zones
+  let serverZoneNames = [];
+
+  async function refreshServerClocks() {
+    try {
+      const tz = encodeURIComponent(localTimeZoneId());
+      const res = await fetch('/api/clocks?tz=' + tz, {method: 'POST', body: JSON.stringify({zones: worldClockZones})});
+      const data = await res.json();
+      if (data.error) return;
+      serverClocks = {};
+      (data.clocks || []).forEach(function(c) {
+        if (!c.error) serverClocks[c.timeZone] = c;
+      });
+      serverLocal = data.local || null;
+      renderWorldClockModule();
+    } catch (e) {
+      if (window.debugError) window.debugError('worldclock', 'Error loading clocks:', e);
+    } finally {
+      serverRefreshPending = false;
+    }
+  }
+
+  /** Offset of a server clock now; after its next change the new offset applies until the next refresh. */
+  function serverOffset(clock, now) {
+    if (clock.nextChange && now.getTime() >= Date.parse(clock.nextChange.at)) {
+      if (!serverRefreshPending) {
+        serverRefreshPending = true;
+        refreshServerClocks();
+      }
+      return clock.nextChange;
+    }
+    return clock;
+  }
+
+  function nextChangeNote(clock) {
+    if (!clock || !clock.nextChange) return '';
+    const at = new Date(clock.nextChange.at);
+    return ' — changes to ' + clock.nextChange.offset + ' (' + clock.nextChange.abbrev + ') on ' + at.toLocaleString();
+  }
+
+  /** Row values of a zone from the server's offsets, or null without them. */
+  function serverRowValues(now, tz) {
+    const clock = serverClocks[tz];
+    if (!clock || !serverLocal) return null;
+    const z = serverOffset(clock, now);
+    const l = serverOffset(serverLocal, now);
+    const zoneTime = new Date(now.getTime() + z.offsetSeconds * 1000);
+    const localTime = new Date(now.getTime() + l.offsetSeconds * 1000);
+    return {
+      time: formatHMS(zoneTime, 'UTC'),
+      offset: z.offset,
+      note: dayDiffLabel(calendarDayDiffDays(localTime.toISOString().slice(0, 10), zoneTime.toISOString().slice(0, 10))),
+      change: nextChangeNote(clock)
+    };
+  }
 
   function loadZones() {
     try {
@@ -115,6 +177,7 @@
     } catch (e) {
       if (window.debugError) window.debugError('worldclock', 'save failed', e);
     }
+    refreshServerClocks();
   }
 
   function loadTwoColumnsPref() {
@@ -202,7 +265,7 @@
   function resolveCanonicalTimeZone(raw) {
     const t = (raw || '').trim();
     if (!t) return null;
-    if (isValidIANATimeZone(t)) return t;
+    if (isValidIANATimeZone(t) || serverZoneNames.includes(t)) return t;
     const underscored = t.replace(/\s+/g, '_');
     if (underscored !== t && isValidIANATimeZone(underscored)) return underscored;
     const key = underscored.toLowerCase();
@@ -242,9 +305,10 @@
   }
 
   function dateDiffVsLocalLabel(now, tz) {
-    const local = ymdLocal(now);
-    const remote = ymdInZone(now, tz);
-    const diff = calendarDayDiffDays(local, remote);
+    return dayDiffLabel(calendarDayDiffDays(ymdLocal(now), ymdInZone(now, tz)));
+  }
+
+  function dayDiffLabel(diff) {
     if (diff === 0) return '';
     if (diff > 0) {
       return '+' + diff + ' day' + (diff === 1 ? '' : 's') + ' vs you';
@@ -419,7 +483,7 @@
     const wrapClass = 'worldclock-rows' + (worldClockTwoColumns ? ' worldclock-two-cols' : '');
 
     const localLbl = getLocalClockLabel();
-    const localTip = buildTooltip(now, localTz, localLbl);
+    const localTip = buildTooltip(now, localTz, localLbl) + nextChangeNote(serverLocal);
 
     if (worldClockZones.length === 0) {
       container.innerHTML =
@@ -436,6 +500,11 @@
     worldClockZones.forEach(function(z) {
       const tz = z.timeZone.trim();
       const label = (z.label && String(z.label).trim()) || tz.split('/').pop().replace(/_/g, ' ');
+      const server = serverRowValues(now, tz);
+      if (server) {
+        inner += rowHtml(label, server.time, server.offset, server.note, buildTooltip(now, tz, label) + server.change);
+        return;
+      }
       const note = dateDiffVsLocalLabel(now, tz);
       const off = utcOffsetLabel(now, tz);
       const tip = buildTooltip(now, tz, label);
@@ -596,12 +665,24 @@
     });
   }
 
-  function fillTzDatalist() {
+  async function fillTzDatalist() {
     const dl = document.getElementById('worldclock-tz-suggestions');
     if (!dl) return;
     dl.innerHTML = COMMON_TIMEZONES.map(function(tz) {
       return '<option value="' + tz + '"></option>';
     }).join('');
+    // Every zone the server knows, when it can list them
+    try {
+      const data = await (await fetch('/api/clocks/zones')).json();
+      if (Array.isArray(data.zones) && data.zones.length) {
+        serverZoneNames = data.zones;
+        dl.innerHTML = serverZoneNames.map(function(tz) {
+          return '<option value="' + attrSafe(tz) + '"></option>';
+        }).join('');
+      }
+    } catch (e) {
+      if (window.debugError) window.debugError('worldclock', 'Error loading time zones:', e);
+    }
   }
 
   function openWorldClockPrefs() {
@@ -688,6 +769,9 @@
 
     if (tickTimer) clearInterval(tickTimer);
     tickTimer = setInterval(renderWorldClockModule, 1000);
+    refreshServerClocks();
+    if (refreshTimer) clearInterval(refreshTimer);
+    refreshTimer = setInterval(refreshServerClocks, 600000);
   }
 
   window.initWorldClock = initWorldClock;
@@ -13,6 +13,7 @@ function refreshPreferenceListsOnOpen() {
   if (window.renderCalendarModuleList) window.renderCalendarModuleList();
   if (window.renderTodoModuleList) window.renderTodoModuleList();
   if (window.initICSCalendars) window.initICSCalendars();
+  if (window.renderBirthdaysStatus) window.renderBirthdaysStatus();
   if (window.loadServerConfigs) window.loadServerConfigs();
   renderModuleList();
   initDebugSettings();
@@ -107,6 +108,8 @@ function initPreferencesModal() {
       // Render layout editor when layout tab is opened
       if (tabName === 'layout' && window.layoutSystem && window.layoutSystem.renderLayoutEditor) {
         window.layoutSystem.renderLayoutEditor();
+        window.layoutSystem.renderLayoutHistory();
+        window.layoutSystem.renderPageTabs();
       }
 
       // Render events list when calendar tab is opened
@@ -160,6 +163,46 @@ function initPreferencesModal() {
   // Debug settings will be initialized when debug tab is opened
 }
 
+// Stores the theme on the server too, which renders the page with it from the first paint
+async function saveCurrentTheme(template, scheme) {
+  const query = window.currentProfile ? '?profile=' + encodeURIComponent(window.currentProfile) : '';
+  try {
+    await fetch('/api/theme/current' + query, {
+      method: 'PUT',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ template: template, scheme: scheme })
+    });
+  } catch (e) {
+    if (window.debugError) window.debugError('themes', 'Error saving current theme:', e);
+  }
+}
+
+// Scheduled light/dark switch pushed by the server: the scheme is applied in place, another
+// template needs a reload
+function applyThemeChange(data) {
+  if ((data.profile || 'default') !== (window.currentProfile || 'default')) return;
+  const root = document.documentElement;
+  const template = data.template || root.getAttribute('data-template');
+  window.saveToStorage('scheme', data.scheme);
+  if (template !== root.getAttribute('data-template')) {
+    window.saveToStorage('template', template);
+    location.reload();
+    return;
+  }
+  fetch(`/api/theme?template=${encodeURIComponent(template)}&scheme=${encodeURIComponent(data.scheme)}`)
+    .then(res => res.text())
+    .then(css => {
+      const style = document.getElementById('theme-css');
+      if (style) style.textContent = css;
+      root.setAttribute('data-scheme', data.scheme);
+      const schemeSelect = document.getElementById('pref-scheme');
+      if (schemeSelect) schemeSelect.value = data.scheme;
+    })
+    .catch(e => {
+      if (window.debugError) window.debugError('themes', 'Error applying scheduled scheme:', e);
+    });
+}
+
 function initThemeSelection() {
   const templateSelect = document.getElementById('pref-template');
   const schemeSelect = document.getElementById('pref-scheme');
@@ -170,22 +213,125 @@ function initThemeSelection() {
 
   if (templateSelect) {
     templateSelect.value = currentTemplate;
-    templateSelect.addEventListener('change', (e) => {
+    templateSelect.addEventListener('change', async (e) => {
       const newTemplate = e.target.value;
       window.saveToStorage('template', newTemplate);
       // Clear scheme when template changes (schemes are template-specific)
       localStorage.removeItem('scheme'); // Use direct removeItem for removal
+      await saveCurrentTheme(newTemplate, '');
       // Reload to apply the new template
       location.reload();
     });
   }
 
   if (schemeSelect) {
-    schemeSelect.addEventListener('change', (e) => {
+    schemeSelect.addEventListener('change', async (e) => {
       window.saveToStorage('scheme', e.target.value);
+      await saveCurrentTheme(document.documentElement.getAttribute('data-template'), e.target.value);
       location.reload();
     });
   }
+
+  // User themes from the server's themes directory
+  const uploadBtn = document.getElementById('themeUploadBtn');
+  const uploadFile = document.getElementById('themeUploadFile');
+  const reloadBtn = document.getElementById('themeReloadBtn');
+  if (uploadBtn && uploadFile) {
+    uploadBtn.addEventListener('click', () => uploadFile.click());
+    uploadFile.addEventListener('change', async () => {
+      const file = uploadFile.files[0];
+      uploadFile.value = '';
+      if (!file) return;
+      try {
+        const body = await file.text();
+        let data = await (await fetch('/api/theme/upload', {method: 'POST', headers: {'Content-Type': 'text/css'}, body})).json();
+        if (data.error && data.error.indexOf('already installed') >= 0 &&
+            await window.popup.confirm(data.error.replace(/ \(overwrite.*\)$/, '') + '. Replace it?', 'Upload Theme')) {
+          data = await (await fetch('/api/theme/upload?overwrite=1', {method: 'POST', headers: {'Content-Type': 'text/css'}, body})).json();
+        }
+        if (data.error) {
+          await window.popup.alert(data.error, 'Upload Failed');
+          return;
+        }
+        updateTemplateOptions(data.templates);
+        await window.popup.alert(data.success + ' with ' + data.schemes.length + ' scheme(s). Pick it under Theme.', 'Upload Theme');
+      } catch (e) {
+        if (window.debugError) window.debugError('themes', 'Error uploading theme:', e);
+        await window.popup.alert('Unable to upload theme', 'Error');
+      }
+    });
+  }
+  if (reloadBtn) {
+    reloadBtn.addEventListener('click', async () => {
+      try {
+        const data = await (await fetch('/api/theme/reload', {method: 'POST'})).json();
+        if (data.error) {
+          await window.popup.alert(data.error, 'Reload Failed');
+          return;
+        }
+        updateTemplateOptions(data.templates);
+        let message = data.templates.length + ' theme(s) loaded, ' + data.user.length + ' from the themes directory.';
+        const failed = Object.keys(data.errors || {});
+        if (failed.length) message += ' Skipped: ' + failed.map(f => f + ' (' + data.errors[f] + ')').join(', ');
+        await window.popup.alert(message, 'Reload Themes');
+      } catch (e) {
+        if (window.debugError) window.debugError('themes', 'Error reloading themes:', e);
+        await window.popup.alert('Unable to reload themes', 'Error');
+      }
+    });
+  }
+
+  // Color schemes generated from an accent color, added to the current template
+  const genBtn = document.getElementById('schemeGenBtn');
+  if (genBtn) {
+    genBtn.addEventListener('click', async () => {
+      const display = document.getElementById('schemeGenName').value.trim();
+      const request = {
+        template: currentTemplate,
+        accent: document.getElementById('schemeGenAccent').value,
+        mode: document.getElementById('schemeGenMode').value,
+        name: display.toLowerCase().replace(/[^a-z0-9_-]+/g, '-').replace(/^-+|-+$/g, ''),
+        display
+      };
+      try {
+        const post = () => fetch('/api/theme/generate', {method: 'POST', headers: {'Content-Type': 'application/json'}, body: JSON.stringify(request)}).then(r => r.json());
+        let data = await post();
+        if (data.error && data.error.indexOf('already exists') >= 0 &&
+            await window.popup.confirm(data.error.replace(/ \(overwrite.*\)$/, '') + '. Replace it?', 'New Scheme')) {
+          request.overwrite = true;
+          data = await post();
+        }
+        if (data.error) {
+          await window.popup.alert(data.error, 'New Scheme Failed');
+          return;
+        }
+        // Replace cached copies of the scheme list and a replaced scheme's CSS before switching
+        await fetch(`/api/schemes?template=${encodeURIComponent(data.template)}`, {cache: 'reload'});
+        await fetch(`/api/theme?template=${encodeURIComponent(data.template)}&scheme=${encodeURIComponent(data.scheme)}`, {cache: 'reload'});
+        window.saveToStorage('scheme', data.scheme);
+        await saveCurrentTheme(data.template, data.scheme);
+        location.reload();
+      } catch (e) {
+        if (window.debugError) window.debugError('themes', 'Error generating scheme:', e);
+        await window.popup.alert('Unable to create scheme', 'Error');
+      }
+    });
+  }
+}
+
+// Rebuild the theme dropdown after the server's themes changed
+function updateTemplateOptions(templates) {
+  const templateSelect = document.getElementById('pref-template');
+  if (!templateSelect || !Array.isArray(templates)) return;
+  const current = templateSelect.value;
+  templateSelect.innerHTML = '';
+  templates.forEach(name => {
+    const option = document.createElement('option');
+    option.value = name;
+    option.textContent = name;
+    templateSelect.appendChild(option);
+  });
+  templateSelect.value = current;
 }
 
 // Populate scheme dropdown for a specific template
@@ -325,65 +471,74 @@ function populateSchemeDropdown() {
 }
 
 function initGeneralSettings() {
-  // Page title
+  // Page title and formats are server settings, shared by every browser
   const titleInput = document.getElementById('pref-title');
   const resetTitleBtn = document.getElementById('resetTitleBtn');
-  const defaultTitle = 'LAN Index';
+  const settingSelects = document.querySelectorAll('#tab-general [data-setting]');
+  let effectiveTitle = document.title;
 
-  if (titleInput) {
-    // Load saved title or use default
-    const savedTitle = window.loadFromStorage('pageTitle');
-    titleInput.value = savedTitle || defaultTitle;
-
-    // Helper function to apply title
-    const applyTitle = (title) => {
-      if (!title) title = defaultTitle;
-      document.title = title;
-      const headerTitle = document.querySelector('.h-title');
-      if (headerTitle) {
-        headerTitle.textContent = title;
-      }
-      // Also update window function if available
-      if (window.applyPageTitle) {
-        window.applyPageTitle(title);
+  const applyTitle = (title) => applyPageTitle(title || effectiveTitle);
+
+  const showSettings = (data) => {
+    if (!data || !data.settings) return;
+    effectiveTitle = data.effective.title || effectiveTitle;
+    if (titleInput && document.activeElement !== titleInput) {
+      titleInput.value = effectiveTitle;
+    }
+    settingSelects.forEach(select => {
+      select.value = data.settings[select.dataset.setting] || '';
+    });
+  };
+  window.showGeneralSettings = showSettings;
+
+  const saveSettings = async (changes) => {
+    try {
+      const res = await fetch('/api/settings', {
+        method: 'POST',
+        headers: {'Content-Type': 'application/json'},
+        body: JSON.stringify(changes)
+      });
+      const data = await res.json();
+      if (data.error) {
+        await window.popup.alert(data.error, 'Settings Not Saved');
+        return;
       }
-    };
+      if (window.onSettingsChange) window.onSettingsChange(data);
+    } catch (err) {
+      await window.popup.alert('Unable to save settings', 'Error');
+    }
+  };
 
-    // Apply title on load
-    applyTitle(savedTitle || defaultTitle);
+  fetch('/api/settings')
+    .then(res => res.json())
+    .then(showSettings)
+    .catch(err => console.error('Error loading settings:', err));
+
+  if (titleInput) {
+    titleInput.value = effectiveTitle;
 
-    // Save on change
     titleInput.addEventListener('change', () => {
-      const title = titleInput.value.trim() || defaultTitle;
-      titleInput.value = title;
-      window.saveToStorage('pageTitle', title);
-      applyTitle(title);
+      saveSettings({title: titleInput.value.trim()});
     });
 
-    // Also update on input (for real-time preview)
+    // Real-time preview
     titleInput.addEventListener('input', () => {
-      const title = titleInput.value.trim() || defaultTitle;
-      applyTitle(title);
+      applyTitle(titleInput.value.trim());
     });
   }
 
   if (resetTitleBtn) {
     resetTitleBtn.addEventListener('click', () => {
-      if (titleInput) {
-        titleInput.value = defaultTitle;
-        window.saveToStorage('pageTitle', defaultTitle);
-        document.title = defaultTitle;
-        const headerTitle = document.querySelector('.h-title');
-        if (headerTitle) {
-          headerTitle.textContent = defaultTitle;
-        }
-        if (window.applyPageTitle) {
-          window.applyPageTitle(defaultTitle);
-        }
-      }
+      saveSettings({title: ''});
     });
   }
 
+  settingSelects.forEach(select => {
+    select.addEventListener('change', () => {
+      saveSettings({[select.dataset.setting]: select.value});
+    });
+  });
+
   // Min bar width
   const minBarWidthInput = document.getElementById('pref-min-bar-width');
   if (minBarWidthInput) {
@@ -846,6 +1001,36 @@ function initWeatherSettings() {
       toggleWeatherApiKey.querySelector('i').className = isPassword ? 'fas fa-eye-slash' : 'fas fa-eye';
     });
   }
+
+  // Units, time zone and locale of this profile, sent with every API request
+  const unitsSelect = document.getElementById('pref-units');
+  const timeZoneInput = document.getElementById('pref-time-zone');
+  const localeInput = document.getElementById('pref-locale');
+  if (unitsSelect && timeZoneInput && localeInput) {
+    const localePrefs = window.loadFromStorage('localePrefs') || {};
+    unitsSelect.value = localePrefs.units || '';
+    timeZoneInput.value = localePrefs.timeZone || '';
+    localeInput.value = localePrefs.locale || '';
+    const saveLocalePrefs = async () => {
+      const timeZone = timeZoneInput.value.trim();
+      if (timeZone) {
+        try {
+          new Intl.DateTimeFormat('en', { timeZone: timeZone });
+        } catch (e) {
+          await window.popup.alert('Unknown time zone ' + timeZone, 'Error');
+          return;
+        }
+      }
+      window.saveToStorage('localePrefs', {
+        units: unitsSelect.value,
+        timeZone: timeZone,
+        locale: localeInput.value.trim()
+      });
+      if (window.refreshWeather) window.refreshWeather();
+      if (window.renderUpcomingEvents) window.renderUpcomingEvents();
+    };
+    [unitsSelect, timeZoneInput, localeInput].forEach(el => el.addEventListener('change', saveLocalePrefs));
+  }
 }
 
 function initSearchSubTabs() {
@@ -977,6 +1162,118 @@ function initSearchSettings() {
   if (window.renderSearchEngines) {
     window.renderSearchEngines();
   }
+
+  // User-defined search engines
+  const customEngineAddBtn = document.getElementById('customEngineAddBtn');
+  if (customEngineAddBtn) {
+    customEngineAddBtn.addEventListener('click', addCustomEngine);
+  }
+
+  // Imported bookmarks
+  const importBookmarksBtn = document.getElementById('importBookmarksBtn');
+  const importBookmarksFile = document.getElementById('importBookmarksFile');
+  const clearBookmarksBtn = document.getElementById('clearBookmarksBtn');
+  if (importBookmarksBtn && importBookmarksFile) {
+    importBookmarksBtn.addEventListener('click', () => importBookmarksFile.click());
+    importBookmarksFile.addEventListener('change', async () => {
+      const file = importBookmarksFile.files[0];
+      importBookmarksFile.value = '';
+      if (!file) return;
+      try {
+        const data = await (await fetch('/api/bookmarks/import', {method: 'POST', body: await file.text()})).json();
+        if (data.error) {
+          await window.popup.alert(data.error, 'Import Failed');
+          return;
+        }
+        const r = data.result;
+        await window.popup.alert(`Found ${r.found} bookmark(s): ${r.added} added, ${r.updated} updated.`, 'Import Bookmarks');
+      } catch (e) {
+        if (window.debugError) window.debugError('bookmarks', 'Error importing bookmarks:', e);
+        await window.popup.alert('Unable to import bookmarks', 'Error');
+      }
+      refreshImportedBookmarksCount();
+    });
+  }
+  if (clearBookmarksBtn) {
+    clearBookmarksBtn.addEventListener('click', async () => {
+      if (!await window.popup.confirm('Remove all imported bookmarks?', 'Bookmarks')) return;
+      try {
+        await fetch('/api/bookmarks/import', {method: 'DELETE'});
+      } catch (e) {
+        if (window.debugError) window.debugError('bookmarks', 'Error removing bookmarks:', e);
+      }
+      refreshImportedBookmarksCount();
+    });
+  }
+  refreshImportedBookmarksCount();
+}
+
+function renderCustomEngines() {
+  const list = document.getElementById('customEnginesList');
+  if (!list) return;
+  const custom = (window.engines || []).filter(e => e.custom);
+  list.innerHTML = custom.map(e => `
+    <div class="pref-row">
+      <label><i class="${window.escapeHtml(e.icon)}" style="color:var(--accent);"></i> ${window.escapeHtml(e.name)}</label>
+      <span class="small mono" style="flex:1; overflow:hidden; text-overflow:ellipsis;">${window.escapeHtml(e.url)}</span>
+      <span class="small mono" style="width:80px;">${e.bang ? '!' + window.escapeHtml(e.bang) : ''}</span>
+      <button class="btn-small" data-engine-id="${window.escapeHtml(e.id)}" title="Delete"><i class="fas fa-trash"></i></button>
+    </div>`).join('');
+  list.querySelectorAll('button[data-engine-id]').forEach(btn => {
+    btn.addEventListener('click', async () => {
+      const engine = custom.find(e => e.id === btn.dataset.engineId);
+      if (!engine || !await window.popup.confirm(`Delete the search engine "${engine.name}"?`, 'Custom Engines')) return;
+      try {
+        await fetch('/api/search-engines/delete', {method: 'POST', body: JSON.stringify({id: engine.id})});
+      } catch (e) {
+        if (window.debugError) window.debugError('search', 'Error deleting search engine:', e);
+      }
+      await reloadSearchEngines();
+    });
+  });
+}
+
+async function addCustomEngine() {
+  const name = document.getElementById('customEngineName');
+  const url = document.getElementById('customEngineUrl');
+  const bang = document.getElementById('customEngineBang');
+  try {
+    const data = await (await fetch('/api/search-engines/add', {
+      method: 'POST',
+      body: JSON.stringify({name: name.value, url: url.value, bang: bang.value})
+    })).json();
+    if (data.error) {
+      await window.popup.alert(data.error, 'Custom Engines');
+      return;
+    }
+    // New engines start enabled
+    const enabled = window.loadFromStorage('enabledSearchEngines');
+    if (Array.isArray(enabled) && !enabled.includes(data.engine.name)) {
+      enabled.push(data.engine.name);
+      window.saveToStorage('enabledSearchEngines', enabled);
+    }
+    name.value = url.value = bang.value = '';
+  } catch (e) {
+    if (window.debugError) window.debugError('search', 'Error adding search engine:', e);
+  }
+  await reloadSearchEngines();
+}
+
+async function reloadSearchEngines() {
+  if (window.loadSearchEngines) await window.loadSearchEngines();
+  renderSearchEngines();
+  if (window.renderEngines) window.renderEngines();
+}
+
+async function refreshImportedBookmarksCount() {
+  const el = document.getElementById('importedBookmarksCount');
+  if (!el) return;
+  try {
+    const data = await (await fetch('/api/bookmarks')).json();
+    el.textContent = data.imported ? `${data.imported} imported.` : '';
+  } catch (e) {
+    el.textContent = '';
+  }
 }
 
 function renderSearchEngines() {
@@ -994,6 +1291,7 @@ function renderSearchEngines() {
   }
 
   container.innerHTML = '';
+  renderCustomEngines();
 
   // Load enabled engines from localStorage - DO NOT auto-add new ones
   let enabledEngines = [];
@@ -1029,7 +1327,8 @@ function renderSearchEngines() {
     "media": "Media",
     "shopping": "Shopping",
     "maps": "Maps",
-    "development": "Development"
+    "development": "Development",
+    "custom": "Custom"
   };
 
   const enginesByCategory = {};
@@ -1470,7 +1769,7 @@ window.renderSMBIOSModuleList = renderSMBIOSModuleList;
 let debugSettingsInitialized = false;
 
 function initDebugSettings() {
-  const debugModules = ['sw', 'network', 'websocket', 'search', 'app', 'core', 'system', 'weather', 'github', 'rss', 'layout', 'preferences', 'config', 'calendar', 'todo', 'quicklinks', 'timer', 'bookmarks', 'worldclock'];
+  const debugModules = ['sw', 'network', 'websocket', 'search', 'app', 'core', 'system', 'weather', 'github', 'rss', 'layout', 'preferences', 'config', 'calendar', 'todo', 'quicklinks', 'timer', 'bookmarks', 'worldclock', 'favicon'];
 
   // Load saved debug preferences
   try {
@@ -1544,6 +1843,7 @@ function applyPageTitle(title) {
 window.initPreferencesModal = initPreferencesModal;
 window.renderModuleList = renderModuleList;
 window.applyPageTitle = applyPageTitle;
+window.onThemeChange = applyThemeChange;
 window.openPreferencesModal = openPreferencesModal;
 window.closePreferencesModal = closePreferencesModal;
 window.openPreferencesTab = function(tabName, afterOpen) {
@@ -135,6 +135,9 @@ setInterval(() => {
   });
 }, 2000);
 
+// Sub-path the dashboard is served under (basePath config), taken from the registration scope
+const BASE_PATH = new URL(self.registration.scope).pathname.replace(/\/$/, '');
+
 // Assets to cache immediately on install (excluding main page - it uses network-first)
 const STATIC_ASSETS = [
   '/static/js/core.js',
@@ -153,6 +156,30 @@ const STATIC_ASSETS = [
   '/static/js/modules/snmp.js',
   '/static/js/modules/calendar.js',
   '/static/js/modules/todo.js',
+  '/static/js/modules/notes.js',
+  '/static/js/modules/drop.js',
+  '/static/js/modules/countdown.js',
+  '/static/js/modules/banners.js',
+  '/static/js/modules/presence.js',
+  '/static/js/modules/guestwifi.js',
+  '/static/js/modules/router.js',
+  '/static/js/modules/virt.js',
+  '/static/js/modules/ups.js',
+  '/static/js/modules/climate.js',
+  '/static/js/modules/astro.js',
+  '/static/js/modules/garden.js',
+  '/static/js/modules/changelog.js',
+  '/static/js/modules/usage.js',
+  '/static/js/modules/shares.js',
+  '/static/js/modules/oob.js',
+  '/static/js/modules/command.js',
+  '/static/js/modules/tools.js',
+  '/static/js/modules/embed.js',
+  '/static/js/modules/health.js',
+  '/static/js/modules/connectivity.js',
+  '/static/js/modules/mqtt.js',
+  '/static/js/modules/quotes.js',
+  '/static/js/modules/currency.js',
   '/static/js/modules/config.js',
 ];
 
@@ -162,7 +189,7 @@ self.addEventListener('install', (event) => {
   event.waitUntil(
     caches.open(STATIC_CACHE_NAME).then((cache) => {
       debugLog('sw', '[Service Worker] Caching static assets');
-      return cache.addAll(STATIC_ASSETS).catch((err) => {
+      return cache.addAll(STATIC_ASSETS.map((asset) => BASE_PATH + asset)).catch((err) => {
         if (isDebugEnabled('sw')) {
           console.warn('[Service Worker] Failed to cache some assets:', err);
         }
@@ -199,6 +226,7 @@ self.addEventListener('activate', (event) => {
 self.addEventListener('fetch', (event) => {
   const { request } = event;
   const url = new URL(request.url);
+  const path = BASE_PATH && url.pathname.startsWith(BASE_PATH + '/') ? url.pathname.slice(BASE_PATH.length) : url.pathname;
 
   debugLog('sw', 'Fetch intercepted:', request.method, url.pathname, request.mode);
 
@@ -209,7 +237,7 @@ self.addEventListener('fetch', (event) => {
   }
 
   // For navigation requests, try network first with timeout, fallback to cache if offline
-  if (request.mode === 'navigate' || url.pathname === '/') {
+  if (request.mode === 'navigate' || path === '/') {
     debugLog('sw', 'Handling navigation request');
     const fetchPromise = fetch(request).catch(err => {
       debugLog('sw', 'Navigation fetch error:', err.message);
@@ -256,13 +284,13 @@ self.addEventListener('fetch', (event) => {
   }
 
   // Don't intercept API requests - let them go through normally
-  if (url.pathname.startsWith('/api/')) {
+  if (path.startsWith('/api/')) {
     debugLog('sw', 'Skipping API request:', url.pathname);
     return;
   }
 
   // Only cache static assets (JS, CSS files in /static/)
-  if (!url.pathname.startsWith('/static/')) {
+  if (!path.startsWith('/static/')) {
     debugLog('sw', 'Skipping non-static request:', url.pathname);
     return;
   }
@@ -293,3 +321,43 @@ self.addEventListener('fetch', (event) => {
   // This ensures the browser handles the request naturally
 });
 
+
+// Push event - show notifications sent by the server (e.g. monitor alerts)
+self.addEventListener('push', (event) => {
+  let data = {};
+  try {
+    data = event.data ? event.data.json() : {};
+  } catch (e) {
+    data = { title: 'Homepage', body: event.data ? event.data.text() : '' };
+  }
+  debugLog('sw', '[Service Worker] Push received:', data);
+
+  event.waitUntil(
+    self.registration.showNotification(data.title || 'Homepage', {
+      body: data.body || '',
+      tag: data.tag || undefined,
+      data: { url: data.url || '/' }
+    })
+  );
+});
+
+// Notification click - focus an open dashboard tab or open a new one
+self.addEventListener('notificationclick', (event) => {
+  event.notification.close();
+  let target = (event.notification.data && event.notification.data.url) || '/';
+  // Server-side URLs are relative to the dashboard root
+  if (target.startsWith('/') && !target.startsWith('//')) {
+    target = BASE_PATH + target;
+  }
+
+  event.waitUntil(
+    self.clients.matchAll({ type: 'window', includeUncontrolled: true }).then((clientList) => {
+      for (const client of clientList) {
+        if (new URL(client.url).origin === location.origin && 'focus' in client) {
+          return client.focus();
+        }
+      }
+      return self.clients.openWindow(target);
+    })
+  );
+});
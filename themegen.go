@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"homepage/api"
+)
+
+// schemesSubdir holds the generated schemes inside the themes directory, one file per
+// scheme, added to the template named by their metadata.
+const schemesSubdir = "schemes"
+
+var hexColorPattern = regexp.MustCompile(`^#?([0-9a-fA-F]{6}|[0-9a-fA-F]{3})$`)
+
+// GenerateSchemeRequest is the body of /api/theme/generate.
+type GenerateSchemeRequest struct {
+	Template  string `json:"template"`  // Template the scheme is added to, default "nordic"
+	Accent    string `json:"accent"`    // Base color as #RRGGBB or #RGB
+	Mode      string `json:"mode"`      // "dark" (default) or "light"
+	Name      string `json:"name"`      // Scheme name, default derived from accent and mode
+	Display   string `json:"display"`   // Name in the scheme menu, default derived from name
+	Preview   bool   `json:"preview"`   // Only return the CSS, do not save
+	Overwrite bool   `json:"overwrite"` // Replace a scheme of the same name
+}
+
+// hsl is a color as hue in degrees and saturation and lightness from 0 to 1.
+type hsl struct {
+	h, s, l float64
+}
+
+// parseHexColor reads #RRGGBB or #RGB.
+func parseHexColor(value string) (hsl, error) {
+	m := hexColorPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return hsl{}, fmt.Errorf("accent must be a hex color such as #3B82F6")
+	}
+	hex := m[1]
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, _ := strconv.ParseUint(hex, 16, 32)
+	r, g, b := float64(v>>16&0xff)/255, float64(v>>8&0xff)/255, float64(v&0xff)/255
+
+	hi, lo := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	c := hsl{l: (hi + lo) / 2}
+	if d := hi - lo; d > 0 {
+		c.s = d / (1 - math.Abs(2*c.l-1))
+		switch hi {
+		case r:
+			c.h = math.Mod((g-b)/d, 6)
+		case g:
+			c.h = (b-r)/d + 2
+		default:
+			c.h = (r-g)/d + 4
+		}
+		c.h *= 60
+		if c.h < 0 {
+			c.h += 360
+		}
+	}
+	return c, nil
+}
+
+// rgb returns the color's red, green and blue from 0 to 255.
+func (c hsl) rgb() (int, int, int) {
+	chroma := (1 - math.Abs(2*c.l-1)) * c.s
+	x := chroma * (1 - math.Abs(math.Mod(c.h/60, 2)-1))
+	m := c.l - chroma/2
+	var r, g, b float64
+	switch {
+	case c.h < 60:
+		r, g = chroma, x
+	case c.h < 120:
+		r, g = x, chroma
+	case c.h < 180:
+		g, b = chroma, x
+	case c.h < 240:
+		g, b = x, chroma
+	case c.h < 300:
+		r, b = x, chroma
+	default:
+		r, b = chroma, x
+	}
+	return int(math.Round((r + m) * 255)), int(math.Round((g + m) * 255)), int(math.Round((b + m) * 255))
+}
+
+// hex formats the color as #RRGGBB.
+func (c hsl) hex() string {
+	r, g, b := c.rgb()
+	return fmt.Sprintf("#%02X%02X%02X", r, g, b)
+}
+
+// rgba formats the color with an alpha value.
+func (c hsl) rgba(alpha float64) string {
+	r, g, b := c.rgb()
+	return fmt.Sprintf("rgba(%d,%d,%d,%s)", r, g, b, strings.TrimPrefix(strconv.FormatFloat(alpha, 'f', -1, 64), "0"))
+}
+
+// with returns the color with another saturation and lightness.
+func (c hsl) with(s, l float64) hsl {
+	return hsl{h: c.h, s: math.Min(math.Max(s, 0), 1), l: math.Min(math.Max(l, 0), 1)}
+}
+
+// generateScheme derives the variables of a scheme from its accent: tinted near-black or
+// near-white surfaces, text of the opposite lightness and the accent itself moved far enough
+// from the background to stay readable.
+func generateScheme(name, display string, base hsl, light bool) SchemeInfo {
+	tint := math.Min(base.s, 0.5)
+	var vars [][2]string
+	accent := base
+	if light {
+		accent = base.with(base.s, math.Min(base.l, 0.45))
+		vars = [][2]string{
+			{"bg", base.with(tint*0.6, 0.96).hex()},
+			{"panel", base.with(tint*0.4, 0.99).hex()},
+			{"panel2", base.with(tint*0.5, 0.92).hex()},
+			{"border", accent.rgba(.25)},
+			{"txt", base.with(tint*0.5, 0.12).rgba(.95)},
+			{"muted", "rgba(75,85,99,.8)"},
+			{"glow", accent.rgba(.15)},
+			{"accent", accent.hex()},
+			{"good", "#059669"},
+			{"warn", "#D97706"},
+		}
+	} else {
+		accent = base.with(base.s, math.Max(base.l, 0.55))
+		vars = [][2]string{
+			{"bg", base.with(tint*0.6, 0.05).hex()},
+			{"panel", base.with(tint*0.5, 0.09).hex()},
+			{"panel2", base.with(tint*0.5, 0.14).hex()},
+			{"border", accent.rgba(.2)},
+			{"txt", base.with(tint*0.3, 0.96).rgba(.95)},
+			{"muted", "rgba(156,163,175,.7)"},
+			{"glow", accent.rgba(.2)},
+			{"accent", accent.hex()},
+			{"good", "#10B981"},
+			{"warn", "#F59E0B"},
+		}
+	}
+
+	var css strings.Builder
+	css.WriteString(`:root[data-scheme="` + name + `"]{` + "\n")
+	for _, v := range vars {
+		css.WriteString("  --" + v[0] + ":" + v[1] + ";\n")
+	}
+	css.WriteString("}")
+	return SchemeInfo{Name: name, Accent: accent.hex(), Display: display, Border: light, CSS: css.String()}
+}
+
+// schemeFile renders a generated scheme with the metadata header parseTheme reads.
+func schemeFile(template string, scheme SchemeInfo) string {
+	return fmt.Sprintf("/*\nTemplate: %s\nScheme: %s\nAccent: %s\nDisplay: %s\nBorder: %t\n*/\n\n%s\n",
+		template, scheme.Name, scheme.Accent, scheme.Display, scheme.Border, scheme.CSS)
+}
+
+// loadGeneratedSchemes adds the schemes of the themes directory's schemes folder to the
+// loaded templates. Files of unknown templates are reported in errors.
+func loadGeneratedSchemes(dir string, loaded map[string]*TemplateInfo, errs map[string]string) {
+	entries, err := os.ReadDir(filepath.Join(dir, schemesSubdir))
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".css") {
+			continue
+		}
+		key := schemesSubdir + "/" + entry.Name()
+		info, err := readUserTheme(filepath.Join(dir, schemesSubdir, entry.Name()))
+		if err != nil {
+			errs[key] = err.Error()
+			continue
+		}
+		tmpl, ok := loaded[info.Name]
+		if !ok {
+			errs[key] = "unknown template " + info.Name
+			continue
+		}
+		for name, scheme := range info.Schemes {
+			tmpl.Schemes[name] = scheme
+		}
+	}
+}
+
+// handleThemeGenerate serves POST /api/theme/generate: derive a color scheme from an accent
+// color and add it to a template, saved in the themes directory.
+func handleThemeGenerate(debug bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req GenerateSchemeRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+			api.WriteJSON(w, map[string]string{"error": "Invalid request body"})
+			return
+		}
+
+		base, err := parseHexColor(req.Accent)
+		if err != nil {
+			api.WriteJSON(w, map[string]string{"error": err.Error()})
+			return
+		}
+		if req.Mode == "" {
+			req.Mode = "dark"
+		}
+		if req.Mode != "dark" && req.Mode != "light" {
+			api.WriteJSON(w, map[string]string{"error": "mode must be dark or light"})
+			return
+		}
+		if req.Template == "" {
+			req.Template = "nordic"
+		}
+		templates, _ := currentThemes()
+		tmpl, ok := templates[req.Template]
+		if !ok {
+			api.WriteJSON(w, map[string]string{"error": "Unknown template " + req.Template})
+			return
+		}
+		if req.Name == "" {
+			req.Name = "custom-" + strings.ToLower(strings.TrimPrefix(base.hex(), "#")) + "-" + req.Mode
+		}
+		req.Name = strings.ToLower(req.Name)
+		if !themeNamePattern.MatchString(req.Name) {
+			api.WriteJSON(w, map[string]string{"error": "Invalid scheme name " + req.Name + " (lowercase letters, digits, dash and underscore, up to 32)"})
+			return
+		}
+		req.Display = strings.TrimSpace(req.Display)
+		if req.Display == "" {
+			req.Display = "Custom " + strings.ToUpper(req.Mode[:1]) + req.Mode[1:]
+		}
+		if len(req.Display) > 40 || strings.ContainsAny(req.Display, "<>\"*/\n") {
+			api.WriteJSON(w, map[string]string{"error": "Invalid display name"})
+			return
+		}
+
+		scheme := generateScheme(req.Name, req.Display, base, req.Mode == "light")
+		response := map[string]any{
+			"template": req.Template,
+			"scheme":   scheme.Name,
+			"display":  scheme.Display,
+			"accent":   scheme.Accent,
+			"css":      scheme.CSS,
+		}
+		if req.Preview {
+			api.WriteJSON(w, response)
+			return
+		}
+
+		templatesMu.RLock()
+		dir := filepath.Join(themesDir, schemesSubdir)
+		templatesMu.RUnlock()
+		path := filepath.Join(dir, req.Template+"."+scheme.Name+".css")
+		_, saved := os.Stat(path)
+		if _, exists := tmpl.Schemes[scheme.Name]; exists && !req.Overwrite {
+			api.WriteJSON(w, map[string]string{"error": "Scheme " + scheme.Name + " already exists in " + req.Template + " (overwrite replaces it)"})
+			return
+		} else if exists && saved != nil {
+			api.WriteJSON(w, map[string]string{"error": "Scheme " + scheme.Name + " is part of the template and cannot be replaced"})
+			return
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			api.Logger("themes").Error("failed to create schemes directory", "dir", dir, "error", err)
+			api.WriteJSON(w, map[string]string{"error": "Failed to save scheme"})
+			return
+		}
+		tmp := path + ".tmp"
+		err = os.WriteFile(tmp, []byte(schemeFile(req.Template, scheme)), 0644)
+		if err == nil {
+			err = os.Rename(tmp, path)
+		}
+		if err != nil {
+			os.Remove(tmp)
+			api.Logger("themes").Error("failed to write scheme", "path", path, "error", err)
+			api.WriteJSON(w, map[string]string{"error": "Failed to save scheme"})
+			return
+		}
+
+		if _, err := reloadThemes(debug); err != nil {
+			api.WriteJSON(w, map[string]string{"error": "Scheme saved but reloading failed: " + err.Error()})
+			return
+		}
+		api.Audit(r, "themes.generate", req.Template+"/"+scheme.Name)
+		response["success"] = "Scheme " + scheme.Display + " added to " + req.Template
+		api.WriteJSON(w, response)
+	}
+}
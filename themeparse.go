@@ -0,0 +1,449 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// A CSS template is a stylesheet with a metadata header per color scheme:
+//
+//	/*
+//	Template: forest
+//	Scheme: dark
+//	Accent: #4ADE80
+//	Display: Dark
+//	Border: true
+//	*/
+//	:root[data-scheme="dark"]{ --bg:#030705; ... }
+//
+// Rules whose selector names a declared scheme with [data-scheme="name"] belong to that
+// scheme, wherever they are in the file; everything else is the template's base CSS. The
+// older form with a plain :root block (and optionally a body block) right after the header
+// is still read and scoped to the scheme.
+
+// defaultSchemeAccent is the menu color of a scheme without an Accent line.
+const defaultSchemeAccent = "rgba(136,192,208,.85)"
+
+var (
+	themeIdentPattern     = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+	themeMetaLinePattern  = regexp.MustCompile(`^([A-Za-z]+)\s*:\s*(.*)$`)
+	schemeSelectorPattern = regexp.MustCompile(`\[data-scheme\s*=\s*["']?([^"'\]\s]+)["']?\s*\]`)
+)
+
+// ThemeMetadata is the header of a scheme, or of the template with only a Template line.
+type ThemeMetadata struct {
+	Template string
+	Scheme   string
+	Accent   string
+	Display  string
+	Border   bool
+	Line     int
+}
+
+// ThemeIssue is a problem found in a CSS template, at a 1-based line when known.
+type ThemeIssue struct {
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i ThemeIssue) Error() string {
+	if i.Line > 0 {
+		return fmt.Sprintf("line %d: %s", i.Line, i.Message)
+	}
+	return i.Message
+}
+
+// ThemeCheck is the result of validating a CSS template.
+type ThemeCheck struct {
+	Valid    bool         `json:"valid"`
+	Template string       `json:"template,omitempty"`
+	Schemes  []string     `json:"schemes"` // In file order
+	Errors   []ThemeIssue `json:"errors"`
+	Warnings []ThemeIssue `json:"warnings"`
+}
+
+func (c *ThemeCheck) errorf(line int, format string, args ...any) {
+	c.Errors = append(c.Errors, ThemeIssue{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *ThemeCheck) warnf(line int, format string, args ...any) {
+	c.Warnings = append(c.Warnings, ThemeIssue{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// cssNodeKind tells the top-level parts of a stylesheet apart.
+type cssNodeKind int
+
+const (
+	cssComment   cssNodeKind = iota // /* ... */
+	cssRule                         // selector or at-rule prelude with a { ... } block
+	cssStatement                    // at-rule ending in ";" such as @import
+)
+
+// cssNode is one top-level part of a stylesheet.
+type cssNode struct {
+	kind    cssNodeKind
+	prelude string // Selector or at-rule of a rule or statement, trimmed
+	block   string // The rule's block including its braces
+	text    string // Source text of the node
+	line    int
+}
+
+// cssScanner splits a stylesheet into top-level nodes. Comments and quoted strings are
+// skipped inside selectors and blocks, so braces in them do not count.
+type cssScanner struct {
+	src    string
+	pos    int
+	line   int
+	issues []ThemeIssue
+}
+
+// advance moves to pos, counting the lines passed.
+func (s *cssScanner) advance(pos int) {
+	s.line += strings.Count(s.src[s.pos:pos], "\n")
+	s.pos = pos
+}
+
+// skipComment returns the end of the comment starting at i, or -1 when it is not closed.
+func (s *cssScanner) skipComment(i int) int {
+	end := strings.Index(s.src[i+2:], "*/")
+	if end == -1 {
+		return -1
+	}
+	return i + 2 + end + 2
+}
+
+// skipString returns the end of the quoted string starting at i; an unclosed string ends
+// at the line break, as in CSS.
+func (s *cssScanner) skipString(i int) int {
+	quote := s.src[i]
+	for j := i + 1; j < len(s.src); j++ {
+		switch s.src[j] {
+		case '\\':
+			j++
+		case '\n':
+			return j
+		case quote:
+			return j + 1
+		}
+	}
+	return len(s.src)
+}
+
+func (s *cssScanner) issue(line int, format string, args ...any) {
+	s.issues = append(s.issues, ThemeIssue{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// tokenizeCSS returns the top-level nodes of a stylesheet and the syntax errors found.
+func tokenizeCSS(src string) ([]cssNode, []ThemeIssue) {
+	s := &cssScanner{src: src, line: 1}
+	var nodes []cssNode
+	for {
+		start := s.pos
+		for start < len(src) && strings.IndexByte(" \t\r\n\f", src[start]) >= 0 {
+			start++
+		}
+		s.advance(start)
+		if start >= len(src) {
+			break
+		}
+		line := s.line
+
+		if strings.HasPrefix(src[start:], "/*") {
+			end := s.skipComment(start)
+			if end == -1 {
+				s.issue(line, "comment is not closed")
+				s.advance(len(src))
+				break
+			}
+			nodes = append(nodes, cssNode{kind: cssComment, text: src[start:end], line: line})
+			s.advance(end)
+			continue
+		}
+		if src[start] == '}' {
+			s.issue(line, "unexpected }")
+			s.advance(start + 1)
+			continue
+		}
+
+		// Prelude up to the block or the end of the statement
+		i := start
+		blockStart := -1
+		for i < len(src) && blockStart == -1 {
+			switch {
+			case strings.HasPrefix(src[i:], "/*"):
+				if end := s.skipComment(i); end != -1 {
+					i = end
+				} else {
+					i = len(src)
+				}
+			case src[i] == '"' || src[i] == '\'':
+				i = s.skipString(i)
+			case src[i] == '{':
+				blockStart = i
+			case src[i] == ';' || src[i] == '}':
+				blockStart = -2
+			default:
+				i++
+			}
+		}
+		if blockStart < 0 {
+			if i < len(src) && src[i] == ';' {
+				nodes = append(nodes, cssNode{kind: cssStatement, prelude: strings.TrimSpace(src[start:i]), text: src[start : i+1], line: line})
+				s.advance(i + 1)
+				continue
+			}
+			if i < len(src) && src[i] == '}' {
+				s.issue(line, "unexpected } after %q", strings.TrimSpace(src[start:i]))
+				s.advance(i + 1)
+				continue
+			}
+			s.issue(line, "%q has no block", strings.TrimSpace(src[start:]))
+			s.advance(len(src))
+			break
+		}
+
+		// Block up to its matching brace
+		depth := 0
+		end := -1
+		for j := blockStart; j < len(src) && end == -1; {
+			switch {
+			case strings.HasPrefix(src[j:], "/*"):
+				if e := s.skipComment(j); e != -1 {
+					j = e
+				} else {
+					j = len(src)
+				}
+			case src[j] == '"' || src[j] == '\'':
+				j = s.skipString(j)
+			case src[j] == '{':
+				depth++
+				j++
+			case src[j] == '}':
+				depth--
+				j++
+				if depth == 0 {
+					end = j
+				}
+			default:
+				j++
+			}
+		}
+		if end == -1 {
+			s.issue(line, "block of %q is not closed", strings.TrimSpace(src[start:blockStart]))
+			s.advance(len(src))
+			break
+		}
+		nodes = append(nodes, cssNode{
+			kind:    cssRule,
+			prelude: strings.TrimSpace(src[start:blockStart]),
+			block:   src[blockStart:end],
+			text:    src[start:end],
+			line:    line,
+		})
+		s.advance(end)
+	}
+	return nodes, s.issues
+}
+
+// parseThemeMetadata reads a comment as a metadata header. ok is false for ordinary
+// comments: a header has only "Key: value" lines, one of them Template or Scheme.
+func parseThemeMetadata(comment string, line int, check *ThemeCheck) (ThemeMetadata, bool) {
+	body := strings.TrimSuffix(strings.TrimPrefix(comment, "/*"), "*/")
+	meta := ThemeMetadata{Line: line}
+	type field struct {
+		key, value string
+		line       int
+	}
+	var fields []field
+	for i, raw := range strings.Split(body, "\n") {
+		text := strings.TrimSpace(raw)
+		if text == "" {
+			continue
+		}
+		m := themeMetaLinePattern.FindStringSubmatch(text)
+		if m == nil {
+			return meta, false
+		}
+		fields = append(fields, field{strings.ToLower(m[1]), strings.TrimSpace(m[2]), line + i})
+	}
+	isHeader := false
+	for _, f := range fields {
+		if f.key == "template" || f.key == "scheme" {
+			isHeader = true
+		}
+	}
+	if !isHeader {
+		return meta, false
+	}
+
+	for _, f := range fields {
+		switch f.key {
+		case "template":
+			meta.Template = f.value
+		case "scheme":
+			meta.Scheme = f.value
+		case "accent":
+			meta.Accent = f.value
+		case "display":
+			meta.Display = f.value
+		case "border":
+			switch strings.ToLower(f.value) {
+			case "true", "1", "yes":
+				meta.Border = true
+			case "false", "0", "no", "":
+			default:
+				check.errorf(f.line, "Border must be true or false, not %q", f.value)
+			}
+		default:
+			check.warnf(f.line, "unknown metadata key %q", f.key)
+		}
+	}
+	return meta, true
+}
+
+// schemeBlock collects the rules of one scheme.
+type schemeBlock struct {
+	meta  ThemeMetadata
+	rules []string
+}
+
+// parseTheme reads a CSS template and validates it. The template is named by its metadata,
+// or else after its file. info is nil when the check found errors.
+func parseTheme(fileName, content string) (*TemplateInfo, ThemeCheck) {
+	check := ThemeCheck{Schemes: []string{}, Errors: []ThemeIssue{}, Warnings: []ThemeIssue{}}
+	nodes, syntaxIssues := tokenizeCSS(content)
+	check.Errors = append(check.Errors, syntaxIssues...)
+
+	var order []string
+	schemes := make(map[string]*schemeBlock)
+	consumed := make([]bool, len(nodes))
+	templateLine := 0
+
+	// Headers first, so rules may come before the header of their scheme
+	for i, node := range nodes {
+		if node.kind != cssComment {
+			continue
+		}
+		meta, ok := parseThemeMetadata(node.text, node.line, &check)
+		if !ok {
+			continue
+		}
+		consumed[i] = true
+		if meta.Template == "" {
+			check.errorf(node.line, "metadata of scheme %q has no Template line", meta.Scheme)
+		} else if check.Template == "" {
+			check.Template, templateLine = meta.Template, node.line
+		} else if meta.Template != check.Template {
+			check.errorf(node.line, "Template %q differs from %q on line %d", meta.Template, check.Template, templateLine)
+		}
+		if meta.Scheme == "" {
+			continue
+		}
+		if !themeIdentPattern.MatchString(meta.Scheme) {
+			check.errorf(node.line, "scheme name %q may only hold letters, digits, dash and underscore (up to 32)", meta.Scheme)
+			continue
+		}
+		if prev, dup := schemes[meta.Scheme]; dup {
+			check.errorf(node.line, "scheme %q is already declared on line %d", meta.Scheme, prev.meta.Line)
+			continue
+		}
+		if meta.Accent == "" {
+			check.warnf(node.line, "scheme %q has no Accent, the menu shows %s", meta.Scheme, defaultSchemeAccent)
+			meta.Accent = defaultSchemeAccent
+		} else if strings.ContainsAny(meta.Accent, ";{}<>\"'\\") {
+			check.errorf(node.line, "Accent %q is not a color", meta.Accent)
+		}
+		if strings.ContainsAny(meta.Display, "<>\"&") {
+			check.errorf(node.line, "Display %q may not contain <, >, \" or &", meta.Display)
+		}
+		schemes[meta.Scheme] = &schemeBlock{meta: meta}
+		order = append(order, meta.Scheme)
+
+		// Older form: a plain :root block, and a body block, right after the header
+		if i+1 < len(nodes) && nodes[i+1].kind == cssRule && nodes[i+1].prelude == ":root" {
+			consumed[i+1] = true
+			schemes[meta.Scheme].rules = append(schemes[meta.Scheme].rules, `:root[data-scheme="`+meta.Scheme+`"]`+nodes[i+1].block)
+			if i+2 < len(nodes) && nodes[i+2].kind == cssRule && nodes[i+2].prelude == "body" {
+				consumed[i+2] = true
+				schemes[meta.Scheme].rules = append(schemes[meta.Scheme].rules, `[data-scheme="`+meta.Scheme+`"] body`+nodes[i+2].block)
+			}
+		}
+	}
+
+	// Rules naming a scheme belong to it
+	for i, node := range nodes {
+		if consumed[i] || node.kind != cssRule || strings.HasPrefix(node.prelude, "@") {
+			continue
+		}
+		// A selector list naming several schemes is added to each of them
+		added := make(map[string]bool)
+		for _, m := range schemeSelectorPattern.FindAllStringSubmatch(node.prelude, -1) {
+			block, ok := schemes[m[1]]
+			if !ok {
+				check.warnf(node.line, "rule for undeclared scheme %q is kept in the base CSS", m[1])
+				continue
+			}
+			if !added[m[1]] {
+				added[m[1]] = true
+				consumed[i] = true
+				block.rules = append(block.rules, node.text)
+			}
+		}
+	}
+
+	if check.Template == "" {
+		check.Template = strings.TrimSuffix(fileName, ".css")
+	}
+	if !themeIdentPattern.MatchString(check.Template) {
+		check.errorf(templateLine, "template name %q may only hold letters, digits, dash and underscore (up to 32)", check.Template)
+	}
+	for _, name := range order {
+		if len(schemes[name].rules) == 0 {
+			check.errorf(schemes[name].meta.Line, "scheme %q has no rule with [data-scheme=%q]", name, name)
+		}
+	}
+	if len(order) == 0 {
+		check.errorf(0, "no schemes found (each needs a /* Template: ... Scheme: ... */ header)")
+	} else if _, ok := schemes["default"]; !ok {
+		check.warnf(0, "no scheme is named default; the first one is used instead")
+	}
+	check.Schemes = append(check.Schemes, order...)
+	check.Valid = len(check.Errors) == 0
+	if !check.Valid {
+		return nil, check
+	}
+
+	info := &TemplateInfo{Name: check.Template, Schemes: make(map[string]SchemeInfo, len(order))}
+	for _, name := range order {
+		block := schemes[name]
+		info.Schemes[name] = SchemeInfo{
+			Name:    name,
+			Accent:  block.meta.Accent,
+			Display: block.meta.Display,
+			Border:  block.meta.Border,
+			CSS:     strings.Join(block.rules, "\n"),
+		}
+	}
+	var base []string
+	for i, node := range nodes {
+		if !consumed[i] {
+			base = append(base, node.text)
+		}
+	}
+	info.BaseCSS = strings.Join(base, "\n")
+	return info, check
+}
+
+// parseTemplate reads the schemes and base CSS of a CSS template, failing with the first
+// error of its check.
+func parseTemplate(fileName, content string) (*TemplateInfo, error) {
+	info, check := parseTheme(fileName, content)
+	if len(check.Errors) > 0 {
+		if more := len(check.Errors) - 1; more > 0 {
+			return nil, fmt.Errorf("%w (and %d more)", check.Errors[0], more)
+		}
+		return nil, check.Errors[0]
+	}
+	return info, nil
+}
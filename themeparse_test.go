@@ -0,0 +1,283 @@
+package main
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestTokenizeCSS(t *testing.T) {
+	src := `/* a { comment } */
+@import url("x.css");
+.a[title="}{"] { content: "/* not a comment */"; }
+@media (max-width: 600px) {
+  .b { color: red; }
+}
+.c{/* } */color:blue}`
+	nodes, issues := tokenizeCSS(src)
+	if len(issues) != 0 {
+		t.Fatalf("unexpected issues: %v", issues)
+	}
+	want := []struct {
+		kind    cssNodeKind
+		prelude string
+		line    int
+	}{
+		{cssComment, "", 1},
+		{cssStatement, `@import url("x.css")`, 2},
+		{cssRule, `.a[title="}{"]`, 3},
+		{cssRule, "@media (max-width: 600px)", 4},
+		{cssRule, ".c", 7},
+	}
+	if len(nodes) != len(want) {
+		t.Fatalf("got %d nodes, want %d: %#v", len(nodes), len(want), nodes)
+	}
+	for i, w := range want {
+		n := nodes[i]
+		if n.kind != w.kind || n.prelude != w.prelude || n.line != w.line {
+			t.Errorf("node %d = {%d %q line %d}, want {%d %q line %d}", i, n.kind, n.prelude, n.line, w.kind, w.prelude, w.line)
+		}
+	}
+	if got := nodes[3].block; !strings.Contains(got, ".b { color: red; }") || !strings.HasSuffix(got, "}") {
+		t.Errorf("nested block = %q", got)
+	}
+}
+
+func TestTokenizeCSSErrors(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+		line            int
+	}{
+		{"unclosed comment", ".a{}\n/* open", "comment is not closed", 2},
+		{"unclosed block", ".a{}\n.b{\n  color: red;", `block of ".b" is not closed`, 2},
+		{"stray brace", ".a{}\n}", "unexpected }", 2},
+		{"selector without block", ".a{}\n.b", `".b" has no block`, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, issues := tokenizeCSS(tt.src)
+			if len(issues) != 1 || issues[0].Message != tt.want || issues[0].Line != tt.line {
+				t.Errorf("issues = %v, want %q on line %d", issues, tt.want, tt.line)
+			}
+		})
+	}
+}
+
+const testTheme = `/* Base CSS (shared styles) */
+:root{ --bg:#000; }
+
+/*
+Template: sample
+Scheme: default
+Accent: #88C0D0
+Display: Default
+*/
+:root[data-scheme="default"]{ --bg:#111; }
+
+/* Note: the dark scheme follows */
+/*
+Template: sample
+Scheme: dark
+Accent: #4ADE80
+Display: Dark
+Border: yes
+*/
+:root[data-scheme="dark"]{ --bg:#222; }
+
+body{ margin:0; }
+:root[data-scheme="dark"] .card{ border-color:#333; }
+`
+
+func TestParseTheme(t *testing.T) {
+	info, check := parseTheme("fallback.css", testTheme)
+	if !check.Valid || info == nil {
+		t.Fatalf("theme invalid: %v", check.Errors)
+	}
+	if len(check.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", check.Warnings)
+	}
+	if info.Name != "sample" {
+		t.Errorf("name = %q, want sample", info.Name)
+	}
+	if strings.Join(check.Schemes, ",") != "default,dark" {
+		t.Errorf("schemes = %v", check.Schemes)
+	}
+	dark := info.Schemes["dark"]
+	if dark.Accent != "#4ADE80" || dark.Display != "Dark" || !dark.Border {
+		t.Errorf("dark metadata = %+v", dark)
+	}
+	if !strings.Contains(dark.CSS, "--bg:#222") || !strings.Contains(dark.CSS, ".card{ border-color:#333; }") {
+		t.Errorf("dark CSS misses its rules: %q", dark.CSS)
+	}
+	if strings.Contains(info.Schemes["default"].CSS, "#222") {
+		t.Errorf("default CSS holds dark rules: %q", info.Schemes["default"].CSS)
+	}
+	for _, want := range []string{"--bg:#000", "body{ margin:0; }", "/* Note: the dark scheme follows */"} {
+		if !strings.Contains(info.BaseCSS, want) {
+			t.Errorf("base CSS misses %q", want)
+		}
+	}
+	for _, unwanted := range []string{"Template:", "#111", "#222"} {
+		if strings.Contains(info.BaseCSS, unwanted) {
+			t.Errorf("base CSS holds %q", unwanted)
+		}
+	}
+}
+
+func TestParseThemeLegacyRoot(t *testing.T) {
+	src := `/*
+Template: legacy
+Scheme: light
+Accent: #fff
+*/
+:root{ --bg:#fff; }
+body{ background:#eee; }
+.x{ color:red; }`
+	info, check := parseTheme("legacy.css", src)
+	if !check.Valid {
+		t.Fatalf("theme invalid: %v", check.Errors)
+	}
+	css := info.Schemes["light"].CSS
+	if !strings.Contains(css, `:root[data-scheme="light"]{ --bg:#fff; }`) || !strings.Contains(css, `[data-scheme="light"] body{ background:#eee; }`) {
+		t.Errorf("legacy blocks not scoped: %q", css)
+	}
+	if info.BaseCSS != ".x{ color:red; }" {
+		t.Errorf("base CSS = %q", info.BaseCSS)
+	}
+}
+
+func TestParseThemeTemplateName(t *testing.T) {
+	src := "/*\nScheme: default\nTemplate: \n*/\n:root[data-scheme=\"default\"]{}"
+	_, check := parseTheme("x.css", src)
+	if check.Valid {
+		t.Fatal("scheme without template accepted")
+	}
+	src = "/*\nTemplate: named\n*/\n/*\nTemplate: named\nScheme: default\nAccent: red\n*/\n:root[data-scheme=default]{}"
+	info, check := parseTheme("file.css", src)
+	if !check.Valid || info.Name != "named" {
+		t.Fatalf("got %+v, %v", info, check.Errors)
+	}
+}
+
+func TestParseThemeErrors(t *testing.T) {
+	header := func(scheme, extra string) string {
+		return "/*\nTemplate: t\nScheme: " + scheme + "\nAccent: #000\n" + extra + "*/\n"
+	}
+	rule := func(scheme string) string {
+		return `:root[data-scheme="` + scheme + `"]{ --bg:#000; }` + "\n"
+	}
+	tests := []struct {
+		name, src, want string
+	}{
+		{"no schemes", ".a{}", "no schemes found"},
+		{"duplicate scheme", header("default", "") + rule("default") + header("default", "") + rule("default"), `scheme "default" is already declared on line 1`},
+		{"scheme without rules", header("default", "") + header("dark", ""), `scheme "dark" has no rule`},
+		{"bad border", header("default", "Border: maybe\n") + rule("default"), `Border must be true or false, not "maybe"`},
+		{"bad scheme name", header("da rk", "") + rule("default"), `scheme name "da rk" may only hold`},
+		{"template mismatch", header("default", "") + rule("default") + "/*\nTemplate: other\nScheme: dark\nAccent: #000\n*/\n" + rule("dark"), `Template "other" differs from "t" on line 1`},
+		{"accent injection", "/*\nTemplate: t\nScheme: default\nAccent: red;\" onload=\"x\n*/\n" + rule("default"), "is not a color"},
+		{"display injection", header("default", "Display: <b>x</b>\n") + rule("default"), "may not contain"},
+		{"syntax error", header("default", "") + rule("default") + ".a{", `block of ".a" is not closed`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, check := parseTheme("t.css", tt.src)
+			if check.Valid || info != nil {
+				t.Fatal("theme accepted")
+			}
+			found := false
+			for _, issue := range check.Errors {
+				if strings.Contains(issue.Error(), tt.want) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("errors %v do not mention %q", check.Errors, tt.want)
+			}
+			if _, err := parseTemplate("t.css", tt.src); err == nil {
+				t.Error("parseTemplate accepted the theme")
+			}
+		})
+	}
+}
+
+func TestParseThemeWarnings(t *testing.T) {
+	src := "/*\nTemplate: t\nScheme: dark\nColour: red\n*/\n" +
+		`:root[data-scheme="dark"]{}` + "\n" +
+		`:root[data-scheme="ghost"] .a{}`
+	info, check := parseTheme("t.css", src)
+	if !check.Valid {
+		t.Fatalf("theme invalid: %v", check.Errors)
+	}
+	var messages []string
+	for _, w := range check.Warnings {
+		messages = append(messages, w.Error())
+	}
+	all := strings.Join(messages, "\n")
+	for _, want := range []string{`line 4: unknown metadata key "colour"`, "has no Accent", `undeclared scheme "ghost"`, "no scheme is named default"} {
+		if !strings.Contains(all, want) {
+			t.Errorf("warnings miss %q:\n%s", want, all)
+		}
+	}
+	if info.Schemes["dark"].Accent != defaultSchemeAccent {
+		t.Errorf("accent = %q", info.Schemes["dark"].Accent)
+	}
+	if !strings.Contains(info.BaseCSS, "ghost") {
+		t.Errorf("rule of undeclared scheme left out of the base CSS")
+	}
+}
+
+func TestParseThemeOrdinaryComments(t *testing.T) {
+	for _, comment := range []string{"/* Note: just a remark */", "/* Base CSS (shared styles) - Default variables */", "/*\nAuthor: me\nLicense: MIT\n*/"} {
+		if _, ok := parseThemeMetadata(comment, 1, &ThemeCheck{}); ok {
+			t.Errorf("%q read as a header", comment)
+		}
+	}
+}
+
+func TestEmbeddedTemplatesValid(t *testing.T) {
+	entries, err := fs.ReadDir(templatesFS, "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".css") {
+			continue
+		}
+		count++
+		content, _ := templatesFS.ReadFile("templates/" + entry.Name())
+		info, check := parseTheme(entry.Name(), string(content))
+		if !check.Valid {
+			t.Errorf("%s: %v", entry.Name(), check.Errors)
+			continue
+		}
+		if len(check.Warnings) > 0 {
+			t.Errorf("%s: warnings %v", entry.Name(), check.Warnings)
+		}
+		if _, ok := info.Schemes["default"]; !ok {
+			t.Errorf("%s has no default scheme", entry.Name())
+		}
+	}
+	if count == 0 {
+		t.Fatal("no embedded templates")
+	}
+}
+
+func TestGeneratedSchemeRoundTrip(t *testing.T) {
+	base, err := parseHexColor("#3B82F6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, light := range []bool{false, true} {
+		scheme := generateScheme("blue", "Blue", base, light)
+		info, check := parseTheme("nordic.blue.css", schemeFile("nordic", scheme))
+		if !check.Valid {
+			t.Fatalf("generated scheme invalid: %v", check.Errors)
+		}
+		got := info.Schemes["blue"]
+		if info.Name != "nordic" || got.Accent != scheme.Accent || got.Border != light || got.CSS != scheme.CSS {
+			t.Errorf("round trip = %+v, want %+v", got, scheme)
+		}
+	}
+}
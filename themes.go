@@ -0,0 +1,425 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"homepage/api"
+)
+
+// defaultThemesDir is where user themes are read from when the config names no directory.
+const defaultThemesDir = "themes"
+
+// maxThemeSize limits uploaded and user theme files.
+const maxThemeSize = 512 * 1024
+
+var themeNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+var (
+	// templatesMu guards swapping templatesMap and templatesList on reload; both are
+	// replaced whole and never changed in place, so a snapshot can be read unlocked
+	templatesMu sync.RWMutex
+	themesDir   = defaultThemesDir
+)
+
+// ThemeLoadResult reports a reload of the theme templates.
+type ThemeLoadResult struct {
+	Templates []string          `json:"templates"`
+	User      []string          `json:"user"`             // Templates read from the themes directory
+	Errors    map[string]string `json:"errors,omitempty"` // Files of the themes directory that could not be used
+}
+
+// currentThemes returns the loaded templates and their display order.
+func currentThemes() (map[string]*TemplateInfo, []string) {
+	templatesMu.RLock()
+	defer templatesMu.RUnlock()
+	return templatesMap, templatesList
+}
+
+// reloadThemes reads the embedded templates and then the CSS files of the themes directory,
+// which may replace a built-in template of the same name. The directory is optional.
+func reloadThemes(debug bool) (ThemeLoadResult, error) {
+	result := ThemeLoadResult{User: []string{}, Errors: map[string]string{}}
+	loaded := make(map[string]*TemplateInfo)
+	builtin := make(map[string]bool)
+
+	entries, err := fs.ReadDir(templatesFS, "templates")
+	if err != nil {
+		return result, err
+	}
+	if debug {
+		// List all files found for debugging
+		allFiles := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			allFiles = append(allFiles, entry.Name())
+		}
+		log.Printf("Found %d entries in templates directory: %v", len(entries), allFiles)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".css") {
+			continue
+		}
+		cssContent, err := templatesFS.ReadFile("templates/" + entry.Name())
+		if err != nil {
+			if debug {
+				log.Printf("Warning: failed to read template %s: %v", entry.Name(), err)
+			}
+			continue
+		}
+		info, err := parseTemplate(entry.Name(), string(cssContent))
+		if err != nil {
+			if debug {
+				log.Printf("Warning: template %s: %v", entry.Name(), err)
+			}
+			continue
+		}
+		loaded[info.Name] = info
+		builtin[info.Name] = true
+	}
+
+	templatesMu.RLock()
+	dir := themesDir
+	templatesMu.RUnlock()
+	userEntries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		api.Logger("themes").Warn("reading themes directory failed", "dir", dir, "error", err)
+	}
+	for _, entry := range userEntries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".css") {
+			continue
+		}
+		info, err := readUserTheme(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			result.Errors[entry.Name()] = err.Error()
+			api.Logger("themes").Warn("skipping theme", "file", entry.Name(), "error", err)
+			continue
+		}
+		if builtin[info.Name] {
+			api.Logger("themes").Info("user theme replaces built-in template", "template", info.Name)
+		}
+		loaded[info.Name] = info
+		result.User = append(result.User, info.Name)
+	}
+	loadGeneratedSchemes(dir, loaded, result.Errors)
+
+	names := make([]string, 0, len(loaded))
+	for name := range loaded {
+		names = append(names, name)
+	}
+	names = sortTemplates(names)
+
+	templatesMu.Lock()
+	templatesMap, templatesList = loaded, names
+	templatesMu.Unlock()
+	result.Templates = names
+
+	if debug {
+		log.Printf("Loaded %d theme templates:", len(loaded))
+		for name, info := range loaded {
+			schemeNames := make([]string, 0, len(info.Schemes))
+			for schemeName := range info.Schemes {
+				schemeNames = append(schemeNames, schemeName)
+			}
+			log.Printf("  - %s: %d schemes (%s)", name, len(info.Schemes), strings.Join(schemeNames, ", "))
+		}
+	}
+	return result, nil
+}
+
+// readUserTheme reads and parses a CSS template of the themes directory.
+func readUserTheme(path string) (*TemplateInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxThemeSize {
+		return nil, fmt.Errorf("larger than %d KB", maxThemeSize/1024)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseTemplate(filepath.Base(path), string(content))
+}
+
+// handleThemeReload serves POST /api/theme/reload: read the themes directory again.
+func handleThemeReload(debug bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		result, err := reloadThemes(debug)
+		if err != nil {
+			api.WriteJSON(w, map[string]string{"error": "Failed to reload themes: " + err.Error()})
+			return
+		}
+		api.Audit(r, "themes.reload", fmt.Sprintf("%d user themes", len(result.User)))
+		api.WriteJSON(w, result)
+	}
+}
+
+// readThemeBody reads a CSS template sent as the request body or as the "file" field of a
+// multipart form, returning its file name ("theme.css" for a plain body).
+func readThemeBody(w http.ResponseWriter, r *http.Request) (string, []byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxThemeSize+64*1024)
+	var content []byte
+	var err error
+	fileName := "theme.css"
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, header, ferr := r.FormFile("file")
+		if ferr != nil {
+			return "", nil, errors.New("A CSS file is required in the \"file\" field")
+		}
+		defer file.Close()
+		fileName = header.Filename
+		content, err = io.ReadAll(io.LimitReader(file, maxThemeSize+1))
+	} else {
+		content, err = io.ReadAll(io.LimitReader(r.Body, maxThemeSize+1))
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to read theme: %w", err)
+	}
+	if len(content) > maxThemeSize {
+		return "", nil, fmt.Errorf("Theme is larger than %d KB", maxThemeSize/1024)
+	}
+	return fileName, content, nil
+}
+
+// handleThemeValidate serves POST /api/theme/validate: check a CSS template, sent like an
+// upload, without installing it. The answer lists its schemes and every error and warning
+// with its line.
+func handleThemeValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	fileName, content, err := readThemeBody(w, r)
+	if err != nil {
+		api.WriteJSON(w, map[string]string{"error": err.Error()})
+		return
+	}
+	_, check := parseTheme(fileName, string(content))
+	api.WriteJSON(w, check)
+}
+
+// handleThemeUpload serves POST /api/theme/upload: install a CSS template into the themes
+// directory, sent as the request body or as the "file" field of a multipart form. The
+// template is named by its metadata; ?overwrite=1 replaces an installed theme of that name.
+func handleThemeUpload(debug bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		fileName, content, err := readThemeBody(w, r)
+		if err != nil {
+			api.WriteJSON(w, map[string]string{"error": err.Error()})
+			return
+		}
+
+		info, err := parseTemplate(fileName, string(content))
+		if err != nil {
+			api.WriteJSON(w, map[string]string{"error": "Invalid theme: " + err.Error()})
+			return
+		}
+		if !themeNamePattern.MatchString(info.Name) {
+			api.WriteJSON(w, map[string]string{"error": "Invalid template name " + info.Name + " (lowercase letters, digits, dash and underscore, up to 32)"})
+			return
+		}
+
+		templatesMu.RLock()
+		dir := themesDir
+		templatesMu.RUnlock()
+		path := filepath.Join(dir, info.Name+".css")
+		if _, err := os.Stat(path); err == nil && r.URL.Query().Get("overwrite") != "1" {
+			api.WriteJSON(w, map[string]string{"error": "Theme " + info.Name + " is already installed (overwrite=1 replaces it)"})
+			return
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			api.Logger("themes").Error("failed to create themes directory", "dir", dir, "error", err)
+			api.WriteJSON(w, map[string]string{"error": "Failed to save theme"})
+			return
+		}
+		tmp := path + ".tmp"
+		err = os.WriteFile(tmp, content, 0644)
+		if err == nil {
+			err = os.Rename(tmp, path)
+		}
+		if err != nil {
+			os.Remove(tmp)
+			api.Logger("themes").Error("failed to write theme", "path", path, "error", err)
+			api.WriteJSON(w, map[string]string{"error": "Failed to save theme"})
+			return
+		}
+
+		result, err := reloadThemes(debug)
+		if err != nil {
+			api.WriteJSON(w, map[string]string{"error": "Theme saved but reloading failed: " + err.Error()})
+			return
+		}
+		schemes := make([]string, 0, len(info.Schemes))
+		for name := range info.Schemes {
+			schemes = append(schemes, name)
+		}
+		api.Audit(r, "themes.upload", info.Name)
+		api.WriteJSON(w, map[string]any{
+			"success":   "Theme " + info.Name + " installed",
+			"template":  info.Name,
+			"schemes":   schemes,
+			"templates": result.Templates,
+		})
+	}
+}
+
+// ThemeChoice is the template and scheme a profile's dashboard is rendered with.
+type ThemeChoice struct {
+	Profile  string `json:"profile"`
+	Template string `json:"template"`
+	Scheme   string `json:"scheme"`
+	Saved    bool   `json:"saved"` // False while the profile has no stored choice and the defaults apply
+}
+
+// themeSchemeFor returns scheme if the template has it, otherwise its default scheme or,
+// without one, the first scheme by name.
+func themeSchemeFor(info *TemplateInfo, scheme string) string {
+	if _, ok := info.Schemes[scheme]; ok {
+		return scheme
+	}
+	if _, ok := info.Schemes["default"]; ok {
+		return "default"
+	}
+	first := ""
+	for name := range info.Schemes {
+		if first == "" || name < first {
+			first = name
+		}
+	}
+	return first
+}
+
+// currentTheme returns the theme stored for a profile. A template or scheme that is no
+// longer loaded falls back to nordic (or the first template) and its default scheme.
+func currentTheme(profile string) ThemeChoice {
+	templates, names := currentThemes()
+	choice := ThemeChoice{Profile: profile}
+	storage := api.GetStorage()
+	var template, scheme string
+	storage.GetAsForProfile(profile, "template", &template)
+	storage.GetAsForProfile(profile, "scheme", &scheme)
+
+	info, ok := templates[template]
+	choice.Saved = ok
+	if !ok {
+		template = "nordic"
+		if info, ok = templates[template]; !ok && len(names) > 0 {
+			template = names[0]
+			info, ok = templates[template]
+		}
+	}
+	choice.Template = template
+	choice.Scheme = "default"
+	if ok {
+		choice.Scheme = themeSchemeFor(info, scheme)
+	}
+	return choice
+}
+
+// themeCSS returns the CSS of a scheme followed by its template's base CSS, or nothing for
+// an unknown template.
+func themeCSS(template, scheme string) string {
+	templates, _ := currentThemes()
+	info, ok := templates[template]
+	if !ok {
+		return ""
+	}
+	if s, ok := info.Schemes[scheme]; ok {
+		return s.CSS + "\n" + info.BaseCSS
+	}
+	if s, ok := info.Schemes["default"]; ok {
+		return s.CSS + "\n" + info.BaseCSS
+	}
+	return ""
+}
+
+// handleThemeCurrent serves /api/theme/current: GET returns the template and scheme of the
+// profile (?profile=), PUT stores {"template", "scheme"} for it. The index page is rendered
+// with this theme, so it shows without a flash of the default one. An empty scheme selects
+// the template's default.
+func handleThemeCurrent(w http.ResponseWriter, r *http.Request) {
+	profile := api.ProfileFromRequest(r)
+	switch r.Method {
+	case http.MethodGet:
+		api.WriteJSON(w, currentTheme(profile))
+	case http.MethodPut:
+		var req struct {
+			Template string `json:"template"`
+			Scheme   string `json:"scheme"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+			api.WriteJSON(w, map[string]string{"error": "Invalid request body"})
+			return
+		}
+		templates, _ := currentThemes()
+		info, ok := templates[req.Template]
+		if !ok {
+			api.WriteJSON(w, map[string]string{"error": "Unknown template " + req.Template})
+			return
+		}
+		if req.Scheme == "" {
+			req.Scheme = themeSchemeFor(info, "")
+		} else if _, ok := info.Schemes[req.Scheme]; !ok {
+			api.WriteJSON(w, map[string]string{"error": "Template " + req.Template + " has no scheme " + req.Scheme})
+			return
+		}
+		storage := api.GetStorage()
+		storage.SetNext(api.ProfileStorageKey(profile, "template"), req.Template)
+		storage.SetNext(api.ProfileStorageKey(profile, "scheme"), req.Scheme)
+		api.WriteJSON(w, ThemeChoice{Profile: profile, Template: req.Template, Scheme: req.Scheme, Saved: true})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// checkThemeSchedule warns about schemes of the theme schedule that its template, or
+// without one every loaded template, lacks. Profiles then show their template's default.
+func checkThemeSchedule(cfg api.ThemeScheduleConfig) {
+	templates, names := currentThemes()
+	if cfg.Template != "" {
+		if _, ok := templates[cfg.Template]; !ok {
+			api.Logger("themes").Warn("theme schedule names an unknown template", "template", cfg.Template)
+			return
+		}
+		names = []string{cfg.Template}
+	}
+	for _, scheme := range []string{cfg.LightScheme, cfg.DarkScheme} {
+		found := false
+		for _, name := range names {
+			_, ok := templates[name].Schemes[scheme]
+			found = found || ok
+		}
+		if !found {
+			api.Logger("themes").Warn("theme schedule names an unknown scheme", "template", cfg.Template, "scheme", scheme)
+		}
+	}
+}
+
+// handleThemeSchedule serves GET /api/theme/schedule: the phase of the theme schedule, its
+// scheme and when it changes next.
+func handleThemeSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	api.WriteJSON(w, api.GetThemeScheduler().Status())
+}